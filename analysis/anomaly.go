@@ -0,0 +1,157 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"finit/engine"
+)
+
+const (
+	AnomalyQueueGrowth    = "queue_growth"
+	AnomalySaturation     = "saturation"
+	AnomalyRejectionSpike = "rejection_spike"
+)
+
+type Anomaly struct {
+	Kind     string `json:"kind"`
+	FromTick int    `json:"from_tick"`
+	ToTick   int    `json:"to_tick"`
+	Detail   string `json:"detail"`
+}
+
+type AnomalyOptions struct {
+	WindowTicks       int
+	QueueGrowthPerSec float64
+	SaturatedTicks    int
+	RejectionRate     float64
+	MinRejections     int
+}
+
+func DefaultAnomalyOptions() AnomalyOptions {
+	return AnomalyOptions{WindowTicks: 8, QueueGrowthPerSec: 1, SaturatedTicks: 20, RejectionRate: 0.25, MinRejections: 3}
+}
+
+func DetectAnomalies(artifact engine.Artifact, opts AnomalyOptions) []Anomaly {
+	m := artifact.Metadata
+	if m.TickCount <= 0 || m.TickDurationMs <= 0 || opts.WindowTicks <= 0 {
+		return nil
+	}
+	queue, used, total := tickSeries(artifact)
+	arrivals := make([]int, m.TickCount)
+	rejections := make([]int, m.TickCount)
+	for _, lifecycle := range engine.Lifecycles(artifact.Events) {
+		if lifecycle.ArrivalTick >= 0 && lifecycle.ArrivalTick < m.TickCount {
+			arrivals[lifecycle.ArrivalTick]++
+		}
+		if lifecycle.Rejected() && lifecycle.RejectTick < m.TickCount {
+			rejections[lifecycle.RejectTick]++
+		}
+	}
+
+	var anomalies []Anomaly
+	windowSeconds := float64(opts.WindowTicks*m.TickDurationMs) / 1000
+	growing := make([]bool, m.TickCount)
+	spiking := make([]bool, m.TickCount)
+	growth := make([]float64, m.TickCount)
+	arrived := make([]int, m.TickCount)
+	rejected := make([]int, m.TickCount)
+	for tick := m.WarmupTicks + opts.WindowTicks; tick < m.TickCount; tick++ {
+		from := tick - opts.WindowTicks
+		growth[tick] = float64(queue[tick]-queue[from]) / windowSeconds
+		if opts.QueueGrowthPerSec > 0 && growth[tick] > opts.QueueGrowthPerSec {
+			markRange(growing, from, tick)
+		}
+		arrived[tick] = sum(arrivals[from+1 : tick+1])
+		rejected[tick] = sum(rejections[from+1 : tick+1])
+		if rejected[tick] >= max(opts.MinRejections, 1) && float64(rejected[tick]) > opts.RejectionRate*float64(arrived[tick]) {
+			markRange(spiking, from+1, tick)
+		}
+	}
+	for _, r := range ranges(growing) {
+		peak := 0.0
+		for tick := r[0]; tick <= r[1]; tick++ {
+			peak = max(peak, growth[tick])
+		}
+		anomalies = append(anomalies, Anomaly{Kind: AnomalyQueueGrowth, FromTick: r[0], ToTick: r[1],
+			Detail: fmt.Sprintf("queue grew from %d to %d, peaking at %.1f/s over %d ticks", queue[r[0]], queue[r[1]], peak, opts.WindowTicks)})
+	}
+	for _, r := range ranges(spiking) {
+		peak := r[0]
+		for tick := r[0]; tick <= r[1]; tick++ {
+			if rejected[tick] > rejected[peak] {
+				peak = tick
+			}
+		}
+		anomalies = append(anomalies, Anomaly{Kind: AnomalyRejectionSpike, FromTick: r[0], ToTick: r[1],
+			Detail: fmt.Sprintf("%d rejections, peaking at %d of %d arrivals over %d ticks", sum(rejections[r[0]:r[1]+1]), rejected[peak], arrived[peak], opts.WindowTicks)})
+	}
+
+	if opts.SaturatedTicks > 0 {
+		saturated := make([]bool, m.TickCount)
+		for tick := m.WarmupTicks; tick < m.TickCount; tick++ {
+			saturated[tick] = total[tick] > 0 && used[tick] >= total[tick]
+		}
+		for _, r := range ranges(saturated) {
+			if ticks := r[1] - r[0] + 1; ticks >= opts.SaturatedTicks {
+				anomalies = append(anomalies, Anomaly{Kind: AnomalySaturation, FromTick: r[0], ToTick: r[1],
+					Detail: fmt.Sprintf("service at 100%% for %d ticks (%.1fs)", ticks, float64(ticks*m.TickDurationMs)/1000)})
+			}
+		}
+	}
+
+	sort.SliceStable(anomalies, func(i, j int) bool { return anomalies[i].FromTick < anomalies[j].FromTick })
+	return anomalies
+}
+
+func tickSeries(artifact engine.Artifact) (queue, used, total []int) {
+	n := artifact.Metadata.TickCount
+	queue, used, total = make([]int, n), make([]int, n), make([]int, n)
+	next := 0
+	q, u, t := 0, 0, 0
+	for tick := 0; tick < n; tick++ {
+		for next < len(artifact.Snapshots) && artifact.Snapshots[next].Tick <= tick {
+			q, u, t = 0, 0, 0
+			for _, stage := range artifact.Snapshots[next].Stages {
+				switch stage.ID {
+				case engine.StageQueue:
+					q = stage.QueueLength
+				case engine.StageService:
+					u, t = stage.Load()
+				}
+			}
+			next++
+		}
+		queue[tick], used[tick], total[tick] = q, u, t
+	}
+	return queue, used, total
+}
+
+func markRange(flags []bool, from, to int) {
+	for i := max(from, 0); i <= to && i < len(flags); i++ {
+		flags[i] = true
+	}
+}
+
+func ranges(flags []bool) [][2]int {
+	var out [][2]int
+	for i := 0; i < len(flags); i++ {
+		if !flags[i] {
+			continue
+		}
+		start := i
+		for i+1 < len(flags) && flags[i+1] {
+			i++
+		}
+		out = append(out, [2]int{start, i})
+	}
+	return out
+}
+
+func sum(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
@@ -0,0 +1,59 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestDetectAnomalies(t *testing.T) {
+	queue := []int{0, 0, 0, 0, 1, 3, 5, 7, 7, 7, 6, 4, 2, 0, 0, 0}
+	artifact := engine.Artifact{Metadata: engine.Metadata{TickCount: len(queue), TickDurationMs: 1000}}
+	for tick, length := range queue {
+		used := min(length+1, 2)
+		artifact.Snapshots = append(artifact.Snapshots, engine.Snapshot{Tick: tick, Stages: []engine.StageState{
+			{ID: engine.StageQueue, QueueLength: length},
+			{ID: engine.StageService, CapacityUsed: used, CapacityTotal: 2},
+		}})
+	}
+	for i, tick := range []int{2, 7, 8, 8, 9, 12} {
+		id := fmt.Sprintf("T%04d", i)
+		artifact.Events = append(artifact.Events, engine.Event{Tick: tick, Type: engine.EventQueue, TokenID: id, Class: engine.ClassAnon})
+		if tick >= 7 && tick <= 9 {
+			artifact.Events = append(artifact.Events, engine.Event{Tick: tick, Type: engine.EventReject, TokenID: id, Class: engine.ClassAnon})
+		}
+	}
+
+	opts := AnomalyOptions{WindowTicks: 2, QueueGrowthPerSec: 1, SaturatedTicks: 5, RejectionRate: 0.5, MinRejections: 2}
+	tests := []struct {
+		name   string
+		warmup int
+		want   []Anomaly
+	}{
+		{"all ticks", 0, []Anomaly{
+			{Kind: AnomalyQueueGrowth, FromTick: 3, ToTick: 7},
+			{Kind: AnomalySaturation, FromTick: 4, ToTick: 12},
+			{Kind: AnomalyRejectionSpike, FromTick: 7, ToTick: 9},
+		}},
+		{"after warmup", 6, []Anomaly{
+			{Kind: AnomalySaturation, FromTick: 6, ToTick: 12},
+			{Kind: AnomalyRejectionSpike, FromTick: 7, ToTick: 9},
+		}},
+		{"quiet", 13, nil},
+	}
+	for _, tt := range tests {
+		artifact.Metadata.WarmupTicks = tt.warmup
+		got := DetectAnomalies(artifact, opts)
+		for i := range got {
+			if got[i].Detail == "" {
+				t.Errorf("%s: anomaly %+v has no detail", tt.name, got[i])
+			}
+			got[i].Detail = ""
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: DetectAnomalies() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
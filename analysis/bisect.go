@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"finit/engine"
+)
+
+type Bisection struct {
+	Divergence
+	Field string `json:"field"`
+	A     string `json:"a"`
+	B     string `json:"b"`
+}
+
+func Bisect(a, b engine.Artifact) (*Bisection, error) {
+	if a.Metadata.ReplayID != b.Metadata.ReplayID {
+		return nil, fmt.Errorf("artifacts belong to different replays (%s and %s)", a.Metadata.ReplayID, b.Metadata.ReplayID)
+	}
+	divergence := Compare(a, b).Divergence
+	if divergence == nil {
+		return nil, nil
+	}
+
+	var ea, eb any
+	switch divergence.Kind {
+	case DivergenceEvent:
+		ea, eb = element(a.Events, divergence.Index), element(b.Events, divergence.Index)
+	case DivergenceSnapshot:
+		sa, sb := engine.ExpandSnapshots(a).Snapshots, engine.ExpandSnapshots(b).Snapshots
+		ea, eb = element(sa, divergence.Index), element(sb, divergence.Index)
+	}
+	field, va, vb := firstField("", toJSON(ea), toJSON(eb))
+	return &Bisection{Divergence: *divergence, Field: field, A: va, B: vb}, nil
+}
+
+func EventsBetween(events []engine.Event, from, to int) []engine.Event {
+	start := sort.Search(len(events), func(i int) bool { return events[i].Tick >= from })
+	end := sort.Search(len(events), func(i int) bool { return events[i].Tick > to })
+	return events[start:max(start, end)]
+}
+
+func element[T any](items []T, i int) any {
+	if i < len(items) {
+		return items[i]
+	}
+	return nil
+}
+
+func toJSON(v any) any {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil
+	}
+	return out
+}
+
+func firstField(path string, a, b any) (string, string, string) {
+	switch va := a.(type) {
+	case map[string]any:
+		if vb, ok := b.(map[string]any); ok {
+			keys := make([]string, 0, len(va)+len(vb))
+			for key := range va {
+				keys = append(keys, key)
+			}
+			for key := range vb {
+				if _, ok := va[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if field, fa, fb := firstField(join(path, key), va[key], vb[key]); field != "" {
+					return field, fa, fb
+				}
+			}
+			return "", "", ""
+		}
+	case []any:
+		if vb, ok := b.([]any); ok {
+			for i := 0; i < max(len(va), len(vb)); i++ {
+				if field, fa, fb := firstField(fmt.Sprintf("%s[%d]", path, i), element(va, i), element(vb, i)); field != "" {
+					return field, fa, fb
+				}
+			}
+			return "", "", ""
+		}
+	}
+	sa, sb := describe(a), describe(b)
+	if sa == sb {
+		return "", "", ""
+	}
+	if path == "" {
+		path = "."
+	}
+	return path, sa, sb
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func describe(v any) string {
+	if v == nil {
+		return "<missing>"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,77 @@
+package analysis
+
+import (
+	"testing"
+
+	"finit/engine"
+)
+
+func TestBisect(t *testing.T) {
+	base := run(t, 1)
+
+	tests := []struct {
+		name   string
+		modify func(a *engine.Artifact)
+		want   Bisection
+	}{
+		{
+			name: "event context",
+			modify: func(a *engine.Artifact) {
+				context := *a.Events[100].Context
+				context.QueueLength += 3
+				a.Events[100].Context = &context
+			},
+			want: Bisection{
+				Divergence: Divergence{Kind: DivergenceEvent, Index: 100, Tick: base.Events[100].Tick},
+				Field:      "context.queue_length",
+			},
+		},
+		{
+			name:   "missing event",
+			modify: func(a *engine.Artifact) { a.Events = a.Events[:len(a.Events)-1] },
+			want: Bisection{
+				Divergence: Divergence{Kind: DivergenceEvent, Index: len(base.Events) - 1, Tick: base.Events[len(base.Events)-1].Tick},
+				Field:      ".",
+				B:          "<missing>",
+			},
+		},
+	}
+	for _, tt := range tests {
+		edited := base
+		edited.Events = append([]engine.Event(nil), base.Events...)
+		tt.modify(&edited)
+
+		got, err := Bisect(base, edited)
+		if err != nil {
+			t.Fatalf("%s: Bisect() error = %v", tt.name, err)
+		}
+		if got == nil {
+			t.Fatalf("%s: Bisect() found no divergence", tt.name)
+		}
+		if got.Divergence != tt.want.Divergence || got.Field != tt.want.Field || (tt.want.B != "" && got.B != tt.want.B) {
+			t.Errorf("%s: Bisect() = %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+
+	if got, err := Bisect(base, base); got != nil || err != nil {
+		t.Errorf("Bisect(same) = %+v, %v, want nil, nil", got, err)
+	}
+	if _, err := Bisect(base, run(t, 2)); err == nil {
+		t.Error("Bisect() across replay ids succeeded, want an error")
+	}
+}
+
+func TestEventsBetween(t *testing.T) {
+	events := []engine.Event{{Tick: 1}, {Tick: 2}, {Tick: 2}, {Tick: 4}, {Tick: 7}}
+	tests := []struct {
+		from, to int
+		want     int
+	}{
+		{0, 1, 1}, {2, 4, 3}, {5, 6, 0}, {0, 10, 5}, {4, 2, 0},
+	}
+	for _, tt := range tests {
+		if got := EventsBetween(events, tt.from, tt.to); len(got) != tt.want {
+			t.Errorf("EventsBetween(%d, %d) = %d events, want %d", tt.from, tt.to, len(got), tt.want)
+		}
+	}
+}
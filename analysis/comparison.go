@@ -0,0 +1,229 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"finit/engine"
+)
+
+const (
+	DirectionLower  = "lower"
+	DirectionHigher = "higher"
+)
+
+type RunComparison struct {
+	Before      ComparedRun         `json:"before"`
+	After       ComparedRun         `json:"after"`
+	Threshold   float64             `json:"threshold"`
+	Metrics     []MetricDelta       `json:"metrics"`
+	Latency     []LatencyComparison `json:"latency"`
+	Divergences []Annotation        `json:"divergences"`
+	Paired      *PairedDeltas       `json:"paired,omitempty"`
+}
+
+type ComparedRun struct {
+	Name       string  `json:"name"`
+	ScenarioID string  `json:"scenario_id"`
+	Seed       int64   `json:"seed"`
+	ReplayID   string  `json:"replay_id"`
+	Summary    Summary `json:"summary"`
+}
+
+type MetricDelta struct {
+	Metric string  `json:"metric"`
+	Class  string  `json:"class,omitempty"`
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+	Change float64 `json:"change,omitempty"`
+	Better string  `json:"better,omitempty"`
+}
+
+func (m MetricDelta) Verdict() string {
+	switch {
+	case m.Before == m.After:
+		return ""
+	case m.Better == "":
+		return VerdictChanged
+	case (m.After < m.Before) == (m.Better == DirectionLower):
+		return VerdictBetter
+	default:
+		return VerdictWorse
+	}
+}
+
+type LatencyComparison struct {
+	Class  string    `json:"class"`
+	Before Histogram `json:"before"`
+	After  Histogram `json:"after"`
+}
+
+type Annotation struct {
+	Metric  string `json:"metric,omitempty"`
+	Class   string `json:"class,omitempty"`
+	Verdict string `json:"verdict"`
+	Text    string `json:"text"`
+}
+
+const (
+	VerdictBetter  = "better"
+	VerdictWorse   = "worse"
+	VerdictChanged = "changed"
+)
+
+func CompareRuns(before, after engine.Artifact, beforeName, afterName string, threshold float64) RunComparison {
+	sb, sa := Summarize(before), Summarize(after)
+	c := RunComparison{
+		Before:    comparedRun(beforeName, before.Metadata, sb),
+		After:     comparedRun(afterName, after.Metadata, sa),
+		Threshold: threshold,
+	}
+
+	metric := func(name, class, better string, vb, va float64) {
+		d := MetricDelta{Metric: name, Class: class, Before: vb, After: va, Better: better}
+		if vb != 0 {
+			d.Change = math.Round(10000*(va-vb)/math.Abs(vb)) / 10000
+		}
+		c.Metrics = append(c.Metrics, d)
+	}
+	metric("arrived", "", "", float64(sb.Arrived), float64(sa.Arrived))
+	metric("completed", "", DirectionHigher, float64(sb.Completed), float64(sa.Completed))
+	metric("rejected", "", DirectionLower, float64(sb.Rejected), float64(sa.Rejected))
+	metric("failed", "", DirectionLower, float64(sb.Failed), float64(sa.Failed))
+	metric("throughput_per_sec", "", DirectionHigher, round3(sb.Throughput), round3(sa.Throughput))
+	metric("max_queue_length", "", DirectionLower, float64(sb.MaxQueueLength), float64(sa.MaxQueueLength))
+	metric("utilization", "", "", round3(sb.Utilization), round3(sa.Utilization))
+	metric("jain_index", "", DirectionHigher, round3(sb.Fairness.JainIndex), round3(sa.Fairness.JainIndex))
+
+	byClass := make(map[string][2]ClassSummary)
+	for _, cs := range sb.Classes {
+		pair := byClass[cs.Class]
+		pair[0] = cs
+		byClass[cs.Class] = pair
+	}
+	for _, cs := range sa.Classes {
+		pair := byClass[cs.Class]
+		pair[1] = cs
+		byClass[cs.Class] = pair
+	}
+	latencyBefore, latencyAfter := Latencies(before), Latencies(after)
+	for _, class := range classOrder(byClass) {
+		pair, ok := byClass[class]
+		if !ok {
+			continue
+		}
+		cb, ca := pair[0], pair[1]
+		metric("completed", class, DirectionHigher, float64(cb.Completed), float64(ca.Completed))
+		metric("rejection_rate", class, DirectionLower, round3(cb.RejectionRate), round3(ca.RejectionRate))
+		metric("mean_wait_ms", class, DirectionLower, math.Round(10*cb.MeanWaitMs)/10, math.Round(10*ca.MeanWaitMs)/10)
+		metric("p50_latency_ms", class, DirectionLower, float64(cb.P50LatencyMs), float64(ca.P50LatencyMs))
+		metric("p95_latency_ms", class, DirectionLower, float64(cb.P95LatencyMs), float64(ca.P95LatencyMs))
+		metric("p99_latency_ms", class, DirectionLower, float64(cb.P99LatencyMs), float64(ca.P99LatencyMs))
+
+		bounds := latencyBounds(slices.Concat(latencyBefore[class], latencyAfter[class]), before.Metadata.TickDurationMs)
+		c.Latency = append(c.Latency, LatencyComparison{
+			Class:  class,
+			Before: NewHistogram(bounds, latencyBefore[class]),
+			After:  NewHistogram(bounds, latencyAfter[class]),
+		})
+	}
+
+	c.Divergences = annotate(c.Metrics, threshold)
+	c.Divergences = append(c.Divergences, anomalyAnnotations(sb.Anomalies, sa.Anomalies)...)
+	if sameArrivals(before, after) {
+		paired := pairTokens(before, after)
+		c.Paired = &paired
+		if paired.Rescued > 0 || paired.Lost > 0 {
+			verdict := VerdictChanged
+			switch {
+			case paired.Lost == 0:
+				verdict = VerdictBetter
+			case paired.Rescued == 0:
+				verdict = VerdictWorse
+			}
+			c.Divergences = append(c.Divergences, Annotation{Verdict: verdict,
+				Text: fmt.Sprintf("of the same requests, %d now complete that did not before and %d no longer complete", paired.Rescued, paired.Lost)})
+		}
+	}
+	return c
+}
+
+func comparedRun(name string, m engine.Metadata, summary Summary) ComparedRun {
+	return ComparedRun{Name: name, ScenarioID: m.ScenarioID, Seed: m.Seed, ReplayID: m.ReplayID, Summary: summary}
+}
+
+func latencyBounds(values []int, tickMs int) []int {
+	peak := 0
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+	step := max(tickMs, 1)
+	width := max((peak/10+step-1)/step*step, step)
+	bounds := make([]int, 0, 9)
+	for i := 1; i < 10; i++ {
+		bounds = append(bounds, i*width)
+	}
+	return bounds
+}
+
+func annotate(metrics []MetricDelta, threshold float64) []Annotation {
+	var annotations []Annotation
+	for _, m := range metrics {
+		if m.Before == m.After || m.Before != 0 && math.Abs(m.Change) < threshold {
+			continue
+		}
+		name := m.Metric
+		if m.Class != "" {
+			name = m.Class + " " + m.Metric
+		}
+		direction := "rose"
+		if m.After < m.Before {
+			direction = "fell"
+		}
+		text := fmt.Sprintf("%s %s from %s to %s", name, direction, formatMetric(m.Before), formatMetric(m.After))
+		if m.Before != 0 {
+			text = fmt.Sprintf("%s %s %.0f%%, from %s to %s", name, direction, 100*math.Abs(m.Change), formatMetric(m.Before), formatMetric(m.After))
+		}
+		annotations = append(annotations, Annotation{Metric: m.Metric, Class: m.Class, Verdict: m.Verdict(), Text: text})
+	}
+	return annotations
+}
+
+func anomalyAnnotations(before, after []Anomaly) []Annotation {
+	kinds := func(anomalies []Anomaly) map[string][]Anomaly {
+		out := make(map[string][]Anomaly)
+		for _, a := range anomalies {
+			out[a.Kind] = append(out[a.Kind], a)
+		}
+		return out
+	}
+	kb, ka := kinds(before), kinds(after)
+	var annotations []Annotation
+	for _, kind := range []string{AnomalyQueueGrowth, AnomalySaturation, AnomalyRejectionSpike} {
+		switch {
+		case len(kb[kind]) > 0 && len(ka[kind]) == 0:
+			a := kb[kind][0]
+			annotations = append(annotations, Annotation{Metric: kind, Verdict: VerdictBetter,
+				Text: fmt.Sprintf("no %s any more (before: ticks %d-%d, %s)", kind, a.FromTick, a.ToTick, a.Detail)})
+		case len(kb[kind]) == 0 && len(ka[kind]) > 0:
+			a := ka[kind][0]
+			annotations = append(annotations, Annotation{Metric: kind, Verdict: VerdictWorse,
+				Text: fmt.Sprintf("new %s at ticks %d-%d: %s", kind, a.FromTick, a.ToTick, a.Detail)})
+		}
+	}
+	return annotations
+}
+
+func sameArrivals(a, b engine.Artifact) bool {
+	return slices.EqualFunc(engine.ExtractArrivals(a), engine.ExtractArrivals(b), func(x, y engine.Arrival) bool {
+		return x.Tick == y.Tick && x.Class == y.Class
+	})
+}
+
+func formatMetric(v float64) string {
+	if v == math.Trunc(v) || math.Abs(v) >= 100 {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.3g", v)
+}
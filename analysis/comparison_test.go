@@ -0,0 +1,85 @@
+package analysis
+
+import (
+	"context"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestCompareRuns(t *testing.T) {
+	before, err := engine.Run(engine.Config{ScenarioID: engine.ScenarioID, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same := CompareRuns(before, before, "a", "b", 0.1)
+	if len(same.Divergences) != 0 {
+		t.Errorf("CompareRuns(same) divergences = %+v, want none", same.Divergences)
+	}
+	if same.Paired == nil || same.Paired.Unchanged != Summarize(before).Arrived {
+		t.Errorf("CompareRuns(same) paired = %+v, want every request unchanged", same.Paired)
+	}
+
+	after, _, err := Counterfactual(context.Background(), before, "", []Parameter{{Name: "capacity", Values: []int{1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := CompareRuns(before, after, "before", "after", 0.1)
+	if c.Before.Name != "before" || c.After.Name != "after" {
+		t.Errorf("CompareRuns() names = %q, %q", c.Before.Name, c.After.Name)
+	}
+	verdicts := make(map[string]string)
+	for _, a := range c.Divergences {
+		if a.Class == "" && a.Metric != "" {
+			verdicts[a.Metric] = a.Verdict
+		}
+	}
+	for metric, want := range map[string]string{
+		"completed":        VerdictWorse,
+		"rejected":         VerdictWorse,
+		"max_queue_length": VerdictWorse,
+		"utilization":      VerdictChanged,
+	} {
+		if verdicts[metric] != want {
+			t.Errorf("CompareRuns() %s verdict = %q, want %q", metric, verdicts[metric], want)
+		}
+	}
+	if c.Paired == nil || c.Paired.Lost == 0 {
+		t.Errorf("CompareRuns() paired = %+v, want lost requests", c.Paired)
+	}
+	for _, l := range c.Latency {
+		if len(l.Before.Bounds) != len(l.After.Bounds) {
+			t.Errorf("CompareRuns() %s latency bounds differ", l.Class)
+		}
+		if want := len(Latencies(after)[l.Class]); l.After.Total() != want {
+			t.Errorf("CompareRuns() %s after latencies = %d, want %d", l.Class, l.After.Total(), want)
+		}
+	}
+
+	other, err := engine.Run(engine.Config{ScenarioID: engine.ScenarioID, Seed: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := CompareRuns(before, other, "a", "b", 0.1); c.Paired != nil {
+		t.Errorf("CompareRuns(different seeds) paired = %+v, want nil", c.Paired)
+	}
+}
+
+func TestMetricDeltaVerdict(t *testing.T) {
+	tests := []struct {
+		delta MetricDelta
+		want  string
+	}{
+		{MetricDelta{Before: 1, After: 1, Better: DirectionLower}, ""},
+		{MetricDelta{Before: 2, After: 1, Better: DirectionLower}, VerdictBetter},
+		{MetricDelta{Before: 2, After: 1, Better: DirectionHigher}, VerdictWorse},
+		{MetricDelta{Before: 1, After: 2, Better: DirectionHigher}, VerdictBetter},
+		{MetricDelta{Before: 1, After: 2}, VerdictChanged},
+	}
+	for _, tt := range tests {
+		if got := tt.delta.Verdict(); got != tt.want {
+			t.Errorf("%+v.Verdict() = %q, want %q", tt.delta, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	MetricMeanWaitMs    = "mean_wait_ms"
+	MetricP95LatencyMs  = "p95_latency_ms"
+	MetricRejectionRate = "rejection_rate"
+)
+
+const ClassAll = "ALL"
+
+type Interval struct {
+	Metric            string  `json:"metric"`
+	Class             string  `json:"class"`
+	Runs              int     `json:"runs"`
+	Mean              float64 `json:"mean"`
+	StdDev            float64 `json:"std_dev"`
+	HalfWidth         float64 `json:"half_width"`
+	RelativeHalfWidth float64 `json:"relative_half_width,omitempty"`
+}
+
+func (e *Experiment) Aggregate(level float64) error {
+	if level <= 0 || level >= 1 {
+		return fmt.Errorf("confidence level must be in (0, 1), got %v", level)
+	}
+	e.Confidence = level
+	e.Intervals = e.ConfidenceIntervals(level)
+	return nil
+}
+
+func (e Experiment) ConfidenceIntervals(level float64) []Interval {
+	if len(e.Runs) < 2 {
+		return nil
+	}
+	byClass := make(map[string]map[string][]float64)
+	add := func(class, metric string, value float64) {
+		if byClass[class] == nil {
+			byClass[class] = make(map[string][]float64)
+		}
+		byClass[class][metric] = append(byClass[class][metric], value)
+	}
+	var rejections []float64
+	for _, run := range e.Runs {
+		s := run.Summary
+		rejection := 0.0
+		if s.Arrived > 0 {
+			rejection = float64(s.Rejected) / float64(s.Arrived)
+		}
+		rejections = append(rejections, rejection)
+		for _, cs := range s.Classes {
+			add(cs.Class, MetricMeanWaitMs, cs.MeanWaitMs)
+			add(cs.Class, MetricP95LatencyMs, float64(cs.P95LatencyMs))
+			add(cs.Class, MetricRejectionRate, cs.RejectionRate)
+		}
+	}
+
+	var intervals []Interval
+	for _, class := range classOrder(byClass) {
+		for _, metric := range []string{MetricMeanWaitMs, MetricP95LatencyMs, MetricRejectionRate} {
+			if values := byClass[class][metric]; len(values) > 1 {
+				intervals = append(intervals, tInterval(class, metric, values, level))
+			}
+		}
+	}
+	return append(intervals, tInterval(ClassAll, MetricRejectionRate, rejections, level))
+}
+
+func tInterval(class, metric string, values []float64, level float64) Interval {
+	n := len(values)
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(n)
+	sumSq := 0.0
+	for _, v := range values {
+		sumSq += (v - mean) * (v - mean)
+	}
+	stddev := math.Sqrt(sumSq / float64(n-1))
+	interval := Interval{
+		Metric:    metric,
+		Class:     class,
+		Runs:      n,
+		Mean:      mean,
+		StdDev:    stddev,
+		HalfWidth: StudentT(1-(1-level)/2, n-1) * stddev / math.Sqrt(float64(n)),
+	}
+	if mean != 0 {
+		interval.RelativeHalfWidth = interval.HalfWidth / math.Abs(mean)
+	}
+	return interval
+}
+
+func StudentT(p float64, df int) float64 {
+	if p == 0.5 {
+		return 0
+	}
+	if p < 0.5 {
+		return -StudentT(1-p, df)
+	}
+	lo, hi := 0.0, 1.0
+	for studentCDF(hi, df) < p {
+		lo, hi = hi, hi*2
+	}
+	for i := 0; i < 100 && hi-lo > 1e-10; i++ {
+		mid := (lo + hi) / 2
+		if studentCDF(mid, df) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+func studentCDF(t float64, df int) float64 {
+	v := float64(df)
+	tail := 0.5 * incompleteBeta(v/(v+t*t), v/2, 0.5)
+	if t < 0 {
+		return tail
+	}
+	return 1 - tail
+}
+
+func incompleteBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaFraction(1-x, b, a)/b
+	}
+	return front * betaFraction(x, a, b) / a
+}
+
+func betaFraction(x, a, b float64) float64 {
+	const tiny = 1e-300
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= 200; m++ {
+		fm := float64(m)
+		for _, num := range []float64{
+			fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm)),
+			-(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1)),
+		} {
+			d = 1 + num*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + num/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			h *= d * c
+		}
+		if math.Abs(d*c-1) < 1e-14 {
+			break
+		}
+	}
+	return h
+}
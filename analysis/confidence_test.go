@@ -0,0 +1,62 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestStudentT(t *testing.T) {
+	tests := []struct {
+		p    float64
+		df   int
+		want float64
+	}{
+		{p: 0.975, df: 1, want: 12.706},
+		{p: 0.975, df: 10, want: 2.228},
+		{p: 0.975, df: 30, want: 2.042},
+		{p: 0.995, df: 5, want: 4.032},
+		{p: 0.95, df: 4, want: 2.132},
+		{p: 0.025, df: 10, want: -2.228},
+		{p: 0.5, df: 3, want: 0},
+	}
+	for _, tt := range tests {
+		if got := StudentT(tt.p, tt.df); math.Abs(got-tt.want) > 1e-3 {
+			t.Errorf("StudentT(%v, %d) = %v, want %v", tt.p, tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestConfidenceIntervals(t *testing.T) {
+	run := func(wait float64, p95, arrived, rejected int) ExperimentRun {
+		return ExperimentRun{Summary: Summary{Arrived: arrived, Rejected: rejected, Classes: []ClassSummary{
+			{Class: engine.ClassPaid, MeanWaitMs: wait, P95LatencyMs: p95, RejectionRate: float64(rejected) / float64(arrived)},
+		}}}
+	}
+	experiment := Experiment{Runs: []ExperimentRun{run(100, 500, 10, 1), run(200, 700, 10, 3), run(300, 900, 10, 2)}}
+	if err := experiment.Aggregate(0.95); err != nil {
+		t.Fatal(err)
+	}
+	if len(experiment.Intervals) != 4 {
+		t.Fatalf("Aggregate() = %d intervals, want 4", len(experiment.Intervals))
+	}
+	wait := experiment.Intervals[0]
+	if wait.Metric != MetricMeanWaitMs || wait.Class != engine.ClassPaid || wait.Runs != 3 || wait.Mean != 200 || wait.StdDev != 100 {
+		t.Errorf("wait interval = %+v, want PAID mean 200, std dev 100 over 3 runs", wait)
+	}
+	if want := 4.303 * 100 / math.Sqrt(3); math.Abs(wait.HalfWidth-want) > 0.1 || math.Abs(wait.RelativeHalfWidth-want/200) > 1e-3 {
+		t.Errorf("wait half width = %v (%v relative), want %v", wait.HalfWidth, wait.RelativeHalfWidth, want)
+	}
+	overall := experiment.Intervals[3]
+	if overall.Class != ClassAll || overall.Metric != MetricRejectionRate || math.Abs(overall.Mean-0.2) > 1e-12 {
+		t.Errorf("overall interval = %+v, want ALL rejection_rate mean 0.2", overall)
+	}
+
+	if err := experiment.Aggregate(1); err == nil {
+		t.Error("Aggregate(1) error = nil")
+	}
+	if got := (Experiment{Runs: experiment.Runs[:1]}).ConfidenceIntervals(0.95); got != nil {
+		t.Errorf("ConfidenceIntervals() with one run = %+v, want nil", got)
+	}
+}
@@ -0,0 +1,102 @@
+package analysis
+
+import (
+	"fmt"
+	"reflect"
+
+	"finit/engine"
+)
+
+const (
+	DivergenceSnapshot = "snapshot"
+	DivergenceEvent    = "event"
+)
+
+type Difference struct {
+	Field string `json:"field"`
+	A     string `json:"a"`
+	B     string `json:"b"`
+}
+
+type Divergence struct {
+	Kind  string `json:"kind"`
+	Index int    `json:"index"`
+	Tick  int    `json:"tick"`
+}
+
+type Diff struct {
+	Metadata   []Difference `json:"metadata,omitempty"`
+	Summary    []Difference `json:"summary,omitempty"`
+	Divergence *Divergence  `json:"divergence,omitempty"`
+}
+
+func (d Diff) Equal() bool {
+	return len(d.Metadata) == 0 && len(d.Summary) == 0 && d.Divergence == nil
+}
+
+func Compare(a, b engine.Artifact) Diff {
+	var diff Diff
+	field := func(into *[]Difference, name string, va, vb any) {
+		sa, sb := fmt.Sprint(va), fmt.Sprint(vb)
+		if sa != sb {
+			*into = append(*into, Difference{Field: name, A: sa, B: sb})
+		}
+	}
+
+	ma, mb := a.Metadata, b.Metadata
+	field(&diff.Metadata, "scenario_id", ma.ScenarioID, mb.ScenarioID)
+	field(&diff.Metadata, "seed", ma.Seed, mb.Seed)
+	field(&diff.Metadata, "engine_version", ma.EngineVersion, mb.EngineVersion)
+	field(&diff.Metadata, "replay_id", ma.ReplayID, mb.ReplayID)
+	field(&diff.Metadata, "tick_count", ma.TickCount, mb.TickCount)
+	field(&diff.Metadata, "tick_duration_ms", ma.TickDurationMs, mb.TickDurationMs)
+	field(&diff.Metadata, "snapshot_interval", max(ma.SnapshotInterval, 1), max(mb.SnapshotInterval, 1))
+
+	sa, sb := Summarize(a), Summarize(b)
+	field(&diff.Summary, "arrived", sa.Arrived, sb.Arrived)
+	field(&diff.Summary, "completed", sa.Completed, sb.Completed)
+	field(&diff.Summary, "rejected", sa.Rejected, sb.Rejected)
+	field(&diff.Summary, "failed", sa.Failed, sb.Failed)
+	field(&diff.Summary, "throughput_per_sec", fmt.Sprintf("%.2f", sa.Throughput), fmt.Sprintf("%.2f", sb.Throughput))
+	field(&diff.Summary, "max_queue_length", sa.MaxQueueLength, sb.MaxQueueLength)
+	field(&diff.Summary, "utilization", fmt.Sprintf("%.4f", sa.Utilization), fmt.Sprintf("%.4f", sb.Utilization))
+	for i := 0; i < min(len(sa.Classes), len(sb.Classes)); i++ {
+		ca, cb := sa.Classes[i], sb.Classes[i]
+		if ca.Class != cb.Class {
+			continue
+		}
+		field(&diff.Summary, ca.Class+".arrived", ca.Arrived, cb.Arrived)
+		field(&diff.Summary, ca.Class+".completed", ca.Completed, cb.Completed)
+		field(&diff.Summary, ca.Class+".rejected", ca.Rejected, cb.Rejected)
+		field(&diff.Summary, ca.Class+".failed", ca.Failed, cb.Failed)
+		field(&diff.Summary, ca.Class+".mean_wait_ms", fmt.Sprintf("%.1f", ca.MeanWaitMs), fmt.Sprintf("%.1f", cb.MeanWaitMs))
+		field(&diff.Summary, ca.Class+".p95_latency_ms", ca.P95LatencyMs, cb.P95LatencyMs)
+		field(&diff.Summary, ca.Class+".p99_latency_ms", ca.P99LatencyMs, cb.P99LatencyMs)
+	}
+
+	snapshot := firstDivergence(engine.ExpandSnapshots(a).Snapshots, engine.ExpandSnapshots(b).Snapshots, DivergenceSnapshot,
+		func(s engine.Snapshot) int { return s.Tick })
+	event := firstDivergence(a.Events, b.Events, DivergenceEvent,
+		func(e engine.Event) int { return e.Tick })
+	switch {
+	case event != nil && (snapshot == nil || event.Tick <= snapshot.Tick):
+		diff.Divergence = event
+	case snapshot != nil:
+		diff.Divergence = snapshot
+	}
+	return diff
+}
+
+func firstDivergence[T any](a, b []T, kind string, tick func(T) int) *Divergence {
+	for i := 0; i < max(len(a), len(b)); i++ {
+		switch {
+		case i >= len(a):
+			return &Divergence{Kind: kind, Index: i, Tick: tick(b[i])}
+		case i >= len(b):
+			return &Divergence{Kind: kind, Index: i, Tick: tick(a[i])}
+		case !reflect.DeepEqual(a[i], b[i]):
+			return &Divergence{Kind: kind, Index: i, Tick: min(tick(a[i]), tick(b[i]))}
+		}
+	}
+	return nil
+}
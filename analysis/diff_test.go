@@ -0,0 +1,55 @@
+package analysis
+
+import (
+	"testing"
+
+	"finit/engine"
+)
+
+func TestCompare(t *testing.T) {
+	one := run(t, 1)
+	two := run(t, 2)
+
+	if diff := Compare(one, one); !diff.Equal() {
+		t.Errorf("Compare(same) = %+v, want equal", diff)
+	}
+	if diff := Compare(one, engine.ExpandSnapshots(one)); !diff.Equal() {
+		t.Errorf("Compare(active, full) = %+v, want equal", diff)
+	}
+
+	diff := Compare(one, two)
+	if diff.Equal() {
+		t.Fatal("Compare(seed 1, seed 2) reported no differences")
+	}
+	fields := make(map[string]bool)
+	for _, d := range diff.Metadata {
+		fields[d.Field] = true
+	}
+	if !fields["seed"] || !fields["replay_id"] || fields["scenario_id"] {
+		t.Errorf("metadata differences = %+v, want seed and replay_id only", diff.Metadata)
+	}
+	if diff.Divergence == nil {
+		t.Fatal("Compare(seed 1, seed 2) found no divergence")
+	}
+
+	edited := one
+	edited.Events = append([]engine.Event(nil), one.Events...)
+	edited.Events[40].Class = "OTHER"
+	diff = Compare(one, edited)
+	want := Divergence{Kind: DivergenceEvent, Index: 40, Tick: one.Events[40].Tick}
+	if diff.Divergence == nil || *diff.Divergence != want {
+		t.Errorf("Divergence = %+v, want %+v", diff.Divergence, want)
+	}
+	if len(diff.Metadata) != 0 {
+		t.Errorf("metadata differences = %+v, want none", diff.Metadata)
+	}
+}
+
+func run(t *testing.T, seed int64) engine.Artifact {
+	t.Helper()
+	artifact, err := engine.Run(engine.Config{Seed: seed})
+	if err != nil {
+		t.Fatalf("Run(%d) error = %v", seed, err)
+	}
+	return artifact
+}
@@ -0,0 +1,90 @@
+package analysis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"finit/engine"
+)
+
+type Experiment struct {
+	EngineVersion string          `json:"engine_version"`
+	Runs          []ExperimentRun `json:"runs"`
+	Confidence    float64         `json:"confidence,omitempty"`
+	Intervals     []Interval      `json:"intervals,omitempty"`
+}
+
+type ExperimentRun struct {
+	Metadata engine.Metadata `json:"metadata"`
+	Summary  Summary         `json:"summary"`
+}
+
+func (e *Experiment) Add(artifact engine.Artifact) error {
+	m := artifact.Metadata
+	if len(e.Runs) == 0 {
+		e.EngineVersion = m.EngineVersion
+	}
+	if m.EngineVersion != e.EngineVersion {
+		return fmt.Errorf("run %s used engine %s, experiment uses %s", m.ReplayID, m.EngineVersion, e.EngineVersion)
+	}
+	for _, run := range e.Runs {
+		if run.Metadata.ReplayID == m.ReplayID {
+			return fmt.Errorf("run %s (scenario %s, seed %d) appears more than once", m.ReplayID, m.ScenarioID, m.Seed)
+		}
+	}
+	e.Runs = append(e.Runs, ExperimentRun{Metadata: m, Summary: Summarize(artifact)})
+	return nil
+}
+
+func (e Experiment) Filter(labels map[string]string) Experiment {
+	filtered := Experiment{EngineVersion: e.EngineVersion}
+	for _, run := range e.Runs {
+		if HasLabels(run.Metadata, labels) {
+			filtered.Runs = append(filtered.Runs, run)
+		}
+	}
+	return filtered
+}
+
+func HasLabels(metadata engine.Metadata, labels map[string]string) bool {
+	for key, value := range labels {
+		if got, ok := metadata.Labels[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+func Merge(artifacts ...engine.Artifact) (Experiment, error) {
+	var experiment Experiment
+	for _, artifact := range artifacts {
+		if err := experiment.Add(artifact); err != nil {
+			return Experiment{}, err
+		}
+	}
+	if len(experiment.Runs) == 0 {
+		return Experiment{}, errors.New("experiment has no runs")
+	}
+	return experiment, nil
+}
+
+func WriteExperiment(w io.Writer, experiment Experiment) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(experiment)
+}
+
+func ReadExperiment(path string) (Experiment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Experiment{}, err
+	}
+	var experiment Experiment
+	if err := json.Unmarshal(data, &experiment); err != nil {
+		return Experiment{}, fmt.Errorf("decode %s: %w", path, err)
+	}
+	return experiment, nil
+}
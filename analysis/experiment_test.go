@@ -0,0 +1,92 @@
+package analysis
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestMerge(t *testing.T) {
+	var artifacts []engine.Artifact
+	for _, seed := range []int64{1, 2} {
+		artifact, err := engine.Run(engine.Config{Seed: seed})
+		if err != nil {
+			t.Fatalf("Run(%d) error = %v", seed, err)
+		}
+		artifacts = append(artifacts, artifact)
+	}
+
+	experiment, err := Merge(artifacts...)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if len(experiment.Runs) != 2 || experiment.EngineVersion != engine.EngineVersion {
+		t.Fatalf("Merge() = %d runs, engine %q", len(experiment.Runs), experiment.EngineVersion)
+	}
+	if got, want := experiment.Runs[1].Summary, Summarize(artifacts[1]); got.Arrived != want.Arrived || got.Rejected != want.Rejected {
+		t.Errorf("run 2 summary = %+v, want %+v", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteExperiment(&buf, experiment); err != nil {
+		t.Fatalf("WriteExperiment() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "experiment.json")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadExperiment(path)
+	if err != nil {
+		t.Fatalf("ReadExperiment() error = %v", err)
+	}
+	if !reflect.DeepEqual(read.Runs[0].Metadata, artifacts[0].Metadata) {
+		t.Errorf("ReadExperiment() metadata = %+v", read.Runs[0].Metadata)
+	}
+
+	if _, err := Merge(artifacts[0], artifacts[0]); err == nil {
+		t.Error("Merge() with a duplicate run error = nil")
+	}
+	other := artifacts[1]
+	other.Metadata.EngineVersion = "9.9.9"
+	if _, err := Merge(artifacts[0], other); err == nil {
+		t.Error("Merge() with mixed engine versions error = nil")
+	}
+	if _, err := Merge(); err == nil {
+		t.Error("Merge() with no runs error = nil")
+	}
+}
+
+func TestExperimentFilter(t *testing.T) {
+	var artifacts []engine.Artifact
+	for seed, group := range map[int64]string{1: "control", 2: "treatment", 3: "treatment"} {
+		artifact, err := engine.Run(engine.Config{Seed: seed, Labels: map[string]string{"group": group, "sha": "abc"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		artifacts = append(artifacts, artifact)
+	}
+	experiment, err := Merge(artifacts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		labels map[string]string
+		want   int
+	}{
+		{nil, 3},
+		{map[string]string{"group": "treatment"}, 2},
+		{map[string]string{"group": "treatment", "sha": "abc"}, 2},
+		{map[string]string{"group": "control", "sha": "def"}, 0},
+		{map[string]string{"owner": "me"}, 0},
+	}
+	for _, tt := range tests {
+		if got := len(experiment.Filter(tt.labels).Runs); got != tt.want {
+			t.Errorf("Filter(%v) = %d runs, want %d", tt.labels, got, tt.want)
+		}
+	}
+}
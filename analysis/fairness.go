@@ -0,0 +1,169 @@
+package analysis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"finit/engine"
+)
+
+const (
+	WeightsDemand     = "demand"
+	WeightsConfigured = "configured"
+)
+
+type Fairness struct {
+	JainIndex float64         `json:"jain_index"`
+	Weights   string          `json:"weights"`
+	Classes   []ClassFairness `json:"classes"`
+}
+
+type ClassFairness struct {
+	Class                  string  `json:"class"`
+	Weight                 float64 `json:"weight"`
+	ServiceTicks           int     `json:"service_ticks"`
+	CapacityShare          float64 `json:"capacity_share"`
+	ShareRatio             float64 `json:"share_ratio"`
+	LongestStarvationTicks int     `json:"longest_starvation_ticks"`
+	LongestStarvationMs    int     `json:"longest_starvation_ms"`
+	StarvedFromTick        int     `json:"starved_from_tick,omitempty"`
+}
+
+func MeasureFairness(artifact engine.Artifact, weights map[string]float64) Fairness {
+	ticks := artifact.Metadata.TickCount
+	lifecycles := engine.Lifecycles(artifact.Events)
+
+	type classState struct {
+		arrived   int
+		service   int
+		waiting   []int
+		scheduled []bool
+	}
+	states := make(map[string]*classState)
+	state := func(class string) *classState {
+		if cs, ok := states[class]; ok {
+			return cs
+		}
+		cs := &classState{waiting: make([]int, ticks+1), scheduled: make([]bool, ticks)}
+		states[class] = cs
+		return cs
+	}
+	for _, class := range Classes {
+		state(class)
+	}
+
+	for _, lifecycle := range lifecycles {
+		if lifecycle.ArrivalTick < artifact.Metadata.WarmupTicks {
+			continue
+		}
+		cs := state(lifecycle.Class)
+		cs.arrived++
+		if lifecycle.Rejected() {
+			continue
+		}
+		waitEnd := ticks
+		if lifecycle.Scheduled() {
+			waitEnd = min(lifecycle.ScheduleTick, ticks)
+			serviceEnd := ticks
+			if lifecycle.Completed() {
+				serviceEnd = lifecycle.CompleteTick
+			}
+			cs.service += serviceEnd - lifecycle.ScheduleTick
+			if lifecycle.ScheduleTick < ticks {
+				cs.scheduled[lifecycle.ScheduleTick] = true
+			}
+		}
+		if start := max(lifecycle.ArrivalTick, 0); start < waitEnd {
+			cs.waiting[start]++
+			cs.waiting[waitEnd]--
+		}
+	}
+
+	fairness := Fairness{Weights: WeightsConfigured}
+	if len(weights) == 0 {
+		fairness.Weights = WeightsDemand
+		weights = make(map[string]float64, len(states))
+		for class, cs := range states {
+			weights[class] = float64(cs.arrived)
+		}
+	}
+
+	totalService := 0
+	totalWeight := 0.0
+	for class, cs := range states {
+		totalService += cs.service
+		if cs.arrived > 0 {
+			totalWeight += weights[class]
+		}
+	}
+
+	byClass := make(map[string]*ClassFairness, len(states))
+	var ratios []float64
+	for class, cs := range states {
+		cf := &ClassFairness{Class: class, ServiceTicks: cs.service}
+		if cs.arrived > 0 && totalWeight > 0 {
+			cf.Weight = weights[class] / totalWeight
+		}
+		if totalService > 0 {
+			cf.CapacityShare = float64(cs.service) / float64(totalService)
+		}
+		if cf.Weight > 0 {
+			cf.ShareRatio = cf.CapacityShare / cf.Weight
+			ratios = append(ratios, cf.ShareRatio)
+		}
+
+		waiting, run := 0, 0
+		for tick := 0; tick < ticks; tick++ {
+			waiting += cs.waiting[tick]
+			if waiting > 0 && !cs.scheduled[tick] {
+				run++
+				if run > cf.LongestStarvationTicks {
+					cf.LongestStarvationTicks = run
+					cf.StarvedFromTick = tick - run + 1
+				}
+				continue
+			}
+			run = 0
+		}
+		cf.LongestStarvationMs = cf.LongestStarvationTicks * artifact.Metadata.TickDurationMs
+		byClass[class] = cf
+	}
+
+	fairness.JainIndex = JainIndex(ratios)
+	for _, class := range classOrder(byClass) {
+		fairness.Classes = append(fairness.Classes, *byClass[class])
+	}
+	return fairness
+}
+
+func JainIndex(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum, squares := 0.0, 0.0
+	for _, v := range values {
+		sum += v
+		squares += v * v
+	}
+	if squares == 0 {
+		return 0
+	}
+	return sum * sum / (float64(len(values)) * squares)
+}
+
+func ParseWeights(list string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, pair := range strings.Split(list, ",") {
+		class, raw, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || class == "" {
+			return nil, fmt.Errorf("weight %q is not CLASS=number", pair)
+		}
+		weight, err := strconv.ParseFloat(raw, 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("weight %q is not a non-negative number", pair)
+		}
+		weights[strings.ToUpper(class)] = weight
+	}
+	return weights, nil
+}
@@ -0,0 +1,75 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestJainIndex(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   float64
+	}{
+		{nil, 0},
+		{[]float64{1, 1, 1}, 1},
+		{[]float64{1, 0, 0, 0}, 0.25},
+		{[]float64{1.5, 0.75}, 0.9},
+	}
+	for _, tt := range tests {
+		if got := JainIndex(tt.values); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("JainIndex(%v) = %v, want %v", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestMeasureFairness(t *testing.T) {
+	artifact := engine.Artifact{
+		Metadata: engine.Metadata{TickCount: 6, TickDurationMs: 250},
+		Events: []engine.Event{
+			{Tick: 0, Type: engine.EventQueue, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 0, Type: engine.EventSchedule, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 0, Type: engine.EventQueue, TokenID: "T0001", Class: engine.ClassAnon},
+			{Tick: 1, Type: engine.EventComplete, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 1, Type: engine.EventReject, TokenID: "T0002", Class: engine.ClassAnon},
+			{Tick: 3, Type: engine.EventSchedule, TokenID: "T0001", Class: engine.ClassAnon},
+			{Tick: 4, Type: engine.EventComplete, TokenID: "T0001", Class: engine.ClassAnon},
+		},
+	}
+
+	fairness := MeasureFairness(artifact, nil)
+	if fairness.Weights != WeightsDemand || math.Abs(fairness.JainIndex-0.9) > 1e-12 {
+		t.Errorf("MeasureFairness() = %s weights, index %v, want demand and 0.9", fairness.Weights, fairness.JainIndex)
+	}
+	paid, free, anon := fairness.Classes[0], fairness.Classes[1], fairness.Classes[2]
+	if paid.CapacityShare != 0.5 || anon.CapacityShare != 0.5 || free.Weight != 0 {
+		t.Errorf("shares = PAID %v ANON %v, FREE weight %v", paid.CapacityShare, anon.CapacityShare, free.Weight)
+	}
+	if anon.LongestStarvationTicks != 3 || anon.StarvedFromTick != 0 || anon.LongestStarvationMs != 750 {
+		t.Errorf("ANON starvation = %d ticks from %d, want 3 from 0", anon.LongestStarvationTicks, anon.StarvedFromTick)
+	}
+	if paid.LongestStarvationTicks != 0 {
+		t.Errorf("PAID starvation = %d ticks, want 0", paid.LongestStarvationTicks)
+	}
+
+	weighted := MeasureFairness(artifact, map[string]float64{engine.ClassPaid: 1, engine.ClassAnon: 1})
+	if weighted.Weights != WeightsConfigured || weighted.JainIndex != 1 {
+		t.Errorf("equal weights index = %v (%s), want 1", weighted.JainIndex, weighted.Weights)
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	weights, err := ParseWeights("paid=3, FREE=2,ANON=0.5")
+	if err != nil {
+		t.Fatalf("ParseWeights() error = %v", err)
+	}
+	if weights[engine.ClassPaid] != 3 || weights[engine.ClassFree] != 2 || weights[engine.ClassAnon] != 0.5 {
+		t.Errorf("ParseWeights() = %v", weights)
+	}
+	for _, bad := range []string{"PAID", "PAID=x", "PAID=-1", "=2"} {
+		if _, err := ParseWeights(bad); err == nil {
+			t.Errorf("ParseWeights(%q) error = nil", bad)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package analysis
+
+import (
+	"fmt"
+
+	"finit/engine"
+)
+
+type Heatmaps struct {
+	BucketTicks int     `json:"bucket_ticks"`
+	Ticks       []int   `json:"ticks"`
+	QueueLength Heatmap `json:"queue_length"`
+	Utilization Heatmap `json:"utilization"`
+}
+
+type Heatmap struct {
+	Rows   []string    `json:"rows"`
+	Values [][]float64 `json:"values"`
+	Max    float64     `json:"max"`
+}
+
+func BuildHeatmaps(artifact engine.Artifact, bucketTicks int) (Heatmaps, error) {
+	if bucketTicks <= 0 {
+		return Heatmaps{}, fmt.Errorf("bucket must be at least one tick, got %d", bucketTicks)
+	}
+	queued := make(map[string][]float64)
+	utilization := make(map[string][]float64)
+	var stages []string
+	var counts []int
+	heatmaps := Heatmaps{BucketTicks: bucketTicks}
+	for _, snapshot := range artifact.Snapshots {
+		bucket := snapshot.Tick / bucketTicks
+		if n := len(heatmaps.Ticks); n == 0 || heatmaps.Ticks[n-1] != bucket*bucketTicks {
+			heatmaps.Ticks = append(heatmaps.Ticks, bucket*bucketTicks)
+			counts = append(counts, 0)
+		}
+		col := len(heatmaps.Ticks) - 1
+		counts[col]++
+		for _, token := range snapshot.Tokens {
+			if token.State == engine.StateQueued {
+				addCell(queued, token.Class, col, 1)
+			}
+		}
+		for _, stage := range snapshot.Stages {
+			if _, total := stage.Load(); total == 0 || stage.ID == engine.StageQueue {
+				continue
+			}
+			if _, ok := utilization[stage.ID]; !ok {
+				stages = append(stages, stage.ID)
+			}
+			addCell(utilization, stage.ID, col, stage.Utilization())
+		}
+	}
+
+	var classes []string
+	for _, class := range artifact.Metadata.Classes {
+		classes = append(classes, class.Name)
+	}
+	if len(classes) == 0 {
+		classes = classOrder(queued)
+	}
+	heatmaps.QueueLength = heatmap(classes, queued, counts)
+	heatmaps.Utilization = heatmap(stages, utilization, counts)
+	return heatmaps, nil
+}
+
+func addCell(cells map[string][]float64, row string, col int, v float64) {
+	values := cells[row]
+	for len(values) <= col {
+		values = append(values, 0)
+	}
+	values[col] += v
+	cells[row] = values
+}
+
+func heatmap(rows []string, cells map[string][]float64, counts []int) Heatmap {
+	h := Heatmap{Rows: rows, Values: make([][]float64, len(rows))}
+	for i, row := range rows {
+		values := make([]float64, len(counts))
+		for col, sum := range cells[row] {
+			values[col] = round3(sum / float64(counts[col]))
+			h.Max = max(h.Max, values[col])
+		}
+		h.Values[i] = values
+	}
+	return h
+}
@@ -0,0 +1,57 @@
+package analysis
+
+import (
+	"slices"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestBuildHeatmaps(t *testing.T) {
+	queued := func(id, class string) engine.TokenState {
+		return engine.TokenState{ID: id, Class: class, State: engine.StateQueued}
+	}
+	stage := func(used int) []engine.StageState {
+		return []engine.StageState{{ID: "intake"}, {ID: "service", CapacityUsed: used, CapacityTotal: 4}}
+	}
+	artifact := engine.Artifact{
+		Metadata: engine.Metadata{Classes: []engine.ClassDef{{Name: engine.ClassPaid}, {Name: engine.ClassFree}}},
+		Snapshots: []engine.Snapshot{
+			{Tick: 0, Tokens: []engine.TokenState{queued("T0000", engine.ClassFree)}, Stages: stage(0)},
+			{Tick: 1, Tokens: []engine.TokenState{queued("T0000", engine.ClassFree), queued("T0001", engine.ClassFree)}, Stages: stage(2)},
+			{Tick: 2, Tokens: []engine.TokenState{queued("T0002", engine.ClassPaid), {ID: "T0000", Class: engine.ClassFree, State: "IN_SERVICE"}}, Stages: stage(4)},
+		},
+	}
+
+	tests := []struct {
+		bucket  int
+		ticks   []int
+		paid    []float64
+		free    []float64
+		service []float64
+	}{
+		{1, []int{0, 1, 2}, []float64{0, 0, 1}, []float64{1, 2, 0}, []float64{0, 0.5, 1}},
+		{2, []int{0, 2}, []float64{0, 1}, []float64{1.5, 0}, []float64{0.25, 1}},
+	}
+	for _, tt := range tests {
+		heatmaps, err := BuildHeatmaps(artifact, tt.bucket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(heatmaps.Ticks, tt.ticks) {
+			t.Errorf("BuildHeatmaps(%d) ticks = %v, want %v", tt.bucket, heatmaps.Ticks, tt.ticks)
+		}
+		q := heatmaps.QueueLength
+		if !slices.Equal(q.Rows, []string{engine.ClassPaid, engine.ClassFree}) || !slices.Equal(q.Values[0], tt.paid) || !slices.Equal(q.Values[1], tt.free) {
+			t.Errorf("BuildHeatmaps(%d) queue length = %v %v, want [PAID FREE] %v %v", tt.bucket, q.Rows, q.Values, tt.paid, tt.free)
+		}
+		u := heatmaps.Utilization
+		if !slices.Equal(u.Rows, []string{"service"}) || !slices.Equal(u.Values[0], tt.service) || u.Max != 1 {
+			t.Errorf("BuildHeatmaps(%d) utilization = %v %v max %v, want [service] %v max 1", tt.bucket, u.Rows, u.Values, u.Max, tt.service)
+		}
+	}
+
+	if _, err := BuildHeatmaps(artifact, 0); err == nil {
+		t.Error("BuildHeatmaps(0) = nil error, want an error")
+	}
+}
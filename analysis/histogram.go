@@ -0,0 +1,75 @@
+package analysis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Histogram struct {
+	Bounds []int `json:"bounds"`
+	Counts []int `json:"counts"`
+}
+
+type HistogramBounds struct {
+	QueueLength []int
+	WaitMs      []int
+}
+
+func DefaultHistogramBounds() HistogramBounds {
+	return HistogramBounds{
+		QueueLength: []int{0, 1, 2, 5, 10, 20, 50, 100},
+		WaitMs:      []int{0, 250, 500, 1000, 2000, 5000, 10000, 30000},
+	}
+}
+
+func NewHistogram(bounds []int, values []int) Histogram {
+	h := Histogram{Bounds: append([]int(nil), bounds...), Counts: make([]int, len(bounds)+1)}
+	for _, v := range values {
+		h.Counts[h.bucket(v)]++
+	}
+	return h
+}
+
+func (h Histogram) bucket(v int) int {
+	for i, bound := range h.Bounds {
+		if v <= bound {
+			return i
+		}
+	}
+	return len(h.Bounds)
+}
+
+func (h Histogram) Total() int {
+	total := 0
+	for _, count := range h.Counts {
+		total += count
+	}
+	return total
+}
+
+func (h Histogram) Labels() []string {
+	labels := make([]string, 0, len(h.Counts))
+	for _, bound := range h.Bounds {
+		labels = append(labels, fmt.Sprintf("<=%d", bound))
+	}
+	if len(h.Bounds) > 0 {
+		return append(labels, fmt.Sprintf(">%d", h.Bounds[len(h.Bounds)-1]))
+	}
+	return append(labels, "all")
+}
+
+func ParseBounds(list string) ([]int, error) {
+	var bounds []int
+	for _, field := range strings.Split(list, ",") {
+		bound, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || bound < 0 {
+			return nil, fmt.Errorf("bucket bound %q is not a non-negative integer", field)
+		}
+		if n := len(bounds); n > 0 && bound <= bounds[n-1] {
+			return nil, fmt.Errorf("bucket bounds %q must be strictly increasing", list)
+		}
+		bounds = append(bounds, bound)
+	}
+	return bounds, nil
+}
@@ -0,0 +1,63 @@
+package analysis
+
+import (
+	"slices"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestNewHistogram(t *testing.T) {
+	tests := []struct {
+		bounds []int
+		values []int
+		want   []int
+	}{
+		{[]int{0, 2, 5}, []int{0, 0, 1, 2, 3, 5, 6, 100}, []int{2, 2, 2, 2}},
+		{[]int{10}, nil, []int{0, 0}},
+		{nil, []int{1, 2}, []int{2}},
+	}
+	for _, tt := range tests {
+		h := NewHistogram(tt.bounds, tt.values)
+		if !slices.Equal(h.Counts, tt.want) || h.Total() != len(tt.values) {
+			t.Errorf("NewHistogram(%v, %v) = %v, want %v", tt.bounds, tt.values, h.Counts, tt.want)
+		}
+	}
+}
+
+func TestSummarizeHistograms(t *testing.T) {
+	artifact, err := engine.Run(engine.NewConfig(engine.WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := SummarizeWith(artifact, SummaryOptions{Histograms: HistogramBounds{QueueLength: []int{0, 10}, WaitMs: []int{0, 1000}}})
+	if got := summary.QueueHistogram.Total(); got != len(artifact.Snapshots) {
+		t.Errorf("queue histogram total = %d, want %d snapshots", got, len(artifact.Snapshots))
+	}
+	if over := summary.QueueHistogram.Counts[2] > 0; over != (summary.MaxQueueLength > 10) {
+		t.Errorf("queue histogram = %v, inconsistent with max queue %d", summary.QueueHistogram.Counts, summary.MaxQueueLength)
+	}
+	for _, c := range summary.Classes {
+		starts := 0
+		for _, lifecycle := range engine.Lifecycles(artifact.Events) {
+			if lifecycle.Class == c.Class && lifecycle.Scheduled() {
+				starts++
+			}
+		}
+		if got := c.WaitHistogram.Total(); got != starts {
+			t.Errorf("%s wait histogram total = %d, want %d starts", c.Class, got, starts)
+		}
+	}
+}
+
+func TestParseBounds(t *testing.T) {
+	bounds, err := ParseBounds("0, 5,10")
+	if err != nil || !slices.Equal(bounds, []int{0, 5, 10}) {
+		t.Errorf("ParseBounds() = %v, %v, want [0 5 10]", bounds, err)
+	}
+	for _, bad := range []string{"", "1,x", "-1", "5,5", "10,2"} {
+		if _, err := ParseBounds(bad); err == nil {
+			t.Errorf("ParseBounds(%q) error = nil", bad)
+		}
+	}
+}
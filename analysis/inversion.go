@@ -0,0 +1,114 @@
+package analysis
+
+import (
+	"slices"
+
+	"finit/engine"
+)
+
+const (
+	CauseAffinity = "affinity"
+	CausePolicy   = "policy"
+)
+
+type Inversions struct {
+	Count      int         `json:"count"`
+	TotalTicks int         `json:"total_ticks"`
+	TotalMs    int         `json:"total_ms"`
+	Episodes   []Inversion `json:"episodes,omitempty"`
+}
+
+type Inversion struct {
+	TokenID   string   `json:"token_id"`
+	Class     string   `json:"class"`
+	FromTick  int      `json:"from_tick"`
+	ToTick    int      `json:"to_tick"`
+	Ticks     int      `json:"ticks"`
+	Ms        int      `json:"ms"`
+	Cause     string   `json:"cause"`
+	Overtaken []string `json:"overtaken"`
+}
+
+func DetectInversions(artifact engine.Artifact) Inversions {
+	m := artifact.Metadata
+	priority := classPriorities(m)
+	type waiting struct {
+		class    string
+		affinity bool
+		episode  *Inversion
+	}
+	queued := make(map[string]*waiting)
+	var order []string
+	var episodes []*Inversion
+	settle := func(id string, tick int) {
+		w, ok := queued[id]
+		if !ok {
+			return
+		}
+		if w.episode != nil {
+			w.episode.ToTick = tick
+		}
+		delete(queued, id)
+		order = slices.DeleteFunc(order, func(queuedID string) bool { return queuedID == id })
+	}
+
+	for _, event := range artifact.Events {
+		switch event.Type {
+		case engine.EventQueue, engine.EventRework:
+			if _, ok := queued[event.TokenID]; !ok {
+				queued[event.TokenID] = &waiting{class: event.Class}
+				order = append(order, event.TokenID)
+			}
+		case engine.EventAffinityWait:
+			if w, ok := queued[event.TokenID]; ok {
+				w.affinity = true
+			}
+		case engine.EventSchedule:
+			settle(event.TokenID, event.Tick)
+			p, ok := priority[event.Class]
+			if !ok {
+				break
+			}
+			for _, id := range order {
+				w := queued[id]
+				if q, ok := priority[w.class]; !ok || q >= p {
+					continue
+				}
+				if w.episode == nil {
+					w.episode = &Inversion{TokenID: id, Class: w.class, FromTick: event.Tick, ToTick: m.TickCount, Cause: CausePolicy}
+					episodes = append(episodes, w.episode)
+				}
+				if w.affinity {
+					w.episode.Cause = CauseAffinity
+				}
+				w.episode.Overtaken = append(w.episode.Overtaken, event.TokenID)
+			}
+		case engine.EventReject, engine.EventDuplicateDrop:
+			settle(event.TokenID, event.Tick)
+		}
+	}
+
+	var inversions Inversions
+	for _, episode := range episodes {
+		episode.Ticks = episode.ToTick - episode.FromTick
+		episode.Ms = episode.Ticks * m.TickDurationMs
+		inversions.Count++
+		inversions.TotalTicks += episode.Ticks
+		inversions.Episodes = append(inversions.Episodes, *episode)
+	}
+	inversions.TotalMs = inversions.TotalTicks * m.TickDurationMs
+	return inversions
+}
+
+func classPriorities(m engine.Metadata) map[string]int {
+	priority := make(map[string]int)
+	for _, class := range m.Classes {
+		priority[class.Name] = class.Priority
+	}
+	if len(priority) == 0 {
+		for i, class := range Classes {
+			priority[class] = i
+		}
+	}
+	return priority
+}
@@ -0,0 +1,71 @@
+package analysis
+
+import (
+	"slices"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestDetectInversions(t *testing.T) {
+	artifact := engine.Artifact{
+		Metadata: engine.Metadata{TickCount: 8, TickDurationMs: 250},
+		Events: []engine.Event{
+			{Tick: 0, Type: engine.EventQueue, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 0, Type: engine.EventQueue, TokenID: "T0001", Class: engine.ClassAnon},
+			{Tick: 1, Type: engine.EventAffinityWait, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 1, Type: engine.EventSchedule, TokenID: "T0001", Class: engine.ClassAnon},
+			{Tick: 1, Type: engine.EventQueue, TokenID: "T0002", Class: engine.ClassFree},
+			{Tick: 2, Type: engine.EventQueue, TokenID: "T0003", Class: engine.ClassAnon},
+			{Tick: 3, Type: engine.EventSchedule, TokenID: "T0003", Class: engine.ClassAnon},
+			{Tick: 4, Type: engine.EventSchedule, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 5, Type: engine.EventReject, TokenID: "T0002", Class: engine.ClassFree},
+		},
+	}
+
+	inversions := DetectInversions(artifact)
+	if inversions.Count != 2 || inversions.TotalTicks != 5 || inversions.TotalMs != 1250 {
+		t.Fatalf("DetectInversions() = %d episodes, %d ticks, %dms, want 2, 5, 1250", inversions.Count, inversions.TotalTicks, inversions.TotalMs)
+	}
+	paid, free := inversions.Episodes[0], inversions.Episodes[1]
+	if paid.TokenID != "T0000" || paid.FromTick != 1 || paid.ToTick != 4 || paid.Cause != CauseAffinity {
+		t.Errorf("first episode = %+v, want T0000 ticks 1-4 by affinity", paid)
+	}
+	if !slices.Equal(paid.Overtaken, []string{"T0001", "T0003"}) {
+		t.Errorf("T0000 overtaken by %v, want [T0001 T0003]", paid.Overtaken)
+	}
+	if free.TokenID != "T0002" || free.FromTick != 3 || free.ToTick != 5 || free.Cause != CausePolicy {
+		t.Errorf("second episode = %+v, want T0002 ticks 3-5 by policy", free)
+	}
+
+	none := DetectInversions(engine.Artifact{Events: artifact.Events[:2]})
+	if none.Count != 0 || none.Episodes != nil {
+		t.Errorf("DetectInversions() without schedules = %+v, want none", none)
+	}
+}
+
+func TestDetectInversionsMatchesLiveEvents(t *testing.T) {
+	scenario := engine.CanonicalScenario()
+	scenario.Affinity = &engine.Affinity{Sessions: 100}
+	scenario.InversionEvents = true
+	artifact, err := engine.Run(engine.NewConfig(engine.WithScenarioSpec(scenario), engine.WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := make(map[string]string)
+	for _, event := range artifact.Events {
+		if event.Type == engine.EventPriorityInversion {
+			live[event.TokenID] = event.ReasonCode
+		}
+	}
+	inversions := DetectInversions(artifact)
+	if inversions.Count == 0 || inversions.Count != len(live) {
+		t.Fatalf("DetectInversions() = %d episodes, want %d live events", inversions.Count, len(live))
+	}
+	for _, inversion := range inversions.Episodes {
+		reason, ok := live[inversion.TokenID]
+		if !ok || (reason == engine.ReasonAffinityInversion) != (inversion.Cause == CauseAffinity) {
+			t.Errorf("%s inverted by %s, live reason %q", inversion.TokenID, inversion.Cause, reason)
+		}
+	}
+}
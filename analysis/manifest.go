@@ -0,0 +1,206 @@
+package analysis
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"finit/engine"
+)
+
+var manifestName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+type Manifest struct {
+	Name     string            `json:"name" yaml:"name"`
+	Scenario string            `json:"scenario,omitempty" yaml:"scenario,omitempty"`
+	Seeds    string            `json:"seeds,omitempty" yaml:"seeds,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Runs     []ManifestRun     `json:"runs" yaml:"runs"`
+
+	dir string
+}
+
+type ManifestRun struct {
+	Name      string            `json:"name" yaml:"name"`
+	Scenario  string            `json:"scenario,omitempty" yaml:"scenario,omitempty"`
+	Policy    string            `json:"policy,omitempty" yaml:"policy,omitempty"`
+	Overrides map[string]int    `json:"overrides,omitempty" yaml:"overrides,omitempty"`
+	Seeds     string            `json:"seeds,omitempty" yaml:"seeds,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+type ExperimentIndex struct {
+	Name          string       `json:"name"`
+	EngineVersion string       `json:"engine_version"`
+	Runs          []IndexedRun `json:"runs"`
+}
+
+type IndexedRun struct {
+	Name      string            `json:"name"`
+	Scenario  string            `json:"scenario"`
+	Policy    string            `json:"policy,omitempty"`
+	Overrides []Parameter       `json:"overrides,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Artifacts []IndexedArtifact `json:"artifacts"`
+	Intervals []Interval        `json:"intervals,omitempty"`
+}
+
+type IndexedArtifact struct {
+	Seed     int64   `json:"seed"`
+	Path     string  `json:"path"`
+	ReplayID string  `json:"replay_id"`
+	Summary  Summary `json:"summary"`
+}
+
+type plannedRun struct {
+	IndexedRun
+	scenario engine.Scenario
+	seeds    []int64
+}
+
+func ReadManifest(path string) (Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+	var manifest Manifest
+	switch ext := filepath.Ext(path); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&manifest)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&manifest)
+	default:
+		err = fmt.Errorf("unsupported manifest format %q (want .yaml, .yml, or .json)", ext)
+	}
+	if err != nil {
+		return Manifest{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if manifest.Name == "" {
+		manifest.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	manifest.dir = filepath.Dir(path)
+	return manifest, nil
+}
+
+func ArtifactPath(run string, seed int64) string {
+	return fmt.Sprintf("runs/%s/seed-%d.json", run, seed)
+}
+
+func RunManifest(ctx context.Context, manifest Manifest, cfg engine.Config, workers int, write func(path string, artifact engine.Artifact) error) (ExperimentIndex, error) {
+	plan, err := manifest.plan()
+	if err != nil {
+		return ExperimentIndex{}, err
+	}
+	index := ExperimentIndex{Name: manifest.Name}
+	for _, run := range plan {
+		cfg := cfg
+		cfg.ScenarioID, cfg.Scenario, cfg.Labels = run.scenario.ID, &run.scenario, run.Labels
+		run.Artifacts = make([]IndexedArtifact, len(run.seeds))
+		experiment := Experiment{Runs: make([]ExperimentRun, len(run.seeds))}
+		err := engine.RunEach(ctx, cfg, run.seeds, workers, func(i int, artifact engine.Artifact) error {
+			path := ArtifactPath(run.Name, run.seeds[i])
+			if err := write(path, artifact); err != nil {
+				return err
+			}
+			summary := Summarize(artifact)
+			run.Artifacts[i] = IndexedArtifact{Seed: run.seeds[i], Path: path, ReplayID: artifact.Metadata.ReplayID, Summary: summary}
+			experiment.Runs[i] = ExperimentRun{Metadata: artifact.Metadata, Summary: summary}
+			return nil
+		})
+		if err != nil {
+			return ExperimentIndex{}, fmt.Errorf("run %s: %w", run.Name, err)
+		}
+		index.EngineVersion = experiment.Runs[0].Metadata.EngineVersion
+		run.Intervals = experiment.ConfidenceIntervals(0.95)
+		index.Runs = append(index.Runs, run.IndexedRun)
+	}
+	return index, nil
+}
+
+func (m Manifest) plan() ([]plannedRun, error) {
+	if !manifestName.MatchString(m.Name) {
+		return nil, fmt.Errorf("experiment name %q must be letters, digits, '.', '_' or '-'", m.Name)
+	}
+	if len(m.Runs) == 0 {
+		return nil, errors.New("manifest has no runs")
+	}
+	seen := make(map[string]bool)
+	plan := make([]plannedRun, 0, len(m.Runs))
+	for _, run := range m.Runs {
+		if !manifestName.MatchString(run.Name) {
+			return nil, fmt.Errorf("run name %q must be letters, digits, '.', '_' or '-'", run.Name)
+		}
+		if seen[run.Name] {
+			return nil, fmt.Errorf("run %s appears more than once", run.Name)
+		}
+		seen[run.Name] = true
+		planned, err := m.planRun(run)
+		if err != nil {
+			return nil, fmt.Errorf("run %s: %w", run.Name, err)
+		}
+		plan = append(plan, planned)
+	}
+	return plan, nil
+}
+
+func (m Manifest) planRun(run ManifestRun) (plannedRun, error) {
+	source := cmp.Or(run.Scenario, m.Scenario, engine.ScenarioID)
+	var scenario engine.Scenario
+	var err error
+	switch filepath.Ext(source) {
+	case ".yaml", ".yml", ".json":
+		path := source
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(m.dir, path)
+		}
+		scenario, err = engine.LoadScenario(path)
+	default:
+		scenario, err = engine.LookupScenario(source)
+	}
+	if err != nil {
+		return plannedRun{}, err
+	}
+
+	var overrides []Parameter
+	for _, name := range slices.Sorted(maps.Keys(run.Overrides)) {
+		canonical, err := parameterName(name)
+		if err != nil {
+			return plannedRun{}, err
+		}
+		overrides = append(overrides, Parameter{Name: canonical, Values: []int{run.Overrides[name]}})
+	}
+	if scenario, err = applyChanges(scenario, run.Policy, overrides); err != nil {
+		return plannedRun{}, err
+	}
+
+	seeds, err := engine.ParseSeeds(cmp.Or(run.Seeds, m.Seeds, "1"))
+	if err != nil {
+		return plannedRun{}, err
+	}
+
+	labels := make(map[string]string)
+	maps.Copy(labels, m.Labels)
+	maps.Copy(labels, run.Labels)
+	labels["experiment"], labels["run"] = m.Name, run.Name
+
+	return plannedRun{
+		IndexedRun: IndexedRun{Name: run.Name, Scenario: source, Policy: run.Policy, Overrides: overrides, Labels: labels},
+		scenario:   scenario,
+		seeds:      seeds,
+	}, nil
+}
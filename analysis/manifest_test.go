@@ -0,0 +1,93 @@
+package analysis
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestRunManifest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "priority.yaml")
+	scenario, err := filepath.Abs("../scenarios/canonical_v1.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := `
+seeds: 1-2
+labels: {owner: capacity}
+runs:
+  - name: baseline
+  - name: one-server
+    scenario: ` + scenario + `
+    overrides: {capacity: 1}
+    seeds: 5
+    labels: {owner: sre}
+`
+	if err := os.WriteFile(path, []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "priority" {
+		t.Errorf("ReadManifest() name = %q, want priority", m.Name)
+	}
+
+	written := make(map[string]engine.Artifact)
+	index, err := RunManifest(context.Background(), m, engine.Config{}, 1, func(path string, artifact engine.Artifact) error {
+		written[path] = artifact
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 3 || len(index.Runs) != 2 {
+		t.Fatalf("RunManifest() wrote %d artifacts in %d runs, want 3 in 2", len(written), len(index.Runs))
+	}
+
+	baseline, oneServer := index.Runs[0], index.Runs[1]
+	if got := baseline.Artifacts[1]; got.Seed != 2 || got.Path != "runs/baseline/seed-2.json" || written[got.Path].Metadata.ReplayID != got.ReplayID {
+		t.Errorf("RunManifest() baseline artifact = %+v", got)
+	}
+	if len(baseline.Intervals) == 0 {
+		t.Error("RunManifest() baseline has no intervals across its two seeds")
+	}
+	labels := written["runs/one-server/seed-5.json"].Metadata.Labels
+	if labels["experiment"] != "priority" || labels["run"] != "one-server" || labels["owner"] != "sre" {
+		t.Errorf("RunManifest() one-server labels = %v", labels)
+	}
+	if got := oneServer.Artifacts[0].Summary.Rejected; got <= baseline.Artifacts[0].Summary.Rejected {
+		t.Errorf("RunManifest() one-server rejected %d, want more than baseline", got)
+	}
+	if len(oneServer.Overrides) != 1 || oneServer.Overrides[0].Name != "capacity" {
+		t.Errorf("RunManifest() one-server overrides = %+v", oneServer.Overrides)
+	}
+}
+
+func TestManifestPlan(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest Manifest
+		wantErr  bool
+	}{
+		{"defaults", Manifest{Name: "x", Runs: []ManifestRun{{Name: "a"}}}, false},
+		{"no runs", Manifest{Name: "x"}, true},
+		{"bad name", Manifest{Name: "x", Runs: []ManifestRun{{Name: "a/b"}}}, true},
+		{"duplicate", Manifest{Name: "x", Runs: []ManifestRun{{Name: "a"}, {Name: "a"}}}, true},
+		{"unknown policy", Manifest{Name: "x", Runs: []ManifestRun{{Name: "a", Policy: "wfq"}}}, true},
+		{"unknown override", Manifest{Name: "x", Runs: []ManifestRun{{Name: "a", Overrides: map[string]int{"servers": 2}}}}, true},
+		{"unknown class", Manifest{Name: "x", Runs: []ManifestRun{{Name: "a", Overrides: map[string]int{"priority.gold": 2}}}}, true},
+		{"bad seeds", Manifest{Name: "x", Seeds: "3-1", Runs: []ManifestRun{{Name: "a"}}}, true},
+		{"unknown scenario", Manifest{Name: "x", Runs: []ManifestRun{{Name: "a", Scenario: "nope"}}}, true},
+	}
+	for _, tt := range tests {
+		if _, err := tt.manifest.plan(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: plan() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package analysis
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"finit/engine"
+)
+
+const (
+	SearchGrid = "grid"
+	SearchHill = "hill"
+)
+
+type Objective struct {
+	Metric Metric  `json:"metric"`
+	Op     string  `json:"op"`
+	Target float64 `json:"target"`
+}
+
+type Optimization struct {
+	Search     string             `json:"search"`
+	Parameters []Parameter        `json:"parameters"`
+	Objectives []Objective        `json:"objectives"`
+	Cost       map[string]float64 `json:"cost"`
+	Seeds      []int64            `json:"seeds"`
+	Evaluated  int                `json:"evaluated"`
+	Best       *Candidate         `json:"best,omitempty"`
+	Frontier   []Candidate        `json:"frontier"`
+}
+
+type Candidate struct {
+	Values     map[string]int `json:"values"`
+	Cost       float64        `json:"cost"`
+	Metrics    []float64      `json:"metrics"`
+	Attainment float64        `json:"attainment"`
+	Feasible   bool           `json:"feasible"`
+	violation  float64
+}
+
+func ParseObjective(spec string) (Objective, error) {
+	spec = strings.TrimSpace(spec)
+	i := strings.IndexAny(spec, "<>")
+	if i <= 0 {
+		return Objective{}, fmt.Errorf("objective %q is not metric<target or metric>target", spec)
+	}
+	o := Objective{Op: spec[i : i+1]}
+	raw := spec[i+1:]
+	if strings.HasPrefix(raw, "=") {
+		o.Op, raw = o.Op+"=", raw[1:]
+	}
+	metric, err := ParseMetric(strings.TrimSpace(spec[:i]))
+	if err != nil {
+		return Objective{}, err
+	}
+	o.Metric = metric
+	if o.Target, err = strconv.ParseFloat(strings.TrimSpace(raw), 64); err != nil {
+		return Objective{}, fmt.Errorf("objective %q: target %q is not a number", spec, raw)
+	}
+	return o, nil
+}
+
+func (o Objective) Met(value float64) bool {
+	switch o.Op {
+	case "<":
+		return value < o.Target
+	case "<=":
+		return value <= o.Target
+	case ">":
+		return value > o.Target
+	default:
+		return value >= o.Target
+	}
+}
+
+func (o Objective) String() string {
+	return o.Metric.Name + o.Op + strconv.FormatFloat(o.Target, 'g', -1, 64)
+}
+
+func (o Objective) shortfall(value float64) float64 {
+	if o.Met(value) {
+		return 0
+	}
+	gap := math.Abs(value - o.Target)
+	if o.Target != 0 {
+		gap /= math.Abs(o.Target)
+	}
+	return gap
+}
+
+func ParseCost(list string) (map[string]float64, error) {
+	cost := make(map[string]float64)
+	for _, pair := range strings.Split(list, ",") {
+		name, raw, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("cost %q is not parameter=number", pair)
+		}
+		weight, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cost %q is not a number", pair)
+		}
+		if name, err = parameterName(name); err != nil {
+			return nil, err
+		}
+		cost[name] = weight
+	}
+	return cost, nil
+}
+
+type optimizer struct {
+	ctx        context.Context
+	cfg        engine.Config
+	base       engine.Scenario
+	workers    int
+	result     *Optimization
+	candidates map[string]*Candidate
+	evaluated  []*Candidate
+}
+
+func Optimize(ctx context.Context, cfg engine.Config, search string, parameters []Parameter, objectives []Objective, cost map[string]float64, seeds []int64, workers int) (Optimization, error) {
+	if search != SearchGrid && search != SearchHill {
+		return Optimization{}, fmt.Errorf("unknown search %q (want %s or %s)", search, SearchGrid, SearchHill)
+	}
+	if len(parameters) == 0 || len(objectives) == 0 {
+		return Optimization{}, errors.New("optimize needs at least one parameter and one objective")
+	}
+	if len(cost) == 0 {
+		cost = make(map[string]float64, len(parameters))
+		for _, p := range parameters {
+			cost[p.Name] = 1
+		}
+	}
+	for name := range cost {
+		if !slices.ContainsFunc(parameters, func(p Parameter) bool { return p.Name == name }) {
+			return Optimization{}, fmt.Errorf("cost names %s, which is not a searched parameter", name)
+		}
+	}
+	base, err := baseScenario(cfg, seeds)
+	if err != nil {
+		return Optimization{}, err
+	}
+	result := Optimization{Search: search, Parameters: parameters, Objectives: objectives, Cost: cost, Seeds: seeds}
+	o := &optimizer{ctx: ctx, cfg: cfg, base: base, workers: workers, result: &result, candidates: make(map[string]*Candidate)}
+
+	if search == SearchGrid {
+		err = o.grid(make([]int, len(parameters)), 0)
+	} else {
+		err = o.hill()
+	}
+	if err != nil {
+		return Optimization{}, err
+	}
+
+	all := make([]Candidate, 0, len(o.evaluated))
+	for _, candidate := range o.evaluated {
+		all = append(all, *candidate)
+	}
+	slices.SortStableFunc(all, func(a, b Candidate) int {
+		if a.Cost != b.Cost {
+			return cmp.Compare(a.Cost, b.Cost)
+		}
+		return cmp.Compare(b.Attainment, a.Attainment)
+	})
+	for _, candidate := range all {
+		if candidate.Feasible && (result.Best == nil || better(candidate, *result.Best)) {
+			best := candidate
+			result.Best = &best
+		}
+		if len(result.Frontier) == 0 || candidate.Attainment > result.Frontier[len(result.Frontier)-1].Attainment {
+			result.Frontier = append(result.Frontier, candidate)
+		}
+	}
+	result.Evaluated = len(all)
+	return result, nil
+}
+
+func (o *optimizer) grid(indices []int, depth int) error {
+	if depth == len(indices) {
+		_, err := o.evaluate(indices)
+		return err
+	}
+	for i := range o.result.Parameters[depth].Values {
+		indices[depth] = i
+		if err := o.grid(indices, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *optimizer) hill() error {
+	current := make([]int, len(o.result.Parameters))
+	best, err := o.evaluate(current)
+	if err != nil {
+		return err
+	}
+	for {
+		var next []int
+		for i, p := range o.result.Parameters {
+			for _, step := range []int{-1, 1} {
+				neighbor := slices.Clone(current)
+				neighbor[i] += step
+				if neighbor[i] < 0 || neighbor[i] >= len(p.Values) {
+					continue
+				}
+				candidate, err := o.evaluate(neighbor)
+				if err != nil {
+					return err
+				}
+				if better(*candidate, *best) {
+					best, next = candidate, neighbor
+				}
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		current = next
+	}
+}
+
+func (o *optimizer) evaluate(indices []int) (*Candidate, error) {
+	key := fmt.Sprint(indices)
+	if candidate, ok := o.candidates[key]; ok {
+		return candidate, nil
+	}
+	candidate := &Candidate{Values: make(map[string]int, len(indices))}
+	scenario := o.base
+	for i, p := range o.result.Parameters {
+		value := p.Values[indices[i]]
+		candidate.Values[p.Name] = value
+		candidate.Cost += o.result.Cost[p.Name] * float64(value)
+		var err error
+		if scenario, err = p.Apply(scenario, value); err != nil {
+			return nil, err
+		}
+	}
+	metrics := make([]Metric, len(o.result.Objectives))
+	for i, objective := range o.result.Objectives {
+		metrics[i] = objective.Metric
+	}
+	runs, err := measureSeeds(o.ctx, o.cfg, scenario, metrics, o.result.Seeds, o.workers)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", candidate.Values, err)
+	}
+
+	met := 0
+	for seed := range o.result.Seeds {
+		all := true
+		for i, objective := range o.result.Objectives {
+			all = all && objective.Met(runs[i][seed])
+		}
+		if all {
+			met++
+		}
+	}
+	candidate.Attainment = float64(met) / float64(len(o.result.Seeds))
+	candidate.Feasible = true
+	for i, objective := range o.result.Objectives {
+		mean := 0.0
+		for _, v := range runs[i] {
+			mean += v
+		}
+		mean /= float64(len(runs[i]))
+		candidate.Metrics = append(candidate.Metrics, mean)
+		candidate.Feasible = candidate.Feasible && objective.Met(mean)
+		candidate.violation += objective.shortfall(mean)
+	}
+	o.candidates[key] = candidate
+	o.evaluated = append(o.evaluated, candidate)
+	return candidate, nil
+}
+
+func better(a, b Candidate) bool {
+	if a.Feasible != b.Feasible {
+		return a.Feasible
+	}
+	if !a.Feasible {
+		return a.violation < b.violation
+	}
+	if a.Cost != b.Cost {
+		return a.Cost < b.Cost
+	}
+	return a.Attainment > b.Attainment
+}
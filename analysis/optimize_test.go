@@ -0,0 +1,83 @@
+package analysis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestParseObjective(t *testing.T) {
+	tests := []struct {
+		spec    string
+		op      string
+		target  float64
+		wantErr bool
+	}{
+		{spec: "p95_latency_paid<2000", op: "<", target: 2000},
+		{spec: " rejection_rate <= 0.01", op: "<=", target: 0.01},
+		{spec: "throughput>=3.5", op: ">=", target: 3.5},
+		{spec: "p95_latency_paid", wantErr: true},
+		{spec: "<5", wantErr: true},
+		{spec: "p95_latency_paid<fast", wantErr: true},
+		{spec: "p95_speed<5", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseObjective(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseObjective(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (got.Op != tt.op || got.Target != tt.target) {
+			t.Errorf("ParseObjective(%q) = %s %v, want %s %v", tt.spec, got.Op, got.Target, tt.op, tt.target)
+		}
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	capacity, err := ParseParameter("capacity=1..5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	limit, err := ParseParameter("queue_limit.anon=2..10:4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	objective, err := ParseObjective("rejection_rate<0.05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cost := map[string]float64{"capacity": 10, "queue_limit.ANON": 1}
+	parameters := []Parameter{capacity, limit}
+	seeds := []int64{1, 2}
+
+	grid, err := Optimize(context.Background(), engine.Config{}, SearchGrid, parameters, []Objective{objective}, cost, seeds, 2)
+	if err != nil {
+		t.Fatalf("Optimize(grid) error = %v", err)
+	}
+	if grid.Evaluated != 15 || grid.Best == nil || !grid.Best.Feasible {
+		t.Fatalf("Optimize(grid) = %d evaluated, best %+v", grid.Evaluated, grid.Best)
+	}
+	for i := 1; i < len(grid.Frontier); i++ {
+		prev, next := grid.Frontier[i-1], grid.Frontier[i]
+		if next.Cost < prev.Cost || next.Attainment <= prev.Attainment {
+			t.Errorf("frontier step %d: cost %v -> %v, attainment %v -> %v", i, prev.Cost, next.Cost, prev.Attainment, next.Attainment)
+		}
+	}
+
+	hill, err := Optimize(context.Background(), engine.Config{}, SearchHill, parameters, []Objective{objective}, cost, seeds, 2)
+	if err != nil {
+		t.Fatalf("Optimize(hill) error = %v", err)
+	}
+	if hill.Evaluated >= grid.Evaluated || hill.Best == nil || !reflect.DeepEqual(hill.Best.Values, grid.Best.Values) {
+		t.Errorf("Optimize(hill) = %d evaluated, best %+v, want fewer than %d and %v", hill.Evaluated, hill.Best, grid.Evaluated, grid.Best.Values)
+	}
+
+	if _, err := Optimize(context.Background(), engine.Config{}, "anneal", parameters, []Objective{objective}, nil, seeds, 1); err == nil {
+		t.Error("Optimize() with an unknown search error = nil")
+	}
+	if _, err := Optimize(context.Background(), engine.Config{}, SearchGrid, parameters, []Objective{objective}, map[string]float64{"service_time": 1}, seeds, 1); err == nil {
+		t.Error("Optimize() costing an unsearched parameter error = nil")
+	}
+}
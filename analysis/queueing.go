@@ -0,0 +1,149 @@
+package analysis
+
+import (
+	"errors"
+	"math"
+
+	"finit/engine"
+)
+
+type MMcOptions struct {
+	WindowTicks int
+	Tolerance   float64
+}
+
+type MMcComparison struct {
+	Tolerance float64     `json:"tolerance"`
+	Overall   MMcWindow   `json:"overall"`
+	Windows   []MMcWindow `json:"windows,omitempty"`
+}
+
+type MMcWindow struct {
+	FromTick             int     `json:"from_tick"`
+	ToTick               int     `json:"to_tick"`
+	Servers              int     `json:"servers"`
+	Arrivals             int     `json:"arrivals"`
+	ArrivalRate          float64 `json:"arrival_rate_per_sec"`
+	ServiceRate          float64 `json:"service_rate_per_sec"`
+	Utilization          float64 `json:"utilization"`
+	Stable               bool    `json:"stable"`
+	ProbWait             float64 `json:"prob_wait,omitempty"`
+	PredictedWaitMs      float64 `json:"predicted_wait_ms,omitempty"`
+	MeasuredWaitMs       float64 `json:"measured_wait_ms"`
+	PredictedQueueLength float64 `json:"predicted_queue_length,omitempty"`
+	MeasuredQueueLength  float64 `json:"measured_queue_length"`
+	Deviation            float64 `json:"deviation"`
+	Flagged              bool    `json:"flagged"`
+}
+
+func CompareMMc(artifact engine.Artifact, opts MMcOptions) (MMcComparison, error) {
+	m := artifact.Metadata
+	if m.TickCount <= 0 || m.TickDurationMs <= 0 {
+		return MMcComparison{}, errors.New("mmc: artifact has no tick duration")
+	}
+	servers := 0
+	for _, snapshot := range artifact.Snapshots {
+		for _, stage := range snapshot.Stages {
+			if stage.ID == engine.StageService {
+				servers = max(servers, stage.CapacityTotal)
+			}
+		}
+	}
+	if servers == 0 {
+		return MMcComparison{}, errors.New("mmc: artifact records no service capacity")
+	}
+
+	var admitted []engine.Lifecycle
+	serviceTicks := 0
+	served := 0
+	for _, lifecycle := range engine.Lifecycles(artifact.Events) {
+		if lifecycle.Rejected() {
+			continue
+		}
+		admitted = append(admitted, lifecycle)
+		if lifecycle.Scheduled() && lifecycle.Completed() {
+			serviceTicks += lifecycle.CompleteTick - lifecycle.ScheduleTick
+			served++
+		}
+	}
+	if served == 0 || serviceTicks == 0 {
+		return MMcComparison{}, errors.New("mmc: no completed service to fit a service rate")
+	}
+	serviceRate := float64(served) / (float64(serviceTicks*m.TickDurationMs) / 1000)
+
+	comparison := MMcComparison{Tolerance: opts.Tolerance}
+	window := func(from, to int) MMcWindow {
+		return fitWindow(artifact, admitted, servers, serviceRate, from, to, opts.Tolerance)
+	}
+	comparison.Overall = window(0, m.TickCount)
+	if opts.WindowTicks > 0 {
+		for from := 0; from < m.TickCount; from += opts.WindowTicks {
+			comparison.Windows = append(comparison.Windows, window(from, min(from+opts.WindowTicks, m.TickCount)))
+		}
+	}
+	return comparison, nil
+}
+
+func fitWindow(artifact engine.Artifact, admitted []engine.Lifecycle, servers int, serviceRate float64, from, to int, tolerance float64) MMcWindow {
+	tickMs := artifact.Metadata.TickDurationMs
+	w := MMcWindow{FromTick: from, ToTick: to, Servers: servers, ServiceRate: serviceRate}
+
+	var waits []int
+	for _, lifecycle := range admitted {
+		if lifecycle.ArrivalTick < from || lifecycle.ArrivalTick >= to {
+			continue
+		}
+		w.Arrivals++
+		if lifecycle.Scheduled() {
+			waits = append(waits, lifecycle.WaitTicks()*tickMs)
+		}
+	}
+	w.MeasuredWaitMs = Mean(waits)
+	seconds := float64((to-from)*tickMs) / 1000
+	w.ArrivalRate = float64(w.Arrivals) / seconds
+
+	var queue []int
+	for _, snapshot := range artifact.Snapshots {
+		if snapshot.Tick < from || snapshot.Tick >= to {
+			continue
+		}
+		for _, stage := range snapshot.Stages {
+			if stage.ID == engine.StageQueue {
+				queue = append(queue, stage.QueueLength)
+			}
+		}
+	}
+	w.MeasuredQueueLength = Mean(queue)
+
+	w.Utilization = w.ArrivalRate / (float64(servers) * serviceRate)
+	w.Stable = w.Utilization < 1
+	if !w.Stable {
+		w.Flagged = true
+		return w
+	}
+	w.ProbWait = ErlangC(servers, w.ArrivalRate/serviceRate)
+	predicted := w.ProbWait / (float64(servers)*serviceRate - w.ArrivalRate)
+	w.PredictedWaitMs = predicted * 1000
+	w.PredictedQueueLength = w.ArrivalRate * predicted
+	w.Deviation = (w.MeasuredWaitMs - w.PredictedWaitMs) / math.Max(w.PredictedWaitMs, float64(tickMs))
+	w.Flagged = math.Abs(w.Deviation) > tolerance
+	return w
+}
+
+func ErlangC(servers int, load float64) float64 {
+	if servers <= 0 || load <= 0 {
+		return 0
+	}
+	rho := load / float64(servers)
+	if rho >= 1 {
+		return 1
+	}
+	term, sum := 1.0, 1.0
+	for k := 1; k < servers; k++ {
+		term *= load / float64(k)
+		sum += term
+	}
+	term *= load / float64(servers)
+	tail := term / (1 - rho)
+	return tail / (sum + tail)
+}
@@ -0,0 +1,64 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestErlangC(t *testing.T) {
+	tests := []struct {
+		servers int
+		load    float64
+		want    float64
+	}{
+		{1, 0.5, 0.5},
+		{2, 1, 1.0 / 3},
+		{3, 1, 1.0 / 11},
+		{3, 3, 1},
+		{3, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := ErlangC(tt.servers, tt.load); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("ErlangC(%d, %v) = %v, want %v", tt.servers, tt.load, got, tt.want)
+		}
+	}
+}
+
+func TestCompareMMc(t *testing.T) {
+	artifact := run(t, 1)
+	comparison, err := CompareMMc(artifact, MMcOptions{WindowTicks: 30, Tolerance: 0.5})
+	if err != nil {
+		t.Fatalf("CompareMMc() error = %v", err)
+	}
+
+	overall := comparison.Overall
+	summary := Summarize(artifact)
+	if overall.Servers != 3 || overall.ServiceRate != 4 {
+		t.Errorf("fit c = %d, mu = %v, want 3 and 4", overall.Servers, overall.ServiceRate)
+	}
+	if want := summary.Arrived - summary.Rejected; overall.Arrivals != want {
+		t.Errorf("Overall.Arrivals = %d, want %d admitted", overall.Arrivals, want)
+	}
+	if len(comparison.Windows) != engine.TickCount/30 {
+		t.Fatalf("len(Windows) = %d, want %d", len(comparison.Windows), engine.TickCount/30)
+	}
+
+	first := comparison.Windows[0]
+	if first.Utilization != 1.0/3 || math.Abs(first.PredictedWaitMs-1000.0/88) > 1e-9 || first.Flagged {
+		t.Errorf("Windows[0] = %+v, want rho 1/3, Wq 11.4ms, not flagged", first)
+	}
+	unstable := 0
+	for _, w := range comparison.Windows {
+		if !w.Stable {
+			unstable++
+			if !w.Flagged {
+				t.Errorf("unstable window %d-%d is not flagged", w.FromTick, w.ToTick)
+			}
+		}
+	}
+	if unstable == 0 {
+		t.Error("expected the peak of the canonical run to be unstable")
+	}
+}
@@ -0,0 +1,77 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"finit/engine"
+)
+
+type Rolling struct {
+	WindowTicks int       `json:"window_ticks"`
+	WarmupTicks int       `json:"warmup_ticks,omitempty"`
+	Ticks       []int     `json:"ticks"`
+	Arrivals    []float64 `json:"arrivals_per_sec"`
+	Throughput  []float64 `json:"throughput_per_sec"`
+	Utilization []float64 `json:"utilization"`
+}
+
+func RollingSeries(artifact engine.Artifact, windowTicks int) (Rolling, error) {
+	m := artifact.Metadata
+	if windowTicks <= 0 {
+		return Rolling{}, fmt.Errorf("rolling window must be at least one tick, got %d", windowTicks)
+	}
+	if m.TickCount <= 0 || m.TickDurationMs <= 0 {
+		return Rolling{}, errors.New("rolling: artifact has no tick duration")
+	}
+
+	arrivals := make([]int, m.TickCount+1)
+	completions := make([]int, m.TickCount+1)
+	for _, lifecycle := range engine.Lifecycles(artifact.Events) {
+		if lifecycle.ArrivalTick >= 0 && lifecycle.ArrivalTick < m.TickCount {
+			arrivals[lifecycle.ArrivalTick+1]++
+		}
+		if lifecycle.Completed() && lifecycle.CompleteTick < m.TickCount {
+			completions[lifecycle.CompleteTick+1]++
+		}
+	}
+	used := make([]int, m.TickCount+1)
+	total := make([]int, m.TickCount+1)
+	for _, snapshot := range artifact.Snapshots {
+		if snapshot.Tick < 0 || snapshot.Tick >= m.TickCount {
+			continue
+		}
+		for _, stage := range snapshot.Stages {
+			if stage.ID == engine.StageService {
+				u, t := stage.Load()
+				used[snapshot.Tick+1] += u
+				total[snapshot.Tick+1] += t
+			}
+		}
+	}
+	for _, counts := range [][]int{arrivals, completions, used, total} {
+		for i := 1; i < len(counts); i++ {
+			counts[i] += counts[i-1]
+		}
+	}
+
+	rolling := Rolling{WindowTicks: windowTicks, WarmupTicks: m.WarmupTicks}
+	for tick := 0; tick < m.TickCount; tick++ {
+		from, to := max(tick+1-windowTicks, 0), tick+1
+		seconds := float64((to-from)*m.TickDurationMs) / 1000
+		utilization := 0.0
+		if t := total[to] - total[from]; t > 0 {
+			utilization = float64(used[to]-used[from]) / float64(t)
+		}
+		rolling.Ticks = append(rolling.Ticks, tick)
+		rolling.Arrivals = append(rolling.Arrivals, round3(float64(arrivals[to]-arrivals[from])/seconds))
+		rolling.Throughput = append(rolling.Throughput, round3(float64(completions[to]-completions[from])/seconds))
+		rolling.Utilization = append(rolling.Utilization, round3(utilization))
+	}
+	return rolling, nil
+}
+
+func round3(v float64) float64 {
+	return math.Round(1000*v) / 1000
+}
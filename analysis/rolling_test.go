@@ -0,0 +1,64 @@
+package analysis
+
+import (
+	"slices"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestRollingSeries(t *testing.T) {
+	service := func(used int) []engine.StageState {
+		return []engine.StageState{{ID: engine.StageService, CapacityUsed: used, CapacityTotal: 2}}
+	}
+	artifact := engine.Artifact{
+		Metadata: engine.Metadata{TickCount: 4, TickDurationMs: 500},
+		Events: []engine.Event{
+			{Tick: 0, Type: engine.EventQueue, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 0, Type: engine.EventSchedule, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 1, Type: engine.EventQueue, TokenID: "T0001", Class: engine.ClassFree},
+			{Tick: 1, Type: engine.EventSchedule, TokenID: "T0001", Class: engine.ClassFree},
+			{Tick: 2, Type: engine.EventComplete, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 3, Type: engine.EventComplete, TokenID: "T0001", Class: engine.ClassFree},
+		},
+		Snapshots: []engine.Snapshot{
+			{Tick: 0, Stages: service(1)},
+			{Tick: 1, Stages: service(2)},
+			{Tick: 2, Stages: service(1)},
+			{Tick: 3, Stages: service(0)},
+		},
+	}
+
+	tests := []struct {
+		window      int
+		arrivals    []float64
+		throughput  []float64
+		utilization []float64
+	}{
+		{1, []float64{2, 2, 0, 0}, []float64{0, 0, 2, 2}, []float64{0.5, 1, 0.5, 0}},
+		{2, []float64{2, 2, 1, 0}, []float64{0, 0, 1, 2}, []float64{0.5, 0.75, 0.75, 0.25}},
+		{10, []float64{2, 2, 1.333, 1}, []float64{0, 0, 0.667, 1}, []float64{0.5, 0.75, 0.667, 0.5}},
+	}
+	for _, tt := range tests {
+		rolling, err := RollingSeries(artifact, tt.window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(rolling.Ticks, []int{0, 1, 2, 3}) {
+			t.Errorf("RollingSeries(%d) ticks = %v, want [0 1 2 3]", tt.window, rolling.Ticks)
+		}
+		if !slices.Equal(rolling.Arrivals, tt.arrivals) {
+			t.Errorf("RollingSeries(%d) arrivals = %v, want %v", tt.window, rolling.Arrivals, tt.arrivals)
+		}
+		if !slices.Equal(rolling.Throughput, tt.throughput) {
+			t.Errorf("RollingSeries(%d) throughput = %v, want %v", tt.window, rolling.Throughput, tt.throughput)
+		}
+		if !slices.Equal(rolling.Utilization, tt.utilization) {
+			t.Errorf("RollingSeries(%d) utilization = %v, want %v", tt.window, rolling.Utilization, tt.utilization)
+		}
+	}
+
+	if _, err := RollingSeries(artifact, 0); err == nil {
+		t.Error("RollingSeries(0) = nil error, want an error")
+	}
+}
@@ -0,0 +1,307 @@
+package analysis
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"finit/engine"
+)
+
+var parameters = map[string]func(*engine.Scenario) *int{
+	"capacity":             func(s *engine.Scenario) *int { return &s.Capacity },
+	"service_time":         func(s *engine.Scenario) *int { return &s.ServiceTime },
+	"reject_threshold":     func(s *engine.Scenario) *int { return &s.RejectThreshold },
+	"starvation_threshold": func(s *engine.Scenario) *int { return &s.StarvationThreshold },
+	"warmup_ticks":         func(s *engine.Scenario) *int { return &s.WarmupTicks },
+}
+
+var classParameters = []string{"queue_limit", "priority"}
+
+var metricStats = []string{"mean", "p50", "p95", "p99", "max"}
+
+type Parameter struct {
+	Name   string `json:"name"`
+	Values []int  `json:"values"`
+}
+
+type Metric struct {
+	Name  string `json:"name"`
+	Stat  string `json:"stat,omitempty"`
+	Of    string `json:"of"`
+	Class string `json:"class,omitempty"`
+}
+
+type Sensitivity struct {
+	Parameter  string             `json:"parameter"`
+	Metric     Metric             `json:"metric"`
+	Seeds      []int64            `json:"seeds"`
+	Confidence float64            `json:"confidence"`
+	Points     []SensitivityPoint `json:"points"`
+}
+
+type SensitivityPoint struct {
+	Value     int       `json:"value"`
+	Mean      float64   `json:"mean"`
+	HalfWidth float64   `json:"half_width"`
+	Min       float64   `json:"min"`
+	Max       float64   `json:"max"`
+	Runs      []float64 `json:"runs"`
+}
+
+func ParseParameter(spec string) (Parameter, error) {
+	name, raw, ok := strings.Cut(strings.TrimSpace(spec), "=")
+	if !ok {
+		return Parameter{}, fmt.Errorf("parameter %q is not name=values", spec)
+	}
+	name, err := parameterName(name)
+	if err != nil {
+		return Parameter{}, err
+	}
+	p := Parameter{Name: name}
+	if first, last, isRange := strings.Cut(raw, ".."); isRange {
+		last, rawStep, hasStep := strings.Cut(last, ":")
+		from, errFrom := strconv.Atoi(first)
+		to, errTo := strconv.Atoi(last)
+		step := 1
+		var errStep error
+		if hasStep {
+			step, errStep = strconv.Atoi(rawStep)
+		}
+		if errFrom != nil || errTo != nil || errStep != nil || to < from || step <= 0 {
+			return Parameter{}, fmt.Errorf("parameter %s: invalid range %q (want from..to or from..to:step)", name, raw)
+		}
+		for v := from; v <= to; v += step {
+			p.Values = append(p.Values, v)
+		}
+		return p, nil
+	}
+	for _, part := range strings.Split(raw, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return Parameter{}, fmt.Errorf("parameter %s: invalid value %q", name, part)
+		}
+		p.Values = append(p.Values, v)
+	}
+	return p, nil
+}
+
+func parameterName(name string) (string, error) {
+	if field, class, ok := strings.Cut(name, "."); ok && slices.Contains(classParameters, field) && class != "" {
+		return field + "." + strings.ToUpper(class), nil
+	}
+	if _, ok := parameters[name]; !ok {
+		return "", fmt.Errorf("unknown parameter %q (want one of %s)", name, strings.Join(parameterNames(), ", "))
+	}
+	return name, nil
+}
+
+func parameterNames() []string {
+	names := make([]string, 0, len(parameters))
+	for name := range parameters {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, field := range classParameters {
+		names = append(names, field+".<class>")
+	}
+	return names
+}
+
+func (p Parameter) Apply(scenario engine.Scenario, value int) (engine.Scenario, error) {
+	field, class, ok := strings.Cut(p.Name, ".")
+	if !ok {
+		*parameters[p.Name](&scenario) = value
+		return scenario, nil
+	}
+	scenario.Classes = slices.Clone(scenario.ClassDefs())
+	for i := range scenario.Classes {
+		if scenario.Classes[i].Name != class {
+			continue
+		}
+		if field == "priority" {
+			scenario.Classes[i].Priority = value
+		} else {
+			limit := value
+			scenario.Classes[i].QueueLimit = &limit
+		}
+		return scenario, nil
+	}
+	return engine.Scenario{}, fmt.Errorf("parameter %s: scenario %s has no class %s", p.Name, scenario.ID, class)
+}
+
+func ParseMetric(name string) (Metric, error) {
+	m := Metric{Name: name}
+	class := ""
+	for _, of := range []string{"rejection_rate", "throughput", "utilization", "max_queue", "net"} {
+		if name == of || strings.HasPrefix(name, of+"_") {
+			m.Of, class = of, strings.TrimPrefix(name[len(of):], "_")
+		}
+	}
+	if m.Of == "" {
+		parts := strings.SplitN(name, "_", 3)
+		if len(parts) < 2 || !slices.Contains(metricStats, parts[0]) || (parts[1] != "wait" && parts[1] != "latency") {
+			return Metric{}, fmt.Errorf("unknown metric %q (want <stat>_wait, <stat>_latency, rejection_rate, throughput, utilization, max_queue or net, optionally suffixed _<class>; stat is one of %s)", name, strings.Join(metricStats, ", "))
+		}
+		m.Stat, m.Of = parts[0], parts[1]
+		if len(parts) == 3 {
+			class = parts[2]
+		}
+	}
+	if class != "" {
+		if m.Of == "utilization" || m.Of == "max_queue" || m.Of == "net" {
+			return Metric{}, fmt.Errorf("metric %s has no per-class breakdown", m.Of)
+		}
+		m.Class = strings.ToUpper(class)
+	}
+	return m, nil
+}
+
+func (m Metric) Measure(artifact engine.Artifact) float64 {
+	switch m.Of {
+	case "utilization":
+		_, utilization := stageTotals(artifact.Snapshots)
+		return utilization
+	case "max_queue":
+		maxQueue, _ := stageTotals(artifact.Snapshots)
+		return float64(maxQueue)
+	case "net":
+		if ledger := ledgerTotals(artifact.Snapshots); ledger != nil {
+			return ledger.Net
+		}
+		return 0
+	}
+
+	tickMs := artifact.Metadata.TickDurationMs
+	warmup := artifact.Metadata.WarmupTicks
+	var values []int
+	arrived, rejected, completed := 0, 0, 0
+	for _, lifecycle := range engine.Lifecycles(artifact.Events) {
+		if lifecycle.ArrivalTick < warmup || (m.Class != "" && lifecycle.Class != m.Class) {
+			continue
+		}
+		arrived++
+		if lifecycle.Rejected() {
+			rejected++
+		}
+		if lifecycle.Completed() {
+			completed++
+		}
+		switch {
+		case m.Of == "wait" && lifecycle.Scheduled():
+			values = append(values, lifecycle.WaitTicks()*tickMs)
+		case m.Of == "latency" && lifecycle.Completed():
+			values = append(values, lifecycle.LatencyTicks()*tickMs)
+		}
+	}
+
+	switch m.Of {
+	case "rejection_rate":
+		if arrived == 0 {
+			return 0
+		}
+		return float64(rejected) / float64(arrived)
+	case "throughput":
+		seconds := float64((artifact.Metadata.TickCount-warmup)*tickMs) / 1000
+		if seconds <= 0 {
+			return 0
+		}
+		return float64(completed) / seconds
+	}
+	if m.Stat == "mean" {
+		return Mean(values)
+	}
+	sorted := sortedCopy(values)
+	switch m.Stat {
+	case "p50":
+		return float64(Percentile(sorted, 50))
+	case "p95":
+		return float64(Percentile(sorted, 95))
+	case "p99":
+		return float64(Percentile(sorted, 99))
+	}
+	if len(sorted) == 0 {
+		return 0
+	}
+	return float64(sorted[len(sorted)-1])
+}
+
+func Sweep(ctx context.Context, cfg engine.Config, parameter Parameter, metric Metric, seeds []int64, workers int, level float64) (Sensitivity, error) {
+	base, err := baseScenario(cfg, seeds)
+	if err != nil {
+		return Sensitivity{}, err
+	}
+	sensitivity := Sensitivity{Parameter: parameter.Name, Metric: metric, Seeds: seeds, Confidence: level}
+	for _, value := range parameter.Values {
+		scenario, err := parameter.Apply(base, value)
+		if err != nil {
+			return Sensitivity{}, err
+		}
+		runs, err := measureSeeds(ctx, cfg, scenario, []Metric{metric}, seeds, workers)
+		if err != nil {
+			return Sensitivity{}, fmt.Errorf("%s=%d: %w", parameter.Name, value, err)
+		}
+		point := SensitivityPoint{Value: value, Runs: runs[0]}
+		point.Min, point.Max = slices.Min(point.Runs), slices.Max(point.Runs)
+		if len(point.Runs) > 1 {
+			interval := tInterval(metric.Class, metric.Name, point.Runs, level)
+			point.Mean, point.HalfWidth = interval.Mean, interval.HalfWidth
+		} else {
+			point.Mean = point.Runs[0]
+		}
+		sensitivity.Points = append(sensitivity.Points, point)
+	}
+	return sensitivity, nil
+}
+
+func baseScenario(cfg engine.Config, seeds []int64) (engine.Scenario, error) {
+	if len(seeds) == 0 {
+		return engine.Scenario{}, errors.New("no seeds given")
+	}
+	if cfg.Scenario != nil {
+		return *cfg.Scenario, nil
+	}
+	return engine.LookupScenario(cfg.ScenarioID)
+}
+
+func measureSeeds(ctx context.Context, cfg engine.Config, scenario engine.Scenario, metrics []Metric, seeds []int64, workers int) ([][]float64, error) {
+	if err := scenario.Validate(); err != nil {
+		return nil, err
+	}
+	cfg.Scenario = &scenario
+	runs := make([][]float64, len(metrics))
+	for i := range runs {
+		runs[i] = make([]float64, len(seeds))
+	}
+	err := engine.RunEach(ctx, cfg, seeds, workers, func(seed int, artifact engine.Artifact) error {
+		for i, metric := range metrics {
+			runs[i][seed] = metric.Measure(artifact)
+		}
+		return nil
+	})
+	return runs, err
+}
+
+func (s Sensitivity) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{s.Parameter, s.Metric.Name, "half_width", "min", "max", "runs"}); err != nil {
+		return err
+	}
+	for _, point := range s.Points {
+		record := []string{strconv.Itoa(point.Value)}
+		for _, v := range []float64{point.Mean, point.HalfWidth, point.Min, point.Max} {
+			record = append(record, strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		record = append(record, strconv.Itoa(len(point.Runs)))
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
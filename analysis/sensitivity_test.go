@@ -0,0 +1,118 @@
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestParseParameter(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []int
+		wantErr bool
+	}{
+		{spec: "capacity=2..5", want: []int{2, 3, 4, 5}},
+		{spec: "capacity=2..10:4", want: []int{2, 6, 10}},
+		{spec: "service_time=3,1,8", want: []int{3, 1, 8}},
+		{spec: "capacity=7..7", want: []int{7}},
+		{spec: "capacity=5..2", wantErr: true},
+		{spec: "capacity=1..4:0", wantErr: true},
+		{spec: "capacity=a", wantErr: true},
+		{spec: "policy=1", wantErr: true},
+		{spec: "queue_limit.anon=4,8", want: []int{4, 8}},
+		{spec: "capacity", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseParameter(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseParameter(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got.Values, tt.want) {
+			t.Errorf("ParseParameter(%q) = %v, want %v", tt.spec, got.Values, tt.want)
+		}
+	}
+}
+
+func TestParseMetric(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Metric
+		wantErr bool
+	}{
+		{name: "p95_wait_paid", want: Metric{Name: "p95_wait_paid", Stat: "p95", Of: "wait", Class: engine.ClassPaid}},
+		{name: "mean_latency", want: Metric{Name: "mean_latency", Stat: "mean", Of: "latency"}},
+		{name: "rejection_rate_anon", want: Metric{Name: "rejection_rate_anon", Of: "rejection_rate", Class: engine.ClassAnon}},
+		{name: "throughput", want: Metric{Name: "throughput", Of: "throughput"}},
+		{name: "utilization", want: Metric{Name: "utilization", Of: "utilization"}},
+		{name: "max_queue_paid", wantErr: true},
+		{name: "p90_wait", wantErr: true},
+		{name: "p95_service", wantErr: true},
+		{name: "wait", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMetric(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMetric(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseMetric(%q) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSweep(t *testing.T) {
+	parameter, err := ParseParameter("capacity=1..3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	metric, err := ParseMetric("rejection_rate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sensitivity, err := Sweep(context.Background(), engine.Config{}, parameter, metric, []int64{1, 2}, 2, 0.95)
+	if err != nil {
+		t.Fatalf("Sweep() error = %v", err)
+	}
+	if len(sensitivity.Points) != 3 {
+		t.Fatalf("Sweep() = %d points, want 3", len(sensitivity.Points))
+	}
+	for i, point := range sensitivity.Points {
+		scenario, err := parameter.Apply(engine.CanonicalScenario(), point.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		artifact, err := engine.Run(engine.NewConfig(engine.WithScenarioSpec(scenario), engine.WithSeed(2)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := metric.Measure(artifact); point.Runs[1] != want {
+			t.Errorf("capacity=%d seed 2 = %v, want %v", point.Value, point.Runs[1], want)
+		}
+		if point.Min > point.Mean || point.Mean > point.Max || point.HalfWidth < 0 {
+			t.Errorf("capacity=%d point = %+v", point.Value, point)
+		}
+		if i > 0 && point.Mean > sensitivity.Points[i-1].Mean {
+			t.Errorf("rejection rate rose from %v to %v as capacity grew to %d", sensitivity.Points[i-1].Mean, point.Mean, point.Value)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := sensitivity.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || lines[0] != "capacity,rejection_rate,half_width,min,max,runs" || !strings.HasPrefix(lines[1], "1,") {
+		t.Errorf("WriteCSV() = %q", buf.String())
+	}
+
+	if _, err := Sweep(context.Background(), engine.Config{}, Parameter{Name: "capacity", Values: []int{0}}, metric, []int64{1}, 1, 0.95); err == nil {
+		t.Error("Sweep() with capacity 0 error = nil")
+	}
+}
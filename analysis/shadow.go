@@ -0,0 +1,85 @@
+package analysis
+
+import (
+	"cmp"
+	"slices"
+
+	"finit/engine"
+)
+
+type ShadowReport struct {
+	Decisions   int           `json:"decisions"`
+	Divergences int           `json:"divergences"`
+	Agreement   float64       `json:"agreement"`
+	Classes     []ShadowClass `json:"classes,omitempty"`
+	Swaps       []ShadowSwap  `json:"swaps,omitempty"`
+}
+
+type ShadowClass struct {
+	Class       string  `json:"class"`
+	Decisions   int     `json:"decisions"`
+	Divergences int     `json:"divergences"`
+	Agreement   float64 `json:"agreement"`
+}
+
+type ShadowSwap struct {
+	Chosen string `json:"chosen"`
+	Shadow string `json:"shadow"`
+	Count  int    `json:"count"`
+}
+
+func CompareShadow(artifact engine.Artifact) ShadowReport {
+	priority := classPriorities(artifact.Metadata)
+	classOf := make(map[string]string)
+	byClass := make(map[string]*ShadowClass)
+	swaps := make(map[[2]string]int)
+	class := func(name string) *ShadowClass {
+		c, ok := byClass[name]
+		if !ok {
+			c = &ShadowClass{Class: name}
+			byClass[name] = c
+		}
+		return c
+	}
+
+	var report ShadowReport
+	for _, event := range artifact.Events {
+		if _, ok := classOf[event.TokenID]; !ok && event.TokenID != "" {
+			classOf[event.TokenID] = event.Class
+		}
+		switch event.Type {
+		case engine.EventSchedule:
+			report.Decisions++
+			class(event.Class).Decisions++
+		case engine.EventShadowDiverge:
+			report.Divergences++
+			class(event.Class).Divergences++
+			if event.Context != nil {
+				swaps[[2]string{event.Class, classOf[event.Context.Shadow]}]++
+			}
+		}
+	}
+
+	report.Agreement = agreement(report.Decisions, report.Divergences)
+	for _, c := range byClass {
+		c.Agreement = agreement(c.Decisions, c.Divergences)
+		report.Classes = append(report.Classes, *c)
+	}
+	slices.SortFunc(report.Classes, func(a, b ShadowClass) int {
+		return cmp.Or(cmp.Compare(priority[a.Class], priority[b.Class]), cmp.Compare(a.Class, b.Class))
+	})
+	for pair, n := range swaps {
+		report.Swaps = append(report.Swaps, ShadowSwap{Chosen: pair[0], Shadow: pair[1], Count: n})
+	}
+	slices.SortFunc(report.Swaps, func(a, b ShadowSwap) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Chosen, b.Chosen), cmp.Compare(a.Shadow, b.Shadow))
+	})
+	return report
+}
+
+func agreement(decisions, divergences int) float64 {
+	if decisions == 0 {
+		return 1
+	}
+	return 1 - float64(divergences)/float64(decisions)
+}
@@ -0,0 +1,40 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestCompareShadow(t *testing.T) {
+	artifact := engine.Artifact{
+		Events: []engine.Event{
+			{Tick: 0, Type: engine.EventQueue, TokenID: "T0000", Class: engine.ClassAnon},
+			{Tick: 0, Type: engine.EventQueue, TokenID: "T0001", Class: engine.ClassPaid},
+			{Tick: 0, Type: engine.EventQueue, TokenID: "T0002", Class: engine.ClassPaid},
+			{Tick: 1, Type: engine.EventSchedule, TokenID: "T0001", Class: engine.ClassPaid},
+			{Tick: 1, Type: engine.EventShadowDiverge, TokenID: "T0001", Class: engine.ClassPaid, Context: &engine.EventContext{Shadow: "T0000"}},
+			{Tick: 2, Type: engine.EventSchedule, TokenID: "T0002", Class: engine.ClassPaid},
+			{Tick: 3, Type: engine.EventSchedule, TokenID: "T0000", Class: engine.ClassAnon},
+		},
+	}
+
+	report := CompareShadow(artifact)
+	if report.Decisions != 3 || report.Divergences != 1 {
+		t.Fatalf("CompareShadow() = %d decisions, %d divergences, want 3, 1", report.Decisions, report.Divergences)
+	}
+	if got, want := report.Agreement, 2.0/3; math.Abs(got-want) > 1e-9 {
+		t.Errorf("Agreement = %v, want %v", got, want)
+	}
+	if len(report.Classes) != 2 || report.Classes[0].Class != engine.ClassPaid || report.Classes[0].Agreement != 0.5 || report.Classes[1].Agreement != 1 {
+		t.Errorf("Classes = %+v, want PAID at 0.5 then ANON at 1", report.Classes)
+	}
+	if len(report.Swaps) != 1 || report.Swaps[0] != (ShadowSwap{Chosen: engine.ClassPaid, Shadow: engine.ClassAnon, Count: 1}) {
+		t.Errorf("Swaps = %+v, want one PAID served where the shadow wanted ANON", report.Swaps)
+	}
+
+	if empty := CompareShadow(engine.Artifact{}); empty.Agreement != 1 || empty.Classes != nil {
+		t.Errorf("CompareShadow() without decisions = %+v, want full agreement", empty)
+	}
+}
@@ -0,0 +1,297 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+
+	"finit/engine"
+)
+
+var Classes = []string{engine.ClassPaid, engine.ClassFree, engine.ClassAnon}
+
+type Summary struct {
+	TickCount      int            `json:"tick_count"`
+	TickDurationMs int            `json:"tick_duration_ms"`
+	WarmupTicks    int            `json:"warmup_ticks,omitempty"`
+	Arrived        int            `json:"arrived"`
+	Completed      int            `json:"completed"`
+	Rejected       int            `json:"rejected"`
+	Failed         int            `json:"failed,omitempty"`
+	Degraded       int            `json:"degraded,omitempty"`
+	Throughput     float64        `json:"throughput_per_sec"`
+	MaxQueueLength int            `json:"max_queue_length"`
+	Utilization    float64        `json:"utilization"`
+	QueueHistogram Histogram      `json:"queue_length_histogram"`
+	Classes        []ClassSummary `json:"classes"`
+	Fairness       Fairness       `json:"fairness"`
+	Ledger         *engine.Ledger `json:"ledger,omitempty"`
+	Anomalies      []Anomaly      `json:"anomalies,omitempty"`
+}
+
+type SummaryOptions struct {
+	Histograms HistogramBounds
+	Anomalies  AnomalyOptions
+}
+
+func DefaultSummaryOptions() SummaryOptions {
+	return SummaryOptions{Histograms: DefaultHistogramBounds(), Anomalies: DefaultAnomalyOptions()}
+}
+
+type ClassSummary struct {
+	Class         string    `json:"class"`
+	Arrived       int       `json:"arrived"`
+	Completed     int       `json:"completed"`
+	Rejected      int       `json:"rejected"`
+	Failed        int       `json:"failed,omitempty"`
+	Degraded      int       `json:"degraded,omitempty"`
+	RejectionRate float64   `json:"rejection_rate"`
+	Throughput    float64   `json:"throughput_per_sec"`
+	MeanWaitMs    float64   `json:"mean_wait_ms"`
+	P50LatencyMs  int       `json:"p50_latency_ms"`
+	P95LatencyMs  int       `json:"p95_latency_ms"`
+	P99LatencyMs  int       `json:"p99_latency_ms"`
+	MaxLatencyMs  int       `json:"max_latency_ms"`
+	WaitHistogram Histogram `json:"wait_histogram_ms"`
+}
+
+func Summarize(artifact engine.Artifact) Summary {
+	return SummarizeWith(artifact, DefaultSummaryOptions())
+}
+
+func SummarizeWith(artifact engine.Artifact, opts SummaryOptions) Summary {
+	tickMs := artifact.Metadata.TickDurationMs
+	summary := Summary{
+		TickCount:      artifact.Metadata.TickCount,
+		TickDurationMs: tickMs,
+		WarmupTicks:    artifact.Metadata.WarmupTicks,
+	}
+
+	waits := make(map[string][]int)
+	latencies := make(map[string][]int)
+	byClass := make(map[string]*ClassSummary)
+	classSummary := func(class string) *ClassSummary {
+		if cs, ok := byClass[class]; ok {
+			return cs
+		}
+		cs := &ClassSummary{Class: class}
+		byClass[class] = cs
+		return cs
+	}
+	for _, class := range Classes {
+		classSummary(class)
+	}
+
+	for _, lifecycle := range engine.Lifecycles(artifact.Events) {
+		if lifecycle.ArrivalTick < summary.WarmupTicks {
+			continue
+		}
+		cs := classSummary(lifecycle.Class)
+		cs.Arrived++
+		summary.Arrived++
+		if lifecycle.Rejected() {
+			cs.Rejected++
+			summary.Rejected++
+		}
+		if lifecycle.Failed() {
+			cs.Failed++
+			summary.Failed++
+		}
+		if lifecycle.Scheduled() {
+			waits[lifecycle.Class] = append(waits[lifecycle.Class], lifecycle.WaitTicks()*tickMs)
+		}
+		if lifecycle.Completed() {
+			cs.Completed++
+			summary.Completed++
+			latencies[lifecycle.Class] = append(latencies[lifecycle.Class], lifecycle.LatencyTicks()*tickMs)
+		}
+		if lifecycle.Quality == engine.QualityDegraded {
+			cs.Degraded++
+			summary.Degraded++
+		}
+	}
+
+	seconds := float64((artifact.Metadata.TickCount-summary.WarmupTicks)*tickMs) / 1000
+	if seconds > 0 {
+		summary.Throughput = float64(summary.Completed) / seconds
+	}
+	for _, cs := range byClass {
+		if cs.Arrived > 0 {
+			cs.RejectionRate = float64(cs.Rejected) / float64(cs.Arrived)
+		}
+		if seconds > 0 {
+			cs.Throughput = float64(cs.Completed) / seconds
+		}
+		cs.MeanWaitMs = Mean(waits[cs.Class])
+		cs.WaitHistogram = NewHistogram(opts.Histograms.WaitMs, waits[cs.Class])
+		sorted := sortedCopy(latencies[cs.Class])
+		cs.P50LatencyMs = Percentile(sorted, 50)
+		cs.P95LatencyMs = Percentile(sorted, 95)
+		cs.P99LatencyMs = Percentile(sorted, 99)
+		if len(sorted) > 0 {
+			cs.MaxLatencyMs = sorted[len(sorted)-1]
+		}
+	}
+
+	summary.Classes = orderedClasses(byClass)
+	summary.MaxQueueLength, summary.Utilization = stageTotals(artifact.Snapshots)
+	summary.QueueHistogram = NewHistogram(opts.Histograms.QueueLength, measuredQueueLengths(artifact.Snapshots))
+	summary.Fairness = MeasureFairness(artifact, nil)
+	summary.Ledger = ledgerTotals(artifact.Snapshots)
+	summary.Anomalies = DetectAnomalies(artifact, opts.Anomalies)
+	return summary
+}
+
+func QueueLengths(artifact engine.Artifact) []int {
+	return stageSeries(artifact.Snapshots, engine.StageQueue, func(stage engine.StageState) int {
+		return stage.QueueLength
+	})
+}
+
+func InService(artifact engine.Artifact) []int {
+	return stageSeries(artifact.Snapshots, engine.StageService, func(stage engine.StageState) int {
+		return stage.CapacityUsed
+	})
+}
+
+func Latencies(artifact engine.Artifact) map[string][]int {
+	tickMs := artifact.Metadata.TickDurationMs
+	latencies := make(map[string][]int)
+	for _, lifecycle := range engine.Lifecycles(artifact.Events) {
+		if lifecycle.Completed() {
+			latencies[lifecycle.Class] = append(latencies[lifecycle.Class], lifecycle.LatencyTicks()*tickMs)
+		}
+	}
+	return latencies
+}
+
+func Mean(values []int) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	total := 0
+	for _, value := range values {
+		total += value
+	}
+	return float64(total) / float64(len(values))
+}
+
+func Percentile(sorted []int, p float64) int {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+func sortedCopy(values []int) []int {
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	return sorted
+}
+
+func orderedClasses(byClass map[string]*ClassSummary) []ClassSummary {
+	out := make([]ClassSummary, 0, len(byClass))
+	for _, class := range classOrder(byClass) {
+		out = append(out, *byClass[class])
+	}
+	return out
+}
+
+func classOrder[T any](byClass map[string]T) []string {
+	order := append([]string(nil), Classes...)
+	var extra []string
+	for class := range byClass {
+		if !isKnownClass(class) {
+			extra = append(extra, class)
+		}
+	}
+	sort.Strings(extra)
+	return append(order, extra...)
+}
+
+func isKnownClass(class string) bool {
+	for _, known := range Classes {
+		if class == known {
+			return true
+		}
+	}
+	return false
+}
+
+func stageTotals(snapshots []engine.Snapshot) (int, float64) {
+	maxQueue := 0
+	used, total := 0, 0
+	for _, snapshot := range snapshots {
+		if snapshot.Warmup {
+			continue
+		}
+		for _, stage := range snapshot.Stages {
+			switch stage.ID {
+			case engine.StageQueue:
+				if stage.QueueLength > maxQueue {
+					maxQueue = stage.QueueLength
+				}
+			case engine.StageService:
+				u, t := stage.Load()
+				used += u
+				total += t
+			}
+		}
+	}
+	if total == 0 {
+		return maxQueue, 0
+	}
+	return maxQueue, float64(used) / float64(total)
+}
+
+func measuredQueueLengths(snapshots []engine.Snapshot) []int {
+	var measured []engine.Snapshot
+	for _, snapshot := range snapshots {
+		if !snapshot.Warmup {
+			measured = append(measured, snapshot)
+		}
+	}
+	return stageSeries(measured, engine.StageQueue, func(stage engine.StageState) int {
+		return stage.QueueLength
+	})
+}
+
+func ledgerTotals(snapshots []engine.Snapshot) *engine.Ledger {
+	var start engine.Ledger
+	var end *engine.Ledger
+	for _, snapshot := range snapshots {
+		if snapshot.Ledger == nil {
+			continue
+		}
+		if snapshot.Warmup {
+			start = *snapshot.Ledger
+		}
+		end = snapshot.Ledger
+	}
+	if end == nil {
+		return nil
+	}
+	totals := end.Sub(start)
+	return &totals
+}
+
+func stageSeries(snapshots []engine.Snapshot, stageID string, value func(engine.StageState) int) []int {
+	series := make([]int, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		v := 0
+		for _, stage := range snapshot.Stages {
+			if stage.ID == stageID {
+				v = value(stage)
+				break
+			}
+		}
+		series = append(series, v)
+	}
+	return series
+}
@@ -0,0 +1,110 @@
+package analysis
+
+import (
+	"testing"
+
+	"finit/engine"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := []int{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+	tests := []struct {
+		p    float64
+		want int
+	}{
+		{p: 0, want: 10},
+		{p: 50, want: 50},
+		{p: 95, want: 100},
+		{p: 100, want: 100},
+	}
+	for _, tt := range tests {
+		if got := Percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("Percentile(%v) = %d, want %d", tt.p, got, tt.want)
+		}
+	}
+	if got := Percentile(nil, 50); got != 0 {
+		t.Errorf("Percentile(nil) = %d, want 0", got)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	artifact := engine.Artifact{
+		Metadata: engine.Metadata{TickCount: 4, TickDurationMs: 250},
+		Snapshots: []engine.Snapshot{
+			{Tick: 0, Stages: []engine.StageState{
+				{ID: engine.StageQueue, QueueLength: 2},
+				{ID: engine.StageService, CapacityUsed: 1, CapacityTotal: 2},
+			}},
+			{Tick: 1, Stages: []engine.StageState{
+				{ID: engine.StageQueue, QueueLength: 5},
+				{ID: engine.StageService, CapacityUsed: 2, CapacityTotal: 2},
+			}},
+		},
+		Events: []engine.Event{
+			{Tick: 0, Type: engine.EventQueue, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 0, Type: engine.EventSchedule, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 0, Type: engine.EventQueue, TokenID: "T0001", Class: engine.ClassAnon},
+			{Tick: 0, Type: engine.EventQueue, TokenID: "T0003", Class: engine.ClassFree},
+			{Tick: 1, Type: engine.EventSchedule, TokenID: "T0003", Class: engine.ClassFree},
+			{Tick: 1, Type: engine.EventComplete, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 1, Type: engine.EventReject, TokenID: "T0002", Class: engine.ClassAnon},
+			{Tick: 2, Type: engine.EventSchedule, TokenID: "T0001", Class: engine.ClassAnon},
+			{Tick: 3, Type: engine.EventComplete, TokenID: "T0001", Class: engine.ClassAnon, Quality: engine.QualityDegraded},
+			{Tick: 3, Type: engine.EventFail, TokenID: "T0003", Class: engine.ClassFree},
+		},
+	}
+
+	summary := Summarize(artifact)
+	if summary.Arrived != 4 || summary.Completed != 2 || summary.Rejected != 1 || summary.Failed != 1 {
+		t.Errorf("totals = %d/%d/%d/%d, want 4/2/1/1", summary.Arrived, summary.Completed, summary.Rejected, summary.Failed)
+	}
+	if free := summary.Classes[1]; free.Failed != 1 || free.Completed != 0 {
+		t.Errorf("FREE failed = %d, completed = %d, want 1 and 0", free.Failed, free.Completed)
+	}
+	if summary.MaxQueueLength != 5 {
+		t.Errorf("MaxQueueLength = %d, want 5", summary.MaxQueueLength)
+	}
+	if summary.Throughput != 2 {
+		t.Errorf("Throughput = %v, want 2 per second", summary.Throughput)
+	}
+	if summary.Utilization != 0.75 {
+		t.Errorf("Utilization = %v, want 0.75", summary.Utilization)
+	}
+
+	anon := summary.Classes[2]
+	if anon.Class != engine.ClassAnon {
+		t.Fatalf("Classes[2] = %s, want ANON", anon.Class)
+	}
+	if anon.RejectionRate != 0.5 {
+		t.Errorf("ANON RejectionRate = %v, want 0.5", anon.RejectionRate)
+	}
+	if anon.MeanWaitMs != 500 || anon.P95LatencyMs != 750 || anon.P99LatencyMs != 750 {
+		t.Errorf("ANON wait = %v p95 = %d p99 = %d, want 500, 750 and 750", anon.MeanWaitMs, anon.P95LatencyMs, anon.P99LatencyMs)
+	}
+	if summary.Degraded != 1 || anon.Degraded != 1 {
+		t.Errorf("Degraded = %d, ANON Degraded = %d, want 1 and 1", summary.Degraded, anon.Degraded)
+	}
+	if anon.Throughput != 1 {
+		t.Errorf("ANON Throughput = %v, want 1 per second", anon.Throughput)
+	}
+
+	artifact.Metadata.WarmupTicks = 1
+	artifact.Snapshots[0].Warmup = true
+	steady := Summarize(artifact)
+	if steady.WarmupTicks != 1 || steady.Arrived != 1 || steady.Completed != 0 || steady.Rejected != 1 {
+		t.Errorf("after warmup totals = %d/%d/%d, want 1/0/1", steady.Arrived, steady.Completed, steady.Rejected)
+	}
+	if steady.Utilization != 1 || steady.MaxQueueLength != 5 {
+		t.Errorf("after warmup utilization = %v, max queue %d, want 1 and 5", steady.Utilization, steady.MaxQueueLength)
+	}
+	if summary.Ledger != nil || steady.Ledger != nil {
+		t.Errorf("Ledger = %+v without costs, want nil", summary.Ledger)
+	}
+
+	artifact.Snapshots[0].Ledger = &engine.Ledger{ServerCost: 2, Revenue: 5, Net: 3}
+	artifact.Snapshots[1].Ledger = &engine.Ledger{ServerCost: 4, RejectionPenalty: 1, Revenue: 5, Net: 0}
+	want := engine.Ledger{ServerCost: 2, RejectionPenalty: 1, Net: -3}
+	if got := Summarize(artifact).Ledger; got == nil || *got != want {
+		t.Errorf("Ledger after warmup = %+v, want %+v", got, want)
+	}
+}
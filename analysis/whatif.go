@@ -0,0 +1,173 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"finit/engine"
+)
+
+const (
+	PolicyFIFO      = "fifo"
+	PolicyLIFO      = "lifo"
+	PolicySJF       = "sjf"
+	PolicyPS        = "processor_sharing"
+	PolicyFCFS      = "fcfs"
+	PolicyUnbounded = "unbounded"
+)
+
+type whatIfPolicy struct {
+	name  string
+	apply func(*engine.Scenario)
+}
+
+var policies = []whatIfPolicy{
+	{PolicyFIFO, func(s *engine.Scenario) { s.Discipline = nil }},
+	{PolicyLIFO, func(s *engine.Scenario) { s.Discipline = &engine.Discipline{Queue: engine.DisciplineLIFO} }},
+	{PolicySJF, func(s *engine.Scenario) { s.Discipline = &engine.Discipline{Queue: engine.DisciplineSJF} }},
+	{PolicyPS, func(s *engine.Scenario) { s.Discipline = &engine.Discipline{Service: engine.ServicePS} }},
+	{PolicyFCFS, func(s *engine.Scenario) {
+		s.Discipline = nil
+		s.Classes = slices.Clone(s.ClassDefs())
+		for i := range s.Classes {
+			s.Classes[i].Priority = 0
+		}
+	}},
+	{PolicyUnbounded, func(s *engine.Scenario) {
+		s.Classes = slices.Clone(s.ClassDefs())
+		for i := range s.Classes {
+			s.Classes[i].QueueLimit = nil
+		}
+		s.Quotas = nil
+	}},
+}
+
+type WhatIf struct {
+	Policy    string       `json:"policy,omitempty"`
+	Overrides []Parameter  `json:"overrides,omitempty"`
+	Arrivals  int          `json:"arrivals"`
+	Diff      Diff         `json:"diff"`
+	Paired    PairedDeltas `json:"paired"`
+}
+
+type PairedDeltas struct {
+	Rescued            int     `json:"rescued"`
+	Lost               int     `json:"lost"`
+	Faster             int     `json:"faster"`
+	Slower             int     `json:"slower"`
+	Unchanged          int     `json:"unchanged"`
+	MeanLatencyDeltaMs float64 `json:"mean_latency_delta_ms"`
+}
+
+func PolicyNames() []string {
+	names := make([]string, len(policies))
+	for i, policy := range policies {
+		names[i] = policy.name
+	}
+	return names
+}
+
+func Counterfactual(ctx context.Context, original engine.Artifact, policy string, overrides []Parameter) (engine.Artifact, WhatIf, error) {
+	metadata := original.Metadata
+	if metadata.Slice != nil {
+		return engine.Artifact{}, WhatIf{}, fmt.Errorf("artifact holds only ticks %d-%d of its run; use the original artifact", metadata.Slice.From, metadata.Slice.To)
+	}
+	var scenario engine.Scenario
+	if metadata.Scenario != nil {
+		scenario = *metadata.Scenario
+	} else {
+		var err error
+		if scenario, err = engine.LookupScenario(metadata.ScenarioID); err != nil {
+			return engine.Artifact{}, WhatIf{}, err
+		}
+	}
+	scenario.Arrivals = engine.ExtractArrivals(original)
+	scenario.Traffic, scenario.Spikes = nil, nil
+
+	whatIf := WhatIf{Policy: policy, Overrides: overrides, Arrivals: len(scenario.Arrivals)}
+	scenario, err := applyChanges(scenario, policy, overrides)
+	if err != nil {
+		return engine.Artifact{}, WhatIf{}, err
+	}
+
+	counterfactual, err := engine.RunContext(ctx, engine.Config{
+		ScenarioID:       scenario.ID,
+		Scenario:         &scenario,
+		Seed:             metadata.Seed,
+		SnapshotInterval: metadata.SnapshotInterval,
+		ETA:              metadata.ETA,
+		TraceIDs:         metadata.TraceIDs,
+		ClassBreakdown:   metadata.ClassBreakdown,
+		TokenIDs:         metadata.TokenIDs,
+		Epoch:            metadata.Epoch,
+		EventFilters:     metadata.EventFilters,
+		EventRetention:   metadata.EventRetention,
+		Reasons:          metadata.Reasons,
+		Labels:           metadata.Labels,
+	})
+	if err != nil {
+		return engine.Artifact{}, WhatIf{}, err
+	}
+	whatIf.Diff = Compare(original, counterfactual)
+	whatIf.Paired = pairTokens(original, counterfactual)
+	return counterfactual, whatIf, nil
+}
+
+func applyChanges(scenario engine.Scenario, policy string, overrides []Parameter) (engine.Scenario, error) {
+	if policy != "" {
+		i := slices.IndexFunc(policies, func(p whatIfPolicy) bool { return p.name == policy })
+		if i < 0 {
+			return engine.Scenario{}, fmt.Errorf("unknown policy %q (want one of %s)", policy, strings.Join(PolicyNames(), ", "))
+		}
+		policies[i].apply(&scenario)
+	}
+	for _, override := range overrides {
+		if len(override.Values) != 1 {
+			return engine.Scenario{}, fmt.Errorf("override %s needs exactly one value, got %d", override.Name, len(override.Values))
+		}
+		var err error
+		if scenario, err = override.Apply(scenario, override.Values[0]); err != nil {
+			return engine.Scenario{}, err
+		}
+	}
+	return scenario, scenario.Validate()
+}
+
+func pairTokens(a, b engine.Artifact) PairedDeltas {
+	after := make(map[string]engine.Lifecycle)
+	for _, lifecycle := range engine.Lifecycles(b.Events) {
+		after[lifecycle.TokenID] = lifecycle
+	}
+	tickMs := a.Metadata.TickDurationMs
+	var paired PairedDeltas
+	var deltas []int
+	for _, before := range engine.Lifecycles(a.Events) {
+		other, ok := after[before.TokenID]
+		if !ok {
+			continue
+		}
+		switch {
+		case !before.Completed() && other.Completed():
+			paired.Rescued++
+		case before.Completed() && !other.Completed():
+			paired.Lost++
+		case before.Completed() && other.Completed():
+			delta := other.LatencyTicks() - before.LatencyTicks()
+			deltas = append(deltas, delta*tickMs)
+			switch {
+			case delta < 0:
+				paired.Faster++
+			case delta > 0:
+				paired.Slower++
+			default:
+				paired.Unchanged++
+			}
+		default:
+			paired.Unchanged++
+		}
+	}
+	paired.MeanLatencyDeltaMs = Mean(deltas)
+	return paired
+}
@@ -0,0 +1,50 @@
+package analysis
+
+import (
+	"context"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestCounterfactual(t *testing.T) {
+	original, err := engine.Run(engine.Config{ScenarioID: engine.ScenarioID, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := Summarize(original)
+
+	_, same, err := Counterfactual(context.Background(), original, PolicyFIFO, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(same.Diff.Summary) != 0 || same.Diff.Divergence != nil {
+		t.Errorf("Counterfactual(fifo) diff = %+v, want no summary changes", same.Diff)
+	}
+	if same.Arrivals != summary.Arrived {
+		t.Errorf("Counterfactual(fifo) arrivals = %d, want %d", same.Arrivals, summary.Arrived)
+	}
+
+	counterfactual, unbounded, err := Counterfactual(context.Background(), original, PolicyUnbounded, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Summarize(counterfactual).Rejected; got != 0 {
+		t.Errorf("Counterfactual(unbounded) rejected = %d, want 0", got)
+	}
+	if unbounded.Paired.Rescued != summary.Rejected || unbounded.Paired.Lost != 0 {
+		t.Errorf("Counterfactual(unbounded) paired = %+v, want %d rescued and none lost", unbounded.Paired, summary.Rejected)
+	}
+
+	_, overridden, err := Counterfactual(context.Background(), original, "", []Parameter{{Name: "capacity", Values: []int{6}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overridden.Paired.Slower != 0 {
+		t.Errorf("Counterfactual(capacity=6) slowed %d tokens", overridden.Paired.Slower)
+	}
+
+	if _, _, err := Counterfactual(context.Background(), original, "wfq", nil); err == nil {
+		t.Error("Counterfactual(wfq) error = nil, want unknown policy")
+	}
+}
@@ -0,0 +1,119 @@
+package arrow
+
+import "encoding/binary"
+
+type builder struct {
+	buf      []byte
+	minAlign int
+	tableEnd int
+	slots    []int
+}
+
+func (b *builder) offset() int {
+	return len(b.buf)
+}
+
+func (b *builder) prepend(p []byte) {
+	b.buf = append(append(make([]byte, 0, len(p)+len(b.buf)), p...), b.buf...)
+}
+
+func (b *builder) prep(size, additional int) {
+	if size > b.minAlign {
+		b.minAlign = size
+	}
+	pad := (size - (len(b.buf)+additional)%size) % size
+	b.prepend(make([]byte, pad))
+}
+
+func (b *builder) uint8(v uint8) {
+	b.prep(1, 0)
+	b.prepend([]byte{v})
+}
+
+func (b *builder) int16(v int16) {
+	b.prep(2, 0)
+	b.prepend(binary.LittleEndian.AppendUint16(nil, uint16(v)))
+}
+
+func (b *builder) int32(v int32) {
+	b.prep(4, 0)
+	b.prepend(binary.LittleEndian.AppendUint32(nil, uint32(v)))
+}
+
+func (b *builder) int64(v int64) {
+	b.prep(8, 0)
+	b.prepend(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+}
+
+func (b *builder) uoffset(off int) {
+	b.prep(4, 0)
+	b.prepend(binary.LittleEndian.AppendUint32(nil, uint32(b.offset()+4-off)))
+}
+
+func (b *builder) string(s string) int {
+	b.prep(4, len(s)+1)
+	b.prepend(append([]byte(s), 0))
+	b.prepend(binary.LittleEndian.AppendUint32(nil, uint32(len(s))))
+	return b.offset()
+}
+
+func (b *builder) offsets(offs []int) int {
+	b.prep(4, 4*len(offs))
+	for i := len(offs) - 1; i >= 0; i-- {
+		b.uoffset(offs[i])
+	}
+	b.prepend(binary.LittleEndian.AppendUint32(nil, uint32(len(offs))))
+	return b.offset()
+}
+
+func (b *builder) structs(fields [][]int64) int {
+	size := 0
+	if len(fields) > 0 {
+		size = 8 * len(fields[0])
+	}
+	b.prep(4, size*len(fields))
+	b.prep(8, size*len(fields))
+	for i := len(fields) - 1; i >= 0; i-- {
+		for j := len(fields[i]) - 1; j >= 0; j-- {
+			b.int64(fields[i][j])
+		}
+	}
+	b.prepend(binary.LittleEndian.AppendUint32(nil, uint32(len(fields))))
+	return b.offset()
+}
+
+func (b *builder) startTable(fields int) {
+	b.tableEnd = b.offset()
+	b.slots = make([]int, fields)
+}
+
+func (b *builder) slot(field int) {
+	b.slots[field] = b.offset()
+}
+
+func (b *builder) endTable() int {
+	b.int32(0)
+	object := b.offset()
+
+	vtable := make([]byte, 0, 4+2*len(b.slots))
+	vtable = binary.LittleEndian.AppendUint16(vtable, uint16(4+2*len(b.slots)))
+	vtable = binary.LittleEndian.AppendUint16(vtable, uint16(object-b.tableEnd))
+	for _, slot := range b.slots {
+		var off uint16
+		if slot != 0 {
+			off = uint16(object - slot)
+		}
+		vtable = binary.LittleEndian.AppendUint16(vtable, off)
+	}
+	b.prepend(vtable)
+
+	pos := len(b.buf) - object
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(b.offset()-object))
+	return object
+}
+
+func (b *builder) finish(root int) []byte {
+	b.prep(b.minAlign, 4)
+	b.uoffset(root)
+	return b.buf
+}
@@ -0,0 +1,297 @@
+package arrow
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	metadataV5 = 4
+
+	headerSchema      = 1
+	headerRecordBatch = 3
+
+	typeInt  = 2
+	typeUtf8 = 5
+)
+
+var magic = []byte("ARROW1")
+
+var errNoColumns = errors.New("arrow: table has no columns")
+
+type Column interface {
+	Name() string
+	Len() int
+	nullable() bool
+	typeTable(b *builder) (uint8, int)
+	buffers(valid []bool) [][]byte
+	validity() []bool
+}
+
+type Int64Column struct {
+	Field  string
+	Values []int64
+	Valid  []bool
+}
+
+func (c *Int64Column) Name() string     { return c.Field }
+func (c *Int64Column) Len() int         { return len(c.Values) }
+func (c *Int64Column) nullable() bool   { return c.Valid != nil }
+func (c *Int64Column) validity() []bool { return c.Valid }
+
+func (c *Int64Column) typeTable(b *builder) (uint8, int) {
+	b.startTable(2)
+	b.int32(64)
+	b.slot(0)
+	b.uint8(1)
+	b.slot(1)
+	return typeInt, b.endTable()
+}
+
+func (c *Int64Column) buffers(valid []bool) [][]byte {
+	data := make([]byte, 0, 8*len(c.Values))
+	for _, v := range c.Values {
+		data = binary.LittleEndian.AppendUint64(data, uint64(v))
+	}
+	return [][]byte{bitmap(valid), data}
+}
+
+type StringColumn struct {
+	Field  string
+	Values []string
+	Valid  []bool
+}
+
+func (c *StringColumn) Name() string     { return c.Field }
+func (c *StringColumn) Len() int         { return len(c.Values) }
+func (c *StringColumn) nullable() bool   { return c.Valid != nil }
+func (c *StringColumn) validity() []bool { return c.Valid }
+
+func (c *StringColumn) typeTable(b *builder) (uint8, int) {
+	b.startTable(0)
+	return typeUtf8, b.endTable()
+}
+
+func (c *StringColumn) buffers(valid []bool) [][]byte {
+	offsets := make([]byte, 0, 4*(len(c.Values)+1))
+	offsets = binary.LittleEndian.AppendUint32(offsets, 0)
+	var data []byte
+	for _, v := range c.Values {
+		data = append(data, v...)
+		offsets = binary.LittleEndian.AppendUint32(offsets, uint32(len(data)))
+	}
+	return [][]byte{bitmap(valid), offsets, data}
+}
+
+type Table struct {
+	Columns []Column
+}
+
+func (t Table) Len() int {
+	if len(t.Columns) == 0 {
+		return 0
+	}
+	return t.Columns[0].Len()
+}
+
+func (t Table) validate() error {
+	if len(t.Columns) == 0 {
+		return errNoColumns
+	}
+	for _, c := range t.Columns {
+		if c.Len() != t.Len() {
+			return fmt.Errorf("column %q has %d rows, want %d", c.Name(), c.Len(), t.Len())
+		}
+		if v := c.validity(); v != nil && len(v) != c.Len() {
+			return fmt.Errorf("column %q has %d validity entries, want %d", c.Name(), len(v), c.Len())
+		}
+	}
+	return nil
+}
+
+func WriteFile(path string, table Table) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Write(file, table); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func Write(w io.Writer, table Table) error {
+	if err := table.validate(); err != nil {
+		return err
+	}
+	out := &countingWriter{w: bufio.NewWriter(w)}
+
+	out.write(magic)
+	out.write(make([]byte, 2))
+
+	schemaMessage := message(headerSchema, 0, func(b *builder) int { return schema(b, table) })
+	out.write(schemaMessage)
+
+	body, nodes, buffers := recordBody(table)
+	batchOffset := out.n
+	batchMessage := message(headerRecordBatch, int64(len(body)), func(b *builder) int {
+		return recordBatch(b, int64(table.Len()), nodes, buffers)
+	})
+	out.write(batchMessage)
+	out.write(body)
+
+	out.write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0})
+
+	footer := footer(table, []int64{batchOffset, int64(len(batchMessage)), int64(len(body))})
+	out.write(footer)
+	out.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	out.write(magic)
+
+	if out.err != nil {
+		return out.err
+	}
+	return out.w.Flush()
+}
+
+func message(header uint8, bodyLength int64, build func(b *builder) int) []byte {
+	b := &builder{}
+	headerOffset := build(b)
+	b.startTable(5)
+	b.int64(bodyLength)
+	b.slot(3)
+	b.uoffset(headerOffset)
+	b.slot(2)
+	b.int16(metadataV5)
+	b.slot(0)
+	b.uint8(header)
+	b.slot(1)
+	meta := b.finish(b.endTable())
+
+	padded := pad8(len(meta))
+	out := make([]byte, 0, 8+padded)
+	out = binary.LittleEndian.AppendUint32(out, 0xffffffff)
+	out = binary.LittleEndian.AppendUint32(out, uint32(padded))
+	out = append(out, meta...)
+	return append(out, make([]byte, padded-len(meta))...)
+}
+
+func schema(b *builder, table Table) int {
+	fields := make([]int, len(table.Columns))
+	for i, c := range table.Columns {
+		name := b.string(c.Name())
+		kind, typ := c.typeTable(b)
+		children := b.offsets(nil)
+		b.startTable(7)
+		b.uoffset(name)
+		b.slot(0)
+		b.uoffset(typ)
+		b.slot(3)
+		b.uoffset(children)
+		b.slot(5)
+		b.uint8(kind)
+		b.slot(2)
+		if c.nullable() {
+			b.uint8(1)
+			b.slot(1)
+		}
+		fields[i] = b.endTable()
+	}
+	vector := b.offsets(fields)
+	b.startTable(4)
+	b.uoffset(vector)
+	b.slot(1)
+	b.int16(0)
+	b.slot(0)
+	return b.endTable()
+}
+
+func recordBody(table Table) ([]byte, [][]int64, [][]int64) {
+	var body []byte
+	var nodes, buffers [][]int64
+	for _, c := range table.Columns {
+		valid := c.validity()
+		nulls := 0
+		for _, ok := range valid {
+			if !ok {
+				nulls++
+			}
+		}
+		if nulls == 0 {
+			valid = nil
+		}
+		nodes = append(nodes, []int64{int64(c.Len()), int64(nulls)})
+		for _, buf := range c.buffers(valid) {
+			buffers = append(buffers, []int64{int64(len(body)), int64(len(buf))})
+			body = append(body, buf...)
+			body = append(body, make([]byte, pad8(len(buf))-len(buf))...)
+		}
+	}
+	return body, nodes, buffers
+}
+
+func recordBatch(b *builder, length int64, nodes, buffers [][]int64) int {
+	bufferVector := b.structs(buffers)
+	nodeVector := b.structs(nodes)
+	b.startTable(5)
+	b.int64(length)
+	b.slot(0)
+	b.uoffset(nodeVector)
+	b.slot(1)
+	b.uoffset(bufferVector)
+	b.slot(2)
+	return b.endTable()
+}
+
+func footer(table Table, block []int64) []byte {
+	b := &builder{}
+	blocks := b.structs([][]int64{block})
+	dictionaries := b.structs(nil)
+	schemaOffset := schema(b, table)
+	b.startTable(5)
+	b.uoffset(schemaOffset)
+	b.slot(1)
+	b.uoffset(dictionaries)
+	b.slot(2)
+	b.uoffset(blocks)
+	b.slot(3)
+	b.int16(metadataV5)
+	b.slot(0)
+	return b.finish(b.endTable())
+}
+
+func bitmap(valid []bool) []byte {
+	if valid == nil {
+		return nil
+	}
+	bits := make([]byte, (len(valid)+7)/8)
+	for i, ok := range valid {
+		if ok {
+			bits[i/8] |= 1 << (i % 8)
+		}
+	}
+	return bits
+}
+
+func pad8(n int) int {
+	return (n + 7) &^ 7
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) write(p []byte) {
+	if c.err != nil {
+		return
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+}
@@ -0,0 +1,117 @@
+package arrow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+type fbTable struct {
+	buf []byte
+	pos int
+}
+
+func root(buf []byte) fbTable {
+	return fbTable{buf: buf, pos: int(binary.LittleEndian.Uint32(buf))}
+}
+
+func (t fbTable) field(i int) int {
+	vtable := t.pos - int(int32(binary.LittleEndian.Uint32(t.buf[t.pos:])))
+	if 4+2*i >= int(binary.LittleEndian.Uint16(t.buf[vtable:])) {
+		return 0
+	}
+	off := int(binary.LittleEndian.Uint16(t.buf[vtable+4+2*i:]))
+	if off == 0 {
+		return 0
+	}
+	return t.pos + off
+}
+
+func (t fbTable) deref(pos int) int {
+	return pos + int(binary.LittleEndian.Uint32(t.buf[pos:]))
+}
+
+func (t fbTable) table(i int) fbTable {
+	return fbTable{buf: t.buf, pos: t.deref(t.field(i))}
+}
+
+func (t fbTable) vector(i int) (int, int) {
+	pos := t.deref(t.field(i))
+	return pos + 4, int(binary.LittleEndian.Uint32(t.buf[pos:]))
+}
+
+func (t fbTable) string(i int) string {
+	start, n := t.vector(i)
+	return string(t.buf[start : start+n])
+}
+
+func (t fbTable) int64(i int) int64 {
+	return int64(binary.LittleEndian.Uint64(t.buf[t.field(i):]))
+}
+
+func TestWrite(t *testing.T) {
+	table := Table{Columns: []Column{
+		&Int64Column{Field: "tick", Values: []int64{0, 1, 2}},
+		&StringColumn{Field: "class", Values: []string{"PAID", "", "ANON"}, Valid: []bool{true, false, true}},
+	}}
+	var buf bytes.Buffer
+	if err := Write(&buf, table); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	data := buf.Bytes()
+
+	if !bytes.HasPrefix(data, []byte("ARROW1\x00\x00")) || !bytes.HasSuffix(data, []byte("ARROW1")) {
+		t.Fatalf("missing ARROW1 magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-10:]))
+	footer := root(data[len(data)-10-footerLen : len(data)-10])
+
+	fieldsStart, fieldCount := footer.table(1).vector(1)
+	if fieldCount != 2 {
+		t.Fatalf("schema fields = %d, want 2", fieldCount)
+	}
+	var names []string
+	for i := 0; i < fieldCount; i++ {
+		field := fbTable{buf: footer.buf, pos: footer.deref(fieldsStart + 4*i)}
+		names = append(names, field.string(0))
+	}
+	if names[0] != "tick" || names[1] != "class" {
+		t.Errorf("field names = %v, want [tick class]", names)
+	}
+
+	blocksStart, blockCount := footer.vector(3)
+	if blockCount != 1 {
+		t.Fatalf("record batches = %d, want 1", blockCount)
+	}
+	offset := int(binary.LittleEndian.Uint64(footer.buf[blocksStart:]))
+	if offset%8 != 0 {
+		t.Errorf("record batch offset %d is not 8-byte aligned", offset)
+	}
+	if got := binary.LittleEndian.Uint32(data[offset:]); got != 0xffffffff {
+		t.Fatalf("record batch continuation = %#x", got)
+	}
+	message := root(data[offset+8:])
+	if kind := message.buf[message.field(1)]; kind != headerRecordBatch {
+		t.Fatalf("message header = %d, want %d", kind, headerRecordBatch)
+	}
+	if got := message.table(2).int64(0); got != 3 {
+		t.Errorf("record batch length = %d, want 3", got)
+	}
+	nodesStart, _ := message.table(2).vector(1)
+	if nulls := binary.LittleEndian.Uint64(message.buf[nodesStart+16+8:]); nulls != 1 {
+		t.Errorf("class null_count = %d, want 1", nulls)
+	}
+}
+
+func TestWriteMismatchedColumns(t *testing.T) {
+	table := Table{Columns: []Column{
+		&Int64Column{Field: "tick", Values: []int64{0, 1}},
+		&StringColumn{Field: "class", Values: []string{"PAID"}},
+	}}
+	if err := Write(&bytes.Buffer{}, table); err == nil {
+		t.Error("Write() error = nil, want row count mismatch")
+	}
+	if err := Write(&bytes.Buffer{}, Table{}); err == nil {
+		t.Error("Write() error = nil, want no columns error")
+	}
+}
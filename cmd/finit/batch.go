@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"finit/engine"
+	"finit/storage"
+)
+
+func runBatch(args []string) error {
+	flags := flag.NewFlagSet("batch", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit batch [flags]")
+		flags.PrintDefaults()
+	}
+	scenarioID := flags.String("scenario_id", engine.ScenarioID, "scenario id")
+	seedList := flags.String("seeds", "1-10", "seeds to run: a range (1-100), a list (1,5,9), or both (1-10,20)")
+	workers := flags.Int("workers", 0, "concurrent simulations (0 uses every CPU)")
+	labels := labelFlags(flags, "tag every artifact's metadata with key=value (repeatable)")
+	limits := limitFlags(flags)
+	build := buildFlags(flags)
+	out := flags.String("out", "runs", "output directory or s3://bucket/prefix; each run is written to seed-<n>.json")
+	if _, err := parseArgs(flags, args); err != nil {
+		return err
+	}
+	seeds, err := engine.ParseSeeds(*seedList)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cfg := engine.Config{ScenarioID: *scenarioID, Labels: labels, Build: build(), Limits: *limits}
+	return engine.RunEach(ctx, cfg, seeds, *workers, func(i int, artifact engine.Artifact) error {
+		dest := fmt.Sprintf("%s/seed-%d.json", strings.TrimSuffix(*out, "/"), seeds[i])
+		if err := storage.WriteArtifact(ctx, dest, artifact); err != nil {
+			return err
+		}
+		fmt.Printf("wrote %s (replay_id=%s)\n", dest, artifact.Metadata.ReplayID)
+		return nil
+	})
+}
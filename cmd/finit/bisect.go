@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"finit/analysis"
+	"finit/engine"
+)
+
+func runBisect(args []string) error {
+	flags := flag.NewFlagSet("bisect", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit bisect [flags] a.json b.json")
+		flags.PrintDefaults()
+	}
+	window := flags.Int("context", 2, "ticks of events to show on each side of the divergence")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		flags.Usage()
+		return errors.New("bisect: expected two artifact paths")
+	}
+
+	var artifacts [2]engine.Artifact
+	for i, path := range positional {
+		if artifacts[i], err = engine.ReadArtifact(path); err != nil {
+			return err
+		}
+	}
+	bisection, err := analysis.Bisect(artifacts[0], artifacts[1])
+	if err != nil {
+		return fmt.Errorf("bisect: %w", err)
+	}
+	replayID := artifacts[0].Metadata.ReplayID
+	if bisection == nil {
+		fmt.Printf("replay %s: artifacts are identical\n", replayID)
+		return nil
+	}
+
+	fmt.Printf("replay %s: first divergence at tick %d in %s %d\n", replayID, bisection.Tick, bisection.Kind, bisection.Index)
+	fmt.Printf("  %s: %s -> %s\n", bisection.Field, bisection.A, bisection.B)
+	from, to := bisection.Tick-*window, bisection.Tick+*window
+	for i, path := range positional {
+		fmt.Printf("\n%s, ticks %d-%d:\n", path, max(from, 0), to)
+		writeEvents(os.Stdout, analysis.EventsBetween(artifacts[i].Events, from, to), bisection)
+	}
+	return errArtifactsDiffer
+}
+
+func writeEvents(w io.Writer, events []engine.Event, bisection *analysis.Bisection) {
+	tick := -1
+	for _, event := range events {
+		if event.Tick != tick {
+			tick = event.Tick
+			marker := " "
+			if tick == bisection.Tick {
+				marker = ">"
+			}
+			fmt.Fprintf(w, "%s tick %d\n", marker, tick)
+		}
+		fmt.Fprintf(w, "    %-8s %-6s %-4s %s", event.Type, event.TokenID, event.Class, event.ReasonCode)
+		if c := event.Context; c != nil {
+			fmt.Fprintf(w, " rule=%s queue=%d", c.Rule, c.QueueLength)
+		}
+		fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"finit/analysis"
+	"finit/engine"
+	"finit/report"
+)
+
+func runCompare(args []string) error {
+	flags := flag.NewFlagSet("compare", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit compare [flags] before.json after.json")
+		flags.PrintDefaults()
+	}
+	format := flags.String("report", "html", "report format: html or json")
+	out := flags.String("o", "", "output file path (- for stdout; default compare.html for html, stdout for json)")
+	threshold := flags.Float64("threshold", 0.1, "relative change a metric needs before it is called out as a divergence")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		flags.Usage()
+		return errors.New("compare: expected two artifact paths")
+	}
+	if *format != "html" && *format != "json" {
+		return fmt.Errorf("unknown -report %q (want html or json)", *format)
+	}
+	if *threshold < 0 {
+		return fmt.Errorf("-threshold must be >= 0, got %g", *threshold)
+	}
+	if *out == "" && *format == "html" {
+		*out = "compare.html"
+	}
+
+	var artifacts [2]engine.Artifact
+	for i, path := range positional {
+		if artifacts[i], err = engine.ReadArtifact(path); err != nil {
+			return err
+		}
+	}
+	comparison := analysis.CompareRuns(artifacts[0], artifacts[1],
+		runName(positional[0]), runName(positional[1]), *threshold)
+
+	file, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if *format == "json" {
+		enc := json.NewEncoder(file)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(comparison)
+	} else {
+		err = report.WriteComparison(file, comparison)
+	}
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func runName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
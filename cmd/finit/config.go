@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+var configFiles = []string{"finit.yaml", "finit.yml", "finit.json"}
+
+type globalKey struct {
+	flag     string
+	commands []string
+}
+
+var globalKeys = map[string]globalKey{
+	"format":      {flag: "format", commands: []string{"diff", "inspect", "optimize", "stats", "whatif"}},
+	"out_dir":     {flag: "out", commands: []string{"batch", "experiment"}},
+	"scenario_id": {flag: "scenario_id"},
+}
+
+func (k globalKey) appliesTo(command, name string) bool {
+	return k.flag == name && (k.commands == nil || slices.Contains(k.commands, command))
+}
+
+type fileConfig struct {
+	path     string
+	defaults map[string]string
+	commands map[string]map[string]string
+}
+
+var (
+	configOnce sync.Once
+	config     fileConfig
+	configErr  error
+)
+
+func loadConfig() (fileConfig, error) {
+	configOnce.Do(func() {
+		config, configErr = findConfig()
+	})
+	return config, configErr
+}
+
+func findConfig() (fileConfig, error) {
+	path := os.Getenv("FINIT_CONFIG")
+	if path == "" {
+		for _, candidate := range configFiles {
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
+	}
+	if path == "" {
+		return fileConfig{}, nil
+	}
+	return readConfigFile(path)
+}
+
+func readConfigFile(path string) (fileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fileConfig{}, err
+	}
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return fileConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	config := fileConfig{path: path, defaults: map[string]string{}, commands: map[string]map[string]string{}}
+	for key, value := range raw {
+		section, ok := value.(map[string]any)
+		if !ok {
+			if _, known := globalKeys[key]; !known {
+				return fileConfig{}, fmt.Errorf("%s: unknown key %q (want a command section or one of %s)", path, key, strings.Join(slices.Sorted(maps.Keys(globalKeys)), ", "))
+			}
+			config.defaults[key] = fmt.Sprint(value)
+			continue
+		}
+		config.commands[key] = map[string]string{}
+		for name, v := range section {
+			if _, nested := v.(map[string]any); nested {
+				return fileConfig{}, fmt.Errorf("%s: %s.%s: expected a value, not a section", path, key, name)
+			}
+			config.commands[key][name] = fmt.Sprint(v)
+		}
+	}
+	return config, nil
+}
+
+func checkConfigSections() error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	for name := range config.commands {
+		if _, ok := commands[name]; !ok {
+			return fmt.Errorf("%s: unknown command section %q", config.path, name)
+		}
+	}
+	return nil
+}
+
+func envName(parts ...string) string {
+	name := strings.Join(append([]string{"finit"}, parts...), "_")
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+func applyDefaults(flags *flag.FlagSet) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	for _, note := range config.ignored(flags) {
+		fmt.Fprintf(os.Stderr, "finit: %s\n", note)
+	}
+	return config.apply(flags)
+}
+
+func (c fileConfig) apply(flags *flag.FlagSet) error {
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	section := c.commands[flags.Name()]
+	for name := range section {
+		if flags.Lookup(name) == nil {
+			return fmt.Errorf("%s: %s has no flag -%s", c.path, flags.Name(), name)
+		}
+	}
+
+	var errs []error
+	flags.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] {
+			return
+		}
+		source, value, ok := c.lookup(flags.Name(), f.Name)
+		if !ok {
+			return
+		}
+		if err := f.Value.Set(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid value %q for -%s: %w", source, value, f.Name, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+func (c fileConfig) ignored(flags *flag.FlagSet) []string {
+	command := flags.Name()
+	var notes []string
+	for _, key := range slices.Sorted(maps.Keys(globalKeys)) {
+		k := globalKeys[key]
+		if flags.Lookup(k.flag) == nil || k.appliesTo(command, k.flag) {
+			continue
+		}
+		source := envName(key)
+		if _, ok := os.LookupEnv(source); !ok {
+			if _, ok := c.defaults[key]; !ok {
+				continue
+			}
+			source = c.path
+		}
+		explicit := false
+		flags.Visit(func(f *flag.Flag) { explicit = explicit || f.Name == k.flag })
+		if _, ok := os.LookupEnv(envName(command, k.flag)); ok {
+			explicit = true
+		}
+		if _, ok := c.commands[command][k.flag]; ok {
+			explicit = true
+		}
+		if !explicit {
+			notes = append(notes, fmt.Sprintf("%s: %s is not used for %s -%s; set %s.%s or %s instead", source, key, command, k.flag, command, k.flag, envName(command, k.flag)))
+		}
+	}
+	return notes
+}
+
+func (c fileConfig) lookup(command, name string) (source, value string, ok bool) {
+	var keys []string
+	for _, key := range slices.Sorted(maps.Keys(globalKeys)) {
+		if globalKeys[key].appliesTo(command, name) {
+			keys = append(keys, key)
+		}
+	}
+	envs := []string{envName(command, name)}
+	for _, key := range keys {
+		envs = append(envs, envName(key))
+	}
+	for _, env := range envs {
+		if value, ok := os.LookupEnv(env); ok {
+			return env, value, true
+		}
+	}
+	if value, ok := c.commands[command][name]; ok {
+		return c.path, value, true
+	}
+	for _, key := range keys {
+		if value, ok := c.defaults[key]; ok {
+			return c.path, value, true
+		}
+	}
+	return "", "", false
+}
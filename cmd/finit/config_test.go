@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"finit.yaml": `# shared defaults
+out_dir: runs  # trailing comment
+scenario_id: "canonical_v1"
+
+batch:
+  workers: 4
+  seeds: '1-10'
+`,
+		"finit.json": `{"out_dir": "runs", "scenario_id": "canonical_v1", "batch": {"workers": 4, "seeds": "1-10"}}`,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		config, err := readConfigFile(path)
+		if err != nil {
+			t.Fatalf("readConfigFile(%s) error = %v", name, err)
+		}
+		if got := config.defaults["out_dir"]; got != "runs" {
+			t.Errorf("%s: out_dir = %q, want runs", name, got)
+		}
+		if got := config.defaults["scenario_id"]; got != "canonical_v1" {
+			t.Errorf("%s: scenario_id = %q, want canonical_v1", name, got)
+		}
+		if got := config.commands["batch"]["workers"]; got != "4" {
+			t.Errorf("%s: batch.workers = %q, want 4", name, got)
+		}
+		if got := config.commands["batch"]["seeds"]; got != "1-10" {
+			t.Errorf("%s: batch.seeds = %q, want 1-10", name, got)
+		}
+	}
+}
+
+func TestReadConfigFile_UnknownKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "finit.yaml")
+	if err := os.WriteFile(path, []byte("fromat: json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readConfigFile(path); err == nil || !strings.Contains(err.Error(), `unknown key "fromat"`) {
+		t.Errorf("readConfigFile() error = %v, want unknown key \"fromat\"", err)
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	config := fileConfig{
+		path:     "finit.yaml",
+		defaults: map[string]string{"out_dir": "file-runs", "format": "json", "scenario_id": "file_id"},
+		commands: map[string]map[string]string{"run": {"seed": "3"}, "stats": {"format": "text"}},
+	}
+	t.Setenv("FINIT_SCENARIO_ID", "env_id")
+	t.Setenv("FINIT_RUN_SCENARIO_ID", "run_id")
+	t.Setenv("FINIT_OUT", "env.json")
+
+	tests := []struct {
+		command, flag string
+		args          []string
+		want          string
+	}{
+		{"run", "seed", nil, "3"},
+		{"run", "seed", []string{"-seed", "9"}, "9"},
+		{"run", "scenario_id", nil, "run_id"},
+		{"batch", "scenario_id", nil, "env_id"},
+		{"batch", "scenario_id", []string{"-scenario_id", "flag_id"}, "flag_id"},
+		{"run", "out", nil, "default"},
+		{"batch", "out", nil, "file-runs"},
+		{"experiment", "out", nil, "file-runs"},
+		{"recover", "out", nil, "default"},
+		{"run", "format", nil, "default"},
+		{"diff", "format", nil, "json"},
+		{"stats", "format", nil, "text"},
+	}
+	for _, tt := range tests {
+		flags := flag.NewFlagSet(tt.command, flag.ContinueOnError)
+		for _, name := range []string{"format", "out", "scenario_id", "seed"} {
+			flags.String(name, "default", "")
+		}
+		if err := flags.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if err := config.apply(flags); err != nil {
+			t.Fatalf("apply(%s %v) error = %v", tt.command, tt.args, err)
+		}
+		if got := flags.Lookup(tt.flag).Value.String(); got != tt.want {
+			t.Errorf("apply(%s %v) -%s = %q, want %q", tt.command, tt.args, tt.flag, got, tt.want)
+		}
+	}
+
+	ignored := []struct {
+		command string
+		flags   []string
+		args    []string
+		want    []string
+	}{
+		{"run", []string{"format", "out", "scenario_id"}, nil, []string{
+			"finit.yaml: format is not used for run -format; set run.format or FINIT_RUN_FORMAT instead",
+			"finit.yaml: out_dir is not used for run -out; set run.out or FINIT_RUN_OUT instead",
+		}},
+		{"run", []string{"format", "out"}, []string{"-format", "sqlite", "-out", "runs"}, nil},
+		{"recover", []string{"out"}, nil, []string{"finit.yaml: out_dir is not used for recover -out; set recover.out or FINIT_RECOVER_OUT instead"}},
+		{"stats", []string{"format"}, nil, nil},
+		{"batch", []string{"out", "scenario_id"}, nil, nil},
+	}
+	for _, tt := range ignored {
+		flags := flag.NewFlagSet(tt.command, flag.ContinueOnError)
+		for _, name := range tt.flags {
+			flags.String(name, "default", "")
+		}
+		if err := flags.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if got := config.ignored(flags); !slices.Equal(got, tt.want) {
+			t.Errorf("ignored(%s %v) = %q, want %q", tt.command, tt.args, got, tt.want)
+		}
+	}
+
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.Int64("seed", 1, "")
+	t.Setenv("FINIT_RUN_SEED", "x")
+	if err := (fileConfig{}).apply(flags); err == nil {
+		t.Error("apply() with FINIT_RUN_SEED=x succeeded, want an error")
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"finit/debugger"
+	"finit/engine"
+)
+
+func runDebug(args []string) error {
+	flags := flag.NewFlagSet("debug", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit debug [flags]")
+		flags.PrintDefaults()
+	}
+	scenarioID := flags.String("scenario_id", engine.ScenarioID, "scenario id")
+	seed := flags.Int64("seed", 1, "random seed")
+	if _, err := parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	sim, err := engine.NewSimulator(engine.Config{
+		ScenarioID: *scenarioID,
+		Seed:       *seed,
+	})
+	if err != nil {
+		return err
+	}
+	return debugger.New(sim, os.Stdout).Run(os.Stdin)
+}
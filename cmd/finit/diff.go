@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"finit/analysis"
+	"finit/engine"
+)
+
+var errArtifactsDiffer = errors.New("artifacts differ")
+
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit diff [flags] a.json b.json")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "text", "output format: text or json")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		flags.Usage()
+		return errors.New("diff: expected two artifact paths")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown -format %q (want text or json)", *format)
+	}
+
+	var artifacts [2]engine.Artifact
+	for i, path := range positional {
+		if artifacts[i], err = engine.ReadArtifact(path); err != nil {
+			return err
+		}
+	}
+	diff := analysis.Compare(artifacts[0], artifacts[1])
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(diff); err != nil {
+			return err
+		}
+	} else {
+		writeDiff(os.Stdout, diff)
+	}
+	if !diff.Equal() {
+		return errArtifactsDiffer
+	}
+	return nil
+}
+
+func writeDiff(w io.Writer, diff analysis.Diff) {
+	if diff.Equal() {
+		fmt.Fprintln(w, "artifacts are identical")
+		return
+	}
+	for _, section := range []struct {
+		name        string
+		differences []analysis.Difference
+	}{{"metadata", diff.Metadata}, {"summary", diff.Summary}} {
+		if len(section.differences) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s:\n", section.name)
+		for _, d := range section.differences {
+			fmt.Fprintf(w, "  %-22s %s -> %s\n", d.Field, d.A, d.B)
+		}
+	}
+	if d := diff.Divergence; d != nil {
+		fmt.Fprintf(w, "first divergence: %s %d at tick %d\n", d.Kind, d.Index, d.Tick)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"finit/analysis"
+	"finit/engine"
+	"finit/storage"
+)
+
+func runExperiment(args []string) error {
+	flags := flag.NewFlagSet("experiment", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit experiment [flags] manifest.yaml")
+		flags.PrintDefaults()
+	}
+	out := flags.String("out", "", "output directory (default experiments/<name>); runs go to runs/<run>/seed-<n>.json beside index.json")
+	workers := flags.Int("workers", 0, "concurrent simulations per run (0 uses every CPU)")
+	limits := limitFlags(flags)
+	build := buildFlags(flags)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return errors.New("experiment: expected one manifest path")
+	}
+
+	manifest, err := analysis.ReadManifest(positional[0])
+	if err != nil {
+		return err
+	}
+	dir := *out
+	if dir == "" {
+		dir = filepath.Join("experiments", manifest.Name)
+	}
+	if storage.IsRemote(dir) {
+		return errors.New("experiment output must be a local directory")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cfg := engine.Config{Build: build(), Limits: *limits}
+	index, err := analysis.RunManifest(ctx, manifest, cfg, *workers, func(path string, artifact engine.Artifact) error {
+		dest := filepath.Join(dir, path)
+		if err := storage.WriteArtifact(ctx, dest, artifact); err != nil {
+			return err
+		}
+		fmt.Printf("wrote %s (replay_id=%s)\n", dest, artifact.Metadata.ReplayID)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	dest := filepath.Join(dir, "index.json")
+	file, err := createOutput(dest)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := writeJSON(file, index); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s (%d runs)\n", dest, len(index.Runs))
+	return nil
+}
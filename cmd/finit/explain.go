@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"finit/engine"
+	"finit/explain"
+)
+
+func runExplain(args []string) error {
+	flags := flag.NewFlagSet("explain", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit explain artifact.json TOKEN_ID")
+		flags.PrintDefaults()
+	}
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		flags.Usage()
+		return errors.New("explain: expected an artifact path and a token id")
+	}
+
+	artifact, err := engine.ReadArtifact(positional[0])
+	if err != nil {
+		return err
+	}
+	narrative, err := explain.Token(artifact, positional[1])
+	if err != nil {
+		return err
+	}
+	return explain.Write(os.Stdout, narrative)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"finit/engine"
+	"finit/storage"
+)
+
+var writers = map[string]func(ctx context.Context, dest string, artifact engine.Artifact) error{
+	"json":   storage.WriteArtifact,
+	"sqlite": storage.WriteSQLite,
+	"arrow":  storage.WriteArrow,
+}
+
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit export [flags] artifact.json")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "arrow", "output format: json, sqlite, arrow, or ui")
+	every := flags.Int("every", 4, "snapshots per keyframe for -format ui; the rest are stored as deltas")
+	out := flags.String("o", "", "output path (a directory for arrow)")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *out == "" {
+		flags.Usage()
+		return errors.New("export: expected one artifact path and -o")
+	}
+	write, ok := writers[*format]
+	if *format == "ui" {
+		write, ok = func(ctx context.Context, dest string, artifact engine.Artifact) error {
+			return storage.WriteUI(ctx, dest, artifact, *every)
+		}, true
+	}
+	if !ok {
+		return fmt.Errorf("export: unknown -format %q", *format)
+	}
+
+	artifact, err := engine.ReadArtifact(positional[0])
+	if err != nil {
+		return err
+	}
+	if err := write(context.Background(), *out, artifact); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s (replay_id=%s)\n", *out, artifact.Metadata.ReplayID)
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"finit/engine"
+)
+
+type stdoutCloser struct {
+	io.Writer
+}
+
+func (stdoutCloser) Close() error {
+	return nil
+}
+
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, applyDefaults(flags)
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+type labelFlag map[string]string
+
+func (l labelFlag) String() string {
+	pairs := make([]string, 0, len(l))
+	for key, value := range l {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (l labelFlag) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return fmt.Errorf("label %q is not key=value", pair)
+		}
+		l[key] = strings.TrimSpace(val)
+	}
+	return nil
+}
+
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func labelFlags(flags *flag.FlagSet, usage string) map[string]string {
+	labels := labelFlag{}
+	flags.Var(labels, "label", usage)
+	return labels
+}
+
+type eventFilterFlag struct {
+	filters *[]engine.EventFilter
+	sample  bool
+}
+
+func (f eventFilterFlag) String() string {
+	if f.filters == nil {
+		return ""
+	}
+	parts := make([]string, 0, len(*f.filters))
+	for _, filter := range *f.filters {
+		if f.sample != (filter.Sample > 0) {
+			continue
+		}
+		part := strings.Join(filter.Types, "|")
+		if part == "" {
+			part = "*"
+		}
+		if len(filter.Classes) > 0 {
+			part += ":" + strings.Join(filter.Classes, "|")
+		}
+		if f.sample {
+			part += "=" + strconv.Itoa(filter.Sample)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f eventFilterFlag) Set(value string) error {
+	var filter engine.EventFilter
+	if f.sample {
+		spec, n, ok := strings.Cut(value, "=")
+		sample, err := strconv.Atoi(n)
+		if !ok || err != nil || sample < 1 {
+			return fmt.Errorf("event sample %q is not TYPE[:CLASS]=N with N >= 1", value)
+		}
+		value, filter.Sample = spec, sample
+	}
+	types, classes, _ := strings.Cut(value, ":")
+	if types != "*" && types != "" {
+		filter.Types = strings.Split(types, "|")
+	}
+	if classes != "" {
+		filter.Classes = strings.Split(classes, "|")
+	}
+	if len(filter.Types) == 0 && len(filter.Classes) == 0 {
+		return fmt.Errorf("event filter %q names no event type or class", value)
+	}
+	*f.filters = append(*f.filters, filter)
+	return nil
+}
+
+func eventFilterFlags(flags *flag.FlagSet) *[]engine.EventFilter {
+	filters := &[]engine.EventFilter{}
+	flags.Var(eventFilterFlag{filters: filters}, "drop-events", "leave out events of TYPE[:CLASS], e.g. POSITION_UPDATE or QUEUE:ANON (* for any type, | between alternatives; repeatable)")
+	flags.Var(eventFilterFlag{filters: filters, sample: true}, "sample-events", "keep one in N events of TYPE[:CLASS]=N, e.g. QUEUE:ANON=10 (repeatable)")
+	return filters
+}
+
+func buildFlags(flags *flag.FlagSet) func() *engine.BuildInfo {
+	provenance := flags.Bool("provenance", true, "record this binary's module version, VCS revision, and Go version in the artifact's metadata")
+	hostname := flags.Bool("hostname", false, "also record the host name with -provenance")
+	return func() *engine.BuildInfo {
+		if !*provenance {
+			return nil
+		}
+		info := engine.ReadBuildInfo(*hostname)
+		return &info
+	}
+}
+
+func tokenIDFlags(flags *flag.FlagSet) func() (*engine.TokenIDs, error) {
+	prefix := flags.String("token-prefix", "", "prefix for token ids (default T)")
+	width := flags.Int("token-width", 0, "minimum digits in token ids (default 4)")
+	classPrefixes := flags.String("token-class-prefixes", "", "number each class separately under its own prefix, e.g. PAID=P,FREE=F,ANON=A")
+	uuid := flags.Bool("token-uuid", false, "use UUIDv5 token ids derived from the replay id")
+	return func() (*engine.TokenIDs, error) {
+		if *prefix == "" && *width == 0 && *classPrefixes == "" && !*uuid {
+			return nil, nil
+		}
+		ids := &engine.TokenIDs{Prefix: *prefix, Width: *width, UUID: *uuid}
+		if *classPrefixes != "" {
+			ids.ClassPrefixes = make(map[string]string)
+			for _, pair := range strings.Split(*classPrefixes, ",") {
+				class, p, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || class == "" || p == "" {
+					return nil, fmt.Errorf("-token-class-prefixes: %q is not CLASS=prefix", pair)
+				}
+				ids.ClassPrefixes[strings.ToUpper(class)] = p
+			}
+		}
+		return ids, nil
+	}
+}
+
+func limitFlags(flags *flag.FlagSet) *engine.Limits {
+	limits := &engine.Limits{}
+	flags.IntVar(&limits.MaxTokens, "max-tokens", 0, "fail the run once it creates more than this many tokens (0 for no limit)")
+	flags.IntVar(&limits.MaxEvents, "max-events", 0, "fail the run once it emits more than this many events (0 for no limit)")
+	flags.DurationVar(&limits.MaxWallClock, "max-wall-clock", 0, "fail the run once it has taken longer than this, e.g. 30s (0 for no limit)")
+	return limits
+}
+
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return stdoutCloser{os.Stdout}, nil
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return os.Create(path)
+}
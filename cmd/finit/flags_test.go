@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestLabelFlag(t *testing.T) {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	labels := labelFlags(flags, "")
+	if _, err := parseArgs(flags, []string{"-label", "experiment=baseline", "-label", "sha=abc, hypothesis=faster"}); err != nil {
+		t.Fatalf("parseArgs() error = %v", err)
+	}
+	want := map[string]string{"experiment": "baseline", "sha": "abc", "hypothesis": "faster"}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("labels = %v, want %v", labels, want)
+	}
+
+	for _, bad := range []string{"novalue", "=value"} {
+		if err := (labelFlag{}).Set(bad); err == nil {
+			t.Errorf("Set(%q) error = nil", bad)
+		}
+	}
+}
+
+func TestEventFilterFlags(t *testing.T) {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	filters := eventFilterFlags(flags)
+	args := []string{"-drop-events", "POSITION_UPDATE", "-drop-events", "QUEUE|SCHEDULE:ANON", "-sample-events", "*:FREE=10"}
+	if _, err := parseArgs(flags, args); err != nil {
+		t.Fatalf("parseArgs() error = %v", err)
+	}
+	want := []engine.EventFilter{
+		{Types: []string{"POSITION_UPDATE"}},
+		{Types: []string{"QUEUE", "SCHEDULE"}, Classes: []string{"ANON"}},
+		{Classes: []string{"FREE"}, Sample: 10},
+	}
+	if !reflect.DeepEqual(*filters, want) {
+		t.Errorf("filters = %+v, want %+v", *filters, want)
+	}
+
+	for _, tt := range []struct {
+		value  string
+		sample bool
+	}{{value: "*"}, {value: "QUEUE", sample: true}, {value: "QUEUE=0", sample: true}, {value: "*=5", sample: true}} {
+		if err := (eventFilterFlag{filters: &[]engine.EventFilter{}, sample: tt.sample}).Set(tt.value); err == nil {
+			t.Errorf("Set(%q) with sample %v error = nil", tt.value, tt.sample)
+		}
+	}
+}
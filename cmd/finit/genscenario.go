@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"finit/engine"
+)
+
+func runGenScenario(args []string) error {
+	flags := flag.NewFlagSet("genscenario", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit genscenario [flags]")
+		flags.PrintDefaults()
+	}
+	seed := flags.Int64("seed", 1, "generator seed; the same seed always yields the same scenario")
+	format := flags.String("format", "yaml", "output format: yaml or json")
+	out := flags.String("o", "-", "output file path (- for stdout)")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		flags.Usage()
+		return fmt.Errorf("genscenario: unexpected argument %q", positional[0])
+	}
+	if *format != "yaml" && *format != "json" {
+		return fmt.Errorf("unknown -format %q (want yaml or json)", *format)
+	}
+
+	scenario := engine.RandomScenario(*seed)
+	file, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if *format == "json" {
+		err = writeJSON(file, scenario)
+	} else {
+		enc := yaml.NewEncoder(file)
+		enc.SetIndent(2)
+		if err = enc.Encode(scenario); err == nil {
+			err = enc.Close()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"finit/engine"
+	"finit/render"
+)
+
+func runGraph(args []string) error {
+	flags := flag.NewFlagSet("graph", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit graph [flags]")
+		flags.PrintDefaults()
+	}
+	scenarioID := flags.String("scenario_id", engine.ScenarioID, "scenario id")
+	scenarioFile := flags.String("scenario", "", "load the scenario from this YAML or JSON file instead of -scenario_id")
+	format := flags.String("format", "mermaid", "output format: mermaid or dot")
+	out := flags.String("o", "-", "output file path (- for stdout)")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		flags.Usage()
+		return errors.New("graph: unexpected arguments")
+	}
+
+	var scenario engine.Scenario
+	if *scenarioFile != "" {
+		scenario, err = engine.LoadScenario(*scenarioFile)
+	} else {
+		scenario, err = engine.LookupScenario(*scenarioID)
+	}
+	if err != nil {
+		return err
+	}
+
+	file, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	switch *format {
+	case "mermaid":
+		err = render.Mermaid(file, scenario.Topology())
+	case "dot":
+		err = render.DOT(file, scenario.Topology())
+	default:
+		return fmt.Errorf("graph: unknown format %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"finit/engine"
+)
+
+func runInspect(args []string) error {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit inspect [flags] artifact.json|dir ...")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "text", "output format: text or json")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		flags.Usage()
+		return errors.New("inspect: expected at least one artifact path or directory")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown -format %q (want text or json)", *format)
+	}
+
+	var paths []string
+	for _, path := range positional {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			paths = append(paths, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return err
+		}
+		paths = append(paths, matches...)
+	}
+
+	var inspections []engine.Inspection
+	for _, path := range paths {
+		inspection, err := engine.Inspect(path)
+		if err != nil {
+			return err
+		}
+		inspections = append(inspections, inspection)
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(inspections)
+	}
+	for i, inspection := range inspections {
+		if i > 0 {
+			fmt.Println()
+		}
+		writeInspection(os.Stdout, inspection)
+	}
+	return nil
+}
+
+func writeInspection(w io.Writer, in engine.Inspection) {
+	m := in.Metadata
+	schema := m.SnapshotSchema
+	if schema == "" {
+		schema = engine.SnapshotSchemaFull
+	}
+	fmt.Fprintln(w, in.Path)
+	fmt.Fprintf(w, "  replay_id  %s\n", m.ReplayID)
+	fmt.Fprintf(w, "  scenario   %s, seed %d\n", m.ScenarioID, m.Seed)
+	fmt.Fprintf(w, "  engine     %s, snapshot schema %s\n", m.EngineVersion, schema)
+	if b := m.Build; b != nil {
+		build := b.Version
+		if b.Revision != "" {
+			build += " @ " + b.Revision
+		}
+		if b.Modified {
+			build += " (modified)"
+		}
+		build += ", " + b.GoVersion + " " + b.Platform
+		if b.Hostname != "" {
+			build += " on " + b.Hostname
+		}
+		fmt.Fprintf(w, "  build      %s\n", strings.TrimPrefix(build, ", "))
+	}
+	if len(m.Labels) > 0 {
+		labels := make([]string, 0, len(m.Labels))
+		for key, value := range m.Labels {
+			labels = append(labels, key+"="+value)
+		}
+		sort.Strings(labels)
+		fmt.Fprintf(w, "  labels     %s\n", strings.Join(labels, ", "))
+	}
+	fmt.Fprintf(w, "  duration   %d ticks x %dms = %s\n", m.TickCount, m.TickDurationMs, time.Duration(m.TotalDurationMs)*time.Millisecond)
+	size := fmt.Sprintf("%d bytes", in.Size)
+	if in.Chunks > 0 {
+		size += fmt.Sprintf(" across %d chunks", in.Chunks)
+	}
+	fmt.Fprintf(w, "  size       %s\n", size)
+	fmt.Fprintf(w, "  snapshots  %d (ticks %d-%d)\n", in.Snapshots, in.FirstTick, in.LastTick)
+
+	types := make([]string, 0, len(in.EventTypes))
+	for eventType := range in.EventTypes {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+	counts := make([]string, len(types))
+	for i, eventType := range types {
+		counts[i] = fmt.Sprintf("%s %d", eventType, in.EventTypes[eventType])
+	}
+	fmt.Fprintf(w, "  events     %d (%s)\n", in.Events, strings.Join(counts, ", "))
+	fmt.Fprintf(w, "  tokens     %d\n", in.Tokens)
+}
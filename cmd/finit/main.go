@@ -1,41 +1,351 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
-	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"finit/engine"
+	"finit/sink"
+	"finit/storage"
 )
 
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]command{
+	"batch":       {"run many seeds in parallel, one artifact per seed", runBatch},
+	"bisect":      {"find the first tick and field where two runs of one replay diverge", runBisect},
+	"compare":     {"summarize how one run differs from another, for before/after writeups", runCompare},
+	"debug":       {"step through a live simulation with breakpoints", runDebug},
+	"diff":        {"compare two artifacts and report where they diverge", runDiff},
+	"experiment":  {"run every named run in a manifest and index the artifacts", runExperiment},
+	"explain":     {"explain the decisions made for one token", runExplain},
+	"export":      {"convert an artifact to Arrow, SQLite, or JSON", runExport},
+	"genscenario": {"emit a random but valid scenario for robustness testing", runGenScenario},
+	"graph":       {"print the scenario topology as Mermaid or DOT", runGraph},
+	"inspect":     {"print metadata and counts for artifacts without loading them", runInspect},
+	"merge":       {"combine run summaries into an experiment file", runMerge},
+	"optimize":    {"search scenario parameters for the cheapest configuration meeting SLOs", runOptimize},
+	"query":       {"filter events or token states", runQuery},
+	"recover":     {"rebuild an artifact from a crashed run's -append-log", runRecover},
+	"render":      {"draw an artifact's timeline as SVG or ASCII", runRender},
+	"replay":      {"re-run an artifact's configuration and check it reproduces", runReplay},
+	"report":      {"write a self-contained HTML report", runReport},
+	"run":         {"run a simulation and write its artifact (the default)", runSimulation},
+	"serve":       {"serve simulation runs over HTTP", runServe},
+	"sensitivity": {"sweep a scenario parameter and chart a metric against it", runSensitivity},
+	"slice":       {"cut a tick range out of an artifact as a smaller, valid artifact", runSlice},
+	"stats":       {"print summary statistics for an artifact", runStats},
+	"trace":       {"send token lifecycles to an OTLP endpoint as traces", runTrace},
+	"tui":         {"play back an artifact or live run in the terminal", runTUI},
+	"validate":    {"check an artifact for structural problems", runValidate},
+	"verify":      {"check an artifact's Ed25519 signature against a public key", runVerify},
+	"watch":       {"re-run a scenario file whenever it changes", runWatch},
+	"whatif":      {"replay an artifact's arrivals under a different policy", runWhatIf},
+}
+
 func main() {
-	scenarioID := flag.String("scenario_id", engine.ScenarioID, "scenario id")
-	seed := flag.Int64("seed", 1, "random seed")
-	out := flag.String("out", "artifacts/run.json", "output file path")
-	flag.Parse()
-
-	artifact, err := engine.Run(engine.Config{
-		ScenarioID: *scenarioID,
-		Seed:       *seed,
-	})
-	if err != nil {
+	args := os.Args[1:]
+	name := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		if len(args) == 0 {
+			usage(os.Stdout)
+			return
+		}
+		name, args = args[0], []string{"-h"}
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "finit: unknown command %q\n\n", name)
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	if err := checkConfigSections(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(exitInvalidConfig)
 	}
+	if err := cmd.run(args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitCode(err))
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: finit <command> [flags] [args]")
+	fmt.Fprintln(w, "       finit [run flags]    (same as finit run)")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	names := make([]string, 0, len(commands))
+	width := 0
+	for name := range commands {
+		names = append(names, name)
+		width = max(width, len(name))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, name, commands[name].summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "run finit help <command> or finit <command> -h for a command's flags")
+}
 
-	outPath := *out
-	if dir := filepath.Dir(outPath); dir != "." {
-		if err := os.MkdirAll(dir, 0o755); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+const (
+	exitFailure         = 1
+	exitUnknownScenario = 3
+	exitInvalidConfig   = 4
+	exitDecode          = 5
+	exitLimit           = 6
+	exitCanceled        = 130
+)
+
+func exitCode(err error) int {
+	var decodeErr *engine.ArtifactDecodeError
+	var limitErr *engine.LimitError
+	switch {
+	case errors.Is(err, engine.ErrUnknownScenario):
+		return exitUnknownScenario
+	case errors.Is(err, engine.ErrInvalidConfig):
+		return exitInvalidConfig
+	case errors.As(err, &decodeErr):
+		return exitDecode
+	case errors.As(err, &limitErr):
+		return exitLimit
+	case errors.Is(err, context.Canceled):
+		return exitCanceled
+	default:
+		return exitFailure
+	}
+}
+
+func runSimulation(args []string) error {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit run [flags]")
+		flags.PrintDefaults()
+	}
+	scenarioID := flags.String("scenario_id", engine.ScenarioID, "scenario id")
+	scenarioFile := flags.String("scenario", "", "load the scenario from this YAML or JSON file instead of -scenario_id")
+	seed := flags.Int64("seed", 1, "random seed")
+	out := flags.String("out", "artifacts/run.json", "output file path or s3://bucket/key")
+	appendLog := flags.String("append-log", "", "append events and snapshots to this fsync'd log while running, so finit recover can salvage a crashed run; removed once the artifact is written")
+	index := flags.Bool("index", false, "also write a random-access index (<out>.idx) for seeking by tick or token")
+	chunkTicks := flags.Int("chunk-ticks", 0, "split the JSON artifact into chunk files of this many ticks, with -out as the manifest")
+	snapshots := flags.String("snapshots", engine.SnapshotSchemaActive, "snapshot shape: active (each token once it is terminal) or full (every token in every snapshot)")
+	snapshotInterval := flags.Int("snapshot-interval", 1, "record a snapshot every N ticks (the last tick is always recorded)")
+	eta := flags.Bool("eta", false, "estimate a wait (eta_ms) for every queued token in each snapshot")
+	epochFlag := flags.String("epoch", "", "anchor tick 0 at this RFC 3339 time and add a wall-clock time to every snapshot and event")
+	traceIDs := flags.Bool("trace-ids", false, "give every token a W3C trace id (trace_id) in snapshots and events, matching finit trace")
+	classBreakdown := flags.Bool("class-breakdown", false, "break each snapshot's queue length and service capacity down by class (stage classes)")
+	format := flags.String("format", "json", "output format: json, sqlite, or arrow (-out is a directory)")
+	labels := labelFlags(flags, "tag the artifact's metadata with key=value (repeatable)")
+	limits := limitFlags(flags)
+	eventFilters := eventFilterFlags(flags)
+	keepFirst := flags.Int("keep-first", 0, "keep only each token's first N events, its last -keep-last events, and its terminal event (both 0 keeps every event)")
+	keepLast := flags.Int("keep-last", 0, "keep each token's last N events alongside -keep-first")
+	build := buildFlags(flags)
+	tokenIDs := tokenIDFlags(flags)
+	signKey := flags.String("sign", "", "sign the artifact with this Ed25519 private key (PEM, PKCS #8); check it with finit verify")
+	dryRun := flags.Bool("dry-run", false, "print the resolved scenario plan as JSON and exit without simulating")
+	progress := flags.Bool("progress", false, "draw a progress bar with estimated time remaining on stderr")
+	logEvents := flags.Bool("log-events", false, "log every simulation event to stderr as JSON")
+	logLevel := flags.String("log-level", "info", "level for logged events: debug, info, warn, error")
+	logSample := flags.Int("log-sample", 1, "log one in every N events")
+	statsdAddr := flags.String("statsd", "", "send per-tick metrics to this StatsD/DogStatsD address (host:port)")
+	statsdTags := flags.String("statsd-tags", "", "comma-separated tags added to every StatsD metric")
+	natsAddr := flags.String("nats", "", "publish every event to this NATS server (host:port)")
+	natsSubject := flags.String("nats-subject", "finit.events", "subject prefix for published events")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		flags.Usage()
+		return fmt.Errorf("run: unexpected argument %q", positional[0])
+	}
+
+	if *snapshots != engine.SnapshotSchemaActive && *snapshots != engine.SnapshotSchemaFull {
+		return fmt.Errorf("unknown -snapshots %q (want %s or %s)", *snapshots, engine.SnapshotSchemaActive, engine.SnapshotSchemaFull)
+	}
+	write, ok := writers[*format]
+	if !ok {
+		return fmt.Errorf("unknown -format %q (want json, sqlite, or arrow)", *format)
+	}
+	if *index && (*format != "json" || storage.IsRemote(*out) || *chunkTicks > 0) {
+		return errors.New("-index requires a single local JSON artifact")
+	}
+	if *signKey != "" && (*format != "json" || *chunkTicks > 0) {
+		return errors.New("-sign requires a single JSON artifact")
+	}
+	if *chunkTicks > 0 {
+		if *format != "json" {
+			return errors.New("-chunk-ticks requires -format json")
+		}
+		write = func(ctx context.Context, dest string, artifact engine.Artifact) error {
+			return storage.WriteChunked(ctx, dest, artifact, *chunkTicks)
 		}
 	}
 
-	if err := engine.WriteArtifact(outPath, artifact); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	var retention *engine.EventRetention
+	if *keepFirst != 0 || *keepLast != 0 {
+		retention = &engine.EventRetention{First: *keepFirst, Last: *keepLast}
+	}
+	var epoch *time.Time
+	if *epochFlag != "" {
+		t, err := time.Parse(time.RFC3339, *epochFlag)
+		if err != nil {
+			return fmt.Errorf("-epoch: %w", err)
+		}
+		epoch = &t
+	}
+
+	ids, err := tokenIDs()
+	if err != nil {
+		return err
+	}
+
+	cfg := engine.Config{
+		ScenarioID:       *scenarioID,
+		Seed:             *seed,
+		SnapshotInterval: *snapshotInterval,
+		ETA:              *eta,
+		TraceIDs:         *traceIDs,
+		ClassBreakdown:   *classBreakdown,
+		TokenIDs:         ids,
+		Epoch:            epoch,
+		EventFilters:     *eventFilters,
+		EventRetention:   retention,
+		Labels:           labels,
+		Build:            build(),
+		Limits:           *limits,
+	}
+	if *scenarioFile != "" {
+		scenario, err := engine.LoadScenario(*scenarioFile)
+		if err != nil {
+			return err
+		}
+		cfg.Scenario = &scenario
+	}
+	if *dryRun {
+		plan, err := engine.NewPlan(cfg)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+	if *progress {
+		cfg.Progress = newProgressBar(os.Stderr).update
+	}
+	if *logEvents {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+			return fmt.Errorf("invalid -log-level: %w", err)
+		}
+		logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+		cfg.Observers = append(cfg.Observers, sink.NewSlog(logger, sink.SlogOptions{
+			Level:       level,
+			SampleEvery: *logSample,
+		}))
+	}
+
+	var tickMetrics *sink.TickMetrics
+	if *statsdAddr != "" {
+		var tags []string
+		if *statsdTags != "" {
+			tags = strings.Split(*statsdTags, ",")
+		}
+		statsd, err := sink.DialStatsD(*statsdAddr, "finit", tags...)
+		if err != nil {
+			return err
+		}
+		defer statsd.Close()
+		tickMetrics = sink.NewTickMetrics(statsd)
+		cfg.Observers = append(cfg.Observers, tickMetrics)
+	}
+
+	var publisher *sink.NATS
+	if *natsAddr != "" {
+		publisher, err = sink.DialNATS(*natsAddr, *natsSubject)
+		if err != nil {
+			return err
+		}
+		cfg.Observers = append(cfg.Observers, publisher)
+	}
+
+	var runLog *storage.AppendLog
+	if *appendLog != "" {
+		runLog, err = storage.CreateAppendLog(*appendLog)
+		if err != nil {
+			return err
+		}
+		defer runLog.Close()
+		cfg.Observers = append(cfg.Observers, runLog)
+	}
+
+	artifact, err := engine.Run(cfg)
+	if err != nil {
+		return err
+	}
+	if runLog != nil {
+		if err := runLog.Finish(artifact.Metadata); err != nil {
+			return fmt.Errorf("-append-log: %w", err)
+		}
+	}
+	if *snapshots == engine.SnapshotSchemaFull {
+		artifact = engine.ExpandSnapshots(artifact)
+	}
+	if *signKey != "" {
+		key, err := readPrivateKey(*signKey)
+		if err != nil {
+			return err
+		}
+		if artifact, err = engine.SignArtifact(artifact, key); err != nil {
+			return err
+		}
+	}
+	if tickMetrics != nil && tickMetrics.Err() != nil {
+		fmt.Fprintln(os.Stderr, tickMetrics.Err())
+	}
+	if publisher != nil {
+		if err := publisher.Close(); err != nil {
+			return err
+		}
+	}
+
+	if err := write(context.Background(), *out, artifact); err != nil {
+		return err
+	}
+	if runLog != nil {
+		if err := runLog.Close(); err != nil {
+			return fmt.Errorf("-append-log: %w", err)
+		}
+		if err := os.Remove(*appendLog); err != nil {
+			return err
+		}
+	}
+
+	if *index {
+		if _, err := engine.WriteIndex(*out); err != nil {
+			return err
+		}
 	}
 
-	fmt.Printf("wrote %s (replay_id=%s)\n", outPath, artifact.Metadata.ReplayID)
+	fmt.Printf("wrote %s (replay_id=%s)\n", *out, artifact.Metadata.ReplayID)
+	return nil
 }
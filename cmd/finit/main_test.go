@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUsage(t *testing.T) {
+	var b strings.Builder
+	usage(&b)
+	column := -1
+	for _, line := range strings.Split(b.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.HasPrefix(line, "  ") {
+			continue
+		}
+		if _, ok := commands[fields[0]]; !ok {
+			continue
+		}
+		at := strings.Index(line, commands[fields[0]].summary)
+		if column < 0 {
+			column = at
+		}
+		if at != column || at <= len("  ")+len(fields[0]) {
+			t.Errorf("usage line %q: summary at column %d, want %d after the name", line, at, column)
+		}
+	}
+	if column < 0 {
+		t.Fatalf("usage() listed no commands:\n%s", b.String())
+	}
+}
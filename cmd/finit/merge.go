@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"finit/analysis"
+	"finit/engine"
+)
+
+func runMerge(args []string) error {
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit merge [flags] run1.json run2.json ...")
+		flags.PrintDefaults()
+	}
+	out := flags.String("o", "experiment.json", "output file path (- for stdout)")
+	confidence := flags.Float64("confidence", 0.95, "confidence level for the across-run intervals (0 omits them)")
+	labels := labelFlags(flags, "only include runs labeled key=value (repeatable; all must match)")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		flags.Usage()
+		return errors.New("merge: expected at least one artifact path")
+	}
+
+	var experiment analysis.Experiment
+	for _, path := range positional {
+		artifact, err := engine.ReadArtifact(path)
+		if err != nil {
+			return err
+		}
+		if !analysis.HasLabels(artifact.Metadata, labels) {
+			continue
+		}
+		if err := experiment.Add(artifact); err != nil {
+			return fmt.Errorf("merge %s: %w", path, err)
+		}
+	}
+
+	if len(experiment.Runs) == 0 {
+		return errors.New("merge: no runs match the given labels")
+	}
+	if *confidence != 0 {
+		if err := experiment.Aggregate(*confidence); err != nil {
+			return fmt.Errorf("merge: %w", err)
+		}
+	}
+
+	file, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := analysis.WriteExperiment(file, experiment); err != nil {
+		return err
+	}
+	return file.Close()
+}
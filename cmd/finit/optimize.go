@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"finit/analysis"
+	"finit/engine"
+)
+
+func runOptimize(args []string) error {
+	flags := flag.NewFlagSet("optimize", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit optimize -param name=from..to -slo 'metric<target' [flags]")
+		flags.PrintDefaults()
+	}
+	scenarioID := flags.String("scenario_id", engine.ScenarioID, "scenario id")
+	scenarioFile := flags.String("scenario", "", "load the scenario from this YAML or JSON file instead of -scenario_id")
+	var params, slos listFlag
+	flags.Var(&params, "param", "scenario parameter to search, as for sensitivity: name=from..to[:step] or name=v1,v2,... (repeatable)")
+	flags.Var(&slos, "slo", "objective every chosen configuration must meet, e.g. 'p95_latency_paid<2000' or 'rejection_rate<=0.01' (repeatable)")
+	costList := flags.String("cost", "", "cost per unit of each parameter, e.g. capacity=10,reject_threshold=0.5 (default 1 for every parameter)")
+	search := flags.String("search", analysis.SearchGrid, "search strategy: grid (every combination) or hill (climb from the first value of each parameter)")
+	seedList := flags.String("seeds", "1-5", "seeds to run for every configuration: a range (1-100), a list (1,5,9), or both (1-10,20)")
+	workers := flags.Int("workers", 0, "concurrent simulations (0 uses every CPU)")
+	format := flags.String("format", "text", "output format: text or json")
+	out := flags.String("o", "-", "output file path (- for stdout)")
+	limits := limitFlags(flags)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		flags.Usage()
+		return fmt.Errorf("optimize: unexpected argument %q", positional[0])
+	}
+	if len(params) == 0 || len(slos) == 0 {
+		flags.Usage()
+		return errors.New("optimize: at least one -param and one -slo are required")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown -format %q (want text or json)", *format)
+	}
+
+	var parameters []analysis.Parameter
+	for _, spec := range params {
+		parameter, err := analysis.ParseParameter(spec)
+		if err != nil {
+			return err
+		}
+		parameters = append(parameters, parameter)
+	}
+	var objectives []analysis.Objective
+	for _, spec := range slos {
+		objective, err := analysis.ParseObjective(spec)
+		if err != nil {
+			return err
+		}
+		objectives = append(objectives, objective)
+	}
+	var cost map[string]float64
+	if *costList != "" {
+		if cost, err = analysis.ParseCost(*costList); err != nil {
+			return err
+		}
+	}
+	seeds, err := engine.ParseSeeds(*seedList)
+	if err != nil {
+		return err
+	}
+
+	cfg := engine.Config{ScenarioID: *scenarioID, Limits: *limits}
+	if *scenarioFile != "" {
+		scenario, err := engine.LoadScenario(*scenarioFile)
+		if err != nil {
+			return err
+		}
+		cfg.Scenario = &scenario
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	result, err := analysis.Optimize(ctx, cfg, *search, parameters, objectives, cost, seeds, *workers)
+	if err != nil {
+		return err
+	}
+
+	file, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if *format == "json" {
+		err = writeJSON(file, result)
+	} else {
+		err = writeOptimization(file, result)
+	}
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func writeOptimization(w io.Writer, result analysis.Optimization) error {
+	goals := make([]string, len(result.Objectives))
+	for i, objective := range result.Objectives {
+		goals[i] = objective.String()
+	}
+	fmt.Fprintf(w, "%s search over %d seeds: %d configurations evaluated against %s\n",
+		result.Search, len(result.Seeds), result.Evaluated, strings.Join(goals, ", "))
+	if result.Best == nil {
+		fmt.Fprintln(w, "no configuration met every objective")
+	} else {
+		fmt.Fprintf(w, "cheapest configuration meeting every objective: %s (cost %g)\n", candidateValues(*result.Best), result.Best.Cost)
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "configuration\tcost\tattainment\t%s\t\n", strings.Join(goals, "\t"))
+	for _, candidate := range result.Frontier {
+		metrics := make([]string, len(candidate.Metrics))
+		for i, v := range candidate.Metrics {
+			metrics[i] = fmt.Sprintf("%.4g", v)
+		}
+		fmt.Fprintf(tw, "%s\t%g\t%.0f%%\t%s\t\n", candidateValues(candidate), candidate.Cost, 100*candidate.Attainment, strings.Join(metrics, "\t"))
+	}
+	return tw.Flush()
+}
+
+func candidateValues(candidate analysis.Candidate) string {
+	pairs := make([]string, 0, len(candidate.Values))
+	for name, value := range candidate.Values {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", name, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"finit/engine"
+)
+
+const progressWidth = 30
+
+type progressBar struct {
+	out   io.Writer
+	meter *engine.ProgressMeter
+	drawn time.Time
+}
+
+func newProgressBar(out io.Writer) *progressBar {
+	return &progressBar{out: out, meter: engine.NewProgressMeter()}
+}
+
+func (p *progressBar) update(tick, totalTicks int) {
+	if tick < totalTicks && time.Since(p.drawn) < 100*time.Millisecond {
+		return
+	}
+	p.drawn = time.Now()
+	filled := progressWidth * tick / max(totalTicks, 1)
+	fmt.Fprintf(p.out, "\r[%s%s] %d/%d ticks  %.0f ticks/s  eta %s ",
+		strings.Repeat("#", filled), strings.Repeat(".", progressWidth-filled),
+		tick, totalTicks, p.meter.TicksPerSecond(tick), p.meter.Remaining(tick, totalTicks).Round(time.Millisecond))
+	if tick >= totalTicks {
+		fmt.Fprintln(p.out)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"finit/engine"
+	"finit/query"
+)
+
+func runQuery(args []string) error {
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit query [flags] artifact.json")
+		flags.PrintDefaults()
+		fmt.Fprintf(flags.Output(), "\nevent fields: %s\ntoken fields: %s\n",
+			strings.Join(query.EventColumns, ", "), strings.Join(query.TokenColumns, ", "))
+	}
+	from := flags.String("from", query.FromEvents, "rows to query: events or tokens (per-tick token states)")
+	where := flags.String("where", "", "filter expression, e.g. 'type==REJECT && class==ANON'")
+	selectFields := flags.String("select", "", "comma-separated fields to output (default all)")
+	format := flags.String("format", "json", "output format: json or csv")
+	out := flags.String("o", "-", "output file path (- for stdout)")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return errors.New("query: expected one artifact path")
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("query: unknown -format %q (want json or csv)", *format)
+	}
+
+	artifact, err := engine.ReadArtifact(positional[0])
+	if err != nil {
+		return err
+	}
+	table, err := query.From(artifact, *from)
+	if err != nil {
+		return err
+	}
+	expr, err := query.Parse(*where, table.Columns)
+	if err != nil {
+		return err
+	}
+	var columns []string
+	if *selectFields != "" {
+		columns = strings.Split(*selectFields, ",")
+	}
+	table, err = table.Where(expr).Select(columns)
+	if err != nil {
+		return err
+	}
+
+	file, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if *format == "csv" {
+		err = table.WriteCSV(file)
+	} else {
+		err = table.WriteJSON(file)
+	}
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
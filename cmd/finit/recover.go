@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"finit/storage"
+)
+
+func runRecover(args []string) error {
+	flags := flag.NewFlagSet("recover", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit recover [flags] partial.log")
+		flags.PrintDefaults()
+	}
+	out := flags.String("out", "", "write the recovered artifact here (default: the log path with a .json extension)")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return errors.New("recover: expected exactly one log path")
+	}
+	path := positional[0]
+	if *out == "" {
+		*out = strings.TrimSuffix(path, filepath.Ext(path)) + ".json"
+	}
+	if *out == path {
+		return errors.New("recover: -out must not overwrite the log")
+	}
+
+	recovered, err := storage.RecoverLog(path)
+	if err != nil {
+		return err
+	}
+	artifact := recovered.Artifact
+	if err := storage.WriteArtifact(context.Background(), *out, artifact); err != nil {
+		return err
+	}
+	state := "complete run"
+	if !recovered.Complete {
+		state = fmt.Sprintf("partial run, stopped after tick %d of %d", recovered.LastTick, artifact.Metadata.TickCount)
+	}
+	fmt.Printf("wrote %s from %s (%s: %d snapshots, %d events)\n", *out, path, state, len(artifact.Snapshots), len(artifact.Events))
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"finit/engine"
+	"finit/render"
+)
+
+func runRender(args []string) error {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit render [flags] artifact.json")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "svg", "output format: svg or ascii")
+	width := flags.Int("width", 120, "ascii timeline width in columns")
+	out := flags.String("o", "-", "output file path (- for stdout)")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return errors.New("render: expected one artifact path")
+	}
+
+	artifact, err := engine.ReadArtifact(positional[0])
+	if err != nil {
+		return err
+	}
+
+	file, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	switch *format {
+	case "svg":
+		err = render.SVG(file, artifact)
+	case "ascii":
+		err = render.ASCII(file, artifact, *width)
+	default:
+		return fmt.Errorf("render: unknown format %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
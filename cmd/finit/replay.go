@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"finit/analysis"
+	"finit/engine"
+	"finit/storage"
+)
+
+func runReplay(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit replay [flags] artifact.json")
+		flags.PrintDefaults()
+	}
+	out := flags.String("o", "", "also write the replayed artifact to this path")
+	toTick := flags.Int("to-tick", -1, "replay only through this tick and check that prefix of the artifact")
+	dumpState := flags.Bool("dump-state", false, "print the simulator's internal state after -to-tick as JSON: queue order, service, retries, RNG positions")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return errors.New("replay: expected one artifact path")
+	}
+
+	original, err := engine.ReadArtifact(positional[0])
+	if err != nil {
+		return err
+	}
+	metadata := original.Metadata
+	if metadata.EngineVersion != engine.EngineVersion {
+		return fmt.Errorf("replay: artifact was produced by engine %s, this is engine %s", metadata.EngineVersion, engine.EngineVersion)
+	}
+	if metadata.Slice != nil {
+		return fmt.Errorf("replay: %s holds only ticks %d-%d of its run; replay the original artifact", positional[0], metadata.Slice.From, metadata.Slice.To)
+	}
+	if *dumpState && *toTick < 0 {
+		return errors.New("replay: -dump-state needs -to-tick")
+	}
+	if *toTick >= metadata.TickCount {
+		return fmt.Errorf("replay: -to-tick %d is past the last tick %d", *toTick, metadata.TickCount-1)
+	}
+
+	sim, err := engine.NewSimulator(engine.Config{
+		ScenarioID:       metadata.ScenarioID,
+		Scenario:         metadata.Scenario,
+		Seed:             metadata.Seed,
+		SnapshotInterval: metadata.SnapshotInterval,
+		ETA:              metadata.ETA,
+		TraceIDs:         metadata.TraceIDs,
+		ClassBreakdown:   metadata.ClassBreakdown,
+		TokenIDs:         metadata.TokenIDs,
+		Epoch:            metadata.Epoch,
+		EventFilters:     metadata.EventFilters,
+		EventRetention:   metadata.EventRetention,
+		Reasons:          metadata.Reasons,
+		Labels:           metadata.Labels,
+	})
+	if err != nil {
+		return err
+	}
+	defer sim.Close()
+	for (*toTick < 0 || sim.Tick() <= *toTick) && sim.Step() {
+	}
+	if err := sim.Err(); err != nil {
+		return err
+	}
+	replayed, err := sim.Artifact()
+	if err != nil {
+		return err
+	}
+	if *toTick >= 0 {
+		original, replayed = throughTick(original, *toTick), throughTick(replayed, *toTick)
+		if metadata.EventRetention != nil {
+			original.Events, replayed.Events = nil, nil
+		}
+	}
+	if metadata.FullSnapshots() {
+		replayed = engine.ExpandSnapshots(replayed)
+	}
+	if *out != "" {
+		if err := storage.WriteArtifact(context.Background(), *out, replayed); err != nil {
+			return err
+		}
+	}
+
+	diff := analysis.Compare(original, replayed)
+	if !diff.Equal() {
+		writeDiff(os.Stdout, diff)
+		return fmt.Errorf("replay %s: %w", metadata.ReplayID, errArtifactsDiffer)
+	}
+	if *dumpState {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sim.DumpState())
+	}
+	through := ""
+	if *toTick >= 0 {
+		through = fmt.Sprintf(" through tick %d", *toTick)
+	}
+	fmt.Printf("replay %s reproduces %s%s (%d snapshots, %d events)\n", metadata.ReplayID, positional[0], through, len(replayed.Snapshots), len(replayed.Events))
+	return nil
+}
+
+func throughTick(artifact engine.Artifact, tick int) engine.Artifact {
+	snapshots := artifact.Snapshots
+	for len(snapshots) > 0 && snapshots[len(snapshots)-1].Tick > tick {
+		snapshots = snapshots[:len(snapshots)-1]
+	}
+	events := artifact.Events
+	for len(events) > 0 && events[len(events)-1].Tick > tick {
+		events = events[:len(events)-1]
+	}
+	artifact.Snapshots, artifact.Events = snapshots, events
+	return artifact
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"finit/engine"
+	"finit/report"
+)
+
+func runReport(args []string) error {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit report [flags] artifact.json")
+		flags.PrintDefaults()
+	}
+	out := flags.String("o", "report.html", "output file path (- for stdout)")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return errors.New("report: expected one artifact path")
+	}
+
+	artifact, err := engine.ReadArtifact(positional[0])
+	if err != nil {
+		return err
+	}
+
+	file, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := report.Write(file, artifact); err != nil {
+		return err
+	}
+	return file.Close()
+}
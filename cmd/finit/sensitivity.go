@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"finit/analysis"
+	"finit/engine"
+)
+
+func runSensitivity(args []string) error {
+	flags := flag.NewFlagSet("sensitivity", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit sensitivity -param name=from..to [flags]")
+		flags.PrintDefaults()
+	}
+	scenarioID := flags.String("scenario_id", engine.ScenarioID, "scenario id")
+	scenarioFile := flags.String("scenario", "", "load the scenario from this YAML or JSON file instead of -scenario_id")
+	param := flags.String("param", "", "scenario parameter to sweep: name=from..to, name=from..to:step, or name=v1,v2,...")
+	metricName := flags.String("metric", "p95_wait_paid", "metric to measure: <stat>_wait or <stat>_latency (stat mean, p50, p95, p99, max), rejection_rate, throughput, utilization, max_queue or net (the cost ledger's net), optionally suffixed _<class>")
+	seedList := flags.String("seeds", "1-10", "seeds to run at every parameter value: a range (1-100), a list (1,5,9), or both (1-10,20)")
+	workers := flags.Int("workers", 0, "concurrent simulations (0 uses every CPU)")
+	confidence := flags.Float64("confidence", 0.95, "confidence level for each point's half-width")
+	format := flags.String("format", "json", "output format: json or csv")
+	out := flags.String("o", "-", "output file path (- for stdout)")
+	limits := limitFlags(flags)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		flags.Usage()
+		return fmt.Errorf("sensitivity: unexpected argument %q", positional[0])
+	}
+	if *param == "" {
+		flags.Usage()
+		return errors.New("sensitivity: -param is required")
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("unknown -format %q (want json or csv)", *format)
+	}
+	if *confidence <= 0 || *confidence >= 1 {
+		return fmt.Errorf("-confidence must be in (0, 1), got %v", *confidence)
+	}
+	parameter, err := analysis.ParseParameter(*param)
+	if err != nil {
+		return err
+	}
+	metric, err := analysis.ParseMetric(*metricName)
+	if err != nil {
+		return err
+	}
+	seeds, err := engine.ParseSeeds(*seedList)
+	if err != nil {
+		return err
+	}
+
+	cfg := engine.Config{ScenarioID: *scenarioID, Limits: *limits}
+	if *scenarioFile != "" {
+		scenario, err := engine.LoadScenario(*scenarioFile)
+		if err != nil {
+			return err
+		}
+		cfg.Scenario = &scenario
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	sensitivity, err := analysis.Sweep(ctx, cfg, parameter, metric, seeds, *workers, *confidence)
+	if err != nil {
+		return err
+	}
+
+	file, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if *format == "csv" {
+		err = sensitivity.WriteCSV(file)
+	} else {
+		err = writeJSON(file, sensitivity)
+	}
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
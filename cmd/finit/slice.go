@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"finit/engine"
+	"finit/storage"
+)
+
+func runSlice(args []string) error {
+	flags := flag.NewFlagSet("slice", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit slice [flags] artifact.json")
+		flags.PrintDefaults()
+	}
+	from := flags.Int("from", 0, "first tick of the slice; it starts at the last snapshot at or before this tick")
+	to := flags.Int("to", -1, "last tick of the slice (default: the artifact's last tick)")
+	out := flags.String("o", "slice.json", "output file path or s3://bucket/key")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return errors.New("slice: expected one artifact path")
+	}
+
+	artifact, err := engine.ReadArtifact(positional[0])
+	if err != nil {
+		return err
+	}
+	if *to < 0 {
+		*to = artifact.Metadata.TickCount - 1
+	}
+	slice, err := engine.SliceArtifact(artifact, *from, *to)
+	if err != nil {
+		return fmt.Errorf("slice: %w", err)
+	}
+	if err := storage.WriteArtifact(context.Background(), *out, slice); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s (ticks %d-%d: %d snapshots, %d events)\n", *out, slice.Metadata.Slice.From, slice.Metadata.Slice.To, len(slice.Snapshots), len(slice.Events))
+	return nil
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+
+	"finit/analysis"
+	"finit/engine"
+)
+
+func runStats(args []string) error {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit stats [flags] artifact.json")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "text", "output format: text or json")
+	out := flags.String("o", "-", "output file path (- for stdout)")
+	mmc := flags.Bool("mmc", false, "compare measured waits against M/M/c predictions fitted to the run")
+	window := flags.Int("window", 30, "ticks per window for -mmc (0 for the whole run only) and -rolling")
+	tolerance := flags.Float64("tolerance", 0.5, "relative wait deviation from M/M/c that -mmc flags")
+	fairness := flags.Bool("fairness", false, "show per-class fairness: Jain's index, capacity share vs weight, and longest starvation")
+	inversions := flags.Bool("inversions", false, "list priority inversions: higher-priority requests left waiting while lower-priority ones started")
+	shadow := flags.Bool("shadow", false, "compare a shadow policy's choices with the scheduling decisions actually taken")
+	rolling := flags.Bool("rolling", false, "show arrival rate, throughput and utilization averaged over a trailing -window of ticks")
+	heatmap := flags.Bool("heatmap", false, "show queue length by class and utilization by stage over time as heatmaps")
+	bucket := flags.Int("bucket", 1, "ticks averaged into each -heatmap column")
+	queueBuckets := flags.String("queue-buckets", "", "queue length histogram bucket upper bounds, e.g. 0,1,2,5,10 (default 0,1,2,5,10,20,50,100)")
+	waitBuckets := flags.String("wait-buckets", "", "wait time histogram bucket upper bounds in ms (default 0,250,500,1000,2000,5000,10000,30000)")
+	queueGrowth := flags.Float64("queue-growth", 1, "queue growth per second, over 8 ticks, that the summary flags as an anomaly")
+	saturatedTicks := flags.Int("saturated-ticks", 20, "consecutive ticks at full utilization that the summary flags as an anomaly")
+	rejectionRate := flags.Float64("rejection-rate", 0.25, "share of arrivals rejected within 8 ticks that the summary flags as an anomaly")
+	weightList := flags.String("weights", "", "class weights for -fairness, e.g. PAID=3,FREE=2,ANON=1 (default: each class's share of arrivals)")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return errors.New("stats: expected one artifact path")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown -format %q (want text or json)", *format)
+	}
+	views := 0
+	for _, view := range []bool{*mmc, *fairness, *inversions, *shadow, *heatmap, *rolling} {
+		if view {
+			views++
+		}
+	}
+	if views > 1 {
+		return errors.New("-mmc, -fairness, -inversions, -shadow, -heatmap and -rolling are separate views; pick one")
+	}
+	opts := analysis.DefaultSummaryOptions()
+	opts.Anomalies.QueueGrowthPerSec = *queueGrowth
+	opts.Anomalies.SaturatedTicks = *saturatedTicks
+	opts.Anomalies.RejectionRate = *rejectionRate
+	if *queueBuckets != "" {
+		if opts.Histograms.QueueLength, err = analysis.ParseBounds(*queueBuckets); err != nil {
+			return err
+		}
+	}
+	if *waitBuckets != "" {
+		if opts.Histograms.WaitMs, err = analysis.ParseBounds(*waitBuckets); err != nil {
+			return err
+		}
+	}
+	var weights map[string]float64
+	if *weightList != "" {
+		if weights, err = analysis.ParseWeights(*weightList); err != nil {
+			return err
+		}
+	}
+
+	artifact, err := engine.ReadArtifact(positional[0])
+	if err != nil {
+		return err
+	}
+	var report any = analysis.SummarizeWith(artifact, opts)
+	if *fairness {
+		report = analysis.MeasureFairness(artifact, weights)
+	}
+	if *inversions {
+		report = analysis.DetectInversions(artifact)
+	}
+	if *shadow {
+		report = analysis.CompareShadow(artifact)
+	}
+	if *heatmap {
+		if report, err = analysis.BuildHeatmaps(artifact, *bucket); err != nil {
+			return err
+		}
+	}
+	if *rolling {
+		if report, err = analysis.RollingSeries(artifact, *window); err != nil {
+			return err
+		}
+	}
+	if *mmc {
+		report, err = analysis.CompareMMc(artifact, analysis.MMcOptions{WindowTicks: *window, Tolerance: *tolerance})
+		if err != nil {
+			return err
+		}
+	}
+
+	file, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	switch report := report.(type) {
+	case analysis.MMcComparison:
+		if *format == "json" {
+			err = writeJSON(file, report)
+		} else {
+			err = writeMMc(file, artifact.Metadata, report)
+		}
+	case analysis.Fairness:
+		if *format == "json" {
+			err = writeJSON(file, report)
+		} else {
+			err = writeFairness(file, artifact.Metadata, report)
+		}
+	case analysis.Inversions:
+		if *format == "json" {
+			err = writeJSON(file, report)
+		} else {
+			err = writeInversions(file, artifact.Metadata, report)
+		}
+	case analysis.ShadowReport:
+		if *format == "json" {
+			err = writeJSON(file, report)
+		} else {
+			err = writeShadow(file, artifact.Metadata, report)
+		}
+	case analysis.Heatmaps:
+		if *format == "json" {
+			err = writeJSON(file, report)
+		} else {
+			err = writeHeatmaps(file, artifact.Metadata, report)
+		}
+	case analysis.Rolling:
+		if *format == "json" {
+			err = writeJSON(file, report)
+		} else {
+			err = writeRolling(file, artifact.Metadata, report)
+		}
+	case analysis.Summary:
+		if *format == "json" {
+			err = writeJSON(file, report)
+		} else {
+			err = writeStats(file, artifact.Metadata, report)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func writeStats(w io.Writer, metadata engine.Metadata, summary analysis.Summary) error {
+	fmt.Fprintf(w, "scenario %s, seed %d, %d ticks of %dms\n", metadata.ScenarioID, metadata.Seed, summary.TickCount, summary.TickDurationMs)
+	if summary.WarmupTicks > 0 {
+		fmt.Fprintf(w, "first %d ticks excluded as warmup\n", summary.WarmupTicks)
+	}
+	fmt.Fprintf(w, "arrived %d, completed %d (%.2f/s), rejected %d, max queue %d, utilization %.1f%%\n",
+		summary.Arrived, summary.Completed, summary.Throughput, summary.Rejected, summary.MaxQueueLength, 100*summary.Utilization)
+	if summary.Failed > 0 {
+		fmt.Fprintf(w, "failed %d of %d arrivals (%s)\n", summary.Failed, summary.Arrived, countByClass(summary.Classes, func(c analysis.ClassSummary) int { return c.Failed }))
+	}
+	if summary.Degraded > 0 {
+		fmt.Fprintf(w, "degraded %d of %d completions (%s)\n", summary.Degraded, summary.Completed, countByClass(summary.Classes, func(c analysis.ClassSummary) int { return c.Degraded }))
+	}
+	if l := summary.Ledger; l != nil {
+		fmt.Fprintf(w, "revenue %.2f, server cost %.2f, rejection penalties %.2f, SLA penalties %.2f, net %.2f\n",
+			l.Revenue, l.ServerCost, l.RejectionPenalty, l.SLAPenalty, l.Net)
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "class\tarrived\tcompleted\tper sec\trejected\treject %\tmean wait ms\tp50 ms\tp95 ms\tp99 ms\tmax ms\t")
+	for _, c := range summary.Classes {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%d\t%.1f\t%.1f\t%d\t%d\t%d\t%d\t\n",
+			c.Class, c.Arrived, c.Completed, c.Throughput, c.Rejected, 100*c.RejectionRate, c.MeanWaitMs, c.P50LatencyMs, c.P95LatencyMs, c.P99LatencyMs, c.MaxLatencyMs)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\nqueue length over %d snapshots: %s\n", summary.QueueHistogram.Total(), histogramCounts(summary.QueueHistogram))
+	for _, c := range summary.Classes {
+		fmt.Fprintf(w, "%s wait ms over %d starts: %s\n", c.Class, c.WaitHistogram.Total(), histogramCounts(c.WaitHistogram))
+	}
+	if len(summary.Anomalies) > 0 {
+		fmt.Fprintln(w, "\nanomalies:")
+		for _, a := range summary.Anomalies {
+			fmt.Fprintf(w, "  ticks %d-%d  %s: %s\n", a.FromTick, a.ToTick, a.Kind, a.Detail)
+		}
+	}
+	_, err := fmt.Fprintf(w, "\nJain's fairness index %.3f (run finit stats -fairness for details)\n", summary.Fairness.JainIndex)
+	return err
+}
+
+func histogramCounts(h analysis.Histogram) string {
+	var parts []string
+	for i, label := range h.Labels() {
+		parts = append(parts, fmt.Sprintf("%s %d", label, h.Counts[i]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func countByClass(classes []analysis.ClassSummary, count func(analysis.ClassSummary) int) string {
+	var parts []string
+	for _, c := range classes {
+		if n := count(c); n > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", c.Class, n))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func writeMMc(w io.Writer, metadata engine.Metadata, comparison analysis.MMcComparison) error {
+	o := comparison.Overall
+	fmt.Fprintf(w, "scenario %s, seed %d: M/M/c fit with c=%d, mu=%.2f/s per server\n", metadata.ScenarioID, metadata.Seed, o.Servers, o.ServiceRate)
+	fmt.Fprintf(w, "deviation is (measured - predicted) / max(predicted, one tick); windows beyond %.0f%% or with rho >= 1 are flagged\n\n", 100*comparison.Tolerance)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "ticks\tlambda/s\trho\tP(wait)\tWq model ms\tWq sim ms\tLq model\tLq sim\tdeviation\t\t")
+	row := func(label string, m analysis.MMcWindow) {
+		flag := ""
+		if m.Flagged {
+			flag = "!"
+		}
+		if !m.Stable {
+			fmt.Fprintf(tw, "%s\t%.2f\t%.2f\t-\t-\t%.1f\t-\t%.2f\tunstable\t%s\t\n", label, m.ArrivalRate, m.Utilization, m.MeasuredWaitMs, m.MeasuredQueueLength, flag)
+			return
+		}
+		fmt.Fprintf(tw, "%s\t%.2f\t%.2f\t%.3f\t%.1f\t%.1f\t%.2f\t%.2f\t%+.0f%%\t%s\t\n",
+			label, m.ArrivalRate, m.Utilization, m.ProbWait, m.PredictedWaitMs, m.MeasuredWaitMs, m.PredictedQueueLength, m.MeasuredQueueLength, 100*m.Deviation, flag)
+	}
+	for _, m := range comparison.Windows {
+		row(fmt.Sprintf("%d-%d", m.FromTick, m.ToTick-1), m)
+	}
+	row("all", o)
+	return tw.Flush()
+}
+
+func writeFairness(w io.Writer, metadata engine.Metadata, fairness analysis.Fairness) error {
+	fmt.Fprintf(w, "scenario %s, seed %d: Jain's fairness index %.3f (capacity share / %s weight)\n\n", metadata.ScenarioID, metadata.Seed, fairness.JainIndex, fairness.Weights)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "class\tweight\tservice ticks\tcapacity share\tshare / weight\tlongest starvation\tfrom tick\t")
+	for _, c := range fairness.Classes {
+		from := "-"
+		if c.LongestStarvationTicks > 0 {
+			from = fmt.Sprint(c.StarvedFromTick)
+		}
+		fmt.Fprintf(tw, "%s\t%.1f%%\t%d\t%.1f%%\t%.2f\t%d ticks (%dms)\t%s\t\n",
+			c.Class, 100*c.Weight, c.ServiceTicks, 100*c.CapacityShare, c.ShareRatio, c.LongestStarvationTicks, c.LongestStarvationMs, from)
+	}
+	return tw.Flush()
+}
+
+func writeInversions(w io.Writer, metadata engine.Metadata, inversions analysis.Inversions) error {
+	fmt.Fprintf(w, "scenario %s, seed %d: %d priority inversions, %d ticks (%dms) of waiting in total\n",
+		metadata.ScenarioID, metadata.Seed, inversions.Count, inversions.TotalTicks, inversions.TotalMs)
+	if inversions.Count == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "token\tclass\tfrom tick\twaited\tcause\tovertaken by\t")
+	for _, inversion := range inversions.Episodes {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d ticks (%dms)\t%s\t%s\t\n",
+			inversion.TokenID, inversion.Class, inversion.FromTick, inversion.Ticks, inversion.Ms, inversion.Cause, strings.Join(inversion.Overtaken, " "))
+	}
+	return tw.Flush()
+}
+
+func writeShadow(w io.Writer, metadata engine.Metadata, report analysis.ShadowReport) error {
+	fmt.Fprintf(w, "scenario %s, seed %d: shadow policy agreed on %d of %d decisions (%.1f%%)\n",
+		metadata.ScenarioID, metadata.Seed, report.Decisions-report.Divergences, report.Decisions, 100*report.Agreement)
+	if report.Decisions == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "class	decisions	diverged	agreement	")
+	for _, c := range report.Classes {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f%%\t\n", c.Class, c.Decisions, c.Divergences, 100*c.Agreement)
+	}
+	if len(report.Swaps) > 0 {
+		fmt.Fprintln(tw, "\t\t\t\t")
+		fmt.Fprintln(tw, "served\tshadow wanted\tcount\t\t")
+		for _, swap := range report.Swaps {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t\t\n", swap.Chosen, swap.Shadow, swap.Count)
+		}
+	}
+	return tw.Flush()
+}
+
+func writeRolling(w io.Writer, metadata engine.Metadata, rolling analysis.Rolling) error {
+	fmt.Fprintf(w, "scenario %s, seed %d: rates over the trailing %d ticks, shown at the end of each window\n\n", metadata.ScenarioID, metadata.Seed, rolling.WindowTicks)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "tick\tarrivals/s\tthroughput/s\tutilization\t\t")
+	for i, tick := range rolling.Ticks {
+		if (tick+1)%rolling.WindowTicks != 0 && i != len(rolling.Ticks)-1 {
+			continue
+		}
+		warmup := ""
+		if tick < rolling.WarmupTicks {
+			warmup = "warmup"
+		}
+		fmt.Fprintf(tw, "%d\t%.2f\t%.2f\t%.1f%%\t%s\t\n", tick, rolling.Arrivals[i], rolling.Throughput[i], 100*rolling.Utilization[i], warmup)
+	}
+	return tw.Flush()
+}
+
+const heatShades = " .:-=+*#%@"
+
+func writeHeatmaps(w io.Writer, metadata engine.Metadata, heatmaps analysis.Heatmaps) error {
+	if len(heatmaps.Ticks) == 0 {
+		_, err := fmt.Fprintf(w, "scenario %s, seed %d: no snapshots to plot\n", metadata.ScenarioID, metadata.Seed)
+		return err
+	}
+	fmt.Fprintf(w, "scenario %s, seed %d: %d columns of %d ticks, from tick %d\n", metadata.ScenarioID, metadata.Seed, len(heatmaps.Ticks), heatmaps.BucketTicks, heatmaps.Ticks[0])
+	for _, section := range []struct {
+		title string
+		h     analysis.Heatmap
+	}{{"queue length", heatmaps.QueueLength}, {"utilization", heatmaps.Utilization}} {
+		fmt.Fprintf(w, "\n%s (max %.2f, shades %q)\n", section.title, section.h.Max, heatShades)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for i, row := range section.h.Rows {
+			line := make([]byte, len(section.h.Values[i]))
+			for col, v := range section.h.Values[i] {
+				shade := 0
+				if section.h.Max > 0 {
+					shade = int(math.Ceil(v / section.h.Max * float64(len(heatShades)-1)))
+				}
+				line[col] = heatShades[shade]
+			}
+			fmt.Fprintf(tw, "%s\t|%s|\n", row, line)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"finit/engine"
+	"finit/otlp"
+)
+
+func runTrace(args []string) error {
+	flags := flag.NewFlagSet("trace", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit trace [flags] artifact.json")
+		flags.PrintDefaults()
+	}
+	endpoint := flags.String("endpoint", "http://localhost:4318", "OTLP/HTTP endpoint")
+	startAt := flags.String("start", "", "RFC3339 time of tick 0 (default: the artifact's epoch, or now)")
+	headers := flags.String("headers", "", "comma-separated key=value request headers")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return errors.New("trace: expected one artifact path")
+	}
+
+	exporter := &otlp.Exporter{Endpoint: *endpoint, Headers: map[string]string{}}
+	for _, pair := range strings.Split(*headers, ",") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(pair), "="); ok {
+			exporter.Headers[key] = value
+		}
+	}
+
+	artifact, err := engine.ReadArtifact(positional[0])
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	if epoch, ok := artifact.Metadata.WallClock(0); ok {
+		start = epoch
+	}
+	if *startAt != "" {
+		start, err = time.Parse(time.RFC3339, *startAt)
+		if err != nil {
+			return fmt.Errorf("trace: invalid -start: %w", err)
+		}
+	}
+	sent, err := exporter.Export(context.Background(), artifact, start)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d spans to %s\n", sent, *endpoint)
+	return nil
+}
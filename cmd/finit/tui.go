@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"golang.org/x/term"
+
+	"finit/engine"
+	"finit/playback"
+	"finit/tui"
+)
+
+func runTUI(args []string) error {
+	flags := flag.NewFlagSet("tui", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit tui [flags] [artifact.json]")
+		flags.PrintDefaults()
+	}
+	scenarioID := flags.String("scenario_id", engine.ScenarioID, "scenario id (live mode)")
+	seed := flags.Int64("seed", 1, "random seed (live mode)")
+	speed := flags.Float64("speed", 1, "playback speed multiplier")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+
+	var source playback.Source
+	live := len(positional) == 0
+	if live {
+		sim, err := engine.NewSimulator(engine.Config{
+			ScenarioID: *scenarioID,
+			Seed:       *seed,
+		})
+		if err != nil {
+			return err
+		}
+		source = playback.LiveSource(sim)
+	} else if _, err := os.Stat(engine.IndexPath(positional[0])); err == nil {
+		artifact, err := engine.OpenIndexed(positional[0])
+		if err != nil {
+			return err
+		}
+		defer artifact.Close()
+		if artifact.SnapshotCount() == 0 {
+			return playback.ErrNoSnapshots
+		}
+		source = playback.IndexedSource(artifact)
+	} else {
+		artifact, err := engine.ReadArtifact(positional[0])
+		if err != nil {
+			return err
+		}
+		if len(artifact.Snapshots) == 0 {
+			return playback.ErrNoSnapshots
+		}
+		source = playback.ArtifactSource(artifact)
+	}
+
+	player := playback.NewPlayer(source, nil)
+	if err := player.SetSpeed(*speed); err != nil {
+		return err
+	}
+
+	width := 80
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = term.Restore(fd, state) }()
+		if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			width = w
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return tui.New(player, os.Stdout, live, width).Run(ctx, os.Stdin)
+}
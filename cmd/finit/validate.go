@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"finit/engine"
+)
+
+func runValidate(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit validate artifact.json ...")
+		flags.PrintDefaults()
+	}
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		flags.Usage()
+		return errors.New("validate: expected at least one artifact path")
+	}
+
+	failed := 0
+	for _, path := range positional {
+		artifact, err := engine.ReadArtifact(path)
+		if err == nil {
+			err = engine.ValidateArtifact(artifact)
+		}
+		if err != nil {
+			failed++
+			fmt.Printf("%s: invalid\n%v\n", path, err)
+			continue
+		}
+		fmt.Printf("%s: ok (%d snapshots, %d events)\n", path, len(artifact.Snapshots), len(artifact.Events))
+	}
+	if failed > 0 {
+		return fmt.Errorf("validate: %d of %d artifacts invalid", failed, len(positional))
+	}
+	return nil
+}
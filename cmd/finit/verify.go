@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"finit/engine"
+)
+
+func runVerify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit verify -pub key.pub artifact.json ...")
+		flags.PrintDefaults()
+	}
+	pubPath := flags.String("pub", "", "Ed25519 public key (PEM, PKIX) the artifacts must be signed with")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if *pubPath == "" || len(positional) == 0 {
+		flags.Usage()
+		return errors.New("verify: expected -pub and at least one artifact path")
+	}
+	key, err := readPublicKey(*pubPath)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, path := range positional {
+		data, err := os.ReadFile(path)
+		var artifact engine.Artifact
+		if err == nil {
+			artifact, err = engine.VerifyArtifactData(data, key)
+		}
+		if err != nil {
+			failed++
+			fmt.Printf("%s: not verified: %v\n", path, err)
+			continue
+		}
+		fmt.Printf("%s: ok (signed by key %s)\n", path, artifact.Signature.KeyID)
+	}
+	if failed > 0 {
+		return fmt.Errorf("verify: %d of %d artifacts failed verification", failed, len(positional))
+	}
+	return nil
+}
+
+func readPEM(path, wantType string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != wantType {
+		return nil, fmt.Errorf("%s: want a PEM %q block", path, wantType)
+	}
+	return block.Bytes, nil
+}
+
+func readPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: %T is not an Ed25519 key", path, parsed)
+	}
+	return key, nil
+}
+
+func readPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: %T is not an Ed25519 key", path, parsed)
+	}
+	return key, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"finit/engine"
+	"finit/server"
+	"finit/storage"
+)
+
+type watchMessage struct {
+	Type     string           `json:"type"`
+	Scenario string           `json:"scenario"`
+	Out      string           `json:"out,omitempty"`
+	Metadata *engine.Metadata `json:"metadata,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+func runWatch(args []string) error {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit watch <scenario.yaml> [flags]")
+		flags.PrintDefaults()
+	}
+	seed := flags.Int64("seed", 1, "random seed")
+	out := flags.String("out", "artifacts/run.json", "output file path or s3://bucket/key, rewritten on every change")
+	interval := flags.Duration("interval", 500*time.Millisecond, "how often to check the scenario file for changes")
+	notify := flags.String("notify", "", "serve a WebSocket on this address that announces every rewrite (host:port)")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return errors.New("watch: expected one scenario file")
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("-interval must be positive, got %s", *interval)
+	}
+	path := positional[0]
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var hub *server.Hub
+	if *notify != "" {
+		hub = server.NewHub()
+		defer hub.Close()
+		srv := &http.Server{Addr: *notify, Handler: hub, ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
+		defer srv.Close()
+		fmt.Fprintf(os.Stderr, "notifying WebSocket clients on %s\n", *notify)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	return watchScenario(ctx, path, *interval, func() error {
+		message := rerun(ctx, path, *seed, *out)
+		if message.Error != "" {
+			fmt.Fprintln(os.Stderr, message.Error)
+		} else {
+			fmt.Printf("wrote %s (replay_id=%s)\n", *out, message.Metadata.ReplayID)
+		}
+		if hub == nil {
+			return nil
+		}
+		data, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		hub.Broadcast(data)
+		return nil
+	})
+}
+
+func watchScenario(ctx context.Context, path string, interval time.Duration, changed func() error) error {
+	var last string
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		stamp, err := scenarioStamp(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err == nil && stamp != last {
+			last = stamp
+			if err := changed(); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func scenarioStamp(path string) (string, error) {
+	files, err := engine.ScenarioFiles(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	var stamp strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&stamp, "%s %d %d\n", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return stamp.String(), nil
+}
+
+func rerun(ctx context.Context, path string, seed int64, out string) watchMessage {
+	message := watchMessage{Type: "error", Scenario: path}
+	scenario, err := engine.LoadScenario(path)
+	if err != nil {
+		message.Error = err.Error()
+		return message
+	}
+	artifact, err := engine.RunContext(ctx, engine.NewConfig(engine.WithScenarioSpec(scenario), engine.WithSeed(seed)))
+	if err != nil {
+		message.Error = err.Error()
+		return message
+	}
+	if err := storage.WriteArtifact(ctx, out, artifact); err != nil {
+		message.Error = err.Error()
+		return message
+	}
+	message.Type = "run"
+	message.Out = out
+	message.Metadata = &artifact.Metadata
+	return message
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchScenario(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	top := filepath.Join(dir, "top.yaml")
+	write := func(path, data string, at time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write(base, "extends: canonical_v1\ncapacity: 2\n", start)
+	write(top, "extends: base.yaml\nid: top\n", start)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- watchScenario(ctx, top, 5*time.Millisecond, func() error {
+			changes <- struct{}{}
+			return nil
+		})
+	}()
+	expect := func(what string) {
+		t.Helper()
+		select {
+		case <-changes:
+		case err := <-done:
+			t.Fatalf("watchScenario() returned %v, want a change after %s", err, what)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no change seen after %s", what)
+		}
+	}
+	quiet := func(what string) {
+		t.Helper()
+		select {
+		case <-changes:
+			t.Fatalf("change seen after %s, want none", what)
+		case err := <-done:
+			t.Fatalf("watchScenario() returned %v after %s, want it to keep watching", err, what)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	expect("start")
+	quiet("no edits")
+	write(base, "extends: canonical_v1\ncapacity: 4\n", start.Add(time.Minute))
+	expect("editing the extended base file")
+
+	if err := os.Remove(top); err != nil {
+		t.Fatal(err)
+	}
+	quiet("removing the scenario during a save")
+	tmp := filepath.Join(dir, "top.yaml.tmp")
+	write(tmp, "extends: base.yaml\nid: top\ncapacity: 3\n", start.Add(2*time.Minute))
+	if err := os.Rename(tmp, top); err != nil {
+		t.Fatal(err)
+	}
+	expect("renaming the saved file into place")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("watchScenario() = %v after cancel, want nil", err)
+	}
+}
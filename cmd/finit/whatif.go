@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"finit/analysis"
+	"finit/engine"
+	"finit/storage"
+)
+
+func runWhatIf(args []string) error {
+	flags := flag.NewFlagSet("whatif", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: finit whatif [flags] artifact.json")
+		flags.PrintDefaults()
+	}
+	policy := flags.String("policy", "", "scheduling or admission policy to replay under: "+strings.Join(analysis.PolicyNames(), ", "))
+	var sets listFlag
+	flags.Var(&sets, "set", "override a scenario parameter for the replay, as name=value (repeatable)")
+	out := flags.String("o", "", "also write the counterfactual artifact to this path")
+	format := flags.String("format", "text", "delta report format: text or json")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return errors.New("whatif: expected one artifact path")
+	}
+	if *policy == "" && len(sets) == 0 {
+		flags.Usage()
+		return errors.New("whatif: nothing to change; pass -policy or -set")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown -format %q (want text or json)", *format)
+	}
+	var overrides []analysis.Parameter
+	for _, set := range sets {
+		override, err := analysis.ParseParameter(set)
+		if err != nil {
+			return err
+		}
+		overrides = append(overrides, override)
+	}
+
+	original, err := engine.ReadArtifact(positional[0])
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	counterfactual, whatIf, err := analysis.Counterfactual(ctx, original, *policy, overrides)
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		if err := storage.WriteArtifact(ctx, *out, counterfactual); err != nil {
+			return err
+		}
+	}
+
+	if *format == "json" {
+		return writeJSON(os.Stdout, whatIf)
+	}
+	writeWhatIf(os.Stdout, whatIf)
+	return nil
+}
+
+func writeWhatIf(w io.Writer, whatIf analysis.WhatIf) {
+	var changes []string
+	if whatIf.Policy != "" {
+		changes = append(changes, "policy "+whatIf.Policy)
+	}
+	for _, override := range whatIf.Overrides {
+		changes = append(changes, fmt.Sprintf("%s=%d", override.Name, override.Values[0]))
+	}
+	fmt.Fprintf(w, "replayed %d arrivals under %s\n", whatIf.Arrivals, strings.Join(changes, ", "))
+	p := whatIf.Paired
+	fmt.Fprintf(w, "paired tokens: %d rescued, %d lost, %d faster, %d slower, %d unchanged (mean latency delta %+.1fms)\n",
+		p.Rescued, p.Lost, p.Faster, p.Slower, p.Unchanged, p.MeanLatencyDeltaMs)
+	writeDiff(w, whatIf.Diff)
+}
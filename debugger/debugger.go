@@ -0,0 +1,232 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"finit/engine"
+)
+
+const prompt = "(finit) "
+
+type Breakpoint map[string]string
+
+func ParseBreakpoint(args []string) (Breakpoint, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("breakpoint needs at least one key=value condition")
+	}
+	bp := make(Breakpoint, len(args))
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid condition %q (want key=value)", arg)
+		}
+		key = strings.ToLower(key)
+		switch key {
+		case "event", "type", "reason", "class", "token", "stage", "tick":
+		default:
+			return nil, fmt.Errorf("unknown breakpoint key %q", key)
+		}
+		bp[key] = value
+	}
+	return bp, nil
+}
+
+func (b Breakpoint) Matches(event engine.Event) bool {
+	for key, value := range b {
+		var field string
+		switch key {
+		case "event", "type":
+			field = event.Type
+		case "reason":
+			field = event.ReasonCode
+		case "class":
+			field = event.Class
+		case "token":
+			field = event.TokenID
+		case "stage":
+			field = event.StageID
+		case "tick":
+			field = strconv.Itoa(event.Tick)
+		}
+		if !strings.EqualFold(field, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (b Breakpoint) String() string {
+	parts := make([]string, 0, len(b))
+	for _, key := range []string{"event", "type", "reason", "class", "token", "stage", "tick"} {
+		if value, ok := b[key]; ok {
+			parts = append(parts, key+"="+value)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+type Session struct {
+	sim         *engine.Simulator
+	out         io.Writer
+	breakpoints []Breakpoint
+	offset      int
+}
+
+func New(sim *engine.Simulator, out io.Writer) *Session {
+	return &Session{sim: sim, out: out}
+}
+
+func (s *Session) Run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	fmt.Fprintf(s.out, "finit debugger · %s seed %d · type 'help' for commands\n", s.sim.Metadata().ScenarioID, s.sim.Metadata().Seed)
+	for {
+		fmt.Fprint(s.out, prompt)
+		if !scanner.Scan() {
+			fmt.Fprintln(s.out)
+			return scanner.Err()
+		}
+		if !s.Exec(scanner.Text()) {
+			return nil
+		}
+	}
+}
+
+func (s *Session) Exec(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+	args := fields[1:]
+	switch fields[0] {
+	case "quit", "exit", "q":
+		return false
+	case "help", "h", "?":
+		s.help()
+	case "step", "s":
+		n := 1
+		if len(args) > 0 {
+			parsed, err := strconv.Atoi(args[0])
+			if err != nil || parsed <= 0 {
+				fmt.Fprintf(s.out, "invalid step count %q\n", args[0])
+				return true
+			}
+			n = parsed
+		}
+		s.step(n)
+	case "continue", "c":
+		s.step(-1)
+	case "show", "p":
+		s.show(args)
+	case "breakpoint", "break", "b":
+		bp, err := ParseBreakpoint(args)
+		if err != nil {
+			fmt.Fprintln(s.out, err)
+			return true
+		}
+		s.breakpoints = append(s.breakpoints, bp)
+		fmt.Fprintf(s.out, "breakpoint %d: %s\n", len(s.breakpoints), bp)
+	case "breakpoints":
+		for i, bp := range s.breakpoints {
+			fmt.Fprintf(s.out, "  %d: %s\n", i+1, bp)
+		}
+	case "clear":
+		s.breakpoints = nil
+		fmt.Fprintln(s.out, "breakpoints cleared")
+	default:
+		fmt.Fprintf(s.out, "unknown command %q (type 'help')\n", fields[0])
+	}
+	return true
+}
+
+func (s *Session) step(n int) {
+	for i := 0; n < 0 || i < n; i++ {
+		if !s.sim.Step() {
+			if err := s.sim.Err(); err != nil {
+				fmt.Fprintf(s.out, "simulation stopped: %v\n", err)
+				return
+			}
+			fmt.Fprintln(s.out, "simulation finished")
+			return
+		}
+		events := s.sim.Events()[s.offset:]
+		s.offset = len(s.sim.Events())
+		for _, event := range events {
+			for j, bp := range s.breakpoints {
+				if bp.Matches(event) {
+					fmt.Fprintf(s.out, "hit breakpoint %d at tick %d: %s\n", j+1, event.Tick, formatEvent(event))
+					return
+				}
+			}
+		}
+	}
+	fmt.Fprintf(s.out, "tick %d\n", s.sim.Tick()-1)
+}
+
+func (s *Session) show(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(s.out, "show what? (queue, service, token ID, events, tick)")
+		return
+	}
+	switch args[0] {
+	case "queue":
+		queue := s.sim.Queue()
+		fmt.Fprintf(s.out, "queue (%d):\n", len(queue))
+		for _, token := range queue {
+			fmt.Fprintf(s.out, "  %2d %s %s\n", token.QueueIndex, token.ID, token.Class)
+		}
+	case "service":
+		service := s.sim.InService()
+		fmt.Fprintf(s.out, "in service (%d):\n", len(service))
+		for _, token := range service {
+			fmt.Fprintf(s.out, "  %s %s remaining=%d\n", token.ID, token.Class, token.ServiceRemaining)
+		}
+	case "token":
+		if len(args) < 2 {
+			fmt.Fprintln(s.out, "show token needs an id")
+			return
+		}
+		token, ok := s.sim.Token(args[1])
+		if !ok {
+			fmt.Fprintf(s.out, "token %s has not arrived yet\n", args[1])
+			return
+		}
+		fmt.Fprintf(s.out, "%s class=%s state=%s stage=%s queue_index=%d service_remaining=%d\n",
+			token.ID, token.Class, token.State, token.StageID, token.QueueIndex, token.ServiceRemaining)
+		for _, event := range s.sim.Events() {
+			if event.TokenID == token.ID {
+				fmt.Fprintf(s.out, "  %s\n", formatEvent(event))
+			}
+		}
+	case "events":
+		tick := s.sim.Tick() - 1
+		for _, event := range s.sim.Events() {
+			if event.Tick == tick {
+				fmt.Fprintf(s.out, "  %s\n", formatEvent(event))
+			}
+		}
+	case "tick":
+		fmt.Fprintf(s.out, "next tick %d of %d\n", s.sim.Tick(), s.sim.Metadata().TickCount)
+	default:
+		fmt.Fprintf(s.out, "unknown show target %q\n", args[0])
+	}
+}
+
+func (s *Session) help() {
+	fmt.Fprint(s.out, `commands:
+  step [N]                  advance one (or N) ticks, stopping at breakpoints
+  continue                  run until a breakpoint or the end of the simulation
+  show queue|service|events|tick
+  show token ID             print a token's state and its events so far
+  breakpoint key=value ...  stop when an event matches (keys: event, reason, class, token, stage, tick)
+  breakpoints | clear       list or remove breakpoints
+  quit
+`)
+}
+
+func formatEvent(event engine.Event) string {
+	return fmt.Sprintf("tick=%d %s %s %s %s", event.Tick, event.Type, event.TokenID, event.Class, event.ReasonCode)
+}
@@ -0,0 +1,80 @@
+package debugger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestParseBreakpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "event", args: []string{"event=REJECT"}},
+		{name: "multiple", args: []string{"event=SCHEDULE", "class=ANON"}},
+		{name: "empty", args: nil, wantErr: true},
+		{name: "missing value", args: []string{"event="}, wantErr: true},
+		{name: "unknown key", args: []string{"color=red"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseBreakpoint(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseBreakpoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBreakpoint_Matches(t *testing.T) {
+	bp, err := ParseBreakpoint([]string{"event=reject", "class=ANON"})
+	if err != nil {
+		t.Fatalf("ParseBreakpoint() error = %v", err)
+	}
+	if !bp.Matches(engine.Event{Type: engine.EventReject, Class: engine.ClassAnon}) {
+		t.Error("Matches() = false for matching event")
+	}
+	if bp.Matches(engine.Event{Type: engine.EventReject, Class: engine.ClassFree}) {
+		t.Error("Matches() = true for non-matching class")
+	}
+}
+
+func TestSession_StopsAtBreakpoint(t *testing.T) {
+	sim, err := engine.NewSimulator(engine.Config{Seed: 1})
+	if err != nil {
+		t.Fatalf("NewSimulator() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	session := New(sim, &out)
+	script := strings.Join([]string{
+		"step 3",
+		"show token T0000",
+		"breakpoint event=REJECT",
+		"continue",
+		"show queue",
+		"quit",
+	}, "\n")
+	if err := session.Run(strings.NewReader(script)); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"tick 2\n",
+		"T0000 class=FREE state=done",
+		"breakpoint 1: event=REJECT",
+		"hit breakpoint 1 at tick",
+		"queue (",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("session output missing %q in:\n%s", want, out.String())
+		}
+	}
+	if sim.Done() {
+		t.Error("continue should stop at the breakpoint before the end")
+	}
+}
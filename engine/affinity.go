@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type Affinity struct {
+	Sessions int      `json:"sessions" yaml:"sessions"`
+	Servers  []string `json:"servers,omitempty" yaml:"servers,omitempty"`
+}
+
+func (a Affinity) validate(s Scenario) []error {
+	var errs []error
+	if a.Sessions <= 0 {
+		errs = append(errs, fmt.Errorf("affinity: sessions must be positive, got %d", a.Sessions))
+	}
+	if len(a.Servers) > 0 && len(a.Servers) != s.Capacity {
+		errs = append(errs, fmt.Errorf("affinity: %d servers named, want capacity %d", len(a.Servers), s.Capacity))
+	}
+	seen := make(map[string]bool, len(a.Servers))
+	for i, name := range a.Servers {
+		if name == "" || seen[name] {
+			errs = append(errs, fmt.Errorf("affinity: servers[%d]: name %q is empty or repeated", i, name))
+		}
+		seen[name] = true
+	}
+	for feature, used := range map[string]bool{
+		"scaling":                      s.Scaling != nil,
+		"stalls":                       len(s.Stalls) > 0,
+		"work_units":                   s.WorkUnits != nil,
+		"discipline.processor_sharing": s.processorSharing(),
+	} {
+		if used {
+			errs = append(errs, fmt.Errorf("affinity: cannot be combined with %s", feature))
+		}
+	}
+	return errs
+}
+
+func (a Affinity) serverName(server int) string {
+	if len(a.Servers) > 0 {
+		return a.Servers[server-1]
+	}
+	return "s" + strconv.Itoa(server)
+}
+
+func (s *Simulator) assignSession(token *Token) {
+	if s.scenario.Affinity == nil {
+		return
+	}
+	token.Session = "session-" + strconv.Itoa(s.sessionRNG.Intn(s.scenario.Affinity.Sessions)+1)
+}
+
+func (s *Simulator) busyServers() []bool {
+	busy := make([]bool, s.capacity+1)
+	for _, token := range s.inService {
+		if token.State != StateCancelled {
+			busy[token.server] = true
+		}
+	}
+	for _, token := range s.transit {
+		if token.transitTo == StageService && token.State != StateCancelled {
+			busy[token.server] = true
+		}
+	}
+	return busy
+}
+
+func (s *Simulator) popAffine(tick int) *Token {
+	busy := s.busyServers()
+	free := 0
+	for server := 1; server <= s.capacity; server++ {
+		if !busy[server] {
+			free = server
+			break
+		}
+	}
+	if free == 0 {
+		return nil
+	}
+	var next *Token
+	var waiting []*Token
+	s.queue.each(func(_ int, token *Token) bool {
+		bound, ok := s.sessions[token.Session]
+		if !ok || token.Session == "" {
+			token.server = free
+			next = token
+			return false
+		}
+		if !busy[bound] {
+			token.server = bound
+			next = token
+			return false
+		}
+		if !token.affinityWait {
+			waiting = append(waiting, token)
+		}
+		return true
+	})
+	for _, token := range waiting {
+		s.emitAffinityWait(tick, token)
+	}
+	if next == nil {
+		return nil
+	}
+	s.queue.remove(next)
+	next.Server = s.scenario.Affinity.serverName(next.server)
+	if next.Session != "" {
+		s.sessions[next.Session] = next.server
+	}
+	return next
+}
+
+func (s *Simulator) emitAffinityWait(tick int, token *Token) {
+	token.affinityWait = true
+	server := s.sessions[token.Session]
+	context := s.newContext()
+	*context = EventContext{
+		Rule:        RuleAffinity,
+		QueueLength: s.queueLength(),
+		WaitTicks:   tick - token.queuedAt(),
+		Server:      s.scenario.Affinity.serverName(server),
+		Session:     token.Session,
+	}
+	s.emit(Event{
+		Tick:       tick,
+		Type:       EventAffinityWait,
+		ReasonCode: ReasonAffinityWait,
+		TokenID:    token.ID,
+		StageID:    StageQueue,
+		Class:      token.Class,
+		Context:    context,
+	})
+}
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+type Arrival struct {
+	Tick    int    `json:"tick" yaml:"tick"`
+	Class   string `json:"class" yaml:"class"`
+	Size    int    `json:"size,omitempty" yaml:"size,omitempty"`
+	Session string `json:"session,omitempty" yaml:"session,omitempty"`
+}
+
+func validateArrivals(s Scenario, classes map[string]bool) []error {
+	var errs []error
+	if s.Traffic != nil || len(s.Spikes) > 0 {
+		errs = append(errs, fmt.Errorf("arrivals: traffic and spikes have no effect with an arrival trace"))
+	}
+	prev := 0
+	for i, arrival := range s.Arrivals {
+		name := fmt.Sprintf("arrivals[%d]", i)
+		if arrival.Tick < 0 || arrival.Tick >= TickCount {
+			errs = append(errs, fmt.Errorf("%s: tick must be in [0, %d), got %d", name, TickCount, arrival.Tick))
+		} else if arrival.Tick < prev {
+			errs = append(errs, fmt.Errorf("%s: tick %d is before the previous arrival at %d", name, arrival.Tick, prev))
+		}
+		prev = max(prev, arrival.Tick)
+		if arrival.Class == "" {
+			errs = append(errs, fmt.Errorf("%s: class is required", name))
+		} else if err := unknownClass(name, arrival.Class, classes); err != nil {
+			errs = append(errs, err)
+		}
+		if arrival.Size < 0 {
+			errs = append(errs, fmt.Errorf("%s: size must not be negative, got %d", name, arrival.Size))
+		}
+	}
+	return errs
+}
+
+func (s *Simulator) tracedArrivals(tick int) {
+	for s.arrivalCursor < len(s.scenario.Arrivals) && s.scenario.Arrivals[s.arrivalCursor].Tick == tick {
+		arrival := s.scenario.Arrivals[s.arrivalCursor]
+		s.arrivalCursor++
+		token := s.newToken(arrival.Class, tick)
+		token.size = arrival.Size
+		if arrival.Session != "" && s.scenario.Affinity != nil {
+			token.Session = arrival.Session
+		} else {
+			s.assignSession(token)
+		}
+		if s.dispatch(tick, token) {
+			s.planDuplicate(tick, token)
+		}
+	}
+}
+
+func ExtractArrivals(artifact Artifact) []Arrival {
+	var arrivals []Arrival
+	scheduled := make(map[string]int)
+	index := make(map[string]int)
+	for _, lifecycle := range Lifecycles(artifact.Events) {
+		if !artifact.Metadata.TokenIDs.primary(lifecycle.TokenID) {
+			continue
+		}
+		arrival := Arrival{Tick: lifecycle.ArrivalTick, Class: lifecycle.Class}
+		if lifecycle.Scheduled() && lifecycle.Completed() {
+			arrival.Size = lifecycle.CompleteTick - lifecycle.ScheduleTick
+		}
+		if lifecycle.Scheduled() {
+			scheduled[lifecycle.TokenID] = lifecycle.ScheduleTick
+		}
+		index[lifecycle.TokenID] = len(arrivals)
+		arrivals = append(arrivals, arrival)
+	}
+
+	for _, snapshot := range ExpandSnapshots(artifact).Snapshots {
+		for _, token := range snapshot.Tokens {
+			i, ok := index[token.ID]
+			if !ok {
+				continue
+			}
+			if arrivals[i].Session == "" {
+				arrivals[i].Session = token.Session
+			}
+			if tick, ok := scheduled[token.ID]; ok && tick == snapshot.Tick && token.State == StateProcessing {
+				arrivals[i].Size = token.ServiceRemaining
+			}
+		}
+	}
+	slices.SortStableFunc(arrivals, func(a, b Arrival) int { return cmp.Compare(a.Tick, b.Tick) })
+	return arrivals
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -14,11 +14,62 @@ func ReplayID(scenarioID string, seed int64, version string) string {
 	return hex.EncodeToString(hash[:])
 }
 
-func WriteArtifact(path string, artifact Artifact) error {
+func (s Scenario) ReplayID(seed int64, version string) (string, error) {
+	spec, err := json.Marshal(s)
+	if err != nil {
+		return "", configError{fmt.Errorf("scenario %s: %w", s.ID, err)}
+	}
+	source := fmt.Sprintf("%s|%d|%s", spec, seed, version)
+	hash := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func MarshalArtifact(artifact Artifact) ([]byte, error) {
 	data, err := json.MarshalIndent(artifact, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func WriteArtifact(path string, artifact Artifact) error {
+	data, err := MarshalArtifact(artifact)
 	if err != nil {
 		return err
 	}
-	data = append(data, '\n')
 	return os.WriteFile(path, data, 0o644)
 }
+
+func ReadArtifact(path string) (Artifact, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Artifact{}, err
+	}
+	var file struct {
+		Artifact
+		Chunks []ChunkRef `json:"chunks"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Artifact{}, decodeError(path, err)
+	}
+	if len(file.Chunks) == 0 {
+		return file.Artifact, nil
+	}
+	reader, err := OpenChunked(path)
+	if err != nil {
+		return Artifact{}, err
+	}
+	return reader.Artifact()
+}
+
+func readArtifactFile(path string) (Artifact, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Artifact{}, err
+	}
+	var artifact Artifact
+	if err := json.Unmarshal(data, &artifact); err != nil {
+		return Artifact{}, decodeError(path, err)
+	}
+	return artifact, nil
+}
@@ -2,6 +2,8 @@ package engine
 
 import (
 	"encoding/hex"
+	"errors"
+	"math"
 	"reflect"
 	"testing"
 )
@@ -71,6 +73,14 @@ func TestReplayID_Deterministic(t *testing.T) {
 	}
 }
 
+func TestScenarioReplayID_NonFinite(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.Rework = &Rework{Probability: math.NaN(), MaxCycles: 1}
+	if _, err := scenario.ReplayID(1, EngineVersion); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("ReplayID() error = %v, want %v", err, ErrInvalidConfig)
+	}
+}
+
 func TestWriteArtifact(t *testing.T) {
 	tmpDir := t.TempDir()
 	path := tmpDir + "/test.json"
@@ -0,0 +1,114 @@
+package engine
+
+import "fmt"
+
+const (
+	BackpressureHold = "hold"
+	BackpressureShed = "shed"
+)
+
+type Backpressure struct {
+	QueueLength   int    `json:"queue_length" yaml:"queue_length"`
+	ReleaseLength int    `json:"release_length,omitempty" yaml:"release_length,omitempty"`
+	Policy        string `json:"policy,omitempty" yaml:"policy,omitempty"`
+}
+
+func (b Backpressure) validate() []error {
+	var errs []error
+	if b.QueueLength <= 0 {
+		errs = append(errs, fmt.Errorf("backpressure: queue_length must be positive, got %d", b.QueueLength))
+	}
+	if b.ReleaseLength < 0 || b.ReleaseLength >= max(b.QueueLength, 1) {
+		errs = append(errs, fmt.Errorf("backpressure: release_length must be between 0 and queue_length %d, got %d", b.QueueLength, b.ReleaseLength))
+	}
+	switch b.Policy {
+	case "", BackpressureHold, BackpressureShed:
+	default:
+		errs = append(errs, fmt.Errorf("backpressure: unknown policy %q (want %s or %s)", b.Policy, BackpressureHold, BackpressureShed))
+	}
+	return errs
+}
+
+func (b Backpressure) holds() bool {
+	return b.Policy != BackpressureShed
+}
+
+func (s *Simulator) dispatch(tick int, token *Token) bool {
+	bp := s.scenario.Backpressure
+	if bp == nil || len(s.held) == 0 && !s.backpressured(tick) {
+		return s.admit(tick, token)
+	}
+	if !bp.holds() {
+		s.reject(tick, token, ReasonBackpressureShed, EventContext{
+			Rule:        RuleBackpressure,
+			QueueLength: s.queueLength(),
+			Limit:       bp.QueueLength,
+		})
+		return false
+	}
+	token.State = StateHeld
+	token.StageID = StageArrivals
+	s.held = append(s.held, token)
+	context := s.newContext()
+	*context = EventContext{
+		Rule:        RuleBackpressure,
+		QueueLength: s.queueLength(),
+		Limit:       bp.QueueLength,
+		Held:        len(s.held),
+	}
+	s.emit(Event{
+		Tick:       tick,
+		Type:       EventHold,
+		ReasonCode: ReasonBackpressureHold,
+		TokenID:    token.ID,
+		StageID:    StageArrivals,
+		Class:      token.Class,
+		Context:    context,
+	})
+	return false
+}
+
+func (s *Simulator) releaseHeld(tick int) {
+	released := 0
+	for released < len(s.held) && !s.backpressured(tick) {
+		token := s.held[released]
+		released++
+		if s.admit(tick, token) && token.duplicateOf == nil {
+			s.planDuplicate(tick, token)
+		}
+	}
+	s.held = s.held[:copy(s.held, s.held[released:])]
+}
+
+func (s *Simulator) backpressured(tick int) bool {
+	bp := s.scenario.Backpressure
+	if bp == nil {
+		return false
+	}
+	queueLength := s.queueLength()
+	switch {
+	case !s.pressure && queueLength >= bp.QueueLength:
+		s.emitBackpressure(tick, EventBackpressureOn, ReasonDownstreamFull, bp.QueueLength)
+	case s.pressure && queueLength <= bp.ReleaseLength:
+		s.emitBackpressure(tick, EventBackpressureOff, ReasonDownstreamDrained, bp.ReleaseLength)
+	}
+	return s.pressure
+}
+
+func (s *Simulator) emitBackpressure(tick int, eventType, reason string, limit int) {
+	s.pressure = !s.pressure
+	context := s.newContext()
+	*context = EventContext{
+		Rule:        RuleBackpressure,
+		QueueLength: s.queueLength(),
+		Limit:       limit,
+		Held:        len(s.held),
+	}
+	s.emit(Event{
+		Tick:       tick,
+		Type:       eventType,
+		ReasonCode: reason,
+		StageID:    StageQueue,
+		Context:    context,
+	})
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+func RunMany(ctx context.Context, cfg Config, seeds []int64, workers int) ([]Artifact, error) {
+	artifacts := make([]Artifact, len(seeds))
+	err := RunEach(ctx, cfg, seeds, workers, func(i int, artifact Artifact) error {
+		artifacts[i] = artifact
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return artifacts, nil
+}
+
+func RunEach(ctx context.Context, cfg Config, seeds []int64, workers int, fn func(i int, artifact Artifact) error) error {
+	if len(cfg.Observers) > 0 || cfg.Progress != nil {
+		return errors.New("observers and progress callbacks cannot be shared across concurrent runs")
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(seeds))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	jobs := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				run := cfg
+				run.Seed = seeds[i]
+				artifact, err := RunContext(ctx, run)
+				if err == nil {
+					err = fn(i, artifact)
+				}
+				if err != nil {
+					once.Do(func() {
+						firstErr = fmt.Errorf("seed %d: %w", seeds[i], err)
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range seeds {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
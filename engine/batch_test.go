@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRunMany(t *testing.T) {
+	seeds := []int64{3, 1, 2, 7, 5}
+	artifacts, err := RunMany(context.Background(), Config{}, seeds, 3)
+	if err != nil {
+		t.Fatalf("RunMany() error = %v", err)
+	}
+	if len(artifacts) != len(seeds) {
+		t.Fatalf("RunMany() returned %d artifacts, want %d", len(artifacts), len(seeds))
+	}
+	for i, seed := range seeds {
+		want, err := Run(Config{Seed: seed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(artifacts[i], want) {
+			t.Errorf("RunMany() artifact %d does not match Run(seed %d)", i, seed)
+		}
+	}
+}
+
+func TestRunManyErrors(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		cfg  Config
+		want error
+	}{
+		{name: "canceled", ctx: canceled, want: context.Canceled},
+		{name: "unknown scenario", ctx: context.Background(), cfg: Config{ScenarioID: "nope"}},
+		{name: "observers", ctx: context.Background(), cfg: Config{Observers: []Observer{nil}}},
+	}
+	for _, tt := range tests {
+		_, err := RunMany(tt.ctx, tt.cfg, []int64{1, 2, 3}, 2)
+		if err == nil {
+			t.Errorf("%s: RunMany() error = nil, want error", tt.name)
+			continue
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: RunMany() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestRunEachStopsOnError(t *testing.T) {
+	failed := errors.New("write failed")
+	calls := 0
+	err := RunEach(context.Background(), Config{}, []int64{1, 2, 3, 4, 5, 6}, 1, func(i int, artifact Artifact) error {
+		calls++
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Errorf("RunEach() error = %v, want %v", err, failed)
+	}
+	if calls != 1 {
+		t.Errorf("RunEach() called fn %d times after an error, want 1", calls)
+	}
+}
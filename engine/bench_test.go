@@ -0,0 +1,77 @@
+package engine
+
+import "testing"
+
+func BenchmarkRun(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Run(Config{Seed: int64(i)}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStep(b *testing.B) {
+	b.ReportAllocs()
+	var sim *Simulator
+	for i := 0; i < b.N; i++ {
+		if sim == nil || sim.Done() {
+			b.StopTimer()
+			var err error
+			sim, err = NewSimulator(Config{Seed: int64(i)})
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.StartTimer()
+		}
+		sim.Step()
+	}
+}
+
+func BenchmarkMarshalArtifact(b *testing.B) {
+	artifact, err := Run(Config{Seed: 1})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := MarshalArtifact(artifact); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkClassQueue(b *testing.B) {
+	const queued = 200000
+	classes := []string{ClassAnon, ClassFree, ClassPaid}
+	var q classQueue
+	for i := 0; i < queued; i++ {
+		q.push(&Token{Class: classes[i%3], ArrivalTick: i})
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		token := q.pop()
+		q.arrivedBefore(token.ArrivalTick)
+		token.ArrivalTick += queued
+		q.push(token)
+	}
+}
+
+func BenchmarkClassQueueSJF(b *testing.B) {
+	const queued = 200000
+	classes := []string{ClassAnon, ClassFree, ClassPaid}
+	q := classQueue{discipline: DisciplineSJF}
+	for i := 0; i < queued; i++ {
+		q.push(&Token{Class: classes[i%3], ArrivalTick: i, size: 1 + i%17})
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		token := q.pop()
+		q.peek()
+		token.ArrivalTick += queued
+		q.push(token)
+	}
+}
@@ -0,0 +1,55 @@
+package engine
+
+func (s *Simulator) breakDownStages(stages []StageState) {
+	index := make(map[string]int, len(s.classes.sorted))
+	for i, def := range s.classes.sorted {
+		index[def.Name] = i
+	}
+	loads := func() []ClassLoad {
+		out := make([]ClassLoad, len(s.classes.sorted))
+		for i, def := range s.classes.sorted {
+			out[i].Class = def.Name
+		}
+		return out
+	}
+	for i := range stages {
+		switch stages[i].ID {
+		case StageQueue:
+			classes := loads()
+			s.queue.each(func(_ int, token *Token) bool {
+				classes[index[token.Class]].QueueLength++
+				classes[index[token.Class]].CapacityUsed++
+				return true
+			})
+			stages[i].Classes = classes
+		case StageService:
+			classes := loads()
+			for _, token := range s.inService {
+				classes[index[token.Class]].CapacityUsed++
+			}
+			if s.scenario.WorkUnits != nil {
+				for _, token := range s.inService {
+					classes[index[token.Class]].UnitsUsed += s.unitsFor(token)
+				}
+				for _, token := range s.transit {
+					if token.transitTo == StageService {
+						classes[index[token.Class]].UnitsUsed += s.unitsFor(token)
+					}
+				}
+			}
+			stages[i].Classes = classes
+		case StageTransit:
+			classes := loads()
+			for _, token := range s.transit {
+				classes[index[token.Class]].QueueLength++
+			}
+			stages[i].Classes = classes
+		case StageArrivals:
+			classes := loads()
+			for _, token := range s.held {
+				classes[index[token.Class]].QueueLength++
+			}
+			stages[i].Classes = classes
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassBreakdown(t *testing.T) {
+	plain, err := Run(NewConfig(WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken, err := Run(NewConfig(WithSeed(1), WithClassBreakdown()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !broken.Metadata.ClassBreakdown || plain.Metadata.ClassBreakdown {
+		t.Errorf("Metadata.ClassBreakdown = %v and %v, want only the broken-down run marked", broken.Metadata.ClassBreakdown, plain.Metadata.ClassBreakdown)
+	}
+	if !reflect.DeepEqual(plain.Events, broken.Events) {
+		t.Error("class breakdown changed the run")
+	}
+	for _, snapshot := range plain.Snapshots {
+		for _, stage := range snapshot.Stages {
+			if stage.Classes != nil {
+				t.Fatalf("tick %d stage %s has classes without the breakdown", snapshot.Tick, stage.ID)
+			}
+		}
+	}
+
+	for _, snapshot := range broken.Snapshots {
+		queued := make(map[string]int)
+		serving := make(map[string]int)
+		for _, token := range snapshot.Tokens {
+			switch token.State {
+			case StateQueued:
+				queued[token.Class]++
+			case StateProcessing:
+				serving[token.Class]++
+			}
+		}
+		for _, stage := range snapshot.Stages {
+			want := map[string]map[string]int{StageQueue: queued, StageService: serving}[stage.ID]
+			if want == nil {
+				continue
+			}
+			if len(stage.Classes) != 3 {
+				t.Fatalf("tick %d stage %s classes = %+v, want one per class", snapshot.Tick, stage.ID, stage.Classes)
+			}
+			for _, class := range stage.Classes {
+				wantQueue := 0
+				if stage.ID == StageQueue {
+					wantQueue = queued[class.Class]
+				}
+				if class.QueueLength != wantQueue {
+					t.Errorf("tick %d stage %s class %s queue_length = %d, want %d", snapshot.Tick, stage.ID, class.Class, class.QueueLength, wantQueue)
+				}
+				if class.CapacityUsed != want[class.Class] {
+					t.Errorf("tick %d stage %s class %s capacity_used = %d, want %d", snapshot.Tick, stage.ID, class.Class, class.CapacityUsed, want[class.Class])
+				}
+			}
+		}
+	}
+	if err := ValidateArtifact(broken); err != nil {
+		t.Errorf("ValidateArtifact() = %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package engine
+
+import "fmt"
+
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+type CircuitBreaker struct {
+	FailureRate float64 `json:"failure_rate" yaml:"failure_rate"`
+	LatencyMs   int     `json:"latency_ms" yaml:"latency_ms"`
+	WindowTicks int     `json:"window_ticks" yaml:"window_ticks"`
+	MinRequests int     `json:"min_requests,omitempty" yaml:"min_requests,omitempty"`
+	OpenTicks   int     `json:"open_ticks" yaml:"open_ticks"`
+	Probes      int     `json:"probes,omitempty" yaml:"probes,omitempty"`
+}
+
+func (b CircuitBreaker) validate() []error {
+	var errs []error
+	if b.FailureRate <= 0 || b.FailureRate > 1 {
+		errs = append(errs, fmt.Errorf("circuit_breaker: failure_rate must be in (0, 1], got %v", b.FailureRate))
+	}
+	if b.LatencyMs <= 0 {
+		errs = append(errs, fmt.Errorf("circuit_breaker: latency_ms must be positive, got %d", b.LatencyMs))
+	}
+	if b.WindowTicks <= 0 {
+		errs = append(errs, fmt.Errorf("circuit_breaker: window_ticks must be positive, got %d", b.WindowTicks))
+	}
+	if b.MinRequests < 0 {
+		errs = append(errs, fmt.Errorf("circuit_breaker: min_requests must not be negative, got %d", b.MinRequests))
+	}
+	if b.OpenTicks <= 0 {
+		errs = append(errs, fmt.Errorf("circuit_breaker: open_ticks must be positive, got %d", b.OpenTicks))
+	}
+	if b.Probes < 0 {
+		errs = append(errs, fmt.Errorf("circuit_breaker: probes must not be negative, got %d", b.Probes))
+	}
+	return errs
+}
+
+func (b CircuitBreaker) probes() int {
+	if b.Probes == 0 {
+		return 1
+	}
+	return b.Probes
+}
+
+type breakerState struct {
+	CircuitBreaker
+	state    string
+	good     []int
+	bad      []int
+	since    int
+	openedAt int
+	probing  int
+	passed   int
+}
+
+func newBreakerState(b *CircuitBreaker) *breakerState {
+	if b == nil {
+		return nil
+	}
+	return &breakerState{CircuitBreaker: *b, state: BreakerClosed, good: make([]int, TickCount), bad: make([]int, TickCount)}
+}
+
+func (b *breakerState) window(tick int) (good, bad int) {
+	for t := max(tick-b.WindowTicks+1, b.since, 0); t <= tick; t++ {
+		good += b.good[t]
+		bad += b.bad[t]
+	}
+	return good, bad
+}
+
+func (s *Simulator) evaluateBreaker(tick int) {
+	b := s.breaker
+	if b == nil {
+		return
+	}
+	switch b.state {
+	case BreakerClosed:
+		good, bad := b.window(tick)
+		total := good + bad
+		if total == 0 || total < b.MinRequests || float64(bad)/float64(total) < b.FailureRate {
+			return
+		}
+		s.tripBreaker(tick, ReasonFailureRate, bad, total)
+	case BreakerOpen:
+		if tick-b.openedAt < b.OpenTicks {
+			return
+		}
+		b.state = BreakerHalfOpen
+		b.probing, b.passed = 0, 0
+		s.emitBreaker(tick, EventBreakerHalfOpen, ReasonBreakerCooldown, 0, 0)
+	}
+}
+
+func (s *Simulator) circuitOpen() bool {
+	b := s.breaker
+	if b == nil || b.state == BreakerClosed {
+		return false
+	}
+	return b.state == BreakerOpen || b.probing >= b.probes()
+}
+
+func (s *Simulator) claimProbe(token *Token) {
+	if b := s.breaker; b != nil && b.state == BreakerHalfOpen {
+		b.probing++
+		token.probe = true
+	}
+}
+
+func (s *Simulator) rejectOpenCircuit(tick int, token *Token) {
+	b := s.breaker
+	s.reject(tick, token, ReasonCircuitOpen, EventContext{
+		Rule:        RuleCircuitBreaker,
+		QueueLength: s.queueLength(),
+		WaitTicks:   max(b.openedAt+b.OpenTicks-tick, 0),
+	})
+}
+
+func (s *Simulator) observeBreaker(tick int, token *Token, ok bool) {
+	b := s.breaker
+	if b == nil {
+		return
+	}
+	ok = ok && (tick-token.ArrivalTick)*TickDurationMs <= b.LatencyMs
+	if ok {
+		b.good[tick]++
+	} else {
+		b.bad[tick]++
+	}
+	if !token.probe || b.state != BreakerHalfOpen {
+		return
+	}
+	if !ok {
+		s.tripBreaker(tick, ReasonProbeFailed, 1, b.passed+1)
+		return
+	}
+	b.passed++
+	if b.passed < b.probes() {
+		return
+	}
+	b.state = BreakerClosed
+	b.since = tick + 1
+	s.emitBreaker(tick, EventBreakerClose, ReasonProbesSucceeded, 0, b.passed)
+}
+
+func (s *Simulator) tripBreaker(tick int, reason string, bad, total int) {
+	b := s.breaker
+	b.state = BreakerOpen
+	b.openedAt = tick
+	s.emitBreaker(tick, EventBreakerOpen, reason, bad, total)
+}
+
+func (s *Simulator) emitBreaker(tick int, eventType, reason string, bad, total int) {
+	b := s.breaker
+	context := s.newContext()
+	*context = EventContext{
+		Rule:        RuleCircuitBreaker,
+		QueueLength: s.queueLength(),
+		WindowTicks: b.WindowTicks,
+		Missed:      bad,
+		Observed:    total,
+	}
+	s.emit(Event{
+		Tick:       tick,
+		Type:       eventType,
+		ReasonCode: reason,
+		StageID:    StageService,
+		Context:    context,
+	})
+}
+
+func (s *Simulator) breakerState() string {
+	if s.breaker == nil {
+		return ""
+	}
+	return s.breaker.state
+}
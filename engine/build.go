@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+)
+
+var BuildTime string
+
+type BuildInfo struct {
+	Module    string `json:"module,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Revision  string `json:"vcs_revision,omitempty"`
+	CommitAt  string `json:"vcs_time,omitempty"`
+	Modified  bool   `json:"vcs_modified,omitempty"`
+	BuiltAt   string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+	Hostname  string `json:"hostname,omitempty"`
+}
+
+func ReadBuildInfo(withHostname bool) BuildInfo {
+	info := BuildInfo{
+		BuiltAt:   BuildTime,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		info.Module = build.Main.Path
+		info.Version = build.Main.Version
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Revision = setting.Value
+			case "vcs.time":
+				info.CommitAt = setting.Value
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	if withHostname {
+		info.Hostname, _ = os.Hostname()
+	}
+	return info
+}
@@ -0,0 +1,30 @@
+package engine
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestReadBuildInfo(t *testing.T) {
+	info := ReadBuildInfo(false)
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("GoVersion = %q, want %q", info.GoVersion, runtime.Version())
+	}
+	if info.Platform != runtime.GOOS+"/"+runtime.GOARCH {
+		t.Errorf("Platform = %q", info.Platform)
+	}
+	if info.Hostname != "" {
+		t.Errorf("Hostname = %q without withHostname", info.Hostname)
+	}
+
+	artifact, err := Run(NewConfig(WithSeed(1), WithBuildInfo(info)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if artifact.Metadata.Build == nil || *artifact.Metadata.Build != info {
+		t.Errorf("Metadata.Build = %+v, want %+v", artifact.Metadata.Build, info)
+	}
+	if want, err := CanonicalScenario().ReplayID(1, EngineVersion); err != nil || artifact.Metadata.ReplayID != want {
+		t.Error("build info changed the replay id")
+	}
+}
@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type ChunkManifest struct {
+	Metadata   Metadata   `json:"metadata"`
+	ChunkTicks int        `json:"chunk_ticks"`
+	Chunks     []ChunkRef `json:"chunks"`
+}
+
+type ChunkRef struct {
+	Path      string `json:"path"`
+	FirstTick int    `json:"first_tick"`
+	LastTick  int    `json:"last_tick"`
+	Snapshots int    `json:"snapshots"`
+	Events    int    `json:"events"`
+}
+
+func ChunkPath(path string, chunk int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.%04d%s", strings.TrimSuffix(path, ext), chunk, ext)
+}
+
+func WriteChunked(path string, artifact Artifact, chunkTicks int) (ChunkManifest, error) {
+	if chunkTicks <= 0 {
+		return ChunkManifest{}, fmt.Errorf("chunk ticks must be positive, got %d", chunkTicks)
+	}
+	manifest := ChunkManifest{Metadata: artifact.Metadata, ChunkTicks: chunkTicks}
+
+	snapshots, events := artifact.Snapshots, artifact.Events
+	for len(snapshots) > 0 {
+		last := snapshots[0].Tick + chunkTicks - 1
+		n := 0
+		for n < len(snapshots) && snapshots[n].Tick <= last {
+			n++
+		}
+		m := 0
+		for m < len(events) && events[m].Tick <= last {
+			m++
+		}
+
+		chunk := Artifact{Metadata: artifact.Metadata, Snapshots: snapshots[:n], Events: events[:m]}
+		chunkPath := ChunkPath(path, len(manifest.Chunks))
+		if err := WriteArtifact(chunkPath, chunk); err != nil {
+			return ChunkManifest{}, err
+		}
+		manifest.Chunks = append(manifest.Chunks, ChunkRef{
+			Path:      filepath.Base(chunkPath),
+			FirstTick: snapshots[0].Tick,
+			LastTick:  snapshots[n-1].Tick,
+			Snapshots: n,
+			Events:    m,
+		})
+		snapshots, events = snapshots[n:], events[m:]
+	}
+	if len(events) > 0 {
+		return ChunkManifest{}, fmt.Errorf("%d events after the last snapshot", len(events))
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return ChunkManifest{}, err
+	}
+	return manifest, os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func ReadChunkManifest(path string) (ChunkManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ChunkManifest{}, err
+	}
+	var manifest ChunkManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return ChunkManifest{}, decodeError(path, err)
+	}
+	if len(manifest.Chunks) == 0 {
+		return ChunkManifest{}, fmt.Errorf("%s: manifest lists no chunks", path)
+	}
+	return manifest, nil
+}
+
+type ChunkedReader struct {
+	dir      string
+	manifest ChunkManifest
+}
+
+func OpenChunked(path string) (*ChunkedReader, error) {
+	manifest, err := ReadChunkManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ChunkedReader{dir: filepath.Dir(path), manifest: manifest}, nil
+}
+
+func (r *ChunkedReader) Metadata() Metadata {
+	return r.manifest.Metadata
+}
+
+func (r *ChunkedReader) Manifest() ChunkManifest {
+	return r.manifest
+}
+
+func (r *ChunkedReader) Chunk(i int) (Artifact, error) {
+	if i < 0 || i >= len(r.manifest.Chunks) {
+		return Artifact{}, fmt.Errorf("chunk %d out of range [0, %d)", i, len(r.manifest.Chunks))
+	}
+	ref := r.manifest.Chunks[i]
+	chunk, err := readArtifactFile(filepath.Join(r.dir, ref.Path))
+	if err != nil {
+		return Artifact{}, err
+	}
+	if chunk.Metadata.ReplayID != r.manifest.Metadata.ReplayID {
+		return Artifact{}, fmt.Errorf("chunk %s belongs to replay %s, want %s", ref.Path, chunk.Metadata.ReplayID, r.manifest.Metadata.ReplayID)
+	}
+	if len(chunk.Snapshots) != ref.Snapshots || len(chunk.Events) != ref.Events {
+		return Artifact{}, fmt.Errorf("chunk %s has %d snapshots and %d events, manifest says %d and %d",
+			ref.Path, len(chunk.Snapshots), len(chunk.Events), ref.Snapshots, ref.Events)
+	}
+	return chunk, nil
+}
+
+func (r *ChunkedReader) Walk(fn func(snapshot Snapshot, events []Event) error) error {
+	for i := range r.manifest.Chunks {
+		chunk, err := r.Chunk(i)
+		if err != nil {
+			return err
+		}
+		events := chunk.Events
+		for _, snapshot := range chunk.Snapshots {
+			n := 0
+			for n < len(events) && events[n].Tick <= snapshot.Tick {
+				n++
+			}
+			if err := fn(snapshot, events[:n]); err != nil {
+				return err
+			}
+			events = events[n:]
+		}
+	}
+	return nil
+}
+
+func (r *ChunkedReader) Artifact() (Artifact, error) {
+	artifact := Artifact{Metadata: r.manifest.Metadata}
+	err := r.Walk(func(snapshot Snapshot, events []Event) error {
+		artifact.Snapshots = append(artifact.Snapshots, snapshot)
+		artifact.Events = append(artifact.Events, events...)
+		return nil
+	})
+	return artifact, err
+}
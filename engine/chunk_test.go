@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteChunked(t *testing.T) {
+	artifact, err := Run(Config{Seed: 1})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "run.json")
+	manifest, err := WriteChunked(path, artifact, 100)
+	if err != nil {
+		t.Fatalf("WriteChunked() error = %v", err)
+	}
+	if len(manifest.Chunks) != 3 {
+		t.Fatalf("chunks = %d, want 3", len(manifest.Chunks))
+	}
+	if got := manifest.Chunks[2]; got.FirstTick != 200 || got.LastTick != 239 || got.Path != "run.0002.json" {
+		t.Errorf("last chunk = %+v", got)
+	}
+
+	chunk, err := ReadArtifact(ChunkPath(path, 1))
+	if err != nil {
+		t.Fatalf("ReadArtifact(chunk) error = %v", err)
+	}
+	if chunk.Metadata.ReplayID != artifact.Metadata.ReplayID || chunk.Snapshots[0].Tick != 100 {
+		t.Errorf("chunk 1 starts at tick %d with replay %s", chunk.Snapshots[0].Tick, chunk.Metadata.ReplayID)
+	}
+
+	got, err := ReadArtifact(path)
+	if err != nil {
+		t.Fatalf("ReadArtifact(manifest) error = %v", err)
+	}
+	if !reflect.DeepEqual(got, artifact) {
+		t.Error("ReadArtifact(manifest) does not reassemble the original artifact")
+	}
+
+	reader, err := OpenChunked(path)
+	if err != nil {
+		t.Fatalf("OpenChunked() error = %v", err)
+	}
+	ticks, events := 0, 0
+	err = reader.Walk(func(snapshot Snapshot, tickEvents []Event) error {
+		if snapshot.Tick != ticks {
+			t.Fatalf("Walk() tick = %d, want %d", snapshot.Tick, ticks)
+		}
+		for _, e := range tickEvents {
+			if e.Tick != snapshot.Tick {
+				t.Fatalf("event at tick %d delivered with snapshot %d", e.Tick, snapshot.Tick)
+			}
+		}
+		ticks++
+		events += len(tickEvents)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	if ticks != len(artifact.Snapshots) || events != len(artifact.Events) {
+		t.Errorf("Walk() visited %d ticks and %d events, want %d and %d", ticks, events, len(artifact.Snapshots), len(artifact.Events))
+	}
+
+	if _, err := WriteChunked(path, artifact, 0); err == nil {
+		t.Error("WriteChunked(0) error = nil, want error")
+	}
+}
@@ -0,0 +1,194 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+type ClassDef struct {
+	Name              string       `json:"name" yaml:"name"`
+	Share             float64      `json:"share" yaml:"share"`
+	Priority          int          `json:"priority" yaml:"priority"`
+	QueueLimit        *int         `json:"queue_limit,omitempty" yaml:"queue_limit,omitempty"`
+	ServiceMultiplier float64      `json:"service_multiplier,omitempty" yaml:"service_multiplier,omitempty"`
+	Service           *ServiceDist `json:"service,omitempty" yaml:"service,omitempty"`
+	ErrorRate         float64      `json:"error_rate,omitempty" yaml:"error_rate,omitempty"`
+	Color             string       `json:"color,omitempty" yaml:"color,omitempty"`
+}
+
+func defaultClasses(rejectThreshold int) []ClassDef {
+	return []ClassDef{
+		{Name: ClassAnon, Share: 0.55, Priority: 2, QueueLimit: &rejectThreshold, Color: "#c2cbd7"},
+		{Name: ClassFree, Share: 0.30, Priority: 1, Color: "#a1aab5"},
+		{Name: ClassPaid, Share: 0.15, Priority: 0, Color: "#253f5d"},
+	}
+}
+
+func (s Scenario) ClassDefs() []ClassDef {
+	if len(s.Classes) > 0 {
+		return s.Classes
+	}
+	return defaultClasses(s.RejectThreshold)
+}
+
+func (s Scenario) classSet() map[string]bool {
+	set := make(map[string]bool)
+	for _, class := range s.ClassDefs() {
+		set[class.Name] = true
+	}
+	return set
+}
+
+func validateClasses(classes []ClassDef) []error {
+	var errs []error
+	seen := make(map[string]bool, len(classes))
+	total := 0.0
+	for i, class := range classes {
+		name := fmt.Sprintf("classes[%d]", i)
+		if class.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", name))
+		} else if seen[class.Name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate name %q", name, class.Name))
+		}
+		seen[class.Name] = true
+		if class.Share < 0 {
+			errs = append(errs, fmt.Errorf("%s: share must not be negative, got %v", name, class.Share))
+		}
+		if class.QueueLimit != nil && *class.QueueLimit < 0 {
+			errs = append(errs, fmt.Errorf("%s: queue_limit must not be negative, got %d", name, *class.QueueLimit))
+		}
+		if class.ServiceMultiplier < 0 {
+			errs = append(errs, fmt.Errorf("%s: service_multiplier must not be negative, got %v", name, class.ServiceMultiplier))
+		}
+		if class.Service != nil {
+			errs = append(errs, class.Service.validate(name)...)
+		}
+		if class.ErrorRate < 0 || class.ErrorRate >= 1 {
+			errs = append(errs, fmt.Errorf("%s: error_rate must be in [0, 1), got %v", name, class.ErrorRate))
+		}
+		total += class.Share
+	}
+	if len(classes) > 0 && math.Abs(total-1) > 1e-6 {
+		errs = append(errs, fmt.Errorf("classes: shares must sum to 1, got %v", total))
+	}
+	return errs
+}
+
+func sampledService(classes []ClassDef) bool {
+	for _, class := range classes {
+		if class.Service != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func unknownClass(name, class string, classes map[string]bool) error {
+	if class == "" || classes[class] {
+		return nil
+	}
+	return fmt.Errorf("%s: unknown class %q", name, class)
+}
+
+type classTable struct {
+	defs   []ClassDef
+	lanes  map[string]int
+	order  []string
+	sorted []ClassDef
+	mix    []ClassShare
+}
+
+func newClassTable(defs []ClassDef) classTable {
+	priorities := make([]int, 0, len(defs))
+	for _, def := range defs {
+		priorities = append(priorities, def.Priority)
+	}
+	slices.Sort(priorities)
+	priorities = slices.Compact(priorities)
+
+	table := classTable{defs: defs, lanes: make(map[string]int, len(defs))}
+	below := 0.0
+	for _, def := range defs {
+		table.lanes[def.Name], _ = slices.BinarySearch(priorities, def.Priority)
+		below += def.Share
+		table.mix = append(table.mix, ClassShare{Class: def.Name, Below: below})
+	}
+	table.mix[len(table.mix)-1].Below = 1
+	for lane := range priorities {
+		for _, def := range defs {
+			if table.lanes[def.Name] == lane {
+				table.order = append(table.order, def.Name)
+				table.sorted = append(table.sorted, def)
+			}
+		}
+	}
+	return table
+}
+
+func (m Metadata) ClassColor(class string) string {
+	if m.Presentation != nil {
+		for _, c := range m.Presentation.Classes {
+			if c.Name == class && c.Color != "" {
+				return c.Color
+			}
+		}
+	}
+	for _, def := range m.Classes {
+		if def.Name == class {
+			return def.Color
+		}
+	}
+	return ""
+}
+
+func (m Metadata) ClassNames() []string {
+	if len(m.Classes) == 0 {
+		return []string{ClassPaid, ClassFree, ClassAnon}
+	}
+	names := make([]string, len(m.Classes))
+	for i, def := range m.Classes {
+		names[i] = def.Name
+	}
+	return names
+}
+
+func (t classTable) laneCount() int {
+	return t.lanes[t.order[len(t.order)-1]] + 1
+}
+
+func (t classTable) lane(class string) int {
+	return t.lanes[class]
+}
+
+func (t classTable) queueLimit(class string) (int, bool) {
+	limit := t.def(class).QueueLimit
+	if limit == nil {
+		return 0, false
+	}
+	return *limit, true
+}
+
+func (t classTable) queueCapacity() int {
+	total := 0
+	for _, def := range t.defs {
+		if def.QueueLimit == nil {
+			return 0
+		}
+		total += *def.QueueLimit
+	}
+	return total
+}
+
+func (t classTable) index(class string) int {
+	return slices.IndexFunc(t.defs, func(def ClassDef) bool { return def.Name == class })
+}
+
+func (t classTable) def(class string) ClassDef {
+	for _, def := range t.defs {
+		if def.Name == class {
+			return def
+		}
+	}
+	return ClassDef{}
+}
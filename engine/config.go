@@ -0,0 +1,202 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	ScenarioID       string
+	Scenario         *Scenario
+	Seed             int64
+	SnapshotInterval int
+	ETA              bool
+	TraceIDs         bool
+	ClassBreakdown   bool
+	TokenIDs         *TokenIDs
+	Epoch            *time.Time
+	EventFilters     []EventFilter
+	EventRetention   *EventRetention
+	Reasons          []Reason
+	Labels           map[string]string
+	Build            *BuildInfo
+	Observers        []Observer
+	Progress         func(tick, totalTicks int)
+	Stages           []Stage
+	Middleware       []Middleware
+	Limits
+}
+
+type Limits struct {
+	MaxTokens    int
+	MaxEvents    int
+	MaxWallClock time.Duration
+}
+
+type Option func(*Config)
+
+func WithScenario(id string) Option {
+	return func(c *Config) { c.ScenarioID = id }
+}
+
+func WithScenarioSpec(scenario Scenario) Option {
+	return func(c *Config) { c.Scenario = &scenario }
+}
+
+func WithSeed(seed int64) Option {
+	return func(c *Config) { c.Seed = seed }
+}
+
+func WithSnapshotInterval(ticks int) Option {
+	return func(c *Config) { c.SnapshotInterval = ticks }
+}
+
+func WithETA() Option {
+	return func(c *Config) { c.ETA = true }
+}
+
+func WithTraceIDs() Option {
+	return func(c *Config) { c.TraceIDs = true }
+}
+
+func WithClassBreakdown() Option {
+	return func(c *Config) { c.ClassBreakdown = true }
+}
+
+func WithTokenIDs(ids TokenIDs) Option {
+	return func(c *Config) { c.TokenIDs = &ids }
+}
+
+func WithEpoch(epoch time.Time) Option {
+	return func(c *Config) { c.Epoch = &epoch }
+}
+
+func WithEventFilters(filters ...EventFilter) Option {
+	return func(c *Config) { c.EventFilters = append(c.EventFilters, filters...) }
+}
+
+func WithReasons(reasons ...Reason) Option {
+	return func(c *Config) { c.Reasons = append(c.Reasons, reasons...) }
+}
+
+func WithEventRetention(first, last int) Option {
+	return func(c *Config) { c.EventRetention = &EventRetention{First: first, Last: last} }
+}
+
+func WithLabels(labels map[string]string) Option {
+	return func(c *Config) {
+		if c.Labels == nil {
+			c.Labels = make(map[string]string, len(labels))
+		}
+		for key, value := range labels {
+			c.Labels[key] = value
+		}
+	}
+}
+
+func WithBuildInfo(info BuildInfo) Option {
+	return func(c *Config) { c.Build = &info }
+}
+
+func WithObservers(observers ...Observer) Option {
+	return func(c *Config) { c.Observers = append(c.Observers, observers...) }
+}
+
+func WithStages(stages ...Stage) Option {
+	return func(c *Config) { c.Stages = stages }
+}
+
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(c *Config) { c.Middleware = middleware }
+}
+
+func WithProgress(fn func(tick, totalTicks int)) Option {
+	return func(c *Config) { c.Progress = fn }
+}
+
+func WithLimits(limits Limits) Option {
+	return func(c *Config) { c.Limits = limits }
+}
+
+func NewConfig(opts ...Option) Config {
+	var cfg Config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+func New(opts ...Option) (*Simulator, error) {
+	return NewSimulator(NewConfig(opts...))
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	var scenarioErr configError
+	scenario, err := c.scenario()
+	if errors.As(err, &scenarioErr) {
+		errs = append(errs, scenarioErr...)
+	} else if err != nil {
+		errs = append(errs, err)
+	}
+	if c.SnapshotInterval < 0 {
+		errs = append(errs, fmt.Errorf("SnapshotInterval must not be negative, got %d", c.SnapshotInterval))
+	}
+	for key := range c.Labels {
+		if strings.TrimSpace(key) == "" {
+			errs = append(errs, errors.New("Labels must not have an empty key"))
+		}
+	}
+	for i, observer := range c.Observers {
+		if observer == nil {
+			errs = append(errs, fmt.Errorf("Observers[%d] is nil", i))
+		}
+	}
+	errs = append(errs, validateStages(c.Stages)...)
+	errs = append(errs, validateMiddleware(c.Middleware, scenario)...)
+	errs = append(errs, validateEventFilters(c.EventFilters, scenario)...)
+	if c.EventRetention != nil {
+		errs = append(errs, c.EventRetention.validate()...)
+	}
+	errs = append(errs, validateReasons(c.Reasons)...)
+	if c.TokenIDs != nil {
+		errs = append(errs, c.TokenIDs.validate(scenario)...)
+	}
+	if c.MaxTokens < 0 {
+		errs = append(errs, fmt.Errorf("MaxTokens must not be negative, got %d", c.MaxTokens))
+	}
+	if c.MaxEvents < 0 {
+		errs = append(errs, fmt.Errorf("MaxEvents must not be negative, got %d", c.MaxEvents))
+	}
+	if c.MaxWallClock < 0 {
+		errs = append(errs, fmt.Errorf("MaxWallClock must not be negative, got %s", c.MaxWallClock))
+	}
+	if len(errs) > 0 {
+		return configError(errs)
+	}
+	return nil
+}
+
+func (c Config) scenario() (Scenario, error) {
+	if c.Scenario == nil {
+		return LookupScenario(c.ScenarioID)
+	}
+	if err := c.Scenario.Validate(); err != nil {
+		return Scenario{}, err
+	}
+	scenario := *c.Scenario
+	if scenario.Plugin != nil && scenario.Plugin.SHA256 == "" {
+		pinned, err := scenario.Plugin.pin()
+		if err != nil {
+			return Scenario{}, err
+		}
+		scenario.Plugin = &pinned
+	}
+	return scenario, nil
+}
+
+func (c Config) snapshotInterval() int {
+	return max(c.SnapshotInterval, 1)
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewOptions(t *testing.T) {
+	cfg := NewConfig(
+		WithScenario(ScenarioID),
+		WithSeed(9),
+		WithSnapshotInterval(5),
+		WithLimits(Limits{MaxTokens: 1000}),
+	)
+	want := Config{ScenarioID: ScenarioID, Seed: 9, SnapshotInterval: 5, Limits: Limits{MaxTokens: 1000}}
+	if cfg.ScenarioID != want.ScenarioID || cfg.Seed != want.Seed || cfg.SnapshotInterval != want.SnapshotInterval || cfg.Limits != want.Limits {
+		t.Errorf("NewConfig() = %+v, want %+v", cfg, want)
+	}
+
+	sim, err := New(WithSeed(9), WithSnapshotInterval(5))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for sim.Step() {
+	}
+	artifact, err := sim.Artifact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(artifact.Snapshots), TickCount/5+1; got != want {
+		t.Errorf("len(Snapshots) = %d, want %d", got, want)
+	}
+	if last := artifact.Snapshots[len(artifact.Snapshots)-1].Tick; last != TickCount-1 {
+		t.Errorf("last snapshot tick = %d, want %d", last, TickCount-1)
+	}
+	if artifact.Metadata.SnapshotInterval != 5 {
+		t.Errorf("Metadata.SnapshotInterval = %d, want 5", artifact.Metadata.SnapshotInterval)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want []string
+	}{
+		{name: "zero value", cfg: Config{}},
+		{name: "unknown scenario", cfg: Config{ScenarioID: "nope"}, want: []string{"scenario_id"}},
+		{
+			name: "every field",
+			cfg: Config{
+				ScenarioID:       "nope",
+				SnapshotInterval: -1,
+				Observers:        []Observer{nil},
+				Limits:           Limits{MaxTokens: -1, MaxEvents: -1, MaxWallClock: -1},
+			},
+			want: []string{"scenario_id", "SnapshotInterval", "Observers[0]", "MaxTokens", "MaxEvents", "MaxWallClock"},
+		},
+	}
+	for _, tt := range tests {
+		err := tt.cfg.Validate()
+		if len(tt.want) == 0 {
+			if err != nil {
+				t.Errorf("%s: Validate() = %v, want nil", tt.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: Validate() = nil, want errors for %v", tt.name, tt.want)
+			continue
+		}
+		for _, field := range tt.want {
+			if !strings.Contains(err.Error(), field) {
+				t.Errorf("%s: Validate() = %q, missing %s", tt.name, err, field)
+			}
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	labels := map[string]string{"experiment": "baseline", "sha": "abc123"}
+	artifact, err := Run(NewConfig(WithSeed(1), WithLabels(labels)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(artifact.Metadata.Labels, labels) {
+		t.Errorf("Metadata.Labels = %v, want %v", artifact.Metadata.Labels, labels)
+	}
+	plain, err := Run(Config{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if artifact.Metadata.ReplayID != plain.Metadata.ReplayID {
+		t.Error("labels changed the replay id")
+	}
+
+	labels["experiment"] = "changed"
+	if artifact.Metadata.Labels["experiment"] != "baseline" {
+		t.Error("Metadata.Labels shares the caller's map")
+	}
+
+	if err := NewConfig(WithLabels(map[string]string{" ": "x"})).Validate(); err == nil {
+		t.Error("Validate() with an empty label key error = nil")
+	}
+}
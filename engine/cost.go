@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+)
+
+type Costs struct {
+	ServerTick float64            `json:"server_tick,omitempty" yaml:"server_tick,omitempty"`
+	Rejection  float64            `json:"rejection,omitempty" yaml:"rejection,omitempty"`
+	SLAMiss    float64            `json:"sla_miss,omitempty" yaml:"sla_miss,omitempty"`
+	Revenue    map[string]float64 `json:"revenue,omitempty" yaml:"revenue,omitempty"`
+}
+
+type Ledger struct {
+	ServerCost       float64 `json:"server_cost"`
+	RejectionPenalty float64 `json:"rejection_penalty"`
+	SLAPenalty       float64 `json:"sla_penalty"`
+	Revenue          float64 `json:"revenue"`
+	Net              float64 `json:"net"`
+}
+
+func (c Costs) validate(classes map[string]bool) []error {
+	var errs []error
+	for _, field := range []struct {
+		name  string
+		value float64
+	}{{"server_tick", c.ServerTick}, {"rejection", c.Rejection}, {"sla_miss", c.SLAMiss}} {
+		if field.value < 0 {
+			errs = append(errs, fmt.Errorf("costs: %s must not be negative, got %v", field.name, field.value))
+		}
+	}
+	names := make([]string, 0, len(c.Revenue))
+	for class := range c.Revenue {
+		names = append(names, class)
+	}
+	sort.Strings(names)
+	for _, class := range names {
+		if err := unknownClass("costs: revenue", class, classes); err != nil {
+			errs = append(errs, err)
+		}
+		if c.Revenue[class] < 0 {
+			errs = append(errs, fmt.Errorf("costs: revenue for %s must not be negative, got %v", class, c.Revenue[class]))
+		}
+	}
+	return errs
+}
+
+func (l Ledger) Sub(earlier Ledger) Ledger {
+	return Ledger{
+		ServerCost:       l.ServerCost - earlier.ServerCost,
+		RejectionPenalty: l.RejectionPenalty - earlier.RejectionPenalty,
+		SLAPenalty:       l.SLAPenalty - earlier.SLAPenalty,
+		Revenue:          l.Revenue - earlier.Revenue,
+		Net:              l.Net - earlier.Net,
+	}
+}
+
+func (l *Ledger) settle() {
+	l.Net = l.Revenue - l.ServerCost - l.RejectionPenalty - l.SLAPenalty
+}
+
+func (s *Simulator) chargeServers() {
+	if s.ledger == nil {
+		return
+	}
+	s.ledger.ServerCost += float64(s.capacity+len(s.warming)) * s.scenario.Costs.ServerTick
+	s.ledger.settle()
+}
+
+func (s *Simulator) chargeRejection() {
+	if s.ledger == nil {
+		return
+	}
+	s.ledger.RejectionPenalty += s.scenario.Costs.Rejection
+	s.ledger.settle()
+}
+
+func (s *Simulator) chargeSLAMiss() {
+	if s.ledger == nil {
+		return
+	}
+	s.ledger.SLAPenalty += s.scenario.Costs.SLAMiss
+	s.ledger.settle()
+}
+
+func (s *Simulator) earn(token *Token) {
+	if s.ledger == nil {
+		return
+	}
+	s.ledger.Revenue += s.scenario.Costs.Revenue[token.Class]
+	s.ledger.settle()
+}
+
+func (s *Simulator) ledgerSnapshot() *Ledger {
+	if s.ledger == nil {
+		return nil
+	}
+	ledger := *s.ledger
+	return &ledger
+}
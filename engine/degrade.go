@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	QualityFull     = "full"
+	QualityDegraded = "degraded"
+)
+
+type Degradation struct {
+	QueueLength  int `json:"queue_length" yaml:"queue_length"`
+	SustainTicks int `json:"sustain_ticks" yaml:"sustain_ticks"`
+	RecoverTicks int `json:"recover_ticks" yaml:"recover_ticks"`
+	ServiceTime  int `json:"service_time" yaml:"service_time"`
+}
+
+func (d Degradation) validate(serviceTime int) []error {
+	var errs []error
+	if d.QueueLength <= 0 {
+		errs = append(errs, fmt.Errorf("degradation: queue_length must be positive, got %d", d.QueueLength))
+	}
+	if d.SustainTicks <= 0 {
+		errs = append(errs, fmt.Errorf("degradation: sustain_ticks must be positive, got %d", d.SustainTicks))
+	}
+	if d.RecoverTicks <= 0 {
+		errs = append(errs, fmt.Errorf("degradation: recover_ticks must be positive, got %d", d.RecoverTicks))
+	}
+	if d.ServiceTime <= 0 || d.ServiceTime > serviceTime {
+		errs = append(errs, fmt.Errorf("degradation: service_time must be between 1 and service_time %d, got %d", serviceTime, d.ServiceTime))
+	}
+	return errs
+}
+
+func degradable(class string) bool {
+	return class == ClassFree || class == ClassAnon
+}
+
+func (s *Simulator) evaluateDegradation(tick int) {
+	d := s.scenario.Degradation
+	if d == nil {
+		return
+	}
+	overloaded := s.queueLength() >= d.QueueLength
+	if overloaded != s.degraded {
+		s.degradeStreak++
+	} else {
+		s.degradeStreak = 0
+	}
+
+	eventType, reason, token := EventDegradeStart, ReasonSustainedOverload, s.lastQueued
+	switch {
+	case !s.degraded && s.degradeStreak >= d.SustainTicks:
+	case s.degraded && s.degradeStreak >= d.RecoverTicks:
+		eventType, reason, token = EventDegradeEnd, ReasonOverloadCleared, s.lastScheduled
+	default:
+		return
+	}
+	s.degraded = !s.degraded
+	s.degradeStreak = 0
+	context := s.newContext()
+	*context = EventContext{
+		Rule:        RuleDegradeUnderLoad,
+		QueueLength: s.queueLength(),
+		Limit:       d.QueueLength,
+	}
+	s.emit(Event{
+		Tick:       tick,
+		Type:       eventType,
+		ReasonCode: reason,
+		TokenID:    token.ID,
+		StageID:    token.StageID,
+		Class:      token.Class,
+		Context:    context,
+	})
+}
+
+func (s *Simulator) serviceTimeFor(token *Token) int {
+	if s.degraded && degradable(token.Class) {
+		token.quality = QualityDegraded
+		return scaleService(s.scenario.Degradation.ServiceTime, s.classes.def(token.Class).ServiceMultiplier)
+	}
+	if s.scenario.Degradation != nil {
+		token.quality = QualityFull
+	}
+	if token.size > 0 {
+		return token.size
+	}
+	return s.jobSize(token)
+}
+
+func (s *Simulator) jobSize(token *Token) int {
+	class := s.classes.def(token.Class)
+	ticks := s.serviceTime
+	if class.Service != nil {
+		ticks = class.Service.sample(s.serviceRNG)
+	}
+	return scaleService(ticks, class.ServiceMultiplier)
+}
+
+func scaleService(ticks int, multiplier float64) int {
+	if multiplier <= 0 {
+		return ticks
+	}
+	return max(int(math.Ceil(float64(ticks)*multiplier)), 1)
+}
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	DisciplineFIFO = "fifo"
+	DisciplineLIFO = "lifo"
+	DisciplineSJF  = "sjf"
+
+	ServiceFCFS = "fcfs"
+	ServicePS   = "processor_sharing"
+)
+
+type Discipline struct {
+	Queue   string `json:"queue,omitempty" yaml:"queue,omitempty"`
+	Service string `json:"service,omitempty" yaml:"service,omitempty"`
+}
+
+func (d Discipline) validate() []error {
+	var errs []error
+	switch d.Queue {
+	case "", DisciplineFIFO, DisciplineLIFO, DisciplineSJF:
+	default:
+		errs = append(errs, fmt.Errorf("discipline: unknown queue discipline %q (want %s, %s or %s)", d.Queue, DisciplineFIFO, DisciplineLIFO, DisciplineSJF))
+	}
+	switch d.Service {
+	case "", ServiceFCFS, ServicePS:
+	default:
+		errs = append(errs, fmt.Errorf("discipline: unknown service discipline %q (want %s or %s)", d.Service, ServiceFCFS, ServicePS))
+	}
+	if d.Service == ServicePS && d.Queue != "" && d.Queue != DisciplineFIFO {
+		errs = append(errs, fmt.Errorf("discipline: queue %s has no effect under %s, which serves every request at once", d.Queue, ServicePS))
+	}
+	return errs
+}
+
+func (s Scenario) processorSharing() bool {
+	return s.Discipline != nil && s.Discipline.Service == ServicePS
+}
+
+func (s Scenario) queueDiscipline() string {
+	if s.Discipline == nil {
+		return ""
+	}
+	return s.Discipline.Queue
+}
+
+func (s *Simulator) scheduleReason(token *Token) string {
+	if token.affinityWait {
+		return ReasonAffinitySchedule
+	}
+	if s.scenario.processorSharing() {
+		return ReasonPSSchedule
+	}
+	if s.scriptedSchedule() {
+		return s.script.scheduleReason
+	}
+	switch s.scenario.queueDiscipline() {
+	case DisciplineLIFO:
+		return ReasonLIFOSchedule
+	case DisciplineSJF:
+		return ReasonSJFSchedule
+	}
+	return ReasonPrioritySchedule
+}
+
+func (s *Simulator) shareRate() float64 {
+	servers := s.capacity - s.draining - len(s.stalled) - s.stalledIdle
+	running := len(s.inService) - len(s.stalled)
+	if servers <= 0 || running <= 0 {
+		return 0
+	}
+	return math.Min(1, float64(servers)/float64(running))
+}
+
+func (s *Simulator) advance(token *Token, rate float64) bool {
+	if !s.scenario.processorSharing() {
+		token.ServiceRemaining--
+		return token.ServiceRemaining <= 0
+	}
+	token.work -= rate
+	if token.work <= 1e-9 {
+		token.ServiceRemaining = 0
+		return true
+	}
+	token.ServiceRemaining = int(math.Ceil(token.work - 1e-9))
+	return false
+}
@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"math/rand"
+	"sort"
+)
+
+type StateDump struct {
+	Tick         int            `json:"tick"`
+	NextToken    string         `json:"next_token"`
+	Capacity     int            `json:"capacity"`
+	Busy         int            `json:"busy"`
+	Warming      []int          `json:"warming,omitempty"`
+	Draining     int            `json:"draining,omitempty"`
+	Degraded     bool           `json:"degraded,omitempty"`
+	Backpressure bool           `json:"backpressure,omitempty"`
+	Breaker      string         `json:"breaker,omitempty"`
+	Queue        []QueuedToken  `json:"queue"`
+	InService    []TokenState   `json:"in_service"`
+	Held         []TokenState   `json:"held,omitempty"`
+	Transit      []TokenState   `json:"transit,omitempty"`
+	Staged       []TokenState   `json:"staged,omitempty"`
+	Retries      []PendingRetry `json:"retries,omitempty"`
+	RNG          []RNGPosition  `json:"rng"`
+}
+
+type QueuedToken struct {
+	TokenState
+	WaitTicks int `json:"wait_ticks"`
+	Size      int `json:"size,omitempty"`
+}
+
+type PendingRetry struct {
+	Tick    int    `json:"tick"`
+	TokenID string `json:"token_id"`
+	Attempt int    `json:"attempt"`
+}
+
+type RNGPosition struct {
+	Stream string `json:"stream"`
+	Seed   int64  `json:"seed"`
+	Draws  uint64 `json:"draws"`
+}
+
+type countingSource struct {
+	rand.Source64
+	draws uint64
+}
+
+func (c *countingSource) Int63() int64 {
+	c.draws++
+	return c.Source64.Int63()
+}
+
+func (c *countingSource) Uint64() uint64 {
+	c.draws++
+	return c.Source64.Uint64()
+}
+
+type rngStream struct {
+	name   string
+	seed   int64
+	source *countingSource
+}
+
+func (s *Simulator) newRNG(stream string, seed int64) *rand.Rand {
+	source := &countingSource{Source64: rand.NewSource(seed).(rand.Source64)}
+	s.streams = append(s.streams, rngStream{name: stream, seed: seed, source: source})
+	return rand.New(source)
+}
+
+func (s *Simulator) DumpState() StateDump {
+	tick := s.tick - 1
+	dump := StateDump{
+		Tick:         tick,
+		NextToken:    tokenID(s.nextID),
+		Capacity:     s.capacity,
+		Busy:         s.busy(),
+		Warming:      append([]int(nil), s.warming...),
+		Draining:     s.draining,
+		Degraded:     s.degraded,
+		Backpressure: s.pressure,
+		Queue:        []QueuedToken{},
+		InService:    s.InService(),
+	}
+	if s.ids != nil {
+		dump.NextToken = s.ids.peek()
+	}
+	if s.breaker != nil {
+		dump.Breaker = s.breaker.state
+	}
+	s.queue.each(func(_ int, token *Token) bool {
+		since := token.ArrivalTick
+		if token.requeuedTick > since {
+			since = token.requeuedTick
+		}
+		dump.Queue = append(dump.Queue, QueuedToken{TokenState: s.tokenState(token), WaitTicks: tick - since, Size: token.size})
+		return true
+	})
+	for _, token := range s.held {
+		dump.Held = append(dump.Held, s.tokenState(token))
+	}
+	for _, token := range s.transit {
+		dump.Transit = append(dump.Transit, s.tokenState(token))
+	}
+	for _, token := range s.staged {
+		dump.Staged = append(dump.Staged, s.tokenState(token))
+	}
+	sort.Slice(dump.Staged, func(i, j int) bool { return dump.Staged[i].ID < dump.Staged[j].ID })
+	dues := make([]int, 0, len(s.retries))
+	for due := range s.retries {
+		dues = append(dues, due)
+	}
+	sort.Ints(dues)
+	for _, due := range dues {
+		for _, token := range s.retries[due] {
+			dump.Retries = append(dump.Retries, PendingRetry{Tick: due, TokenID: token.ID, Attempt: token.attempt})
+		}
+	}
+	for _, stream := range s.streams {
+		dump.RNG = append(dump.RNG, RNGPosition{Stream: stream.name, Seed: stream.seed, Draws: stream.source.draws})
+	}
+	return dump
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestDumpState(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.Classes = defaultClasses(scenario.RejectThreshold)
+	scenario.Classes[0].ErrorRate = 0.2
+	scenario.Retry = &Retry{Attempts: 2, DelayTicks: 3}
+	scenario.Capacity = 2
+	sim, err := NewSimulator(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sim.Close()
+
+	queued, retried := false, false
+	for sim.Step() {
+		dump := sim.DumpState()
+		snapshot := sim.Snapshots()[len(sim.Snapshots())-1]
+		if dump.Tick != snapshot.Tick {
+			t.Fatalf("DumpState().Tick = %d, want %d", dump.Tick, snapshot.Tick)
+		}
+		var want []string
+		waiting := make(map[string]int)
+		for _, token := range snapshot.Tokens {
+			if token.State == StateQueued {
+				waiting[token.ID] = token.QueueIndex
+				want = append(want, token.ID)
+			}
+		}
+		sort.Slice(want, func(i, j int) bool { return waiting[want[i]] < waiting[want[j]] })
+		var got []string
+		for _, token := range dump.Queue {
+			got = append(got, token.ID)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("tick %d: DumpState().Queue = %v, want %v", dump.Tick, got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("tick %d: DumpState().Queue = %v, want %v", dump.Tick, got, want)
+			}
+		}
+		queued = queued || len(dump.Queue) > 0
+		retried = retried || len(dump.Retries) > 0
+	}
+	if !queued || !retried {
+		t.Errorf("dumps saw a queue %v and pending retries %v, want both", queued, retried)
+	}
+
+	draws := make(map[string]uint64)
+	for _, position := range sim.DumpState().RNG {
+		draws[position.Stream] = position.Draws
+	}
+	if draws["arrivals"] == 0 || draws["failures"] == 0 {
+		t.Errorf("DumpState().RNG draws = %v, want arrivals and failures drawn from", draws)
+	}
+}
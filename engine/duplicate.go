@@ -0,0 +1,157 @@
+package engine
+
+import "fmt"
+
+const duplicateSuffix = "d"
+
+const (
+	DedupDrop     = "drop"
+	DedupCoalesce = "coalesce"
+)
+
+type Duplicates struct {
+	Probability float64 `json:"probability" yaml:"probability"`
+	DelayTicks  int     `json:"delay_ticks" yaml:"delay_ticks"`
+	QueueLength int     `json:"queue_length,omitempty" yaml:"queue_length,omitempty"`
+}
+
+type Dedup struct {
+	Mode string `json:"mode" yaml:"mode"`
+}
+
+func (d Duplicates) validate() []error {
+	var errs []error
+	if d.Probability <= 0 || d.Probability > 1 {
+		errs = append(errs, fmt.Errorf("duplicates: probability must be in (0, 1], got %v", d.Probability))
+	}
+	if d.DelayTicks <= 0 {
+		errs = append(errs, fmt.Errorf("duplicates: delay_ticks must be positive, got %d", d.DelayTicks))
+	}
+	if d.QueueLength < 0 {
+		errs = append(errs, fmt.Errorf("duplicates: queue_length must not be negative, got %d", d.QueueLength))
+	}
+	return errs
+}
+
+func (d Dedup) validate() []error {
+	switch d.Mode {
+	case DedupDrop, DedupCoalesce:
+		return nil
+	default:
+		return []error{fmt.Errorf("dedup: unknown mode %q (want %s or %s)", d.Mode, DedupDrop, DedupCoalesce)}
+	}
+}
+
+func (t *Token) duplicateKey() string {
+	if t.duplicateOf == nil && t.attempt == 0 {
+		return ""
+	}
+	return t.RequestKey
+}
+
+func (s *Simulator) planDuplicate(tick int, token *Token) {
+	d := s.scenario.Duplicates
+	if d == nil || s.queueLength() < d.QueueLength {
+		return
+	}
+	if s.duplicateRNG.Float64() >= d.Probability {
+		return
+	}
+	due := tick + d.DelayTicks
+	if due >= TickCount {
+		return
+	}
+	token.RequestKey = token.ID
+	s.duplicates[due] = append(s.duplicates[due], token)
+}
+
+func (s *Simulator) duplicateArrivals(tick int) {
+	originals := s.duplicates[tick]
+	delete(s.duplicates, tick)
+	for _, original := range originals {
+		duplicate := s.takeToken()
+		*duplicate = Token{
+			ID:          original.ID + duplicateSuffix,
+			Class:       original.Class,
+			ArrivalTick: tick,
+			QueueIndex:  -1,
+			RequestKey:  original.RequestKey,
+			Session:     original.Session,
+			duplicateOf: original,
+		}
+		s.tokens = append(s.tokens, duplicate)
+		s.active = append(s.active, duplicate)
+
+		dedup := s.scenario.Dedup
+		if dedup == nil {
+			s.dispatch(tick, duplicate)
+			continue
+		}
+		reason, rule := ReasonDuplicateDropped, RuleDedupDrop
+		if dedup.Mode == DedupCoalesce {
+			reason, rule = ReasonDuplicateCoalesced, RuleDedupCoalesce
+		}
+		context := s.newContext()
+		*context = EventContext{
+			Rule:        rule,
+			QueueLength: s.queueLength(),
+			RequestKey:  duplicate.RequestKey,
+		}
+		s.emit(Event{
+			Tick:       tick,
+			Type:       EventDuplicateDrop,
+			ReasonCode: reason,
+			TokenID:    duplicate.ID,
+			StageID:    original.StageID,
+			Class:      duplicate.Class,
+			Context:    context,
+		})
+		switch {
+		case dedup.Mode == DedupDrop:
+			duplicate.State = StateRejected
+			duplicate.StageID = StageRejected
+		case original.State == StateDone || original.State == StateCancelled:
+			s.settleDuplicate(tick, duplicate, true)
+		case original.State == StateFailed:
+			s.settleDuplicate(tick, duplicate, false)
+		default:
+			duplicate.State = StateCoalesced
+			duplicate.StageID = original.StageID
+			original.followers = append(original.followers, duplicate)
+		}
+	}
+}
+
+func (s *Simulator) settleDuplicates(tick int, request *Token, ok bool) {
+	for _, duplicate := range request.followers {
+		s.settleDuplicate(tick, duplicate, ok)
+	}
+	request.followers = nil
+}
+
+func (s *Simulator) settleDuplicate(tick int, duplicate *Token, ok bool) {
+	eventType, reason := EventComplete, ReasonServiceComplete
+	duplicate.State = StateDone
+	duplicate.StageID = StageDone
+	if !ok {
+		eventType, reason = EventFail, ReasonServiceError
+		duplicate.State = StateFailed
+		duplicate.StageID = StageFailed
+	}
+	s.recordOutcome(tick, duplicate, ok)
+	context := s.newContext()
+	*context = EventContext{
+		Rule:        RuleDedupCoalesce,
+		QueueLength: s.queueLength(),
+		RequestKey:  duplicate.RequestKey,
+	}
+	s.emit(Event{
+		Tick:       tick,
+		Type:       eventType,
+		ReasonCode: reason,
+		TokenID:    duplicate.ID,
+		StageID:    duplicate.StageID,
+		Class:      duplicate.Class,
+		Context:    context,
+	})
+}
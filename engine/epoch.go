@@ -0,0 +1,20 @@
+package engine
+
+import "time"
+
+const wallClockLayout = "2006-01-02T15:04:05.000Z07:00"
+
+func (m Metadata) WallClock(tick int) (time.Time, bool) {
+	if m.Epoch == nil {
+		return time.Time{}, false
+	}
+	return m.Epoch.Add(time.Duration(tick*m.TickDurationMs) * time.Millisecond), true
+}
+
+func (s *Simulator) wallClock(tick int) string {
+	if tick != s.clockTick || s.clockTime == "" {
+		s.clockTick = tick
+		s.clockTime = s.epoch.Add(time.Duration(tick*TickDurationMs) * time.Millisecond).Format(wallClockLayout)
+	}
+	return s.clockTime
+}
@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEpoch(t *testing.T) {
+	epoch := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	artifact, err := Run(NewConfig(WithSeed(1), WithEpoch(epoch)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := artifact.Metadata; m.Epoch == nil || !m.Epoch.Equal(epoch) {
+		t.Fatalf("Metadata.Epoch = %v, want %v", m.Epoch, epoch)
+	}
+	tests := []struct {
+		tick int
+		want string
+	}{
+		{tick: 0, want: "2024-06-01T12:00:00.000Z"},
+		{tick: 1, want: "2024-06-01T12:00:00.250Z"},
+		{tick: 239, want: "2024-06-01T12:00:59.750Z"},
+	}
+	for _, tt := range tests {
+		if got := artifact.Snapshots[tt.tick].Time; got != tt.want {
+			t.Errorf("snapshot %d time = %q, want %q", tt.tick, got, tt.want)
+		}
+		if got, _ := artifact.Metadata.WallClock(tt.tick); got.Format(wallClockLayout) != tt.want {
+			t.Errorf("WallClock(%d) = %v, want %s", tt.tick, got, tt.want)
+		}
+	}
+	for _, event := range artifact.Events {
+		if want := artifact.Snapshots[event.Tick].Time; event.Time != want {
+			t.Fatalf("event at tick %d time = %q, want %q", event.Tick, event.Time, want)
+		}
+	}
+
+	plain, err := Run(NewConfig(WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := plain.Metadata.WallClock(0); ok || plain.Snapshots[0].Time != "" || plain.Events[0].Time != "" {
+		t.Error("a run without an epoch has wall-clock times")
+	}
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrUnknownScenario = errors.New("unknown scenario_id")
+	ErrInvalidConfig   = errors.New("invalid config")
+)
+
+type configError []error
+
+func (e configError) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return ErrInvalidConfig.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e configError) Unwrap() []error {
+	return append([]error{ErrInvalidConfig}, e...)
+}
+
+type ArtifactDecodeError struct {
+	Path   string
+	Offset int64
+	Err    error
+}
+
+func (e *ArtifactDecodeError) Error() string {
+	if e.Offset > 0 {
+		return fmt.Sprintf("decode %s at offset %d: %v", e.Path, e.Offset, e.Err)
+	}
+	return fmt.Sprintf("decode %s: %v", e.Path, e.Err)
+}
+
+func (e *ArtifactDecodeError) Unwrap() error {
+	return e.Err
+}
+
+func decodeError(path string, err error) error {
+	decodeErr := &ArtifactDecodeError{Path: path, Err: err}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		decodeErr.Offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		decodeErr.Offset = typeErr.Offset
+	}
+	return decodeErr
+}
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigErrors(t *testing.T) {
+	_, err := NewSimulator(Config{ScenarioID: "nope", SnapshotInterval: -1})
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("NewSimulator() error = %v, want ErrInvalidConfig", err)
+	}
+	if !errors.Is(err, ErrUnknownScenario) {
+		t.Errorf("NewSimulator() error = %v, want ErrUnknownScenario", err)
+	}
+	if want := "invalid config: unknown scenario_id: nope; SnapshotInterval must not be negative, got -1"; err.Error() != want {
+		t.Errorf("NewSimulator() error = %q, want %q", err, want)
+	}
+	if _, err := LookupScenario("nope"); !errors.Is(err, ErrUnknownScenario) {
+		t.Errorf("LookupScenario() error = %v, want ErrUnknownScenario", err)
+	}
+}
+
+func TestArtifactDecodeError(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name       string
+		data       string
+		wantOffset int64
+	}{
+		{name: "syntax", data: `{"metadata": {"seed": 1,}}`, wantOffset: 25},
+		{name: "type", data: `{"metadata": {"seed": "one"}}`, wantOffset: 27},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name+".json")
+		if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := ReadArtifact(path)
+		var decodeErr *ArtifactDecodeError
+		if !errors.As(err, &decodeErr) {
+			t.Errorf("%s: ReadArtifact() error = %v, want *ArtifactDecodeError", tt.name, err)
+			continue
+		}
+		if decodeErr.Path != path || decodeErr.Offset != tt.wantOffset {
+			t.Errorf("%s: ArtifactDecodeError = {%s %d}, want {%s %d}", tt.name, decodeErr.Path, decodeErr.Offset, path, tt.wantOffset)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package engine
+
+import "math"
+
+const etaSmoothing = 0.2
+
+type etaEstimator struct {
+	capacity     int
+	serviceTicks int
+	served       int
+	arrivals     []float64
+	tickArrivals []int
+}
+
+func newETAEstimator(scenario Scenario, lanes int) *etaEstimator {
+	return &etaEstimator{
+		capacity:     scenario.Capacity,
+		serviceTicks: scenario.ServiceTime,
+		served:       1,
+		arrivals:     make([]float64, lanes),
+		tickArrivals: make([]int, lanes),
+	}
+}
+
+func (e *etaEstimator) observeArrival(lane int) {
+	e.tickArrivals[lane]++
+}
+
+func (e *etaEstimator) observeService(ticks int) {
+	e.serviceTicks += ticks
+	e.served++
+}
+
+func (e *etaEstimator) endTick() {
+	for i := range e.arrivals {
+		e.arrivals[i] += etaSmoothing * (float64(e.tickArrivals[i]) - e.arrivals[i])
+		e.tickArrivals[i] = 0
+	}
+}
+
+func (e *etaEstimator) serviceRate() float64 {
+	return float64(e.capacity) * float64(e.served) / float64(e.serviceTicks)
+}
+
+func (e *etaEstimator) estimateMs(index int, lane int) int {
+	rate := e.serviceRate()
+	for i := 0; i < lane; i++ {
+		rate -= e.arrivals[i]
+	}
+	if rate <= 0 {
+		return -1
+	}
+	return int(math.Ceil(float64(index+1)/rate)) * TickDurationMs
+}
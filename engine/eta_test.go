@@ -0,0 +1,71 @@
+package engine
+
+import "testing"
+
+func TestETAEstimator(t *testing.T) {
+	scenario := Scenario{Capacity: 2, ServiceTime: 4}
+	tests := []struct {
+		name     string
+		arrivals map[string]int
+		index    int
+		class    string
+		want     int
+	}{
+		{name: "head of queue", index: 0, class: ClassPaid, want: 2 * TickDurationMs},
+		{name: "position", index: 3, class: ClassPaid, want: 8 * TickDurationMs},
+		{name: "own class arrivals ignored", arrivals: map[string]int{ClassFree: 2}, index: 0, class: ClassFree, want: 2 * TickDurationMs},
+		{name: "higher class arrivals", arrivals: map[string]int{ClassPaid: 1}, index: 0, class: ClassFree, want: 4 * TickDurationMs},
+		{name: "unbounded", arrivals: map[string]int{ClassPaid: 3}, index: 0, class: ClassAnon, want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newETAEstimator(scenario, 3)
+			for class, n := range tt.arrivals {
+				for i := 0; i < n; i++ {
+					e.observeArrival(lane(class))
+				}
+			}
+			e.endTick()
+			if got := e.estimateMs(tt.index, lane(tt.class)); got != tt.want {
+				t.Errorf("estimateMs(%d, %s) = %d, want %d", tt.index, tt.class, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_ETA(t *testing.T) {
+	artifact, err := Run(NewConfig(WithSeed(1), WithETA()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !artifact.Metadata.ETA {
+		t.Error("Metadata.ETA = false, want true")
+	}
+	estimated := 0
+	for _, snapshot := range artifact.Snapshots {
+		for _, token := range snapshot.Tokens {
+			queued := token.State == StateQueued
+			if queued != (token.EtaMs != 0) {
+				t.Fatalf("tick %d: %s %s has eta_ms %d", snapshot.Tick, token.ID, token.State, token.EtaMs)
+			}
+			if queued {
+				estimated++
+			}
+		}
+	}
+	if estimated == 0 {
+		t.Error("no queued token received an eta_ms")
+	}
+
+	plain, err := Run(Config{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, snapshot := range plain.Snapshots {
+		for _, token := range snapshot.Tokens {
+			if token.EtaMs != 0 {
+				t.Fatalf("eta_ms recorded without ETA: %+v", token)
+			}
+		}
+	}
+}
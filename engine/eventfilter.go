@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+)
+
+type EventFilter struct {
+	Types   []string `json:"types,omitempty"`
+	Classes []string `json:"classes,omitempty"`
+	Sample  int      `json:"sample,omitempty"`
+}
+
+func (f EventFilter) matches(event Event) bool {
+	return (len(f.Types) == 0 || slices.Contains(f.Types, event.Type)) &&
+		(len(f.Classes) == 0 || slices.Contains(f.Classes, event.Class))
+}
+
+func validateEventFilters(filters []EventFilter, scenario Scenario) []error {
+	var errs []error
+	classes := scenario.classSet()
+	for i, f := range filters {
+		name := fmt.Sprintf("EventFilters[%d]", i)
+		if len(f.Types) == 0 && len(f.Classes) == 0 {
+			errs = append(errs, fmt.Errorf("%s: set Types, Classes, or both", name))
+		}
+		for _, eventType := range f.Types {
+			if eventType == "" {
+				errs = append(errs, fmt.Errorf("%s: empty event type", name))
+			}
+		}
+		for _, class := range f.Classes {
+			if !classes[class] {
+				errs = append(errs, fmt.Errorf("%s: unknown class %q", name, class))
+			}
+		}
+		if f.Sample < 0 {
+			errs = append(errs, fmt.Errorf("%s: Sample must not be negative, got %d", name, f.Sample))
+		}
+	}
+	return errs
+}
+
+func (s *Simulator) filtered(event Event) bool {
+	for i, f := range s.eventFilters {
+		if !f.matches(event) {
+			continue
+		}
+		if f.Sample == 0 {
+			return true
+		}
+		seen := s.filterSeen[i]
+		s.filterSeen[i]++
+		return seen%f.Sample != 0
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEventFilters(t *testing.T) {
+	plain, err := Run(NewConfig(WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filters := []EventFilter{
+		{Types: []string{EventQueue}, Classes: []string{ClassAnon}},
+		{Types: []string{EventSchedule}, Sample: 3},
+	}
+	filtered, err := Run(NewConfig(WithSeed(1), WithEventFilters(filters...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want []Event
+	scheduled := 0
+	for _, event := range plain.Events {
+		switch {
+		case event.Type == EventQueue && event.Class == ClassAnon:
+			continue
+		case event.Type == EventSchedule:
+			scheduled++
+			if (scheduled-1)%3 != 0 {
+				continue
+			}
+		}
+		want = append(want, event)
+	}
+	if !reflect.DeepEqual(filtered.Events, want) {
+		t.Errorf("filtered run has %d events, want %d: every ANON QUEUE dropped and one in three SCHEDULE kept", len(filtered.Events), len(want))
+	}
+	if !reflect.DeepEqual(filtered.Snapshots, plain.Snapshots) {
+		t.Error("event filters changed the snapshots")
+	}
+	if !reflect.DeepEqual(filtered.Metadata.EventFilters, filters) {
+		t.Errorf("Metadata.EventFilters = %+v, want %+v", filtered.Metadata.EventFilters, filters)
+	}
+}
+
+func TestEventFilters_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  EventFilter
+		wantErr string
+	}{
+		{name: "empty", filter: EventFilter{Sample: 2}, wantErr: "EventFilters[0]: set Types, Classes, or both"},
+		{name: "type", filter: EventFilter{Types: []string{""}}, wantErr: "empty event type"},
+		{name: "class", filter: EventFilter{Classes: []string{"GOLD"}}, wantErr: `unknown class "GOLD"`},
+		{name: "sample", filter: EventFilter{Types: []string{EventQueue}, Sample: -1}, wantErr: "Sample must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewConfig(WithEventFilters(tt.filter)).Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
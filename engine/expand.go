@@ -0,0 +1,43 @@
+package engine
+
+type SnapshotExpander struct {
+	order []string
+	last  map[string]TokenState
+}
+
+func NewSnapshotExpander() *SnapshotExpander {
+	return &SnapshotExpander{last: make(map[string]TokenState)}
+}
+
+func (e *SnapshotExpander) Expand(snapshot Snapshot) Snapshot {
+	for _, token := range snapshot.Tokens {
+		if _, ok := e.last[token.ID]; !ok {
+			e.order = append(e.order, token.ID)
+		}
+		e.last[token.ID] = token
+	}
+	tokens := make([]TokenState, len(e.order))
+	for i, id := range e.order {
+		tokens[i] = e.last[id]
+	}
+	snapshot.Tokens = tokens
+	return snapshot
+}
+
+func (m Metadata) FullSnapshots() bool {
+	return m.SnapshotSchema == "" || m.SnapshotSchema == SnapshotSchemaFull
+}
+
+func ExpandSnapshots(artifact Artifact) Artifact {
+	if artifact.Metadata.FullSnapshots() {
+		return artifact
+	}
+	expander := NewSnapshotExpander()
+	snapshots := make([]Snapshot, len(artifact.Snapshots))
+	for i, snapshot := range artifact.Snapshots {
+		snapshots[i] = expander.Expand(snapshot)
+	}
+	artifact.Metadata.SnapshotSchema = ""
+	artifact.Snapshots = snapshots
+	return artifact
+}
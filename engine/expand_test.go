@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+const fullSnapshotsSHA256 = "a788f2d093e15bded14c18c4789529c85dbca41eb5151e1159a1233094ecf1c9"
+
+func TestActiveSnapshots(t *testing.T) {
+	artifact, err := Run(Config{Seed: 1})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if artifact.Metadata.SnapshotSchema != SnapshotSchemaActive {
+		t.Fatalf("SnapshotSchema = %q, want %q", artifact.Metadata.SnapshotSchema, SnapshotSchemaActive)
+	}
+
+	terminal := make(map[string]int)
+	for _, snapshot := range artifact.Snapshots {
+		for _, token := range snapshot.Tokens {
+			if token.State == StateDone || token.State == StateRejected {
+				terminal[token.ID]++
+			}
+		}
+	}
+	for id, n := range terminal {
+		if n != 1 {
+			t.Errorf("terminal token %s appears in %d snapshots, want 1", id, n)
+		}
+	}
+	if len(terminal) == 0 {
+		t.Fatal("expected terminal tokens in the canonical run")
+	}
+}
+
+func TestExpandSnapshots(t *testing.T) {
+	artifact, err := Run(Config{Seed: 1})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	expanded := ExpandSnapshots(artifact)
+	if !expanded.Metadata.FullSnapshots() {
+		t.Errorf("expanded SnapshotSchema = %q", expanded.Metadata.SnapshotSchema)
+	}
+	legacy := expanded
+	legacy.Metadata.ReplayID = ReplayID(ScenarioID, 1, EngineVersion)
+	legacy.Metadata.Scenario = nil
+	legacy.Metadata.Classes = nil
+	data, err := MarshalArtifact(legacy)
+	if err != nil {
+		t.Fatalf("MarshalArtifact() error = %v", err)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != fullSnapshotsSHA256 {
+		t.Errorf("expanded artifact sha256 = %s, want the pre-compaction artifact %s", got, fullSnapshotsSHA256)
+	}
+
+	if again := ExpandSnapshots(expanded); len(again.Snapshots[100].Tokens) != len(expanded.Snapshots[100].Tokens) {
+		t.Error("ExpandSnapshots() on a full artifact changed it")
+	}
+}
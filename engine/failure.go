@@ -0,0 +1,104 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const retrySuffix = "r"
+
+type Retry struct {
+	Attempts   int `json:"attempts" yaml:"attempts"`
+	DelayTicks int `json:"delay_ticks" yaml:"delay_ticks"`
+}
+
+func (r Retry) validate() []error {
+	var errs []error
+	if r.Attempts <= 0 {
+		errs = append(errs, fmt.Errorf("retry: attempts must be positive, got %d", r.Attempts))
+	}
+	if r.DelayTicks <= 0 {
+		errs = append(errs, fmt.Errorf("retry: delay_ticks must be positive, got %d", r.DelayTicks))
+	}
+	return errs
+}
+
+func failingClasses(classes []ClassDef) bool {
+	for _, class := range classes {
+		if class.ErrorRate > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Simulator) fails(token *Token) bool {
+	if s.failureRNG == nil {
+		return false
+	}
+	rate := s.classes.def(token.Class).ErrorRate
+	return rate > 0 && s.failureRNG.Float64() < rate
+}
+
+func (s *Simulator) fail(tick int, token *Token, reason, rule string) {
+	token.State = StateFailed
+	token.StageID = StageFailed
+	token.QueueIndex = -1
+	request := token.request()
+	s.recordOutcome(tick, request, false)
+	context := s.newContext()
+	*context = EventContext{Rule: rule, QueueLength: s.queueLength()}
+	if token.hedgeOf != nil {
+		context.Hedge = token.ID
+	}
+	s.emit(Event{
+		Tick:       tick,
+		Type:       EventFail,
+		ReasonCode: reason,
+		TokenID:    request.ID,
+		StageID:    StageFailed,
+		Class:      token.Class,
+		Context:    context,
+	})
+	s.cancelCopy(tick, token)
+	s.settleDuplicates(tick, request, false)
+	s.observeBreaker(tick, request, false)
+	s.planRetry(tick, request)
+}
+
+func (s *Simulator) planRetry(tick int, token *Token) {
+	r := s.scenario.Retry
+	if r == nil || token.attempt >= r.Attempts {
+		return
+	}
+	due := tick + r.DelayTicks
+	if due >= TickCount {
+		return
+	}
+	s.retries[due] = append(s.retries[due], token)
+}
+
+func (s *Simulator) retryArrivals(tick int) {
+	failed := s.retries[tick]
+	delete(s.retries, tick)
+	for _, previous := range failed {
+		root := previous.ID
+		if previous.attempt > 0 {
+			root = previous.RequestKey
+		}
+		attempt := previous.attempt + 1
+		retry := s.takeToken()
+		*retry = Token{
+			ID:          root + retrySuffix + strconv.Itoa(attempt),
+			Class:       previous.Class,
+			ArrivalTick: tick,
+			QueueIndex:  -1,
+			RequestKey:  root,
+			Session:     previous.Session,
+			attempt:     attempt,
+		}
+		s.tokens = append(s.tokens, retry)
+		s.active = append(s.active, retry)
+		s.dispatch(tick, retry)
+	}
+}
@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+var generatedClassNames = []string{"GOLD", "SILVER", "BRONZE", "BATCH"}
+
+func RandomScenario(seed int64) Scenario {
+	rng := rand.New(rand.NewSource(DeriveSeed(seed, "genscenario")))
+	between := func(lo, hi int) int { return lo + rng.Intn(hi-lo+1) }
+	uniform := func(lo, hi float64) float64 { return round2(lo + rng.Float64()*(hi-lo)) }
+	chance := func(p float64) bool { return rng.Float64() < p }
+
+	s := Scenario{
+		ID:              fmt.Sprintf("random_%d", seed),
+		Capacity:        between(1, 8),
+		ServiceTime:     between(1, 6),
+		RejectThreshold: between(4, 40),
+	}
+	if chance(0.3) {
+		s.WarmupTicks = between(1, 30)
+	}
+
+	if chance(0.5) {
+		s.Classes = randomClasses(rng, between(1, len(generatedClassNames)))
+	}
+	failing := false
+	for i := range s.Classes {
+		if chance(0.3) {
+			s.Classes[i].ErrorRate = uniform(0.01, 0.2)
+			failing = true
+		}
+		if chance(0.3) {
+			s.Classes[i].Service = randomService(rng, s.ServiceTime)
+		}
+	}
+
+	if chance(0.4) {
+		s.Traffic = randomTraffic(rng)
+	}
+	for tick, n := between(0, 80), between(0, 2); n > 0 && tick < TickCount; n-- {
+		spike := Spike{At: tick, Ticks: between(5, 40), Factor: uniform(1.5, 4)}
+		if len(s.Classes) > 0 && chance(0.5) {
+			spike.Class = s.Classes[rng.Intn(len(s.Classes))].Name
+		}
+		s.Spikes = append(s.Spikes, spike)
+		tick += spike.Ticks + between(0, 80)
+	}
+	for tick, n := between(10, 100), between(0, 2); n > 0 && tick < TickCount-20; n-- {
+		stall := Stall{Tick: tick, Ticks: between(1, 15)}
+		if chance(0.5) {
+			stall.Slots = between(1, s.Capacity)
+		}
+		s.Stalls = append(s.Stalls, stall)
+		tick += stall.Ticks + between(5, 60)
+	}
+	if chance(0.3) {
+		scaling := &Scaling{WarmupTicks: between(0, 10)}
+		for tick, n := between(10, 80), between(1, 3); n > 0 && tick < TickCount; n-- {
+			scaling.Steps = append(scaling.Steps, ScaleStep{Tick: tick, Capacity: between(1, 10)})
+			tick += between(20, 80)
+		}
+		s.Scaling = scaling
+	}
+
+	switch rng.Intn(5) {
+	case 1:
+		s.Discipline = &Discipline{Queue: DisciplineLIFO}
+	case 2:
+		s.Discipline = &Discipline{Queue: DisciplineSJF}
+	case 3:
+		s.Discipline = &Discipline{Service: ServicePS}
+	}
+	if chance(0.3) {
+		s.Degradation = &Degradation{
+			QueueLength:  between(2, 20),
+			SustainTicks: between(1, 10),
+			RecoverTicks: between(1, 10),
+			ServiceTime:  between(1, s.ServiceTime),
+		}
+	}
+	if failing && chance(0.6) {
+		s.Retry = &Retry{Attempts: between(1, 3), DelayTicks: between(1, 10)}
+	}
+	if chance(0.2) {
+		s.CircuitBreaker = &CircuitBreaker{
+			FailureRate: uniform(0.2, 0.8),
+			LatencyMs:   between(2, 20) * TickDurationMs,
+			WindowTicks: between(5, 30),
+			OpenTicks:   between(5, 30),
+		}
+	}
+	return s
+}
+
+func randomClasses(rng *rand.Rand, n int) []ClassDef {
+	weights := make([]int, n)
+	total := 0
+	for i := range weights {
+		weights[i] = 1 + rng.Intn(9)
+		total += weights[i]
+	}
+	classes := make([]ClassDef, n)
+	remaining := 100
+	for i := range classes {
+		percent := weights[i] * 100 / total
+		if i == n-1 {
+			percent = remaining
+		}
+		remaining -= percent
+		share := float64(percent) / 100
+		classes[i] = ClassDef{Name: generatedClassNames[i], Share: share, Priority: rng.Intn(n)}
+		if rng.Intn(3) == 0 {
+			limit := 2 + rng.Intn(30)
+			classes[i].QueueLimit = &limit
+		}
+	}
+	return classes
+}
+
+func randomService(rng *rand.Rand, serviceTime int) *ServiceDist {
+	switch rng.Intn(3) {
+	case 0:
+		return &ServiceDist{Distribution: ServiceUniform, Min: 1, Max: serviceTime + rng.Intn(4)}
+	case 1:
+		return &ServiceDist{Distribution: ServiceExponential, Mean: float64(serviceTime)}
+	}
+	return &ServiceDist{Distribution: ServiceLognormal, Mean: float64(serviceTime), Sigma: round2(0.2 + rng.Float64())}
+}
+
+func randomTraffic(rng *rand.Rand) *Traffic {
+	switch rng.Intn(3) {
+	case 0:
+		return &Traffic{
+			Model:    TrafficOnOff,
+			Sources:  1 + rng.Intn(8),
+			Rate:     round2(0.2 + rng.Float64()*1.8),
+			Shape:    round2(1.2 + rng.Float64()*1.3),
+			MinTicks: 1 + rng.Intn(10),
+		}
+	case 1:
+		return &Traffic{Model: TrafficBModel, Bias: round2(0.5 + rng.Float64()*0.4), Total: 100 + rng.Intn(1900)}
+	}
+	trough := round2(rng.Float64())
+	return &Traffic{Model: TrafficDiurnal, Trough: trough, Peak: round2(trough + 0.5 + rng.Float64()*3.5), Days: rng.Intn(3)}
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestRandomScenario(t *testing.T) {
+	for seed := int64(1); seed <= 100; seed++ {
+		scenario := RandomScenario(seed)
+		if err := scenario.Validate(); err != nil {
+			t.Fatalf("RandomScenario(%d) is invalid: %v", seed, err)
+		}
+		if again := RandomScenario(seed); !reflect.DeepEqual(again, scenario) {
+			t.Fatalf("RandomScenario(%d) is not deterministic", seed)
+		}
+		data, err := yaml.Marshal(scenario)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := ParseScenario(data, ".yaml")
+		if err != nil {
+			t.Fatalf("RandomScenario(%d) does not round-trip: %v", seed, err)
+		}
+		if !reflect.DeepEqual(parsed, scenario) {
+			t.Fatalf("RandomScenario(%d) round-trips to %+v, want %+v", seed, parsed, scenario)
+		}
+		artifact, err := Run(Config{ScenarioID: scenario.ID, Scenario: &scenario, Seed: seed})
+		if err != nil {
+			t.Fatalf("RandomScenario(%d) run error = %v", seed, err)
+		}
+		if err := ValidateArtifact(artifact); err != nil {
+			t.Errorf("RandomScenario(%d) artifact is invalid: %v", seed, err)
+		}
+	}
+}
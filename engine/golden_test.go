@@ -0,0 +1,25 @@
+package engine
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestGoldenArtifact(t *testing.T) {
+	want, err := os.ReadFile("../artifacts/run.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifact, err := Run(Config{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := MarshalArtifact(artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Run(seed 1) does not match artifacts/run.json; regenerate it with go run ./cmd/finit -provenance=false if the change is intended")
+	}
+}
@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+)
+
+const hedgeSuffix = "h"
+
+type Hedging struct {
+	AfterTicks int      `json:"after_ticks" yaml:"after_ticks"`
+	Classes    []string `json:"classes,omitempty" yaml:"classes,omitempty"`
+}
+
+func (h Hedging) validate(classes map[string]bool) []error {
+	var errs []error
+	if h.AfterTicks <= 0 {
+		errs = append(errs, fmt.Errorf("hedging: after_ticks must be positive, got %d", h.AfterTicks))
+	}
+	for i, class := range h.Classes {
+		if !classes[class] {
+			errs = append(errs, fmt.Errorf("hedging: classes[%d]: unknown class %q", i, class))
+		}
+	}
+	return errs
+}
+
+func (h Hedging) hedges(class string) bool {
+	return len(h.Classes) == 0 || slices.Contains(h.Classes, class)
+}
+
+func (s *Simulator) spawnHedges(tick int) {
+	hedging := s.scenario.Hedging
+	if hedging == nil {
+		return
+	}
+	var due []*Token
+	s.queue.each(func(_ int, token *Token) bool {
+		if token.hedge == nil && token.hedgeOf == nil && tick-token.queuedAt() >= hedging.AfterTicks && hedging.hedges(token.Class) {
+			due = append(due, token)
+		}
+		return true
+	})
+	for _, token := range due {
+		s.spawnHedge(tick, token)
+	}
+}
+
+func (s *Simulator) spawnHedge(tick int, token *Token) {
+	hedge := s.takeToken()
+	*hedge = Token{
+		ID:          token.ID + hedgeSuffix,
+		Class:       token.Class,
+		State:       StateQueued,
+		StageID:     StageQueue,
+		QueueIndex:  -1,
+		ArrivalTick: tick,
+		hedgeOf:     token,
+		size:        token.size,
+	}
+	token.hedge = hedge
+	s.tokens = append(s.tokens, hedge)
+	s.active = append(s.active, hedge)
+	s.enqueue(hedge)
+	context := s.newContext()
+	*context = EventContext{
+		Rule:        RuleHedge,
+		QueueLength: s.queueLength(),
+		Limit:       s.scenario.Hedging.AfterTicks,
+		WaitTicks:   tick - token.queuedAt(),
+		Hedge:       hedge.ID,
+	}
+	s.emit(Event{
+		Tick:       tick,
+		Type:       EventHedgeSpawn,
+		ReasonCode: ReasonHedgeDelay,
+		TokenID:    token.ID,
+		StageID:    StageQueue,
+		Class:      token.Class,
+		Context:    context,
+	})
+}
+
+func (t *Token) request() *Token {
+	if t.hedgeOf != nil {
+		return t.hedgeOf
+	}
+	return t
+}
+
+func (t *Token) hedgeOfID() string {
+	if t.hedgeOf == nil {
+		return ""
+	}
+	return t.hedgeOf.ID
+}
+
+func (s *Simulator) hedgeContext(token *Token) *EventContext {
+	if token.hedgeOf == nil {
+		return nil
+	}
+	context := s.newContext()
+	*context = EventContext{Rule: RuleHedge, QueueLength: s.queueLength(), Hedge: token.ID}
+	return context
+}
+
+func (t *Token) copy() *Token {
+	if t.hedgeOf != nil {
+		return t.hedgeOf
+	}
+	return t.hedge
+}
+
+func (s *Simulator) cancelCopy(tick int, winner *Token) {
+	loser := winner.copy()
+	if loser == nil || loser.terminal() {
+		return
+	}
+	if loser.State == StateQueued {
+		s.queue.remove(loser)
+	}
+	loser.State = StateCancelled
+	loser.StageID = StageDone
+	loser.QueueIndex = -1
+	loser.ServiceRemaining = 0
+	loser.EtaMs = 0
+	request := winner.request()
+	context := s.newContext()
+	*context = EventContext{
+		Rule:        RuleHedge,
+		QueueLength: s.queueLength(),
+		Hedge:       request.hedge.ID,
+		Cancelled:   loser.ID,
+	}
+	s.emit(Event{
+		Tick:       tick,
+		Type:       EventHedgeCancel,
+		ReasonCode: ReasonHedgeLost,
+		TokenID:    request.ID,
+		StageID:    StageDone,
+		Class:      request.Class,
+		Context:    context,
+	})
+}
@@ -0,0 +1,244 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+type Span struct {
+	Tick   int   `json:"tick"`
+	Offset int64 `json:"offset"`
+	Length int   `json:"length"`
+}
+
+type Index struct {
+	ReplayID    string           `json:"replay_id"`
+	Size        int64            `json:"size"`
+	Metadata    Span             `json:"metadata"`
+	Snapshots   []Span           `json:"snapshots"`
+	Events      []Span           `json:"events"`
+	TokenEvents map[string][]int `json:"token_events"`
+}
+
+func IndexPath(path string) string {
+	return path + ".idx"
+}
+
+func BuildIndex(data []byte) (Index, error) {
+	index := Index{Size: int64(len(data)), TokenEvents: make(map[string][]int)}
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	element := func(into any) (Span, error) {
+		start := dec.InputOffset()
+		for start < int64(len(data)) && bytes.IndexByte([]byte(" \t\r\n,:"), data[start]) >= 0 {
+			start++
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return Span{}, err
+		}
+		if err := json.Unmarshal(raw, into); err != nil {
+			return Span{}, err
+		}
+		return Span{Offset: start, Length: int(dec.InputOffset() - start)}, nil
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return Index{}, fmt.Errorf("index: %w", err)
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return Index{}, fmt.Errorf("index: %w", err)
+		}
+		switch key {
+		case "metadata":
+			var metadata Metadata
+			index.Metadata, err = element(&metadata)
+			index.ReplayID = metadata.ReplayID
+		case "snapshots":
+			err = streamArray(dec, func() error {
+				var snapshot struct {
+					Tick int `json:"tick"`
+				}
+				span, err := element(&snapshot)
+				span.Tick = snapshot.Tick
+				index.Snapshots = append(index.Snapshots, span)
+				return err
+			})
+		case "events":
+			err = streamArray(dec, func() error {
+				var event struct {
+					Tick    int    `json:"tick"`
+					TokenID string `json:"token_id"`
+				}
+				span, err := element(&event)
+				span.Tick = event.Tick
+				if event.TokenID != "" {
+					index.TokenEvents[event.TokenID] = append(index.TokenEvents[event.TokenID], len(index.Events))
+				}
+				index.Events = append(index.Events, span)
+				return err
+			})
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			return Index{}, fmt.Errorf("index %v: %w", key, err)
+		}
+	}
+	return index, nil
+}
+
+func WriteIndex(path string) (Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Index{}, err
+	}
+	index, err := BuildIndex(data)
+	if err != nil {
+		return Index{}, err
+	}
+	encoded, err := json.Marshal(index)
+	if err != nil {
+		return Index{}, err
+	}
+	return index, os.WriteFile(IndexPath(path), append(encoded, '\n'), 0o644)
+}
+
+type IndexedArtifact struct {
+	file     *os.File
+	index    Index
+	metadata Metadata
+	ticks    map[int]int
+}
+
+func OpenIndexed(path string) (*IndexedArtifact, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	index, err := loadIndex(path, file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	a := &IndexedArtifact{file: file, index: index, ticks: make(map[int]int, len(index.Snapshots))}
+	for i, span := range index.Snapshots {
+		a.ticks[span.Tick] = i
+	}
+	if err := a.read(index.Metadata, &a.metadata); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+func loadIndex(path string, file *os.File) (Index, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return Index{}, err
+	}
+	var index Index
+	data, err := os.ReadFile(IndexPath(path))
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &index); err != nil {
+			return Index{}, decodeError(IndexPath(path), err)
+		}
+		if index.Size == info.Size() {
+			return index, nil
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return Index{}, err
+	}
+
+	data, err = io.ReadAll(file)
+	if err != nil {
+		return Index{}, err
+	}
+	return BuildIndex(data)
+}
+
+func (a *IndexedArtifact) read(span Span, into any) error {
+	buf := make([]byte, span.Length)
+	if _, err := a.file.ReadAt(buf, span.Offset); err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, into)
+}
+
+func (a *IndexedArtifact) Metadata() Metadata {
+	return a.metadata
+}
+
+func (a *IndexedArtifact) Index() Index {
+	return a.index
+}
+
+func (a *IndexedArtifact) SnapshotCount() int {
+	return len(a.index.Snapshots)
+}
+
+func (a *IndexedArtifact) SnapshotAt(i int) (Snapshot, error) {
+	if i < 0 || i >= len(a.index.Snapshots) {
+		return Snapshot{}, fmt.Errorf("snapshot %d out of range [0, %d)", i, len(a.index.Snapshots))
+	}
+	var snapshot Snapshot
+	err := a.read(a.index.Snapshots[i], &snapshot)
+	return snapshot, err
+}
+
+func (a *IndexedArtifact) SnapshotIndex(tick int) (int, bool) {
+	i, ok := a.ticks[tick]
+	return i, ok
+}
+
+func (a *IndexedArtifact) Snapshot(tick int) (Snapshot, error) {
+	i, ok := a.SnapshotIndex(tick)
+	if !ok {
+		return Snapshot{}, fmt.Errorf("no snapshot at tick %d", tick)
+	}
+	return a.SnapshotAt(i)
+}
+
+func (a *IndexedArtifact) EventsAt(tick int) ([]Event, error) {
+	return a.EventsBetween(tick, tick)
+}
+
+func (a *IndexedArtifact) EventsBetween(from, to int) ([]Event, error) {
+	spans := a.index.Events
+	start := sort.Search(len(spans), func(i int) bool { return spans[i].Tick >= from })
+	var events []Event
+	for i := start; i < len(spans) && spans[i].Tick <= to; i++ {
+		var event Event
+		if err := a.read(spans[i], &event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (a *IndexedArtifact) TokenEvents(tokenID string) ([]Event, error) {
+	var events []Event
+	for _, i := range a.index.TokenEvents[tokenID] {
+		var event Event
+		if err := a.read(a.index.Events[i], &event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (a *IndexedArtifact) Close() error {
+	return a.file.Close()
+}
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIndexedArtifact(t *testing.T) {
+	artifact, err := Run(Config{Seed: 1})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "run.json")
+	if err := WriteArtifact(path, artifact); err != nil {
+		t.Fatalf("WriteArtifact() error = %v", err)
+	}
+	index, err := WriteIndex(path)
+	if err != nil {
+		t.Fatalf("WriteIndex() error = %v", err)
+	}
+	if len(index.Snapshots) != len(artifact.Snapshots) || len(index.Events) != len(artifact.Events) {
+		t.Fatalf("index has %d snapshots, %d events; want %d, %d",
+			len(index.Snapshots), len(index.Events), len(artifact.Snapshots), len(artifact.Events))
+	}
+
+	check := func(name string) {
+		indexed, err := OpenIndexed(path)
+		if err != nil {
+			t.Fatalf("%s: OpenIndexed() error = %v", name, err)
+		}
+		defer indexed.Close()
+
+		if got := indexed.Metadata(); !reflect.DeepEqual(got, artifact.Metadata) {
+			t.Errorf("%s: Metadata() = %+v, want %+v", name, got, artifact.Metadata)
+		}
+		for _, tick := range []int{0, 117, len(artifact.Snapshots) - 1} {
+			got, err := indexed.Snapshot(tick)
+			if err != nil {
+				t.Fatalf("%s: Snapshot(%d) error = %v", name, tick, err)
+			}
+			if !reflect.DeepEqual(got, artifact.Snapshots[tick]) {
+				t.Errorf("%s: Snapshot(%d) does not match the artifact", name, tick)
+			}
+
+			var want []Event
+			for _, e := range artifact.Events {
+				if e.Tick == tick {
+					want = append(want, e)
+				}
+			}
+			events, err := indexed.EventsAt(tick)
+			if err != nil {
+				t.Fatalf("%s: EventsAt(%d) error = %v", name, tick, err)
+			}
+			if !reflect.DeepEqual(events, want) {
+				t.Errorf("%s: EventsAt(%d) = %d events, want %d", name, tick, len(events), len(want))
+			}
+		}
+
+		tokenID := artifact.Events[0].TokenID
+		var want []Event
+		for _, e := range artifact.Events {
+			if e.TokenID == tokenID {
+				want = append(want, e)
+			}
+		}
+		events, err := indexed.TokenEvents(tokenID)
+		if err != nil {
+			t.Fatalf("%s: TokenEvents() error = %v", name, err)
+		}
+		if !reflect.DeepEqual(events, want) {
+			t.Errorf("%s: TokenEvents(%s) = %v, want %v", name, tokenID, events, want)
+		}
+		if _, err := indexed.Snapshot(-1); err == nil {
+			t.Errorf("%s: Snapshot(-1) error = nil", name)
+		}
+	}
+
+	check("with index")
+	if err := os.Remove(IndexPath(path)); err != nil {
+		t.Fatal(err)
+	}
+	check("without index")
+}
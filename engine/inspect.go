@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type Inspection struct {
+	Path       string         `json:"path"`
+	Size       int64          `json:"size"`
+	Metadata   Metadata       `json:"metadata"`
+	Chunks     int            `json:"chunks,omitempty"`
+	Snapshots  int            `json:"snapshots"`
+	FirstTick  int            `json:"first_tick"`
+	LastTick   int            `json:"last_tick"`
+	Events     int            `json:"events"`
+	EventTypes map[string]int `json:"event_types"`
+	Tokens     int            `json:"tokens"`
+}
+
+func Inspect(path string) (Inspection, error) {
+	inspection := Inspection{Path: path, FirstTick: -1, LastTick: -1, EventTypes: make(map[string]int)}
+	tokens := make(map[string]bool)
+	refs, err := inspection.scan(path, tokens)
+	if err != nil {
+		return Inspection{}, err
+	}
+	for _, ref := range refs {
+		inspection.Chunks++
+		var chunk Inspection
+		if _, err := chunk.scan(filepath.Join(filepath.Dir(path), ref.Path), tokens); err != nil {
+			return Inspection{}, err
+		}
+		inspection.Size += chunk.Size
+		inspection.merge(chunk)
+	}
+	inspection.Tokens = len(tokens)
+	return inspection, nil
+}
+
+func (in *Inspection) scan(path string, tokens map[string]bool) ([]ChunkRef, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	in.Size += info.Size()
+	if in.EventTypes == nil {
+		in.EventTypes = make(map[string]int)
+	}
+
+	var refs []ChunkRef
+	dec := json.NewDecoder(file)
+	if _, err := dec.Token(); err != nil {
+		return nil, decodeError(path, err)
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, decodeError(path, err)
+		}
+		switch key {
+		case "metadata":
+			err = dec.Decode(&in.Metadata)
+		case "chunks":
+			err = dec.Decode(&refs)
+		case "snapshots":
+			err = streamArray(dec, func() error {
+				var snapshot struct {
+					Tick int `json:"tick"`
+				}
+				if err := dec.Decode(&snapshot); err != nil {
+					return err
+				}
+				in.tick(snapshot.Tick)
+				in.Snapshots++
+				return nil
+			})
+		case "events":
+			err = streamArray(dec, func() error {
+				var event struct {
+					Type    string `json:"type"`
+					TokenID string `json:"token_id"`
+				}
+				if err := dec.Decode(&event); err != nil {
+					return err
+				}
+				in.Events++
+				in.EventTypes[event.Type]++
+				if event.TokenID != "" {
+					tokens[event.TokenID] = true
+				}
+				return nil
+			})
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			return nil, decodeError(path, fmt.Errorf("%v: %w", key, err))
+		}
+	}
+	return refs, nil
+}
+
+func (in *Inspection) tick(tick int) {
+	if in.FirstTick < 0 || tick < in.FirstTick {
+		in.FirstTick = tick
+	}
+	in.LastTick = max(in.LastTick, tick)
+}
+
+func (in *Inspection) merge(chunk Inspection) {
+	if chunk.Snapshots > 0 {
+		in.tick(chunk.FirstTick)
+		in.tick(chunk.LastTick)
+	}
+	in.Snapshots += chunk.Snapshots
+	in.Events += chunk.Events
+	for eventType, n := range chunk.EventTypes {
+		in.EventTypes[eventType] += n
+	}
+}
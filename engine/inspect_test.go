@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	artifact, err := Run(Config{Seed: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Inspection{
+		Metadata:   artifact.Metadata,
+		Snapshots:  len(artifact.Snapshots),
+		FirstTick:  0,
+		LastTick:   TickCount - 1,
+		Events:     len(artifact.Events),
+		EventTypes: make(map[string]int),
+		Tokens:     len(Lifecycles(artifact.Events)),
+	}
+	for _, event := range artifact.Events {
+		want.EventTypes[event.Type]++
+	}
+
+	dir := t.TempDir()
+	single := filepath.Join(dir, "run.json")
+	if err := WriteArtifact(single, artifact); err != nil {
+		t.Fatal(err)
+	}
+	chunked := filepath.Join(dir, "chunks", "run.json")
+	if err := os.MkdirAll(filepath.Dir(chunked), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WriteChunked(chunked, artifact, 50); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		path   string
+		chunks int
+	}{{single, 0}, {chunked, 5}} {
+		got, err := Inspect(tt.path)
+		if err != nil {
+			t.Fatalf("Inspect(%s) error = %v", tt.path, err)
+		}
+		if !reflect.DeepEqual(got.Metadata, want.Metadata) || got.Snapshots != want.Snapshots || got.Events != want.Events ||
+			got.Tokens != want.Tokens || got.FirstTick != want.FirstTick || got.LastTick != want.LastTick || got.Chunks != tt.chunks {
+			t.Errorf("Inspect(%s) = %+v, want %+v with %d chunks", tt.path, got, want, tt.chunks)
+		}
+		for eventType, n := range want.EventTypes {
+			if got.EventTypes[eventType] != n {
+				t.Errorf("Inspect(%s) %s events = %d, want %d", tt.path, eventType, got.EventTypes[eventType], n)
+			}
+		}
+		if got.Size <= 0 {
+			t.Errorf("Inspect(%s) Size = %d, want > 0", tt.path, got.Size)
+		}
+	}
+}
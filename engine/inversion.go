@@ -0,0 +1,41 @@
+package engine
+
+func (s *Simulator) detectInversion(tick int, scheduled *Token) {
+	if !s.scenario.InversionEvents {
+		return
+	}
+	lane := s.classes.lane(scheduled.Class)
+	var overtaken []*Token
+	s.queue.each(func(_ int, token *Token) bool {
+		if s.classes.lane(token.Class) >= lane {
+			return false
+		}
+		if !token.inverted {
+			overtaken = append(overtaken, token)
+		}
+		return true
+	})
+	for _, token := range overtaken {
+		token.inverted = true
+		reason := ReasonPolicyInversion
+		if token.affinityWait {
+			reason = ReasonAffinityInversion
+		}
+		context := s.newContext()
+		*context = EventContext{
+			Rule:        RuleInversion,
+			QueueLength: s.queueLength(),
+			WaitTicks:   tick - token.queuedAt(),
+			Overtaken:   scheduled.request().ID,
+		}
+		s.emit(Event{
+			Tick:       tick,
+			Type:       EventPriorityInversion,
+			ReasonCode: reason,
+			TokenID:    token.request().ID,
+			StageID:    StageQueue,
+			Class:      token.Class,
+			Context:    context,
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package engine
+
+type Lifecycle struct {
+	TokenID      string
+	Class        string
+	ArrivalTick  int
+	ScheduleTick int
+	CompleteTick int
+	RejectTick   int
+	FailTick     int
+	Quality      string
+}
+
+func (l Lifecycle) Scheduled() bool {
+	return l.ScheduleTick >= 0
+}
+
+func (l Lifecycle) Completed() bool {
+	return l.CompleteTick >= 0
+}
+
+func (l Lifecycle) Rejected() bool {
+	return l.RejectTick >= 0
+}
+
+func (l Lifecycle) Failed() bool {
+	return l.FailTick >= 0
+}
+
+func (l Lifecycle) WaitTicks() int {
+	if !l.Scheduled() {
+		return -1
+	}
+	return l.ScheduleTick - l.ArrivalTick
+}
+
+func (l Lifecycle) LatencyTicks() int {
+	if !l.Completed() {
+		return -1
+	}
+	return l.CompleteTick - l.ArrivalTick
+}
+
+func Lifecycles(events []Event) []Lifecycle {
+	index := make(map[string]int)
+	var lifecycles []Lifecycle
+	for _, event := range events {
+		if event.TokenID == "" {
+			continue
+		}
+		i, ok := index[event.TokenID]
+		if !ok {
+			i = len(lifecycles)
+			index[event.TokenID] = i
+			lifecycles = append(lifecycles, Lifecycle{
+				TokenID:      event.TokenID,
+				Class:        event.Class,
+				ArrivalTick:  event.Tick,
+				ScheduleTick: -1,
+				CompleteTick: -1,
+				RejectTick:   -1,
+				FailTick:     -1,
+			})
+		}
+		switch event.Type {
+		case EventSchedule:
+			if lifecycles[i].ScheduleTick < 0 {
+				lifecycles[i].ScheduleTick = event.Tick
+			}
+		case EventComplete:
+			lifecycles[i].CompleteTick = event.Tick
+			lifecycles[i].Quality = event.Quality
+		case EventReject:
+			lifecycles[i].RejectTick = event.Tick
+		case EventFail:
+			lifecycles[i].FailTick = event.Tick
+		case EventDuplicateDrop:
+			if event.ReasonCode == ReasonDuplicateDropped {
+				lifecycles[i].RejectTick = event.Tick
+			}
+		}
+	}
+	return lifecycles
+}
@@ -0,0 +1,30 @@
+package engine
+
+import "testing"
+
+func TestLifecycles(t *testing.T) {
+	events := []Event{
+		{Tick: 0, Type: EventQueue, TokenID: "T0000", Class: ClassFree},
+		{Tick: 1, Type: EventQueue, TokenID: "T0001", Class: ClassPaid},
+		{Tick: 1, Type: EventReject, TokenID: "T0002", Class: ClassAnon},
+		{Tick: 2, Type: EventSchedule, TokenID: "T0001", Class: ClassPaid},
+		{Tick: 3, Type: EventComplete, TokenID: "T0001", Class: ClassPaid},
+		{Tick: 4, Type: EventSchedule, TokenID: "T0000", Class: ClassFree},
+	}
+
+	lifecycles := Lifecycles(events)
+	if len(lifecycles) != 3 {
+		t.Fatalf("Lifecycles() returned %d tokens, want 3", len(lifecycles))
+	}
+
+	free, paid, anon := lifecycles[0], lifecycles[1], lifecycles[2]
+	if free.TokenID != "T0000" || free.WaitTicks() != 4 || free.Completed() {
+		t.Errorf("free lifecycle = %+v", free)
+	}
+	if paid.WaitTicks() != 1 || paid.LatencyTicks() != 2 {
+		t.Errorf("paid wait = %d latency = %d, want 1 and 2", paid.WaitTicks(), paid.LatencyTicks())
+	}
+	if !anon.Rejected() || anon.Scheduled() || anon.WaitTicks() != -1 {
+		t.Errorf("anon lifecycle = %+v", anon)
+	}
+}
@@ -0,0 +1,41 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	LimitTokens    = "MaxTokens"
+	LimitEvents    = "MaxEvents"
+	LimitWallClock = "MaxWallClock"
+)
+
+type LimitError struct {
+	Limit string
+	Max   int64
+	Value int64
+	Tick  int
+}
+
+func (e *LimitError) Error() string {
+	if e.Limit == LimitWallClock {
+		return fmt.Sprintf("run exceeded %s=%s at tick %d (%s elapsed)", e.Limit, time.Duration(e.Max), e.Tick, time.Duration(e.Value).Round(time.Millisecond))
+	}
+	return fmt.Sprintf("run exceeded %s=%d at tick %d (%d produced)", e.Limit, e.Max, e.Tick, e.Value)
+}
+
+func (s *Simulator) checkLimits(tick int) error {
+	if s.limits.MaxTokens > 0 && s.nextID > s.limits.MaxTokens {
+		return &LimitError{Limit: LimitTokens, Max: int64(s.limits.MaxTokens), Value: int64(s.nextID), Tick: tick}
+	}
+	if s.limits.MaxEvents > 0 && len(s.events) > s.limits.MaxEvents {
+		return &LimitError{Limit: LimitEvents, Max: int64(s.limits.MaxEvents), Value: int64(len(s.events)), Tick: tick}
+	}
+	if s.limits.MaxWallClock > 0 {
+		if elapsed := time.Since(s.started); elapsed > s.limits.MaxWallClock {
+			return &LimitError{Limit: LimitWallClock, Max: int64(s.limits.MaxWallClock), Value: int64(elapsed), Tick: tick}
+		}
+	}
+	return nil
+}
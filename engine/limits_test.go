@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits Limits
+		want   string
+	}{
+		{name: "tokens", limits: Limits{MaxTokens: 50}, want: LimitTokens},
+		{name: "events", limits: Limits{MaxEvents: 100}, want: LimitEvents},
+		{name: "wall clock", limits: Limits{MaxWallClock: 1}, want: LimitWallClock},
+		{name: "generous", limits: Limits{MaxTokens: 1 << 20, MaxEvents: 1 << 20}},
+	}
+	for _, tt := range tests {
+		_, err := Run(Config{Seed: 1, Limits: tt.limits})
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: Run() error = %v, want nil", tt.name, err)
+			}
+			continue
+		}
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) {
+			t.Errorf("%s: Run() error = %v, want *LimitError", tt.name, err)
+			continue
+		}
+		if limitErr.Limit != tt.want || limitErr.Value <= limitErr.Max {
+			t.Errorf("%s: Run() error = %+v, want %s exceeded", tt.name, limitErr, tt.want)
+		}
+	}
+}
+
+func TestLimitsStopStepping(t *testing.T) {
+	sim, err := NewSimulator(Config{Seed: 1, Limits: Limits{MaxTokens: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for sim.Step() {
+	}
+	if sim.Err() == nil {
+		t.Fatal("Err() = nil after exceeding MaxTokens")
+	}
+	if sim.Done() {
+		t.Errorf("Done() = true, want the run to stop at tick %d", sim.Tick())
+	}
+	if got := len(sim.Snapshots()); got != sim.Tick() {
+		t.Errorf("%d snapshots after stopping at tick %d", got, sim.Tick())
+	}
+}
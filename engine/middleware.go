@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+)
+
+const middlewareCaveat = "scheduling decisions pass through Go middleware outside the scenario; replays reproduce this run only with the same middleware"
+
+type Decision struct {
+	Tick        int
+	QueueLength int
+	Busy        int
+	Capacity    int
+	Candidates  []PolicyCandidate
+
+	shadow func(chosen, shadow int)
+}
+
+type SchedulerPolicy interface {
+	Pick(decision Decision) int
+}
+
+type SchedulerPolicyFunc func(decision Decision) int
+
+func (f SchedulerPolicyFunc) Pick(decision Decision) int {
+	return f(decision)
+}
+
+type Middleware func(next SchedulerPolicy) SchedulerPolicy
+
+func LogDecisions(w io.Writer) Middleware {
+	return func(next SchedulerPolicy) SchedulerPolicy {
+		return SchedulerPolicyFunc(func(d Decision) int {
+			chosen := next.Pick(d)
+			if chosen >= 0 && chosen < len(d.Candidates) {
+				c := d.Candidates[chosen]
+				fmt.Fprintf(w, "tick %d: %s (%s) from %d waiting, waited %d ticks\n", d.Tick, c.ID, c.Class, len(d.Candidates), c.Wait)
+			}
+			return chosen
+		})
+	}
+}
+
+func CountDecisions(counts map[string]int) Middleware {
+	return func(next SchedulerPolicy) SchedulerPolicy {
+		return SchedulerPolicyFunc(func(d Decision) int {
+			chosen := next.Pick(d)
+			if chosen >= 0 && chosen < len(d.Candidates) {
+				counts[d.Candidates[chosen].Class]++
+			}
+			return chosen
+		})
+	}
+}
+
+func Shadow(policy SchedulerPolicy) Middleware {
+	return func(next SchedulerPolicy) SchedulerPolicy {
+		return SchedulerPolicyFunc(func(d Decision) int {
+			chosen := next.Pick(d)
+			if alt := policy.Pick(d); alt != chosen && d.shadow != nil {
+				d.shadow(chosen, alt)
+			}
+			return chosen
+		})
+	}
+}
+
+func validateMiddleware(middleware []Middleware, scenario Scenario) []error {
+	var errs []error
+	for i, mw := range middleware {
+		if mw == nil {
+			errs = append(errs, fmt.Errorf("Middleware[%d] is nil", i))
+		}
+	}
+	if len(middleware) > 0 && scenario.Affinity != nil {
+		errs = append(errs, fmt.Errorf("Middleware cannot wrap the affinity scheduler"))
+	}
+	return errs
+}
+
+type builtinPolicy struct {
+	sim *Simulator
+}
+
+func (p builtinPolicy) Pick(d Decision) int {
+	if p.sim.scriptedSchedule() {
+		return p.sim.scriptedPick(p.sim.decisionEnvs)
+	}
+	return 0
+}
+
+func chainPolicy(base SchedulerPolicy, middleware []Middleware) SchedulerPolicy {
+	policy := base
+	for i := len(middleware) - 1; i >= 0; i-- {
+		policy = middleware[i](policy)
+	}
+	return policy
+}
+
+func (s *Simulator) popPolicy(tick int) *Token {
+	tokens, envs := s.candidates(tick)
+	if len(tokens) == 0 {
+		return nil
+	}
+	s.decisionEnvs = envs
+	decision := Decision{
+		Tick:        tick,
+		QueueLength: len(tokens),
+		Busy:        s.busy(),
+		Capacity:    s.capacity,
+		Candidates:  make([]PolicyCandidate, len(envs)),
+	}
+	for i := range envs {
+		decision.Candidates[i] = policyCandidate(&envs[i])
+	}
+	decision.shadow = func(chosen, shadow int) {
+		if chosen < 0 || chosen >= len(tokens) || shadow < 0 || shadow >= len(tokens) {
+			return
+		}
+		s.emit(Event{
+			Tick:       tick,
+			Type:       EventShadowDiverge,
+			ReasonCode: ReasonShadowDiverged,
+			TokenID:    tokens[chosen].request().ID,
+			StageID:    StageQueue,
+			Class:      tokens[chosen].Class,
+			Context:    &EventContext{Rule: RuleShadowPolicy, QueueLength: len(tokens), Shadow: tokens[shadow].request().ID},
+		})
+	}
+	chosen := s.policy.Pick(decision)
+	s.decisionEnvs = nil
+	if chosen < 0 || chosen >= len(tokens) {
+		if s.hookErr == nil {
+			s.hookErr = fmt.Errorf("scheduler policy picked candidate %d of %d at tick %d", chosen, len(tokens), tick)
+		}
+		chosen = 0
+	}
+	s.queue.remove(tokens[chosen])
+	return tokens[chosen]
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var pickLast = SchedulerPolicyFunc(func(d Decision) int { return len(d.Candidates) - 1 })
+
+func TestMiddleware(t *testing.T) {
+	baseline, err := Run(NewConfig(WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]int)
+	var log bytes.Buffer
+	wrapped, err := Run(NewConfig(WithSeed(1), WithMiddleware(LogDecisions(&log), CountDecisions(counts), Shadow(pickLast))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := Lifecycles(wrapped.Events), Lifecycles(baseline.Events); !reflect.DeepEqual(got, want) {
+		t.Error("observing middleware changed the lifecycles of the default scheduler")
+	}
+	scheduled := make(map[string]int)
+	diverged := 0
+	for _, event := range wrapped.Events {
+		switch event.Type {
+		case EventSchedule:
+			scheduled[event.Class]++
+		case EventShadowDiverge:
+			diverged++
+			if event.Context.Shadow == "" || event.Context.Shadow == event.TokenID || event.Context.Rule != RuleShadowPolicy {
+				t.Errorf("shadow event for %s = %+v", event.TokenID, *event.Context)
+			}
+		}
+	}
+	if !reflect.DeepEqual(counts, scheduled) {
+		t.Errorf("CountDecisions() counted %v, want %v", counts, scheduled)
+	}
+	if diverged == 0 {
+		t.Error("a last-in shadow policy never disagreed with the priority scheduler")
+	}
+	total := 0
+	for _, n := range scheduled {
+		total += n
+	}
+	if lines := strings.Count(log.String(), "\n"); lines != total {
+		t.Errorf("LogDecisions() wrote %d lines, want one per decision (%d)", lines, total)
+	}
+	if len(wrapped.Metadata.Caveats) != 1 {
+		t.Errorf("Metadata.Caveats = %q, want the middleware caveat", wrapped.Metadata.Caveats)
+	}
+
+	overridden, err := Run(NewConfig(WithSeed(1), WithMiddleware(func(SchedulerPolicy) SchedulerPolicy { return pickLast })))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(Lifecycles(overridden.Events), Lifecycles(baseline.Events)) {
+		t.Error("a middleware that replaces the policy did not change any decision")
+	}
+
+	outOfRange := func(SchedulerPolicy) SchedulerPolicy {
+		return SchedulerPolicyFunc(func(d Decision) int { return len(d.Candidates) })
+	}
+	if _, err := Run(NewConfig(WithSeed(1), WithMiddleware(outOfRange))); err == nil || !strings.Contains(err.Error(), "scheduler policy picked candidate") {
+		t.Errorf("Run() with an out-of-range pick error = %v", err)
+	}
+}
+
+func TestMiddleware_Invalid(t *testing.T) {
+	affine := CanonicalScenario()
+	affine.Affinity = &Affinity{Sessions: 10}
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr string
+	}{
+		{name: "nil", opts: []Option{WithMiddleware(nil)}, wantErr: "Middleware[0] is nil"},
+		{name: "affinity", opts: []Option{WithScenarioSpec(affine), WithMiddleware(Shadow(pickLast))}, wantErr: "cannot wrap the affinity scheduler"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewConfig(tt.opts...).Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScenarioShadow(t *testing.T) {
+	sized := func(s *Scenario) {
+		s.Classes = s.ClassDefs()
+		for i := range s.Classes {
+			s.Classes[i].Service = &ServiceDist{Distribution: ServiceExponential, Mean: 4}
+		}
+		s.Script = &Script{Schedule: "priority*1000 - wait"}
+	}
+	tests := []struct {
+		name   string
+		shadow ShadowPolicy
+		edit   func(*Scenario)
+	}{
+		{name: "lifo", shadow: ShadowPolicy{Queue: DisciplineLIFO}},
+		{name: "sjf", shadow: ShadowPolicy{Queue: DisciplineSJF}, edit: sized},
+		{name: "schedule", shadow: ShadowPolicy{Schedule: "-age"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := CanonicalScenario()
+			if tt.edit != nil {
+				tt.edit(&scenario)
+			}
+			baseline, err := Run(NewConfig(WithSeed(1), WithScenarioSpec(scenario)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			scenario.Shadow = &tt.shadow
+			artifact, err := Run(NewConfig(WithSeed(1), WithScenarioSpec(scenario)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(Lifecycles(artifact.Events), Lifecycles(baseline.Events)) {
+				t.Error("a shadow policy changed the lifecycles of the primary scheduler")
+			}
+			diverged := 0
+			for _, event := range artifact.Events {
+				if event.Type == EventShadowDiverge {
+					diverged++
+				}
+			}
+			if diverged == 0 {
+				t.Errorf("shadow %+v never disagreed with the primary scheduler", tt.shadow)
+			}
+			if len(artifact.Metadata.Caveats) != 0 {
+				t.Errorf("Metadata.Caveats = %q, want none for a shadow declared in the scenario", artifact.Metadata.Caveats)
+			}
+		})
+	}
+}
+
+func TestScenarioShadow_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		shadow  ShadowPolicy
+		edit    func(*Scenario)
+		wantErr string
+	}{
+		{name: "empty", wantErr: "shadow: set queue or schedule"},
+		{name: "both", shadow: ShadowPolicy{Queue: DisciplineLIFO, Schedule: "wait"}, wantErr: "not both"},
+		{name: "unknown", shadow: ShadowPolicy{Queue: "random"}, wantErr: `unknown queue discipline "random"`},
+		{name: "schedule", shadow: ShadowPolicy{Schedule: "wait >"}, wantErr: "shadow: schedule:"},
+		{name: "same", shadow: ShadowPolicy{Queue: DisciplineLIFO}, edit: func(s *Scenario) { s.Discipline = &Discipline{Queue: DisciplineLIFO} }, wantErr: "queue lifo is already"},
+		{name: "processor sharing", shadow: ShadowPolicy{Queue: DisciplineLIFO}, edit: func(s *Scenario) { s.Discipline = &Discipline{Service: ServicePS} }, wantErr: "no decisions to shadow"},
+		{name: "affinity", shadow: ShadowPolicy{Queue: DisciplineLIFO}, edit: func(s *Scenario) { s.Affinity = &Affinity{Sessions: 10} }, wantErr: "shadow: cannot be combined with affinity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := CanonicalScenario()
+			scenario.Shadow = &tt.shadow
+			if tt.edit != nil {
+				tt.edit(&scenario)
+			}
+			if err := NewConfig(WithScenarioSpec(scenario)).Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"math"
+	"time"
+)
+
+type ArrivalPhase struct {
+	FromTick int `json:"from_tick"`
+	ToTick   int `json:"to_tick"`
+	PerTick  int `json:"per_tick"`
+}
+
+type ClassShare struct {
+	Class string  `json:"class"`
+	Share float64 `json:"share"`
+	Below float64 `json:"-"`
+}
+
+type ClassPin struct {
+	FromTick int      `json:"from_tick"`
+	ToTick   int      `json:"to_tick"`
+	Classes  []string `json:"classes"`
+}
+
+type AdmissionPolicy struct {
+	Rule  string `json:"rule"`
+	Class string `json:"class"`
+	Limit int    `json:"queue_limit"`
+}
+
+type SchedulingPolicy struct {
+	Order []string `json:"order"`
+}
+
+type Plan struct {
+	Scenario         Scenario          `json:"scenario"`
+	Seed             int64             `json:"seed"`
+	EngineVersion    string            `json:"engine_version"`
+	ReplayID         string            `json:"replay_id"`
+	TickCount        int               `json:"tick_count"`
+	TickDurationMs   int               `json:"tick_duration_ms"`
+	TotalDurationMs  int               `json:"total_duration_ms"`
+	SnapshotInterval int               `json:"snapshot_interval"`
+	ETA              bool              `json:"eta,omitempty"`
+	TraceIDs         bool              `json:"trace_ids,omitempty"`
+	ClassBreakdown   bool              `json:"class_breakdown,omitempty"`
+	TokenIDs         *TokenIDs         `json:"token_ids,omitempty"`
+	Epoch            *time.Time        `json:"epoch,omitempty"`
+	EventFilters     []EventFilter     `json:"event_filters,omitempty"`
+	EventRetention   *EventRetention   `json:"event_retention,omitempty"`
+	Reasons          []Reason          `json:"reasons,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	Limits           PlanLimits        `json:"limits"`
+	Arrivals         []ArrivalPhase    `json:"arrivals"`
+	ExpectedArrivals int               `json:"expected_arrivals"`
+	ClassMix         []ClassShare      `json:"class_mix"`
+	ClassPins        []ClassPin        `json:"class_pins"`
+	Classes          []ClassDef        `json:"classes"`
+	Admission        []AdmissionPolicy `json:"admission"`
+	Scheduling       SchedulingPolicy  `json:"scheduling"`
+}
+
+type PlanLimits struct {
+	MaxTokens    int    `json:"max_tokens,omitempty"`
+	MaxEvents    int    `json:"max_events,omitempty"`
+	MaxWallClock string `json:"max_wall_clock,omitempty"`
+}
+
+var arrivalPhases = []ArrivalPhase{
+	{FromTick: 0, ToTick: 110, PerTick: 1},
+	{FromTick: 110, ToTick: 150, PerTick: 2},
+	{FromTick: 150, ToTick: 180, PerTick: 4},
+	{FromTick: 180, ToTick: 210, PerTick: 3},
+	{FromTick: 210, ToTick: TickCount, PerTick: 1},
+}
+
+func NewPlan(cfg Config) (Plan, error) {
+	if err := cfg.Validate(); err != nil {
+		return Plan{}, err
+	}
+	scenario, err := cfg.scenario()
+	if err != nil {
+		return Plan{}, err
+	}
+
+	replayID, err := scenario.ReplayID(cfg.Seed, EngineVersion)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	classes := newClassTable(scenario.ClassDefs())
+	plan := Plan{
+		Scenario:         scenario,
+		Seed:             cfg.Seed,
+		EngineVersion:    EngineVersion,
+		ReplayID:         replayID,
+		TickCount:        TickCount,
+		TickDurationMs:   TickDurationMs,
+		TotalDurationMs:  TotalDurationMs,
+		SnapshotInterval: cfg.snapshotInterval(),
+		ETA:              cfg.ETA,
+		TraceIDs:         cfg.TraceIDs,
+		ClassBreakdown:   cfg.ClassBreakdown,
+		TokenIDs:         cfg.TokenIDs,
+		Epoch:            cfg.Epoch,
+		EventFilters:     cfg.EventFilters,
+		EventRetention:   cfg.EventRetention,
+		Reasons:          cfg.Reasons,
+		Labels:           cfg.Labels,
+		Limits: PlanLimits{
+			MaxTokens: cfg.MaxTokens,
+			MaxEvents: cfg.MaxEvents,
+		},
+		Arrivals:   append([]ArrivalPhase(nil), arrivalPhases...),
+		ClassPins:  []ClassPin{},
+		Classes:    scenario.ClassDefs(),
+		Admission:  []AdmissionPolicy{},
+		Scheduling: SchedulingPolicy{Order: classes.order},
+	}
+	if cfg.MaxWallClock > 0 {
+		plan.Limits.MaxWallClock = cfg.MaxWallClock.String()
+	}
+	if scenario.Traffic != nil {
+		plan.Arrivals = []ArrivalPhase{}
+		plan.ExpectedArrivals = scenario.Traffic.expectedArrivals()
+	}
+	if len(scenario.Arrivals) > 0 {
+		plan.Arrivals = []ArrivalPhase{}
+		plan.ExpectedArrivals = len(scenario.Arrivals)
+	}
+	for _, phase := range plan.Arrivals {
+		plan.ExpectedArrivals += (phase.ToTick - phase.FromTick) * phase.PerTick
+	}
+	for _, sp := range scenario.Spikes {
+		if len(sp.Classes) > 0 {
+			plan.ClassPins = append(plan.ClassPins, sp.pin())
+			continue
+		}
+		share := 1.0
+		if sp.Class != "" {
+			share = classes.def(sp.Class).Share
+		}
+		for tick := sp.At; tick < min(sp.At+sp.Ticks, TickCount); tick++ {
+			plan.ExpectedArrivals += int(math.Round(float64(arrivalCount(tick)) * share * (sp.Factor - 1)))
+		}
+	}
+	for _, def := range plan.Classes {
+		plan.ClassMix = append(plan.ClassMix, ClassShare{
+			Class: def.Name,
+			Share: math.Round(def.Share*1e6) / 1e6,
+		})
+		if def.QueueLimit != nil {
+			plan.Admission = append(plan.Admission, AdmissionPolicy{
+				Rule:  queueLimitRule(def.Name),
+				Class: def.Name,
+				Limit: *def.QueueLimit,
+			})
+		}
+	}
+	return plan, nil
+}
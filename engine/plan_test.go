@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewPlan_MatchesRun(t *testing.T) {
+	cfg := NewConfig(WithSeed(4), WithSnapshotInterval(10))
+	plan, err := NewPlan(cfg)
+	if err != nil {
+		t.Fatalf("NewPlan() error = %v", err)
+	}
+	artifact, err := Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plan.ReplayID != artifact.Metadata.ReplayID {
+		t.Errorf("ReplayID = %s, want %s", plan.ReplayID, artifact.Metadata.ReplayID)
+	}
+	if plan.SnapshotInterval != 10 {
+		t.Errorf("SnapshotInterval = %d, want 10", plan.SnapshotInterval)
+	}
+	arrivals := 0
+	for _, event := range artifact.Events {
+		if event.Type == EventQueue || event.Type == EventReject {
+			arrivals++
+		}
+	}
+	if plan.ExpectedArrivals != arrivals {
+		t.Errorf("ExpectedArrivals = %d, want %d", plan.ExpectedArrivals, arrivals)
+	}
+
+	total := 0.0
+	for _, share := range plan.ClassMix {
+		total += share.Share
+	}
+	if total < 0.999999 || total > 1.000001 {
+		t.Errorf("ClassMix shares sum to %v, want 1", total)
+	}
+}
+
+func TestNewPlan_InvalidConfig(t *testing.T) {
+	_, err := NewPlan(Config{ScenarioID: "missing"})
+	if !errors.Is(err, ErrUnknownScenario) {
+		t.Errorf("NewPlan() error = %v, want ErrUnknownScenario", err)
+	}
+}
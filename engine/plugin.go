@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+
+	"finit/wasm"
+)
+
+const pluginFuel = 1_000_000
+
+var pluginParams = []string{"class", "priority", "wait", "age", "size", "tick", "queue_length", "class_queue_length", "busy", "capacity"}
+
+type Plugin struct {
+	Path   string `json:"path" yaml:"path"`
+	SHA256 string `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+}
+
+func (p Plugin) validate(s Scenario) []error {
+	var errs []error
+	if p.Path == "" {
+		return []error{fmt.Errorf("plugin: path is required")}
+	}
+	if s.Script != nil {
+		errs = append(errs, fmt.Errorf("plugin: cannot be combined with script"))
+	}
+	compiled, err := p.load()
+	if err != nil {
+		return append(errs, err)
+	}
+	if compiled.schedule != nil {
+		errs = append(errs, scheduleConflicts("plugin", s)...)
+	}
+	return errs
+}
+
+func (p Plugin) pin() (Plugin, error) {
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return Plugin{}, fmt.Errorf("plugin: %w", err)
+	}
+	p.SHA256 = pluginDigest(data)
+	return p, nil
+}
+
+func pluginDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (p Plugin) load() (*compiledScript, error) {
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, fmt.Errorf("plugin: %w", err)
+	}
+	if digest := pluginDigest(data); p.SHA256 != "" && digest != p.SHA256 {
+		return nil, fmt.Errorf("plugin: %s has sha256 %s, want %s", p.Path, digest, p.SHA256)
+	}
+	module, err := wasm.Compile(data)
+	if err != nil {
+		return nil, fmt.Errorf("plugin: %s: %w", p.Path, err)
+	}
+
+	compiled := &compiledScript{
+		scheduleReason: ReasonPluginSchedule,
+		scheduleRule:   RulePluginSchedule,
+		admitReason:    ReasonPluginReject,
+		admitRule:      RulePluginAdmit,
+	}
+	call := func(name string, e *scriptEnv) (uint64, bool) {
+		if compiled.err != nil {
+			return 0, false
+		}
+		results, err := module.Call(name, pluginFuel, pluginArgs(e)...)
+		if err != nil {
+			compiled.err = fmt.Errorf("plugin: %s at tick %d: %w", name, int(e.tick), err)
+			return 0, false
+		}
+		return results[0], true
+	}
+	if typ, ok := module.Export("schedule"); ok {
+		result, err := pluginSignature("schedule", typ, wasm.I32, wasm.I64, wasm.F64)
+		if err != nil {
+			return nil, err
+		}
+		compiled.schedule = func(e *scriptEnv) float64 {
+			v, _ := call("schedule", e)
+			switch result {
+			case wasm.I32:
+				return float64(int32(v))
+			case wasm.I64:
+				return float64(int64(v))
+			}
+			return math.Float64frombits(v)
+		}
+	}
+	if typ, ok := module.Export("admit"); ok {
+		if _, err := pluginSignature("admit", typ, wasm.I32); err != nil {
+			return nil, err
+		}
+		compiled.admit = func(e *scriptEnv) bool {
+			v, ok := call("admit", e)
+			return !ok || uint32(v) != 0
+		}
+	}
+	if compiled.schedule == nil && compiled.admit == nil {
+		return nil, fmt.Errorf("plugin: %s exports neither schedule nor admit", p.Path)
+	}
+	return compiled, nil
+}
+
+func pluginSignature(name string, typ wasm.FuncType, results ...wasm.ValueType) (wasm.ValueType, error) {
+	ok := len(typ.Params) == len(pluginParams) && len(typ.Results) == 1 && slices.Contains(results, typ.Results[0])
+	for _, param := range typ.Params {
+		ok = ok && param == wasm.I32
+	}
+	if !ok {
+		return 0, fmt.Errorf("plugin: %s must take %d i32 parameters (%v) and return one of %v", name, len(pluginParams), pluginParams, results)
+	}
+	return typ.Results[0], nil
+}
+
+func pluginArgs(e *scriptEnv) []uint64 {
+	values := []float64{e.priority, e.wait, e.age, e.size, e.tick, e.queueLength, e.classQueueLength, e.busy, e.capacity}
+	args := make([]uint64, 0, len(pluginParams))
+	args = append(args, uint64(uint32(int32(e.classIndex))))
+	for _, v := range values {
+		args = append(args, uint64(uint32(int32(v))))
+	}
+	return args
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+)
+
+type Presentation struct {
+	Classes []ClassPresentation `json:"classes,omitempty" yaml:"classes,omitempty"`
+	Stages  []StagePresentation `json:"stages,omitempty" yaml:"stages,omitempty"`
+}
+
+type ClassPresentation struct {
+	Name  string `json:"name" yaml:"name"`
+	Label string `json:"label,omitempty" yaml:"label,omitempty"`
+	Color string `json:"color,omitempty" yaml:"color,omitempty"`
+	Icon  string `json:"icon,omitempty" yaml:"icon,omitempty"`
+}
+
+type StagePresentation struct {
+	ID    string  `json:"id" yaml:"id"`
+	Label string  `json:"label,omitempty" yaml:"label,omitempty"`
+	X     float64 `json:"x" yaml:"x"`
+	Y     float64 `json:"y" yaml:"y"`
+	Icon  string  `json:"icon,omitempty" yaml:"icon,omitempty"`
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func (p Presentation) validate(classes map[string]bool) []error {
+	var errs []error
+	seen := make(map[string]bool)
+	for i, class := range p.Classes {
+		name := fmt.Sprintf("presentation: classes[%d]", i)
+		switch {
+		case !classes[class.Name]:
+			errs = append(errs, fmt.Errorf("%s: unknown class %q", name, class.Name))
+		case seen[class.Name]:
+			errs = append(errs, fmt.Errorf("%s: duplicate class %q", name, class.Name))
+		}
+		seen[class.Name] = true
+		if class.Color != "" && !hexColor.MatchString(class.Color) {
+			errs = append(errs, fmt.Errorf("%s: color %q is not #rgb or #rrggbb", name, class.Color))
+		}
+	}
+	seen = make(map[string]bool)
+	for i, stage := range p.Stages {
+		name := fmt.Sprintf("presentation: stages[%d]", i)
+		switch {
+		case stage.ID == "":
+			errs = append(errs, fmt.Errorf("%s: id must not be empty", name))
+		case seen[stage.ID]:
+			errs = append(errs, fmt.Errorf("%s: duplicate stage %q", name, stage.ID))
+		}
+		seen[stage.ID] = true
+		if stage.X < 0 || stage.X > 1 || stage.Y < 0 || stage.Y > 1 {
+			errs = append(errs, fmt.Errorf("%s: x and y are fractions of the canvas, want 0..1, got %v, %v", name, stage.X, stage.Y))
+		}
+	}
+	return errs
+}
+
+func (s *Simulator) presentation() *Presentation {
+	p := s.scenario.Presentation
+	configured := make(map[string]ClassPresentation, len(p.Classes))
+	for _, class := range p.Classes {
+		configured[class.Name] = class
+	}
+	resolved := &Presentation{Stages: p.Stages}
+	for _, def := range s.classes.sorted {
+		class := configured[def.Name]
+		class.Name = def.Name
+		if class.Label == "" {
+			class.Label = def.Name
+		}
+		if class.Color == "" {
+			class.Color = def.Color
+		}
+		resolved.Classes = append(resolved.Classes, class)
+	}
+	return resolved
+}
+
+func (m Metadata) ClassLabel(class string) string {
+	if m.Presentation != nil {
+		for _, c := range m.Presentation.Classes {
+			if c.Name == class && c.Label != "" {
+				return c.Label
+			}
+		}
+	}
+	return class
+}
@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPresentation(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.Presentation = &Presentation{
+		Classes: []ClassPresentation{{Name: ClassPaid, Label: "Paid plan", Color: "#0a7", Icon: "star"}},
+		Stages:  []StagePresentation{{ID: StageQueue, Label: "Waiting room", X: 0.2, Y: 0.5}},
+	}
+	artifact, err := Run(NewConfig(WithSeed(1), WithScenarioSpec(scenario)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Presentation{
+		Classes: []ClassPresentation{
+			{Name: ClassPaid, Label: "Paid plan", Color: "#0a7", Icon: "star"},
+			{Name: ClassFree, Label: ClassFree, Color: "#a1aab5"},
+			{Name: ClassAnon, Label: ClassAnon, Color: "#c2cbd7"},
+		},
+		Stages: scenario.Presentation.Stages,
+	}
+	m := artifact.Metadata
+	if !reflect.DeepEqual(m.Presentation, want) {
+		t.Errorf("Metadata.Presentation = %+v, want %+v", m.Presentation, want)
+	}
+	if got := m.ClassColor(ClassPaid); got != "#0a7" {
+		t.Errorf("ClassColor(PAID) = %q, want the presentation color", got)
+	}
+	if got := m.ClassLabel(ClassPaid); got != "Paid plan" {
+		t.Errorf("ClassLabel(PAID) = %q, want %q", got, "Paid plan")
+	}
+
+	plain, err := Run(NewConfig(WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.Metadata.Presentation != nil || plain.Metadata.ClassLabel(ClassPaid) != ClassPaid {
+		t.Errorf("a scenario without presentation got %+v", plain.Metadata.Presentation)
+	}
+}
+
+func TestPresentation_Invalid(t *testing.T) {
+	tests := []struct {
+		name         string
+		presentation Presentation
+		wantErr      string
+	}{
+		{name: "class", presentation: Presentation{Classes: []ClassPresentation{{Name: "GOLD"}}}, wantErr: `classes[0]: unknown class "GOLD"`},
+		{name: "duplicate class", presentation: Presentation{Classes: []ClassPresentation{{Name: ClassPaid}, {Name: ClassPaid}}}, wantErr: `classes[1]: duplicate class "PAID"`},
+		{name: "color", presentation: Presentation{Classes: []ClassPresentation{{Name: ClassPaid, Color: "teal"}}}, wantErr: `color "teal" is not #rgb or #rrggbb`},
+		{name: "stage id", presentation: Presentation{Stages: []StagePresentation{{}}}, wantErr: "stages[0]: id must not be empty"},
+		{name: "duplicate stage", presentation: Presentation{Stages: []StagePresentation{{ID: StageQueue}, {ID: StageQueue}}}, wantErr: `stages[1]: duplicate stage "queue"`},
+		{name: "coordinates", presentation: Presentation{Stages: []StagePresentation{{ID: StageQueue, X: 1.5}}}, wantErr: "want 0..1, got 1.5, 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := CanonicalScenario()
+			scenario.Presentation = &tt.presentation
+			if err := NewConfig(WithScenarioSpec(scenario)).Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
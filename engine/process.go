@@ -0,0 +1,296 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	PolicyProtocolVersion   = 1
+	defaultPolicyTimeoutMs  = 1000
+	policyProcessStderrTail = 2048
+)
+
+type PolicyProcess struct {
+	Command   []string `json:"command" yaml:"command"`
+	Schedule  bool     `json:"schedule,omitempty" yaml:"schedule,omitempty"`
+	Admit     bool     `json:"admit,omitempty" yaml:"admit,omitempty"`
+	TimeoutMs int      `json:"timeout_ms,omitempty" yaml:"timeout_ms,omitempty"`
+}
+
+type PolicyRequest struct {
+	Type             string            `json:"type"`
+	Version          int               `json:"version,omitempty"`
+	ScenarioID       string            `json:"scenario_id,omitempty"`
+	Seed             int64             `json:"seed,omitempty"`
+	Tick             int               `json:"tick"`
+	QueueLength      int               `json:"queue_length"`
+	ClassQueueLength int               `json:"class_queue_length,omitempty"`
+	Busy             int               `json:"busy"`
+	Capacity         int               `json:"capacity"`
+	Token            *PolicyCandidate  `json:"token,omitempty"`
+	Candidates       []PolicyCandidate `json:"candidates,omitempty"`
+}
+
+type PolicyCandidate struct {
+	ID       string `json:"id"`
+	Class    string `json:"class"`
+	Priority int    `json:"priority"`
+	Wait     int    `json:"wait"`
+	Age      int    `json:"age"`
+	Size     int    `json:"size"`
+}
+
+type PolicyResponse struct {
+	Token string `json:"token,omitempty"`
+	Admit *bool  `json:"admit,omitempty"`
+}
+
+func (p PolicyProcess) validate(s Scenario) []error {
+	var errs []error
+	if len(p.Command) == 0 || p.Command[0] == "" {
+		errs = append(errs, fmt.Errorf("policy_process: command is required"))
+	}
+	if !p.Schedule && !p.Admit {
+		errs = append(errs, fmt.Errorf("policy_process: set schedule, admit, or both"))
+	}
+	if p.TimeoutMs < 0 {
+		errs = append(errs, fmt.Errorf("policy_process: timeout_ms must be >= 0"))
+	}
+	if s.Script != nil || s.Plugin != nil {
+		errs = append(errs, fmt.Errorf("policy_process: cannot be combined with script or plugin"))
+	}
+	if p.Schedule {
+		errs = append(errs, scheduleConflicts("policy_process", s)...)
+	}
+	return errs
+}
+
+func (p PolicyProcess) timeout() time.Duration {
+	if p.TimeoutMs == 0 {
+		return defaultPolicyTimeoutMs * time.Millisecond
+	}
+	return time.Duration(p.TimeoutMs) * time.Millisecond
+}
+
+func (p PolicyProcess) caveat() string {
+	return fmt.Sprintf("policy decisions come from the external process %q; replays reproduce this run only if that process is deterministic and unchanged", strings.Join(p.Command, " "))
+}
+
+type policyLine struct {
+	data []byte
+	err  error
+}
+
+type policyConn struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	lines   chan policyLine
+	stderr  *tailWriter
+	timeout time.Duration
+	closed  bool
+}
+
+func (p PolicyProcess) start(scenarioID string, seed int64) (*compiledScript, error) {
+	cmd := exec.Command(p.Command[0], p.Command[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("policy_process: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("policy_process: %w", err)
+	}
+	conn := &policyConn{cmd: cmd, stdin: stdin, lines: make(chan policyLine), stderr: &tailWriter{}, timeout: p.timeout()}
+	cmd.Stderr = conn.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("policy_process: %w", err)
+	}
+	go conn.read(stdout)
+	if err := conn.send(PolicyRequest{Type: "start", Version: PolicyProtocolVersion, ScenarioID: scenarioID, Seed: seed}); err != nil {
+		conn.close()
+		return nil, err
+	}
+
+	compiled := &compiledScript{
+		scheduleReason: ReasonExternalSchedule,
+		scheduleRule:   RuleExternalSchedule,
+		admitReason:    ReasonExternalReject,
+		admitRule:      RuleExternalAdmit,
+		close:          conn.close,
+	}
+	if p.Schedule {
+		compiled.choose = func(envs []scriptEnv) int {
+			if compiled.err != nil {
+				return 0
+			}
+			request := policyRequest("schedule", &envs[0])
+			for i := range envs {
+				request.Candidates = append(request.Candidates, policyCandidate(&envs[i]))
+			}
+			response, err := conn.exchange(request)
+			if err == nil {
+				for i := range envs {
+					if envs[i].token == response.Token {
+						return i
+					}
+				}
+				err = fmt.Errorf("policy_process: schedule at tick %d chose %q, which is not queued", request.Tick, response.Token)
+			}
+			compiled.err = err
+			return 0
+		}
+	}
+	if p.Admit {
+		compiled.admit = func(e *scriptEnv) bool {
+			if compiled.err != nil {
+				return true
+			}
+			request := policyRequest("admit", e)
+			request.ClassQueueLength = int(e.classQueueLength)
+			candidate := policyCandidate(e)
+			request.Token = &candidate
+			response, err := conn.exchange(request)
+			if err == nil && response.Admit == nil {
+				err = fmt.Errorf("policy_process: admit at tick %d: response has no admit field", request.Tick)
+			}
+			if err != nil {
+				compiled.err = err
+				return true
+			}
+			return *response.Admit
+		}
+	}
+	return compiled, nil
+}
+
+func policyRequest(kind string, e *scriptEnv) PolicyRequest {
+	return PolicyRequest{
+		Type:        kind,
+		Tick:        int(e.tick),
+		QueueLength: int(e.queueLength),
+		Busy:        int(e.busy),
+		Capacity:    int(e.capacity),
+	}
+}
+
+func policyCandidate(e *scriptEnv) PolicyCandidate {
+	return PolicyCandidate{
+		ID:       e.token,
+		Class:    e.class,
+		Priority: int(e.priority),
+		Wait:     int(e.wait),
+		Age:      int(e.age),
+		Size:     int(e.size),
+	}
+}
+
+func (c *policyConn) read(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		c.lines <- policyLine{data: append([]byte(nil), scanner.Bytes()...)}
+	}
+	err := scanner.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	c.lines <- policyLine{err: err}
+	close(c.lines)
+}
+
+func (c *policyConn) send(request PolicyRequest) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	if _, err := c.stdin.Write(append(data, '\n')); err != nil {
+		return c.fail(fmt.Sprintf("%s at tick %d", request.Type, request.Tick), err)
+	}
+	return nil
+}
+
+func (c *policyConn) exchange(request PolicyRequest) (PolicyResponse, error) {
+	if err := c.send(request); err != nil {
+		return PolicyResponse{}, err
+	}
+	what := fmt.Sprintf("%s at tick %d", request.Type, request.Tick)
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+	select {
+	case line, ok := <-c.lines:
+		if !ok {
+			return PolicyResponse{}, c.fail(what, io.EOF)
+		}
+		if line.err != nil {
+			return PolicyResponse{}, c.fail(what, line.err)
+		}
+		var response PolicyResponse
+		if err := json.Unmarshal(line.data, &response); err != nil {
+			return PolicyResponse{}, fmt.Errorf("policy_process: %s: bad response %q: %w", what, line.data, err)
+		}
+		return response, nil
+	case <-timer.C:
+		c.cmd.Process.Kill()
+		return PolicyResponse{}, fmt.Errorf("policy_process: %s: no response within %s", what, c.timeout)
+	}
+}
+
+func (c *policyConn) fail(what string, err error) error {
+	if errors.Is(err, io.EOF) {
+		err = errors.New("process exited")
+	}
+	if tail := c.stderr.String(); tail != "" {
+		return fmt.Errorf("policy_process: %s: %w (stderr: %s)", what, err, tail)
+	}
+	return fmt.Errorf("policy_process: %s: %w", what, err)
+}
+
+func (c *policyConn) close() {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.stdin.Close()
+	done := make(chan struct{})
+	go func() {
+		for range c.lines {
+		}
+		c.cmd.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(c.timeout):
+		c.cmd.Process.Kill()
+		<-done
+	}
+}
+
+type tailWriter struct {
+	mu   sync.Mutex
+	tail []byte
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.tail = append(w.tail, p...)
+	if over := len(w.tail) - policyProcessStderrTail; over > 0 {
+		w.tail = w.tail[over:]
+	}
+	return len(p), nil
+}
+
+func (w *tailWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return strings.TrimSpace(string(w.tail))
+}
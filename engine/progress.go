@@ -0,0 +1,32 @@
+package engine
+
+import "time"
+
+type ProgressMeter struct {
+	start time.Time
+	now   func() time.Time
+}
+
+func NewProgressMeter() *ProgressMeter {
+	return &ProgressMeter{start: time.Now(), now: time.Now}
+}
+
+func (m *ProgressMeter) Elapsed() time.Duration {
+	return m.now().Sub(m.start)
+}
+
+func (m *ProgressMeter) TicksPerSecond(tick int) float64 {
+	elapsed := m.Elapsed()
+	if tick <= 0 || elapsed <= 0 {
+		return 0
+	}
+	return float64(tick) / elapsed.Seconds()
+}
+
+func (m *ProgressMeter) Remaining(tick, totalTicks int) time.Duration {
+	if tick <= 0 {
+		return 0
+	}
+	remaining := float64(max(totalTicks-tick, 0)) / float64(tick)
+	return time.Duration(float64(m.Elapsed()) * remaining)
+}
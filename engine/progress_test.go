@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	var calls, last, total int
+	_, err := Run(Config{Seed: 1, Progress: func(tick, totalTicks int) {
+		if tick != last+1 {
+			t.Errorf("Progress(%d) after %d, want consecutive ticks", tick, last)
+		}
+		calls++
+		last, total = tick, totalTicks
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != TickCount || last != TickCount || total != TickCount {
+		t.Errorf("Progress called %d times, last (%d, %d), want %d calls ending at (%d, %d)", calls, last, total, TickCount, TickCount, TickCount)
+	}
+}
+
+func TestProgressMeter(t *testing.T) {
+	start := time.Unix(0, 0)
+	now := start.Add(2 * time.Second)
+	meter := &ProgressMeter{start: start, now: func() time.Time { return now }}
+
+	tests := []struct {
+		tick, total   int
+		wantRemaining time.Duration
+		wantRate      float64
+	}{
+		{0, 240, 0, 0},
+		{40, 240, 10 * time.Second, 20},
+		{120, 240, 2 * time.Second, 60},
+		{240, 240, 0, 120},
+	}
+	for _, tt := range tests {
+		if got := meter.Remaining(tt.tick, tt.total); got != tt.wantRemaining {
+			t.Errorf("Remaining(%d, %d) = %v, want %v", tt.tick, tt.total, got, tt.wantRemaining)
+		}
+		if got := meter.TicksPerSecond(tt.tick); got != tt.wantRate {
+			t.Errorf("TicksPerSecond(%d) = %v, want %v", tt.tick, got, tt.wantRate)
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package engine
+
+import "sort"
+
+type ring struct {
+	buf  []*Token
+	head int
+	size int
+}
+
+func (r *ring) len() int {
+	return r.size
+}
+
+func (r *ring) at(i int) *Token {
+	return r.buf[(r.head+i)&(len(r.buf)-1)]
+}
+
+func (r *ring) push(token *Token) {
+	if r.size == len(r.buf) {
+		r.grow()
+	}
+	r.buf[(r.head+r.size)&(len(r.buf)-1)] = token
+	r.size++
+}
+
+func (r *ring) pop() *Token {
+	if r.size == 0 {
+		return nil
+	}
+	token := r.buf[r.head]
+	r.buf[r.head] = nil
+	r.head = (r.head + 1) & (len(r.buf) - 1)
+	r.size--
+	return token
+}
+
+func (r *ring) remove(token *Token) bool {
+	for i := 0; i < r.size; i++ {
+		if r.at(i) == token {
+			r.removeAt(i)
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ring) removeAt(i int) *Token {
+	token := r.at(i)
+	for j := i; j < r.size-1; j++ {
+		r.buf[(r.head+j)&(len(r.buf)-1)] = r.at(j + 1)
+	}
+	r.buf[(r.head+r.size-1)&(len(r.buf)-1)] = nil
+	r.size--
+	return token
+}
+
+func (r *ring) grow() {
+	buf := make([]*Token, max(2*len(r.buf), 16))
+	for i := 0; i < r.size; i++ {
+		buf[i] = r.at(i)
+	}
+	r.buf = buf
+	r.head = 0
+}
+
+type sizeHeap struct {
+	tokens   []*Token
+	frontier []int
+}
+
+func shorterJob(a, b *Token) bool {
+	if a.size != b.size {
+		return a.size < b.size
+	}
+	if a.queuedAt() != b.queuedAt() {
+		return a.queuedAt() < b.queuedAt()
+	}
+	return a.queueSeq < b.queueSeq
+}
+
+func (h *sizeHeap) len() int {
+	return len(h.tokens)
+}
+
+func (h *sizeHeap) push(token *Token) {
+	token.heapIndex = len(h.tokens)
+	h.tokens = append(h.tokens, token)
+	h.up(token.heapIndex)
+}
+
+func (h *sizeHeap) pop() *Token {
+	if len(h.tokens) == 0 {
+		return nil
+	}
+	return h.removeAt(0)
+}
+
+func (h *sizeHeap) remove(token *Token) bool {
+	i := token.heapIndex
+	if i < 0 || i >= len(h.tokens) || h.tokens[i] != token {
+		return false
+	}
+	h.removeAt(i)
+	return true
+}
+
+func (h *sizeHeap) removeAt(i int) *Token {
+	token := h.tokens[i]
+	last := len(h.tokens) - 1
+	if i != last {
+		h.swap(i, last)
+	}
+	h.tokens[last] = nil
+	h.tokens = h.tokens[:last]
+	if i != last && !h.down(i) {
+		h.up(i)
+	}
+	token.heapIndex = -1
+	return token
+}
+
+func (h *sizeHeap) swap(i, j int) {
+	h.tokens[i], h.tokens[j] = h.tokens[j], h.tokens[i]
+	h.tokens[i].heapIndex = i
+	h.tokens[j].heapIndex = j
+}
+
+func (h *sizeHeap) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !shorterJob(h.tokens[i], h.tokens[parent]) {
+			return
+		}
+		h.swap(i, parent)
+		i = parent
+	}
+}
+
+func (h *sizeHeap) down(i int) bool {
+	start := i
+	for {
+		child := 2*i + 1
+		if child >= len(h.tokens) {
+			break
+		}
+		if right := child + 1; right < len(h.tokens) && shorterJob(h.tokens[right], h.tokens[child]) {
+			child = right
+		}
+		if !shorterJob(h.tokens[child], h.tokens[i]) {
+			break
+		}
+		h.swap(i, child)
+		i = child
+	}
+	return i > start
+}
+
+func (h *sizeHeap) each(fn func(token *Token) bool) bool {
+	if len(h.tokens) == 0 {
+		return true
+	}
+	frontier := append(h.frontier[:0], 0)
+	h.frontier = nil
+	defer func() { h.frontier = frontier[:0] }()
+	before := func(a, b int) bool { return shorterJob(h.tokens[frontier[a]], h.tokens[frontier[b]]) }
+	for len(frontier) > 0 {
+		i := frontier[0]
+		last := len(frontier) - 1
+		frontier[0] = frontier[last]
+		frontier = frontier[:last]
+		for j := 0; ; {
+			child := 2*j + 1
+			if child >= len(frontier) {
+				break
+			}
+			if child+1 < len(frontier) && before(child+1, child) {
+				child++
+			}
+			if !before(child, j) {
+				break
+			}
+			frontier[j], frontier[child] = frontier[child], frontier[j]
+			j = child
+		}
+		if !fn(h.tokens[i]) {
+			return false
+		}
+		for _, child := range [2]int{2*i + 1, 2*i + 2} {
+			if child >= len(h.tokens) {
+				continue
+			}
+			frontier = append(frontier, child)
+			for j := len(frontier) - 1; j > 0; {
+				parent := (j - 1) / 2
+				if !before(j, parent) {
+					break
+				}
+				frontier[j], frontier[parent] = frontier[parent], frontier[j]
+				j = parent
+			}
+		}
+	}
+	return true
+}
+
+type classQueue struct {
+	lanes      []ring
+	sized      []sizeHeap
+	front      []ring
+	laneOf     map[string]int
+	discipline string
+	pushed     int
+}
+
+func lane(class string) int {
+	switch class {
+	case ClassPaid:
+		return 0
+	case ClassFree:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (q *classQueue) laneIndex(class string) int {
+	i := lane(class)
+	if q.laneOf != nil {
+		i = q.laneOf[class]
+	}
+	for len(q.lanes) <= i {
+		q.lanes = append(q.lanes, ring{})
+		q.sized = append(q.sized, sizeHeap{})
+		q.front = append(q.front, ring{})
+	}
+	return i
+}
+
+func (q *classQueue) lane(class string) *ring {
+	return &q.lanes[q.laneIndex(class)]
+}
+
+func (q *classQueue) laneLen(i int) int {
+	return q.front[i].len() + q.lanes[i].len() + q.sized[i].len()
+}
+
+func (q *classQueue) len() int {
+	n := 0
+	for i := range q.lanes {
+		n += q.laneLen(i)
+	}
+	return n
+}
+
+func (q *classQueue) push(token *Token) {
+	q.pushed++
+	token.queueSeq = q.pushed
+	i := q.laneIndex(token.Class)
+	if q.discipline == DisciplineSJF {
+		q.sized[i].push(token)
+		return
+	}
+	q.lanes[i].push(token)
+}
+
+func (q *classQueue) pushFront(token *Token) {
+	q.pushed++
+	token.queueSeq = q.pushed
+	q.front[q.laneIndex(token.Class)].push(token)
+}
+
+func (q *classQueue) pop() *Token {
+	for i := range q.lanes {
+		switch l := &q.lanes[i]; {
+		case q.front[i].len() > 0:
+			return q.front[i].pop()
+		case q.sized[i].len() > 0:
+			return q.sized[i].pop()
+		case l.len() > 0 && q.discipline == DisciplineLIFO:
+			return l.removeAt(l.len() - 1)
+		case l.len() > 0:
+			return l.pop()
+		}
+	}
+	return nil
+}
+
+func (q *classQueue) peek() *Token {
+	var next *Token
+	q.each(func(_ int, token *Token) bool {
+		next = token
+		return false
+	})
+	return next
+}
+
+func (q *classQueue) remove(token *Token) bool {
+	i := q.laneIndex(token.Class)
+	if q.front[i].remove(token) {
+		return true
+	}
+	if q.discipline == DisciplineSJF {
+		return q.sized[i].remove(token)
+	}
+	return q.lanes[i].remove(token)
+}
+
+func (q *classQueue) each(fn func(index int, token *Token) bool) {
+	index := 0
+	visit := func(token *Token) bool {
+		if !fn(index, token) {
+			return false
+		}
+		index++
+		return true
+	}
+	for i := range q.lanes {
+		front, l := &q.front[i], &q.lanes[i]
+		for j := 0; j < front.len(); j++ {
+			if !visit(front.at(j)) {
+				return
+			}
+		}
+		if !q.sized[i].each(visit) {
+			return
+		}
+		if q.discipline == DisciplineLIFO {
+			for j := l.len() - 1; j >= 0; j-- {
+				if !visit(l.at(j)) {
+					return
+				}
+			}
+			continue
+		}
+		for j := 0; j < l.len(); j++ {
+			if !visit(l.at(j)) {
+				return
+			}
+		}
+	}
+}
+
+func (q *classQueue) ahead(token *Token, limit int) (int, []string) {
+	count, known := q.tailPosition(token)
+	var ids []string
+	q.each(func(index int, queued *Token) bool {
+		if queued == token {
+			count, known = index, true
+			return false
+		}
+		if len(ids) < limit {
+			ids = append(ids, queued.ID)
+		}
+		return !known || len(ids) < limit
+	})
+	return count, ids
+}
+
+func (q *classQueue) tailPosition(token *Token) (int, bool) {
+	if q.discipline != "" && q.discipline != DisciplineFIFO {
+		return 0, false
+	}
+	i := q.laneIndex(token.Class)
+	l := &q.lanes[i]
+	if l.len() == 0 || l.at(l.len()-1) != token {
+		return 0, false
+	}
+	position := q.laneLen(i) - 1
+	for j := range i {
+		position += q.laneLen(j)
+	}
+	return position, true
+}
+
+func (q *classQueue) arrivedBefore(tick int) int {
+	count := 0
+	for i := range q.sized {
+		for _, token := range q.sized[i].tokens {
+			if token.queuedAt() < tick {
+				count++
+			}
+		}
+	}
+	for _, rings := range [][]ring{q.front, q.lanes} {
+		for i := range rings {
+			l := &rings[i]
+			count += sort.Search(l.len(), func(j int) bool { return l.at(j).queuedAt() >= tick })
+		}
+	}
+	return count
+}
@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	var r ring
+	tokens := make([]*Token, 40)
+	for i := range tokens {
+		tokens[i] = &Token{ID: tokenID(i)}
+	}
+
+	next := 0
+	for i, token := range tokens {
+		r.push(token)
+		if i%3 == 2 {
+			if got := r.pop(); got != tokens[next] {
+				t.Fatalf("pop() = %s, want %s", got.ID, tokens[next].ID)
+			}
+			next++
+		}
+	}
+	if r.len() != len(tokens)-next {
+		t.Errorf("len() = %d, want %d", r.len(), len(tokens)-next)
+	}
+	for i := 0; i < r.len(); i++ {
+		if got := r.at(i); got != tokens[next+i] {
+			t.Errorf("at(%d) = %s, want %s", i, got.ID, tokens[next+i].ID)
+		}
+	}
+	for r.len() > 0 {
+		r.pop()
+	}
+	if got := r.pop(); got != nil {
+		t.Errorf("pop() on empty ring = %s, want nil", got.ID)
+	}
+}
+
+func TestClassQueue(t *testing.T) {
+	var q classQueue
+	arrivals := []struct {
+		class string
+		tick  int
+	}{
+		{ClassAnon, 0}, {ClassFree, 1}, {ClassPaid, 2}, {ClassAnon, 2}, {ClassFree, 3}, {ClassPaid, 4},
+	}
+	var tokens []*Token
+	for i, a := range arrivals {
+		token := &Token{ID: tokenID(i), Class: a.class, ArrivalTick: a.tick}
+		tokens = append(tokens, token)
+		q.push(token)
+	}
+
+	for _, tt := range []struct {
+		token     *Token
+		limit     int
+		wantCount int
+		want      []string
+	}{
+		{tokens[4], 5, 3, []string{"T0002", "T0005", "T0001"}},
+		{tokens[4], 2, 3, []string{"T0002", "T0005"}},
+		{tokens[3], 2, 5, []string{"T0002", "T0005"}},
+		{tokens[5], 5, 1, []string{"T0002"}},
+		{tokens[2], 5, 0, nil},
+	} {
+		count, ids := q.ahead(tt.token, tt.limit)
+		if count != tt.wantCount || !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("ahead(%s, %d) = %d, %v, want %d, %v", tt.token.ID, tt.limit, count, ids, tt.wantCount, tt.want)
+		}
+	}
+	for _, tt := range []struct {
+		tick int
+		want int
+	}{{0, 0}, {1, 1}, {2, 2}, {3, 4}, {5, 6}} {
+		if got := q.arrivedBefore(tt.tick); got != tt.want {
+			t.Errorf("arrivedBefore(%d) = %d, want %d", tt.tick, got, tt.want)
+		}
+	}
+
+	var order []string
+	for token := q.pop(); token != nil; token = q.pop() {
+		order = append(order, token.ID)
+	}
+	if want := []string{"T0002", "T0005", "T0001", "T0004", "T0000", "T0003"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("pop order = %v, want %v", order, want)
+	}
+}
+
+func TestClassQueueRemove(t *testing.T) {
+	var q classQueue
+	tokens := make([]*Token, 20)
+	for i := range tokens {
+		tokens[i] = &Token{ID: tokenID(i), Class: ClassFree, ArrivalTick: i}
+		q.push(tokens[i])
+	}
+	q.pop()
+	for _, i := range []int{5, 19, 1} {
+		if !q.remove(tokens[i]) {
+			t.Fatalf("remove(%s) = false, want true", tokens[i].ID)
+		}
+	}
+	if q.remove(tokens[0]) {
+		t.Error("remove of a popped token = true, want false")
+	}
+	var got []int
+	q.each(func(_ int, token *Token) bool {
+		got = append(got, token.ArrivalTick)
+		return true
+	})
+	want := []int{2, 3, 4, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("queue after remove = %v, want %v", got, want)
+	}
+}
+
+func TestClassQueueSJF(t *testing.T) {
+	q := classQueue{discipline: DisciplineSJF}
+	sizes := []int{5, 2, 9, 2, 1, 7, 2, 3, 8, 1, 4, 6}
+	tokens := make([]*Token, len(sizes))
+	for i, size := range sizes {
+		class := ClassFree
+		if i%4 == 3 {
+			class = ClassPaid
+		}
+		tokens[i] = &Token{ID: tokenID(i), Class: class, ArrivalTick: i, size: size}
+		q.push(tokens[i])
+	}
+	order := func() []string {
+		var ids []string
+		q.each(func(_ int, token *Token) bool {
+			ids = append(ids, token.ID)
+			return true
+		})
+		return ids
+	}
+
+	want := []string{"T0003", "T0007", "T0011", "T0004", "T0009", "T0001", "T0006", "T0010", "T0000", "T0005", "T0008", "T0002"}
+	if got := order(); !reflect.DeepEqual(got, want) {
+		t.Errorf("each() = %v, want %v", got, want)
+	}
+	if count, ids := q.ahead(tokens[6], 2); count != 6 || !reflect.DeepEqual(ids, want[:2]) {
+		t.Errorf("ahead(T0006, 2) = %d, %v, want 6, %v", count, ids, want[:2])
+	}
+	if got := q.arrivedBefore(4); got != 4 {
+		t.Errorf("arrivedBefore(4) = %d, want 4", got)
+	}
+
+	if !q.remove(tokens[9]) || q.remove(tokens[9]) {
+		t.Error("remove(T0009) twice, want true then false")
+	}
+	want = slices.DeleteFunc(want, func(id string) bool { return id == "T0009" })
+	if got := order(); !reflect.DeepEqual(got, want) {
+		t.Errorf("each() after remove = %v, want %v", got, want)
+	}
+	for _, id := range want {
+		if got := q.pop(); got == nil || got.ID != id {
+			t.Fatalf("pop() = %v, want %s", got, id)
+		}
+	}
+	if got := q.pop(); got != nil {
+		t.Errorf("pop() on empty queue = %s, want nil", got.ID)
+	}
+}
@@ -0,0 +1,81 @@
+package engine
+
+import "fmt"
+
+type Quota struct {
+	Class       string `json:"class" yaml:"class"`
+	Limit       int    `json:"limit" yaml:"limit"`
+	WindowTicks int    `json:"window_ticks" yaml:"window_ticks"`
+}
+
+type QuotaState struct {
+	Class       string `json:"class"`
+	Used        int    `json:"used"`
+	Limit       int    `json:"limit"`
+	WindowTicks int    `json:"window_ticks"`
+}
+
+func (q Quota) validate(i int, classes map[string]bool) []error {
+	var errs []error
+	name := fmt.Sprintf("quotas[%d]", i)
+	if !classes[q.Class] {
+		errs = append(errs, fmt.Errorf("%s: unknown class %q", name, q.Class))
+	}
+	if q.Limit <= 0 {
+		errs = append(errs, fmt.Errorf("%s: limit must be positive, got %d", name, q.Limit))
+	}
+	if q.WindowTicks <= 0 {
+		errs = append(errs, fmt.Errorf("%s: window_ticks must be positive, got %d", name, q.WindowTicks))
+	}
+	return errs
+}
+
+type quotaState struct {
+	Quota
+	admitted []int
+}
+
+func newQuotaStates(quotas []Quota) []*quotaState {
+	states := make([]*quotaState, len(quotas))
+	for i, quota := range quotas {
+		states[i] = &quotaState{Quota: quota}
+	}
+	return states
+}
+
+func (q *quotaState) used(tick int) int {
+	expired := 0
+	for expired < len(q.admitted) && q.admitted[expired] <= tick-q.WindowTicks {
+		expired++
+	}
+	q.admitted = q.admitted[expired:]
+	return len(q.admitted)
+}
+
+func (s *Simulator) exceededQuota(tick int, token *Token) *quotaState {
+	for _, quota := range s.quotas {
+		if quota.Class == token.Class && quota.used(tick) >= quota.Limit {
+			return quota
+		}
+	}
+	return nil
+}
+
+func (s *Simulator) admitQuota(tick int, token *Token) {
+	for _, quota := range s.quotas {
+		if quota.Class == token.Class {
+			quota.admitted = append(quota.admitted, tick)
+		}
+	}
+}
+
+func (s *Simulator) quotaStates() []QuotaState {
+	if len(s.quotas) == 0 {
+		return nil
+	}
+	states := make([]QuotaState, len(s.quotas))
+	for i, quota := range s.quotas {
+		states[i] = QuotaState{Class: quota.Class, Used: quota.used(s.tick), Limit: quota.Limit, WindowTicks: quota.WindowTicks}
+	}
+	return states
+}
@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const ReasonNamespaceCore = "core"
+
+type Reason struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+var coreReasons = map[string]string{
+	ReasonQueueAdmission:     "admitted to the queue",
+	ReasonPrioritySchedule:   "picked by class priority, oldest first within a priority",
+	ReasonLIFOSchedule:       "picked newest first",
+	ReasonSJFSchedule:        "picked as the shortest waiting job",
+	ReasonPSSchedule:         "given a share of a processor-sharing server",
+	ReasonServiceComplete:    "finished service",
+	ReasonRejectOverload:     "turned away because the queue was at its limit",
+	ReasonQuotaExceeded:      "turned away because the class used up its admission quota",
+	ReasonStarvationWait:     "waited past the starvation threshold while higher classes were served",
+	ReasonSLOBurn:            "an SLO error budget burned faster than the configured rate",
+	ReasonPositionImproved:   "moved up in the queue",
+	ReasonSustainedOverload:  "degraded service started under sustained overload",
+	ReasonOverloadCleared:    "degraded service ended once the overload cleared",
+	ReasonHedgeDelay:         "still queued after the hedge delay, so a duplicate was sent",
+	ReasonHedgeLost:          "cancelled because the other copy finished first",
+	ReasonDuplicateDropped:   "dropped as a resubmission of a request already seen",
+	ReasonDuplicateCoalesced: "settled with the result of the original request",
+	ReasonScaleUp:            "a server was added and started warming up",
+	ReasonWarmupComplete:     "a server finished warming up",
+	ReasonScaleDown:          "a server was removed and started draining",
+	ReasonDrainComplete:      "a draining server finished its last request",
+	ReasonStallInjected:      "a server stalled",
+	ReasonStallResumed:       "a stalled server resumed",
+	ReasonBackpressureHold:   "held back while the queue was at its limit",
+	ReasonBackpressureShed:   "shed while the queue was at its limit",
+	ReasonDownstreamFull:     "backpressure started because the queue filled up",
+	ReasonDownstreamDrained:  "backpressure ended because the queue drained",
+	ReasonCircuitOpen:        "rejected because the circuit breaker was open",
+	ReasonFailureRate:        "the circuit breaker opened because too many requests failed",
+	ReasonBreakerCooldown:    "the circuit breaker cooled down and let probes through",
+	ReasonProbeFailed:        "a probe failed and the circuit breaker opened again",
+	ReasonProbesSucceeded:    "enough probes succeeded and the circuit breaker closed",
+	ReasonServiceError:       "service failed with the class error rate",
+	ReasonReworkRequired:     "sent back to the queue for another pass",
+	ReasonAffinityWait:       "waited for the server that owns its session",
+	ReasonAffinitySchedule:   "picked by the server that owns its session",
+	ReasonAffinityInversion:  "a lower-priority request took the session's server first",
+	ReasonPolicyInversion:    "a lower-priority request started while this one waited",
+	ReasonScriptSchedule:     "picked by the scenario script",
+	ReasonScriptReject:       "turned away by the scenario script",
+	ReasonPluginSchedule:     "picked by the WebAssembly plugin",
+	ReasonPluginReject:       "turned away by the WebAssembly plugin",
+	ReasonExternalSchedule:   "picked by the external policy process",
+	ReasonExternalReject:     "turned away by the external policy process",
+	ReasonStageAdmitted:      "entered a custom stage",
+	ReasonStageRefused:       "a custom stage refused to admit it",
+	ReasonStageError:         "a custom stage reported a failure",
+	ReasonShadowDiverged:     "the shadow policy would have picked a different request",
+}
+
+var reasonCode = regexp.MustCompile(`^[a-z][a-z0-9_-]*(\.[a-z][a-z0-9_-]*)*\.[A-Z][A-Z0-9_]*$`)
+
+func CoreReasons() []Reason {
+	reasons := make([]Reason, 0, len(coreReasons))
+	for code, description := range coreReasons {
+		reasons = append(reasons, Reason{Code: code, Description: description})
+	}
+	sort.Slice(reasons, func(i, j int) bool { return reasons[i].Code < reasons[j].Code })
+	return reasons
+}
+
+func QualifiedReason(code string) string {
+	if strings.Contains(code, ".") {
+		return code
+	}
+	return ReasonNamespaceCore + "." + code
+}
+
+func coreReason(code string) (string, bool) {
+	description, ok := coreReasons[strings.TrimPrefix(code, ReasonNamespaceCore+".")]
+	return description, ok
+}
+
+func validateReasons(reasons []Reason) []error {
+	var errs []error
+	seen := make(map[string]bool)
+	for i, reason := range reasons {
+		name := fmt.Sprintf("Reasons[%d]", i)
+		switch {
+		case !reasonCode.MatchString(reason.Code):
+			errs = append(errs, fmt.Errorf("%s: code %q must be namespace.CODE, like plugin.example.MY_REASON", name, reason.Code))
+		case strings.HasPrefix(reason.Code, ReasonNamespaceCore+"."):
+			errs = append(errs, fmt.Errorf("%s: code %q uses the reserved %s namespace", name, reason.Code, ReasonNamespaceCore))
+		case seen[reason.Code]:
+			errs = append(errs, fmt.Errorf("%s: duplicate code %q", name, reason.Code))
+		}
+		seen[reason.Code] = true
+		if reason.Description == "" {
+			errs = append(errs, fmt.Errorf("%s: description is required", name))
+		}
+	}
+	return errs
+}
+
+func (s *Simulator) checkReason(event Event) {
+	if _, ok := coreReason(event.ReasonCode); ok || s.reasons[event.ReasonCode] || s.hookErr != nil {
+		return
+	}
+	s.hookErr = fmt.Errorf("%s event at tick %d uses unregistered reason code %q", event.Type, event.Tick, event.ReasonCode)
+}
+
+func (m Metadata) reason(code string) (string, bool) {
+	if description, ok := coreReason(code); ok {
+		return description, true
+	}
+	for _, reason := range m.Reasons {
+		if reason.Code == code {
+			return reason.Description, true
+		}
+	}
+	return "", false
+}
+
+func (m Metadata) ReasonDescription(code string) string {
+	if description, ok := m.reason(code); ok {
+		return description
+	}
+	return code
+}
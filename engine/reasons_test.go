@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReasons(t *testing.T) {
+	custom := Reason{Code: "plugin.audit.POLICY_VIOLATION", Description: "the audit stage found a policy violation"}
+	stage := newDelayStage("audit", 1)
+	stage.fail = "T0005"
+	stage.reason = custom.Code
+	artifact, err := Run(NewConfig(WithSeed(1), WithStages(stage), WithReasons(custom)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(artifact.Metadata.Reasons, []Reason{custom}) {
+		t.Errorf("Metadata.Reasons = %+v, want %+v", artifact.Metadata.Reasons, []Reason{custom})
+	}
+	failed := false
+	for _, event := range artifact.Events {
+		failed = failed || event.Type == EventFail && event.ReasonCode == custom.Code
+	}
+	if !failed {
+		t.Errorf("no FAIL event with reason %s", custom.Code)
+	}
+	if err := ValidateArtifact(artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+	artifact.Metadata.Reasons = nil
+	if err := ValidateArtifact(artifact); err == nil || !strings.Contains(err.Error(), custom.Code) {
+		t.Errorf("ValidateArtifact() without the registry error = %v, want mentioning %s", err, custom.Code)
+	}
+
+	stage = newDelayStage("audit", 1)
+	stage.fail = "T0005"
+	stage.reason = custom.Code
+	if _, err := Run(NewConfig(WithSeed(1), WithStages(stage))); err == nil || !strings.Contains(err.Error(), "unregistered reason code") {
+		t.Errorf("Run() with an unregistered reason error = %v", err)
+	}
+}
+
+func TestReasonDescription(t *testing.T) {
+	m := Metadata{Reasons: []Reason{{Code: "plugin.x.MY_REASON", Description: "mine"}}}
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: ReasonRejectOverload, want: coreReasons[ReasonRejectOverload]},
+		{code: "core." + ReasonRejectOverload, want: coreReasons[ReasonRejectOverload]},
+		{code: "plugin.x.MY_REASON", want: "mine"},
+		{code: "plugin.y.OTHER", want: "plugin.y.OTHER"},
+	}
+	for _, tt := range tests {
+		if got := m.ReasonDescription(tt.code); got != tt.want {
+			t.Errorf("ReasonDescription(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+	if got := QualifiedReason(ReasonRejectOverload); got != "core.REJECT_OVERLOAD" {
+		t.Errorf("QualifiedReason() = %q, want core.REJECT_OVERLOAD", got)
+	}
+}
+
+func TestReasons_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		reason  Reason
+		wantErr string
+	}{
+		{name: "bare", reason: Reason{Code: "MY_REASON", Description: "x"}, wantErr: "must be namespace.CODE"},
+		{name: "lowercase", reason: Reason{Code: "plugin.x.my_reason", Description: "x"}, wantErr: "must be namespace.CODE"},
+		{name: "core", reason: Reason{Code: "core.MY_REASON", Description: "x"}, wantErr: "reserved core namespace"},
+		{name: "description", reason: Reason{Code: "plugin.x.MY_REASON"}, wantErr: "description is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewConfig(WithReasons(tt.reason)).Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+	if err := NewConfig(WithReasons(Reason{Code: "plugin.x.A", Description: "a"}, Reason{Code: "plugin.x.A", Description: "a"})).Validate(); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("Validate() with a duplicate error = %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package engine
+
+import "fmt"
+
+type EventRetention struct {
+	First   int `json:"first"`
+	Last    int `json:"last"`
+	Dropped int `json:"dropped,omitempty"`
+	Tokens  int `json:"tokens,omitempty"`
+}
+
+var terminalEvents = map[string]bool{
+	EventComplete:      true,
+	EventReject:        true,
+	EventFail:          true,
+	EventDuplicateDrop: true,
+}
+
+func (r EventRetention) validate() []error {
+	var errs []error
+	if r.First < 0 || r.Last < 0 {
+		errs = append(errs, fmt.Errorf("EventRetention: First and Last must not be negative, got %d and %d", r.First, r.Last))
+	}
+	if r.First == 0 && r.Last == 0 {
+		errs = append(errs, fmt.Errorf("EventRetention: keep at least one First or Last event per token"))
+	}
+	return errs
+}
+
+func RetainEvents(events []Event, retention EventRetention) ([]Event, EventRetention) {
+	total := make(map[string]int)
+	for _, event := range events {
+		if event.TokenID != "" {
+			total[event.TokenID]++
+		}
+	}
+	summary := EventRetention{First: retention.First, Last: retention.Last}
+	seen := make(map[string]int, len(total))
+	trimmed := make(map[string]bool)
+	kept := make([]Event, 0, len(events))
+	for _, event := range events {
+		id := event.TokenID
+		if id == "" {
+			kept = append(kept, event)
+			continue
+		}
+		i := seen[id]
+		seen[id]++
+		if i < retention.First || i >= total[id]-retention.Last || terminalEvents[event.Type] {
+			kept = append(kept, event)
+			continue
+		}
+		summary.Dropped++
+		trimmed[id] = true
+	}
+	summary.Tokens = len(trimmed)
+	return kept, summary
+}
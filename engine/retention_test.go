@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRetainEvents(t *testing.T) {
+	events := []Event{
+		{Type: EventPositionUpdate, TokenID: "a"},
+		{Type: EventQueue, TokenID: "a"},
+		{Type: EventDrainStart},
+		{Type: EventSchedule, TokenID: "a"},
+		{Type: EventQueue, TokenID: "a"},
+		{Type: EventSchedule, TokenID: "a"},
+		{Type: EventComplete, TokenID: "a"},
+		{Type: EventPositionUpdate, TokenID: "b"},
+		{Type: EventReject, TokenID: "b"},
+	}
+	tests := []struct {
+		name      string
+		retention EventRetention
+		want      []int
+		dropped   int
+		tokens    int
+	}{
+		{name: "first", retention: EventRetention{First: 2}, want: []int{0, 1, 2, 6, 7, 8}, dropped: 3, tokens: 1},
+		{name: "last", retention: EventRetention{Last: 1}, want: []int{2, 6, 8}, dropped: 6, tokens: 2},
+		{name: "both", retention: EventRetention{First: 1, Last: 2}, want: []int{0, 2, 5, 6, 7, 8}, dropped: 3, tokens: 1},
+		{name: "roomy", retention: EventRetention{First: 3, Last: 3}, want: []int{0, 1, 2, 3, 4, 5, 6, 7, 8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, summary := RetainEvents(events, tt.retention)
+			var want []Event
+			for _, i := range tt.want {
+				want = append(want, events[i])
+			}
+			if !reflect.DeepEqual(kept, want) {
+				t.Errorf("RetainEvents() kept %+v, want %+v", kept, want)
+			}
+			if summary.Dropped != tt.dropped || summary.Tokens != tt.tokens {
+				t.Errorf("RetainEvents() dropped %d from %d tokens, want %d from %d", summary.Dropped, summary.Tokens, tt.dropped, tt.tokens)
+			}
+		})
+	}
+}
+
+func TestEventRetention(t *testing.T) {
+	plain, err := Run(NewConfig(WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	capped, err := Run(NewConfig(WithSeed(1), WithEventRetention(1, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	retention := capped.Metadata.EventRetention
+	if retention == nil || retention.Dropped == 0 {
+		t.Fatalf("Metadata.EventRetention = %+v, want dropped events", retention)
+	}
+	if got, want := len(capped.Events), len(plain.Events)-retention.Dropped; got != want {
+		t.Errorf("capped run has %d events, want %d", got, want)
+	}
+	if !reflect.DeepEqual(capped.Snapshots, plain.Snapshots) {
+		t.Error("event retention changed the snapshots")
+	}
+	count := func(events []Event) int {
+		n := 0
+		for _, event := range events {
+			if terminalEvents[event.Type] {
+				n++
+			}
+		}
+		return n
+	}
+	if got, want := count(capped.Events), count(plain.Events); got != want {
+		t.Errorf("capped run kept %d terminal events, want %d", got, want)
+	}
+}
+
+func TestEventRetention_Invalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		first, last int
+		wantErr     string
+	}{
+		{name: "negative", first: -1, last: 2, wantErr: "must not be negative"},
+		{name: "empty", wantErr: "keep at least one"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewConfig(WithEventRetention(tt.first, tt.last)).Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
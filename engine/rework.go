@@ -0,0 +1,63 @@
+package engine
+
+import "fmt"
+
+type Rework struct {
+	Probability float64 `json:"probability" yaml:"probability"`
+	MaxCycles   int     `json:"max_cycles" yaml:"max_cycles"`
+}
+
+func (r Rework) validate() []error {
+	var errs []error
+	if r.Probability <= 0 || r.Probability >= 1 {
+		errs = append(errs, fmt.Errorf("rework: probability must be in (0, 1), got %v", r.Probability))
+	}
+	if r.MaxCycles <= 0 {
+		errs = append(errs, fmt.Errorf("rework: max_cycles must be positive, got %d", r.MaxCycles))
+	}
+	return errs
+}
+
+func (t *Token) queuedAt() int {
+	if t.requeuedTick > 0 {
+		return t.requeuedTick
+	}
+	return t.ArrivalTick
+}
+
+func (s *Simulator) needsRework(token *Token) bool {
+	r := s.scenario.Rework
+	if r == nil || token.hedge != nil || token.hedgeOf != nil || token.Cycle >= r.MaxCycles {
+		return false
+	}
+	return s.reworkRNG.Float64() < r.Probability
+}
+
+func (s *Simulator) rework(tick int, token *Token) {
+	token.Cycle++
+	token.requeuedTick = tick
+	token.State = StateQueued
+	token.StageID = StageQueue
+	token.QueueIndex = -1
+	token.ServiceRemaining = 0
+	token.warned = false
+	token.affinityWait = false
+	token.inverted = false
+	s.enqueue(token)
+	context := s.newContext()
+	*context = EventContext{
+		Rule:        RuleRework,
+		QueueLength: s.queueLength(),
+		Limit:       s.scenario.Rework.MaxCycles,
+		Cycle:       token.Cycle,
+	}
+	s.emit(Event{
+		Tick:       tick,
+		Type:       EventRework,
+		ReasonCode: ReasonReworkRequired,
+		TokenID:    token.ID,
+		StageID:    StageQueue,
+		Class:      token.Class,
+		Context:    context,
+	})
+}
@@ -0,0 +1,119 @@
+package engine
+
+import "fmt"
+
+type Scaling struct {
+	WarmupTicks int         `json:"warmup_ticks" yaml:"warmup_ticks"`
+	Steps       []ScaleStep `json:"steps" yaml:"steps"`
+}
+
+type ScaleStep struct {
+	Tick     int `json:"tick" yaml:"tick"`
+	Capacity int `json:"capacity" yaml:"capacity"`
+}
+
+func (s Scaling) validate() []error {
+	var errs []error
+	if s.WarmupTicks < 0 {
+		errs = append(errs, fmt.Errorf("scaling: warmup_ticks must not be negative, got %d", s.WarmupTicks))
+	}
+	if len(s.Steps) == 0 {
+		errs = append(errs, fmt.Errorf("scaling: at least one step is required"))
+	}
+	prev := -1
+	for i, step := range s.Steps {
+		if step.Tick <= prev || step.Tick >= TickCount {
+			errs = append(errs, fmt.Errorf("scaling: steps[%d]: tick %d must be increasing and below %d", i, step.Tick, TickCount))
+		}
+		if step.Capacity <= 0 {
+			errs = append(errs, fmt.Errorf("scaling: steps[%d]: capacity must be positive, got %d", i, step.Capacity))
+		}
+		prev = step.Tick
+	}
+	return errs
+}
+
+func ServerEvent(eventType string) bool {
+	switch eventType {
+	case EventWarmupStart, EventWarmupEnd, EventDrainStart, EventDrainEnd, EventStallStart, EventStallEnd, EventBackpressureOn, EventBackpressureOff,
+		EventBreakerOpen, EventBreakerHalfOpen, EventBreakerClose:
+		return true
+	}
+	return false
+}
+
+func (s *Simulator) busy() int {
+	return len(s.inService) + s.reservedTransit()
+}
+
+func (s *Simulator) scale(tick int) {
+	scaling := s.scenario.Scaling
+	if scaling == nil {
+		return
+	}
+
+	warm := 0
+	for len(s.warming) > 0 && s.warming[0] <= tick {
+		s.warming = s.warming[1:]
+		warm++
+	}
+	if warm > 0 {
+		s.capacity += warm
+		s.emitScale(tick, EventWarmupEnd, ReasonWarmupComplete, warm)
+	}
+
+	for len(s.steps) > 0 && s.steps[0].Tick == tick {
+		s.target = s.steps[0].Capacity
+		s.steps = s.steps[1:]
+		planned := s.capacity - s.draining + len(s.warming)
+		switch {
+		case s.target > planned:
+			add := s.target - planned
+			undrain := min(add, s.draining)
+			s.draining -= undrain
+			add -= undrain
+			for i := 0; i < add; i++ {
+				s.warming = append(s.warming, tick+scaling.WarmupTicks)
+			}
+			if add > 0 {
+				s.emitScale(tick, EventWarmupStart, ReasonScaleUp, add)
+			}
+		case s.target < planned:
+			remove := planned - s.target
+			cancelled := min(remove, len(s.warming))
+			s.warming = s.warming[:len(s.warming)-cancelled]
+			remove -= cancelled
+			if remove > 0 {
+				s.draining += remove
+				s.emitScale(tick, EventDrainStart, ReasonScaleDown, remove)
+			}
+		}
+	}
+
+	if retired := min(s.draining, s.capacity-s.busy()); retired > 0 {
+		s.capacity -= retired
+		s.draining -= retired
+		s.emitScale(tick, EventDrainEnd, ReasonDrainComplete, retired)
+	}
+	if s.eta != nil {
+		s.eta.capacity = s.capacity - s.draining
+	}
+}
+
+func (s *Simulator) emitScale(tick int, eventType, reason string, servers int) {
+	context := s.newContext()
+	*context = EventContext{
+		Rule:         RuleScaleStep,
+		QueueLength:  s.queueLength(),
+		Limit:        s.target,
+		CapacityUsed: s.busy(),
+		Servers:      servers,
+	}
+	s.emit(Event{
+		Tick:       tick,
+		Type:       eventType,
+		ReasonCode: reason,
+		StageID:    StageService,
+		Context:    context,
+	})
+}
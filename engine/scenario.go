@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+const StageArrivals = "arrivals"
+
+const (
+	NodeSource = "source"
+	NodeQueue  = "queue"
+	NodeServer = "server"
+	NodeSink   = "sink"
+	NodeDelay  = "delay"
+)
+
+type Scenario struct {
+	ID              string `json:"id" yaml:"id"`
+	Capacity        int    `json:"capacity" yaml:"capacity"`
+	ServiceTime     int    `json:"service_time" yaml:"service_time"`
+	RejectThreshold int    `json:"reject_threshold" yaml:"reject_threshold"`
+
+	StarvationThreshold int             `json:"starvation_threshold,omitempty" yaml:"starvation_threshold,omitempty"`
+	WarmupTicks         int             `json:"warmup_ticks,omitempty" yaml:"warmup_ticks,omitempty"`
+	InversionEvents     bool            `json:"inversion_events,omitempty" yaml:"inversion_events,omitempty"`
+	Classes             []ClassDef      `json:"classes,omitempty" yaml:"classes,omitempty"`
+	Discipline          *Discipline     `json:"discipline,omitempty" yaml:"discipline,omitempty"`
+	SLOs                []SLO           `json:"slos,omitempty" yaml:"slos,omitempty"`
+	WaitingRoom         *WaitingRoom    `json:"waiting_room,omitempty" yaml:"waiting_room,omitempty"`
+	Degradation         *Degradation    `json:"degradation,omitempty" yaml:"degradation,omitempty"`
+	Backpressure        *Backpressure   `json:"backpressure,omitempty" yaml:"backpressure,omitempty"`
+	CircuitBreaker      *CircuitBreaker `json:"circuit_breaker,omitempty" yaml:"circuit_breaker,omitempty"`
+	Retry               *Retry          `json:"retry,omitempty" yaml:"retry,omitempty"`
+	Rework              *Rework         `json:"rework,omitempty" yaml:"rework,omitempty"`
+	WorkUnits           *WorkUnits      `json:"work_units,omitempty" yaml:"work_units,omitempty"`
+	Affinity            *Affinity       `json:"affinity,omitempty" yaml:"affinity,omitempty"`
+	Costs               *Costs          `json:"costs,omitempty" yaml:"costs,omitempty"`
+	Script              *Script         `json:"script,omitempty" yaml:"script,omitempty"`
+	Plugin              *Plugin         `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	PolicyProcess       *PolicyProcess  `json:"policy_process,omitempty" yaml:"policy_process,omitempty"`
+	Shadow              *ShadowPolicy   `json:"shadow,omitempty" yaml:"shadow,omitempty"`
+	Presentation        *Presentation   `json:"presentation,omitempty" yaml:"presentation,omitempty"`
+	Quotas              []Quota         `json:"quotas,omitempty" yaml:"quotas,omitempty"`
+	Hedging             *Hedging        `json:"hedging,omitempty" yaml:"hedging,omitempty"`
+	Duplicates          *Duplicates     `json:"duplicates,omitempty" yaml:"duplicates,omitempty"`
+	Dedup               *Dedup          `json:"dedup,omitempty" yaml:"dedup,omitempty"`
+	Transit             []Transit       `json:"transit,omitempty" yaml:"transit,omitempty"`
+	Scaling             *Scaling        `json:"scaling,omitempty" yaml:"scaling,omitempty"`
+	Stalls              []Stall         `json:"stalls,omitempty" yaml:"stalls,omitempty"`
+	Traffic             *Traffic        `json:"traffic,omitempty" yaml:"traffic,omitempty"`
+	Spikes              []Spike         `json:"spikes,omitempty" yaml:"spikes,omitempty"`
+	Arrivals            []Arrival       `json:"arrivals,omitempty" yaml:"arrivals,omitempty"`
+}
+
+type Topology struct {
+	ScenarioID string
+	Nodes      []Node
+	Edges      []Edge
+}
+
+type Node struct {
+	ID          string
+	Kind        string
+	Capacity    int
+	ServiceTime int
+}
+
+type Edge struct {
+	From        string
+	To          string
+	Probability float64
+	Condition   string
+}
+
+func CanonicalScenario() Scenario {
+	return Scenario{
+		ID:              ScenarioID,
+		Capacity:        3,
+		ServiceTime:     1,
+		RejectThreshold: 12,
+		Spikes: []Spike{
+			{At: 150, Ticks: 41, Classes: []string{ClassPaid, ClassFree}},
+		},
+	}
+}
+
+func LookupScenario(id string) (Scenario, error) {
+	if id == "" {
+		id = ScenarioID
+	}
+	if id != ScenarioID {
+		return Scenario{}, fmt.Errorf("%w: %s", ErrUnknownScenario, id)
+	}
+	return CanonicalScenario(), nil
+}
+
+func (s Scenario) warmup(tick int) bool {
+	return tick < s.WarmupTicks
+}
+
+func (s Scenario) Topology() Topology {
+	classes := newClassTable(s.ClassDefs())
+	edges := []Edge{{From: StageArrivals, To: StageQueue, Condition: "admitted"}}
+	for _, def := range classes.defs {
+		if def.QueueLimit != nil {
+			edges = append(edges, Edge{From: StageArrivals, To: StageRejected, Condition: fmt.Sprintf("%s and queue >= %d", def.Name, *def.QueueLimit)})
+		}
+	}
+	errorRate := 0.0
+	for _, def := range classes.defs {
+		errorRate += def.Share * def.ErrorRate
+	}
+	reworkRate := 0.0
+	if s.Rework != nil {
+		reworkRate = (1 - errorRate) * s.Rework.Probability
+	}
+	if s.CircuitBreaker != nil {
+		edges = append(edges, Edge{From: StageArrivals, To: StageRejected, Condition: "circuit open"})
+	}
+	if s.Duplicates != nil && s.Dedup != nil {
+		switch s.Dedup.Mode {
+		case DedupDrop:
+			edges = append(edges, Edge{From: StageArrivals, To: StageRejected, Condition: "duplicate dropped"})
+		case DedupCoalesce:
+			edges = append(edges, Edge{From: StageArrivals, To: StageDone, Condition: "duplicate coalesced"})
+		}
+	}
+	edges = append(edges, s.viaTransit(Edge{From: StageQueue, To: StageService, Probability: 1, Condition: "priority " + strings.Join(classes.order, " > ")})...)
+	edges = append(edges, s.viaTransit(Edge{From: StageService, To: StageDone, Probability: 1 - errorRate - reworkRate})...)
+	if reworkRate > 0 {
+		edges = append(edges, Edge{From: StageService, To: StageQueue, Probability: reworkRate, Condition: fmt.Sprintf("rework and cycle < %d", s.Rework.MaxCycles)})
+	}
+	nodes := []Node{
+		{ID: StageArrivals, Kind: NodeSource},
+		{ID: StageQueue, Kind: NodeQueue, Capacity: s.RejectThreshold},
+	}
+	if len(s.Transit) > 0 {
+		nodes = append(nodes, Node{ID: StageTransit, Kind: NodeDelay})
+	}
+	nodes = append(nodes,
+		Node{ID: StageService, Kind: NodeServer, Capacity: s.Capacity, ServiceTime: s.ServiceTime},
+		Node{ID: StageDone, Kind: NodeSink},
+		Node{ID: StageRejected, Kind: NodeSink},
+	)
+	if errorRate > 0 {
+		edges = append(edges, Edge{From: StageService, To: StageFailed, Probability: errorRate, Condition: "error"})
+		nodes = append(nodes, Node{ID: StageFailed, Kind: NodeSink})
+	}
+	return Topology{ScenarioID: s.ID, Nodes: nodes, Edges: edges}
+}
+
+func (s Scenario) viaTransit(edge Edge) []Edge {
+	ticks := s.transitTicks(edge.From, edge.To)
+	if ticks == 0 {
+		return []Edge{edge}
+	}
+	hop := Edge{From: StageTransit, To: edge.To, Condition: fmt.Sprintf("%d tick(s)", ticks)}
+	edge.To = StageTransit
+	return []Edge{edge, hop}
+}
@@ -0,0 +1,334 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const scenarioExtends = "extends"
+
+func LoadScenario(path string) (Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+	scenario, err := parseScenario(data, filepath.Ext(path), filepath.Dir(path), []string{path})
+	if err != nil {
+		return Scenario{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return scenario, nil
+}
+
+func ParseScenario(data []byte, ext string) (Scenario, error) {
+	return parseScenario(data, ext, ".", nil)
+}
+
+func parseScenario(data []byte, ext, dir string, chain []string) (Scenario, error) {
+	if ext != ".json" && ext != ".yaml" && ext != ".yml" {
+		return Scenario{}, fmt.Errorf("unsupported scenario format %q (want .yaml, .yml, or .json)", ext)
+	}
+	if fields, err := scenarioFields(data, ext); err == nil && fields[scenarioExtends] != nil {
+		merged, err := resolveExtends(fields, dir, chain)
+		if err != nil {
+			return Scenario{}, err
+		}
+		if data, err = json.Marshal(merged); err != nil {
+			return Scenario{}, err
+		}
+		ext = ".json"
+	}
+	var scenario Scenario
+	switch ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&scenario); err != nil {
+			return Scenario{}, err
+		}
+	default:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&scenario); err != nil {
+			return Scenario{}, err
+		}
+	}
+	if scenario.Plugin != nil && !filepath.IsAbs(scenario.Plugin.Path) {
+		scenario.Plugin.Path = filepath.Join(dir, scenario.Plugin.Path)
+	}
+	return scenario, scenario.Validate()
+}
+
+func scenarioFields(data []byte, ext string) (map[string]any, error) {
+	var fields map[string]any
+	if ext == ".json" {
+		return fields, json.Unmarshal(data, &fields)
+	}
+	return fields, yaml.Unmarshal(data, &fields)
+}
+
+func resolveExtends(fields map[string]any, dir string, chain []string) (map[string]any, error) {
+	name, ok := fields[scenarioExtends].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("%s must be a scenario id or file path", scenarioExtends)
+	}
+	delete(fields, scenarioExtends)
+
+	var base map[string]any
+	switch filepath.Ext(name) {
+	case ".json", ".yaml", ".yml":
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		for _, seen := range chain {
+			if sameFile(seen, path) {
+				return nil, fmt.Errorf("%s cycle: %s", scenarioExtends, strings.Join(append(chain, path), " -> "))
+			}
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if base, err = scenarioFields(data, filepath.Ext(path)); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if base[scenarioExtends] != nil {
+			if base, err = resolveExtends(base, filepath.Dir(path), append(chain, path)); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	default:
+		scenario, err := LookupScenario(name)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(scenario)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &base); err != nil {
+			return nil, err
+		}
+	}
+	return overlayFields(base, fields), nil
+}
+
+func overlayFields(base, overlay map[string]any) map[string]any {
+	if base == nil {
+		base = make(map[string]any, len(overlay))
+	}
+	for key, value := range overlay {
+		if sub, ok := value.(map[string]any); ok {
+			if baseSub, ok := base[key].(map[string]any); ok {
+				base[key] = overlayFields(baseSub, sub)
+				continue
+			}
+		}
+		base[key] = value
+	}
+	return base
+}
+
+func ScenarioFiles(path string) ([]string, error) {
+	files := []string{path}
+	for {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return files, err
+		}
+		fields, err := scenarioFields(data, filepath.Ext(path))
+		if err != nil {
+			return files, fmt.Errorf("%s: %w", path, err)
+		}
+		name, _ := fields[scenarioExtends].(string)
+		switch filepath.Ext(name) {
+		case ".json", ".yaml", ".yml":
+		default:
+			return files, nil
+		}
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(filepath.Dir(path), name)
+		}
+		if slices.ContainsFunc(files, func(seen string) bool { return sameFile(seen, name) }) {
+			return files, nil
+		}
+		files = append(files, name)
+		path = name
+	}
+}
+
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+func (s Scenario) Validate() error {
+	var errs []error
+	if s.ID == "" {
+		errs = append(errs, errors.New("id is required"))
+	}
+	if s.Capacity <= 0 {
+		errs = append(errs, fmt.Errorf("capacity must be positive, got %d", s.Capacity))
+	}
+	if s.ServiceTime <= 0 {
+		errs = append(errs, fmt.Errorf("service_time must be positive, got %d", s.ServiceTime))
+	}
+	if s.RejectThreshold < 0 {
+		errs = append(errs, fmt.Errorf("reject_threshold must not be negative, got %d", s.RejectThreshold))
+	}
+	if s.StarvationThreshold < 0 {
+		errs = append(errs, fmt.Errorf("starvation_threshold must not be negative, got %d", s.StarvationThreshold))
+	}
+	if s.WarmupTicks < 0 || s.WarmupTicks >= TickCount {
+		errs = append(errs, fmt.Errorf("warmup_ticks must be in [0, %d), got %d", TickCount, s.WarmupTicks))
+	}
+	errs = append(errs, nonFinite(reflect.ValueOf(s), "")...)
+	errs = append(errs, validateClasses(s.Classes)...)
+	classes := s.classSet()
+	names := make(map[string]bool, len(s.SLOs))
+	for i, slo := range s.SLOs {
+		errs = append(errs, slo.validate(i, classes)...)
+		if slo.Name != "" && names[slo.Name] {
+			errs = append(errs, fmt.Errorf("slos[%d]: duplicate name %q", i, slo.Name))
+		}
+		names[slo.Name] = true
+	}
+	for i, quota := range s.Quotas {
+		errs = append(errs, quota.validate(i, classes)...)
+	}
+	hops := make(map[[2]string]bool, len(s.Transit))
+	for i, transit := range s.Transit {
+		errs = append(errs, transit.validate(i)...)
+		if hop := [2]string{transit.From, transit.To}; hops[hop] {
+			errs = append(errs, fmt.Errorf("transit[%d]: duplicate hop %s -> %s", i, transit.From, transit.To))
+		} else {
+			hops[hop] = true
+		}
+	}
+	for i, spike := range s.Spikes {
+		errs = append(errs, spike.validate(i, classes)...)
+	}
+	if s.Discipline != nil {
+		errs = append(errs, s.Discipline.validate()...)
+	}
+	if s.Traffic != nil {
+		errs = append(errs, s.Traffic.validate()...)
+	}
+	if s.Scaling != nil {
+		errs = append(errs, s.Scaling.validate()...)
+	}
+	prevEnd := 0
+	for i, stall := range s.Stalls {
+		errs = append(errs, stall.validate(i, prevEnd)...)
+		prevEnd = stall.Tick + max(stall.Ticks, 0)
+	}
+	if s.WaitingRoom != nil {
+		errs = append(errs, s.WaitingRoom.validate()...)
+	}
+	if s.Hedging != nil {
+		errs = append(errs, s.Hedging.validate(classes)...)
+	}
+	if s.Duplicates != nil {
+		errs = append(errs, s.Duplicates.validate()...)
+	}
+	if s.Dedup != nil {
+		errs = append(errs, s.Dedup.validate()...)
+	}
+	if s.Degradation != nil {
+		errs = append(errs, s.Degradation.validate(s.ServiceTime)...)
+	}
+	if s.Backpressure != nil {
+		errs = append(errs, s.Backpressure.validate()...)
+	}
+	if s.CircuitBreaker != nil {
+		errs = append(errs, s.CircuitBreaker.validate()...)
+	}
+	if s.Retry != nil {
+		errs = append(errs, s.Retry.validate()...)
+	}
+	if s.Rework != nil {
+		errs = append(errs, s.Rework.validate()...)
+	}
+	if s.WorkUnits != nil {
+		errs = append(errs, s.WorkUnits.validate()...)
+	}
+	if s.Affinity != nil {
+		errs = append(errs, s.Affinity.validate(s)...)
+	}
+	if s.Costs != nil {
+		errs = append(errs, s.Costs.validate(classes)...)
+	}
+	if len(s.Arrivals) > 0 {
+		errs = append(errs, validateArrivals(s, classes)...)
+	}
+	if s.Script != nil {
+		errs = append(errs, s.Script.validate(s)...)
+	}
+	if s.Plugin != nil {
+		errs = append(errs, s.Plugin.validate(s)...)
+	}
+	if s.PolicyProcess != nil {
+		errs = append(errs, s.PolicyProcess.validate(s)...)
+	}
+	if s.Shadow != nil {
+		errs = append(errs, s.Shadow.validate(s)...)
+	}
+	if s.Presentation != nil {
+		errs = append(errs, s.Presentation.validate(classes)...)
+	}
+	if len(errs) > 0 {
+		return configError(errs)
+	}
+	return nil
+}
+
+func nonFinite(v reflect.Value, path string) []error {
+	var errs []error
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		if f := v.Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+			errs = append(errs, fmt.Errorf("%s must be finite, got %v", path, f))
+		}
+	case reflect.Pointer:
+		if !v.IsNil() {
+			errs = append(errs, nonFinite(v.Elem(), path)...)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			errs = append(errs, nonFinite(v.Index(i), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(fmt.Sprint(a), fmt.Sprint(b)) })
+		for _, key := range keys {
+			errs = append(errs, nonFinite(v.MapIndex(key), fmt.Sprintf("%s.%v", path, key))...)
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				name = field.Name
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			errs = append(errs, nonFinite(v.Field(i), name)...)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,258 @@
+package engine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseScenario(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		ext     string
+		want    Scenario
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			data: "id: busy\ncapacity: 5\nservice_time: 2\nreject_threshold: 20\n",
+			ext:  ".yaml",
+			want: Scenario{ID: "busy", Capacity: 5, ServiceTime: 2, RejectThreshold: 20},
+		},
+		{
+			name: "json",
+			data: `{"id":"busy","capacity":5,"service_time":2,"reject_threshold":20}`,
+			ext:  ".json",
+			want: Scenario{ID: "busy", Capacity: 5, ServiceTime: 2, RejectThreshold: 20},
+		},
+		{name: "unknown yaml field", data: "id: busy\ncapacty: 5\n", ext: ".yml", wantErr: "capacty"},
+		{name: "unknown json field", data: `{"id":"busy","capacty":5}`, ext: ".json", wantErr: "capacty"},
+		{name: "invalid", data: "id: busy\ncapacity: 0\nservice_time: 1\n", ext: ".yaml", wantErr: "capacity must be positive"},
+		{name: "waiting room", data: "id: busy\ncapacity: 1\nservice_time: 1\nwaiting_room:\n  position_step: -1\n", ext: ".yaml", wantErr: "position_step must not be negative"},
+		{name: "degradation", data: "id: busy\ncapacity: 1\nservice_time: 1\ndegradation:\n  queue_length: 4\n  sustain_ticks: 2\n  recover_ticks: 2\n  service_time: 2\n", ext: ".yaml", wantErr: "degradation: service_time must be between 1 and service_time 1"},
+		{name: "quota", data: "id: busy\ncapacity: 1\nservice_time: 1\nquotas:\n  - class: GOLD\n    limit: 5\n    window_ticks: 10\n", ext: ".yaml", wantErr: `quotas[0]: unknown class "GOLD"`},
+		{name: "hedging", data: "id: busy\ncapacity: 1\nservice_time: 1\nhedging:\n  after_ticks: 0\n", ext: ".yaml", wantErr: "hedging: after_ticks must be positive"},
+		{name: "dedup", data: "id: busy\ncapacity: 1\nservice_time: 1\ndedup:\n  mode: merge\n", ext: ".yaml", wantErr: `dedup: unknown mode "merge"`},
+		{name: "transit", data: "id: busy\ncapacity: 1\nservice_time: 1\ntransit:\n  - from: done\n    to: queue\n    ticks: 1\n", ext: ".yaml", wantErr: "transit[0]: unsupported hop done -> queue"},
+		{name: "scaling", data: "id: busy\ncapacity: 1\nservice_time: 1\nscaling:\n  warmup_ticks: 2\n  steps:\n    - tick: 5\n      capacity: 0\n", ext: ".yaml", wantErr: "scaling: steps[0]: capacity must be positive"},
+		{name: "stalls", data: "id: busy\ncapacity: 1\nservice_time: 1\nstalls:\n  - tick: 5\n    ticks: 4\n  - tick: 7\n    ticks: 2\n", ext: ".yaml", wantErr: "stalls[1]: tick 7 must not overlap the previous stall"},
+		{name: "traffic", data: "id: busy\ncapacity: 1\nservice_time: 1\ntraffic:\n  model: pareto\n", ext: ".yaml", wantErr: `traffic: unknown model "pareto"`},
+		{name: "diurnal profile", data: "id: busy\ncapacity: 1\nservice_time: 1\ntraffic:\n  model: diurnal\n  profile: [1, 2, 3]\n", ext: ".yaml", wantErr: "traffic: profile must have 24 hourly rates, got 3"},
+		{name: "spikes", data: "id: busy\ncapacity: 1\nservice_time: 1\nspikes:\n  - at: 150\n    ticks: 20\n    class: ANON\n", ext: ".yaml", wantErr: "spikes[0]: set exactly one of factor or classes"},
+		{name: "classes", data: "id: busy\ncapacity: 1\nservice_time: 1\nclasses:\n  - name: GOLD\n    share: 0.5\n  - name: LEAD\n    share: 0.2\n", ext: ".yaml", wantErr: "classes: shares must sum to 1"},
+		{name: "class service", data: "id: busy\ncapacity: 1\nservice_time: 1\nclasses:\n  - name: GOLD\n    share: 1\n    service:\n      distribution: pareto\n", ext: ".yaml", wantErr: `classes[0]: service: unknown distribution "pareto"`},
+		{name: "discipline", data: "id: busy\ncapacity: 1\nservice_time: 1\ndiscipline:\n  queue: random\n", ext: ".yaml", wantErr: `discipline: unknown queue discipline "random"`},
+		{name: "backpressure", data: "id: busy\ncapacity: 1\nservice_time: 1\nbackpressure:\n  queue_length: 4\n  release_length: 4\n", ext: ".yaml", wantErr: "backpressure: release_length must be between 0 and queue_length 4, got 4"},
+		{name: "circuit breaker", data: "id: busy\ncapacity: 1\nservice_time: 1\ncircuit_breaker:\n  failure_rate: 1.5\n  latency_ms: 1000\n  window_ticks: 10\n  open_ticks: 5\n", ext: ".yaml", wantErr: "circuit_breaker: failure_rate must be in (0, 1], got 1.5"},
+		{name: "error rate", data: "id: busy\ncapacity: 1\nservice_time: 1\nclasses:\n  - name: GOLD\n    share: 1\n    error_rate: 1\n", ext: ".yaml", wantErr: "classes[0]: error_rate must be in [0, 1), got 1"},
+		{name: "retry", data: "id: busy\ncapacity: 1\nservice_time: 1\nretry:\n  attempts: 0\n  delay_ticks: 2\n", ext: ".yaml", wantErr: "retry: attempts must be positive, got 0"},
+		{name: "rework", data: "id: busy\ncapacity: 1\nservice_time: 1\nrework:\n  probability: 0.2\n  max_cycles: 0\n", ext: ".yaml", wantErr: "rework: max_cycles must be positive, got 0"},
+		{name: "work units", data: "id: busy\ncapacity: 1\nservice_time: 1\nwork_units:\n  capacity: 0\n", ext: ".yaml", wantErr: "work_units: capacity must be positive, got 0"},
+		{name: "affinity", data: "id: busy\ncapacity: 2\nservice_time: 1\naffinity:\n  sessions: 4\n  servers: [a]\n", ext: ".yaml", wantErr: "affinity: 1 servers named, want capacity 2"},
+		{name: "warmup", data: "id: busy\ncapacity: 1\nservice_time: 1\nwarmup_ticks: 240\n", ext: ".yaml", wantErr: "warmup_ticks must be in [0, 240), got 240"},
+		{name: "costs", data: "id: busy\ncapacity: 1\nservice_time: 1\ncosts:\n  server_tick: 0.5\n  revenue:\n    GOLD: 2\n", ext: ".yaml", wantErr: `costs: revenue: unknown class "GOLD"`},
+		{name: "arrivals", data: "id: busy\ncapacity: 1\nservice_time: 1\narrivals:\n  - tick: 5\n    class: PAID\n  - tick: 3\n    class: PAID\n", ext: ".yaml", wantErr: "arrivals[1]: tick 3 is before the previous arrival at 5"},
+		{name: "script", data: "id: busy\ncapacity: 1\nservice_time: 1\nscript:\n  admit: queue_length + 1\n", ext: ".yaml", wantErr: `script: admit: "queue_length + 1" is a number, want a bool`},
+		{name: "plugin", data: "id: busy\ncapacity: 1\nservice_time: 1\nplugin:\n  path: missing.wasm\n", ext: ".yaml", wantErr: "plugin: open missing.wasm"},
+		{name: "policy process", data: "id: busy\ncapacity: 1\nservice_time: 1\npolicy_process:\n  command: [./policy]\n", ext: ".yaml", wantErr: "policy_process: set schedule, admit, or both"},
+		{name: "shadow", data: "id: busy\ncapacity: 1\nservice_time: 1\nshadow:\n  queue: fifo\n", ext: ".yaml", wantErr: "shadow: queue fifo is already the scenario's discipline"},
+		{name: "nan", data: "id: busy\ncapacity: 1\nservice_time: 1\nrework:\n  probability: .nan\n  max_cycles: 1\n", ext: ".yaml", wantErr: "rework.probability must be finite, got NaN"},
+		{name: "inf", data: "id: busy\ncapacity: 1\nservice_time: 1\ncosts:\n  server_tick: .inf\n", ext: ".yaml", wantErr: "costs.server_tick must be finite, got +Inf"},
+		{name: "format", data: "id = busy", ext: ".toml", wantErr: "unsupported scenario format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseScenario([]byte(tt.data), tt.ext)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseScenario() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseScenario() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseScenario() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadScenario_Canonical(t *testing.T) {
+	scenario, err := LoadScenario("../scenarios/canonical_v1.yaml")
+	if err != nil {
+		t.Fatalf("LoadScenario() error = %v", err)
+	}
+	if !reflect.DeepEqual(scenario, CanonicalScenario()) {
+		t.Errorf("LoadScenario() = %+v, want %+v", scenario, CanonicalScenario())
+	}
+
+	fromFile, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtin, err := Run(Config{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromFile, builtin) {
+		t.Error("run from scenario file differs from the built-in scenario")
+	}
+}
+
+func TestLoadScenario_Extends(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"busy.yaml":    "extends: canonical_v1\nid: busy\ncapacity: 5\ncosts:\n  server_tick: 1\n  revenue:\n    PAID: 2\n",
+		"cheap.json":   `{"extends": "busy.yaml", "id": "cheap", "costs": {"revenue": {"FREE": 1}}, "spikes": null}`,
+		"loop-a.yaml":  "extends: loop-b.yaml\nid: a\n",
+		"loop-b.yaml":  "extends: loop-a.yaml\nid: b\n",
+		"unknown.yaml": "extends: nope_v9\ncapacity: 2\n",
+		"typo.yaml":    "extends: canonical_v1\ncapacty: 2\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	busy := CanonicalScenario()
+	busy.ID, busy.Capacity = "busy", 5
+	busy.Costs = &Costs{ServerTick: 1, Revenue: map[string]float64{ClassPaid: 2}}
+	cheap := busy
+	cheap.ID, cheap.Spikes = "cheap", nil
+	cheap.Costs = &Costs{ServerTick: 1, Revenue: map[string]float64{ClassPaid: 2, ClassFree: 1}}
+
+	tests := []struct {
+		file    string
+		want    Scenario
+		wantErr string
+	}{
+		{file: "busy.yaml", want: busy},
+		{file: "cheap.json", want: cheap},
+		{file: "loop-a.yaml", wantErr: "extends cycle"},
+		{file: "unknown.yaml", wantErr: "unknown scenario"},
+		{file: "typo.yaml", wantErr: "capacty"},
+	}
+	for _, tt := range tests {
+		got, err := LoadScenario(filepath.Join(dir, tt.file))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadScenario(%s) error = %v, want containing %q", tt.file, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("LoadScenario(%s) error = %v", tt.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LoadScenario(%s) = %+v, want %+v", tt.file, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		file string
+		want []string
+	}{
+		{"busy.yaml", []string{"busy.yaml"}},
+		{"cheap.json", []string{"cheap.json", "busy.yaml"}},
+		{"loop-a.yaml", []string{"loop-a.yaml", "loop-b.yaml"}},
+	} {
+		got, err := ScenarioFiles(filepath.Join(dir, tt.file))
+		if err != nil {
+			t.Errorf("ScenarioFiles(%s) error = %v", tt.file, err)
+			continue
+		}
+		var want []string
+		for _, name := range tt.want {
+			want = append(want, filepath.Join(dir, name))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ScenarioFiles(%s) = %v, want %v", tt.file, got, want)
+		}
+	}
+}
+
+func TestConfigValidate_ScenarioSpec(t *testing.T) {
+	err := NewConfig(WithScenarioSpec(Scenario{ID: "broken"})).Validate()
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("Validate() error = %v, want ErrInvalidConfig", err)
+	}
+	for _, want := range []string{"capacity must be positive", "service_time must be positive"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error = %q, want containing %q", err, want)
+		}
+	}
+}
+
+func TestEmbeddedScenario_Replay(t *testing.T) {
+	spec := Scenario{ID: "edited", Capacity: 2, ServiceTime: 3, RejectThreshold: 8}
+	original, err := Run(NewConfig(WithScenarioSpec(spec), WithSeed(7)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedded := original.Metadata.Scenario
+	if embedded == nil || !reflect.DeepEqual(*embedded, spec) {
+		t.Fatalf("Metadata.Scenario = %+v, want %+v", embedded, spec)
+	}
+	if want, err := spec.ReplayID(7, EngineVersion); err != nil || original.Metadata.ReplayID != want {
+		t.Errorf("ReplayID = %s, want it derived from the spec", original.Metadata.ReplayID)
+	}
+
+	replayed, err := Run(Config{Scenario: embedded, Seed: original.Metadata.Seed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(replayed, original) {
+		t.Error("replay from the embedded scenario differs from the original run")
+	}
+
+	changed := spec
+	changed.Capacity++
+	if id, err := changed.ReplayID(7, EngineVersion); err != nil || id == original.Metadata.ReplayID {
+		t.Error("changing the scenario did not change the replay id")
+	}
+
+	tampered := original
+	tampered.Metadata.Scenario = &changed
+	if err := ValidateArtifact(tampered); err == nil || !strings.Contains(err.Error(), "embedded scenario") {
+		t.Errorf("ValidateArtifact() with an edited scenario error = %v", err)
+	}
+}
+
+func TestParseScenario_SLOs(t *testing.T) {
+	data := `id: slo
+capacity: 3
+service_time: 1
+reject_threshold: 12
+slos:
+  - name: paid-p95
+    class: PAID
+    target: 0.95
+    latency_ms: 2000
+    window_ticks: 40
+    burn_rate: 14.4
+`
+	scenario, err := ParseScenario([]byte(data), ".yaml")
+	if err != nil {
+		t.Fatalf("ParseScenario() error = %v", err)
+	}
+	want := SLO{Name: "paid-p95", Class: ClassPaid, Target: 0.95, LatencyMs: 2000, WindowTicks: 40, BurnRate: 14.4}
+	if len(scenario.SLOs) != 1 || scenario.SLOs[0] != want {
+		t.Errorf("SLOs = %+v, want [%+v]", scenario.SLOs, want)
+	}
+
+	scenario.SLOs = append(scenario.SLOs, SLO{Name: "paid-p95", Class: "GOLD", Target: 1})
+	err = scenario.Validate()
+	for _, want := range []string{"duplicate name", `unknown class "GOLD"`, "target must be between 0 and 1", "latency_ms must be positive", "window_ticks must be positive"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error = %v, want containing %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestScenario_Topology(t *testing.T) {
+	rework := CanonicalScenario()
+	rework.Rework = &Rework{Probability: 0.25, MaxCycles: 2}
+
+	failing := rework
+	failing.Classes = []ClassDef{{Name: "PAID", Share: 1, ErrorRate: 0.2}}
+
+	tests := []struct {
+		name     string
+		scenario Scenario
+		from, to string
+		want     float64
+	}{
+		{"canonical done", CanonicalScenario(), StageService, StageDone, 1},
+		{"rework done", rework, StageService, StageDone, 0.75},
+		{"rework requeue", rework, StageService, StageQueue, 0.25},
+		{"rework with errors done", failing, StageService, StageDone, 0.6},
+		{"rework with errors requeue", failing, StageService, StageQueue, 0.2},
+		{"rework with errors failed", failing, StageService, StageFailed, 0.2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := topologyEdge(tt.scenario.Topology(), tt.from, tt.to)
+			if !ok {
+				t.Fatalf("Topology() has no %s -> %s edge", tt.from, tt.to)
+			}
+			if math.Abs(got.Probability-tt.want) > 1e-9 {
+				t.Errorf("Topology() %s -> %s probability = %v, want %v", tt.from, tt.to, got.Probability, tt.want)
+			}
+		})
+	}
+
+	if _, ok := topologyEdge(CanonicalScenario().Topology(), StageService, StageQueue); ok {
+		t.Errorf("Topology() has a %s -> %s edge without rework", StageService, StageQueue)
+	}
+	var total float64
+	for _, edge := range failing.Topology().Edges {
+		if edge.From == StageService {
+			total += edge.Probability
+		}
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("Topology() service edge probabilities sum to %v, want 1", total)
+	}
+}
+
+func topologyEdge(topology Topology, from, to string) (Edge, bool) {
+	for _, edge := range topology.Edges {
+		if edge.From == from && edge.To == to {
+			return edge, true
+		}
+	}
+	return Edge{}, false
+}
+
+func TestScenario_TopologyOptionalStages(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.CircuitBreaker = &CircuitBreaker{FailureRate: 0.5, LatencyMs: 100, WindowTicks: 10, OpenTicks: 5}
+	scenario.Duplicates = &Duplicates{Probability: 0.1, DelayTicks: 2}
+	scenario.Dedup = &Dedup{Mode: DedupCoalesce}
+	scenario.Transit = []Transit{{From: StageQueue, To: StageService, Ticks: 2}}
+	topology := scenario.Topology()
+
+	tests := []struct {
+		from, to  string
+		condition string
+	}{
+		{StageArrivals, StageRejected, "circuit open"},
+		{StageArrivals, StageDone, "duplicate coalesced"},
+		{StageQueue, StageTransit, "priority PAID > FREE > ANON"},
+		{StageTransit, StageService, "2 tick(s)"},
+		{StageService, StageDone, ""},
+	}
+	for _, tt := range tests {
+		found := false
+		for _, edge := range topology.Edges {
+			if edge.From == tt.from && edge.To == tt.to && edge.Condition == tt.condition {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Topology() missing %s -> %s %q in %+v", tt.from, tt.to, tt.condition, topology.Edges)
+		}
+	}
+	if _, ok := topologyEdge(topology, StageQueue, StageService); ok {
+		t.Errorf("Topology() kept the direct %s -> %s edge despite transit", StageQueue, StageService)
+	}
+	if !slices.ContainsFunc(topology.Nodes, func(node Node) bool { return node.ID == StageTransit && node.Kind == NodeDelay }) {
+		t.Errorf("Topology() nodes = %+v, want a %s node", topology.Nodes, StageTransit)
+	}
+}
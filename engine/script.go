@@ -0,0 +1,427 @@
+package engine
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"strconv"
+)
+
+type Script struct {
+	Schedule string `json:"schedule,omitempty" yaml:"schedule,omitempty"`
+	Admit    string `json:"admit,omitempty" yaml:"admit,omitempty"`
+}
+
+type scriptKind int
+
+const (
+	kindNumber scriptKind = iota
+	kindString
+	kindBool
+)
+
+func (k scriptKind) String() string {
+	switch k {
+	case kindString:
+		return "string"
+	case kindBool:
+		return "bool"
+	}
+	return "number"
+}
+
+type scriptEnv struct {
+	token            string
+	class            string
+	classIndex       int
+	priority         float64
+	wait             float64
+	age              float64
+	size             float64
+	tick             float64
+	queueLength      float64
+	classQueueLength float64
+	busy             float64
+	capacity         float64
+}
+
+var scriptNumbers = map[string]func(*scriptEnv) float64{
+	"priority":           func(e *scriptEnv) float64 { return e.priority },
+	"wait":               func(e *scriptEnv) float64 { return e.wait },
+	"age":                func(e *scriptEnv) float64 { return e.age },
+	"size":               func(e *scriptEnv) float64 { return e.size },
+	"tick":               func(e *scriptEnv) float64 { return e.tick },
+	"queue_length":       func(e *scriptEnv) float64 { return e.queueLength },
+	"class_queue_length": func(e *scriptEnv) float64 { return e.classQueueLength },
+	"busy":               func(e *scriptEnv) float64 { return e.busy },
+	"capacity":           func(e *scriptEnv) float64 { return e.capacity },
+}
+
+type scriptExpr struct {
+	kind scriptKind
+	num  func(*scriptEnv) float64
+	str  func(*scriptEnv) string
+	bool func(*scriptEnv) bool
+}
+
+type compiledScript struct {
+	schedule       func(*scriptEnv) float64
+	admit          func(*scriptEnv) bool
+	choose         func([]scriptEnv) int
+	close          func()
+	scheduleReason string
+	scheduleRule   string
+	admitReason    string
+	admitRule      string
+	err            error
+}
+
+func (sc Script) validate(s Scenario) []error {
+	var errs []error
+	if sc.Schedule == "" && sc.Admit == "" {
+		errs = append(errs, fmt.Errorf("script: set schedule, admit, or both"))
+	}
+	if _, err := sc.compile(); err != nil {
+		errs = append(errs, err)
+	}
+	if sc.Schedule != "" {
+		errs = append(errs, scheduleConflicts("script", s)...)
+	}
+	return errs
+}
+
+func scheduleConflicts(name string, s Scenario) []error {
+	var errs []error
+	if s.processorSharing() {
+		errs = append(errs, fmt.Errorf("%s: schedule has no effect under %s, which serves every request at once", name, ServicePS))
+	}
+	if queue := s.queueDiscipline(); queue != "" && queue != DisciplineFIFO {
+		errs = append(errs, fmt.Errorf("%s: schedule replaces the %s queue discipline; drop one", name, queue))
+	}
+	if s.Affinity != nil {
+		errs = append(errs, fmt.Errorf("%s: schedule cannot be combined with affinity", name))
+	}
+	return errs
+}
+
+func (sc Script) compile() (*compiledScript, error) {
+	compiled := &compiledScript{
+		scheduleReason: ReasonScriptSchedule,
+		scheduleRule:   RuleScriptSchedule,
+		admitReason:    ReasonScriptReject,
+		admitRule:      RuleScriptAdmit,
+	}
+	if sc.Schedule != "" {
+		expr, err := compileScript(sc.Schedule, kindNumber)
+		if err != nil {
+			return nil, fmt.Errorf("script: schedule: %w", err)
+		}
+		compiled.schedule = expr.num
+	}
+	if sc.Admit != "" {
+		expr, err := compileScript(sc.Admit, kindBool)
+		if err != nil {
+			return nil, fmt.Errorf("script: admit: %w", err)
+		}
+		compiled.admit = expr.bool
+	}
+	return compiled, nil
+}
+
+func compileScript(source string, want scriptKind) (scriptExpr, error) {
+	node, err := parser.ParseExpr(source)
+	if err != nil {
+		return scriptExpr{}, fmt.Errorf("%q: %v", source, err)
+	}
+	expr, err := compileNode(node)
+	if err != nil {
+		return scriptExpr{}, fmt.Errorf("%q: %v", source, err)
+	}
+	if expr.kind != want {
+		return scriptExpr{}, fmt.Errorf("%q is a %s, want a %s", source, expr.kind, want)
+	}
+	return expr, nil
+}
+
+func compileNode(node ast.Expr) (scriptExpr, error) {
+	switch n := node.(type) {
+	case *ast.ParenExpr:
+		return compileNode(n.X)
+	case *ast.BasicLit:
+		return compileLiteral(n)
+	case *ast.Ident:
+		switch n.Name {
+		case "true", "false":
+			value := n.Name == "true"
+			return scriptExpr{kind: kindBool, bool: func(*scriptEnv) bool { return value }}, nil
+		case "class":
+			return scriptExpr{kind: kindString, str: func(e *scriptEnv) string { return e.class }}, nil
+		}
+		if get, ok := scriptNumbers[n.Name]; ok {
+			return scriptExpr{kind: kindNumber, num: get}, nil
+		}
+		return scriptExpr{}, fmt.Errorf("unknown name %q", n.Name)
+	case *ast.UnaryExpr:
+		x, err := compileNode(n.X)
+		if err != nil {
+			return scriptExpr{}, err
+		}
+		switch {
+		case n.Op == token.SUB && x.kind == kindNumber:
+			return scriptExpr{kind: kindNumber, num: func(e *scriptEnv) float64 { return -x.num(e) }}, nil
+		case n.Op == token.ADD && x.kind == kindNumber:
+			return x, nil
+		case n.Op == token.NOT && x.kind == kindBool:
+			return scriptExpr{kind: kindBool, bool: func(e *scriptEnv) bool { return !x.bool(e) }}, nil
+		}
+		return scriptExpr{}, fmt.Errorf("operator %s does not apply to a %s", n.Op, x.kind)
+	case *ast.BinaryExpr:
+		return compileBinary(n)
+	case *ast.CallExpr:
+		return compileCall(n)
+	}
+	return scriptExpr{}, fmt.Errorf("unsupported expression %T", node)
+}
+
+func compileLiteral(n *ast.BasicLit) (scriptExpr, error) {
+	switch n.Kind {
+	case token.INT, token.FLOAT:
+		value, err := strconv.ParseFloat(n.Value, 64)
+		if err != nil {
+			return scriptExpr{}, err
+		}
+		return scriptExpr{kind: kindNumber, num: func(*scriptEnv) float64 { return value }}, nil
+	case token.STRING:
+		value, err := strconv.Unquote(n.Value)
+		if err != nil {
+			return scriptExpr{}, err
+		}
+		return scriptExpr{kind: kindString, str: func(*scriptEnv) string { return value }}, nil
+	}
+	return scriptExpr{}, fmt.Errorf("unsupported literal %s", n.Value)
+}
+
+func compileBinary(n *ast.BinaryExpr) (scriptExpr, error) {
+	x, err := compileNode(n.X)
+	if err != nil {
+		return scriptExpr{}, err
+	}
+	y, err := compileNode(n.Y)
+	if err != nil {
+		return scriptExpr{}, err
+	}
+	if x.kind != y.kind {
+		return scriptExpr{}, fmt.Errorf("operator %s mixes a %s and a %s", n.Op, x.kind, y.kind)
+	}
+	number := func(fn func(a, b float64) float64) scriptExpr {
+		return scriptExpr{kind: kindNumber, num: func(e *scriptEnv) float64 { return fn(x.num(e), y.num(e)) }}
+	}
+	compare := func(fn func(a, b float64) bool) scriptExpr {
+		return scriptExpr{kind: kindBool, bool: func(e *scriptEnv) bool { return fn(x.num(e), y.num(e)) }}
+	}
+
+	switch x.kind {
+	case kindNumber:
+		switch n.Op {
+		case token.ADD:
+			return number(func(a, b float64) float64 { return a + b }), nil
+		case token.SUB:
+			return number(func(a, b float64) float64 { return a - b }), nil
+		case token.MUL:
+			return number(func(a, b float64) float64 { return a * b }), nil
+		case token.QUO:
+			return number(func(a, b float64) float64 { return a / b }), nil
+		case token.REM:
+			return number(math.Mod), nil
+		case token.LSS:
+			return compare(func(a, b float64) bool { return a < b }), nil
+		case token.LEQ:
+			return compare(func(a, b float64) bool { return a <= b }), nil
+		case token.GTR:
+			return compare(func(a, b float64) bool { return a > b }), nil
+		case token.GEQ:
+			return compare(func(a, b float64) bool { return a >= b }), nil
+		case token.EQL:
+			return compare(func(a, b float64) bool { return a == b }), nil
+		case token.NEQ:
+			return compare(func(a, b float64) bool { return a != b }), nil
+		}
+	case kindString:
+		switch n.Op {
+		case token.EQL:
+			return scriptExpr{kind: kindBool, bool: func(e *scriptEnv) bool { return x.str(e) == y.str(e) }}, nil
+		case token.NEQ:
+			return scriptExpr{kind: kindBool, bool: func(e *scriptEnv) bool { return x.str(e) != y.str(e) }}, nil
+		}
+	case kindBool:
+		switch n.Op {
+		case token.LAND:
+			return scriptExpr{kind: kindBool, bool: func(e *scriptEnv) bool { return x.bool(e) && y.bool(e) }}, nil
+		case token.LOR:
+			return scriptExpr{kind: kindBool, bool: func(e *scriptEnv) bool { return x.bool(e) || y.bool(e) }}, nil
+		case token.EQL:
+			return scriptExpr{kind: kindBool, bool: func(e *scriptEnv) bool { return x.bool(e) == y.bool(e) }}, nil
+		case token.NEQ:
+			return scriptExpr{kind: kindBool, bool: func(e *scriptEnv) bool { return x.bool(e) != y.bool(e) }}, nil
+		}
+	}
+	return scriptExpr{}, fmt.Errorf("operator %s does not apply to a %s", n.Op, x.kind)
+}
+
+func compileCall(n *ast.CallExpr) (scriptExpr, error) {
+	fn, ok := n.Fun.(*ast.Ident)
+	if !ok {
+		return scriptExpr{}, fmt.Errorf("unsupported call")
+	}
+	args := make([]scriptExpr, len(n.Args))
+	for i, arg := range n.Args {
+		var err error
+		if args[i], err = compileNode(arg); err != nil {
+			return scriptExpr{}, err
+		}
+	}
+	numbers := func(count int) error {
+		if count >= 0 && len(args) != count || count < 0 && len(args) < 2 {
+			return fmt.Errorf("%s: wrong number of arguments", fn.Name)
+		}
+		for _, arg := range args {
+			if arg.kind != kindNumber {
+				return fmt.Errorf("%s: arguments must be numbers, got a %s", fn.Name, arg.kind)
+			}
+		}
+		return nil
+	}
+
+	switch fn.Name {
+	case "min", "max":
+		if err := numbers(-1); err != nil {
+			return scriptExpr{}, err
+		}
+		pick := math.Min
+		if fn.Name == "max" {
+			pick = math.Max
+		}
+		return scriptExpr{kind: kindNumber, num: func(e *scriptEnv) float64 {
+			v := args[0].num(e)
+			for _, arg := range args[1:] {
+				v = pick(v, arg.num(e))
+			}
+			return v
+		}}, nil
+	case "abs":
+		if err := numbers(1); err != nil {
+			return scriptExpr{}, err
+		}
+		return scriptExpr{kind: kindNumber, num: func(e *scriptEnv) float64 { return math.Abs(args[0].num(e)) }}, nil
+	case "cond":
+		if len(args) != 3 || args[0].kind != kindBool || args[1].kind != args[2].kind {
+			return scriptExpr{}, fmt.Errorf("cond: want cond(bool, a, b) with a and b of one type")
+		}
+		test, a, b := args[0].bool, args[1], args[2]
+		return scriptExpr{
+			kind: a.kind,
+			num:  func(e *scriptEnv) float64 { return pickScript(test(e), a.num, b.num)(e) },
+			str:  func(e *scriptEnv) string { return pickScript(test(e), a.str, b.str)(e) },
+			bool: func(e *scriptEnv) bool { return pickScript(test(e), a.bool, b.bool)(e) },
+		}, nil
+	}
+	return scriptExpr{}, fmt.Errorf("unknown function %q (want min, max, abs or cond)", fn.Name)
+}
+
+func pickScript[T any](test bool, a, b T) T {
+	if test {
+		return a
+	}
+	return b
+}
+
+func (s *Simulator) scriptEnv(tick int, token *Token, classQueued map[string]int) *scriptEnv {
+	env := &s.scriptScratch
+	*env = scriptEnv{
+		token:            token.ID,
+		class:            token.Class,
+		classIndex:       s.classes.index(token.Class),
+		priority:         float64(s.classes.def(token.Class).Priority),
+		size:             float64(token.size),
+		tick:             float64(tick),
+		queueLength:      float64(s.queueLength()),
+		classQueueLength: float64(classQueued[token.Class]),
+		busy:             float64(s.busy()),
+		capacity:         float64(s.capacity),
+	}
+	if token.State == StateQueued {
+		env.wait = float64(tick - token.queuedAt())
+		env.age = float64(tick - token.ArrivalTick)
+	}
+	return env
+}
+
+func (s *Simulator) classQueued() map[string]int {
+	counts := make(map[string]int, len(s.classes.defs))
+	s.queue.each(func(_ int, token *Token) bool {
+		counts[token.Class]++
+		return true
+	})
+	return counts
+}
+
+func (s *Simulator) scriptAdmits(tick int, token *Token) bool {
+	if s.script == nil || s.script.admit == nil {
+		return true
+	}
+	return s.script.admit(s.scriptEnv(tick, token, s.classQueued()))
+}
+
+func (s *Simulator) scriptErr() error {
+	if s.script == nil {
+		return nil
+	}
+	return s.script.err
+}
+
+func (s *Simulator) scriptedSchedule() bool {
+	return s.script != nil && (s.script.schedule != nil || s.script.choose != nil)
+}
+
+func (s *Simulator) closeScript() {
+	if s.script != nil && s.script.close != nil {
+		s.script.close()
+	}
+}
+
+func (s *Simulator) popScripted(tick int) *Token {
+	tokens, envs := s.candidates(tick)
+	if len(tokens) == 0 {
+		return nil
+	}
+	chosen := tokens[s.scriptedPick(envs)]
+	s.queue.remove(chosen)
+	return chosen
+}
+
+func (s *Simulator) candidates(tick int) ([]*Token, []scriptEnv) {
+	classQueued := s.classQueued()
+	var tokens []*Token
+	var envs []scriptEnv
+	s.queue.each(func(_ int, token *Token) bool {
+		tokens = append(tokens, token)
+		envs = append(envs, *s.scriptEnv(tick, token, classQueued))
+		return true
+	})
+	return tokens, envs
+}
+
+func (s *Simulator) scriptedPick(envs []scriptEnv) int {
+	if s.script.choose != nil {
+		return s.script.choose(envs)
+	}
+	best, bestScore := 0, math.Inf(1)
+	for i := range envs {
+		if score := s.script.schedule(&envs[i]); i == 0 || score < bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
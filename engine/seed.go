@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func DeriveSeed(masterSeed int64, labels ...string) int64 {
+	h := sha256.New()
+	h.Write([]byte(strconv.FormatInt(masterSeed, 10)))
+	h.Write([]byte{0})
+	for _, label := range labels {
+		h.Write([]byte(label))
+		h.Write([]byte{0})
+	}
+	sum := h.Sum(nil)
+	return int64(binary.BigEndian.Uint64(sum[:8]) &^ (1 << 63))
+}
+
+func ParseSeeds(list string) ([]int64, error) {
+	var seeds []int64
+	seen := make(map[int64]bool)
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.ParseInt(first, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid seed %q", part)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.ParseInt(last, 10, 64); err != nil || to < from {
+				return nil, fmt.Errorf("invalid seed range %q", part)
+			}
+		}
+		for seed := from; seed <= to; seed++ {
+			if !seen[seed] {
+				seen[seed] = true
+				seeds = append(seeds, seed)
+			}
+		}
+	}
+	if len(seeds) == 0 {
+		return nil, errors.New("no seeds given")
+	}
+	return seeds, nil
+}
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDeriveSeed(t *testing.T) {
+	tests := []struct {
+		master int64
+		labels []string
+		want   int64
+	}{
+		{1, nil, 7466477311582418281},
+		{1, []string{"capacity=5/policy=wfq/rep=3"}, 1081255531573784900},
+		{42, []string{"capacity=5", "policy=wfq", "rep=3"}, 2346945470705727785},
+	}
+	for _, tt := range tests {
+		if got := DeriveSeed(tt.master, tt.labels...); got != tt.want {
+			t.Errorf("DeriveSeed(%d, %q) = %d, want %d", tt.master, tt.labels, got, tt.want)
+		}
+	}
+
+	distinct := [][]string{
+		{"ab", "c"},
+		{"a", "bc"},
+		{"abc"},
+		{"c", "ab"},
+		{"ab", "c", ""},
+	}
+	seen := make(map[int64][]string)
+	for _, labels := range distinct {
+		seed := DeriveSeed(1, labels...)
+		if seed < 0 {
+			t.Errorf("DeriveSeed(1, %q) = %d, want non-negative", labels, seed)
+		}
+		if other, ok := seen[seed]; ok {
+			t.Errorf("DeriveSeed(1, %q) collides with %q", labels, other)
+		}
+		seen[seed] = labels
+	}
+	if DeriveSeed(1, "rep=1") == DeriveSeed(2, "rep=1") {
+		t.Error("DeriveSeed() ignores the master seed")
+	}
+}
+
+func TestParseSeeds(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []int64
+		wantErr bool
+	}{
+		{"5", []int64{5}, false},
+		{"1-3", []int64{1, 2, 3}, false},
+		{"1-3, 2, 9", []int64{1, 2, 3, 9}, false},
+		{"3-1", nil, true},
+		{"x", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSeeds(tt.list)
+		if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+			t.Errorf("ParseSeeds(%q) = %v, %v, want %v", tt.list, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+const (
+	ServiceUniform     = "uniform"
+	ServiceExponential = "exponential"
+	ServiceLognormal   = "lognormal"
+)
+
+type ServiceDist struct {
+	Distribution string  `json:"distribution" yaml:"distribution"`
+	Min          int     `json:"min,omitempty" yaml:"min,omitempty"`
+	Max          int     `json:"max,omitempty" yaml:"max,omitempty"`
+	Mean         float64 `json:"mean,omitempty" yaml:"mean,omitempty"`
+	Sigma        float64 `json:"sigma,omitempty" yaml:"sigma,omitempty"`
+}
+
+func (d ServiceDist) validate(name string) []error {
+	var errs []error
+	switch d.Distribution {
+	case ServiceUniform:
+		if d.Min <= 0 || d.Max < d.Min {
+			errs = append(errs, fmt.Errorf("%s: service: want 0 < min <= max, got min %d and max %d", name, d.Min, d.Max))
+		}
+	case ServiceExponential, ServiceLognormal:
+		if d.Mean <= 0 {
+			errs = append(errs, fmt.Errorf("%s: service: mean must be positive, got %v", name, d.Mean))
+		}
+		if d.Distribution == ServiceLognormal && d.Sigma <= 0 {
+			errs = append(errs, fmt.Errorf("%s: service: sigma must be positive, got %v", name, d.Sigma))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%s: service: unknown distribution %q (want %s, %s or %s)",
+			name, d.Distribution, ServiceUniform, ServiceExponential, ServiceLognormal))
+	}
+	return errs
+}
+
+func (d ServiceDist) sample(rng *rand.Rand) int {
+	var ticks float64
+	switch d.Distribution {
+	case ServiceUniform:
+		return d.Min + rng.Intn(d.Max-d.Min+1)
+	case ServiceExponential:
+		ticks = rng.ExpFloat64() * d.Mean
+	case ServiceLognormal:
+		mu := math.Log(d.Mean) - d.Sigma*d.Sigma/2
+		ticks = math.Exp(mu + d.Sigma*rng.NormFloat64())
+	}
+	return max(int(math.Round(ticks)), 1)
+}
+
+func (d ServiceDist) mean() float64 {
+	if d.Distribution == ServiceUniform {
+		return float64(d.Min+d.Max) / 2
+	}
+	return d.Mean
+}
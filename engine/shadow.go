@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+)
+
+type ShadowPolicy struct {
+	Queue    string `json:"queue,omitempty" yaml:"queue,omitempty"`
+	Schedule string `json:"schedule,omitempty" yaml:"schedule,omitempty"`
+}
+
+func (sp ShadowPolicy) validate(s Scenario) []error {
+	var errs []error
+	switch {
+	case sp.Queue == "" && sp.Schedule == "":
+		errs = append(errs, fmt.Errorf("shadow: set queue or schedule"))
+	case sp.Queue != "" && sp.Schedule != "":
+		errs = append(errs, fmt.Errorf("shadow: set queue or schedule, not both"))
+	}
+	switch sp.Queue {
+	case "":
+	case DisciplineFIFO, DisciplineLIFO, DisciplineSJF:
+		primary := s.queueDiscipline()
+		if primary == "" {
+			primary = DisciplineFIFO
+		}
+		if sp.Queue == primary && !s.customSchedule() {
+			errs = append(errs, fmt.Errorf("shadow: queue %s is already the scenario's discipline, so it can never diverge", sp.Queue))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("shadow: unknown queue discipline %q (want %s, %s or %s)", sp.Queue, DisciplineFIFO, DisciplineLIFO, DisciplineSJF))
+	}
+	if sp.Schedule != "" {
+		if _, err := compileScript(sp.Schedule, kindNumber); err != nil {
+			errs = append(errs, fmt.Errorf("shadow: schedule: %w", err))
+		}
+	}
+	if s.processorSharing() {
+		errs = append(errs, fmt.Errorf("shadow: %s serves every request at once, so there are no decisions to shadow", ServicePS))
+	}
+	if s.Affinity != nil {
+		errs = append(errs, fmt.Errorf("shadow: cannot be combined with affinity"))
+	}
+	return errs
+}
+
+func (s Scenario) customSchedule() bool {
+	return (s.Script != nil && s.Script.Schedule != "") || s.Plugin != nil || (s.PolicyProcess != nil && s.PolicyProcess.Schedule)
+}
+
+type shadowPolicy struct {
+	sim      *Simulator
+	queue    string
+	schedule func(*scriptEnv) float64
+}
+
+func (sp ShadowPolicy) compile(sim *Simulator) (shadowPolicy, error) {
+	policy := shadowPolicy{sim: sim, queue: sp.Queue}
+	if sp.Schedule != "" {
+		expr, err := compileScript(sp.Schedule, kindNumber)
+		if err != nil {
+			return shadowPolicy{}, fmt.Errorf("shadow: schedule: %w", err)
+		}
+		policy.schedule = expr.num
+	}
+	return policy, nil
+}
+
+func (p shadowPolicy) Pick(d Decision) int {
+	if p.schedule != nil {
+		envs := p.sim.decisionEnvs
+		best, bestScore := 0, math.Inf(1)
+		for i := range envs {
+			if score := p.schedule(&envs[i]); i == 0 || score < bestScore {
+				best, bestScore = i, score
+			}
+		}
+		return best
+	}
+	next := p.sim.queue.first(p.queue, p.sim.expectedSize)
+	for i, c := range d.Candidates {
+		if next != nil && c.ID == next.ID {
+			return i
+		}
+	}
+	return 0
+}
+
+func (q *classQueue) first(discipline string, size func(*Token) float64) *Token {
+	for i := range q.lanes {
+		l := &q.lanes[i]
+		if l.len() == 0 {
+			continue
+		}
+		switch discipline {
+		case DisciplineLIFO:
+			return l.at(l.len() - 1)
+		case DisciplineSJF:
+			best := l.at(0)
+			for j := 1; j < l.len(); j++ {
+				if size(l.at(j)) < size(best) {
+					best = l.at(j)
+				}
+			}
+			return best
+		}
+		return l.at(0)
+	}
+	return nil
+}
+
+func (s *Simulator) expectedSize(token *Token) float64 {
+	if token.size > 0 {
+		return float64(token.size)
+	}
+	class := s.classes.def(token.Class)
+	ticks := float64(s.serviceTime)
+	if class.Service != nil {
+		ticks = class.Service.mean()
+	}
+	if class.ServiceMultiplier > 0 {
+		ticks *= class.ServiceMultiplier
+	}
+	return ticks
+}
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const SignatureEd25519 = "ed25519"
+
+var (
+	ErrUnsigned     = errors.New("artifact is not signed")
+	ErrBadSignature = errors.New("artifact signature does not match its contents")
+	ErrNotCanonical = errors.New("artifact file differs from the encoding it was signed in")
+)
+
+type Signature struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	Value     string `json:"value"`
+}
+
+func CanonicalArtifact(artifact Artifact) ([]byte, error) {
+	artifact.Signature = nil
+	return json.Marshal(artifact)
+}
+
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+func SignArtifact(artifact Artifact, key ed25519.PrivateKey) (Artifact, error) {
+	data, err := CanonicalArtifact(artifact)
+	if err != nil {
+		return Artifact{}, err
+	}
+	artifact.Signature = &Signature{
+		Algorithm: SignatureEd25519,
+		KeyID:     KeyID(key.Public().(ed25519.PublicKey)),
+		Value:     base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)),
+	}
+	return artifact, nil
+}
+
+func VerifyArtifact(artifact Artifact, key ed25519.PublicKey) error {
+	sig := artifact.Signature
+	if sig == nil {
+		return ErrUnsigned
+	}
+	if sig.Algorithm != SignatureEd25519 {
+		return fmt.Errorf("unsupported signature algorithm %q (want %s)", sig.Algorithm, SignatureEd25519)
+	}
+	if id := KeyID(key); sig.KeyID != id {
+		return fmt.Errorf("artifact was signed by key %s, not %s", sig.KeyID, id)
+	}
+	value, err := base64.StdEncoding.DecodeString(sig.Value)
+	if err != nil {
+		return fmt.Errorf("decode signature: %w", err)
+	}
+	data, err := CanonicalArtifact(artifact)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, data, value) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+func VerifyArtifactData(data []byte, key ed25519.PublicKey) (Artifact, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var artifact Artifact
+	if err := dec.Decode(&artifact); err != nil {
+		return Artifact{}, fmt.Errorf("%w: %v", ErrNotCanonical, err)
+	}
+	if artifact.Signature == nil {
+		return Artifact{}, ErrUnsigned
+	}
+	canonical, err := MarshalArtifact(artifact)
+	if err != nil {
+		return Artifact{}, err
+	}
+	if !bytes.Equal(data, canonical) {
+		return Artifact{}, ErrNotCanonical
+	}
+	return artifact, VerifyArtifact(artifact, key)
+}
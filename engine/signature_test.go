@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignArtifact(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifact, err := Run(NewConfig(WithSeed(1), WithEpoch(time.Date(2024, 6, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))), WithTraceIDs()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := SignArtifact(artifact, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := MarshalArtifact(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Artifact
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyArtifact(decoded, pub); err != nil {
+		t.Errorf("VerifyArtifact() after a round trip error = %v", err)
+	}
+
+	tampered := decoded
+	tampered.Events = append([]Event(nil), decoded.Events...)
+	tampered.Events[0].Class = ClassPaid
+	if err := VerifyArtifact(tampered, pub); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("VerifyArtifact() of an edited artifact error = %v, want %v", err, ErrBadSignature)
+	}
+	if err := VerifyArtifact(decoded, other); err == nil || !strings.Contains(err.Error(), "signed by key") {
+		t.Errorf("VerifyArtifact() with another key error = %v", err)
+	}
+	if err := VerifyArtifact(artifact, pub); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("VerifyArtifact() of an unsigned artifact error = %v, want %v", err, ErrUnsigned)
+	}
+
+	if _, err := VerifyArtifactData(data, pub); err != nil {
+		t.Errorf("VerifyArtifactData() of the written file error = %v", err)
+	}
+	for _, tt := range []struct {
+		name, old, new string
+	}{
+		{"shadowed key", `"seed": 1,`, `"seed": 999, "SEED": 1,`},
+		{"unknown top-level key", `"metadata": {`, `"note": "edited", "metadata": {`},
+		{"unknown metadata key", `"seed": 1,`, `"seed": 1, "reviewed_by": "nobody",`},
+		{"reformatted", "\n  ", "\n "},
+	} {
+		edited := strings.Replace(string(data), tt.old, tt.new, 1)
+		if edited == string(data) {
+			t.Fatalf("%s: %q not found in the artifact", tt.name, tt.old)
+		}
+		if _, err := VerifyArtifactData([]byte(edited), pub); !errors.Is(err, ErrNotCanonical) {
+			t.Errorf("%s: VerifyArtifactData() error = %v, want %v", tt.name, err, ErrNotCanonical)
+		}
+	}
+	unsigned, err := MarshalArtifact(artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyArtifactData(unsigned, pub); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("VerifyArtifactData() of an unsigned file error = %v, want %v", err, ErrUnsigned)
+	}
+}
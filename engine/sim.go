@@ -1,14 +1,22 @@
 package engine
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"maps"
 	"math/rand"
+	"slices"
+	"strconv"
+	"time"
 )
 
-type Config struct {
-	ScenarioID string
-	Seed       int64
+type Observer interface {
+	OnEvent(event Event)
+	OnSnapshot(snapshot Snapshot)
+}
+
+type StartObserver interface {
+	OnStart(metadata Metadata)
 }
 
 type Token struct {
@@ -19,313 +27,983 @@ type Token struct {
 	QueueIndex       int
 	ServiceRemaining int
 	ArrivalTick      int
+	EtaMs            int
+	Ticket           int
+	RequestKey       string
+	Cycle            int
+	Session          string
+	Server           string
+
+	reportedIndex   int
+	hedge           *Token
+	hedgeOf         *Token
+	duplicateOf     *Token
+	followers       []*Token
+	transitTo       string
+	transitUntil    int
+	quality         string
+	scheduledTick   int
+	higherScheduled int
+	warned          bool
+	stalled         bool
+	probe           bool
+	attempt         int
+	requeuedTick    int
+	server          int
+	affinityWait    bool
+	inverted        bool
+	size            int
+	queueSeq        int
+	heapIndex       int
+	work            float64
+	stage           int
 }
 
 type Simulator struct {
-	rng             *rand.Rand
-	seed            int64
-	nextID          int
-	tokens          []*Token
-	paidQueue       []*Token
-	freeQueue       []*Token
-	anonQueue       []*Token
-	inService       []*Token
-	snapshots       []Snapshot
-	events          []Event
-	capacity        int
-	serviceTime     int
-	rejectThreshold int
+	rng            *rand.Rand
+	scenario       Scenario
+	replayID       string
+	seed           int64
+	interval       int
+	labels         map[string]string
+	build          *BuildInfo
+	tick           int
+	nextID         int
+	tokens         []*Token
+	active         []*Token
+	queue          classQueue
+	classes        classTable
+	scheduled      []int
+	tickets        []int
+	lastQueued     *Token
+	lastScheduled  *Token
+	degraded       bool
+	degradeStreak  int
+	held           []*Token
+	pressure       bool
+	slos           []*sloState
+	breaker        *breakerState
+	ledger         *Ledger
+	quotas         []*quotaState
+	duplicateRNG   *rand.Rand
+	serviceRNG     *rand.Rand
+	duplicates     map[int][]*Token
+	failureRNG     *rand.Rand
+	reworkRNG      *rand.Rand
+	sessionRNG     *rand.Rand
+	sessions       map[string]int
+	retries        map[int][]*Token
+	eta            *etaEstimator
+	inService      []*Token
+	transit        []*Token
+	steps          []ScaleStep
+	target         int
+	warming        []int
+	draining       int
+	stalls         []Stall
+	traffic        []int
+	arrivalCursor  int
+	script         *compiledScript
+	scriptScratch  scriptEnv
+	stages         []Stage
+	staged         map[string]*Token
+	policy         SchedulerPolicy
+	wrapped        bool
+	decisionEnvs   []scriptEnv
+	traces         map[string]string
+	classBreakdown bool
+	ids            *tokenIDGen
+	epoch          *time.Time
+	clockTick      int
+	clockTime      string
+	eventFilters   []EventFilter
+	filterSeen     []int
+	retention      *EventRetention
+	customReasons  []Reason
+	reasons        map[string]bool
+	streams        []rngStream
+	hookErr        error
+	stalled        []*Token
+	stalledIdle    int
+	stalledUntil   int
+	snapshots      []Snapshot
+	events         []Event
+	capacity       int
+	serviceTime    int
+	observers      []Observer
+	progress       func(tick, totalTicks int)
+	limits         Limits
+	started        time.Time
+	err            error
+
+	tokenSlab   []Token
+	contextSlab []EventContext
+	stateSlab   []TokenState
+	stageSlab   []StageState
+	classBuf    []string
 }
 
+const slabSize = 128
+
 func Run(cfg Config) (Artifact, error) {
-	if cfg.ScenarioID == "" {
-		cfg.ScenarioID = ScenarioID
+	return RunContext(context.Background(), cfg)
+}
+
+func RunContext(ctx context.Context, cfg Config) (Artifact, error) {
+	sim, err := NewSimulator(cfg)
+	if err != nil {
+		return Artifact{}, err
 	}
-	if cfg.ScenarioID != ScenarioID {
-		return Artifact{}, fmt.Errorf("unknown scenario_id: %s", cfg.ScenarioID)
+	defer sim.Close()
+	for sim.Step() {
+		if err := ctx.Err(); err != nil {
+			return Artifact{}, err
+		}
+	}
+	if err := sim.Err(); err != nil {
+		return Artifact{}, err
+	}
+	return sim.Artifact()
+}
+
+func NewSimulator(cfg Config) (*Simulator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	scenario, err := cfg.scenario()
+	if err != nil {
+		return nil, err
+	}
+
+	replayID, err := scenario.ReplayID(cfg.Seed, EngineVersion)
+	if err != nil {
+		return nil, err
 	}
 
+	classes := newClassTable(scenario.ClassDefs())
 	sim := &Simulator{
-		rng:             rand.New(rand.NewSource(cfg.Seed)),
-		seed:            cfg.Seed,
-		capacity:        3,
-		serviceTime:     1,
-		rejectThreshold: 12,
+		classes:     classes,
+		queue:       classQueue{laneOf: classes.lanes, discipline: scenario.queueDiscipline()},
+		scheduled:   make([]int, classes.laneCount()),
+		tickets:     make([]int, classes.laneCount()),
+		scenario:    scenario,
+		replayID:    replayID,
+		slos:        newSLOStates(scenario.SLOs),
+		quotas:      newQuotaStates(scenario.Quotas),
+		breaker:     newBreakerState(scenario.CircuitBreaker),
+		seed:        cfg.Seed,
+		interval:    cfg.snapshotInterval(),
+		labels:      maps.Clone(cfg.Labels),
+		build:       cfg.Build,
+		capacity:    scenario.Capacity,
+		serviceTime: scenario.ServiceTime,
+		observers:   cfg.Observers,
+		progress:    cfg.Progress,
+		limits:      cfg.Limits,
+		started:     time.Now(),
+	}
+	sim.rng = sim.newRNG("arrivals", cfg.Seed)
+	if cfg.ETA {
+		sim.eta = newETAEstimator(scenario, classes.laneCount())
+	}
+	if cfg.TraceIDs {
+		sim.traces = make(map[string]string)
+	}
+	sim.classBreakdown = cfg.ClassBreakdown
+	if cfg.TokenIDs != nil {
+		sim.ids = newTokenIDGen(*cfg.TokenIDs, sim.replayID)
+	}
+	if cfg.Epoch != nil {
+		epoch := *cfg.Epoch
+		sim.epoch = &epoch
+	}
+	if len(cfg.EventFilters) > 0 {
+		sim.eventFilters = slices.Clone(cfg.EventFilters)
+		sim.filterSeen = make([]int, len(cfg.EventFilters))
+	}
+	if len(cfg.Reasons) > 0 {
+		sim.customReasons = slices.Clone(cfg.Reasons)
+		sim.reasons = make(map[string]bool, len(cfg.Reasons))
+		for _, reason := range cfg.Reasons {
+			sim.reasons[reason.Code] = true
+		}
+	}
+	if cfg.EventRetention != nil {
+		sim.retention = &EventRetention{First: cfg.EventRetention.First, Last: cfg.EventRetention.Last}
+	}
+	if scenario.Scaling != nil {
+		sim.steps = scenario.Scaling.Steps
+		sim.target = scenario.Capacity
+	}
+	sim.stalls = scenario.Stalls
+	if failingClasses(classes.defs) {
+		sim.failureRNG = sim.newRNG("failures", DeriveSeed(cfg.Seed, "failures"))
+		sim.retries = make(map[int][]*Token)
+	}
+	if scenario.Script != nil {
+		if sim.script, err = scenario.Script.compile(); err != nil {
+			return nil, err
+		}
+	}
+	if scenario.Plugin != nil {
+		if sim.script, err = scenario.Plugin.load(); err != nil {
+			return nil, err
+		}
 	}
+	if scenario.PolicyProcess != nil {
+		if sim.script, err = scenario.PolicyProcess.start(scenario.ID, cfg.Seed); err != nil {
+			return nil, err
+		}
+	}
+	if len(cfg.Stages) > 0 {
+		sim.stages = cfg.Stages
+		sim.staged = make(map[string]*Token)
+	}
+	middleware := cfg.Middleware
+	if scenario.Shadow != nil {
+		shadow, err := scenario.Shadow.compile(sim)
+		if err != nil {
+			return nil, err
+		}
+		middleware = append(slices.Clip(middleware), Shadow(shadow))
+	}
+	if len(middleware) > 0 {
+		sim.policy = chainPolicy(builtinPolicy{sim: sim}, middleware)
+		sim.wrapped = len(cfg.Middleware) > 0
+	}
+	if scenario.Affinity != nil {
+		sim.sessionRNG = sim.newRNG("sessions", DeriveSeed(cfg.Seed, "sessions"))
+		sim.sessions = make(map[string]int)
+	}
+	if scenario.Costs != nil {
+		sim.ledger = &Ledger{}
+	}
+	if scenario.Rework != nil {
+		sim.reworkRNG = sim.newRNG("rework", DeriveSeed(cfg.Seed, "rework"))
+	}
+	if sampledService(classes.defs) {
+		sim.serviceRNG = sim.newRNG("service", DeriveSeed(cfg.Seed, "service"))
+	}
+	if scenario.Traffic != nil {
+		sim.traffic = scenario.Traffic.series(sim.rng)
+	}
+	if scenario.Duplicates != nil {
+		sim.duplicateRNG = sim.newRNG("duplicates", DeriveSeed(cfg.Seed, "duplicates"))
+		sim.duplicates = make(map[int][]*Token)
+	}
+	for _, observer := range sim.observers {
+		if starter, ok := observer.(StartObserver); ok {
+			starter.OnStart(sim.Metadata())
+		}
+	}
+	return sim, nil
+}
 
-	for tick := 0; tick < TickCount; tick++ {
-		sim.step(tick)
+func (s *Simulator) Step() bool {
+	if s.Done() || s.err != nil {
+		return false
+	}
+	s.step(s.tick)
+	if s.err = s.scriptErr(); s.err == nil {
+		s.err = s.hookErr
+	}
+	if s.err == nil {
+		s.err = s.checkLimits(s.tick)
 	}
+	s.tick++
+	if s.Done() || s.err != nil {
+		s.Close()
+	}
+	if s.progress != nil {
+		s.progress(s.tick, TickCount)
+	}
+	return true
+}
 
+func (s *Simulator) Done() bool {
+	return s.tick >= TickCount
+}
+
+func (s *Simulator) Err() error {
+	return s.err
+}
+
+func (s *Simulator) Close() {
+	s.closeScript()
+}
+
+func (s *Simulator) Tick() int {
+	return s.tick
+}
+
+func (s *Simulator) Metadata() Metadata {
+	scenario := s.scenario
 	metadata := Metadata{
-		ScenarioID:      cfg.ScenarioID,
-		Seed:            cfg.Seed,
+		ScenarioID:      scenario.ID,
+		Seed:            s.seed,
 		EngineVersion:   EngineVersion,
-		ReplayID:        ReplayID(cfg.ScenarioID, cfg.Seed, EngineVersion),
+		ReplayID:        s.replayID,
+		Scenario:        &scenario,
 		TickCount:       TickCount,
 		TickDurationMs:  TickDurationMs,
 		TotalDurationMs: TotalDurationMs,
+		SnapshotSchema:  SnapshotSchemaActive,
+		WarmupTicks:     scenario.WarmupTicks,
+	}
+	if s.interval > 1 {
+		metadata.SnapshotInterval = s.interval
+	}
+	metadata.ETA = s.eta != nil
+	metadata.TraceIDs = s.traces != nil
+	metadata.ClassBreakdown = s.classBreakdown
+	if s.ids != nil {
+		ids := s.ids.scheme
+		ids.ClassPrefixes = maps.Clone(ids.ClassPrefixes)
+		metadata.TokenIDs = &ids
+	}
+	if s.epoch != nil {
+		epoch := *s.epoch
+		metadata.Epoch = &epoch
+	}
+	if len(s.eventFilters) > 0 {
+		metadata.EventFilters = slices.Clone(s.eventFilters)
+	}
+	if len(s.customReasons) > 0 {
+		metadata.Reasons = slices.Clone(s.customReasons)
+	}
+	if s.retention != nil {
+		_, summary := RetainEvents(s.events, *s.retention)
+		metadata.EventRetention = &summary
 	}
+	metadata.Classes = slices.Clone(s.classes.sorted)
+	if scenario.Presentation != nil {
+		metadata.Presentation = s.presentation()
+	}
+	if len(s.labels) > 0 {
+		metadata.Labels = maps.Clone(s.labels)
+	}
+	if s.build != nil {
+		build := *s.build
+		metadata.Build = &build
+	}
+	if s.scenario.PolicyProcess != nil {
+		metadata.Caveats = append(metadata.Caveats, s.scenario.PolicyProcess.caveat())
+	}
+	if len(s.stages) > 0 {
+		metadata.Caveats = append(metadata.Caveats, stagesCaveat(s.stages))
+	}
+	if s.wrapped {
+		metadata.Caveats = append(metadata.Caveats, middlewareCaveat)
+	}
+	return metadata
+}
 
-	if len(sim.events) == 0 {
+func (s *Simulator) Snapshots() []Snapshot {
+	return s.snapshots[:len(s.snapshots):len(s.snapshots)]
+}
+
+func (s *Simulator) Events() []Event {
+	return s.events[:len(s.events):len(s.events)]
+}
+
+func (s *Simulator) Artifact() (Artifact, error) {
+	if len(s.events) == 0 {
 		return Artifact{}, errors.New("no events produced")
 	}
 
+	metadata := s.Metadata()
+	events := s.events
+	if s.retention != nil {
+		var summary EventRetention
+		events, summary = RetainEvents(s.events, *s.retention)
+		metadata.EventRetention = &summary
+	}
 	return Artifact{
 		Metadata:  metadata,
-		Snapshots: sim.snapshots,
-		Events:    sim.events,
+		Snapshots: s.snapshots,
+		Events:    events,
 	}, nil
 }
 
 func (s *Simulator) step(tick int) {
+	s.stall(tick)
 	s.nextService(tick)
+	s.advanceTransit(tick)
+	s.advanceStages(tick)
+	s.scale(tick)
+	s.evaluateBreaker(tick)
 	s.arrivals(tick)
+	s.evaluateDegradation(tick)
 	s.schedule(tick)
+	s.detectStarvation(tick)
+	s.evaluateSLOs(tick)
+	s.spawnHedges(tick)
 	s.updateQueueIndices()
-	s.snapshots = append(s.snapshots, Snapshot{
+	s.updatePositions(tick)
+	s.chargeServers()
+	if tick%s.interval != 0 && tick != TickCount-1 {
+		return
+	}
+	snapshot := Snapshot{
 		Tick:   tick,
 		TimeMs: tick * TickDurationMs,
 		Tokens: s.snapshotTokens(),
 		Stages: s.snapshotStages(),
-	})
+		Warmup: s.scenario.warmup(tick),
+		Ledger: s.ledgerSnapshot(),
+	}
+	if s.epoch != nil {
+		snapshot.Time = s.wallClock(tick)
+	}
+	s.snapshots = append(s.snapshots, snapshot)
+	for _, observer := range s.observers {
+		observer.OnSnapshot(snapshot)
+	}
+}
+
+func (s *Simulator) emit(event Event) {
+	s.checkReason(event)
+	if s.eventFilters != nil && s.filtered(event) {
+		return
+	}
+	event.Warmup = s.scenario.warmup(event.Tick)
+	if s.traces != nil && event.TokenID != "" {
+		event.TraceID = s.traceID(event.TokenID)
+	}
+	if s.epoch != nil {
+		event.Time = s.wallClock(event.Tick)
+	}
+	s.events = append(s.events, event)
+	for _, observer := range s.observers {
+		observer.OnEvent(event)
+	}
 }
 
 func (s *Simulator) nextService(tick int) {
 	remaining := s.inService[:0]
+	var finished []*Token
+	rate := s.shareRate()
 	for _, token := range s.inService {
-		token.ServiceRemaining--
-		if token.ServiceRemaining <= 0 {
-			token.State = StateDone
-			token.StageID = StageDone
-			token.QueueIndex = -1
-			s.events = append(s.events, Event{
-				Tick:       tick,
-				Type:       EventComplete,
-				ReasonCode: ReasonServiceComplete,
-				TokenID:    token.ID,
-				StageID:    StageDone,
-				Class:      token.Class,
-			})
+		if token.State == StateCancelled {
+			continue
+		}
+		if token.stalled {
+			remaining = append(remaining, token)
+			continue
+		}
+		if s.advance(token, rate) {
+			finished = append(finished, token)
 			continue
 		}
 		remaining = append(remaining, token)
 	}
 	s.inService = remaining
+	for _, token := range finished {
+		if token.State == StateCancelled {
+			continue
+		}
+		if s.eta != nil {
+			s.eta.observeService(tick - token.scheduledTick)
+		}
+		if ticks := s.scenario.transitTicks(StageService, StageDone); ticks > 0 {
+			s.startTransit(tick, token, StageDone, ticks)
+			continue
+		}
+		s.finishService(tick, token)
+	}
+	s.inService = slices.DeleteFunc(s.inService, func(token *Token) bool { return token.State == StateCancelled })
+}
+
+func (s *Simulator) complete(tick int, token *Token) {
+	if s.fails(token) {
+		s.fail(tick, token, ReasonServiceError, RuleErrorRate)
+		return
+	}
+	if s.needsRework(token) {
+		s.rework(tick, token)
+		return
+	}
+	token.State = StateDone
+	token.StageID = StageDone
+	token.QueueIndex = -1
+	request := token.request()
+	s.recordOutcome(tick, request, true)
+	s.emit(Event{
+		Tick:       tick,
+		Type:       EventComplete,
+		ReasonCode: ReasonServiceComplete,
+		TokenID:    request.ID,
+		StageID:    StageDone,
+		Class:      token.Class,
+		Quality:    token.quality,
+		Context:    s.hedgeContext(token),
+	})
+	s.cancelCopy(tick, token)
+	s.settleDuplicates(tick, request, true)
+	s.observeBreaker(tick, request, true)
 }
 
 func (s *Simulator) schedule(tick int) {
-	capacityAvailable := s.capacity - len(s.inService)
-	for capacityAvailable > 0 {
-		token := s.popNextQueued()
+	capacityAvailable := s.capacity - s.draining - s.busy() - s.stalledIdle
+	if s.scenario.processorSharing() {
+		capacityAvailable = s.queueLength()
+	}
+	for capacityAvailable > 0 && s.unitsFit() {
+		queueLength := s.queueLength()
+		token := s.popNextQueued(tick)
 		if token == nil {
 			return
 		}
-		token.State = StateProcessing
-		token.StageID = StageService
+		context := s.newContext()
+		*context = EventContext{
+			Rule:         s.scheduleRule(token.Class),
+			QueueLength:  queueLength,
+			CapacityUsed: len(s.inService),
+			WaitTicks:    tick - token.queuedAt(),
+			Bypassed:     s.bypassed(token),
+			Server:       token.Server,
+		}
+		if token.hedgeOf != nil {
+			context.Hedge = token.ID
+		}
 		token.QueueIndex = -1
-		token.ServiceRemaining = s.serviceTime
-		s.inService = append(s.inService, token)
-		s.events = append(s.events, Event{
+		token.EtaMs = 0
+		token.scheduledTick = tick
+		s.lastScheduled = token.request()
+		if ticks := s.scenario.transitTicks(StageQueue, StageService); ticks > 0 {
+			s.startTransit(tick, token, StageService, ticks)
+		} else {
+			s.startService(token)
+		}
+		s.scheduled[s.classes.lane(token.Class)]++
+		s.emit(Event{
 			Tick:       tick,
 			Type:       EventSchedule,
-			ReasonCode: ReasonPrioritySchedule,
-			TokenID:    token.ID,
-			StageID:    StageService,
+			ReasonCode: s.scheduleReason(token),
+			TokenID:    token.request().ID,
+			StageID:    token.StageID,
 			Class:      token.Class,
+			Context:    context,
 		})
+		s.detectInversion(tick, token)
 		capacityAvailable--
 	}
 }
 
+func (s *Simulator) startService(token *Token) {
+	token.State = StateProcessing
+	token.StageID = StageService
+	token.ServiceRemaining = s.serviceTimeFor(token)
+	token.work = float64(token.ServiceRemaining)
+	s.inService = append(s.inService, token)
+}
+
 func (s *Simulator) arrivals(tick int) {
-	count := arrivalCount(tick)
-	classes := s.arrivalClasses(tick, count)
-	for _, class := range classes {
-		token := s.newToken(class, tick)
-		if s.shouldReject(token) {
-			token.State = StateRejected
-			token.StageID = StageRejected
-			token.QueueIndex = -1
-			s.events = append(s.events, Event{
-				Tick:       tick,
-				Type:       EventReject,
-				ReasonCode: ReasonRejectOverload,
-				TokenID:    token.ID,
-				StageID:    StageRejected,
-				Class:      token.Class,
-			})
-			continue
+	s.releaseHeld(tick)
+	if len(s.scenario.Arrivals) > 0 {
+		s.tracedArrivals(tick)
+	} else {
+		for _, class := range s.arrivalClasses(tick, s.arrivalCount(tick)) {
+			token := s.newToken(class, tick)
+			s.assignSession(token)
+			if s.dispatch(tick, token) {
+				s.planDuplicate(tick, token)
+			}
 		}
+	}
+	s.duplicateArrivals(tick)
+	s.retryArrivals(tick)
+}
 
-		token.State = StateQueued
-		token.StageID = StageQueue
-		token.QueueIndex = -1
-		s.enqueue(token)
-		s.events = append(s.events, Event{
-			Tick:       tick,
-			Type:       EventQueue,
-			ReasonCode: ReasonQueueAdmission,
-			TokenID:    token.ID,
-			StageID:    StageQueue,
-			Class:      token.Class,
+func (s *Simulator) admit(tick int, token *Token) bool {
+	if s.circuitOpen() {
+		s.rejectOpenCircuit(tick, token)
+		return false
+	}
+	if quota := s.exceededQuota(tick, token); quota != nil {
+		s.reject(tick, token, ReasonQuotaExceeded, EventContext{
+			Rule:        RuleClassQuota,
+			QueueLength: s.queueLength(),
+			Limit:       quota.Limit,
+			WindowTicks: quota.WindowTicks,
+		})
+		return false
+	}
+	if limit, ok := s.classes.queueLimit(token.Class); ok && s.queueLength() >= limit {
+		s.reject(tick, token, ReasonRejectOverload, EventContext{
+			Rule:        queueLimitRule(token.Class),
+			QueueLength: s.queueLength(),
+			Limit:       limit,
 		})
+		return false
+	}
+	if !s.scriptAdmits(tick, token) {
+		s.reject(tick, token, s.script.admitReason, EventContext{
+			Rule:        s.script.admitRule,
+			QueueLength: s.queueLength(),
+		})
+		return false
+	}
+
+	s.claimProbe(token)
+	token.State = StateQueued
+	token.StageID = StageQueue
+	token.QueueIndex = -1
+	if s.scenario.sizedJobs() && token.size == 0 {
+		token.size = s.jobSize(token)
+	}
+	s.enqueue(token)
+	s.admitQuota(tick, token)
+	s.issueTicket(token)
+	if s.eta != nil {
+		s.eta.observeArrival(s.classes.lane(token.Class))
 	}
+	aheadCount, ahead := s.queue.ahead(token, MaxAheadListed)
+	context := s.newContext()
+	*context = EventContext{
+		Rule:        RuleAdmit,
+		QueueLength: s.queueLength(),
+		AheadCount:  aheadCount,
+		Ahead:       ahead,
+		RequestKey:  token.duplicateKey(),
+	}
+	s.emit(Event{
+		Tick:       tick,
+		Type:       EventQueue,
+		ReasonCode: ReasonQueueAdmission,
+		TokenID:    token.ID,
+		StageID:    StageQueue,
+		Class:      token.Class,
+		Context:    context,
+	})
+	return true
+}
+
+func (s *Simulator) reject(tick int, token *Token, reason string, ctx EventContext) {
+	token.State = StateRejected
+	token.StageID = StageRejected
+	token.QueueIndex = -1
+	s.recordOutcome(tick, token, false)
+	s.chargeRejection()
+	context := s.newContext()
+	*context = ctx
+	s.emit(Event{
+		Tick:       tick,
+		Type:       EventReject,
+		ReasonCode: reason,
+		TokenID:    token.ID,
+		StageID:    StageRejected,
+		Class:      token.Class,
+		Context:    context,
+	})
 }
 
 func (s *Simulator) newToken(class string, tick int) *Token {
-	id := fmt.Sprintf("T%04d", s.nextID)
+	var id string
+	if s.ids != nil {
+		id = s.ids.next(class)
+	} else {
+		id = tokenID(s.nextID)
+	}
 	s.nextID++
-	token := &Token{
+	token := s.takeToken()
+	*token = Token{
 		ID:          id,
 		Class:       class,
 		ArrivalTick: tick,
 		QueueIndex:  -1,
 	}
 	s.tokens = append(s.tokens, token)
+	s.active = append(s.active, token)
 	return token
 }
 
-func (s *Simulator) enqueue(token *Token) {
-	switch token.Class {
-	case ClassPaid:
-		s.paidQueue = append(s.paidQueue, token)
-	case ClassFree:
-		s.freeQueue = append(s.freeQueue, token)
-	default:
-		s.anonQueue = append(s.anonQueue, token)
+func (s *Simulator) takeToken() *Token {
+	if len(s.tokenSlab) == 0 {
+		s.tokenSlab = make([]Token, slabSize)
+	}
+	token := &s.tokenSlab[0]
+	s.tokenSlab = s.tokenSlab[1:]
+	return token
+}
+
+func tokenID(n int) string {
+	var buf [24]byte
+	b := append(buf[:0], 'T')
+	for width := 1000; width > 1 && n < width; width /= 10 {
+		b = append(b, '0')
 	}
+	return string(strconv.AppendInt(b, int64(n), 10))
 }
 
-func (s *Simulator) popNextQueued() *Token {
-	if len(s.paidQueue) > 0 {
-		token := s.paidQueue[0]
-		s.paidQueue = s.paidQueue[1:]
-		return token
+func (s *Simulator) newContext() *EventContext {
+	if len(s.contextSlab) == 0 {
+		s.contextSlab = make([]EventContext, slabSize)
 	}
-	if len(s.freeQueue) > 0 {
-		token := s.freeQueue[0]
-		s.freeQueue = s.freeQueue[1:]
-		return token
+	context := &s.contextSlab[0]
+	s.contextSlab = s.contextSlab[1:]
+	return context
+}
+
+func (s *Simulator) takeStates(n int) []TokenState {
+	if len(s.stateSlab) < n {
+		s.stateSlab = make([]TokenState, max(n, slabSize))
+	}
+	states := s.stateSlab[:0:n]
+	s.stateSlab = s.stateSlab[n:]
+	return states
+}
+
+func (s *Simulator) takeStages(n int) []StageState {
+	if len(s.stageSlab) < n {
+		s.stageSlab = make([]StageState, max(n, slabSize))
 	}
-	if len(s.anonQueue) > 0 {
-		token := s.anonQueue[0]
-		s.anonQueue = s.anonQueue[1:]
-		return token
+	stages := s.stageSlab[:n:n]
+	s.stageSlab = s.stageSlab[n:]
+	return stages
+}
+
+func (s *Simulator) enqueue(token *Token) {
+	token.higherScheduled = s.higherScheduled(token)
+	if token.hedgeOf != nil {
+		s.queue.pushFront(token)
+	} else {
+		s.queue.push(token)
 	}
-	return nil
+	s.lastQueued = token.request()
 }
 
-func (s *Simulator) shouldReject(token *Token) bool {
-	if token.Class != ClassAnon {
-		return false
+func (s *Simulator) higherScheduled(token *Token) int {
+	count := 0
+	for i := 0; i < s.classes.lane(token.Class); i++ {
+		count += s.scheduled[i]
 	}
-	queueLength := len(s.paidQueue) + len(s.freeQueue) + len(s.anonQueue)
-	return queueLength >= s.rejectThreshold
+	return count
 }
 
-func (s *Simulator) updateQueueIndices() {
-	for _, token := range s.tokens {
-		if token.State == StateQueued {
-			token.QueueIndex = -1
+func (s *Simulator) detectStarvation(tick int) {
+	threshold := s.scenario.StarvationThreshold
+	if threshold <= 0 {
+		return
+	}
+	s.queue.each(func(_ int, token *Token) bool {
+		wait := tick - token.queuedAt()
+		if token.warned || token.hedgeOf != nil || wait < threshold {
+			return true
+		}
+		bypassed := s.higherScheduled(token) - token.higherScheduled
+		if bypassed == 0 {
+			return true
 		}
+		token.warned = true
+		context := s.newContext()
+		*context = EventContext{
+			Rule:        RuleStarvation,
+			QueueLength: s.queueLength(),
+			Limit:       threshold,
+			WaitTicks:   wait,
+			Bypassed:    bypassed,
+		}
+		s.emit(Event{
+			Tick:       tick,
+			Type:       EventStarvationWarning,
+			ReasonCode: ReasonStarvationWait,
+			TokenID:    token.ID,
+			StageID:    StageQueue,
+			Class:      token.Class,
+			Context:    context,
+		})
+		return true
+	})
+}
+
+func (s *Simulator) popNextQueued(tick int) *Token {
+	if s.scenario.Affinity != nil {
+		return s.popAffine(tick)
+	}
+	if s.policy != nil {
+		return s.popPolicy(tick)
+	}
+	if s.scriptedSchedule() {
+		return s.popScripted(tick)
+	}
+	return s.queue.pop()
+}
+
+func queueLimitRule(class string) string {
+	if class == ClassAnon {
+		return RuleAnonQueueLimit
 	}
+	return RuleClassQueueLimit
+}
 
-	index := 0
-	for _, token := range s.paidQueue {
-		token.QueueIndex = index
-		index++
+func (s *Simulator) queueLength() int {
+	return s.queue.len()
+}
+
+func (s *Simulator) bypassed(token *Token) int {
+	return s.queue.arrivedBefore(token.queuedAt())
+}
+
+func (s *Simulator) scheduleRule(class string) string {
+	if s.scriptedSchedule() {
+		return s.script.scheduleRule
 	}
-	for _, token := range s.freeQueue {
-		token.QueueIndex = index
-		index++
+	switch class {
+	case ClassPaid:
+		return RulePaidFirst
+	case ClassFree:
+		return RuleFreeBeforeAnon
+	case ClassAnon:
+		return RuleAnonWhenIdle
+	default:
+		return RulePriorityOrder
 	}
-	for _, token := range s.anonQueue {
-		token.QueueIndex = index
-		index++
+}
+
+func (s *Simulator) updateQueueIndices() {
+	if s.eta != nil {
+		s.eta.endTick()
 	}
+	s.queue.each(func(index int, token *Token) bool {
+		token.QueueIndex = index
+		if s.eta != nil {
+			token.EtaMs = s.eta.estimateMs(index, s.classes.lane(token.Class))
+		}
+		return true
+	})
 }
 
 func (s *Simulator) snapshotTokens() []TokenState {
-	states := make([]TokenState, 0, len(s.tokens))
-	for _, token := range s.tokens {
-		states = append(states, TokenState{
-			ID:               token.ID,
-			Class:            token.Class,
-			State:            token.State,
-			StageID:          token.StageID,
-			QueueIndex:       token.QueueIndex,
-			ServiceRemaining: token.ServiceRemaining,
-		})
+	states := s.takeStates(len(s.active))
+	active := s.active[:0]
+	for _, token := range s.active {
+		states = append(states, s.tokenState(token))
+		if !token.terminal() {
+			active = append(active, token)
+		}
+	}
+	clear(s.active[len(active):])
+	s.active = active
+	return states
+}
+
+func (s *Simulator) Queue() []TokenState {
+	states := make([]TokenState, 0, s.queueLength())
+	s.queue.each(func(_ int, token *Token) bool {
+		states = append(states, s.tokenState(token))
+		return true
+	})
+	return states
+}
+
+func (s *Simulator) InService() []TokenState {
+	states := make([]TokenState, 0, len(s.inService))
+	for _, token := range s.inService {
+		states = append(states, s.tokenState(token))
 	}
 	return states
 }
 
+func (s *Simulator) Token(id string) (TokenState, bool) {
+	for _, token := range s.tokens {
+		if token.ID == id {
+			return s.tokenState(token), true
+		}
+	}
+	return TokenState{}, false
+}
+
+func (t *Token) terminal() bool {
+	return t.State == StateDone || t.State == StateRejected || t.State == StateCancelled || t.State == StateFailed
+}
+
+func (t *Token) snapshot() TokenState {
+	return TokenState{
+		ID:               t.ID,
+		Class:            t.Class,
+		State:            t.State,
+		StageID:          t.StageID,
+		QueueIndex:       t.QueueIndex,
+		ServiceRemaining: t.ServiceRemaining,
+		EtaMs:            t.EtaMs,
+		Ticket:           t.Ticket,
+		HedgeOf:          t.hedgeOfID(),
+		RequestKey:       t.RequestKey,
+		Cycle:            t.Cycle,
+		Session:          t.Session,
+		Server:           t.Server,
+	}
+}
+
 func (s *Simulator) snapshotStages() []StageState {
-	queueLength := len(s.paidQueue) + len(s.freeQueue) + len(s.anonQueue)
-	return []StageState{
-		{
-			ID:            StageQueue,
-			QueueLength:   queueLength,
-			CapacityUsed:  0,
-			CapacityTotal: 0,
-		},
-		{
-			ID:            StageService,
-			QueueLength:   0,
-			CapacityUsed:  len(s.inService),
-			CapacityTotal: s.capacity,
-		},
-		{
-			ID:            StageDone,
-			QueueLength:   0,
-			CapacityUsed:  0,
-			CapacityTotal: 0,
-		},
-		{
-			ID:            StageRejected,
-			QueueLength:   0,
-			CapacityUsed:  0,
-			CapacityTotal: 0,
-		},
+	transit := len(s.scenario.Transit) > 0
+	held := s.scenario.Backpressure != nil && s.scenario.Backpressure.holds()
+	failed := s.failureRNG != nil
+	n := 4 + len(s.stages)
+	for _, extra := range []bool{transit, held, failed} {
+		if extra {
+			n++
+		}
 	}
+	stages := s.takeStages(n)[:4]
+	stages[0] = StageState{ID: StageQueue, QueueLength: s.queueLength(), CapacityUsed: s.queueLength(), CapacityTotal: s.classes.queueCapacity(), Quotas: s.quotaStates()}
+	stages[1] = StageState{ID: StageService, CapacityUsed: len(s.inService), CapacityTotal: s.capacity, Warming: len(s.warming), Draining: s.draining, Stalled: len(s.stalled) + s.stalledIdle, Breaker: s.breakerState()}
+	if w := s.scenario.WorkUnits; w != nil {
+		stages[1].UnitsUsed, stages[1].UnitsTotal = s.unitsInUse(), w.Capacity
+	}
+	stages[2] = StageState{ID: StageDone}
+	stages[3] = StageState{ID: StageRejected}
+	if transit {
+		stages = append(stages, StageState{ID: StageTransit, QueueLength: len(s.transit)})
+	}
+	if held {
+		stages = append(stages, StageState{ID: StageArrivals, QueueLength: len(s.held)})
+	}
+	if failed {
+		stages = append(stages, StageState{ID: StageFailed})
+	}
+	if s.classBreakdown {
+		s.breakDownStages(stages)
+	}
+	if len(s.stages) > 0 {
+		stages = append(stages, s.stageStates(s.tick)...)
+	}
+	return stages
+}
+
+func (s *Simulator) arrivalCount(tick int) int {
+	if s.traffic != nil {
+		return s.traffic[tick]
+	}
+	return arrivalCount(tick)
 }
 
 func arrivalCount(tick int) int {
-	switch {
-	case tick < 110:
-		return 1
-	case tick < 150:
-		return 2
-	case tick < 180:
-		return 4
-	case tick < 210:
-		return 3
-	default:
-		return 1
+	for _, phase := range arrivalPhases {
+		if tick < phase.ToTick {
+			return phase.PerTick
+		}
 	}
+	return 0
 }
 
 func (s *Simulator) arrivalClasses(tick int, count int) []string {
-	classes := make([]string, 0, count)
+	classes := s.classBuf[:0]
 	for i := 0; i < count; i++ {
-		classes = append(classes, pickClass(s.rng))
-	}
-	if count >= 2 && tick >= 150 && tick <= 190 {
-		classes[0] = ClassPaid
-		classes[1] = ClassFree
+		classes = append(classes, pickClass(s.rng, s.classes.mix))
 	}
+	classes = s.applySpikes(tick, classes)
+	s.classBuf = classes
 	return classes
 }
 
-func pickClass(rng *rand.Rand) string {
+func pickClass(rng *rand.Rand, mix []ClassShare) string {
 	r := rng.Float64()
-	switch {
-	case r < 0.55:
-		return ClassAnon
-	case r < 0.85:
-		return ClassFree
-	default:
-		return ClassPaid
+	for _, share := range mix {
+		if r < share.Below {
+			return share.Class
+		}
 	}
+	return mix[len(mix)-1].Class
 }
@@ -0,0 +1,1841 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenID(t *testing.T) {
+	for _, n := range []int{0, 7, 42, 999, 1000, 9999, 10000, 123456} {
+		if got, want := tokenID(n), fmt.Sprintf("T%04d", n); got != want {
+			t.Errorf("tokenID(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestSnapshotsDoNotShareBuffers(t *testing.T) {
+	artifact, err := Run(Config{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := artifact.Snapshots[10], artifact.Snapshots[11]
+	want := second.Stages[0]
+	_ = append(first.Stages, StageState{ID: "extra"})
+	_ = append(first.Tokens, TokenState{ID: "extra"})
+	if !reflect.DeepEqual(second.Stages[0], want) {
+		t.Errorf("Stages[0] = %+v after append to previous snapshot, want %+v", second.Stages[0], want)
+	}
+	if len(second.Tokens) > 0 && second.Tokens[0].ID == "extra" {
+		t.Errorf("Tokens[0] overwritten by append to previous snapshot")
+	}
+}
+
+func TestStarvationWarnings(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.StarvationThreshold = 16
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lifecycles := make(map[string]Lifecycle)
+	for _, lifecycle := range Lifecycles(artifact.Events) {
+		lifecycles[lifecycle.TokenID] = lifecycle
+	}
+	warned := make(map[string]bool)
+	for _, event := range artifact.Events {
+		if event.Type != EventStarvationWarning {
+			continue
+		}
+		if warned[event.TokenID] {
+			t.Errorf("token %s warned more than once", event.TokenID)
+		}
+		warned[event.TokenID] = true
+		if event.Class == ClassPaid {
+			t.Errorf("PAID token %s warned; nothing outranks it", event.TokenID)
+		}
+		ctx := event.Context
+		if ctx == nil || ctx.Rule != RuleStarvation || ctx.Limit != 16 || ctx.WaitTicks < 16 || ctx.Bypassed == 0 {
+			t.Errorf("warning for %s context = %+v", event.TokenID, ctx)
+		}
+		if lifecycle := lifecycles[event.TokenID]; lifecycle.Scheduled() && lifecycle.ScheduleTick <= event.Tick {
+			t.Errorf("token %s warned at tick %d after it was scheduled at %d", event.TokenID, event.Tick, lifecycle.ScheduleTick)
+		}
+	}
+	if len(warned) == 0 {
+		t.Fatal("expected starvation warnings at the canonical peak")
+	}
+
+	plain, err := Run(Config{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(artifact.Events)-len(warned), len(plain.Events); got != want {
+		t.Errorf("non-warning events = %d, want %d from the same run without a threshold", got, want)
+	}
+}
+
+func TestSLOAlerts(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.SLOs = []SLO{
+		{Name: "paid", Class: ClassPaid, Target: 0.95, LatencyMs: 500, WindowTicks: 40},
+		{Name: "anon", Class: ClassAnon, Target: 0.9, LatencyMs: 2000, WindowTicks: 20, BurnRate: 2},
+	}
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alerts := make(map[string]int)
+	for _, event := range artifact.Events {
+		if event.Type != EventSLOAlert {
+			continue
+		}
+		ctx := event.Context
+		alerts[ctx.SLO]++
+		if ctx.Rule != RuleSLOBurnRate || ctx.BurnRate < 2 || ctx.Missed == 0 || ctx.Observed < ctx.Missed {
+			t.Errorf("alert at tick %d context = %+v", event.Tick, ctx)
+		}
+		if event.Class != ClassAnon || event.TokenID == "" {
+			t.Errorf("alert at tick %d attributed to %s %q, want an ANON token", event.Tick, event.Class, event.TokenID)
+		}
+	}
+	if alerts["paid"] != 0 {
+		t.Errorf("paid SLO fired %d times; every PAID request finishes in one tick", alerts["paid"])
+	}
+	if alerts["anon"] == 0 {
+		t.Error("anon SLO never fired during the canonical peak")
+	}
+	if err := ValidateArtifact(artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+}
+
+func TestWaitingRoom(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.WaitingRoom = &WaitingRoom{PositionStep: 2}
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tickets := make(map[string]int)
+	last := make(map[string]int)
+	for _, event := range artifact.Events {
+		switch event.Type {
+		case EventQueue:
+			tickets[event.TokenID] = 0
+		case EventPositionUpdate:
+			ctx := event.Context
+			if ctx.Rule != RulePositionStep || ctx.PreviousPosition-ctx.Position <= 2 || ctx.Ticket == 0 {
+				t.Errorf("update at tick %d context = %+v", event.Tick, ctx)
+			}
+			if prev, ok := last[event.TokenID]; ok && ctx.PreviousPosition != prev {
+				t.Errorf("%s: previous_position = %d, want last reported %d", event.TokenID, ctx.PreviousPosition, prev)
+			}
+			last[event.TokenID] = ctx.Position
+		}
+	}
+	if len(last) == 0 {
+		t.Fatal("no POSITION_UPDATE events during the canonical peak")
+	}
+
+	next := make(map[string]int)
+	for _, snapshot := range artifact.Snapshots {
+		for _, token := range snapshot.Tokens {
+			if token.State == StateRejected {
+				if token.Ticket != 0 {
+					t.Errorf("rejected %s has ticket %d", token.ID, token.Ticket)
+				}
+				continue
+			}
+			if seen := tickets[token.ID]; seen != 0 {
+				if token.Ticket != seen {
+					t.Errorf("%s ticket changed from %d to %d", token.ID, seen, token.Ticket)
+				}
+				continue
+			}
+			next[token.Class]++
+			if token.Ticket != next[token.Class] {
+				t.Errorf("%s %s ticket = %d, want %d", token.Class, token.ID, token.Ticket, next[token.Class])
+			}
+			tickets[token.ID] = token.Ticket
+		}
+	}
+	if err := ValidateArtifact(artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+}
+
+func TestDegradation(t *testing.T) {
+	scenario := Scenario{ID: "degrade", Capacity: 3, ServiceTime: 2, RejectThreshold: 12,
+		Degradation: &Degradation{QueueLength: 8, SustainTicks: 4, RecoverTicks: 4, ServiceTime: 1}}
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	degraded := false
+	transitions := 0
+	scheduled := make(map[string]bool)
+	for _, event := range artifact.Events {
+		switch event.Type {
+		case EventDegradeStart, EventDegradeEnd:
+			if (event.Type == EventDegradeStart) == degraded {
+				t.Fatalf("tick %d: %s while degraded = %v", event.Tick, event.Type, degraded)
+			}
+			degraded = !degraded
+			transitions++
+		case EventSchedule:
+			scheduled[event.TokenID] = degraded
+		case EventComplete:
+			want := QualityFull
+			if scheduled[event.TokenID] && event.Class != ClassPaid {
+				want = QualityDegraded
+			}
+			if event.Quality != want {
+				t.Errorf("tick %d: %s %s completed with quality %q, want %q", event.Tick, event.Class, event.TokenID, event.Quality, want)
+			}
+		}
+	}
+	if transitions == 0 {
+		t.Error("service never degraded during the canonical peak")
+	}
+	if err := ValidateArtifact(artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+
+	plain, err := Run(Config{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range plain.Events {
+		if event.Quality != "" {
+			t.Fatalf("quality recorded without degradation: %+v", event)
+		}
+	}
+}
+
+func TestQuotas(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.Quotas = []Quota{{Class: ClassAnon, Limit: 50, WindowTicks: 60}}
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var admitted []int
+	quotaRejects := 0
+	for _, event := range artifact.Events {
+		if event.Class != ClassAnon {
+			continue
+		}
+		switch {
+		case event.Type == EventQueue:
+			admitted = append(admitted, event.Tick)
+		case event.Type == EventReject && event.ReasonCode == ReasonQuotaExceeded:
+			quotaRejects++
+			if event.Context.Rule != RuleClassQuota || event.Context.Limit != 50 || event.Context.WindowTicks != 60 {
+				t.Errorf("quota reject at tick %d context = %+v", event.Tick, event.Context)
+			}
+		}
+	}
+	if quotaRejects == 0 {
+		t.Fatal("ANON quota never rejected a request")
+	}
+	for i := range admitted {
+		if i >= 50 && admitted[i]-admitted[i-50] < 60 {
+			t.Fatalf("51 ANON admissions within 60 ticks ending at tick %d", admitted[i])
+		}
+	}
+
+	for _, snapshot := range artifact.Snapshots {
+		quotas := snapshot.Stages[0].Quotas
+		if len(quotas) != 1 || quotas[0].Class != ClassAnon || quotas[0].Used > quotas[0].Limit {
+			t.Fatalf("tick %d: queue stage quotas = %+v", snapshot.Tick, quotas)
+		}
+	}
+	if err := ValidateArtifact(artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+}
+
+func TestHedging(t *testing.T) {
+	tests := []struct {
+		name     string
+		scenario Scenario
+		seed     int64
+	}{
+		{
+			name:     "late hedges",
+			scenario: Scenario{ID: "hedge", Capacity: 3, ServiceTime: 1, RejectThreshold: 12, Hedging: &Hedging{AfterTicks: 8}},
+			seed:     1,
+		},
+		{
+			name: "degraded service",
+			scenario: Scenario{ID: "hedge", Capacity: 3, ServiceTime: 3, RejectThreshold: 12,
+				Hedging:     &Hedging{AfterTicks: 1},
+				Degradation: &Degradation{QueueLength: 7, SustainTicks: 1, RecoverTicks: 1, ServiceTime: 1}},
+			seed: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			artifact, err := Run(NewConfig(WithScenarioSpec(tt.scenario), WithSeed(tt.seed)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			spawned := make(map[string]string)
+			completed := make(map[string]bool)
+			cancels, hedgeWins := 0, 0
+			for _, event := range artifact.Events {
+				if strings.HasSuffix(event.TokenID, hedgeSuffix) {
+					t.Fatalf("event attributed to hedge copy: %+v", event)
+				}
+				switch event.Type {
+				case EventHedgeSpawn:
+					spawned[event.TokenID] = event.Context.Hedge
+				case EventComplete:
+					if completed[event.TokenID] {
+						t.Errorf("%s completed twice", event.TokenID)
+					}
+					completed[event.TokenID] = true
+					if event.Context != nil && event.Context.Hedge != "" {
+						hedgeWins++
+					}
+				case EventHedgeCancel:
+					cancels++
+					if !completed[event.TokenID] {
+						t.Errorf("tick %d: %s cancelled a copy before completing", event.Tick, event.TokenID)
+					}
+					if c := event.Context.Cancelled; c != event.TokenID && c != spawned[event.TokenID] {
+						t.Errorf("tick %d: %s cancelled unrelated copy %s", event.Tick, event.TokenID, c)
+					}
+				}
+			}
+			if len(spawned) == 0 || cancels == 0 {
+				t.Fatalf("spawned %d hedges and cancelled %d copies, want both", len(spawned), cancels)
+			}
+			if hedgeWins == 0 {
+				t.Error("hedge copies never won, want them served ahead of their originals")
+			}
+			for _, snapshot := range artifact.Snapshots {
+				for _, token := range snapshot.Tokens {
+					if strings.HasSuffix(token.ID, hedgeSuffix) && spawned[token.HedgeOf] != token.ID {
+						t.Fatalf("tick %d: hedge copy %s has hedge_of %q", snapshot.Tick, token.ID, token.HedgeOf)
+					}
+				}
+			}
+			if err := ValidateArtifact(artifact); err != nil {
+				t.Errorf("ValidateArtifact() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestHedging_CopyServedFirst(t *testing.T) {
+	scenario := Scenario{ID: "hedge", Capacity: 1, ServiceTime: 4, RejectThreshold: 12,
+		Hedging:  &Hedging{AfterTicks: 2},
+		Arrivals: []Arrival{{Tick: 0, Class: ClassFree}, {Tick: 0, Class: ClassFree}, {Tick: 1, Class: ClassFree}},
+	}
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, event := range artifact.Events {
+		switch event.Type {
+		case EventHedgeSpawn:
+			got = append(got, fmt.Sprintf("%d spawn %s", event.Tick, event.Context.Hedge))
+		case EventComplete:
+			got = append(got, fmt.Sprintf("%d complete %s hedge=%v", event.Tick, event.TokenID, event.Context != nil))
+		case EventHedgeCancel:
+			got = append(got, fmt.Sprintf("%d cancel %s", event.Tick, event.Context.Cancelled))
+		}
+	}
+	want := []string{
+		"2 spawn T0001h",
+		"3 spawn T0002h",
+		"4 complete T0000 hedge=false",
+		"8 complete T0001 hedge=true",
+		"8 cancel T0001",
+		"12 complete T0002 hedge=true",
+		"12 cancel T0002",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hedge events = %q, want %q", got, want)
+	}
+}
+
+func TestHedging_AgeFromRequeue(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.Rework = &Rework{Probability: 0.5, MaxCycles: 3}
+	scenario.Hedging = &Hedging{AfterTicks: 3}
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	queued := make(map[string]int)
+	reworked := false
+	for _, event := range artifact.Events {
+		switch event.Type {
+		case EventQueue:
+			queued[event.TokenID] = event.Tick
+		case EventRework:
+			queued[event.TokenID] = event.Tick
+			reworked = true
+		case EventHedgeSpawn:
+			if age := event.Tick - queued[event.TokenID]; age < scenario.Hedging.AfterTicks {
+				t.Errorf("tick %d: %s hedged %d tick(s) after entering the queue, want at least %d", event.Tick, event.TokenID, age, scenario.Hedging.AfterTicks)
+			}
+		}
+	}
+	if !reworked {
+		t.Fatal("no token was reworked")
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	duplicates := &Duplicates{Probability: 0.3, DelayTicks: 4, QueueLength: 6}
+	tests := []struct {
+		name  string
+		dedup *Dedup
+	}{
+		{name: "no dedup"},
+		{name: "drop", dedup: &Dedup{Mode: DedupDrop}},
+		{name: "coalesce", dedup: &Dedup{Mode: DedupCoalesce}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := CanonicalScenario()
+			scenario.Duplicates = duplicates
+			scenario.Dedup = tt.dedup
+			artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			completed := make(map[string]int)
+			droppedAt := make(map[string]int)
+			dupes, dropped, queued := 0, 0, 0
+			for _, event := range artifact.Events {
+				if event.Type == EventComplete {
+					completed[event.TokenID] = event.Tick
+				}
+				if !strings.HasSuffix(event.TokenID, duplicateSuffix) {
+					continue
+				}
+				switch event.Type {
+				case EventQueue:
+					queued++
+					if event.Context.RequestKey+duplicateSuffix != event.TokenID {
+						t.Errorf("%s queued with request_key %q", event.TokenID, event.Context.RequestKey)
+					}
+				case EventDuplicateDrop:
+					dropped++
+					droppedAt[event.TokenID] = event.Tick
+				}
+				if event.Type == EventQueue || event.Type == EventDuplicateDrop {
+					dupes++
+				}
+			}
+			if dupes == 0 {
+				t.Fatal("no duplicate submissions were injected")
+			}
+			if tt.dedup == nil && (queued != dupes || dropped != 0) {
+				t.Errorf("without dedup: %d queued, %d dropped of %d duplicates", queued, dropped, dupes)
+			}
+			if tt.dedup != nil && (dropped != dupes || queued != 0) {
+				t.Errorf("with dedup %s: %d queued, %d dropped of %d duplicates", tt.dedup.Mode, queued, dropped, dupes)
+			}
+			if tt.dedup != nil && tt.dedup.Mode == DedupCoalesce {
+				for id, tick := range completed {
+					key, ok := strings.CutSuffix(id, duplicateSuffix)
+					if want := max(completed[key], droppedAt[id]); ok && tick != want {
+						t.Errorf("%s completed at tick %d, want %d", id, tick, want)
+					}
+				}
+			}
+			if err := ValidateArtifact(artifact); err != nil {
+				t.Errorf("ValidateArtifact() error = %v", err)
+			}
+		})
+	}
+
+	base, err := Run(Config{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scenario := CanonicalScenario()
+	scenario.Duplicates = duplicates
+	scenario.Dedup = &Dedup{Mode: DedupDrop}
+	deduped, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Event
+	for _, event := range deduped.Events {
+		if !strings.HasSuffix(event.TokenID, duplicateSuffix) {
+			event.Context = nil
+			got = append(got, event)
+		}
+	}
+	for i := range base.Events {
+		base.Events[i].Context = nil
+	}
+	if !reflect.DeepEqual(got, base.Events) {
+		t.Error("dropping duplicates changed how the original requests were served")
+	}
+}
+
+func TestTransit(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.Transit = []Transit{
+		{From: StageQueue, To: StageService, Ticks: 2},
+		{From: StageService, To: StageDone, Ticks: 1},
+	}
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := 2 + scenario.ServiceTime + 1
+	completed := 0
+	for _, lifecycle := range Lifecycles(artifact.Events) {
+		if !lifecycle.Completed() {
+			continue
+		}
+		completed++
+		if got := lifecycle.CompleteTick - lifecycle.ScheduleTick; got != want {
+			t.Fatalf("%s took %d ticks from dispatch to completion, want %d", lifecycle.TokenID, got, want)
+		}
+	}
+	if completed == 0 {
+		t.Fatal("no request completed")
+	}
+
+	inTransit := false
+	for _, snapshot := range artifact.Snapshots {
+		stages := snapshot.Stages
+		if len(stages) != 5 || stages[4].ID != StageTransit {
+			t.Fatalf("tick %d: stages = %+v, want a transit stage last", snapshot.Tick, stages)
+		}
+		transit := 0
+		for _, token := range snapshot.Tokens {
+			if token.State == StateTransit {
+				transit++
+				if token.StageID != StageTransit {
+					t.Errorf("tick %d: %s in transit at stage %s", snapshot.Tick, token.ID, token.StageID)
+				}
+			}
+		}
+		if transit != stages[4].QueueLength {
+			t.Errorf("tick %d: %d tokens in transit, stage reports %d", snapshot.Tick, transit, stages[4].QueueLength)
+		}
+		inTransit = inTransit || transit > 0
+	}
+	if !inTransit {
+		t.Error("no token was ever in transit")
+	}
+	if err := ValidateArtifact(artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+}
+
+func TestScaling(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.ServiceTime = 4
+	scenario.Scaling = &Scaling{WarmupTicks: 5, Steps: []ScaleStep{{Tick: 10, Capacity: 6}, {Tick: 40, Capacity: 1}}}
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	servers := make(map[string]int)
+	for _, event := range artifact.Events {
+		if !ServerEvent(event.Type) {
+			continue
+		}
+		if event.TokenID != "" {
+			t.Errorf("%s at tick %d belongs to %s, want no token", event.Type, event.Tick, event.TokenID)
+		}
+		servers[event.Type] += event.Context.Servers
+		switch event.Type {
+		case EventWarmupStart, EventDrainStart:
+			if event.Tick != 10 && event.Tick != 40 {
+				t.Errorf("%s at tick %d, want a step tick", event.Type, event.Tick)
+			}
+		case EventWarmupEnd:
+			if event.Tick != 15 {
+				t.Errorf("%s at tick %d, want 15", event.Type, event.Tick)
+			}
+		}
+	}
+	want := map[string]int{EventWarmupStart: 3, EventWarmupEnd: 3, EventDrainStart: 5, EventDrainEnd: 5}
+	if !reflect.DeepEqual(servers, want) {
+		t.Errorf("servers by event = %v, want %v", servers, want)
+	}
+
+	for _, snapshot := range artifact.Snapshots {
+		service := snapshot.Stages[1]
+		if service.CapacityUsed > service.CapacityTotal {
+			t.Errorf("tick %d: %d in service with %d servers", snapshot.Tick, service.CapacityUsed, service.CapacityTotal)
+		}
+		switch snapshot.Tick {
+		case 12:
+			if service.CapacityTotal != 3 || service.Warming != 3 {
+				t.Errorf("tick 12: service = %+v, want 3 servers and 3 warming", service)
+			}
+		case 20:
+			if service.CapacityTotal != 6 {
+				t.Errorf("tick 20: service = %+v, want 6 servers", service)
+			}
+		case 60:
+			if service.CapacityTotal != 1 || service.Draining != 0 {
+				t.Errorf("tick 60: service = %+v, want 1 server", service)
+			}
+		}
+	}
+	if err := ValidateArtifact(artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+}
+
+func TestStalls(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.ServiceTime = 4
+	scenario.Stalls = []Stall{{Tick: 20, Ticks: 6, Slots: 1}, {Tick: 40, Ticks: 5}}
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type window struct{ start, end, servers int }
+	var stalls []window
+	for _, event := range artifact.Events {
+		switch event.Type {
+		case EventStallStart:
+			stalls = append(stalls, window{start: event.Tick, servers: event.Context.Servers})
+		case EventStallEnd:
+			stalls[len(stalls)-1].end = event.Tick
+		case EventComplete:
+			if event.Tick >= 40 && event.Tick < 45 {
+				t.Errorf("%s completed at tick %d while every slot was stalled", event.TokenID, event.Tick)
+			}
+		}
+	}
+	want := []window{{20, 26, 1}, {40, 45, scenario.Capacity}}
+	if !reflect.DeepEqual(stalls, want) {
+		t.Fatalf("stalls = %v, want %v", stalls, want)
+	}
+
+	remaining := make(map[string]int)
+	for _, snapshot := range artifact.Snapshots {
+		stalled := snapshot.Stages[1].Stalled
+		if inStall := snapshot.Tick >= 40 && snapshot.Tick < 45; inStall != (stalled == scenario.Capacity) {
+			t.Errorf("tick %d: %d slots stalled", snapshot.Tick, stalled)
+		}
+		for _, token := range snapshot.Tokens {
+			if token.State != StateProcessing {
+				continue
+			}
+			if prev, ok := remaining[token.ID]; ok && snapshot.Tick > 40 && snapshot.Tick < 45 && token.ServiceRemaining != prev {
+				t.Errorf("tick %d: %s progressed from %d to %d during a stall", snapshot.Tick, token.ID, prev, token.ServiceRemaining)
+			}
+			remaining[token.ID] = token.ServiceRemaining
+		}
+	}
+	if err := ValidateArtifact(artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+}
+
+func TestTraffic(t *testing.T) {
+	tests := []struct {
+		name    string
+		traffic Traffic
+	}{
+		{name: "on/off sources", traffic: Traffic{Model: TrafficOnOff, Sources: 3, Rate: 0.7, Shape: 1.3, MinTicks: 5}},
+		{name: "b-model", traffic: Traffic{Model: TrafficBModel, Bias: 0.7, Total: 500}},
+		{name: "diurnal", traffic: Traffic{Model: TrafficDiurnal, Peak: 3, Trough: 0.2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := CanonicalScenario()
+			scenario.Traffic = &tt.traffic
+			cfg := NewConfig(WithScenarioSpec(scenario), WithSeed(1))
+			artifact, err := Run(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, err := Run(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(artifact, again) {
+				t.Fatal("same seed produced different traffic")
+			}
+
+			windows := make([]float64, TickCount/10)
+			lifecycles := Lifecycles(artifact.Events)
+			for _, lifecycle := range lifecycles {
+				windows[lifecycle.ArrivalTick/10]++
+			}
+			if tt.traffic.Model == TrafficBModel && len(lifecycles) != tt.traffic.Total {
+				t.Errorf("arrivals = %d, want %d", len(lifecycles), tt.traffic.Total)
+			}
+			if tt.traffic.Model == TrafficDiurnal {
+				night := windows[0] + windows[1] + windows[len(windows)-2] + windows[len(windows)-1]
+				day := windows[len(windows)/2-2] + windows[len(windows)/2-1] + windows[len(windows)/2] + windows[len(windows)/2+1]
+				if day <= 4*night {
+					t.Errorf("midday arrivals = %v, night arrivals = %v, want a day/night cycle", day, night)
+				}
+			}
+			mean, variance := 0.0, 0.0
+			for _, n := range windows {
+				mean += n / float64(len(windows))
+			}
+			for _, n := range windows {
+				variance += (n - mean) * (n - mean) / float64(len(windows))
+			}
+			if dispersion := variance / mean; dispersion < 1.5 {
+				t.Errorf("index of dispersion = %.2f, want bursty traffic above 1.5", dispersion)
+			}
+			if err := ValidateArtifact(artifact); err != nil {
+				t.Errorf("ValidateArtifact() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestDiurnalRate(t *testing.T) {
+	profile := make([]float64, 24)
+	profile[6], profile[7] = 2, 4
+	tests := []struct {
+		name    string
+		traffic Traffic
+		tick    int
+		want    float64
+	}{
+		{name: "sine midnight", traffic: Traffic{Peak: 3, Trough: 1}, tick: 0, want: 1},
+		{name: "sine noon", traffic: Traffic{Peak: 3, Trough: 1}, tick: TickCount / 2, want: 3},
+		{name: "sine second day noon", traffic: Traffic{Peak: 3, Trough: 1, Days: 2}, tick: TickCount / 4, want: 3},
+		{name: "profile hour", traffic: Traffic{Profile: profile}, tick: TickCount * 6 / 24, want: 2},
+		{name: "profile between hours", traffic: Traffic{Profile: profile}, tick: TickCount * 13 / 48, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.traffic.diurnalRate(tt.tick); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("diurnalRate(%d) = %v, want %v", tt.tick, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpikes(t *testing.T) {
+	anonArrivals := func(scenario Scenario) int {
+		artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateArtifact(artifact); err != nil {
+			t.Errorf("ValidateArtifact() error = %v", err)
+		}
+		count := 0
+		for _, lifecycle := range Lifecycles(artifact.Events) {
+			if lifecycle.Class == ClassAnon && lifecycle.ArrivalTick >= 50 && lifecycle.ArrivalTick < 70 {
+				count++
+			}
+		}
+		return count
+	}
+
+	base := CanonicalScenario()
+	spiked := CanonicalScenario()
+	spiked.Spikes = append(spiked.Spikes, Spike{At: 50, Ticks: 20, Class: ClassAnon, Factor: 10})
+	want := 10 * anonArrivals(base)
+	if got := anonArrivals(spiked); got != want || want == 0 {
+		t.Errorf("ANON arrivals during the spike = %d, want %d", got, want)
+	}
+
+	plan, err := NewPlan(NewConfig(WithScenarioSpec(spiked)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPins := []ClassPin{{FromTick: 150, ToTick: 190, Classes: []string{ClassPaid, ClassFree}}}
+	if !reflect.DeepEqual(plan.ClassPins, wantPins) {
+		t.Errorf("ClassPins = %+v, want %+v", plan.ClassPins, wantPins)
+	}
+}
+
+func TestClasses(t *testing.T) {
+	limit := 4
+	scenario := CanonicalScenario()
+	scenario.ServiceTime = 2
+	scenario.Spikes = nil
+	scenario.Classes = []ClassDef{
+		{Name: "BULK", Share: 0.3, Priority: 2, QueueLimit: &limit},
+		{Name: ClassFree, Share: 0.5, Priority: 1},
+		{Name: "ENTERPRISE", Share: 0.2, Priority: 0, ServiceMultiplier: 1.5, Color: "#7a3b69"},
+	}
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := artifact.Metadata.ClassNames(), []string{"ENTERPRISE", ClassFree, "BULK"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ClassNames() = %v, want %v", got, want)
+	}
+	if got := artifact.Metadata.ClassColor("ENTERPRISE"); got != "#7a3b69" {
+		t.Errorf("ClassColor(ENTERPRISE) = %q", got)
+	}
+
+	arrivals := make(map[string]int)
+	for _, lifecycle := range Lifecycles(artifact.Events) {
+		arrivals[lifecycle.Class]++
+		if !lifecycle.Scheduled() || !lifecycle.Completed() {
+			continue
+		}
+		want := 2
+		if lifecycle.Class == "ENTERPRISE" {
+			want = 3
+		}
+		if got := lifecycle.CompleteTick - lifecycle.ScheduleTick; got != want {
+			t.Errorf("%s (%s) served in %d ticks, want %d", lifecycle.TokenID, lifecycle.Class, got, want)
+		}
+	}
+	if len(arrivals) != 3 || arrivals[ClassAnon]+arrivals[ClassPaid] > 0 {
+		t.Errorf("arrivals by class = %v, want only the configured classes", arrivals)
+	}
+
+	rejected := 0
+	for _, event := range artifact.Events {
+		switch event.Type {
+		case EventReject:
+			rejected++
+			if event.Class != "BULK" || event.Context.Rule != RuleClassQueueLimit || event.Context.Limit != limit {
+				t.Errorf("unexpected reject %+v %+v", event, event.Context)
+			}
+		case EventSchedule:
+			if event.Class != ClassFree && event.Context.Rule != RulePriorityOrder {
+				t.Errorf("%s scheduled by rule %s, want %s", event.Class, event.Context.Rule, RulePriorityOrder)
+			}
+		}
+	}
+	if rejected == 0 {
+		t.Error("no BULK request hit its queue limit")
+	}
+	for _, snapshot := range artifact.Snapshots {
+		queue := snapshot.Stages[0]
+		if queue.ID != StageQueue || queue.CapacityUsed != queue.QueueLength || queue.CapacityTotal != 0 {
+			t.Fatalf("tick %d queue stage = %+v, want capacity %d of unbounded", snapshot.Tick, queue, queue.QueueLength)
+		}
+	}
+	if err := ValidateArtifact(artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+}
+
+func TestQueueCapacity(t *testing.T) {
+	limits := []int{3, 5, 4}
+	scenario := CanonicalScenario()
+	scenario.Capacity = 1
+	scenario.Classes = []ClassDef{
+		{Name: ClassAnon, Share: 0.55, Priority: 2, QueueLimit: &limits[0]},
+		{Name: ClassFree, Share: 0.3, Priority: 1, QueueLimit: &limits[1]},
+		{Name: ClassPaid, Share: 0.15, Priority: 0, QueueLimit: &limits[2]},
+	}
+	tests := []struct {
+		name     string
+		scenario Scenario
+		want     int
+	}{
+		{name: "canonical", scenario: CanonicalScenario(), want: 0},
+		{name: "every class limited", scenario: scenario, want: 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			artifact, err := Run(NewConfig(WithScenarioSpec(tt.scenario), WithSeed(1)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			full := false
+			for _, snapshot := range artifact.Snapshots {
+				queue := snapshot.Stages[0]
+				if queue.CapacityTotal != tt.want {
+					t.Fatalf("tick %d queue capacity_total = %d, want %d", snapshot.Tick, queue.CapacityTotal, tt.want)
+				}
+				if tt.want > 0 && queue.CapacityUsed > queue.CapacityTotal {
+					t.Errorf("tick %d queue capacity_used = %d, over capacity_total %d", snapshot.Tick, queue.CapacityUsed, queue.CapacityTotal)
+				}
+				full = full || queue.CapacityUsed >= 4
+			}
+			if !full {
+				t.Error("queue never held 4 requests")
+			}
+		})
+	}
+}
+
+func TestServiceDistributions(t *testing.T) {
+	tests := []struct {
+		name       string
+		service    ServiceDist
+		multiplier float64
+		min, max   int
+		mean       float64
+	}{
+		{name: "uniform", service: ServiceDist{Distribution: ServiceUniform, Min: 2, Max: 5}, min: 2, max: 5, mean: 3.5},
+		{name: "uniform with multiplier", service: ServiceDist{Distribution: ServiceUniform, Min: 2, Max: 2}, multiplier: 2, min: 4, max: 4, mean: 4},
+		{name: "exponential", service: ServiceDist{Distribution: ServiceExponential, Mean: 3}, min: 1, max: TickCount, mean: 3},
+		{name: "lognormal", service: ServiceDist{Distribution: ServiceLognormal, Mean: 3, Sigma: 0.5}, min: 1, max: TickCount, mean: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := CanonicalScenario()
+			scenario.Capacity = 8
+			scenario.Classes = defaultClasses(scenario.RejectThreshold)
+			scenario.Classes[2].Service = &tt.service
+			scenario.Classes[2].ServiceMultiplier = tt.multiplier
+			artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var durations []int
+			total := 0
+			for _, lifecycle := range Lifecycles(artifact.Events) {
+				if !lifecycle.Completed() {
+					continue
+				}
+				ticks := lifecycle.CompleteTick - lifecycle.ScheduleTick
+				if lifecycle.Class != ClassPaid {
+					if ticks != scenario.ServiceTime {
+						t.Fatalf("%s (%s) served in %d ticks, want %d", lifecycle.TokenID, lifecycle.Class, ticks, scenario.ServiceTime)
+					}
+					continue
+				}
+				if ticks < tt.min || ticks > tt.max {
+					t.Errorf("%s served in %d ticks, want [%d, %d]", lifecycle.TokenID, ticks, tt.min, tt.max)
+				}
+				durations = append(durations, ticks)
+				total += ticks
+			}
+			if len(durations) < 20 {
+				t.Fatalf("only %d PAID requests completed", len(durations))
+			}
+			if mean := float64(total) / float64(len(durations)); math.Abs(mean-tt.mean) > 0.35*tt.mean {
+				t.Errorf("mean service = %.2f ticks, want about %v", mean, tt.mean)
+			}
+		})
+	}
+}
+
+func TestDisciplines(t *testing.T) {
+	run := func(t *testing.T, scenario Scenario, reason string) []Lifecycle {
+		artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, event := range artifact.Events {
+			if event.Type == EventSchedule && event.ReasonCode != reason {
+				t.Fatalf("SCHEDULE reason = %s, want %s", event.ReasonCode, reason)
+			}
+		}
+		if err := ValidateArtifact(artifact); err != nil {
+			t.Errorf("ValidateArtifact() error = %v", err)
+		}
+		return Lifecycles(artifact.Events)
+	}
+	// waitingAt reports whether b was queued when a was scheduled.
+	waitingAt := func(a, b Lifecycle) bool {
+		return a.Class == b.Class && a.Scheduled() && b.ArrivalTick <= a.ScheduleTick && b.ScheduleTick > a.ScheduleTick && !b.Rejected()
+	}
+
+	t.Run("lifo", func(t *testing.T) {
+		scenario := CanonicalScenario()
+		scenario.ServiceTime = 2
+		scenario.Discipline = &Discipline{Queue: DisciplineLIFO}
+		lifecycles := run(t, scenario, ReasonLIFOSchedule)
+		overtaken := 0
+		for i, a := range lifecycles {
+			for _, b := range lifecycles[i+1:] {
+				if waitingAt(a, b) {
+					t.Fatalf("%s was scheduled at tick %d while newer %s waited", a.TokenID, a.ScheduleTick, b.TokenID)
+				}
+				if waitingAt(b, a) {
+					overtaken++
+				}
+			}
+		}
+		if overtaken == 0 {
+			t.Error("no newer request overtook an older one")
+		}
+	})
+
+	t.Run("sjf", func(t *testing.T) {
+		scenario := CanonicalScenario()
+		scenario.Classes = defaultClasses(scenario.RejectThreshold)
+		for i := range scenario.Classes {
+			scenario.Classes[i].Service = &ServiceDist{Distribution: ServiceUniform, Min: 1, Max: 6}
+		}
+		scenario.Discipline = &Discipline{Queue: DisciplineSJF}
+		lifecycles := run(t, scenario, ReasonSJFSchedule)
+		size := func(l Lifecycle) int { return l.CompleteTick - l.ScheduleTick }
+		compared := 0
+		for _, a := range lifecycles {
+			for _, b := range lifecycles {
+				if !waitingAt(a, b) || !a.Completed() || !b.Completed() {
+					continue
+				}
+				compared++
+				if size(b) < size(a) {
+					t.Fatalf("%s (size %d) was scheduled at tick %d before the smaller %s (size %d)", a.TokenID, size(a), a.ScheduleTick, b.TokenID, size(b))
+				}
+			}
+		}
+		if compared == 0 {
+			t.Error("no two requests of a class ever waited together")
+		}
+	})
+
+	t.Run("processor sharing", func(t *testing.T) {
+		scenario := CanonicalScenario()
+		scenario.Capacity = 1
+		scenario.ServiceTime = 2
+		scenario.Discipline = &Discipline{Service: ServicePS}
+		slowed := 0
+		for _, l := range run(t, scenario, ReasonPSSchedule) {
+			if l.Rejected() {
+				continue
+			}
+			if l.WaitTicks() != 0 {
+				t.Fatalf("%s waited %d ticks, want immediate service", l.TokenID, l.WaitTicks())
+			}
+			if l.Completed() && l.CompleteTick-l.ScheduleTick > scenario.ServiceTime {
+				slowed++
+			}
+		}
+		if slowed == 0 {
+			t.Error("sharing one server never slowed a request down")
+		}
+	})
+}
+
+func TestBackpressure(t *testing.T) {
+	run := func(t *testing.T, bp *Backpressure) Artifact {
+		scenario := CanonicalScenario()
+		scenario.Capacity = 1
+		scenario.RejectThreshold = 1000
+		scenario.Backpressure = bp
+		artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateArtifact(artifact); err != nil {
+			t.Errorf("ValidateArtifact() error = %v", err)
+		}
+		return artifact
+	}
+	longestQueue := func(artifact Artifact) int {
+		longest := 0
+		for _, snapshot := range artifact.Snapshots {
+			longest = max(longest, snapshot.Stages[0].QueueLength)
+		}
+		return longest
+	}
+
+	unbounded := longestQueue(run(t, nil))
+	for _, policy := range []string{BackpressureHold, BackpressureShed} {
+		t.Run(policy, func(t *testing.T) {
+			bp := &Backpressure{QueueLength: 8, ReleaseLength: 4, Policy: policy}
+			artifact := run(t, bp)
+			if got := longestQueue(artifact); got > bp.QueueLength || got >= unbounded {
+				t.Errorf("longest queue = %d, want at most %d (unbounded %d)", got, bp.QueueLength, unbounded)
+			}
+
+			on := false
+			toggles, held, shed := 0, 0, 0
+			for _, event := range artifact.Events {
+				switch event.Type {
+				case EventBackpressureOn, EventBackpressureOff:
+					if (event.Type == EventBackpressureOn) == on {
+						t.Fatalf("tick %d: %s while backpressure on = %v", event.Tick, event.Type, on)
+					}
+					on = !on
+					toggles++
+				case EventHold:
+					held++
+				case EventReject:
+					if event.ReasonCode == ReasonBackpressureShed {
+						shed++
+					}
+				}
+			}
+			if toggles < 2 {
+				t.Errorf("backpressure toggled %d times, want it to engage and release", toggles)
+			}
+			if policy == BackpressureHold && (held == 0 || shed != 0) {
+				t.Errorf("hold policy held %d and shed %d requests", held, shed)
+			}
+			if policy == BackpressureShed && (shed == 0 || held != 0) {
+				t.Errorf("shed policy held %d and shed %d requests", held, shed)
+			}
+
+			if policy != BackpressureHold {
+				return
+			}
+			heldAt := make(map[string]int)
+			released := 0
+			for _, event := range artifact.Events {
+				switch event.Type {
+				case EventHold:
+					heldAt[event.TokenID] = event.Tick
+				case EventQueue:
+					if tick, ok := heldAt[event.TokenID]; ok && event.Tick > tick {
+						released++
+					}
+				}
+			}
+			if released == 0 {
+				t.Error("no held request was released into the queue")
+			}
+			last := artifact.Snapshots[len(artifact.Snapshots)-1]
+			if stage := last.Stages[len(last.Stages)-1]; stage.ID != StageArrivals {
+				t.Errorf("last stage = %s, want %s", stage.ID, StageArrivals)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.Capacity = 1
+	scenario.CircuitBreaker = &CircuitBreaker{FailureRate: 0.5, LatencyMs: 2000, WindowTicks: 20, MinRequests: 5, OpenTicks: 10, Probes: 2}
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateArtifact(artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+
+	next := map[string]map[string]string{
+		BreakerClosed:   {EventBreakerOpen: BreakerOpen},
+		BreakerOpen:     {EventBreakerHalfOpen: BreakerHalfOpen},
+		BreakerHalfOpen: {EventBreakerOpen: BreakerOpen, EventBreakerClose: BreakerClosed},
+	}
+	state := BreakerClosed
+	states := make([]string, TickCount)
+	seen := make(map[string]bool)
+	fastRejected := 0
+	for _, event := range artifact.Events {
+		switch event.Type {
+		case EventBreakerOpen, EventBreakerHalfOpen, EventBreakerClose:
+			to, ok := next[state][event.Type]
+			if !ok {
+				t.Fatalf("tick %d: %s while %s", event.Tick, event.Type, state)
+			}
+			state = to
+			seen[event.Type] = true
+		case EventQueue:
+			if state == BreakerOpen {
+				t.Errorf("tick %d: %s admitted while the breaker was open", event.Tick, event.TokenID)
+			}
+		case EventReject:
+			if event.ReasonCode != ReasonCircuitOpen {
+				break
+			}
+			fastRejected++
+			if state == BreakerClosed {
+				t.Errorf("tick %d: %s fast-rejected while the breaker was closed", event.Tick, event.TokenID)
+			}
+		}
+		states[event.Tick] = state
+	}
+	for _, eventType := range []string{EventBreakerOpen, EventBreakerHalfOpen, EventBreakerClose} {
+		if !seen[eventType] {
+			t.Errorf("no %s event", eventType)
+		}
+	}
+	if fastRejected == 0 {
+		t.Error("no request was fast-rejected")
+	}
+
+	state = BreakerClosed
+	for _, snapshot := range artifact.Snapshots {
+		if states[snapshot.Tick] != "" {
+			state = states[snapshot.Tick]
+		}
+		if got := snapshot.Stages[1].Breaker; got != state {
+			t.Errorf("tick %d: service breaker = %q, want %q", snapshot.Tick, got, state)
+		}
+	}
+}
+
+func TestFailures(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.Classes = defaultClasses(scenario.RejectThreshold)
+	scenario.Classes[0].ErrorRate = 0.1
+	scenario.Classes[1].ErrorRate = 0.2
+	scenario.Retry = &Retry{Attempts: 2, DelayTicks: 3}
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateArtifact(artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+	baseline, err := Run(Config{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := make(map[string]int)
+	for _, event := range artifact.Events {
+		if event.Type != EventFail {
+			continue
+		}
+		failed[event.TokenID] = event.Tick
+		if event.Class == ClassPaid {
+			t.Errorf("%s failed but PAID has no error rate", event.TokenID)
+		}
+	}
+	if len(failed) == 0 {
+		t.Fatal("no request failed")
+	}
+
+	arrivals := func(artifact Artifact) map[string]string {
+		classes := make(map[string]string)
+		for _, l := range Lifecycles(artifact.Events) {
+			if !strings.Contains(l.TokenID, retrySuffix) {
+				classes[l.TokenID] = l.Class
+			}
+		}
+		return classes
+	}
+	if !reflect.DeepEqual(arrivals(artifact), arrivals(baseline)) {
+		t.Error("failures changed the arrival stream")
+	}
+
+	retried := 0
+	for _, l := range Lifecycles(artifact.Events) {
+		if l.Failed() && (l.Completed() || l.Rejected()) {
+			t.Errorf("%s failed and also completed or was rejected", l.TokenID)
+		}
+		root, attempt, ok := strings.Cut(l.TokenID, retrySuffix)
+		if !ok {
+			continue
+		}
+		retried++
+		n, _ := strconv.Atoi(attempt)
+		if n > scenario.Retry.Attempts {
+			t.Errorf("%s exceeds %d attempts", l.TokenID, scenario.Retry.Attempts)
+		}
+		previous := root
+		if n > 1 {
+			previous = root + retrySuffix + strconv.Itoa(n-1)
+		}
+		if tick, ok := failed[previous]; !ok || l.ArrivalTick != tick+scenario.Retry.DelayTicks {
+			t.Errorf("%s arrived at tick %d, want %d ticks after %s failed (at %d, failed %v)", l.TokenID, l.ArrivalTick, scenario.Retry.DelayTicks, previous, tick, ok)
+		}
+	}
+	if retried == 0 {
+		t.Error("no failed request was retried")
+	}
+
+	last := artifact.Snapshots[len(artifact.Snapshots)-1]
+	if stage := last.Stages[len(last.Stages)-1]; stage.ID != StageFailed {
+		t.Errorf("last stage = %s, want %s", stage.ID, StageFailed)
+	}
+}
+
+func TestRework(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.Rework = &Rework{Probability: 0.3, MaxCycles: 2}
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateArtifact(artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+
+	cycles := make(map[string]int)
+	schedules := make(map[string]int)
+	completed := make(map[string]bool)
+	for _, event := range artifact.Events {
+		switch event.Type {
+		case EventRework:
+			cycles[event.TokenID]++
+			if got := event.Context.Cycle; got != cycles[event.TokenID] || got > scenario.Rework.MaxCycles {
+				t.Errorf("tick %d: %s rework cycle = %d, want %d (max %d)", event.Tick, event.TokenID, got, cycles[event.TokenID], scenario.Rework.MaxCycles)
+			}
+			if schedules[event.TokenID] != cycles[event.TokenID] {
+				t.Errorf("tick %d: %s reworked after %d passes through service", event.Tick, event.TokenID, schedules[event.TokenID])
+			}
+		case EventSchedule:
+			schedules[event.TokenID]++
+		case EventComplete:
+			completed[event.TokenID] = true
+		}
+	}
+	if len(cycles) == 0 {
+		t.Fatal("no request was reworked")
+	}
+	for id, n := range cycles {
+		if completed[id] && schedules[id] != n+1 {
+			t.Errorf("%s completed after %d passes, want %d", id, schedules[id], n+1)
+		}
+	}
+
+	seen := make(map[string]int)
+	for _, snapshot := range artifact.Snapshots {
+		for _, token := range snapshot.Tokens {
+			if token.Cycle < seen[token.ID] || token.Cycle > cycles[token.ID] {
+				t.Errorf("tick %d: %s cycle = %d after %d, want at most %d", snapshot.Tick, token.ID, token.Cycle, seen[token.ID], cycles[token.ID])
+			}
+			seen[token.ID] = token.Cycle
+		}
+	}
+}
+
+func TestWorkUnits(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.Capacity = 10
+	scenario.WorkUnits = &WorkUnits{Capacity: 8}
+	scenario.Classes = defaultClasses(scenario.RejectThreshold)
+	scenario.Classes[2].Service = &ServiceDist{Distribution: ServiceUniform, Min: 4, Max: 8}
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateArtifact(artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+
+	units := make([]int, TickCount)
+	for _, l := range Lifecycles(artifact.Events) {
+		if !l.Completed() {
+			continue
+		}
+		for tick := l.ScheduleTick; tick < l.CompleteTick; tick++ {
+			units[tick] += min(l.CompleteTick-l.ScheduleTick, scenario.WorkUnits.Capacity)
+		}
+	}
+	saturated := false
+	for _, snapshot := range artifact.Snapshots {
+		service := snapshot.Stages[1]
+		if service.UnitsTotal != scenario.WorkUnits.Capacity || service.UnitsUsed > service.UnitsTotal {
+			t.Fatalf("tick %d: units %d/%d, want at most %d", snapshot.Tick, service.UnitsUsed, service.UnitsTotal, scenario.WorkUnits.Capacity)
+		}
+		if units[snapshot.Tick] > service.UnitsUsed {
+			t.Errorf("tick %d: completed requests held %d units, snapshot reports %d", snapshot.Tick, units[snapshot.Tick], service.UnitsUsed)
+		}
+		if got, want := service.Utilization(), float64(service.UnitsUsed)/float64(service.UnitsTotal); got != want {
+			t.Errorf("tick %d: Utilization() = %v, want %v", snapshot.Tick, got, want)
+		}
+		if service.CapacityUsed < service.CapacityTotal && snapshot.Stages[0].QueueLength > 0 {
+			saturated = true
+		}
+	}
+	if !saturated {
+		t.Error("work units never held requests back while server slots were free")
+	}
+}
+
+func TestAffinity(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.Affinity = &Affinity{Sessions: 6, Servers: []string{"a", "b", "c"}}
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateArtifact(artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+
+	sessions := make(map[string]string)
+	for _, snapshot := range artifact.Snapshots {
+		for _, token := range snapshot.Tokens {
+			sessions[token.ID] = token.Session
+		}
+	}
+	type interval struct{ from, to int }
+	busy := make(map[string][]interval)
+	servers := make(map[string]string)
+	scheduled := make(map[string]Event)
+	waited := make(map[string]Event)
+	for _, event := range artifact.Events {
+		switch event.Type {
+		case EventAffinityWait:
+			waited[event.TokenID] = event
+		case EventSchedule:
+			server := event.Context.Server
+			if !slices.Contains(scenario.Affinity.Servers, server) {
+				t.Fatalf("%s scheduled on unknown server %q", event.TokenID, server)
+			}
+			session := sessions[event.TokenID]
+			if session == "" {
+				t.Fatalf("%s has no session", event.TokenID)
+			}
+			if bound, ok := servers[session]; ok && bound != server {
+				t.Errorf("%s of %s served by %s, want %s", event.TokenID, session, server, bound)
+			}
+			servers[session] = server
+			scheduled[event.TokenID] = event
+		case EventComplete:
+			schedule := scheduled[event.TokenID]
+			busy[schedule.Context.Server] = append(busy[schedule.Context.Server], interval{schedule.Tick, event.Tick})
+		}
+	}
+	for server, intervals := range busy {
+		slices.SortFunc(intervals, func(a, b interval) int { return a.from - b.from })
+		for i := 1; i < len(intervals); i++ {
+			if intervals[i].from < intervals[i-1].to {
+				t.Errorf("server %s served two requests at once: %v and %v", server, intervals[i-1], intervals[i])
+			}
+		}
+	}
+	if len(waited) == 0 {
+		t.Fatal("no request waited for its server")
+	}
+	for id, wait := range waited {
+		schedule, ok := scheduled[id]
+		if !ok {
+			continue
+		}
+		if schedule.ReasonCode != ReasonAffinitySchedule || schedule.Context.Server != wait.Context.Server || schedule.Tick <= wait.Tick {
+			t.Errorf("%s waited for %s at tick %d, then %s on %s at tick %d", id, wait.Context.Server, wait.Tick, schedule.ReasonCode, schedule.Context.Server, schedule.Tick)
+		}
+	}
+}
+
+func TestInversionEvents(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.Affinity = &Affinity{Sessions: 100}
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range artifact.Events {
+		if event.Type == EventPriorityInversion {
+			t.Fatalf("inversion event at tick %d without inversion_events", event.Tick)
+		}
+	}
+
+	scenario.InversionEvents = true
+	artifact, err = Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateArtifact(artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+	priority := make(map[string]int)
+	for _, class := range scenario.ClassDefs() {
+		priority[class.Name] = class.Priority
+	}
+	scheduled := make(map[string]Event)
+	waited := make(map[string]bool)
+	inverted := make(map[string]bool)
+	reasons := make(map[string]int)
+	for _, event := range artifact.Events {
+		switch event.Type {
+		case EventAffinityWait:
+			waited[event.TokenID] = true
+		case EventSchedule:
+			scheduled[event.TokenID] = event
+		case EventPriorityInversion:
+			reasons[event.ReasonCode]++
+			if inverted[event.TokenID] {
+				t.Errorf("%s reported as inverted twice", event.TokenID)
+			}
+			inverted[event.TokenID] = true
+			if _, ok := scheduled[event.TokenID]; ok {
+				t.Errorf("%s inverted at tick %d after it was scheduled", event.TokenID, event.Tick)
+			}
+			overtaker, ok := scheduled[event.Context.Overtaken]
+			if !ok || overtaker.Tick != event.Tick || priority[overtaker.Class] <= priority[event.Class] {
+				t.Errorf("%s (%s) overtaken by %s (%s) at tick %d", event.TokenID, event.Class, event.Context.Overtaken, overtaker.Class, event.Tick)
+			}
+			if want := event.ReasonCode == ReasonAffinityInversion; waited[event.TokenID] != want {
+				t.Errorf("%s inverted with %s, affinity wait = %v", event.TokenID, event.ReasonCode, waited[event.TokenID])
+			}
+		}
+	}
+	if reasons[ReasonAffinityInversion] == 0 {
+		t.Errorf("inversion reasons = %v, want some %s", reasons, ReasonAffinityInversion)
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.WarmupTicks = 30
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline, err := Run(NewConfig(WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artifact.Events) != len(baseline.Events) || len(artifact.Snapshots) != len(baseline.Snapshots) {
+		t.Fatalf("warmup changed the run: %d events, %d snapshots, want %d and %d", len(artifact.Events), len(artifact.Snapshots), len(baseline.Events), len(baseline.Snapshots))
+	}
+	if artifact.Metadata.WarmupTicks != 30 {
+		t.Errorf("Metadata.WarmupTicks = %d, want 30", artifact.Metadata.WarmupTicks)
+	}
+	for _, event := range artifact.Events {
+		if event.Warmup != (event.Tick < 30) {
+			t.Fatalf("%s at tick %d has warmup = %v", event.Type, event.Tick, event.Warmup)
+		}
+	}
+	for _, snapshot := range artifact.Snapshots {
+		if snapshot.Warmup != (snapshot.Tick < 30) {
+			t.Fatalf("snapshot at tick %d has warmup = %v", snapshot.Tick, snapshot.Warmup)
+		}
+	}
+}
+
+func TestCosts(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.SLOs = []SLO{{Name: "paid-fast", Class: ClassPaid, Target: 0.9, LatencyMs: 1000, WindowTicks: 20}}
+	scenario.Costs = &Costs{ServerTick: 0.25, Rejection: 1, SLAMiss: 2, Revenue: map[string]float64{ClassPaid: 5, ClassFree: 1}}
+	artifact, err := Run(NewConfig(WithScenarioSpec(scenario), WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateArtifact(artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+
+	revenue, rejections, misses := 0.0, 0, 0
+	for _, event := range artifact.Events {
+		switch event.Type {
+		case EventComplete:
+			revenue += scenario.Costs.Revenue[event.Class]
+		case EventReject:
+			rejections++
+		}
+	}
+	for _, lifecycle := range Lifecycles(artifact.Events) {
+		if lifecycle.Class != ClassPaid {
+			continue
+		}
+		if lifecycle.Rejected() || (lifecycle.Completed() && lifecycle.LatencyTicks()*TickDurationMs > 1000) {
+			misses++
+		}
+	}
+
+	var previous Ledger
+	for _, snapshot := range artifact.Snapshots {
+		l := snapshot.Ledger
+		if l == nil {
+			t.Fatalf("snapshot %d has no ledger", snapshot.Tick)
+		}
+		if want := float64((snapshot.Tick+1)*scenario.Capacity) * 0.25; l.ServerCost != want {
+			t.Errorf("tick %d server cost = %v, want %v", snapshot.Tick, l.ServerCost, want)
+		}
+		if l.Revenue < previous.Revenue || l.RejectionPenalty < previous.RejectionPenalty || l.SLAPenalty < previous.SLAPenalty {
+			t.Errorf("tick %d ledger %+v shrank from %+v", snapshot.Tick, *l, previous)
+		}
+		previous = *l
+	}
+	want := Ledger{
+		ServerCost:       float64(TickCount*scenario.Capacity) * 0.25,
+		RejectionPenalty: float64(rejections),
+		SLAPenalty:       float64(2 * misses),
+		Revenue:          revenue,
+	}
+	want.Net = want.Revenue - want.ServerCost - want.RejectionPenalty - want.SLAPenalty
+	if previous != want {
+		t.Errorf("final ledger = %+v, want %+v", previous, want)
+	}
+}
+
+func TestArrivalTrace(t *testing.T) {
+	scenario := CanonicalScenario()
+	scenario.ServiceTime = 2
+	original, err := Run(Config{ScenarioID: scenario.ID, Scenario: &scenario, Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	arrivals := ExtractArrivals(original)
+	if len(arrivals) == 0 {
+		t.Fatal("ExtractArrivals() returned no arrivals")
+	}
+	for i, arrival := range arrivals {
+		if arrival.Size != 0 && arrival.Size != scenario.ServiceTime {
+			t.Errorf("arrivals[%d].Size = %d, want %d", i, arrival.Size, scenario.ServiceTime)
+		}
+	}
+
+	traced := scenario
+	traced.Spikes = nil
+	traced.Arrivals = arrivals
+	if err := traced.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := Run(Config{ScenarioID: traced.ID, Scenario: &traced, Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Lifecycles(replayed.Events), Lifecycles(original.Events); !reflect.DeepEqual(got, want) {
+		t.Errorf("traced lifecycles differ from the original run (%d vs %d tokens)", len(got), len(want))
+	}
+	if got := ExtractArrivals(replayed); !reflect.DeepEqual(got, arrivals) {
+		t.Errorf("ExtractArrivals(replayed) differs from the trace it ran")
+	}
+}
+
+func TestScriptPolicies(t *testing.T) {
+	baseline, err := Run(Config{Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scenario := CanonicalScenario()
+	scenario.Script = &Script{Schedule: "priority"}
+	scripted, err := Run(Config{ScenarioID: scenario.ID, Scenario: &scenario, Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Lifecycles(scripted.Events), Lifecycles(baseline.Events); !reflect.DeepEqual(got, want) {
+		t.Error("schedule script \"priority\" changed the lifecycles of the default scheduler")
+	}
+	for _, event := range scripted.Events {
+		if event.Type == EventSchedule && (event.ReasonCode != ReasonScriptSchedule || event.Context.Rule != RuleScriptSchedule) {
+			t.Fatalf("schedule event = %s/%s, want %s/%s", event.ReasonCode, event.Context.Rule, ReasonScriptSchedule, RuleScriptSchedule)
+		}
+	}
+
+	scenario = CanonicalScenario()
+	scenario.Script = &Script{Admit: `class != "ANON" || queue_length < 4`}
+	admitted, err := Run(Config{ScenarioID: scenario.ID, Scenario: &scenario, Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected := 0
+	for _, event := range admitted.Events {
+		if event.Type != EventReject || event.ReasonCode != ReasonScriptReject {
+			continue
+		}
+		rejected++
+		if event.Class != ClassAnon || event.Context.QueueLength < 4 || event.Context.Rule != RuleScriptAdmit {
+			t.Errorf("script rejected %s at queue length %d under rule %s", event.Class, event.Context.QueueLength, event.Context.Rule)
+		}
+	}
+	if rejected == 0 {
+		t.Error("admit script rejected nothing")
+	}
+}
+
+func TestCompileScript(t *testing.T) {
+	env := &scriptEnv{class: ClassPaid, priority: 2, wait: 5, queueLength: 8, capacity: 3}
+	tests := []struct {
+		source  string
+		want    scriptKind
+		num     float64
+		ok      bool
+		wantErr string
+	}{
+		{source: "priority*10 - wait", want: kindNumber, num: 15},
+		{source: "min(wait, 3, capacity) + abs(-1)", want: kindNumber, num: 4},
+		{source: "cond(class == \"PAID\", 0, priority)", want: kindNumber, num: 0},
+		{source: "7 % 4 / 2", want: kindNumber, num: 1.5},
+		{source: "queue_length >= 8 && !(class == \"ANON\")", want: kindBool, ok: true},
+		{source: "false || wait < 5", want: kindBool, ok: false},
+		{source: "priority + class", want: kindNumber, wantErr: "mixes a number and a string"},
+		{source: "deadline", want: kindNumber, wantErr: `unknown name "deadline"`},
+		{source: "sqrt(wait)", want: kindNumber, wantErr: `unknown function "sqrt"`},
+		{source: "wait > ", want: kindBool, wantErr: "expected operand"},
+		{source: "wait", want: kindBool, wantErr: "is a number, want a bool"},
+	}
+	for _, tt := range tests {
+		expr, err := compileScript(tt.source, tt.want)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("compileScript(%q) error = %v, want containing %q", tt.source, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("compileScript(%q) error = %v", tt.source, err)
+			continue
+		}
+		if tt.want == kindNumber && expr.num(env) != tt.num {
+			t.Errorf("compileScript(%q) = %v, want %v", tt.source, expr.num(env), tt.num)
+		}
+		if tt.want == kindBool && expr.bool(env) != tt.ok {
+			t.Errorf("compileScript(%q) = %v, want %v", tt.source, expr.bool(env), tt.ok)
+		}
+	}
+}
+
+func pluginModule(export string, body ...byte) []byte {
+	sig := append([]byte{1, 0x60, 10}, bytes.Repeat([]byte{0x7f}, 10)...)
+	sig = append(sig, 1, 0x7f)
+	code := append(append([]byte{0}, body...), 0x0b)
+	data := []byte("\x00asm\x01\x00\x00\x00")
+	data = append(append(data, 1, byte(len(sig))), sig...)
+	data = append(data, 3, 2, 1, 0)
+	data = append(append(data, 7, byte(len(export)+4), 1, byte(len(export))), export...)
+	data = append(data, 0, 0)
+	return append(append(data, 10, byte(len(code)+2), 1, byte(len(code))), code...)
+}
+
+func TestPluginPolicies(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	baseline, err := Run(Config{Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenario := CanonicalScenario()
+	scenario.Plugin = &Plugin{Path: write("priority.wasm", pluginModule("schedule", 0x20, 1))}
+	scheduled, err := Run(Config{ScenarioID: scenario.ID, Scenario: &scenario, Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Lifecycles(scheduled.Events), Lifecycles(baseline.Events); !reflect.DeepEqual(got, want) {
+		t.Error("plugin scheduling by priority changed the lifecycles of the default scheduler")
+	}
+	for _, event := range scheduled.Events {
+		if event.Type == EventSchedule && (event.ReasonCode != ReasonPluginSchedule || event.Context.Rule != RulePluginSchedule) {
+			t.Fatalf("schedule event = %s/%s, want %s/%s", event.ReasonCode, event.Context.Rule, ReasonPluginSchedule, RulePluginSchedule)
+		}
+	}
+	if got := scheduled.Metadata.Scenario.Plugin.SHA256; len(got) != 64 {
+		t.Errorf("Metadata.Scenario.Plugin.SHA256 = %q, want the pinned module digest", got)
+	}
+
+	// admit unless the request is ANON (class 0) and queue_length >= 4.
+	scenario = CanonicalScenario()
+	scenario.Plugin = &Plugin{Path: write("admit.wasm", pluginModule("admit", 0x20, 0, 0x20, 6, 0x41, 4, 0x48, 0x72))}
+	admitted, err := Run(Config{ScenarioID: scenario.ID, Scenario: &scenario, Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected := 0
+	for _, event := range admitted.Events {
+		if event.Type != EventReject || event.ReasonCode != ReasonPluginReject {
+			continue
+		}
+		rejected++
+		if event.Class != ClassAnon || event.Context.QueueLength < 4 || event.Context.Rule != RulePluginAdmit {
+			t.Errorf("plugin rejected %s at queue length %d under rule %s", event.Class, event.Context.QueueLength, event.Context.Rule)
+		}
+	}
+	if rejected == 0 {
+		t.Error("admit plugin rejected nothing")
+	}
+
+	scenario = CanonicalScenario()
+	scenario.Plugin = &Plugin{Path: write("trap.wasm", pluginModule("admit", 0x00))}
+	if _, err := Run(Config{ScenarioID: scenario.ID, Scenario: &scenario, Seed: 3}); err == nil || !strings.Contains(err.Error(), "unreachable") {
+		t.Errorf("Run() with a trapping plugin error = %v, want the trap", err)
+	}
+	scenario.Plugin.SHA256 = strings.Repeat("0", 64)
+	if _, err := Run(Config{ScenarioID: scenario.ID, Scenario: &scenario, Seed: 3}); err == nil || !strings.Contains(err.Error(), "has sha256") {
+		t.Errorf("Run() with a mismatched digest error = %v, want a digest error", err)
+	}
+}
+
+func TestPolicyProcessHelper(t *testing.T) {
+	mode := os.Getenv("FINIT_POLICY_HELPER")
+	if mode == "" {
+		t.Skip("runs only as a policy subprocess")
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(nil, 1<<20)
+	enc := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var request PolicyRequest
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		switch {
+		case request.Type == "start":
+			continue
+		case mode == "stall":
+			time.Sleep(time.Minute)
+		case request.Type == "schedule":
+			best := request.Candidates[0]
+			for _, candidate := range request.Candidates[1:] {
+				if candidate.Priority < best.Priority {
+					best = candidate
+				}
+			}
+			enc.Encode(PolicyResponse{Token: best.ID})
+		case request.Type == "admit":
+			admit := request.Token.Class != ClassAnon || request.QueueLength < 4
+			enc.Encode(PolicyResponse{Admit: &admit})
+		}
+	}
+	os.Exit(0)
+}
+
+func TestPolicyProcess(t *testing.T) {
+	helper := []string{os.Args[0], "-test.run=^TestPolicyProcessHelper$"}
+	baseline, err := Run(Config{Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("FINIT_POLICY_HELPER", "priority")
+	scenario := CanonicalScenario()
+	scenario.PolicyProcess = &PolicyProcess{Command: helper, Schedule: true, Admit: true, TimeoutMs: 5000}
+	artifact, err := Run(Config{ScenarioID: scenario.ID, Scenario: &scenario, Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artifact.Metadata.Caveats) != 1 {
+		t.Errorf("Metadata.Caveats = %q, want the external policy caveat", artifact.Metadata.Caveats)
+	}
+	rejected := 0
+	for _, event := range artifact.Events {
+		switch {
+		case event.Type == EventSchedule && (event.ReasonCode != ReasonExternalSchedule || event.Context.Rule != RuleExternalSchedule):
+			t.Fatalf("schedule event = %s/%s, want %s/%s", event.ReasonCode, event.Context.Rule, ReasonExternalSchedule, RuleExternalSchedule)
+		case event.Type == EventReject && event.ReasonCode == ReasonExternalReject:
+			rejected++
+			if event.Class != ClassAnon || event.Context.QueueLength < 4 {
+				t.Errorf("policy process rejected %s at queue length %d", event.Class, event.Context.QueueLength)
+			}
+		}
+	}
+	if rejected == 0 {
+		t.Error("policy process rejected nothing")
+	}
+
+	scenario.PolicyProcess.Admit = false
+	scheduled, err := Run(Config{ScenarioID: scenario.ID, Scenario: &scenario, Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Lifecycles(scheduled.Events), Lifecycles(baseline.Events); !reflect.DeepEqual(got, want) {
+		t.Error("policy process scheduling by priority changed the lifecycles of the default scheduler")
+	}
+
+	t.Setenv("FINIT_POLICY_HELPER", "stall")
+	scenario.PolicyProcess.TimeoutMs = 50
+	if _, err := Run(Config{ScenarioID: scenario.ID, Scenario: &scenario, Seed: 3}); err == nil || !strings.Contains(err.Error(), "no response within 50ms") {
+		t.Errorf("Run() with a stalled policy process error = %v, want a timeout", err)
+	}
+}
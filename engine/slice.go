@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+)
+
+const sliceCaveat = "a tick range cut from a longer run and renumbered from 0 (metadata.slice has the original ticks); events before the range are not included, so it cannot be replayed"
+
+type SliceRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+func SliceArtifact(artifact Artifact, from, to int) (Artifact, error) {
+	m := artifact.Metadata
+	if from < 0 || to >= m.TickCount || from > to {
+		return Artifact{}, fmt.Errorf("slice %d-%d is outside ticks 0-%d", from, to, m.TickCount-1)
+	}
+	start := -1
+	for i, snapshot := range artifact.Snapshots {
+		if snapshot.Tick > from {
+			break
+		}
+		start = i
+	}
+	if start < 0 {
+		return Artifact{}, fmt.Errorf("no snapshot at or before tick %d to start the slice from", from)
+	}
+	shift := artifact.Snapshots[start].Tick
+
+	slice := Artifact{Metadata: m}
+	for _, snapshot := range artifact.Snapshots[start:] {
+		if snapshot.Tick > to {
+			break
+		}
+		snapshot.Tick -= shift
+		snapshot.TimeMs = snapshot.Tick * m.TickDurationMs
+		slice.Snapshots = append(slice.Snapshots, snapshot)
+	}
+	for _, event := range artifact.Events {
+		if event.Tick < shift || event.Tick > to {
+			continue
+		}
+		event.Tick -= shift
+		slice.Events = append(slice.Events, event)
+	}
+
+	sm := &slice.Metadata
+	origin := shift
+	if m.Slice != nil {
+		origin += m.Slice.From
+	} else {
+		sm.Caveats = append(append([]string(nil), m.Caveats...), sliceCaveat)
+	}
+	sm.Slice = &SliceRange{From: origin, To: origin + to - shift}
+	sm.TickCount = to - shift + 1
+	sm.TotalDurationMs = sm.TickCount * sm.TickDurationMs
+	sm.WarmupTicks = min(max(m.WarmupTicks-shift, 0), sm.TickCount)
+	if m.Epoch != nil {
+		epoch := m.Epoch.Add(time.Duration(shift*m.TickDurationMs) * time.Millisecond)
+		sm.Epoch = &epoch
+	}
+	return slice, nil
+}
@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSliceArtifact(t *testing.T) {
+	artifact, err := Run(NewConfig(WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slice, err := SliceArtifact(artifact, 100, 160)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateArtifact(slice); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+	m := slice.Metadata
+	if m.TickCount != 61 || m.TotalDurationMs != 61*TickDurationMs || !reflect.DeepEqual(m.Slice, &SliceRange{From: 100, To: 160}) {
+		t.Errorf("metadata tick_count %d, total_duration_ms %d, slice %+v, want 61 ticks from 100 to 160", m.TickCount, m.TotalDurationMs, m.Slice)
+	}
+	first := slice.Snapshots[0]
+	want := artifact.Snapshots[100]
+	if first.Tick != 0 || first.TimeMs != 0 || !reflect.DeepEqual(first.Tokens, want.Tokens) {
+		t.Errorf("first snapshot = tick %d with %d tokens, want tick 0 with the %d tokens of tick 100", first.Tick, len(first.Tokens), len(want.Tokens))
+	}
+	events := 0
+	for _, event := range artifact.Events {
+		if event.Tick >= 100 && event.Tick <= 160 {
+			events++
+		}
+	}
+	if len(slice.Events) != events || slice.Events[0].Tick != 0 {
+		t.Errorf("slice has %d events from tick %d, want %d from tick 0", len(slice.Events), slice.Events[0].Tick, events)
+	}
+	if len(m.Caveats) != 1 {
+		t.Errorf("Metadata.Caveats = %q, want the slice caveat", m.Caveats)
+	}
+
+	nested, err := SliceArtifact(slice, 10, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(nested.Metadata.Slice, &SliceRange{From: 110, To: 120}) || len(nested.Metadata.Caveats) != 1 {
+		t.Errorf("slice of a slice = %+v with caveats %q, want ticks 110-120 and one caveat", nested.Metadata.Slice, nested.Metadata.Caveats)
+	}
+
+	sparse, err := Run(NewConfig(WithSeed(1), WithSnapshotInterval(10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyframed, err := SliceArtifact(sparse, 105, 160)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := keyframed.Metadata.Slice.From; got != 100 {
+		t.Errorf("slice of sparse snapshots starts at tick %d, want the keyframe at tick 100", got)
+	}
+
+	for _, r := range [][2]int{{-1, 10}, {10, 240}, {50, 40}} {
+		if _, err := SliceArtifact(artifact, r[0], r[1]); err == nil {
+			t.Errorf("SliceArtifact(%d, %d) error = nil", r[0], r[1])
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+)
+
+type SLO struct {
+	Name        string  `json:"name" yaml:"name"`
+	Class       string  `json:"class,omitempty" yaml:"class,omitempty"`
+	Target      float64 `json:"target" yaml:"target"`
+	LatencyMs   int     `json:"latency_ms" yaml:"latency_ms"`
+	WindowTicks int     `json:"window_ticks" yaml:"window_ticks"`
+	BurnRate    float64 `json:"burn_rate,omitempty" yaml:"burn_rate,omitempty"`
+}
+
+func (o SLO) validate(i int, classes map[string]bool) []error {
+	var errs []error
+	name := fmt.Sprintf("slos[%d]", i)
+	if o.Name == "" {
+		errs = append(errs, fmt.Errorf("%s: name is required", name))
+	} else {
+		name = fmt.Sprintf("slos[%d] (%s)", i, o.Name)
+	}
+	if err := unknownClass(name, o.Class, classes); err != nil {
+		errs = append(errs, err)
+	}
+	if o.Target <= 0 || o.Target >= 1 {
+		errs = append(errs, fmt.Errorf("%s: target must be between 0 and 1, got %v", name, o.Target))
+	}
+	if o.LatencyMs <= 0 {
+		errs = append(errs, fmt.Errorf("%s: latency_ms must be positive, got %d", name, o.LatencyMs))
+	}
+	if o.WindowTicks <= 0 {
+		errs = append(errs, fmt.Errorf("%s: window_ticks must be positive, got %d", name, o.WindowTicks))
+	}
+	if o.BurnRate < 0 {
+		errs = append(errs, fmt.Errorf("%s: burn_rate must not be negative, got %v", name, o.BurnRate))
+	}
+	return errs
+}
+
+func (o SLO) alertBurnRate() float64 {
+	if o.BurnRate == 0 {
+		return 1
+	}
+	return o.BurnRate
+}
+
+type sloState struct {
+	SLO
+	good    []int
+	bad     []int
+	lastBad *Token
+	firing  bool
+}
+
+func newSLOStates(slos []SLO) []*sloState {
+	states := make([]*sloState, len(slos))
+	for i, slo := range slos {
+		states[i] = &sloState{SLO: slo, good: make([]int, TickCount), bad: make([]int, TickCount)}
+	}
+	return states
+}
+
+func (s *Simulator) recordOutcome(tick int, token *Token, ok bool) {
+	if ok {
+		s.earn(token)
+	}
+	for _, slo := range s.slos {
+		if slo.Class != "" && slo.Class != token.Class {
+			continue
+		}
+		if ok && (tick-token.ArrivalTick)*TickDurationMs <= slo.LatencyMs {
+			slo.good[tick]++
+			continue
+		}
+		slo.bad[tick]++
+		slo.lastBad = token
+		s.chargeSLAMiss()
+	}
+}
+
+func (s *Simulator) evaluateSLOs(tick int) {
+	for _, slo := range s.slos {
+		good, bad := 0, 0
+		for t := max(tick-slo.WindowTicks+1, 0); t <= tick; t++ {
+			good += slo.good[t]
+			bad += slo.bad[t]
+		}
+		burn := 0.0
+		if total := good + bad; total > 0 {
+			burn = float64(bad) / float64(total) / (1 - slo.Target)
+		}
+		if burn < slo.alertBurnRate() {
+			slo.firing = false
+			continue
+		}
+		if slo.firing {
+			continue
+		}
+		slo.firing = true
+		token := slo.lastBad
+		context := s.newContext()
+		*context = EventContext{
+			Rule:        RuleSLOBurnRate,
+			QueueLength: s.queueLength(),
+			Limit:       slo.LatencyMs,
+			SLO:         slo.Name,
+			BurnRate:    math.Round(burn*1000) / 1000,
+			Missed:      bad,
+			Observed:    good + bad,
+		}
+		s.emit(Event{
+			Tick:       tick,
+			Type:       EventSLOAlert,
+			ReasonCode: ReasonSLOBurn,
+			TokenID:    token.ID,
+			StageID:    token.StageID,
+			Class:      token.Class,
+			Context:    context,
+		})
+	}
+}
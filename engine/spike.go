@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+)
+
+type Spike struct {
+	At      int      `json:"at" yaml:"at"`
+	Ticks   int      `json:"ticks" yaml:"ticks"`
+	Class   string   `json:"class,omitempty" yaml:"class,omitempty"`
+	Factor  float64  `json:"factor,omitempty" yaml:"factor,omitempty"`
+	Classes []string `json:"classes,omitempty" yaml:"classes,omitempty"`
+}
+
+func (sp Spike) validate(i int, classes map[string]bool) []error {
+	var errs []error
+	name := fmt.Sprintf("spikes[%d]", i)
+	if sp.At < 0 || sp.At >= TickCount {
+		errs = append(errs, fmt.Errorf("%s: at must be in [0, %d), got %d", name, TickCount, sp.At))
+	}
+	if sp.Ticks <= 0 {
+		errs = append(errs, fmt.Errorf("%s: ticks must be positive, got %d", name, sp.Ticks))
+	}
+	if (sp.Factor != 0) == (len(sp.Classes) > 0) {
+		errs = append(errs, fmt.Errorf("%s: set exactly one of factor or classes", name))
+	}
+	if sp.Factor != 0 && sp.Factor <= 1 {
+		errs = append(errs, fmt.Errorf("%s: factor must be greater than 1, got %v", name, sp.Factor))
+	}
+	for _, class := range append([]string{sp.Class}, sp.Classes...) {
+		if err := unknownClass(name, class, classes); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if sp.Class != "" && len(sp.Classes) > 0 {
+		errs = append(errs, fmt.Errorf("%s: class only applies to factor spikes", name))
+	}
+	return errs
+}
+
+func (sp Spike) active(tick int) bool {
+	return tick >= sp.At && tick < sp.At+sp.Ticks
+}
+
+func (sp Spike) extra(classes []string) int {
+	matching := 0
+	for _, class := range classes {
+		if sp.Class == "" || class == sp.Class {
+			matching++
+		}
+	}
+	return int(math.Round(float64(matching) * (sp.Factor - 1)))
+}
+
+func (sp Spike) pin() ClassPin {
+	return ClassPin{FromTick: sp.At, ToTick: sp.At + sp.Ticks - 1, Classes: sp.Classes}
+}
+
+func (s *Simulator) applySpikes(tick int, classes []string) []string {
+	for _, sp := range s.scenario.Spikes {
+		if sp.active(tick) && len(sp.Classes) > 0 && len(classes) >= len(sp.Classes) {
+			copy(classes, sp.Classes)
+		}
+	}
+	base := len(classes)
+	for _, sp := range s.scenario.Spikes {
+		if !sp.active(tick) || sp.Factor == 0 {
+			continue
+		}
+		class := sp.Class
+		for n := sp.extra(classes[:base]); n > 0; n-- {
+			if sp.Class == "" {
+				class = pickClass(s.rng, s.classes.mix)
+			}
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
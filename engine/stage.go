@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type Stage interface {
+	ID() string
+	Admit(tick int, token StageToken) bool
+	Tick(tick int) []StageResult
+	Emit(tick int) StageState
+}
+
+type StageToken struct {
+	ID          string
+	Class       string
+	ArrivalTick int
+	Size        int
+}
+
+type StageResult struct {
+	TokenID string
+	Failed  bool
+	Reason  string
+}
+
+var builtinStages = []string{StageArrivals, StageQueue, StageService, StageTransit, StageDone, StageRejected, StageFailed}
+
+func validateStages(stages []Stage) []error {
+	var errs []error
+	seen := make(map[string]bool)
+	for i, stage := range stages {
+		if stage == nil {
+			errs = append(errs, fmt.Errorf("Stages[%d] is nil", i))
+			continue
+		}
+		id := stage.ID()
+		switch {
+		case id == "":
+			errs = append(errs, fmt.Errorf("Stages[%d]: ID must not be empty", i))
+		case slices.Contains(builtinStages, id):
+			errs = append(errs, fmt.Errorf("Stages[%d]: ID %q is a built-in stage", i, id))
+		case seen[id]:
+			errs = append(errs, fmt.Errorf("Stages[%d]: duplicate ID %q", i, id))
+		}
+		seen[id] = true
+	}
+	return errs
+}
+
+func stagesCaveat(stages []Stage) string {
+	ids := make([]string, len(stages))
+	for i, stage := range stages {
+		ids[i] = stage.ID()
+	}
+	return fmt.Sprintf("custom stages %s are Go code outside the scenario; replays reproduce this run only with the same stages", strings.Join(ids, ", "))
+}
+
+func (s *Simulator) finishService(tick int, token *Token) {
+	if len(s.stages) > 0 {
+		s.enterStage(tick, token, 0)
+		return
+	}
+	s.complete(tick, token)
+}
+
+func (s *Simulator) enterStage(tick int, token *Token, i int) {
+	if i == len(s.stages) {
+		s.complete(tick, token)
+		return
+	}
+	stage := s.stages[i]
+	id := stage.ID()
+	if !stage.Admit(tick, StageToken{ID: token.ID, Class: token.Class, ArrivalTick: token.ArrivalTick, Size: token.size}) {
+		s.fail(tick, token, ReasonStageRefused, RuleCustomStage)
+		return
+	}
+	token.State = StateStaged
+	token.StageID = id
+	token.QueueIndex = -1
+	token.stage = i
+	s.staged[token.ID] = token
+	s.emit(Event{
+		Tick:       tick,
+		Type:       EventStageEnter,
+		ReasonCode: ReasonStageAdmitted,
+		TokenID:    token.request().ID,
+		StageID:    id,
+		Class:      token.Class,
+		Context:    &EventContext{Rule: RuleCustomStage, QueueLength: s.queueLength()},
+	})
+}
+
+func (s *Simulator) advanceStages(tick int) {
+	for i, stage := range s.stages {
+		for _, result := range stage.Tick(tick) {
+			token := s.staged[result.TokenID]
+			if token == nil || token.stage != i {
+				if s.hookErr == nil {
+					s.hookErr = fmt.Errorf("stage %s: Tick released %q, which it does not hold", stage.ID(), result.TokenID)
+				}
+				continue
+			}
+			delete(s.staged, result.TokenID)
+			if token.State == StateCancelled {
+				continue
+			}
+			if result.Failed {
+				reason := ReasonStageError
+				if result.Reason != "" {
+					reason = result.Reason
+				}
+				s.fail(tick, token, reason, RuleCustomStage)
+				continue
+			}
+			s.enterStage(tick, token, i+1)
+		}
+	}
+}
+
+func (s *Simulator) stageStates(tick int) []StageState {
+	states := make([]StageState, len(s.stages))
+	for i, stage := range s.stages {
+		states[i] = stage.Emit(tick)
+		states[i].ID = stage.ID()
+	}
+	return states
+}
@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+type delayStage struct {
+	id      string
+	latency int
+	limit   int
+	fail    string
+	reason  string
+	due     map[string]int
+	order   []string
+}
+
+func newDelayStage(id string, latency int) *delayStage {
+	return &delayStage{id: id, latency: latency, due: make(map[string]int)}
+}
+
+func (d *delayStage) ID() string {
+	return d.id
+}
+
+func (d *delayStage) Admit(tick int, token StageToken) bool {
+	if d.limit > 0 && len(d.order) >= d.limit {
+		return false
+	}
+	d.due[token.ID] = tick + d.latency
+	d.order = append(d.order, token.ID)
+	return true
+}
+
+func (d *delayStage) Tick(tick int) []StageResult {
+	var results []StageResult
+	remaining := d.order[:0]
+	for _, id := range d.order {
+		if d.due[id] > tick {
+			remaining = append(remaining, id)
+			continue
+		}
+		delete(d.due, id)
+		result := StageResult{TokenID: id, Failed: id == d.fail}
+		if result.Failed {
+			result.Reason = d.reason
+		}
+		results = append(results, result)
+	}
+	d.order = remaining
+	return results
+}
+
+func (d *delayStage) Emit(tick int) StageState {
+	return StageState{QueueLength: len(d.order), CapacityUsed: len(d.order), CapacityTotal: d.limit}
+}
+
+func TestStages(t *testing.T) {
+	api := newDelayStage("api", 3)
+	api.fail = "T0005"
+	artifact, err := Run(NewConfig(WithSeed(1), WithStages(api, newDelayStage("audit", 1))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entered := make(map[string][]int)
+	completed := make(map[string]int)
+	failed := 0
+	for _, event := range artifact.Events {
+		switch event.Type {
+		case EventStageEnter:
+			entered[event.TokenID] = append(entered[event.TokenID], event.Tick)
+		case EventComplete:
+			completed[event.TokenID] = event.Tick
+		case EventFail:
+			if event.TokenID != "T0005" || event.ReasonCode != ReasonStageError || event.Context.Rule != RuleCustomStage {
+				t.Errorf("unexpected failure %s %s/%s", event.TokenID, event.ReasonCode, event.Context.Rule)
+			}
+			delete(entered, event.TokenID)
+			failed++
+		}
+	}
+	if len(completed) == 0 {
+		t.Fatal("no request completed")
+	}
+	for id, tick := range completed {
+		if got := entered[id]; len(got) != 2 || got[1] != got[0]+3 || tick != got[1]+1 {
+			t.Errorf("%s entered stages at %v and completed at %d, want api, audit 3 ticks later, done 1 tick after", id, got, tick)
+		}
+	}
+	if failed != 1 {
+		t.Errorf("api stage failed %d requests, want only T0005", failed)
+	}
+
+	last := artifact.Snapshots[len(artifact.Snapshots)-1].Stages
+	if n := len(last); n < 2 || last[n-2].ID != "api" || last[n-1].ID != "audit" {
+		t.Errorf("snapshot stages = %v, want api and audit last", last)
+	}
+	if len(artifact.Metadata.Caveats) != 1 || !strings.Contains(artifact.Metadata.Caveats[0], "api, audit") {
+		t.Errorf("Metadata.Caveats = %q, want the custom stage caveat", artifact.Metadata.Caveats)
+	}
+	if err := ValidateArtifact(artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+}
+
+func TestStages_Refused(t *testing.T) {
+	full := newDelayStage("api", 20)
+	full.limit = 2
+	artifact, err := Run(NewConfig(WithSeed(1), WithStages(full)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused := 0
+	for _, event := range artifact.Events {
+		if event.Type == EventFail && event.ReasonCode == ReasonStageRefused {
+			refused++
+		}
+	}
+	if refused == 0 {
+		t.Error("a stage at its limit refused nothing")
+	}
+}
+
+func TestStages_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		stages  []Stage
+		wantErr string
+	}{
+		{name: "nil", stages: []Stage{nil}, wantErr: "Stages[0] is nil"},
+		{name: "empty", stages: []Stage{newDelayStage("", 1)}, wantErr: "ID must not be empty"},
+		{name: "builtin", stages: []Stage{newDelayStage(StageService, 1)}, wantErr: `ID "service" is a built-in stage`},
+		{name: "duplicate", stages: []Stage{newDelayStage("api", 1), newDelayStage("api", 2)}, wantErr: `Stages[1]: duplicate ID "api"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewConfig(WithStages(tt.stages...)).Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type leakyStage struct {
+	*delayStage
+}
+
+func (l leakyStage) Tick(tick int) []StageResult {
+	return []StageResult{{TokenID: "T9999"}}
+}
+
+func TestStages_UnknownToken(t *testing.T) {
+	_, err := Run(NewConfig(WithStages(leakyStage{newDelayStage("api", 1)})))
+	if err == nil || !strings.Contains(err.Error(), `stage api: Tick released "T9999"`) {
+		t.Errorf("Run() error = %v, want the unknown token", err)
+	}
+}
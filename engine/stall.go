@@ -0,0 +1,75 @@
+package engine
+
+import "fmt"
+
+type Stall struct {
+	Tick  int `json:"tick" yaml:"tick"`
+	Ticks int `json:"ticks" yaml:"ticks"`
+	Slots int `json:"slots,omitempty" yaml:"slots,omitempty"`
+}
+
+func (st Stall) validate(i int, prevEnd int) []error {
+	var errs []error
+	name := fmt.Sprintf("stalls[%d]", i)
+	if st.Tick < prevEnd || st.Tick >= TickCount {
+		errs = append(errs, fmt.Errorf("%s: tick %d must not overlap the previous stall and must be below %d", name, st.Tick, TickCount))
+	}
+	if st.Ticks <= 0 {
+		errs = append(errs, fmt.Errorf("%s: ticks must be positive, got %d", name, st.Ticks))
+	}
+	if st.Slots < 0 {
+		errs = append(errs, fmt.Errorf("%s: slots must not be negative, got %d", name, st.Slots))
+	}
+	return errs
+}
+
+func (s *Simulator) stall(tick int) {
+	if s.stalledUntil > 0 && tick == s.stalledUntil {
+		servers := len(s.stalled) + s.stalledIdle
+		for _, token := range s.stalled {
+			token.stalled = false
+		}
+		s.stalled = s.stalled[:0]
+		s.stalledIdle = 0
+		s.stalledUntil = 0
+		s.emitStall(tick, EventStallEnd, ReasonStallResumed, servers)
+	}
+	if len(s.stalls) == 0 || s.stalls[0].Tick != tick {
+		return
+	}
+	st := s.stalls[0]
+	s.stalls = s.stalls[1:]
+	slots := s.capacity
+	if st.Slots > 0 {
+		slots = min(st.Slots, s.capacity)
+	}
+	for _, token := range s.inService {
+		if len(s.stalled) == slots {
+			break
+		}
+		if token.State == StateProcessing {
+			token.stalled = true
+			s.stalled = append(s.stalled, token)
+		}
+	}
+	s.stalledIdle = slots - len(s.stalled)
+	s.stalledUntil = tick + st.Ticks
+	s.emitStall(tick, EventStallStart, ReasonStallInjected, slots)
+}
+
+func (s *Simulator) emitStall(tick int, eventType, reason string, servers int) {
+	context := s.newContext()
+	*context = EventContext{
+		Rule:         RuleStall,
+		QueueLength:  s.queueLength(),
+		CapacityUsed: len(s.inService),
+		Servers:      servers,
+	}
+	s.emit(Event{
+		Tick:       tick,
+		Type:       eventType,
+		ReasonCode: reason,
+		StageID:    StageService,
+		Context:    context,
+	})
+}
@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+func StreamArtifact(r io.Reader, fn func(Snapshot) error, fnEv func(Event) error) (Metadata, error) {
+	var metadata Metadata
+	dec := json.NewDecoder(r)
+	if _, err := dec.Token(); err != nil {
+		return Metadata{}, fmt.Errorf("stream artifact: %w", err)
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return Metadata{}, fmt.Errorf("stream artifact: %w", err)
+		}
+		switch key {
+		case "metadata":
+			err = dec.Decode(&metadata)
+		case "snapshots":
+			err = streamArray(dec, func() error {
+				if fn == nil {
+					var skip json.RawMessage
+					return dec.Decode(&skip)
+				}
+				var snapshot Snapshot
+				if err := dec.Decode(&snapshot); err != nil {
+					return err
+				}
+				return fn(snapshot)
+			})
+		case "events":
+			err = streamArray(dec, func() error {
+				if fnEv == nil {
+					var skip json.RawMessage
+					return dec.Decode(&skip)
+				}
+				var event Event
+				if err := dec.Decode(&event); err != nil {
+					return err
+				}
+				return fnEv(event)
+			})
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			return metadata, fmt.Errorf("stream artifact %v: %w", key, err)
+		}
+	}
+	return metadata, nil
+}
+
+func streamArray(dec *json.Decoder, fn func() error) error {
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
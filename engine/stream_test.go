@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestStreamArtifact(t *testing.T) {
+	artifact, err := Run(Config{Seed: 1})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	data, err := MarshalArtifact(artifact)
+	if err != nil {
+		t.Fatalf("MarshalArtifact() error = %v", err)
+	}
+
+	var snapshots []Snapshot
+	var events []Event
+	metadata, err := StreamArtifact(bytes.NewReader(data),
+		func(s Snapshot) error { snapshots = append(snapshots, s); return nil },
+		func(e Event) error { events = append(events, e); return nil },
+	)
+	if err != nil {
+		t.Fatalf("StreamArtifact() error = %v", err)
+	}
+	if !reflect.DeepEqual(metadata, artifact.Metadata) {
+		t.Errorf("StreamArtifact() metadata = %+v, want %+v", metadata, artifact.Metadata)
+	}
+	if len(snapshots) != len(artifact.Snapshots) || len(events) != len(artifact.Events) {
+		t.Fatalf("streamed %d snapshots, %d events; want %d, %d", len(snapshots), len(events), len(artifact.Snapshots), len(artifact.Events))
+	}
+	if snapshots[100].Tick != 100 || !reflect.DeepEqual(events[10], artifact.Events[10]) {
+		t.Errorf("streamed values differ from the artifact")
+	}
+
+	rejects := 0
+	if _, err := StreamArtifact(bytes.NewReader(data), nil, func(e Event) error {
+		if e.Type == EventReject {
+			rejects++
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("StreamArtifact(events only) error = %v", err)
+	}
+	if rejects == 0 {
+		t.Error("expected rejections in the canonical run")
+	}
+
+	stop := errors.New("stop")
+	seen := 0
+	_, err = StreamArtifact(bytes.NewReader(data), func(Snapshot) error {
+		seen++
+		if seen == 3 {
+			return stop
+		}
+		return nil
+	}, nil)
+	if !errors.Is(err, stop) || seen != 3 {
+		t.Errorf("StreamArtifact() error = %v after %d snapshots, want stop after 3", err, seen)
+	}
+
+	if _, err := StreamArtifact(bytes.NewReader(data[:len(data)/2]), nil, nil); err == nil {
+		t.Error("StreamArtifact(truncated) error = nil")
+	}
+}
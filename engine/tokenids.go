@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const defaultTokenPrefix = "T"
+
+var (
+	tokenPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]*[A-Za-z_.:-]$`)
+	uuidPattern        = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	uuidNamespaceURL   = [16]byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+)
+
+type TokenIDs struct {
+	Prefix        string            `json:"prefix,omitempty"`
+	Width         int               `json:"width,omitempty"`
+	ClassPrefixes map[string]string `json:"class_prefixes,omitempty"`
+	UUID          bool              `json:"uuid,omitempty"`
+}
+
+func (t TokenIDs) validate(scenario Scenario) []error {
+	var errs []error
+	if t.UUID && (t.Prefix != "" || t.Width != 0 || len(t.ClassPrefixes) > 0) {
+		errs = append(errs, fmt.Errorf("TokenIDs: UUID ids take no Prefix, Width or ClassPrefixes"))
+	}
+	if t.Width < 0 || t.Width > 18 {
+		errs = append(errs, fmt.Errorf("TokenIDs: Width must be between 0 and 18, got %d", t.Width))
+	}
+	prefixes := map[string]string{t.prefix(): "Prefix"}
+	if t.Prefix != "" && !tokenPrefixPattern.MatchString(t.Prefix) {
+		errs = append(errs, fmt.Errorf("TokenIDs: Prefix %q must be letters, digits or _.:- and not end in a digit", t.Prefix))
+	}
+	classes := scenario.classSet()
+	for _, class := range sortedClassKeys(t.ClassPrefixes) {
+		prefix := t.ClassPrefixes[class]
+		if !classes[class] {
+			errs = append(errs, fmt.Errorf("TokenIDs: ClassPrefixes has unknown class %q", class))
+		}
+		if !tokenPrefixPattern.MatchString(prefix) {
+			errs = append(errs, fmt.Errorf("TokenIDs: %s prefix %q must be letters, digits or _.:- and not end in a digit", class, prefix))
+		}
+		if other, ok := prefixes[prefix]; ok {
+			errs = append(errs, fmt.Errorf("TokenIDs: %s prefix %q is already used by %s", class, prefix, other))
+		}
+		prefixes[prefix] = class
+	}
+	return errs
+}
+
+func (t TokenIDs) prefix() string {
+	if t.Prefix == "" {
+		return defaultTokenPrefix
+	}
+	return t.Prefix
+}
+
+func (t *TokenIDs) primary(id string) bool {
+	if t == nil {
+		digits, ok := strings.CutPrefix(id, defaultTokenPrefix)
+		return ok && allDigits(digits)
+	}
+	if t.UUID {
+		return uuidPattern.MatchString(id)
+	}
+	if digits, ok := strings.CutPrefix(id, t.prefix()); ok && allDigits(digits) {
+		return true
+	}
+	for _, prefix := range t.ClassPrefixes {
+		if digits, ok := strings.CutPrefix(id, prefix); ok && allDigits(digits) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedClassKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type tokenIDGen struct {
+	scheme    TokenIDs
+	namespace [16]byte
+	counters  map[string]int
+}
+
+func newTokenIDGen(scheme TokenIDs, replayID string) *tokenIDGen {
+	return &tokenIDGen{
+		scheme:    scheme,
+		namespace: uuidV5(uuidNamespaceURL, "urn:finit:replay:"+replayID),
+		counters:  make(map[string]int),
+	}
+}
+
+func (g *tokenIDGen) next(class string) string {
+	prefix, ok := g.scheme.ClassPrefixes[class]
+	if !ok {
+		prefix = g.scheme.prefix()
+	}
+	id := g.format(prefix, g.counters[prefix])
+	g.counters[prefix]++
+	return id
+}
+
+func (g *tokenIDGen) peek() string {
+	prefix := g.scheme.prefix()
+	return g.format(prefix, g.counters[prefix])
+}
+
+func (g *tokenIDGen) format(prefix string, n int) string {
+	width := g.scheme.Width
+	if width == 0 {
+		width = 4
+	}
+	digits := strconv.Itoa(n)
+	for len(digits) < width {
+		digits = "0" + digits
+	}
+	if g.scheme.UUID {
+		return formatUUID(uuidV5(g.namespace, prefix+digits))
+	}
+	return prefix + digits
+}
+
+func uuidV5(namespace [16]byte, name string) [16]byte {
+	h := sha1.New()
+	h.Write(namespace[:])
+	h.Write([]byte(name))
+	var id [16]byte
+	copy(id[:], h.Sum(nil))
+	id[6] = id[6]&0x0f | 0x50
+	id[8] = id[8]&0x3f | 0x80
+	return id
+}
+
+func formatUUID(id [16]byte) string {
+	s := hex.EncodeToString(id[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var uuidV5Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestTokenIDs(t *testing.T) {
+	plain, err := Run(NewConfig(WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		ids   TokenIDs
+		first map[string]string
+		match func(id string) bool
+	}{
+		{"prefix and width", TokenIDs{Prefix: "run-a.", Width: 6}, nil, func(id string) bool {
+			return regexp.MustCompile(`^run-a\.\d{6}$`).MatchString(id)
+		}},
+		{"class prefixes", TokenIDs{ClassPrefixes: map[string]string{ClassPaid: "P", ClassFree: "F", ClassAnon: "A"}},
+			map[string]string{ClassPaid: "P0000", ClassFree: "F0000", ClassAnon: "A0000"}, func(id string) bool {
+				return regexp.MustCompile(`^[PFA]\d{4}$`).MatchString(id)
+			}},
+		{"uuid", TokenIDs{UUID: true}, nil, uuidV5Pattern.MatchString},
+	}
+	for _, tt := range tests {
+		artifact, err := Run(NewConfig(WithSeed(1), WithTokenIDs(tt.ids)))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if artifact.Metadata.TokenIDs == nil {
+			t.Errorf("%s: Metadata.TokenIDs is nil", tt.name)
+		}
+		if len(artifact.Events) != len(plain.Events) {
+			t.Fatalf("%s: %d events, want %d", tt.name, len(artifact.Events), len(plain.Events))
+		}
+		renamed := make(map[string]string)
+		first := make(map[string]string)
+		for i, event := range artifact.Events {
+			want := plain.Events[i]
+			if event.Type != want.Type || event.Tick != want.Tick || event.Class != want.Class {
+				t.Fatalf("%s: events[%d] = %s at %d, want %s at %d", tt.name, i, event.Type, event.Tick, want.Type, want.Tick)
+			}
+			if want.TokenID == "" {
+				continue
+			}
+			if !tt.match(event.TokenID) {
+				t.Fatalf("%s: token id %q does not match the scheme", tt.name, event.TokenID)
+			}
+			if previous, ok := renamed[want.TokenID]; ok && previous != event.TokenID {
+				t.Fatalf("%s: %s renamed to both %s and %s", tt.name, want.TokenID, previous, event.TokenID)
+			}
+			renamed[want.TokenID] = event.TokenID
+			if _, ok := first[event.Class]; !ok {
+				first[event.Class] = event.TokenID
+			}
+		}
+		if got, want := ExtractArrivals(artifact), ExtractArrivals(plain); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: ExtractArrivals() found %d arrivals, want %d", tt.name, len(got), len(want))
+		}
+		seen := make(map[string]bool)
+		for _, id := range renamed {
+			if seen[id] {
+				t.Errorf("%s: token id %s is reused", tt.name, id)
+			}
+			seen[id] = true
+		}
+		for class, want := range tt.first {
+			if first[class] != want {
+				t.Errorf("%s: first %s token = %s, want %s", tt.name, class, first[class], want)
+			}
+		}
+	}
+}
+
+func TestTokenIDsValidate(t *testing.T) {
+	tests := []struct {
+		ids  TokenIDs
+		want string
+	}{
+		{TokenIDs{Prefix: "ok-"}, ""},
+		{TokenIDs{Prefix: "T1"}, "Prefix"},
+		{TokenIDs{Width: -1}, "Width"},
+		{TokenIDs{UUID: true, Prefix: "X"}, "UUID"},
+		{TokenIDs{ClassPrefixes: map[string]string{"GOLD": "G"}}, "unknown class"},
+		{TokenIDs{ClassPrefixes: map[string]string{ClassPaid: "P", ClassFree: "P"}}, "already used"},
+		{TokenIDs{ClassPrefixes: map[string]string{ClassPaid: "T"}}, "already used"},
+	}
+	for _, tt := range tests {
+		err := NewConfig(WithTokenIDs(tt.ids)).Validate()
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("Validate(%+v) = %v, want nil", tt.ids, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Validate(%+v) = %v, want mention of %s", tt.ids, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+func TraceID(replayID, tokenID string) string {
+	sum := sha256.Sum256([]byte(replayID + "|" + tokenID))
+	return hex.EncodeToString(sum[:16])
+}
+
+func SpanID(replayID, tokenID, name string) string {
+	sum := sha256.Sum256([]byte(replayID + "|" + tokenID + "|" + name))
+	return hex.EncodeToString(sum[:8])
+}
+
+func Traceparent(replayID, tokenID string) string {
+	return "00-" + TraceID(replayID, tokenID) + "-" + SpanID(replayID, tokenID, "request") + "-01"
+}
+
+func (s *Simulator) traceID(id string) string {
+	trace, ok := s.traces[id]
+	if !ok {
+		trace = TraceID(s.replayID, id)
+		s.traces[id] = trace
+	}
+	return trace
+}
+
+func (s *Simulator) tokenState(token *Token) TokenState {
+	state := token.snapshot()
+	if s.traces != nil {
+		state.TraceID = s.traceID(token.request().ID)
+	}
+	return state
+}
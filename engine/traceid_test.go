@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+var traceparent = regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`)
+
+func TestTraceIDs(t *testing.T) {
+	plain, err := Run(NewConfig(WithSeed(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	traced, err := Run(NewConfig(WithSeed(1), WithTraceIDs()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !traced.Metadata.TraceIDs || plain.Metadata.TraceIDs {
+		t.Errorf("Metadata.TraceIDs = %v and %v, want only the traced run marked", traced.Metadata.TraceIDs, plain.Metadata.TraceIDs)
+	}
+	if !reflect.DeepEqual(Lifecycles(traced.Events), Lifecycles(plain.Events)) {
+		t.Error("trace ids changed the run")
+	}
+	replayID := traced.Metadata.ReplayID
+	for _, event := range traced.Events {
+		if event.TokenID != "" && event.TraceID != TraceID(replayID, event.TokenID) {
+			t.Fatalf("event %s %s trace_id = %q, want %q", event.Type, event.TokenID, event.TraceID, TraceID(replayID, event.TokenID))
+		}
+	}
+	for _, snapshot := range traced.Snapshots {
+		for _, token := range snapshot.Tokens {
+			want := TraceID(replayID, token.ID)
+			if token.HedgeOf != "" {
+				want = TraceID(replayID, token.HedgeOf)
+			}
+			if token.TraceID != want {
+				t.Fatalf("token %s trace_id = %q, want %q", token.ID, token.TraceID, want)
+			}
+		}
+	}
+	for _, event := range plain.Events {
+		if event.TraceID != "" {
+			t.Fatalf("untraced event %s carries trace_id %q", event.TokenID, event.TraceID)
+		}
+	}
+
+	if got := Traceparent(replayID, "T0001"); !traceparent.MatchString(got) {
+		t.Errorf("Traceparent() = %q, want a W3C version 00 header", got)
+	}
+	if TraceID("a", "T0001") == TraceID("b", "T0001") || TraceID("a", "T0001") == TraceID("a", "T0002") {
+		t.Error("TraceID() collides across replay ids or tokens")
+	}
+}
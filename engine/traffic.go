@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+const (
+	TrafficOnOff   = "on_off"
+	TrafficBModel  = "b_model"
+	TrafficDiurnal = "diurnal"
+)
+
+const profileHours = 24
+
+type Traffic struct {
+	Model    string    `json:"model" yaml:"model"`
+	Sources  int       `json:"sources,omitempty" yaml:"sources,omitempty"`
+	Rate     float64   `json:"rate,omitempty" yaml:"rate,omitempty"`
+	Shape    float64   `json:"shape,omitempty" yaml:"shape,omitempty"`
+	MinTicks int       `json:"min_ticks,omitempty" yaml:"min_ticks,omitempty"`
+	Bias     float64   `json:"bias,omitempty" yaml:"bias,omitempty"`
+	Total    int       `json:"total,omitempty" yaml:"total,omitempty"`
+	Peak     float64   `json:"peak,omitempty" yaml:"peak,omitempty"`
+	Trough   float64   `json:"trough,omitempty" yaml:"trough,omitempty"`
+	Profile  []float64 `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Days     int       `json:"days,omitempty" yaml:"days,omitempty"`
+}
+
+func (t Traffic) validate() []error {
+	var errs []error
+	switch t.Model {
+	case TrafficOnOff:
+		if t.Sources <= 0 {
+			errs = append(errs, fmt.Errorf("traffic: sources must be positive, got %d", t.Sources))
+		}
+		if t.Rate <= 0 {
+			errs = append(errs, fmt.Errorf("traffic: rate must be positive, got %v", t.Rate))
+		}
+		if t.Shape <= 1 {
+			errs = append(errs, fmt.Errorf("traffic: shape must be greater than 1, got %v", t.Shape))
+		}
+		if t.MinTicks <= 0 {
+			errs = append(errs, fmt.Errorf("traffic: min_ticks must be positive, got %d", t.MinTicks))
+		}
+	case TrafficBModel:
+		if t.Bias < 0.5 || t.Bias >= 1 {
+			errs = append(errs, fmt.Errorf("traffic: bias must be in [0.5, 1), got %v", t.Bias))
+		}
+		if t.Total <= 0 {
+			errs = append(errs, fmt.Errorf("traffic: total must be positive, got %d", t.Total))
+		}
+	case TrafficDiurnal:
+		errs = append(errs, t.validateDiurnal()...)
+	default:
+		errs = append(errs, fmt.Errorf("traffic: unknown model %q (want %s, %s or %s)", t.Model, TrafficOnOff, TrafficBModel, TrafficDiurnal))
+	}
+	return errs
+}
+
+func (t Traffic) validateDiurnal() []error {
+	var errs []error
+	if t.Days < 0 {
+		errs = append(errs, fmt.Errorf("traffic: days must not be negative, got %d", t.Days))
+	}
+	if t.Profile == nil {
+		if t.Trough < 0 || t.Peak <= 0 || t.Peak < t.Trough {
+			errs = append(errs, fmt.Errorf("traffic: want 0 <= trough <= peak and peak > 0, got trough %v and peak %v", t.Trough, t.Peak))
+		}
+		return errs
+	}
+	if len(t.Profile) != profileHours {
+		errs = append(errs, fmt.Errorf("traffic: profile must have %d hourly rates, got %d", profileHours, len(t.Profile)))
+	}
+	for i, rate := range t.Profile {
+		if rate < 0 {
+			errs = append(errs, fmt.Errorf("traffic: profile[%d] must not be negative, got %v", i, rate))
+		}
+	}
+	return errs
+}
+
+func (t Traffic) expectedArrivals() int {
+	switch t.Model {
+	case TrafficBModel:
+		return t.Total
+	case TrafficDiurnal:
+		total := 0.0
+		for tick := 0; tick < TickCount; tick++ {
+			total += t.diurnalRate(tick)
+		}
+		return int(math.Round(total))
+	}
+	return int(math.Round(float64(t.Sources) * t.Rate * TickCount / 2))
+}
+
+func (t Traffic) diurnalRate(tick int) float64 {
+	days := float64(max(t.Days, 1))
+	hour := float64(tick) / TickCount * days * profileHours
+	if t.Profile == nil {
+		return t.Trough + (t.Peak-t.Trough)*(1-math.Cos(2*math.Pi*hour/profileHours))/2
+	}
+	i := int(hour) % profileHours
+	frac := hour - math.Floor(hour)
+	return t.Profile[i] + (t.Profile[(i+1)%profileHours]-t.Profile[i])*frac
+}
+
+func (t Traffic) series(rng *rand.Rand) []int {
+	counts := make([]int, TickCount)
+	switch t.Model {
+	case TrafficBModel:
+		t.cascade(rng, counts, t.Total)
+		return counts
+	case TrafficDiurnal:
+		for tick := range counts {
+			counts[tick] = poisson(rng, t.diurnalRate(tick))
+		}
+		return counts
+	}
+	for i := 0; i < t.Sources; i++ {
+		on := rng.Intn(2) == 0
+		for tick := 0; tick < TickCount; {
+			end := min(tick+t.period(rng), TickCount)
+			for ; tick < end; tick++ {
+				if on {
+					counts[tick] += poisson(rng, t.Rate)
+				}
+			}
+			on = !on
+		}
+	}
+	return counts
+}
+
+func (t Traffic) period(rng *rand.Rand) int {
+	ticks := float64(t.MinTicks) / math.Pow(1-rng.Float64(), 1/t.Shape)
+	return int(math.Min(math.Ceil(ticks), TickCount))
+}
+
+func (t Traffic) cascade(rng *rand.Rand, counts []int, total int) {
+	if len(counts) == 1 {
+		counts[0] += total
+		return
+	}
+	if total == 0 {
+		return
+	}
+	share := t.Bias
+	if rng.Intn(2) == 0 {
+		share = 1 - share
+	}
+	left := int(math.Round(float64(total) * share))
+	half := len(counts) / 2
+	t.cascade(rng, counts[:half], left)
+	t.cascade(rng, counts[half:], total-left)
+}
+
+func poisson(rng *rand.Rand, mean float64) int {
+	limit := math.Exp(-mean)
+	n := 0
+	for p := rng.Float64(); p > limit; p *= rng.Float64() {
+		n++
+	}
+	return n
+}
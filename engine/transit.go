@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+)
+
+type Transit struct {
+	From  string `json:"from" yaml:"from"`
+	To    string `json:"to" yaml:"to"`
+	Ticks int    `json:"ticks" yaml:"ticks"`
+}
+
+var transitEdges = [][2]string{
+	{StageQueue, StageService},
+	{StageService, StageDone},
+}
+
+func (t Transit) validate(i int) []error {
+	var errs []error
+	name := fmt.Sprintf("transit[%d]", i)
+	if !slices.Contains(transitEdges, [2]string{t.From, t.To}) {
+		errs = append(errs, fmt.Errorf("%s: unsupported hop %s -> %s (want %s -> %s or %s -> %s)",
+			name, t.From, t.To, StageQueue, StageService, StageService, StageDone))
+	}
+	if t.Ticks <= 0 {
+		errs = append(errs, fmt.Errorf("%s: ticks must be positive, got %d", name, t.Ticks))
+	}
+	return errs
+}
+
+func (s Scenario) transitTicks(from, to string) int {
+	for _, t := range s.Transit {
+		if t.From == from && t.To == to {
+			return t.Ticks
+		}
+	}
+	return 0
+}
+
+func (s *Simulator) startTransit(tick int, token *Token, to string, ticks int) {
+	token.State = StateTransit
+	token.StageID = StageTransit
+	token.transitTo = to
+	token.transitUntil = tick + ticks
+	s.transit = append(s.transit, token)
+}
+
+func (s *Simulator) advanceTransit(tick int) {
+	var arrived []*Token
+	remaining := s.transit[:0]
+	for _, token := range s.transit {
+		if token.State == StateCancelled {
+			continue
+		}
+		if token.transitUntil > tick {
+			remaining = append(remaining, token)
+			continue
+		}
+		arrived = append(arrived, token)
+	}
+	clear(s.transit[len(remaining):])
+	s.transit = remaining
+	for _, token := range arrived {
+		if token.State == StateCancelled {
+			continue
+		}
+		switch token.transitTo {
+		case StageService:
+			s.startService(token)
+		case StageDone:
+			s.finishService(tick, token)
+		}
+	}
+	s.transit = slices.DeleteFunc(s.transit, func(token *Token) bool { return token.State == StateCancelled })
+}
+
+func (s *Simulator) reservedTransit() int {
+	reserved := 0
+	for _, token := range s.transit {
+		if token.transitTo == StageService {
+			reserved++
+		}
+	}
+	return reserved
+}
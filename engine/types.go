@@ -1,5 +1,7 @@
 package engine
 
+import "time"
+
 const (
 	ScenarioID      = "canonical_v1"
 	EngineVersion   = "0.1.0"
@@ -20,6 +22,12 @@ const (
 	StateProcessing = "processing"
 	StateDone       = "done"
 	StateRejected   = "rejected"
+	StateCancelled  = "cancelled"
+	StateCoalesced  = "coalesced"
+	StateTransit    = "in_transit"
+	StateHeld       = "held"
+	StateFailed     = "failed"
+	StateStaged     = "staged"
 )
 
 const (
@@ -27,6 +35,13 @@ const (
 	StageService  = "service"
 	StageDone     = "done"
 	StageRejected = "rejected"
+	StageTransit  = "transit"
+	StageFailed   = "failed"
+)
+
+const (
+	SnapshotSchemaFull   = "full"
+	SnapshotSchemaActive = "active"
 )
 
 const (
@@ -34,36 +49,163 @@ const (
 	EventSchedule = "SCHEDULE"
 	EventComplete = "COMPLETE"
 	EventReject   = "REJECT"
+
+	EventStarvationWarning = "STARVATION_WARNING"
+	EventSLOAlert          = "SLO_ALERT"
+	EventPositionUpdate    = "POSITION_UPDATE"
+	EventDegradeStart      = "DEGRADE_START"
+	EventDegradeEnd        = "DEGRADE_END"
+	EventHedgeSpawn        = "HEDGE_SPAWN"
+	EventHedgeCancel       = "HEDGE_CANCEL"
+	EventDuplicateDrop     = "DUPLICATE_DROP"
+	EventWarmupStart       = "WARMUP_START"
+	EventWarmupEnd         = "WARMUP_END"
+	EventDrainStart        = "DRAIN_START"
+	EventDrainEnd          = "DRAIN_END"
+	EventStallStart        = "STALL_START"
+	EventStallEnd          = "STALL_END"
+	EventHold              = "HOLD"
+	EventBackpressureOn    = "BACKPRESSURE_ON"
+	EventBackpressureOff   = "BACKPRESSURE_OFF"
+	EventBreakerOpen       = "BREAKER_OPEN"
+	EventBreakerHalfOpen   = "BREAKER_HALF_OPEN"
+	EventBreakerClose      = "BREAKER_CLOSE"
+	EventFail              = "FAIL"
+	EventRework            = "REWORK"
+	EventAffinityWait      = "AFFINITY_WAIT"
+	EventPriorityInversion = "PRIORITY_INVERSION"
+	EventStageEnter        = "STAGE_ENTER"
+	EventShadowDiverge     = "SHADOW_DIVERGE"
 )
 
 const (
-	ReasonQueueAdmission   = "QUEUE_ADMISSION"
-	ReasonPrioritySchedule = "PRIORITY_SCHEDULE"
-	ReasonServiceComplete  = "SERVICE_COMPLETE"
-	ReasonRejectOverload   = "REJECT_OVERLOAD"
+	ReasonQueueAdmission     = "QUEUE_ADMISSION"
+	ReasonPrioritySchedule   = "PRIORITY_SCHEDULE"
+	ReasonLIFOSchedule       = "LIFO_SCHEDULE"
+	ReasonSJFSchedule        = "SJF_SCHEDULE"
+	ReasonPSSchedule         = "PS_SCHEDULE"
+	ReasonServiceComplete    = "SERVICE_COMPLETE"
+	ReasonRejectOverload     = "REJECT_OVERLOAD"
+	ReasonQuotaExceeded      = "REJECT_QUOTA"
+	ReasonStarvationWait     = "STARVATION_WAIT"
+	ReasonSLOBurn            = "SLO_BURN_RATE"
+	ReasonPositionImproved   = "QUEUE_POSITION_IMPROVED"
+	ReasonSustainedOverload  = "SUSTAINED_OVERLOAD"
+	ReasonOverloadCleared    = "OVERLOAD_CLEARED"
+	ReasonHedgeDelay         = "HEDGE_DELAY"
+	ReasonHedgeLost          = "HEDGE_LOST"
+	ReasonDuplicateDropped   = "DUPLICATE_DROPPED"
+	ReasonDuplicateCoalesced = "DUPLICATE_COALESCED"
+	ReasonScaleUp            = "SCALE_UP"
+	ReasonWarmupComplete     = "WARMUP_COMPLETE"
+	ReasonScaleDown          = "SCALE_DOWN"
+	ReasonDrainComplete      = "DRAIN_COMPLETE"
+	ReasonStallInjected      = "STALL_INJECTED"
+	ReasonStallResumed       = "STALL_RESUMED"
+	ReasonBackpressureHold   = "BACKPRESSURE_HOLD"
+	ReasonBackpressureShed   = "BACKPRESSURE_SHED"
+	ReasonDownstreamFull     = "DOWNSTREAM_QUEUE_FULL"
+	ReasonDownstreamDrained  = "DOWNSTREAM_QUEUE_DRAINED"
+	ReasonCircuitOpen        = "CIRCUIT_OPEN"
+	ReasonFailureRate        = "FAILURE_RATE_EXCEEDED"
+	ReasonBreakerCooldown    = "BREAKER_COOLDOWN_ELAPSED"
+	ReasonProbeFailed        = "PROBE_FAILED"
+	ReasonProbesSucceeded    = "PROBES_SUCCEEDED"
+	ReasonServiceError       = "SERVICE_ERROR"
+	ReasonReworkRequired     = "REWORK_REQUIRED"
+	ReasonAffinityWait       = "SERVER_BUSY"
+	ReasonAffinitySchedule   = "AFFINITY_SCHEDULE"
+	ReasonAffinityInversion  = "AFFINITY_INVERSION"
+	ReasonPolicyInversion    = "POLICY_INVERSION"
+	ReasonScriptSchedule     = "SCRIPT_SCHEDULE"
+	ReasonScriptReject       = "SCRIPT_REJECT"
+	ReasonPluginSchedule     = "PLUGIN_SCHEDULE"
+	ReasonPluginReject       = "PLUGIN_REJECT"
+	ReasonExternalSchedule   = "EXTERNAL_SCHEDULE"
+	ReasonExternalReject     = "EXTERNAL_REJECT"
+	ReasonStageAdmitted      = "STAGE_ADMITTED"
+	ReasonStageRefused       = "STAGE_REFUSED"
+	ReasonStageError         = "STAGE_ERROR"
+	ReasonShadowDiverged     = "SHADOW_DIVERGED"
+)
+
+const (
+	RuleAdmit            = "admit"
+	RuleAnonQueueLimit   = "anon_queue_limit"
+	RulePaidFirst        = "paid_first"
+	RuleFreeBeforeAnon   = "free_before_anon"
+	RuleAnonWhenIdle     = "anon_when_idle"
+	RulePriorityOrder    = "priority_order"
+	RuleClassQueueLimit  = "class_queue_limit"
+	RuleStarvation       = "starvation_threshold"
+	RuleSLOBurnRate      = "slo_burn_rate"
+	RulePositionStep     = "position_step"
+	RuleDegradeUnderLoad = "degrade_under_load"
+	RuleClassQuota       = "class_quota"
+	RuleHedge            = "hedge_after"
+	RuleDedupDrop        = "dedup_drop"
+	RuleDedupCoalesce    = "dedup_coalesce"
+	RuleScaleStep        = "scale_step"
+	RuleStall            = "stall"
+	RuleBackpressure     = "backpressure"
+	RuleCircuitBreaker   = "circuit_breaker"
+	RuleErrorRate        = "error_rate"
+	RuleRework           = "rework"
+	RuleAffinity         = "session_affinity"
+	RuleInversion        = "priority_inversion"
+	RuleScriptSchedule   = "script_schedule"
+	RuleScriptAdmit      = "script_admit"
+	RulePluginSchedule   = "plugin_schedule"
+	RulePluginAdmit      = "plugin_admit"
+	RuleExternalSchedule = "external_schedule"
+	RuleExternalAdmit    = "external_admit"
+	RuleCustomStage      = "custom_stage"
+	RuleShadowPolicy     = "shadow_policy"
 )
 
 type Artifact struct {
 	Metadata  Metadata   `json:"metadata"`
 	Snapshots []Snapshot `json:"snapshots"`
 	Events    []Event    `json:"events"`
+	Signature *Signature `json:"signature,omitempty"`
 }
 
 type Metadata struct {
-	ScenarioID      string `json:"scenario_id"`
-	Seed            int64  `json:"seed"`
-	EngineVersion   string `json:"engine_version"`
-	ReplayID        string `json:"replay_id"`
-	TickCount       int    `json:"tick_count"`
-	TickDurationMs  int    `json:"tick_duration_ms"`
-	TotalDurationMs int    `json:"total_duration_ms"`
+	ScenarioID       string            `json:"scenario_id"`
+	Seed             int64             `json:"seed"`
+	EngineVersion    string            `json:"engine_version"`
+	ReplayID         string            `json:"replay_id"`
+	Scenario         *Scenario         `json:"scenario,omitempty"`
+	Classes          []ClassDef        `json:"classes,omitempty"`
+	TickCount        int               `json:"tick_count"`
+	TickDurationMs   int               `json:"tick_duration_ms"`
+	TotalDurationMs  int               `json:"total_duration_ms"`
+	WarmupTicks      int               `json:"warmup_ticks,omitempty"`
+	SnapshotSchema   string            `json:"snapshot_schema,omitempty"`
+	SnapshotInterval int               `json:"snapshot_interval,omitempty"`
+	ETA              bool              `json:"eta,omitempty"`
+	TraceIDs         bool              `json:"trace_ids,omitempty"`
+	ClassBreakdown   bool              `json:"class_breakdown,omitempty"`
+	TokenIDs         *TokenIDs         `json:"token_ids,omitempty"`
+	Epoch            *time.Time        `json:"epoch,omitempty"`
+	EventFilters     []EventFilter     `json:"event_filters,omitempty"`
+	EventRetention   *EventRetention   `json:"event_retention,omitempty"`
+	Reasons          []Reason          `json:"reasons,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	Build            *BuildInfo        `json:"build,omitempty"`
+	Presentation     *Presentation     `json:"presentation,omitempty"`
+	Slice            *SliceRange       `json:"slice,omitempty"`
+	Caveats          []string          `json:"caveats,omitempty"`
 }
 
 type Snapshot struct {
 	Tick   int          `json:"tick"`
 	TimeMs int          `json:"time_ms"`
+	Time   string       `json:"time,omitempty"`
 	Tokens []TokenState `json:"tokens"`
 	Stages []StageState `json:"stages"`
+	Warmup bool         `json:"warmup,omitempty"`
+	Ledger *Ledger      `json:"ledger,omitempty"`
 }
 
 type TokenState struct {
@@ -73,20 +215,79 @@ type TokenState struct {
 	StageID          string `json:"stage_id"`
 	QueueIndex       int    `json:"queue_index"`
 	ServiceRemaining int    `json:"service_remaining"`
+	EtaMs            int    `json:"eta_ms,omitempty"`
+	Ticket           int    `json:"ticket,omitempty"`
+	HedgeOf          string `json:"hedge_of,omitempty"`
+	RequestKey       string `json:"request_key,omitempty"`
+	Cycle            int    `json:"cycle,omitempty"`
+	Session          string `json:"session,omitempty"`
+	Server           string `json:"server,omitempty"`
+	TraceID          string `json:"trace_id,omitempty"`
 }
 
 type StageState struct {
-	ID            string `json:"id"`
-	QueueLength   int    `json:"queue_length"`
-	CapacityUsed  int    `json:"capacity_used"`
-	CapacityTotal int    `json:"capacity_total"`
+	ID            string       `json:"id"`
+	QueueLength   int          `json:"queue_length"`
+	CapacityUsed  int          `json:"capacity_used"`
+	CapacityTotal int          `json:"capacity_total"`
+	Quotas        []QuotaState `json:"quotas,omitempty"`
+	Warming       int          `json:"warming,omitempty"`
+	Draining      int          `json:"draining,omitempty"`
+	Stalled       int          `json:"stalled,omitempty"`
+	Breaker       string       `json:"breaker,omitempty"`
+	UnitsUsed     int          `json:"units_used,omitempty"`
+	UnitsTotal    int          `json:"units_total,omitempty"`
+	Classes       []ClassLoad  `json:"classes,omitempty"`
+}
+
+type ClassLoad struct {
+	Class        string `json:"class"`
+	QueueLength  int    `json:"queue_length"`
+	CapacityUsed int    `json:"capacity_used"`
+	UnitsUsed    int    `json:"units_used,omitempty"`
 }
 
 type Event struct {
-	Tick       int    `json:"tick"`
-	Type       string `json:"type"`
-	ReasonCode string `json:"reason_code"`
-	TokenID    string `json:"token_id"`
-	StageID    string `json:"stage_id"`
-	Class      string `json:"class"`
+	Tick       int           `json:"tick"`
+	Type       string        `json:"type"`
+	ReasonCode string        `json:"reason_code"`
+	TokenID    string        `json:"token_id"`
+	StageID    string        `json:"stage_id"`
+	Class      string        `json:"class"`
+	Quality    string        `json:"quality,omitempty"`
+	Warmup     bool          `json:"warmup,omitempty"`
+	TraceID    string        `json:"trace_id,omitempty"`
+	Time       string        `json:"time,omitempty"`
+	Context    *EventContext `json:"context,omitempty"`
+}
+
+const MaxAheadListed = 5
+
+type EventContext struct {
+	Rule             string   `json:"rule"`
+	QueueLength      int      `json:"queue_length"`
+	AheadCount       int      `json:"ahead_count,omitempty"`
+	Ahead            []string `json:"ahead,omitempty"`
+	Limit            int      `json:"limit,omitempty"`
+	CapacityUsed     int      `json:"capacity_used,omitempty"`
+	WaitTicks        int      `json:"wait_ticks,omitempty"`
+	WindowTicks      int      `json:"window_ticks,omitempty"`
+	Bypassed         int      `json:"bypassed,omitempty"`
+	SLO              string   `json:"slo,omitempty"`
+	BurnRate         float64  `json:"burn_rate,omitempty"`
+	Missed           int      `json:"missed,omitempty"`
+	Observed         int      `json:"observed,omitempty"`
+	Ticket           int      `json:"ticket,omitempty"`
+	Position         int      `json:"position,omitempty"`
+	PreviousPosition int      `json:"previous_position,omitempty"`
+	Hedge            string   `json:"hedge,omitempty"`
+	Cancelled        string   `json:"cancelled,omitempty"`
+	RequestKey       string   `json:"request_key,omitempty"`
+	Servers          int      `json:"servers,omitempty"`
+	Held             int      `json:"held,omitempty"`
+	Cycle            int      `json:"cycle,omitempty"`
+	Session          string   `json:"session,omitempty"`
+	Server           string   `json:"server,omitempty"`
+	Overtaken        string   `json:"overtaken,omitempty"`
+	Shadow           string   `json:"shadow,omitempty"`
 }
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+func ValidateArtifact(artifact Artifact) error {
+	var errs []error
+	problem := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	m := artifact.Metadata
+	if m.EngineVersion == "" {
+		problem("metadata: engine_version is empty")
+	}
+	if m.Scenario != nil {
+		if m.Scenario.ID != m.ScenarioID {
+			problem("metadata: scenario.id %q does not match scenario_id %q", m.Scenario.ID, m.ScenarioID)
+		}
+		if err := m.Scenario.Validate(); err != nil {
+			problem("metadata: scenario: %v", err)
+		} else if want, err := m.Scenario.ReplayID(m.Seed, m.EngineVersion); err != nil {
+			problem("metadata: scenario: %v", err)
+		} else if m.ReplayID != want {
+			problem("metadata: replay_id %s does not match the embedded scenario, seed and engine version (want %s)", m.ReplayID, want)
+		}
+	} else if want := ReplayID(m.ScenarioID, m.Seed, m.EngineVersion); m.ReplayID != want {
+		problem("metadata: replay_id %s does not match scenario, seed and engine version (want %s)", m.ReplayID, want)
+	}
+	if m.TickCount <= 0 || m.TickDurationMs <= 0 {
+		problem("metadata: tick_count %d and tick_duration_ms %d must be positive", m.TickCount, m.TickDurationMs)
+	}
+	if m.TotalDurationMs != m.TickCount*m.TickDurationMs {
+		problem("metadata: total_duration_ms %d, want tick_count * tick_duration_ms = %d", m.TotalDurationMs, m.TickCount*m.TickDurationMs)
+	}
+	switch m.SnapshotSchema {
+	case "", SnapshotSchemaFull, SnapshotSchemaActive:
+	default:
+		problem("metadata: unknown snapshot_schema %q", m.SnapshotSchema)
+	}
+
+	if len(artifact.Snapshots) == 0 {
+		problem("snapshots: none recorded")
+	}
+	prev := -1
+	for i, snapshot := range artifact.Snapshots {
+		if snapshot.Tick <= prev || snapshot.Tick >= m.TickCount {
+			problem("snapshots[%d]: tick %d is out of order or outside [0, %d)", i, snapshot.Tick, m.TickCount)
+		}
+		if snapshot.TimeMs != snapshot.Tick*m.TickDurationMs {
+			problem("snapshots[%d]: time_ms %d, want %d", i, snapshot.TimeMs, snapshot.Tick*m.TickDurationMs)
+		}
+		for _, stage := range snapshot.Stages {
+			if len(stage.Classes) == 0 {
+				continue
+			}
+			queued, used := 0, 0
+			for _, class := range stage.Classes {
+				queued += class.QueueLength
+				used += class.CapacityUsed
+			}
+			if queued != stage.QueueLength || used != stage.CapacityUsed {
+				problem("snapshots[%d]: stage %s classes add up to queue_length %d and capacity_used %d, want %d and %d", i, stage.ID, queued, used, stage.QueueLength, stage.CapacityUsed)
+			}
+		}
+		prev = snapshot.Tick
+	}
+
+	if len(artifact.Events) == 0 {
+		problem("events: none recorded")
+	}
+	prev = 0
+	for i, event := range artifact.Events {
+		if event.Tick < prev || event.Tick >= m.TickCount {
+			problem("events[%d]: tick %d is out of order or outside [0, %d)", i, event.Tick, m.TickCount)
+		}
+		if event.Type == "" || event.TokenID == "" && !ServerEvent(event.Type) {
+			problem("events[%d]: type and token_id are required", i)
+		}
+		if _, ok := m.reason(event.ReasonCode); !ok {
+			problem("events[%d]: reason_code %q is neither a core code nor in metadata.reasons", i, event.ReasonCode)
+		}
+		prev = event.Tick
+	}
+	return errors.Join(errs...)
+}
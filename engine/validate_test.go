@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateArtifact(t *testing.T) {
+	valid, err := Run(Config{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(a *Artifact)
+		want   string
+	}{
+		{name: "valid", modify: func(a *Artifact) {}},
+		{name: "replay id", modify: func(a *Artifact) { a.Metadata.Seed = 2 }, want: "replay_id"},
+		{name: "schema", modify: func(a *Artifact) { a.Metadata.SnapshotSchema = "sparse" }, want: "snapshot_schema"},
+		{name: "snapshot order", modify: func(a *Artifact) { a.Snapshots[3].Tick = 1 }, want: "snapshots[3]"},
+		{name: "stage classes", modify: func(a *Artifact) {
+			a.Snapshots[3].Stages = []StageState{{ID: StageQueue, QueueLength: 2, Classes: []ClassLoad{{Class: ClassAnon, QueueLength: 1}}}}
+		}, want: "stage queue classes"},
+		{name: "event order", modify: func(a *Artifact) { a.Events[5].Tick = 200 }, want: "events[6]"},
+		{name: "event token", modify: func(a *Artifact) { a.Events[0].TokenID = "" }, want: "events[0]"},
+		{name: "no events", modify: func(a *Artifact) { a.Events = nil }, want: "events: none"},
+	}
+	for _, tt := range tests {
+		artifact := valid
+		artifact.Snapshots = append([]Snapshot(nil), valid.Snapshots...)
+		artifact.Events = append([]Event(nil), valid.Events...)
+		tt.modify(&artifact)
+
+		err := ValidateArtifact(artifact)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: ValidateArtifact() = %v, want nil", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ValidateArtifact() = %v, want mention of %s", tt.name, err, tt.want)
+		}
+	}
+}
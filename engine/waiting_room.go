@@ -0,0 +1,63 @@
+package engine
+
+import "fmt"
+
+type WaitingRoom struct {
+	PositionStep int `json:"position_step" yaml:"position_step"`
+}
+
+func (w WaitingRoom) validate() []error {
+	if w.PositionStep < 0 {
+		return []error{fmt.Errorf("waiting_room: position_step must not be negative, got %d", w.PositionStep)}
+	}
+	return nil
+}
+
+func (s *Simulator) issueTicket(token *Token) {
+	if s.scenario.WaitingRoom == nil {
+		return
+	}
+	lane := s.classes.lane(token.Class)
+	s.tickets[lane]++
+	token.Ticket = s.tickets[lane]
+	token.reportedIndex = -1
+}
+
+func (s *Simulator) updatePositions(tick int) {
+	room := s.scenario.WaitingRoom
+	if room == nil {
+		return
+	}
+	s.queue.each(func(index int, token *Token) bool {
+		if token.hedgeOf != nil {
+			return true
+		}
+		if token.reportedIndex < 0 {
+			token.reportedIndex = index
+			return true
+		}
+		if token.reportedIndex-index <= room.PositionStep {
+			return true
+		}
+		context := s.newContext()
+		*context = EventContext{
+			Rule:             RulePositionStep,
+			QueueLength:      s.queueLength(),
+			Limit:            room.PositionStep,
+			Ticket:           token.Ticket,
+			Position:         index + 1,
+			PreviousPosition: token.reportedIndex + 1,
+		}
+		token.reportedIndex = index
+		s.emit(Event{
+			Tick:       tick,
+			Type:       EventPositionUpdate,
+			ReasonCode: ReasonPositionImproved,
+			TokenID:    token.ID,
+			StageID:    StageQueue,
+			Class:      token.Class,
+			Context:    context,
+		})
+		return true
+	})
+}
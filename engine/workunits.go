@@ -0,0 +1,58 @@
+package engine
+
+import "fmt"
+
+type WorkUnits struct {
+	Capacity int `json:"capacity" yaml:"capacity"`
+}
+
+func (w WorkUnits) validate() []error {
+	if w.Capacity <= 0 {
+		return []error{fmt.Errorf("work_units: capacity must be positive, got %d", w.Capacity)}
+	}
+	return nil
+}
+
+func (s Scenario) sizedJobs() bool {
+	return s.WorkUnits != nil || s.queueDiscipline() == DisciplineSJF || (s.Script != nil && s.Script.Schedule != "") || s.Plugin != nil || (s.PolicyProcess != nil && s.PolicyProcess.Schedule)
+}
+
+func (s *Simulator) unitsFor(token *Token) int {
+	return min(max(token.size, 1), s.scenario.WorkUnits.Capacity)
+}
+
+func (s *Simulator) unitsInUse() int {
+	used := 0
+	for _, token := range s.inService {
+		used += s.unitsFor(token)
+	}
+	for _, token := range s.transit {
+		if token.transitTo == StageService {
+			used += s.unitsFor(token)
+		}
+	}
+	return used
+}
+
+func (s *Simulator) unitsFit() bool {
+	if s.scenario.WorkUnits == nil {
+		return true
+	}
+	next := s.queue.peek()
+	return next == nil || s.unitsInUse()+s.unitsFor(next) <= s.scenario.WorkUnits.Capacity
+}
+
+func (s StageState) Load() (used, total int) {
+	if s.UnitsTotal > 0 {
+		return s.UnitsUsed, s.UnitsTotal
+	}
+	return s.CapacityUsed, s.CapacityTotal
+}
+
+func (s StageState) Utilization() float64 {
+	used, total := s.Load()
+	if total == 0 {
+		return 0
+	}
+	return float64(used) / float64(total)
+}
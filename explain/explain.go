@@ -0,0 +1,339 @@
+package explain
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"finit/engine"
+)
+
+var classLabels = map[string]string{
+	engine.ClassAnon: "anonymous",
+	engine.ClassFree: "free",
+	engine.ClassPaid: "paid",
+}
+
+var ruleDescriptions = map[string]string{
+	engine.RuleAdmit:            "requests are admitted while the queue has room",
+	engine.RuleAnonQueueLimit:   "anonymous requests are turned away once the queue reaches its limit",
+	engine.RulePaidFirst:        "paid requests are served before free and anonymous ones",
+	engine.RuleFreeBeforeAnon:   "free requests are served once no paid request is waiting",
+	engine.RuleAnonWhenIdle:     "anonymous requests are served only when no paid or free request is waiting",
+	engine.RulePriorityOrder:    "requests are served in class priority order, oldest first within a priority",
+	engine.RuleClassQueueLimit:  "requests of this class are turned away once the queue reaches the class limit",
+	engine.RuleStarvation:       "a request that waits this long while higher classes keep being served is flagged as starving",
+	engine.RuleSLOBurnRate:      "an SLO alert fires when its error budget burns faster than the configured rate over the rolling window",
+	engine.RulePositionStep:     "a waiting request is told its new place in line once it moves up by more than this many positions",
+	engine.RuleDegradeUnderLoad: "under sustained overload free and anonymous requests get a faster, lower-quality service",
+	engine.RuleClassQuota:       "each class may only be admitted so many times per rolling window",
+	engine.RuleHedge:            "a request still queued after this many ticks gets a duplicate; the first copy to finish wins and the other is cancelled",
+	engine.RuleDedupDrop:        "a resubmitted request whose key was already seen is dropped",
+	engine.RuleDedupCoalesce:    "a resubmitted request whose key was already seen waits for the original and shares its result",
+	engine.RuleScaleStep:        "added servers warm up before taking work and removed servers drain their current requests first",
+	engine.RuleStall:            "a stalled server makes no progress on its request and takes no new work until the stall ends",
+	engine.RuleCircuitBreaker:   "while the breaker is open new requests are rejected immediately; once it cools down a few probes decide whether it closes again",
+	engine.RuleErrorRate:        "each completion of this class fails with the class error rate, and a failed request may be retried after a delay",
+	engine.RuleRework:           "a completed request is sent back to the queue for another pass with this probability, up to the cycle limit",
+	engine.RuleAffinity:         "every request of a session is served by the server that served the session first, even if it has to wait for it",
+	engine.RuleInversion:        "a lower-priority request started while this one was still waiting",
+	engine.RuleBackpressure:     "new requests are held back or shed while the queue is at its limit, until it drains to the release length",
+	engine.RuleScriptSchedule:   "the scenario's schedule script scores every waiting request and the lowest score is served next",
+	engine.RuleScriptAdmit:      "the scenario's admit script decides whether each arriving request may join the queue",
+	engine.RulePluginSchedule:   "the scenario's WASM plugin scores every waiting request and the lowest score is served next",
+	engine.RulePluginAdmit:      "the scenario's WASM plugin decides whether each arriving request may join the queue",
+	engine.RuleExternalSchedule: "the scenario's policy process picks which waiting request is served next",
+	engine.RuleExternalAdmit:    "the scenario's policy process decides whether each arriving request may join the queue",
+	engine.RuleShadowPolicy:     "a shadow policy is evaluated on every scheduling decision without acting on it, and each disagreement is recorded",
+	engine.RuleCustomStage:      "a custom stage added by the embedding program takes each request after service and may delay, fail or refuse it",
+}
+
+type Step struct {
+	Tick   int
+	TimeMs int
+	Text   string
+}
+
+type Narrative struct {
+	TokenID string
+	Class   string
+	Steps   []Step
+}
+
+func Token(artifact engine.Artifact, tokenID string) (Narrative, error) {
+	tickMs := artifact.Metadata.TickDurationMs
+	narrative := Narrative{TokenID: tokenID}
+
+	scheduledAt, arrivedAt := -1, -1
+	for _, event := range artifact.Events {
+		if event.TokenID != tokenID {
+			continue
+		}
+		narrative.Class = event.Class
+		if arrivedAt < 0 {
+			arrivedAt = event.Tick
+		}
+
+		var text string
+		switch event.Type {
+		case engine.EventQueue:
+			text = describeQueue(event)
+		case engine.EventReject:
+			text = describeReject(event)
+		case engine.EventSchedule:
+			if event.Context != nil && event.Context.Hedge != "" {
+				text = fmt.Sprintf("its hedge %s started service after waiting %s.", event.Context.Hedge, ticks(event.Context.WaitTicks, tickMs))
+				break
+			}
+			scheduledAt = event.Tick
+			text = describeSchedule(event, event.Tick-arrivedAt, tickMs)
+		case engine.EventComplete:
+			text = describeComplete(event, scheduledAt, arrivedAt, tickMs)
+		case engine.EventStarvationWarning:
+			text = describeStarvation(event, tickMs)
+		case engine.EventSLOAlert:
+			text = describeSLOAlert(event)
+		case engine.EventPositionUpdate:
+			text = describePositionUpdate(event)
+		case engine.EventDegradeStart, engine.EventDegradeEnd:
+			text = describeDegrade(event)
+		case engine.EventHedgeSpawn:
+			text = describeHedgeSpawn(event, tickMs)
+		case engine.EventHedgeCancel:
+			text = describeHedgeCancel(event)
+		case engine.EventDuplicateDrop:
+			text = describeDuplicate(event)
+		case engine.EventHold:
+			text = describeHold(event)
+		case engine.EventAffinityWait:
+			text = describeAffinityWait(event)
+		case engine.EventFail:
+			text = describeFail(event, arrivedAt, tickMs)
+		case engine.EventShadowDiverge:
+			text = describeShadow(event)
+		default:
+			text = fmt.Sprintf("%s (%s): %s.", event.Type, event.ReasonCode, artifact.Metadata.ReasonDescription(event.ReasonCode))
+		}
+		narrative.Steps = append(narrative.Steps, Step{
+			Tick:   event.Tick,
+			TimeMs: event.Tick * tickMs,
+			Text:   text,
+		})
+	}
+
+	if len(narrative.Steps) == 0 {
+		return Narrative{}, fmt.Errorf("token %s not found in artifact", tokenID)
+	}
+
+	last := artifact.Events[len(artifact.Events)-1].Tick
+	if final := narrative.Steps[len(narrative.Steps)-1]; final.Tick < last && !terminal(artifact.Events, tokenID) {
+		narrative.Steps = append(narrative.Steps, Step{
+			Tick:   last,
+			TimeMs: last * tickMs,
+			Text:   "the run ended before this request finished.",
+		})
+	}
+	return narrative, nil
+}
+
+func Write(w io.Writer, narrative Narrative) error {
+	label := classLabels[narrative.Class]
+	if label == "" {
+		label = strings.ToLower(narrative.Class)
+	}
+	if _, err := fmt.Fprintf(w, "%s (%s request)\n", narrative.TokenID, label); err != nil {
+		return err
+	}
+	for _, step := range narrative.Steps {
+		if _, err := fmt.Fprintf(w, "  tick %3d (%6.2fs)  %s\n", step.Tick, float64(step.TimeMs)/1000, step.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func describeQueue(event engine.Event) string {
+	if event.Context == nil {
+		return "arrived and joined the queue."
+	}
+	c := event.Context
+	if c.AheadCount == 0 {
+		return fmt.Sprintf("arrived and joined the queue at the front (%d waiting).", c.QueueLength)
+	}
+	suffix := ""
+	if more := c.AheadCount - len(c.Ahead); more > 0 {
+		suffix = fmt.Sprintf(" and %d more", more)
+	}
+	return fmt.Sprintf("arrived and joined the queue at position %d of %d, behind %s%s.",
+		c.AheadCount+1, c.QueueLength, strings.Join(c.Ahead, ", "), suffix)
+}
+
+func describeReject(event engine.Event) string {
+	if event.Context == nil {
+		return "was turned away on arrival because the system was overloaded."
+	}
+	if c := event.Context; c.Rule == engine.RuleClassQuota {
+		return fmt.Sprintf("was turned away on arrival: its class already used its quota of %d admissions in the last %d ticks — rule %s: %s.",
+			c.Limit, c.WindowTicks, c.Rule, ruleDescriptions[c.Rule])
+	}
+	return fmt.Sprintf("was turned away on arrival: %d requests were already queued (limit %d) — rule %s: %s.",
+		event.Context.QueueLength, event.Context.Limit, event.Context.Rule, ruleDescriptions[event.Context.Rule])
+}
+
+func describeSchedule(event engine.Event, waited int, tickMs int) string {
+	if event.Context != nil {
+		waited = event.Context.WaitTicks
+	}
+	action := "started service"
+	if event.StageID == engine.StageTransit {
+		action = "was dispatched to a server (in transit)"
+	}
+	text := fmt.Sprintf("%s after waiting %s", action, ticks(waited, tickMs))
+	if event.Context == nil {
+		return text + "."
+	}
+	text += fmt.Sprintf(" — rule %s: %s", event.Context.Rule, ruleDescriptions[event.Context.Rule])
+	if event.Context.Bypassed > 0 {
+		text += fmt.Sprintf("; moved ahead of %d request(s) that arrived earlier", event.Context.Bypassed)
+	}
+	return text + "."
+}
+
+func describeStarvation(event engine.Event, tickMs int) string {
+	if event.Context == nil {
+		return "was flagged as starving in the queue."
+	}
+	return fmt.Sprintf("was flagged as starving after waiting %s, past the %d-tick threshold, while %d higher-priority request(s) were served — rule %s: %s.",
+		ticks(event.Context.WaitTicks, tickMs), event.Context.Limit, event.Context.Bypassed, event.Context.Rule, ruleDescriptions[event.Context.Rule])
+}
+
+func describeSLOAlert(event engine.Event) string {
+	if event.Context == nil {
+		return "tipped an SLO into alerting."
+	}
+	c := event.Context
+	return fmt.Sprintf("tipped SLO %s into alerting: %d of the last %d requests missed %d ms, burning error budget at %.2fx — rule %s: %s.",
+		c.SLO, c.Missed, c.Observed, c.Limit, c.BurnRate, c.Rule, ruleDescriptions[c.Rule])
+}
+
+func describePositionUpdate(event engine.Event) string {
+	if event.Context == nil {
+		return "moved up in the queue."
+	}
+	c := event.Context
+	return fmt.Sprintf("ticket %d moved up from position %d to %d of %d, more than the %d-position step — rule %s: %s.",
+		c.Ticket, c.PreviousPosition, c.Position, c.QueueLength, c.Limit, c.Rule, ruleDescriptions[c.Rule])
+}
+
+func describeDegrade(event engine.Event) string {
+	action := "switched service to degraded mode"
+	if event.Type == engine.EventDegradeEnd {
+		action = "restored full service"
+	}
+	if event.Context == nil {
+		return action + "."
+	}
+	c := event.Context
+	return fmt.Sprintf("%s with %d requests queued (overload at %d) — rule %s: %s.", action, c.QueueLength, c.Limit, c.Rule, ruleDescriptions[c.Rule])
+}
+
+func describeHedgeSpawn(event engine.Event, tickMs int) string {
+	if event.Context == nil {
+		return "spawned a hedge copy."
+	}
+	c := event.Context
+	return fmt.Sprintf("spawned hedge %s after waiting %s with %d requests queued — rule %s: %s.",
+		c.Hedge, ticks(c.WaitTicks, tickMs), c.QueueLength, c.Rule, ruleDescriptions[c.Rule])
+}
+
+func describeHedgeCancel(event engine.Event) string {
+	if event.Context == nil {
+		return "cancelled its slower copy."
+	}
+	return fmt.Sprintf("cancelled the slower copy %s.", event.Context.Cancelled)
+}
+
+func describeDuplicate(event engine.Event) string {
+	if event.Context == nil {
+		return "was recognized as a duplicate submission."
+	}
+	c := event.Context
+	action := "was dropped"
+	if c.Rule == engine.RuleDedupCoalesce {
+		action = "was coalesced"
+	}
+	return fmt.Sprintf("%s as a duplicate of %s — rule %s: %s.", action, c.RequestKey, c.Rule, ruleDescriptions[c.Rule])
+}
+
+func describeHold(event engine.Event) string {
+	if event.Context == nil {
+		return "was held back at the source by backpressure."
+	}
+	c := event.Context
+	return fmt.Sprintf("was held back at the source (number %d in line) because %d requests were already queued (limit %d) — rule %s: %s.",
+		c.Held, c.QueueLength, c.Limit, c.Rule, ruleDescriptions[c.Rule])
+}
+
+func describeAffinityWait(event engine.Event) string {
+	if event.Context == nil {
+		return "waited for the server its session is pinned to."
+	}
+	c := event.Context
+	return fmt.Sprintf("waited for server %s, which %s is pinned to, while another server was free — rule %s: %s.",
+		c.Server, c.Session, c.Rule, ruleDescriptions[c.Rule])
+}
+
+func describeShadow(event engine.Event) string {
+	if event.Context == nil {
+		return "was chosen where the shadow policy would have chosen another request."
+	}
+	c := event.Context
+	return fmt.Sprintf("was chosen where the shadow policy would have served %s — rule %s: %s.", c.Shadow, c.Rule, ruleDescriptions[c.Rule])
+}
+
+func describeFail(event engine.Event, arrivedAt int, tickMs int) string {
+	if event.Context == nil {
+		return "failed with a service error."
+	}
+	c := event.Context
+	return fmt.Sprintf("failed with a service error after %s in the system — rule %s: %s.",
+		ticks(event.Tick-arrivedAt, tickMs), c.Rule, ruleDescriptions[c.Rule])
+}
+
+func describeComplete(event engine.Event, scheduledAt int, arrivedAt int, tickMs int) string {
+	if c := event.Context; c != nil && c.Rule == engine.RuleDedupCoalesce {
+		return fmt.Sprintf("completed with the original request %s; %s in the system overall.", c.RequestKey, ticks(event.Tick-arrivedAt, tickMs))
+	}
+	if scheduledAt < 0 {
+		return "completed."
+	}
+	quality := ""
+	if event.Quality == engine.QualityDegraded {
+		quality = " at degraded quality"
+	}
+	if event.Context != nil && event.Context.Hedge != "" {
+		quality += " through its hedge " + event.Context.Hedge
+	}
+	return fmt.Sprintf("completed%s after %s of service; %s in the system overall.",
+		quality, ticks(event.Tick-scheduledAt, tickMs), ticks(event.Tick-arrivedAt, tickMs))
+}
+
+func ticks(n int, tickMs int) string {
+	unit := "ticks"
+	if n == 1 {
+		unit = "tick"
+	}
+	return fmt.Sprintf("%d %s (%d ms)", n, unit, n*tickMs)
+}
+
+func terminal(events []engine.Event, tokenID string) bool {
+	for _, event := range events {
+		if event.TokenID != tokenID {
+			continue
+		}
+		if event.Type == engine.EventComplete || event.Type == engine.EventReject || event.ReasonCode == engine.ReasonDuplicateDropped {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package explain
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestToken(t *testing.T) {
+	artifact := engine.Artifact{
+		Metadata: engine.Metadata{TickDurationMs: 250},
+		Events: []engine.Event{
+			{Tick: 3, Type: engine.EventQueue, TokenID: "T0001", Class: engine.ClassFree,
+				Context: &engine.EventContext{Rule: engine.RuleAdmit, QueueLength: 2, AheadCount: 1, Ahead: []string{"T0000"}}},
+			{Tick: 4, Type: engine.EventStarvationWarning, TokenID: "T0001", Class: engine.ClassFree,
+				Context: &engine.EventContext{Rule: engine.RuleStarvation, QueueLength: 2, Limit: 1, WaitTicks: 1, Bypassed: 3}},
+			{Tick: 5, Type: engine.EventSchedule, TokenID: "T0001", Class: engine.ClassFree,
+				Context: &engine.EventContext{Rule: engine.RuleFreeBeforeAnon, QueueLength: 1, WaitTicks: 2, Bypassed: 1}},
+			{Tick: 6, Type: engine.EventComplete, TokenID: "T0001", Class: engine.ClassFree},
+			{Tick: 6, Type: engine.EventReject, TokenID: "T0002", Class: engine.ClassAnon,
+				Context: &engine.EventContext{Rule: engine.RuleAnonQueueLimit, QueueLength: 12, Limit: 12}},
+		},
+	}
+
+	narrative, err := Token(artifact, "T0001")
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if len(narrative.Steps) != 4 {
+		t.Fatalf("Token() steps = %d, want 4", len(narrative.Steps))
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, narrative); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	for _, want := range []string{
+		"T0001 (free request)",
+		"position 2 of 2, behind T0000",
+		"starving after waiting 1 tick (250 ms), past the 1-tick threshold, while 3 higher-priority request(s)",
+		"waiting 2 ticks (500 ms) — rule free_before_anon",
+		"moved ahead of 1 request(s)",
+		"1 tick (250 ms) of service; 3 ticks (750 ms) in the system",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Write() missing %q in:\n%s", want, buf.String())
+		}
+	}
+
+	rejected, err := Token(artifact, "T0002")
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if !strings.Contains(rejected.Steps[0].Text, "limit 12") {
+		t.Errorf("reject step = %q", rejected.Steps[0].Text)
+	}
+
+	if _, err := Token(artifact, "T9999"); err == nil {
+		t.Error("Token() for unknown id should fail")
+	}
+}
@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"finit/engine"
+)
+
+var WaitBuckets = []float64{0, 0.25, 0.5, 1, 2, 5, 10, 30}
+
+type series struct {
+	runID    string
+	scenario string
+	class    string
+}
+
+type histogram struct {
+	counts []int
+	count  int
+	sum    float64
+}
+
+type Registry struct {
+	mu          sync.Mutex
+	runs        map[string]int
+	completions map[series]int
+	rejections  map[series]int
+	failures    map[series]int
+	waits       map[series]*histogram
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		runs:        make(map[string]int),
+		completions: make(map[series]int),
+		rejections:  make(map[series]int),
+		failures:    make(map[series]int),
+		waits:       make(map[series]*histogram),
+	}
+}
+
+func (r *Registry) Observe(artifact engine.Artifact) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	runID := artifact.Metadata.ReplayID
+	scenario := artifact.Metadata.ScenarioID
+	tickSeconds := float64(artifact.Metadata.TickDurationMs) / 1000
+	r.runs[scenario]++
+
+	for _, lifecycle := range engine.Lifecycles(artifact.Events) {
+		key := series{runID: runID, scenario: scenario, class: lifecycle.Class}
+		if lifecycle.Completed() {
+			r.completions[key]++
+		}
+		if lifecycle.Rejected() {
+			r.rejections[key]++
+		}
+		if lifecycle.Failed() {
+			r.failures[key]++
+		}
+		if lifecycle.Scheduled() {
+			h, ok := r.waits[key]
+			if !ok {
+				h = &histogram{counts: make([]int, len(WaitBuckets))}
+				r.waits[key] = h
+			}
+			h.observe(float64(lifecycle.WaitTicks()) * tickSeconds)
+		}
+	}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range WaitBuckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := bufio.NewWriter(w)
+
+	header(out, "finit_runs_total", "counter", "Simulation runs observed by this process.")
+	for _, scenario := range sortedKeys(r.runs) {
+		fmt.Fprintf(out, "finit_runs_total{scenario=%q} %d\n", scenario, r.runs[scenario])
+	}
+
+	writeCounter(out, "finit_token_completions_total", "Tokens that completed service.", r.completions)
+	writeCounter(out, "finit_token_rejections_total", "Tokens rejected on admission.", r.rejections)
+	writeCounter(out, "finit_token_failures_total", "Tokens whose service ended in failure.", r.failures)
+
+	header(out, "finit_token_wait_seconds", "histogram", "Simulated time tokens spent queued before service.")
+	for _, key := range sortedSeries(r.waits) {
+		h := r.waits[key]
+		for i, bound := range WaitBuckets {
+			fmt.Fprintf(out, "finit_token_wait_seconds_bucket{%s,le=%q} %d\n", key.labels(), formatFloat(bound), h.counts[i])
+		}
+		fmt.Fprintf(out, "finit_token_wait_seconds_bucket{%s,le=\"+Inf\"} %d\n", key.labels(), h.count)
+		fmt.Fprintf(out, "finit_token_wait_seconds_sum{%s} %s\n", key.labels(), formatFloat(h.sum))
+		fmt.Fprintf(out, "finit_token_wait_seconds_count{%s} %d\n", key.labels(), h.count)
+	}
+
+	return out.Flush()
+}
+
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WriteText(w)
+	})
+}
+
+func (s series) labels() string {
+	return fmt.Sprintf("class=%q,run_id=%q,scenario=%q", s.class, s.runID, s.scenario)
+}
+
+func writeCounter(out io.Writer, name string, help string, values map[series]int) {
+	header(out, name, "counter", help)
+	for _, key := range sortedSeries(values) {
+		fmt.Fprintf(out, "%s{%s} %d\n", name, key.labels(), values[key])
+	}
+}
+
+func header(out io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedSeries[V any](m map[series]V) []series {
+	keys := make([]series, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.Compare(keys[i].labels(), keys[j].labels()) < 0
+	})
+	return keys
+}
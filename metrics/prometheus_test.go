@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	artifact := engine.Artifact{
+		Metadata: engine.Metadata{ScenarioID: engine.ScenarioID, ReplayID: "abc", TickDurationMs: 250},
+		Events: []engine.Event{
+			{Tick: 0, Type: engine.EventQueue, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 0, Type: engine.EventSchedule, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 1, Type: engine.EventComplete, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 1, Type: engine.EventQueue, TokenID: "T0001", Class: engine.ClassAnon},
+			{Tick: 4, Type: engine.EventSchedule, TokenID: "T0001", Class: engine.ClassAnon},
+			{Tick: 2, Type: engine.EventReject, TokenID: "T0002", Class: engine.ClassAnon},
+			{Tick: 2, Type: engine.EventQueue, TokenID: "T0003", Class: engine.ClassPaid},
+			{Tick: 2, Type: engine.EventSchedule, TokenID: "T0003", Class: engine.ClassPaid},
+			{Tick: 3, Type: engine.EventFail, TokenID: "T0003", Class: engine.ClassPaid},
+		},
+	}
+
+	registry := NewRegistry()
+	registry.Observe(artifact)
+
+	var buf bytes.Buffer
+	if err := registry.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	text := buf.String()
+	for _, want := range []string{
+		"# TYPE finit_runs_total counter",
+		`finit_runs_total{scenario="canonical_v1"} 1`,
+		`finit_token_completions_total{class="PAID",run_id="abc",scenario="canonical_v1"} 1`,
+		`finit_token_rejections_total{class="ANON",run_id="abc",scenario="canonical_v1"} 1`,
+		`finit_token_wait_seconds_bucket{class="ANON",run_id="abc",scenario="canonical_v1",le="0.5"} 0`,
+		`finit_token_wait_seconds_bucket{class="ANON",run_id="abc",scenario="canonical_v1",le="1"} 1`,
+		`finit_token_wait_seconds_sum{class="ANON",run_id="abc",scenario="canonical_v1"} 0.75`,
+		`finit_token_failures_total{class="PAID",run_id="abc",scenario="canonical_v1"} 1`,
+		`finit_token_wait_seconds_count{class="PAID",run_id="abc",scenario="canonical_v1"} 2`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("WriteText() missing %q in:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"finit/engine"
+)
+
+const DefaultBatchSize = 512
+
+type Exporter struct {
+	Endpoint  string
+	Headers   map[string]string
+	BatchSize int
+	Client    *http.Client
+}
+
+func (e *Exporter) Export(ctx context.Context, artifact engine.Artifact, start time.Time) (int, error) {
+	client := e.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	batchSize := e.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	url := strings.TrimSuffix(e.Endpoint, "/") + "/v1/traces"
+
+	spans := Spans(artifact, start)
+	for offset := 0; offset < len(spans); offset += batchSize {
+		batch := spans[offset:min(offset+batchSize, len(spans))]
+		body, err := json.Marshal(Request(artifact.Metadata, batch))
+		if err != nil {
+			return offset, err
+		}
+		if err := e.post(ctx, client, url, body); err != nil {
+			return offset, err
+		}
+	}
+	return len(spans), nil
+}
+
+func (e *Exporter) post(ctx context.Context, client *http.Client, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("otlp export: %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finit/engine"
+)
+
+func testArtifact() engine.Artifact {
+	return engine.Artifact{
+		Metadata: engine.Metadata{ScenarioID: engine.ScenarioID, ReplayID: "r1", TickCount: 10, TickDurationMs: 250},
+		Events: []engine.Event{
+			{Tick: 0, Type: engine.EventQueue, TokenID: "T0000", Class: engine.ClassFree},
+			{Tick: 2, Type: engine.EventSchedule, TokenID: "T0000", Class: engine.ClassFree},
+			{Tick: 3, Type: engine.EventComplete, TokenID: "T0000", Class: engine.ClassFree},
+			{Tick: 3, Type: engine.EventReject, TokenID: "T0001", Class: engine.ClassAnon},
+			{Tick: 1, Type: engine.EventQueue, TokenID: "T0002", Class: engine.ClassPaid},
+			{Tick: 2, Type: engine.EventSchedule, TokenID: "T0002", Class: engine.ClassPaid},
+			{Tick: 4, Type: engine.EventFail, TokenID: "T0002", Class: engine.ClassPaid},
+		},
+	}
+}
+
+func TestSpans(t *testing.T) {
+	start := time.Unix(100, 0)
+	spans := Spans(testArtifact(), start)
+	if len(spans) != 7 {
+		t.Fatalf("Spans() returned %d spans, want 7", len(spans))
+	}
+
+	root, queue, service, rejected := spans[0], spans[1], spans[2], spans[3]
+	if len(root.TraceID) != 32 || len(root.SpanID) != 16 {
+		t.Errorf("ids have wrong length: trace=%q span=%q", root.TraceID, root.SpanID)
+	}
+	if queue.ParentSpanID != root.SpanID || service.ParentSpanID != root.SpanID {
+		t.Error("queue and service spans should be children of the request span")
+	}
+	if queue.EndTimeUnixNano != service.StartTimeUnixNano {
+		t.Errorf("queue ends at %s, service starts at %s", queue.EndTimeUnixNano, service.StartTimeUnixNano)
+	}
+	if want := "100750000000"; service.EndTimeUnixNano != want {
+		t.Errorf("service end = %s, want %s", service.EndTimeUnixNano, want)
+	}
+	if rejected.Status == nil || rejected.Status.Code != statusCodeError {
+		t.Errorf("rejected status = %+v, want error", rejected.Status)
+	}
+	failedRoot, failedService := spans[4], spans[6]
+	for name, span := range map[string]Span{"root": failedRoot, "service": failedService} {
+		if span.Status == nil || span.Status.Code != statusCodeError {
+			t.Errorf("failed %s status = %+v, want error", name, span.Status)
+		}
+		if want := "101000000000"; span.EndTimeUnixNano != want {
+			t.Errorf("failed %s end = %s, want %s at the failure", name, span.EndTimeUnixNano, want)
+		}
+	}
+	if TraceID("r1", "T0000") != root.TraceID {
+		t.Error("TraceID() should be deterministic")
+	}
+}
+
+func TestExporter_Export(t *testing.T) {
+	var requests []TracesRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Authorization") != "token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req TracesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, req)
+	}))
+	defer srv.Close()
+
+	exporter := &Exporter{Endpoint: srv.URL, Headers: map[string]string{"Authorization": "token"}, BatchSize: 3}
+	sent, err := exporter.Export(context.Background(), testArtifact(), time.Unix(0, 0))
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if sent != 7 || len(requests) != 3 {
+		t.Errorf("Export() sent %d spans in %d requests, want 7 in 3", sent, len(requests))
+	}
+}
+
+func TestExporter_ExportFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	exporter := &Exporter{Endpoint: srv.URL}
+	if _, err := exporter.Export(context.Background(), testArtifact(), time.Unix(0, 0)); err == nil {
+		t.Error("Export() should fail on a non-2xx response")
+	}
+}
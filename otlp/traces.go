@@ -0,0 +1,186 @@
+package otlp
+
+import (
+	"strconv"
+	"time"
+
+	"finit/engine"
+)
+
+const (
+	spanKindInternal = 1
+	statusCodeOK     = 1
+	statusCodeError  = 2
+)
+
+type TracesRequest struct {
+	ResourceSpans []ResourceSpans `json:"resourceSpans"`
+}
+
+type ResourceSpans struct {
+	Resource   Resource     `json:"resource"`
+	ScopeSpans []ScopeSpans `json:"scopeSpans"`
+}
+
+type Resource struct {
+	Attributes []KeyValue `json:"attributes"`
+}
+
+type ScopeSpans struct {
+	Scope Scope  `json:"scope"`
+	Spans []Span `json:"spans"`
+}
+
+type Scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type Span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	Status            *Status    `json:"status,omitempty"`
+}
+
+type Status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+type AnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func String(key string, value string) KeyValue {
+	return KeyValue{Key: key, Value: AnyValue{StringValue: &value}}
+}
+
+func Int(key string, value int64) KeyValue {
+	v := strconv.FormatInt(value, 10)
+	return KeyValue{Key: key, Value: AnyValue{IntValue: &v}}
+}
+
+func TraceID(replayID string, tokenID string) string {
+	return engine.TraceID(replayID, tokenID)
+}
+
+func spanID(replayID string, tokenID string, name string) string {
+	return engine.SpanID(replayID, tokenID, name)
+}
+
+func Spans(artifact engine.Artifact, start time.Time) []Span {
+	metadata := artifact.Metadata
+	tick := time.Duration(metadata.TickDurationMs) * time.Millisecond
+	at := func(t int) string {
+		return strconv.FormatInt(start.Add(time.Duration(t)*tick).UnixNano(), 10)
+	}
+	end := metadata.TickCount
+	if end == 0 {
+		end = len(artifact.Snapshots)
+	}
+
+	var spans []Span
+	for _, l := range engine.Lifecycles(artifact.Events) {
+		traceID := TraceID(metadata.ReplayID, l.TokenID)
+		rootID := spanID(metadata.ReplayID, l.TokenID, "request")
+		attributes := []KeyValue{
+			String("finit.token_id", l.TokenID),
+			String("finit.class", l.Class),
+		}
+
+		root := Span{
+			TraceID:           traceID,
+			SpanID:            rootID,
+			Name:              "request " + l.Class,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: at(l.ArrivalTick),
+			EndTimeUnixNano:   at(end),
+			Attributes:        attributes,
+		}
+		switch {
+		case l.Rejected():
+			root.EndTimeUnixNano = at(l.RejectTick)
+			root.Status = &Status{Code: statusCodeError, Message: "rejected"}
+		case l.Failed():
+			root.EndTimeUnixNano = at(l.FailTick)
+			root.Status = &Status{Code: statusCodeError, Message: "failed"}
+		case l.Completed():
+			root.EndTimeUnixNano = at(l.CompleteTick)
+			root.Status = &Status{Code: statusCodeOK}
+		}
+		spans = append(spans, root)
+
+		if l.Rejected() {
+			continue
+		}
+		finished := end
+		switch {
+		case l.Failed():
+			finished = l.FailTick
+		case l.Completed():
+			finished = l.CompleteTick
+		}
+		queueEnd := finished
+		if l.Scheduled() {
+			queueEnd = l.ScheduleTick
+		}
+		spans = append(spans, Span{
+			TraceID:           traceID,
+			SpanID:            spanID(metadata.ReplayID, l.TokenID, engine.StageQueue),
+			ParentSpanID:      rootID,
+			Name:              "queue wait",
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: at(l.ArrivalTick),
+			EndTimeUnixNano:   at(queueEnd),
+			Attributes:        append(attributes, String("finit.stage_id", engine.StageQueue)),
+		})
+		if !l.Scheduled() {
+			continue
+		}
+		service := Span{
+			TraceID:           traceID,
+			SpanID:            spanID(metadata.ReplayID, l.TokenID, engine.StageService),
+			ParentSpanID:      rootID,
+			Name:              "service",
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: at(l.ScheduleTick),
+			EndTimeUnixNano:   at(finished),
+			Attributes:        append(attributes, String("finit.stage_id", engine.StageService)),
+		}
+		if l.Failed() {
+			service.Status = &Status{Code: statusCodeError, Message: "failed"}
+		}
+		spans = append(spans, service)
+	}
+	return spans
+}
+
+func Request(metadata engine.Metadata, spans []Span) TracesRequest {
+	return TracesRequest{
+		ResourceSpans: []ResourceSpans{{
+			Resource: Resource{Attributes: []KeyValue{
+				String("service.name", "finit"),
+				String("finit.scenario_id", metadata.ScenarioID),
+				String("finit.replay_id", metadata.ReplayID),
+				String("finit.engine_version", metadata.EngineVersion),
+				Int("finit.seed", metadata.Seed),
+			}},
+			ScopeSpans: []ScopeSpans{{
+				Scope: Scope{Name: "finit", Version: engine.EngineVersion},
+				Spans: spans,
+			}},
+		}},
+	}
+}
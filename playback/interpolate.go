@@ -0,0 +1,137 @@
+package playback
+
+import "finit/engine"
+
+type Interpolated struct {
+	Tick   float64
+	TimeMs float64
+	Tokens []TokenMotion
+	Stages []StageLevel
+}
+
+type TokenMotion struct {
+	ID               string
+	Class            string
+	From             string
+	To               string
+	Progress         float64
+	QueuePosition    float64
+	ServiceRemaining float64
+	Opacity          float64
+}
+
+type StageLevel struct {
+	ID           string
+	QueueLength  float64
+	CapacityUsed float64
+}
+
+func Interpolate(a, b engine.Snapshot, fraction float64) Interpolated {
+	f := clampFraction(fraction)
+	frame := Interpolated{
+		Tick:   lerp(float64(a.Tick), float64(b.Tick), f),
+		TimeMs: lerp(float64(a.TimeMs), float64(b.TimeMs), f),
+	}
+
+	next := make(map[string]*engine.TokenState, len(b.Tokens))
+	for i := range b.Tokens {
+		next[b.Tokens[i].ID] = &b.Tokens[i]
+	}
+	seen := make(map[string]bool, len(a.Tokens))
+	for i := range a.Tokens {
+		from := &a.Tokens[i]
+		seen[from.ID] = true
+		frame.Tokens = append(frame.Tokens, interpolateToken(from, next[from.ID], f))
+	}
+	for i := range b.Tokens {
+		if !seen[b.Tokens[i].ID] {
+			frame.Tokens = append(frame.Tokens, interpolateToken(nil, &b.Tokens[i], f))
+		}
+	}
+
+	levels := make(map[string]engine.StageState, len(b.Stages))
+	for _, stage := range b.Stages {
+		levels[stage.ID] = stage
+	}
+	stageSeen := make(map[string]bool, len(a.Stages))
+	for _, stage := range a.Stages {
+		stageSeen[stage.ID] = true
+		to := levels[stage.ID]
+		frame.Stages = append(frame.Stages, StageLevel{
+			ID:           stage.ID,
+			QueueLength:  lerp(float64(stage.QueueLength), float64(to.QueueLength), f),
+			CapacityUsed: lerp(float64(stage.CapacityUsed), float64(to.CapacityUsed), f),
+		})
+	}
+	for _, stage := range b.Stages {
+		if !stageSeen[stage.ID] {
+			frame.Stages = append(frame.Stages, StageLevel{
+				ID:           stage.ID,
+				QueueLength:  lerp(0, float64(stage.QueueLength), f),
+				CapacityUsed: lerp(0, float64(stage.CapacityUsed), f),
+			})
+		}
+	}
+	return frame
+}
+
+func interpolateToken(from, to *engine.TokenState, f float64) TokenMotion {
+	switch {
+	case to == nil:
+		return TokenMotion{
+			ID:               from.ID,
+			Class:            from.Class,
+			From:             from.StageID,
+			QueuePosition:    queuePosition(from),
+			ServiceRemaining: lerp(float64(from.ServiceRemaining), 0, f),
+			Opacity:          1 - f,
+		}
+	case from == nil:
+		return TokenMotion{
+			ID:               to.ID,
+			Class:            to.Class,
+			To:               to.StageID,
+			Progress:         1,
+			QueuePosition:    queuePosition(to),
+			ServiceRemaining: float64(to.ServiceRemaining),
+			Opacity:          f,
+		}
+	}
+	motion := TokenMotion{
+		ID:               from.ID,
+		Class:            from.Class,
+		From:             from.StageID,
+		To:               to.StageID,
+		QueuePosition:    lerp(queuePosition(from), queuePosition(to), f),
+		ServiceRemaining: lerp(float64(from.ServiceRemaining), float64(to.ServiceRemaining), f),
+		Opacity:          1,
+	}
+	if from.StageID != to.StageID {
+		motion.Progress = f
+	}
+	if queuePosition(from) < 0 && queuePosition(to) >= 0 {
+		motion.QueuePosition = queuePosition(to)
+	}
+	if from.ServiceRemaining == 0 && to.ServiceRemaining > 0 {
+		motion.ServiceRemaining = float64(to.ServiceRemaining)
+	}
+	return motion
+}
+
+func queuePosition(token *engine.TokenState) float64 {
+	if token.StageID != engine.StageQueue || token.QueueIndex < 0 {
+		return -1
+	}
+	return float64(token.QueueIndex)
+}
+
+func clampFraction(f float64) float64 {
+	if !(f > 0) {
+		return 0
+	}
+	return min(f, 1)
+}
+
+func lerp(a, b, f float64) float64 {
+	return a*(1-f) + b*f
+}
@@ -0,0 +1,71 @@
+package playback
+
+import (
+	"reflect"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestInterpolate(t *testing.T) {
+	a := engine.Snapshot{
+		Tick:   4,
+		TimeMs: 1000,
+		Tokens: []engine.TokenState{
+			{ID: "T0001", Class: engine.ClassPaid, StageID: engine.StageService, QueueIndex: -1, ServiceRemaining: 2},
+			{ID: "T0002", Class: engine.ClassFree, StageID: engine.StageQueue, QueueIndex: 0},
+			{ID: "T0003", Class: engine.ClassAnon, StageID: engine.StageQueue, QueueIndex: 2},
+			{ID: "T0004", Class: engine.ClassAnon, StageID: engine.StageDone, QueueIndex: -1},
+		},
+		Stages: []engine.StageState{{ID: engine.StageQueue, QueueLength: 3}, {ID: engine.StageService, CapacityUsed: 1}},
+	}
+	b := engine.Snapshot{
+		Tick:   5,
+		TimeMs: 1250,
+		Tokens: []engine.TokenState{
+			{ID: "T0001", Class: engine.ClassPaid, StageID: engine.StageService, QueueIndex: -1, ServiceRemaining: 1},
+			{ID: "T0002", Class: engine.ClassFree, StageID: engine.StageService, QueueIndex: -1, ServiceRemaining: 3},
+			{ID: "T0003", Class: engine.ClassAnon, StageID: engine.StageQueue, QueueIndex: 1},
+			{ID: "T0005", Class: engine.ClassPaid, StageID: engine.StageQueue, QueueIndex: 0},
+		},
+		Stages: []engine.StageState{{ID: engine.StageQueue, QueueLength: 2}, {ID: engine.StageService, CapacityUsed: 2}},
+	}
+
+	got := Interpolate(a, b, 0.25)
+	want := Interpolated{
+		Tick:   4.25,
+		TimeMs: 1062.5,
+		Tokens: []TokenMotion{
+			{ID: "T0001", Class: engine.ClassPaid, From: engine.StageService, To: engine.StageService, QueuePosition: -1, ServiceRemaining: 1.75, Opacity: 1},
+			{ID: "T0002", Class: engine.ClassFree, From: engine.StageQueue, To: engine.StageService, Progress: 0.25, QueuePosition: -0.25, ServiceRemaining: 3, Opacity: 1},
+			{ID: "T0003", Class: engine.ClassAnon, From: engine.StageQueue, To: engine.StageQueue, QueuePosition: 1.75, Opacity: 1},
+			{ID: "T0004", Class: engine.ClassAnon, From: engine.StageDone, QueuePosition: -1, Opacity: 0.75},
+			{ID: "T0005", Class: engine.ClassPaid, To: engine.StageQueue, Progress: 1, QueuePosition: 0, Opacity: 0.25},
+		},
+		Stages: []StageLevel{{ID: engine.StageQueue, QueueLength: 2.75}, {ID: engine.StageService, CapacityUsed: 1.25}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Interpolate(0.25) = %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		fraction float64
+		wantTick float64
+	}{
+		{fraction: 0, wantTick: 4},
+		{fraction: 1, wantTick: 5},
+		{fraction: -1, wantTick: 4},
+		{fraction: 2, wantTick: 5},
+	}
+	for _, tt := range tests {
+		if got := Interpolate(a, b, tt.fraction); got.Tick != tt.wantTick {
+			t.Errorf("Interpolate(%v).Tick = %v, want %v", tt.fraction, got.Tick, tt.wantTick)
+		}
+	}
+	end := Interpolate(a, b, 1)
+	for _, motion := range end.Tokens {
+		if motion.ID == "T0003" && motion.QueuePosition != 1 {
+			t.Errorf("Interpolate(1) left T0003 at queue position %v, want 1", motion.QueuePosition)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package playback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"finit/engine"
+)
+
+var ErrNoSnapshots = errors.New("artifact has no snapshots")
+
+type Frame struct {
+	Tick     int
+	TimeMs   int
+	Snapshot engine.Snapshot
+	Events   []engine.Event
+}
+
+type Player struct {
+	source   Source
+	interval time.Duration
+	clock    Clock
+	wake     chan struct{}
+
+	mu     sync.Mutex
+	index  int
+	speed  float64
+	paused bool
+	steps  int
+}
+
+func New(artifact engine.Artifact, clock Clock) (*Player, error) {
+	if len(artifact.Snapshots) == 0 {
+		return nil, ErrNoSnapshots
+	}
+	return NewPlayer(ArtifactSource(artifact), clock), nil
+}
+
+func NewPlayer(source Source, clock Clock) *Player {
+	if clock == nil {
+		clock = SystemClock()
+	}
+	return &Player{
+		source:   source,
+		interval: tickInterval(source.Metadata()),
+		clock:    clock,
+		wake:     make(chan struct{}, 1),
+		speed:    1,
+	}
+}
+
+func (p *Player) Metadata() engine.Metadata {
+	return p.source.Metadata()
+}
+
+func (p *Player) Tick() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if frame, ok := p.source.Frame(p.index); ok {
+		return frame.Tick
+	}
+	return p.source.Metadata().TickCount
+}
+
+func (p *Player) Speed() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.speed
+}
+
+func (p *Player) SetSpeed(speed float64) error {
+	if speed <= 0 {
+		return fmt.Errorf("speed must be positive: %v", speed)
+	}
+	p.mu.Lock()
+	p.speed = speed
+	p.mu.Unlock()
+	p.notify()
+	return nil
+}
+
+func (p *Player) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+func (p *Player) Pause() {
+	p.mu.Lock()
+	p.paused = true
+	p.mu.Unlock()
+	p.notify()
+}
+
+func (p *Player) Resume() {
+	p.mu.Lock()
+	p.paused = false
+	p.mu.Unlock()
+	p.notify()
+}
+
+func (p *Player) Step() {
+	p.mu.Lock()
+	p.paused = true
+	p.steps++
+	p.mu.Unlock()
+	p.notify()
+}
+
+func (p *Player) Seek(tick int) error {
+	index, ok := p.source.Index(tick)
+	if !ok {
+		return fmt.Errorf("tick out of range: %d", tick)
+	}
+	p.mu.Lock()
+	p.index = index
+	p.steps = 0
+	p.mu.Unlock()
+	p.notify()
+	return nil
+}
+
+func (p *Player) Run(ctx context.Context, emit func(Frame) error) error {
+	select {
+	case <-p.wake:
+	default:
+	}
+	for {
+		p.mu.Lock()
+		if p.paused && p.steps == 0 {
+			p.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-p.wake:
+			}
+			continue
+		}
+		frame, ok := p.source.Frame(p.index)
+		if !ok {
+			p.mu.Unlock()
+			return nil
+		}
+		p.index++
+		stepping := p.paused
+		if stepping {
+			p.steps--
+		}
+		next, more := p.source.Frame(p.index)
+		delay := time.Duration(float64(p.interval) * float64(next.Tick-frame.Tick) / p.speed)
+		p.mu.Unlock()
+
+		if err := emit(frame); err != nil {
+			return err
+		}
+		if stepping || !more {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-p.clock.After(delay):
+		case <-p.wake:
+		}
+	}
+}
+
+func (p *Player) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
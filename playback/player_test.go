@@ -0,0 +1,382 @@
+package playback
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"finit/engine"
+)
+
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+	waits   chan time.Duration
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now:   time.Unix(0, 0),
+		waits: make(chan time.Duration, 16),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	c.mu.Unlock()
+	c.waits <- d
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.waiters[:0]
+	for _, waiter := range c.waiters {
+		if !waiter.at.After(c.now) {
+			waiter.ch <- c.now
+			continue
+		}
+		pending = append(pending, waiter)
+	}
+	c.waiters = pending
+}
+
+func testArtifact(ticks int) engine.Artifact {
+	artifact := engine.Artifact{
+		Metadata: engine.Metadata{
+			ScenarioID:     engine.ScenarioID,
+			TickCount:      ticks,
+			TickDurationMs: engine.TickDurationMs,
+		},
+	}
+	for tick := 0; tick < ticks; tick++ {
+		artifact.Snapshots = append(artifact.Snapshots, engine.Snapshot{
+			Tick:   tick,
+			TimeMs: tick * engine.TickDurationMs,
+		})
+		artifact.Events = append(artifact.Events, engine.Event{
+			Tick:       tick,
+			Type:       engine.EventQueue,
+			ReasonCode: engine.ReasonQueueAdmission,
+		})
+	}
+	return artifact
+}
+
+func TestNew_NoSnapshots(t *testing.T) {
+	_, err := New(engine.Artifact{}, nil)
+	if !errors.Is(err, ErrNoSnapshots) {
+		t.Errorf("New() error = %v, want %v", err, ErrNoSnapshots)
+	}
+}
+
+func TestPlayer_RunPacesFrames(t *testing.T) {
+	clock := newFakeClock()
+	player, err := New(testArtifact(3), clock)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := player.SetSpeed(2); err != nil {
+		t.Fatalf("SetSpeed() error = %v", err)
+	}
+
+	frames := make(chan Frame, 3)
+	done := make(chan error, 1)
+	go func() {
+		done <- player.Run(context.Background(), func(frame Frame) error {
+			frames <- frame
+			return nil
+		})
+	}()
+
+	for want := 0; want < 3; want++ {
+		frame := <-frames
+		if frame.Tick != want {
+			t.Fatalf("frame tick = %d, want %d", frame.Tick, want)
+		}
+		if len(frame.Events) != 1 {
+			t.Errorf("frame %d events = %d, want 1", want, len(frame.Events))
+		}
+		if want == 2 {
+			break
+		}
+		delay := <-clock.waits
+		if delay != 125*time.Millisecond {
+			t.Errorf("wait = %v, want %v", delay, 125*time.Millisecond)
+		}
+		clock.Advance(delay)
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+func TestPlayer_SeekAndPause(t *testing.T) {
+	clock := newFakeClock()
+	player, err := New(testArtifact(10), clock)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := player.Seek(42); err == nil {
+		t.Error("Seek() out of range should fail")
+	}
+	if err := player.Seek(7); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	player.Pause()
+	if !player.Paused() {
+		t.Error("Paused() = false after Pause()")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	frames := make(chan Frame, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- player.Run(ctx, func(frame Frame) error {
+			frames <- frame
+			return nil
+		})
+	}()
+
+	player.Resume()
+	frame := <-frames
+	if frame.Tick != 7 {
+		t.Errorf("first frame after seek = %d, want 7", frame.Tick)
+	}
+
+	<-clock.waits
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestPlayer_SetSpeedRejectsNonPositive(t *testing.T) {
+	player, err := New(testArtifact(1), nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := player.SetSpeed(0); err == nil {
+		t.Error("SetSpeed(0) should fail")
+	}
+	if player.Speed() != 1 {
+		t.Errorf("Speed() = %v, want 1", player.Speed())
+	}
+}
+
+func TestPlayer_StepWhilePaused(t *testing.T) {
+	player, err := New(testArtifact(5), newFakeClock())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	player.Pause()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	frames := make(chan Frame, 5)
+	done := make(chan error, 1)
+	go func() {
+		done <- player.Run(ctx, func(frame Frame) error {
+			frames <- frame
+			return nil
+		})
+	}()
+
+	player.Step()
+	player.Step()
+	for want := 0; want < 2; want++ {
+		if frame := <-frames; frame.Tick != want {
+			t.Errorf("stepped frame = %d, want %d", frame.Tick, want)
+		}
+	}
+	if !player.Paused() {
+		t.Error("Paused() = false after Step()")
+	}
+	if player.Tick() != 2 {
+		t.Errorf("Tick() = %d, want 2", player.Tick())
+	}
+
+	cancel()
+	<-done
+}
+
+func TestLiveSource_MatchesRun(t *testing.T) {
+	for _, interval := range []int{1, 10} {
+		cfg := engine.Config{ScenarioID: engine.ScenarioID, Seed: 7, SnapshotInterval: interval}
+		artifact, err := engine.Run(cfg)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		sim, err := engine.NewSimulator(cfg)
+		if err != nil {
+			t.Fatalf("NewSimulator() error = %v", err)
+		}
+
+		source := LiveSource(sim)
+		events := 0
+		for i := range artifact.Snapshots {
+			frame, ok := source.Frame(i)
+			if !ok {
+				t.Fatalf("interval %d: Frame(%d) missing", interval, i)
+			}
+			if frame.Tick != artifact.Snapshots[i].Tick {
+				t.Fatalf("interval %d: Frame(%d).Tick = %d, want %d", interval, i, frame.Tick, artifact.Snapshots[i].Tick)
+			}
+			if index, ok := source.Index(frame.Tick); !ok || index != i {
+				t.Errorf("interval %d: Index(%d) = %d, %v, want %d", interval, frame.Tick, index, ok, i)
+			}
+			events += len(frame.Events)
+		}
+		if _, ok := source.Frame(len(artifact.Snapshots)); ok {
+			t.Errorf("interval %d: Frame() past the end should report false", interval)
+		}
+		if events != len(artifact.Events) {
+			t.Errorf("interval %d: live events = %d, want %d", interval, events, len(artifact.Events))
+		}
+	}
+}
+
+func TestIndexedSource_MatchesArtifact(t *testing.T) {
+	artifact, err := engine.Run(engine.Config{Seed: 3})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "run.json")
+	if err := engine.WriteArtifact(path, artifact); err != nil {
+		t.Fatalf("WriteArtifact() error = %v", err)
+	}
+	if _, err := engine.WriteIndex(path); err != nil {
+		t.Fatalf("WriteIndex() error = %v", err)
+	}
+	indexed, err := engine.OpenIndexed(path)
+	if err != nil {
+		t.Fatalf("OpenIndexed() error = %v", err)
+	}
+	defer indexed.Close()
+
+	source, want := IndexedSource(indexed), ArtifactSource(artifact)
+	for _, tick := range []int{0, 42, 239} {
+		i, ok := source.Index(tick)
+		if !ok {
+			t.Fatalf("Index(%d) missing", tick)
+		}
+		got, _ := source.Frame(i)
+		expected, _ := want.Frame(i)
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Frame(%d) does not match the artifact source", i)
+		}
+	}
+	if _, ok := source.Frame(len(artifact.Snapshots)); ok {
+		t.Error("Frame() past the end should report false")
+	}
+}
+
+func TestArtifactSource_BucketsEventsIntoFrames(t *testing.T) {
+	for _, interval := range []int{1, 10} {
+		artifact, err := engine.Run(engine.Config{ScenarioID: engine.ScenarioID, Seed: 7, SnapshotInterval: interval})
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		path := filepath.Join(t.TempDir(), "run.json")
+		if err := engine.WriteArtifact(path, artifact); err != nil {
+			t.Fatalf("WriteArtifact() error = %v", err)
+		}
+		if _, err := engine.WriteIndex(path); err != nil {
+			t.Fatalf("WriteIndex() error = %v", err)
+		}
+		indexed, err := engine.OpenIndexed(path)
+		if err != nil {
+			t.Fatalf("OpenIndexed() error = %v", err)
+		}
+		defer indexed.Close()
+
+		for name, source := range map[string]Source{"artifact": ArtifactSource(artifact), "indexed": IndexedSource(indexed)} {
+			events, previous := 0, -1
+			for i := range artifact.Snapshots {
+				frame, ok := source.Frame(i)
+				if !ok {
+					t.Fatalf("interval %d: %s Frame(%d) missing", interval, name, i)
+				}
+				for _, event := range frame.Events {
+					if event.Tick <= previous || event.Tick > frame.Tick {
+						t.Errorf("interval %d: %s frame at tick %d holds an event from tick %d", interval, name, frame.Tick, event.Tick)
+					}
+				}
+				events += len(frame.Events)
+				previous = frame.Tick
+			}
+			if events != len(artifact.Events) {
+				t.Errorf("interval %d: %s events = %d, want %d", interval, name, events, len(artifact.Events))
+			}
+		}
+	}
+}
+
+func TestPlayer_RunPacesSnapshotGaps(t *testing.T) {
+	artifact := testArtifact(21)
+	var sparse []engine.Snapshot
+	for _, snapshot := range artifact.Snapshots {
+		if snapshot.Tick%10 == 0 {
+			sparse = append(sparse, snapshot)
+		}
+	}
+	artifact.Snapshots = sparse
+	artifact.Metadata.SnapshotInterval = 10
+
+	clock := newFakeClock()
+	player, err := New(artifact, clock)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	frames := make(chan Frame, 3)
+	done := make(chan error, 1)
+	go func() {
+		done <- player.Run(context.Background(), func(frame Frame) error {
+			frames <- frame
+			return nil
+		})
+	}()
+
+	want := 10 * engine.TickDurationMs * time.Millisecond
+	for i := 0; i < 3; i++ {
+		frame := <-frames
+		wantEvents := 10
+		if frame.Tick == 0 {
+			wantEvents = 1
+		}
+		if len(frame.Events) != wantEvents {
+			t.Errorf("frame at tick %d events = %d, want %d", frame.Tick, len(frame.Events), wantEvents)
+		}
+		if i == 2 {
+			break
+		}
+		if delay := <-clock.waits; delay != want {
+			t.Errorf("wait = %v, want %v", delay, want)
+		}
+		clock.Advance(want)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
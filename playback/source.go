@@ -0,0 +1,200 @@
+package playback
+
+import (
+	"sort"
+	"time"
+
+	"finit/engine"
+)
+
+type Source interface {
+	Metadata() engine.Metadata
+	Frame(index int) (Frame, bool)
+	Index(tick int) (int, bool)
+}
+
+type artifactSource struct {
+	artifact engine.Artifact
+	events   [][]engine.Event
+	indices  map[int]int
+}
+
+func ArtifactSource(artifact engine.Artifact) Source {
+	artifact = engine.ExpandSnapshots(artifact)
+	snapshots := artifact.Snapshots
+	events := make([][]engine.Event, len(snapshots))
+	for _, event := range artifact.Events {
+		i := sort.Search(len(snapshots), func(i int) bool { return snapshots[i].Tick >= event.Tick })
+		if i == len(snapshots) {
+			continue
+		}
+		events[i] = append(events[i], event)
+	}
+	indices := make(map[int]int, len(artifact.Snapshots))
+	for i, snapshot := range artifact.Snapshots {
+		indices[snapshot.Tick] = i
+	}
+	return &artifactSource{
+		artifact: artifact,
+		events:   events,
+		indices:  indices,
+	}
+}
+
+func (a *artifactSource) Metadata() engine.Metadata {
+	return a.artifact.Metadata
+}
+
+func (a *artifactSource) Frame(index int) (Frame, bool) {
+	if index < 0 || index >= len(a.artifact.Snapshots) {
+		return Frame{}, false
+	}
+	snapshot := a.artifact.Snapshots[index]
+	return Frame{
+		Tick:     snapshot.Tick,
+		TimeMs:   snapshot.TimeMs,
+		Snapshot: snapshot,
+		Events:   a.events[index],
+	}, true
+}
+
+func (a *artifactSource) Index(tick int) (int, bool) {
+	index, ok := a.indices[tick]
+	return index, ok
+}
+
+type indexedSource struct {
+	artifact *engine.IndexedArtifact
+	expander *engine.SnapshotExpander
+	last     Frame
+	next     int
+}
+
+func IndexedSource(artifact *engine.IndexedArtifact) Source {
+	return &indexedSource{artifact: artifact}
+}
+
+func (s *indexedSource) Metadata() engine.Metadata {
+	return s.artifact.Metadata()
+}
+
+func (s *indexedSource) Frame(index int) (Frame, bool) {
+	if index < 0 || index >= s.artifact.SnapshotCount() {
+		return Frame{}, false
+	}
+	if s.expander != nil && index == s.next-1 {
+		return s.last, true
+	}
+	snapshot, err := s.snapshot(index)
+	if err != nil {
+		return Frame{}, false
+	}
+	from := 0
+	if index > 0 {
+		previous, err := s.artifact.SnapshotAt(index - 1)
+		if err != nil {
+			return Frame{}, false
+		}
+		from = previous.Tick + 1
+	}
+	events, err := s.artifact.EventsBetween(from, snapshot.Tick)
+	if err != nil {
+		return Frame{}, false
+	}
+	s.last = Frame{
+		Tick:     snapshot.Tick,
+		TimeMs:   snapshot.TimeMs,
+		Snapshot: snapshot,
+		Events:   events,
+	}
+	return s.last, true
+}
+
+func (s *indexedSource) snapshot(index int) (engine.Snapshot, error) {
+	if s.artifact.Metadata().FullSnapshots() {
+		return s.artifact.SnapshotAt(index)
+	}
+	if s.expander == nil || index < s.next {
+		s.expander = engine.NewSnapshotExpander()
+		s.next = 0
+	}
+	for ; s.next < index; s.next++ {
+		snapshot, err := s.artifact.SnapshotAt(s.next)
+		if err != nil {
+			return engine.Snapshot{}, err
+		}
+		s.expander.Expand(snapshot)
+	}
+	snapshot, err := s.artifact.SnapshotAt(index)
+	if err != nil {
+		return engine.Snapshot{}, err
+	}
+	s.next = index + 1
+	return s.expander.Expand(snapshot), nil
+}
+
+func (s *indexedSource) Index(tick int) (int, bool) {
+	return s.artifact.SnapshotIndex(tick)
+}
+
+type liveSource struct {
+	sim      *engine.Simulator
+	expander *engine.SnapshotExpander
+	frames   []Frame
+	offset   int
+}
+
+func LiveSource(sim *engine.Simulator) Source {
+	return &liveSource{sim: sim, expander: engine.NewSnapshotExpander()}
+}
+
+func (l *liveSource) Metadata() engine.Metadata {
+	return l.sim.Metadata()
+}
+
+func (l *liveSource) Frame(index int) (Frame, bool) {
+	if index < 0 {
+		return Frame{}, false
+	}
+	for len(l.frames) <= index {
+		for len(l.sim.Snapshots()) == len(l.frames) {
+			if !l.sim.Step() {
+				return Frame{}, false
+			}
+		}
+		snapshots := l.sim.Snapshots()
+		events := l.sim.Events()
+		snapshot := l.expander.Expand(snapshots[len(snapshots)-1])
+		l.frames = append(l.frames, Frame{
+			Tick:     snapshot.Tick,
+			TimeMs:   snapshot.TimeMs,
+			Snapshot: snapshot,
+			Events:   events[l.offset:],
+		})
+		l.offset = len(events)
+	}
+	return l.frames[index], true
+}
+
+func (l *liveSource) Index(tick int) (int, bool) {
+	metadata := l.sim.Metadata()
+	if tick < 0 || tick >= metadata.TickCount {
+		return 0, false
+	}
+	interval := max(metadata.SnapshotInterval, 1)
+	if tick == metadata.TickCount-1 {
+		return (tick + interval - 1) / interval, true
+	}
+	if tick%interval != 0 {
+		return 0, false
+	}
+	return tick / interval, true
+}
+
+func tickInterval(metadata engine.Metadata) time.Duration {
+	interval := time.Duration(metadata.TickDurationMs) * time.Millisecond
+	if interval <= 0 {
+		interval = engine.TickDurationMs * time.Millisecond
+	}
+	return interval
+}
@@ -0,0 +1,240 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type Expr interface {
+	Match(row Row) bool
+}
+
+type and struct{ left, right Expr }
+
+func (e and) Match(row Row) bool { return e.left.Match(row) && e.right.Match(row) }
+
+type or struct{ left, right Expr }
+
+func (e or) Match(row Row) bool { return e.left.Match(row) || e.right.Match(row) }
+
+type not struct{ expr Expr }
+
+func (e not) Match(row Row) bool { return !e.expr.Match(row) }
+
+type all struct{}
+
+func (all) Match(Row) bool { return true }
+
+type comparison struct {
+	field string
+	op    string
+	value string
+}
+
+func (c comparison) Match(row Row) bool {
+	left := format(row[c.field])
+	var cmp int
+	l, lerr := strconv.ParseFloat(left, 64)
+	r, rerr := strconv.ParseFloat(c.value, 64)
+	if lerr == nil && rerr == nil {
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(left, c.value)
+	}
+
+	switch c.op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+func Parse(input string, columns []string) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return all{}, nil
+	}
+	p := &parser{tokens: tokens, columns: columns}
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("query: unexpected %q", p.tokens[p.pos].text)
+	}
+	return expr, nil
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(input[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("query: unterminated string at offset %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: input[i+1 : i+1+end]})
+			i += end + 2
+		case isWord(rune(c)):
+			start := i
+			for i < len(input) && isWord(rune(input[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: input[start:i]})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(input[i:], op) {
+					tokens = append(tokens, token{kind: tokenOp, text: op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("query: unexpected %q at offset %d", c, i)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+func isWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'
+}
+
+type parser struct {
+	tokens  []token
+	pos     int
+	columns []string
+}
+
+func (p *parser) peek(op string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOp && p.tokens[p.pos].text == op
+}
+
+func (p *parser) or() (Expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("||") {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("&&") {
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = and{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (Expr, error) {
+	if p.peek("!") {
+		p.pos++
+		expr, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return not{expr}, nil
+	}
+	if p.peek("(") {
+		p.pos++
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek(")") {
+			return nil, fmt.Errorf("query: missing )")
+		}
+		p.pos++
+		return expr, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (Expr, error) {
+	if p.pos+3 > len(p.tokens) {
+		return nil, fmt.Errorf("query: incomplete comparison")
+	}
+	field, op, value := p.tokens[p.pos], p.tokens[p.pos+1], p.tokens[p.pos+2]
+	if field.kind != tokenWord {
+		return nil, fmt.Errorf("query: expected field name, got %q", field.text)
+	}
+	if !contains(p.columns, field.text) {
+		return nil, fmt.Errorf("query: unknown field %q (fields: %s)", field.text, strings.Join(p.columns, ", "))
+	}
+	switch op.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return nil, fmt.Errorf("query: expected comparison after %q, got %q", field.text, op.text)
+	}
+	if op.kind != tokenOp || value.kind == tokenOp {
+		return nil, fmt.Errorf("query: expected value after %s %s", field.text, op.text)
+	}
+	p.pos += 3
+	return comparison{field: field.text, op: op.text, value: value.text}, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package query
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestParseMatch(t *testing.T) {
+	row := Row{"tick": 12, "type": "REJECT", "class": "ANON", "token_id": "T0007", "rule": "anon_queue_limit"}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{expr: "", want: true},
+		{expr: "type==REJECT && class==ANON", want: true},
+		{expr: "type==REJECT && class==PAID", want: false},
+		{expr: "class==PAID || tick>=12", want: true},
+		{expr: "tick<9", want: false},
+		{expr: "tick > 9 && !(class == 'FREE')", want: true},
+		{expr: `token_id != "T0007"`, want: false},
+		{expr: "rule==anon_queue_limit", want: true},
+		{expr: "queue_length==0", want: false},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.expr, EventColumns)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.expr, err)
+			continue
+		}
+		if got := expr.Match(row); got != tt.want {
+			t.Errorf("Parse(%q).Match() = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"color==red",
+		"type==",
+		"type REJECT",
+		"(type==REJECT",
+		"type=='REJECT",
+		"type==REJECT &&",
+		"type==REJECT extra",
+	} {
+		if _, err := Parse(expr, EventColumns); err == nil {
+			t.Errorf("Parse(%q) error = nil, want error", expr)
+		}
+	}
+}
+
+func TestQueryArtifact(t *testing.T) {
+	artifact, err := engine.Run(engine.Config{Seed: 1})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	expr, err := Parse("type==REJECT && class==ANON", EventColumns)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	table, err := Events(artifact).Where(expr).Select([]string{"tick", "token_id"})
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	want := 0
+	for _, e := range artifact.Events {
+		if e.Type == engine.EventReject {
+			want++
+		}
+	}
+	if len(table.Rows) != want || want == 0 {
+		t.Fatalf("rows = %d, want %d", len(table.Rows), want)
+	}
+
+	var csv bytes.Buffer
+	if err := table.WriteCSV(&csv); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if lines[0] != "tick,token_id" || len(lines) != want+1 {
+		t.Errorf("WriteCSV() header = %q, lines = %d", lines[0], len(lines))
+	}
+
+	var js bytes.Buffer
+	if err := table.WriteJSON(&js); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if !strings.Contains(js.String(), `"tick":`) || strings.Contains(js.String(), `"class"`) {
+		t.Errorf("WriteJSON() = %s", js.String())
+	}
+
+	if _, err := Tokens(artifact).Select([]string{"type"}); err == nil {
+		t.Error("Select(type) on tokens error = nil, want unknown field")
+	}
+}
@@ -0,0 +1,194 @@
+package query
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"finit/engine"
+)
+
+const (
+	FromEvents = "events"
+	FromTokens = "tokens"
+)
+
+var EventColumns = []string{
+	"tick", "time_ms", "type", "reason_code", "token_id", "stage_id", "class", "quality", "warmup",
+	"rule", "queue_length", "ahead_count", "ahead", "limit", "capacity_used", "wait_ticks", "window_ticks", "bypassed",
+	"slo", "burn_rate", "missed", "observed", "ticket", "position", "previous_position", "hedge", "cancelled", "request_key",
+	"servers", "held", "cycle", "session", "server", "overtaken", "shadow",
+}
+
+var TokenColumns = []string{
+	"tick", "time_ms", "token_id", "class", "state", "stage_id", "queue_index", "service_remaining", "cycle", "warmup",
+}
+
+type Row map[string]any
+
+type Table struct {
+	Columns []string
+	Rows    []Row
+}
+
+func From(artifact engine.Artifact, source string) (Table, error) {
+	switch source {
+	case FromEvents:
+		return Events(artifact), nil
+	case FromTokens:
+		return Tokens(artifact), nil
+	default:
+		return Table{}, fmt.Errorf("query: unknown source %q (want %s or %s)", source, FromEvents, FromTokens)
+	}
+}
+
+func Events(artifact engine.Artifact) Table {
+	tickMs := artifact.Metadata.TickDurationMs
+	rows := make([]Row, 0, len(artifact.Events))
+	for _, e := range artifact.Events {
+		row := Row{
+			"tick":        e.Tick,
+			"time_ms":     e.Tick * tickMs,
+			"type":        e.Type,
+			"reason_code": e.ReasonCode,
+			"token_id":    e.TokenID,
+			"stage_id":    e.StageID,
+			"class":       e.Class,
+			"quality":     e.Quality,
+			"warmup":      e.Warmup,
+		}
+		if c := e.Context; c != nil {
+			row["rule"] = c.Rule
+			row["queue_length"] = c.QueueLength
+			row["ahead_count"] = c.AheadCount
+			row["ahead"] = strings.Join(c.Ahead, " ")
+			row["limit"] = c.Limit
+			row["capacity_used"] = c.CapacityUsed
+			row["wait_ticks"] = c.WaitTicks
+			row["window_ticks"] = c.WindowTicks
+			row["bypassed"] = c.Bypassed
+			row["slo"] = c.SLO
+			row["burn_rate"] = c.BurnRate
+			row["missed"] = c.Missed
+			row["observed"] = c.Observed
+			row["ticket"] = c.Ticket
+			row["position"] = c.Position
+			row["previous_position"] = c.PreviousPosition
+			row["hedge"] = c.Hedge
+			row["cancelled"] = c.Cancelled
+			row["request_key"] = c.RequestKey
+			row["servers"] = c.Servers
+			row["held"] = c.Held
+			row["cycle"] = c.Cycle
+			row["session"] = c.Session
+			row["server"] = c.Server
+			row["overtaken"] = c.Overtaken
+			row["shadow"] = c.Shadow
+		}
+		rows = append(rows, row)
+	}
+	return Table{Columns: EventColumns, Rows: rows}
+}
+
+func Tokens(artifact engine.Artifact) Table {
+	var rows []Row
+	for _, snapshot := range artifact.Snapshots {
+		for _, t := range snapshot.Tokens {
+			rows = append(rows, Row{
+				"tick":              snapshot.Tick,
+				"time_ms":           snapshot.TimeMs,
+				"token_id":          t.ID,
+				"class":             t.Class,
+				"state":             t.State,
+				"stage_id":          t.StageID,
+				"queue_index":       t.QueueIndex,
+				"service_remaining": t.ServiceRemaining,
+				"cycle":             t.Cycle,
+				"warmup":            snapshot.Warmup,
+			})
+		}
+	}
+	return Table{Columns: TokenColumns, Rows: rows}
+}
+
+func (t Table) Where(expr Expr) Table {
+	var rows []Row
+	for _, row := range t.Rows {
+		if expr.Match(row) {
+			rows = append(rows, row)
+		}
+	}
+	return Table{Columns: t.Columns, Rows: rows}
+}
+
+func (t Table) Select(columns []string) (Table, error) {
+	if len(columns) == 0 {
+		return t, nil
+	}
+	for _, column := range columns {
+		if !contains(t.Columns, column) {
+			return Table{}, fmt.Errorf("query: unknown field %q (fields: %s)", column, strings.Join(t.Columns, ", "))
+		}
+	}
+	return Table{Columns: columns, Rows: t.Rows}, nil
+}
+
+func (t Table) WriteJSON(w io.Writer) error {
+	out := make([]json.RawMessage, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		var b strings.Builder
+		b.WriteByte('{')
+		for i, column := range t.Columns {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			key, _ := json.Marshal(column)
+			value, err := json.Marshal(row[column])
+			if err != nil {
+				return err
+			}
+			b.Write(key)
+			b.WriteByte(':')
+			b.Write(value)
+		}
+		b.WriteByte('}')
+		out = append(out, json.RawMessage(b.String()))
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+func (t Table) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(t.Columns); err != nil {
+		return err
+	}
+	record := make([]string, len(t.Columns))
+	for _, row := range t.Rows {
+		for i, column := range t.Columns {
+			record[i] = format(row[column])
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func format(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case int:
+		return strconv.Itoa(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,59 @@
+package render
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"finit/engine"
+)
+
+var asciiGlyphs = map[string]byte{
+	engine.StateQueued:     '.',
+	engine.StateProcessing: '#',
+	engine.StateDone:       '-',
+	engine.StateRejected:   'x',
+}
+
+var asciiPriority = map[byte]int{
+	' ': 0,
+	'-': 1,
+	'.': 2,
+	'x': 3,
+	'#': 4,
+}
+
+func ASCII(w io.Writer, artifact engine.Artifact, width int) error {
+	ticks := tickCount(artifact)
+	if ticks == 0 {
+		return errors.New("artifact has no ticks to render")
+	}
+	if width <= 0 || width > ticks {
+		width = ticks
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "%s seed=%d ticks=%d (1 column = %.2f ticks)\n",
+		artifact.Metadata.ScenarioID, artifact.Metadata.Seed, ticks, float64(ticks)/float64(width))
+	fmt.Fprintln(out, "legend: . queued  # processing  - done  x rejected")
+
+	for _, row := range Timeline(artifact) {
+		line := make([]byte, width)
+		for i := range line {
+			line[i] = ' '
+		}
+		for _, segment := range row.Segments {
+			glyph := asciiGlyphs[segment.State]
+			first := segment.Start * width / ticks
+			last := (segment.End*width - 1) / ticks
+			for col := first; col <= last && col < width; col++ {
+				if asciiPriority[glyph] > asciiPriority[line[col]] {
+					line[col] = glyph
+				}
+			}
+		}
+		fmt.Fprintf(out, "%-6s %-4s |%s|\n", row.TokenID, row.Class, line)
+	}
+	return out.Flush()
+}
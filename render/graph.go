@@ -0,0 +1,94 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"finit/engine"
+)
+
+var mermaidShapes = map[string][2]string{
+	engine.NodeSource: {"([", "])"},
+	engine.NodeQueue:  {"[/", "/]"},
+	engine.NodeServer: {"[", "]"},
+	engine.NodeSink:   {"((", "))"},
+	engine.NodeDelay:  {"[[", "]]"},
+}
+
+var dotShapes = map[string]string{
+	engine.NodeSource: "oval",
+	engine.NodeQueue:  "parallelogram",
+	engine.NodeServer: "box",
+	engine.NodeSink:   "doublecircle",
+	engine.NodeDelay:  "cds",
+}
+
+func Mermaid(w io.Writer, topology engine.Topology) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "flowchart LR")
+	fmt.Fprintf(out, "  %%%% scenario %s\n", topology.ScenarioID)
+	for _, node := range topology.Nodes {
+		shape, ok := mermaidShapes[node.Kind]
+		if !ok {
+			shape = mermaidShapes[engine.NodeServer]
+		}
+		fmt.Fprintf(out, "  %s%s\"%s\"%s\n", node.ID, shape[0], nodeLabel(node, "<br/>"), shape[1])
+	}
+	for _, edge := range topology.Edges {
+		if label := edgeLabel(edge); label != "" {
+			fmt.Fprintf(out, "  %s -->|\"%s\"| %s\n", edge.From, label, edge.To)
+			continue
+		}
+		fmt.Fprintf(out, "  %s --> %s\n", edge.From, edge.To)
+	}
+	return out.Flush()
+}
+
+func DOT(w io.Writer, topology engine.Topology) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "digraph %q {\n", topology.ScenarioID)
+	fmt.Fprintln(out, "  rankdir=LR;")
+	for _, node := range topology.Nodes {
+		shape, ok := dotShapes[node.Kind]
+		if !ok {
+			shape = dotShapes[engine.NodeServer]
+		}
+		fmt.Fprintf(out, "  %q [shape=%s, label=%q];\n", node.ID, shape, nodeLabel(node, "\n"))
+	}
+	for _, edge := range topology.Edges {
+		if label := edgeLabel(edge); label != "" {
+			fmt.Fprintf(out, "  %q -> %q [label=%q];\n", edge.From, edge.To, label)
+			continue
+		}
+		fmt.Fprintf(out, "  %q -> %q;\n", edge.From, edge.To)
+	}
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+func nodeLabel(node engine.Node, sep string) string {
+	parts := []string{node.ID}
+	switch {
+	case node.Kind == engine.NodeQueue && node.Capacity > 0:
+		parts = append(parts, fmt.Sprintf("limit %d", node.Capacity))
+	case node.Capacity > 0:
+		parts = append(parts, fmt.Sprintf("capacity %d", node.Capacity))
+	}
+	if node.ServiceTime > 0 {
+		parts = append(parts, fmt.Sprintf("service %d tick(s)", node.ServiceTime))
+	}
+	return strings.Join(parts, sep)
+}
+
+func edgeLabel(edge engine.Edge) string {
+	var parts []string
+	if edge.Condition != "" {
+		parts = append(parts, edge.Condition)
+	}
+	if edge.Probability > 0 && edge.Probability < 1 {
+		parts = append(parts, fmt.Sprintf("p=%.2f", edge.Probability))
+	}
+	return strings.ReplaceAll(strings.Join(parts, ", "), `"`, "'")
+}
@@ -0,0 +1,56 @@
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestMermaid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Mermaid(&buf, engine.CanonicalScenario().Topology()); err != nil {
+		t.Fatalf("Mermaid() error = %v", err)
+	}
+	for _, want := range []string{
+		"flowchart LR",
+		`service["service<br/>capacity 3<br/>service 1 tick(s)"]`,
+		`queue[/"queue<br/>limit 12"/]`,
+		`arrivals -->|"ANON and queue >= 12"| rejected`,
+		"service --> done",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Mermaid() missing %q in:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestDOT(t *testing.T) {
+	topology := engine.Topology{
+		ScenarioID: "split",
+		Nodes: []engine.Node{
+			{ID: "a", Kind: engine.NodeServer, Capacity: 2},
+			{ID: "b", Kind: engine.NodeSink},
+			{ID: "t", Kind: engine.NodeDelay},
+		},
+		Edges: []engine.Edge{
+			{From: "a", To: "b", Probability: 0.25},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := DOT(&buf, topology); err != nil {
+		t.Fatalf("DOT() error = %v", err)
+	}
+	for _, want := range []string{
+		`digraph "split" {`,
+		`"a" [shape=box, label="a\ncapacity 2"];`,
+		`"a" -> "b" [label="p=0.25"];`,
+		`"t" [shape=cds, label="t"];`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("DOT() missing %q in:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"finit/engine"
+)
+
+func testArtifact() engine.Artifact {
+	return engine.Artifact{
+		Metadata: engine.Metadata{ScenarioID: engine.ScenarioID, Seed: 1, TickCount: 10},
+		Events: []engine.Event{
+			{Tick: 0, Type: engine.EventQueue, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 2, Type: engine.EventSchedule, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 3, Type: engine.EventComplete, TokenID: "T0000", Class: engine.ClassPaid},
+			{Tick: 4, Type: engine.EventReject, TokenID: "T0001", Class: engine.ClassAnon},
+			{Tick: 5, Type: engine.EventQueue, TokenID: "T0002", Class: engine.ClassFree},
+		},
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	rows := Timeline(testArtifact())
+	want := []Row{
+		{TokenID: "T0000", Class: engine.ClassPaid, Segments: []Segment{
+			{State: engine.StateQueued, Start: 0, End: 2},
+			{State: engine.StateProcessing, Start: 2, End: 3},
+			{State: engine.StateDone, Start: 3, End: 10},
+		}},
+		{TokenID: "T0001", Class: engine.ClassAnon, Segments: []Segment{
+			{State: engine.StateRejected, Start: 4, End: 5},
+		}},
+		{TokenID: "T0002", Class: engine.ClassFree, Segments: []Segment{
+			{State: engine.StateQueued, Start: 5, End: 10},
+		}},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("Timeline() = %+v, want %+v", rows, want)
+	}
+}
+
+func TestASCII(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ASCII(&buf, testArtifact(), 0); err != nil {
+		t.Fatalf("ASCII() error = %v", err)
+	}
+	for _, want := range []string{
+		"T0000  PAID |..#-------|",
+		"T0001  ANON |    x     |",
+		"T0002  FREE |     .....|",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("ASCII() missing %q in:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestASCII_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ASCII(&buf, engine.Artifact{}, 80); err == nil {
+		t.Errorf("ASCII() error = nil, want an error for an artifact without ticks")
+	}
+}
+
+func TestSVG_WellFormed(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SVG(&buf, testArtifact()); err != nil {
+		t.Fatalf("SVG() error = %v", err)
+	}
+	decoder := xml.NewDecoder(&buf)
+	for {
+		_, err := decoder.Token()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("SVG() is not well-formed XML: %v", err)
+			}
+			break
+		}
+	}
+}
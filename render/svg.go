@@ -0,0 +1,82 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+
+	"finit/engine"
+)
+
+const (
+	svgLabelWidth = 96
+	svgTickWidth  = 4
+	svgRowHeight  = 8
+	svgBarHeight  = 6
+	svgHeader     = 28
+)
+
+var classColors = map[string]string{
+	engine.ClassPaid: "#253f5d",
+	engine.ClassFree: "#a1aab5",
+	engine.ClassAnon: "#c2cbd7",
+}
+
+var stateOpacity = map[string]string{
+	engine.StateQueued:     "0.35",
+	engine.StateProcessing: "1",
+	engine.StateDone:       "0.12",
+}
+
+const rejectedColor = "#b5473a"
+
+func SVG(w io.Writer, artifact engine.Artifact) error {
+	ticks := tickCount(artifact)
+	rows := Timeline(artifact)
+	width := svgLabelWidth + ticks*svgTickWidth + 8
+	height := svgHeader + len(rows)*svgRowHeight + 8
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="8">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(out, `<rect width="%d" height="%d" fill="#f5f2ed"/>`+"\n", width, height)
+	fmt.Fprintf(out, `<text x="4" y="12" font-size="10" fill="#10141c">%s · seed %d · %s</text>`+"\n",
+		html.EscapeString(artifact.Metadata.ScenarioID), artifact.Metadata.Seed, html.EscapeString(artifact.Metadata.ReplayID))
+
+	for tick := 0; tick <= ticks; tick += 20 {
+		x := svgLabelWidth + tick*svgTickWidth
+		fmt.Fprintf(out, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#d7d0c5" stroke-width="0.5"/>`+"\n",
+			x, svgHeader-6, x, height-8)
+		fmt.Fprintf(out, `<text x="%d" y="%d" fill="#5b6572">%d</text>`+"\n", x+1, svgHeader-8, tick)
+	}
+
+	for i, row := range rows {
+		y := svgHeader + i*svgRowHeight
+		color := artifact.Metadata.ClassColor(row.Class)
+		if color == "" {
+			color = classColors[row.Class]
+		}
+		if color == "" {
+			color = "#5b6572"
+		}
+		fmt.Fprintf(out, `<g><title>%s %s</title>`, html.EscapeString(row.TokenID), html.EscapeString(row.Class))
+		fmt.Fprintf(out, `<text x="4" y="%d" fill="#10141c" font-size="6">%s %s</text>`,
+			y+svgBarHeight, html.EscapeString(row.TokenID), html.EscapeString(row.Class))
+		for _, segment := range row.Segments {
+			x := svgLabelWidth + segment.Start*svgTickWidth
+			w := (segment.End - segment.Start) * svgTickWidth
+			if segment.State == engine.StateRejected {
+				fmt.Fprintf(out, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`,
+					x, y, w, svgBarHeight, rejectedColor)
+				continue
+			}
+			fmt.Fprintf(out, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" fill-opacity="%s"/>`,
+				x, y, w, svgBarHeight, color, stateOpacity[segment.State])
+		}
+		fmt.Fprintln(out, `</g>`)
+	}
+
+	fmt.Fprintln(out, `</svg>`)
+	return out.Flush()
+}
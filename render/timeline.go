@@ -0,0 +1,64 @@
+package render
+
+import "finit/engine"
+
+type Segment struct {
+	State string
+	Start int
+	End   int
+}
+
+type Row struct {
+	TokenID  string
+	Class    string
+	Segments []Segment
+}
+
+func Timeline(artifact engine.Artifact) []Row {
+	tickCount := tickCount(artifact)
+	lifecycles := engine.Lifecycles(artifact.Events)
+	rows := make([]Row, 0, len(lifecycles))
+	for _, lifecycle := range lifecycles {
+		rows = append(rows, Row{
+			TokenID:  lifecycle.TokenID,
+			Class:    lifecycle.Class,
+			Segments: segments(lifecycle, tickCount),
+		})
+	}
+	return rows
+}
+
+func segments(l engine.Lifecycle, tickCount int) []Segment {
+	if l.Rejected() {
+		return []Segment{{State: engine.StateRejected, Start: l.RejectTick, End: l.RejectTick + 1}}
+	}
+
+	var out []Segment
+	queueEnd := tickCount
+	if l.Scheduled() {
+		queueEnd = l.ScheduleTick
+	}
+	if queueEnd > l.ArrivalTick {
+		out = append(out, Segment{State: engine.StateQueued, Start: l.ArrivalTick, End: queueEnd})
+	}
+	if !l.Scheduled() {
+		return out
+	}
+
+	serviceEnd := tickCount
+	if l.Completed() {
+		serviceEnd = l.CompleteTick
+	}
+	out = append(out, Segment{State: engine.StateProcessing, Start: l.ScheduleTick, End: serviceEnd})
+	if l.Completed() && l.CompleteTick < tickCount {
+		out = append(out, Segment{State: engine.StateDone, Start: l.CompleteTick, End: tickCount})
+	}
+	return out
+}
+
+func tickCount(artifact engine.Artifact) int {
+	if artifact.Metadata.TickCount > 0 {
+		return artifact.Metadata.TickCount
+	}
+	return len(artifact.Snapshots)
+}
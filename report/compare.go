@@ -0,0 +1,110 @@
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"strings"
+
+	"finit/analysis"
+)
+
+//go:embed compare.html.tmpl
+var compareTemplate string
+
+const (
+	beforeColor = "#a1aab5"
+	afterColor  = "#253f5d"
+)
+
+type comparePage struct {
+	analysis.RunComparison
+	Overall []analysis.MetricDelta
+	Classes []classDeltas
+}
+
+type classDeltas struct {
+	Class   string
+	Metrics []analysis.MetricDelta
+	Chart   template.HTML
+}
+
+var compareTmpl = template.Must(template.New("compare").Funcs(template.FuncMap{
+	"value": func(v float64) string {
+		if v == math.Trunc(v) || math.Abs(v) >= 100 {
+			return fmt.Sprintf("%.0f", v)
+		}
+		return fmt.Sprintf("%.3g", v)
+	},
+	"change": func(m analysis.MetricDelta) string {
+		if m.Before == 0 || m.Change == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%+.0f%%", 100*m.Change)
+	},
+	"mul100": func(v float64) float64 { return 100 * v },
+}).Parse(compareTemplate))
+
+func WriteComparison(w io.Writer, comparison analysis.RunComparison) error {
+	page := comparePage{RunComparison: comparison}
+	index := make(map[string]int)
+	for _, m := range comparison.Metrics {
+		if m.Class == "" {
+			page.Overall = append(page.Overall, m)
+			continue
+		}
+		i, ok := index[m.Class]
+		if !ok {
+			i = len(page.Classes)
+			index[m.Class] = i
+			page.Classes = append(page.Classes, classDeltas{Class: m.Class})
+		}
+		page.Classes[i].Metrics = append(page.Classes[i].Metrics, m)
+	}
+	for _, latency := range comparison.Latency {
+		if i, ok := index[latency.Class]; ok {
+			page.Classes[i].Chart = comparisonChart(latency)
+		}
+	}
+	return compareTmpl.Execute(w, page)
+}
+
+func comparisonChart(latency analysis.LatencyComparison) template.HTML {
+	if latency.Before.Total() == 0 && latency.After.Total() == 0 {
+		return template.HTML(`<p class="muted">No completions.</p>`)
+	}
+	labels := latency.Before.Labels()
+	peak := 1
+	share := func(h analysis.Histogram, i int) int {
+		if h.Total() == 0 {
+			return 0
+		}
+		return h.Counts[i] * 1000 / h.Total()
+	}
+	for i := range labels {
+		peak = max(peak, share(latency.Before, i), share(latency.After, i))
+	}
+
+	slot := histogramWidth / len(labels)
+	bar := max(slot/2-1, 1)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg viewBox="0 0 %d 100" width="%d" height="100" role="img" aria-label="Latency distribution before and after">`,
+		histogramWidth, histogramWidth)
+	for i, label := range labels {
+		for j, side := range []struct {
+			name  string
+			h     analysis.Histogram
+			color string
+		}{{"before", latency.Before, beforeColor}, {"after", latency.After, afterColor}} {
+			h := share(side.h, i) * 70 / peak
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"><title>%s %s ms: %d</title></rect>`,
+				i*slot+j*(bar+1), 80-h, bar, h, side.color, side.name, label, side.h.Counts[i])
+		}
+	}
+	fmt.Fprintf(&b, `<text x="0" y="94" font-size="9" fill="#5b6572">0 ms</text>`)
+	fmt.Fprintf(&b, `<text x="%d" y="94" font-size="9" fill="#5b6572" text-anchor="end">%s ms</text>`, histogramWidth, labels[len(labels)-1])
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
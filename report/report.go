@@ -0,0 +1,156 @@
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"finit/analysis"
+	"finit/engine"
+)
+
+//go:embed report.html.tmpl
+var reportTemplate string
+
+const (
+	chartWidth     = 720
+	chartHeight    = 160
+	histogramWidth = 220
+	histogramBins  = 12
+)
+
+var classColors = map[string]string{
+	engine.ClassPaid: "#253f5d",
+	engine.ClassFree: "#a1aab5",
+	engine.ClassAnon: "#c2cbd7",
+}
+
+func classColor(metadata engine.Metadata, class string) string {
+	if color := metadata.ClassColor(class); color != "" {
+		return color
+	}
+	return classColors[class]
+}
+
+type page struct {
+	Metadata   engine.Metadata
+	Summary    analysis.Summary
+	QueueChart template.HTML
+	Histograms []histogram
+	Events     []engine.Event
+}
+
+type histogram struct {
+	Class string
+	Count int
+	Chart template.HTML
+}
+
+var tmpl = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent": func(v float64) string { return fmt.Sprintf("%.1f%%", v*100) },
+	"ms":      func(v float64) string { return fmt.Sprintf("%.0f ms", v) },
+	"seconds": func(ms int) string { return fmt.Sprintf("%.1f s", float64(ms)/1000) },
+}).Parse(reportTemplate))
+
+func Write(w io.Writer, artifact engine.Artifact) error {
+	summary := analysis.Summarize(artifact)
+	latencies := analysis.Latencies(artifact)
+
+	histograms := make([]histogram, 0, len(summary.Classes))
+	for _, cs := range summary.Classes {
+		histograms = append(histograms, histogram{
+			Class: cs.Class,
+			Count: len(latencies[cs.Class]),
+			Chart: histogramChart(latencies[cs.Class], classColor(artifact.Metadata, cs.Class)),
+		})
+	}
+
+	return tmpl.Execute(w, page{
+		Metadata:   artifact.Metadata,
+		Summary:    summary,
+		QueueChart: queueChart(analysis.QueueLengths(artifact), analysis.InService(artifact), summary.Anomalies, artifact.Metadata.TickCount),
+		Histograms: histograms,
+		Events:     artifact.Events,
+	})
+}
+
+func queueChart(queue []int, inService []int, anomalies []analysis.Anomaly, tickCount int) template.HTML {
+	maxValue := 1
+	for _, series := range [][]int{queue, inService} {
+		for _, v := range series {
+			if v > maxValue {
+				maxValue = v
+			}
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg viewBox="0 0 %d %d" width="%d" height="%d" role="img" aria-label="Queue length over time">`,
+		chartWidth, chartHeight+20, chartWidth, chartHeight+20)
+	for _, a := range anomalies {
+		x := float64(a.FromTick) / float64(max(tickCount-1, 1)) * chartWidth
+		width := float64(a.ToTick-a.FromTick) / float64(max(tickCount-1, 1)) * chartWidth
+		fmt.Fprintf(&b, `<rect x="%.1f" y="0" width="%.1f" height="%d" fill="#f3d9c4" opacity="0.45"><title>%s, ticks %d-%d</title></rect>`,
+			x, width, chartHeight, a.Kind, a.FromTick, a.ToTick)
+	}
+	fmt.Fprintf(&b, `<line x1="0" y1="%d" x2="%d" y2="%d" stroke="#d7d0c5"/>`, chartHeight, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<text x="2" y="10" font-size="10" fill="#5b6572">%d</text>`, maxValue)
+	b.WriteString(polyline(queue, maxValue, "#253f5d"))
+	b.WriteString(polyline(inService, maxValue, "#a1aab5"))
+	fmt.Fprintf(&b, `<text x="2" y="%d" font-size="10" fill="#253f5d">queue length</text>`, chartHeight+16)
+	fmt.Fprintf(&b, `<text x="90" y="%d" font-size="10" fill="#7f8995">in service</text>`, chartHeight+16)
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+func polyline(series []int, maxValue int, color string) string {
+	if len(series) == 0 {
+		return ""
+	}
+	step := float64(chartWidth) / float64(max(len(series)-1, 1))
+	points := make([]string, 0, len(series))
+	for i, v := range series {
+		x := float64(i) * step
+		y := float64(chartHeight) - float64(v)/float64(maxValue)*float64(chartHeight-12)
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	return fmt.Sprintf(`<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"/>`, color, strings.Join(points, " "))
+}
+
+func histogramChart(values []int, color string) template.HTML {
+	if len(values) == 0 {
+		return template.HTML(`<p class="muted">No completions.</p>`)
+	}
+	low, high := values[0], values[0]
+	for _, v := range values {
+		low = min(low, v)
+		high = max(high, v)
+	}
+	width := (high - low + histogramBins) / histogramBins
+	if width <= 0 {
+		width = 1
+	}
+	bins := make([]int, histogramBins)
+	peak := 1
+	for _, v := range values {
+		i := min((v-low)/width, histogramBins-1)
+		bins[i]++
+		peak = max(peak, bins[i])
+	}
+
+	barWidth := histogramWidth / histogramBins
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg viewBox="0 0 %d 100" width="%d" height="100" role="img" aria-label="Latency histogram">`,
+		histogramWidth, histogramWidth)
+	for i, count := range bins {
+		h := count * 70 / peak
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"><title>%d–%d ms: %d</title></rect>`,
+			i*barWidth, 80-h, barWidth-1, h, color, low+i*width, low+(i+1)*width, count)
+	}
+	fmt.Fprintf(&b, `<text x="0" y="94" font-size="9" fill="#5b6572">%d ms</text>`, low)
+	fmt.Fprintf(&b, `<text x="%d" y="94" font-size="9" fill="#5b6572" text-anchor="end">%d ms</text>`, histogramWidth, high)
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
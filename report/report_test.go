@@ -0,0 +1,74 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"finit/analysis"
+	"finit/engine"
+)
+
+func TestWrite(t *testing.T) {
+	artifact, err := engine.Run(engine.Config{Seed: 1})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, artifact); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	html := buf.String()
+	for _, want := range []string{
+		"<!doctype html>",
+		artifact.Metadata.ReplayID,
+		"<td>PAID</td>",
+		"<polyline",
+		"REJECT_OVERLOAD",
+		"<h3>Anomalies</h3>",
+		"saturation",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Write() output missing %q", want)
+		}
+	}
+	for _, external := range []string{"<script src", "<link", "<img src"} {
+		if strings.Contains(html, external) {
+			t.Errorf("Write() output references external resource %q", external)
+		}
+	}
+}
+
+func TestWriteComparison(t *testing.T) {
+	before, err := engine.Run(engine.Config{Seed: 1})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	scenario := engine.CanonicalScenario()
+	scenario.Capacity = 1
+	after, err := engine.Run(engine.NewConfig(engine.WithScenarioSpec(scenario), engine.WithSeed(1)))
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteComparison(&buf, analysis.CompareRuns(before, after, "baseline", "one-server", 0.1)); err != nil {
+		t.Fatalf("WriteComparison() error = %v", err)
+	}
+
+	html := buf.String()
+	for _, want := range []string{
+		"baseline",
+		"one-server",
+		"<h3>PAID</h3>",
+		`<li class="worse">completed fell`,
+		`fill="#a1aab5"`,
+		`fill="#253f5d"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("WriteComparison() output missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"finit/engine"
+	"finit/metrics"
+)
+
+func TestServer_RunsAndMetrics(t *testing.T) {
+	srv := httptest.NewServer(New(metrics.NewRegistry()))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/runs?seed=3", "", nil)
+	if err != nil {
+		t.Fatalf("POST /runs error = %v", err)
+	}
+	var artifact engine.Artifact
+	if err := json.NewDecoder(resp.Body).Decode(&artifact); err != nil {
+		t.Fatalf("decode artifact: %v", err)
+	}
+	resp.Body.Close()
+	if artifact.Metadata.Seed != 3 {
+		t.Errorf("artifact seed = %d, want 3", artifact.Metadata.Seed)
+	}
+
+	resp, err = http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read metrics: %v", err)
+	}
+	if !strings.Contains(string(body), `run_id="`+artifact.Metadata.ReplayID+`"`) {
+		t.Errorf("metrics missing run_id label:\n%s", body)
+	}
+}
+
+func TestServer_RunsRejectsBadInput(t *testing.T) {
+	handler := New(metrics.NewRegistry())
+	tests := []struct {
+		name   string
+		method string
+		target string
+		want   int
+	}{
+		{name: "wrong method", method: http.MethodGet, target: "/runs", want: http.StatusMethodNotAllowed},
+		{name: "bad seed", method: http.MethodPost, target: "/runs?seed=x", want: http.StatusBadRequest},
+		{name: "unknown scenario", method: http.MethodPost, target: "/runs?scenario_id=nope", want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
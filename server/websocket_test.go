@@ -0,0 +1,84 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcceptKey(t *testing.T) {
+	got := AcceptKey("dGhlIHNhbXBsZSBub25jZQ==")
+	if want := "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Errorf("AcceptKey() = %q, want %q", got, want)
+	}
+}
+
+func TestHub_Broadcast(t *testing.T) {
+	hub := NewHub()
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+	defer hub.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n"))
+	if err != nil {
+		t.Fatalf("write handshake: %v", err)
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatalf("read handshake: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status = %d, want 101", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", got)
+	}
+
+	for hub.Clients() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	hub.Broadcast([]byte(`{"type":"run"}`))
+
+	opcode, payload, err := readFrame(r)
+	if err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	if opcode != opText || string(payload) != `{"type":"run"}` {
+		t.Errorf("frame = (%d, %q), want text %q", opcode, payload, `{"type":"run"}`)
+	}
+
+	frame := []byte{0x80 | opClose, 0x80, 1, 2, 3, 4}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("write close: %v", err)
+	}
+	if opcode, _, err := readFrame(r); err != nil || opcode != opClose {
+		t.Errorf("close reply = (%d, %v), want close frame", opcode, err)
+	}
+}
+
+func TestHub_RejectsPlainRequest(t *testing.T) {
+	srv := httptest.NewServer(NewHub())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+}
@@ -0,0 +1,170 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"finit/engine"
+)
+
+const natsTimeout = 5 * time.Second
+
+type NATS struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	w       *bufio.Writer
+	prefix  string
+	headers string
+	err     error
+}
+
+func DialNATS(addr string, subjectPrefix string) (*NATS, error) {
+	conn, err := net.DialTimeout("tcp", addr, natsTimeout)
+	if err != nil {
+		return nil, err
+	}
+	n := &NATS{
+		conn:   conn,
+		r:      bufio.NewReader(conn),
+		w:      bufio.NewWriter(conn),
+		prefix: strings.TrimSuffix(subjectPrefix, "."),
+	}
+	if err := n.handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n *NATS) handshake() error {
+	_ = n.conn.SetDeadline(time.Now().Add(natsTimeout))
+	defer func() { _ = n.conn.SetDeadline(time.Time{}) }()
+
+	line, err := n.readLine()
+	if err != nil {
+		return fmt.Errorf("nats: read INFO: %w", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("nats: unexpected greeting %q", line)
+	}
+	var info struct {
+		Headers bool `json:"headers"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info); err != nil {
+		return fmt.Errorf("nats: decode INFO: %w", err)
+	}
+	if !info.Headers {
+		return errors.New("nats: server does not support message headers")
+	}
+
+	connect, _ := json.Marshal(map[string]any{
+		"verbose":  false,
+		"pedantic": false,
+		"headers":  true,
+		"name":     "finit",
+		"lang":     "go",
+		"version":  engine.EngineVersion,
+		"protocol": 1,
+	})
+	fmt.Fprintf(n.w, "CONNECT %s\r\n", connect)
+	return n.flushAndPing()
+}
+
+func (n *NATS) OnStart(metadata engine.Metadata) {
+	var b strings.Builder
+	b.WriteString("NATS/1.0\r\n")
+	fmt.Fprintf(&b, "Finit-Scenario-Id: %s\r\n", metadata.ScenarioID)
+	fmt.Fprintf(&b, "Finit-Replay-Id: %s\r\n", metadata.ReplayID)
+	fmt.Fprintf(&b, "Finit-Seed: %d\r\n", metadata.Seed)
+	fmt.Fprintf(&b, "Finit-Engine-Version: %s\r\n", metadata.EngineVersion)
+	b.WriteString("\r\n")
+	n.headers = b.String()
+}
+
+func (n *NATS) OnEvent(event engine.Event) {
+	if n.err != nil {
+		return
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		n.err = err
+		return
+	}
+	n.err = n.publish(n.Subject(event), payload)
+}
+
+func (n *NATS) OnSnapshot(engine.Snapshot) {}
+
+func (n *NATS) Subject(event engine.Event) string {
+	return n.prefix + "." + strings.ToLower(event.Type)
+}
+
+func (n *NATS) Err() error {
+	return n.err
+}
+
+func (n *NATS) Close() error {
+	err := n.err
+	if err == nil {
+		err = n.flushAndPing()
+	}
+	if cerr := n.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (n *NATS) publish(subject string, payload []byte) error {
+	headers := n.headers
+	if headers == "" {
+		headers = "NATS/1.0\r\n\r\n"
+	}
+	fmt.Fprintf(n.w, "HPUB %s %d %d\r\n", subject, len(headers), len(headers)+len(payload))
+	n.w.WriteString(headers)
+	n.w.Write(payload)
+	_, err := n.w.WriteString("\r\n")
+	return err
+}
+
+func (n *NATS) flushAndPing() error {
+	if _, err := n.w.WriteString("PING\r\n"); err != nil {
+		return err
+	}
+	if err := n.w.Flush(); err != nil {
+		return err
+	}
+	_ = n.conn.SetReadDeadline(time.Now().Add(natsTimeout))
+	defer func() { _ = n.conn.SetReadDeadline(time.Time{}) }()
+	for {
+		line, err := n.readLine()
+		if err != nil {
+			return fmt.Errorf("nats: %w", err)
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := n.w.WriteString("PONG\r\n"); err != nil {
+				return err
+			}
+			if err := n.w.Flush(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (n *NATS) readLine() (string, error) {
+	line, err := n.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,122 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"finit/engine"
+)
+
+type natsMessage struct {
+	subject string
+	headers string
+	payload string
+}
+
+func fakeNATSServer(t *testing.T) (string, <-chan []natsMessage) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []natsMessage, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, "INFO {\"server_id\":\"test\",\"headers\":true}\r\n")
+
+		var messages []natsMessage
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				received <- messages
+				return
+			}
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case "PING":
+				fmt.Fprint(conn, "PONG\r\n")
+			case "HPUB":
+				headerLen, _ := strconv.Atoi(fields[2])
+				totalLen, _ := strconv.Atoi(fields[3])
+				body := make([]byte, totalLen+2)
+				if _, err := io.ReadFull(r, body); err != nil {
+					received <- messages
+					return
+				}
+				messages = append(messages, natsMessage{
+					subject: fields[1],
+					headers: string(body[:headerLen]),
+					payload: string(body[headerLen:totalLen]),
+				})
+			}
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestNATS_PublishesEventsWithHeaders(t *testing.T) {
+	addr, received := fakeNATSServer(t)
+
+	publisher, err := DialNATS(addr, "finit.events.")
+	if err != nil {
+		t.Fatalf("DialNATS() error = %v", err)
+	}
+	publisher.OnStart(engine.Metadata{ScenarioID: engine.ScenarioID, ReplayID: "r1", Seed: 9, EngineVersion: engine.EngineVersion})
+	publisher.OnEvent(engine.Event{Tick: 2, Type: engine.EventQueue, TokenID: "T0002", Class: engine.ClassPaid})
+	publisher.OnEvent(engine.Event{Tick: 3, Type: engine.EventReject, TokenID: "T0003", Class: engine.ClassAnon})
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	messages := <-received
+	if len(messages) != 2 {
+		t.Fatalf("received %d messages, want 2", len(messages))
+	}
+	if messages[0].subject != "finit.events.queue" || messages[1].subject != "finit.events.reject" {
+		t.Errorf("subjects = %q, %q", messages[0].subject, messages[1].subject)
+	}
+	if !strings.Contains(messages[0].headers, "Finit-Replay-Id: r1\r\n") || !strings.Contains(messages[0].headers, "Finit-Seed: 9\r\n") {
+		t.Errorf("headers = %q", messages[0].headers)
+	}
+	var event engine.Event
+	if err := json.Unmarshal([]byte(messages[1].payload), &event); err != nil {
+		t.Fatalf("payload is not an event: %v", err)
+	}
+	if event.TokenID != "T0003" {
+		t.Errorf("payload token = %s, want T0003", event.TokenID)
+	}
+}
+
+func TestDialNATS_RequiresHeaders(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, "INFO {\"server_id\":\"old\"}\r\n")
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+
+	if _, err := DialNATS(listener.Addr().String(), "finit"); err == nil {
+		t.Error("DialNATS() should fail when the server lacks header support")
+	}
+}
@@ -0,0 +1,54 @@
+package sink
+
+import (
+	"context"
+	"log/slog"
+
+	"finit/engine"
+)
+
+type SlogOptions struct {
+	Level       slog.Level
+	SampleEvery int
+}
+
+type Slog struct {
+	logger *slog.Logger
+	opts   SlogOptions
+	seen   int
+}
+
+func NewSlog(logger *slog.Logger, opts SlogOptions) *Slog {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if opts.SampleEvery <= 0 {
+		opts.SampleEvery = 1
+	}
+	return &Slog{logger: logger, opts: opts}
+}
+
+func (s *Slog) OnEvent(event engine.Event) {
+	s.seen++
+	if (s.seen-1)%s.opts.SampleEvery != 0 {
+		return
+	}
+	ctx := context.Background()
+	if !s.logger.Enabled(ctx, s.opts.Level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.Int("tick", event.Tick),
+		slog.String("type", event.Type),
+		slog.String("token_id", event.TokenID),
+		slog.String("class", event.Class),
+		slog.String("stage_id", event.StageID),
+		slog.String("reason", event.ReasonCode),
+	}
+	if event.Context != nil {
+		attrs = append(attrs, slog.String("rule", event.Context.Rule), slog.Int("queue_length", event.Context.QueueLength))
+	}
+	s.logger.LogAttrs(ctx, s.opts.Level, "simulation event", attrs...)
+}
+
+func (s *Slog) OnSnapshot(engine.Snapshot) {}
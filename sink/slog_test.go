@@ -0,0 +1,79 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestSlog_LogsStructuredEvents(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	observer := NewSlog(logger, SlogOptions{Level: slog.LevelInfo})
+
+	observer.OnEvent(engine.Event{
+		Tick:       4,
+		Type:       engine.EventReject,
+		ReasonCode: engine.ReasonRejectOverload,
+		TokenID:    "T0004",
+		StageID:    engine.StageRejected,
+		Class:      engine.ClassAnon,
+	})
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	want := map[string]any{
+		"msg":      "simulation event",
+		"tick":     float64(4),
+		"type":     engine.EventReject,
+		"token_id": "T0004",
+		"class":    engine.ClassAnon,
+		"reason":   engine.ReasonRejectOverload,
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("record[%q] = %v, want %v", key, record[key], value)
+		}
+	}
+}
+
+func TestSlog_SamplingAndLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	sampled := NewSlog(logger, SlogOptions{Level: slog.LevelInfo, SampleEvery: 3})
+	for i := 0; i < 7; i++ {
+		sampled.OnEvent(engine.Event{Tick: i})
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 3 {
+		t.Errorf("sampled lines = %d, want 3", lines)
+	}
+
+	buf.Reset()
+	quiet := NewSlog(logger, SlogOptions{Level: slog.LevelDebug})
+	quiet.OnEvent(engine.Event{Tick: 1})
+	if buf.Len() != 0 {
+		t.Errorf("debug-level events should be filtered by an info handler, got %q", buf.String())
+	}
+}
+
+func TestSlog_AsObserver(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	artifact, err := engine.Run(engine.Config{
+		Seed:      1,
+		Observers: []engine.Observer{NewSlog(logger, SlogOptions{})},
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != len(artifact.Events) {
+		t.Errorf("logged %d events, want %d", lines, len(artifact.Events))
+	}
+}
@@ -0,0 +1,132 @@
+package sink
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"finit/engine"
+)
+
+const maxPacketSize = 1432
+
+type MetricsSink interface {
+	Gauge(name string, value float64, tags ...string) error
+	Count(name string, value int64, tags ...string) error
+	Flush() error
+}
+
+type StatsD struct {
+	conn   io.Writer
+	prefix string
+	tags   []string
+	buf    bytes.Buffer
+}
+
+func DialStatsD(addr string, prefix string, tags ...string) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewStatsD(conn, prefix, tags...), nil
+}
+
+func NewStatsD(conn io.Writer, prefix string, tags ...string) *StatsD {
+	if prefix != "" && !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+	return &StatsD{conn: conn, prefix: prefix, tags: tags}
+}
+
+func (s *StatsD) Gauge(name string, value float64, tags ...string) error {
+	return s.write(name, strconv.FormatFloat(value, 'f', -1, 64), "g", tags)
+}
+
+func (s *StatsD) Count(name string, value int64, tags ...string) error {
+	return s.write(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+func (s *StatsD) Flush() error {
+	if s.buf.Len() == 0 {
+		return nil
+	}
+	_, err := s.conn.Write(s.buf.Bytes())
+	s.buf.Reset()
+	return err
+}
+
+func (s *StatsD) Close() error {
+	err := s.Flush()
+	if closer, ok := s.conn.(io.Closer); ok {
+		if cerr := closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (s *StatsD) write(name string, value string, kind string, tags []string) error {
+	line := s.prefix + name + ":" + value + "|" + kind
+	if all := append(append([]string(nil), s.tags...), tags...); len(all) > 0 {
+		line += "|#" + strings.Join(all, ",")
+	}
+	if s.buf.Len() > 0 && s.buf.Len()+1+len(line) > maxPacketSize {
+		if err := s.Flush(); err != nil {
+			return err
+		}
+	}
+	if s.buf.Len() > 0 {
+		s.buf.WriteByte('\n')
+	}
+	s.buf.WriteString(line)
+	return nil
+}
+
+type TickMetrics struct {
+	sink       MetricsSink
+	rejections map[string]int64
+	err        error
+}
+
+func NewTickMetrics(sink MetricsSink) *TickMetrics {
+	return &TickMetrics{sink: sink, rejections: make(map[string]int64)}
+}
+
+func (t *TickMetrics) OnEvent(event engine.Event) {
+	if event.Type == engine.EventReject {
+		t.rejections[event.Class]++
+	}
+}
+
+func (t *TickMetrics) OnSnapshot(snapshot engine.Snapshot) {
+	for _, stage := range snapshot.Stages {
+		stageTag := "stage:" + stage.ID
+		switch stage.ID {
+		case engine.StageQueue:
+			t.record(t.sink.Gauge("queue_depth", float64(stage.QueueLength), stageTag))
+		case engine.StageService:
+			t.record(t.sink.Gauge("in_service", float64(stage.CapacityUsed), stageTag))
+			if _, total := stage.Load(); total > 0 {
+				t.record(t.sink.Gauge("utilization", stage.Utilization(), stageTag))
+			}
+		}
+	}
+	for class, count := range t.rejections {
+		t.record(t.sink.Count("rejections", count, "class:"+class))
+		delete(t.rejections, class)
+	}
+	t.record(t.sink.Flush())
+}
+
+func (t *TickMetrics) Err() error {
+	return t.err
+}
+
+func (t *TickMetrics) record(err error) {
+	if err != nil && t.err == nil {
+		t.err = fmt.Errorf("metrics sink: %w", err)
+	}
+}
@@ -0,0 +1,87 @@
+package sink
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"finit/engine"
+)
+
+type packetRecorder struct {
+	packets []string
+}
+
+func (p *packetRecorder) Write(b []byte) (int, error) {
+	p.packets = append(p.packets, string(b))
+	return len(b), nil
+}
+
+func TestStatsD_Format(t *testing.T) {
+	var buf bytes.Buffer
+	statsd := NewStatsD(&buf, "finit", "env:test")
+	if err := statsd.Gauge("queue_depth", 4, "stage:queue"); err != nil {
+		t.Fatalf("Gauge() error = %v", err)
+	}
+	if err := statsd.Count("rejections", 2); err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	if err := statsd.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	want := "finit.queue_depth:4|g|#env:test,stage:queue\nfinit.rejections:2|c|#env:test"
+	if buf.String() != want {
+		t.Errorf("packet = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestStatsD_SplitsPackets(t *testing.T) {
+	var recorder packetRecorder
+	statsd := NewStatsD(&recorder, "")
+	for i := 0; i < 200; i++ {
+		if err := statsd.Gauge("a_fairly_long_metric_name_for_testing", float64(i)); err != nil {
+			t.Fatalf("Gauge() error = %v", err)
+		}
+	}
+	if err := statsd.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if len(recorder.packets) < 2 {
+		t.Fatalf("packets = %d, want several", len(recorder.packets))
+	}
+	for _, packet := range recorder.packets {
+		if len(packet) > maxPacketSize {
+			t.Errorf("packet size %d exceeds %d", len(packet), maxPacketSize)
+		}
+	}
+}
+
+func TestTickMetrics(t *testing.T) {
+	var recorder packetRecorder
+	metrics := NewTickMetrics(NewStatsD(&recorder, "finit"))
+
+	metrics.OnEvent(engine.Event{Type: engine.EventReject, Class: engine.ClassAnon})
+	metrics.OnEvent(engine.Event{Type: engine.EventReject, Class: engine.ClassAnon})
+	metrics.OnSnapshot(engine.Snapshot{Stages: []engine.StageState{
+		{ID: engine.StageQueue, QueueLength: 12},
+		{ID: engine.StageService, CapacityUsed: 3, CapacityTotal: 3},
+	}})
+	metrics.OnSnapshot(engine.Snapshot{})
+
+	if metrics.Err() != nil {
+		t.Fatalf("Err() = %v", metrics.Err())
+	}
+	if len(recorder.packets) != 1 {
+		t.Fatalf("packets = %d, want 1 (empty ticks send nothing)", len(recorder.packets))
+	}
+	for _, want := range []string{
+		"finit.queue_depth:12|g|#stage:queue",
+		"finit.utilization:1|g|#stage:service",
+		"finit.rejections:2|c|#class:ANON",
+	} {
+		if !strings.Contains(recorder.packets[0], want) {
+			t.Errorf("packet missing %q in %q", want, recorder.packets[0])
+		}
+	}
+}
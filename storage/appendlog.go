@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"finit/engine"
+)
+
+type logRecord struct {
+	Metadata *engine.Metadata `json:"metadata,omitempty"`
+	Event    *engine.Event    `json:"event,omitempty"`
+	Snapshot *engine.Snapshot `json:"snapshot,omitempty"`
+	Final    *engine.Metadata `json:"final,omitempty"`
+}
+
+type AppendLog struct {
+	f      *os.File
+	w      *bufio.Writer
+	enc    *json.Encoder
+	err    error
+	closed bool
+}
+
+func CreateAppendLog(path string) (*AppendLog, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	return &AppendLog{f: f, w: w, enc: json.NewEncoder(w)}, nil
+}
+
+func (l *AppendLog) OnStart(metadata engine.Metadata) {
+	l.append(logRecord{Metadata: &metadata})
+	l.sync()
+}
+
+func (l *AppendLog) OnEvent(event engine.Event) {
+	l.append(logRecord{Event: &event})
+}
+
+func (l *AppendLog) OnSnapshot(snapshot engine.Snapshot) {
+	l.append(logRecord{Snapshot: &snapshot})
+	l.sync()
+}
+
+func (l *AppendLog) Finish(metadata engine.Metadata) error {
+	l.append(logRecord{Final: &metadata})
+	l.sync()
+	return l.err
+}
+
+func (l *AppendLog) append(record logRecord) {
+	if l.err == nil {
+		l.err = l.enc.Encode(record)
+	}
+}
+
+func (l *AppendLog) sync() {
+	if l.err == nil {
+		l.err = l.w.Flush()
+	}
+	if l.err == nil {
+		l.err = l.f.Sync()
+	}
+}
+
+func (l *AppendLog) Err() error {
+	return l.err
+}
+
+func (l *AppendLog) Close() error {
+	if l.closed {
+		return l.err
+	}
+	l.closed = true
+	l.sync()
+	if err := l.f.Close(); l.err == nil {
+		l.err = err
+	}
+	return l.err
+}
+
+type Recovered struct {
+	Artifact engine.Artifact
+	Complete bool
+	LastTick int
+}
+
+func RecoverLog(path string) (Recovered, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Recovered{}, err
+	}
+	defer f.Close()
+
+	var recovered Recovered
+	var metadata, final *engine.Metadata
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Recovered{}, err
+		}
+		var record logRecord
+		if json.Unmarshal(line, &record) != nil {
+			break
+		}
+		switch {
+		case record.Metadata != nil:
+			metadata = record.Metadata
+		case record.Event != nil:
+			recovered.Artifact.Events = append(recovered.Artifact.Events, *record.Event)
+		case record.Snapshot != nil:
+			recovered.Artifact.Snapshots = append(recovered.Artifact.Snapshots, *record.Snapshot)
+		case record.Final != nil:
+			final = record.Final
+		}
+		if final != nil {
+			break
+		}
+	}
+	if metadata == nil {
+		return Recovered{}, fmt.Errorf("recover %s: no metadata record", path)
+	}
+	if len(recovered.Artifact.Snapshots) == 0 {
+		return Recovered{}, fmt.Errorf("recover %s: no complete tick was logged", path)
+	}
+
+	snapshots := recovered.Artifact.Snapshots
+	recovered.LastTick = snapshots[len(snapshots)-1].Tick
+	if final != nil {
+		recovered.Complete = true
+		recovered.Artifact.Metadata = *final
+	} else {
+		events := recovered.Artifact.Events[:0]
+		for _, event := range recovered.Artifact.Events {
+			if event.Tick <= recovered.LastTick {
+				events = append(events, event)
+			}
+		}
+		recovered.Artifact.Events = events
+		recovered.Artifact.Metadata = *metadata
+		recovered.Artifact.Metadata.Caveats = append(recovered.Artifact.Metadata.Caveats,
+			fmt.Sprintf("recovered from a partial log: the run stopped after tick %d of %d", recovered.LastTick, metadata.TickCount))
+	}
+	if retention := recovered.Artifact.Metadata.EventRetention; retention != nil {
+		events, summary := engine.RetainEvents(recovered.Artifact.Events, *retention)
+		recovered.Artifact.Events = events
+		recovered.Artifact.Metadata.EventRetention = &summary
+	}
+	return recovered, nil
+}
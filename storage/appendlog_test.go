@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestAppendLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.log")
+	log, err := CreateAppendLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifact, err := engine.Run(engine.Config{Seed: 1, Observers: []engine.Observer{log}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if err := log.Finish(artifact.Metadata); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	recovered, err := RecoverLog(path)
+	if err != nil {
+		t.Fatalf("RecoverLog() error = %v", err)
+	}
+	want, _ := engine.MarshalArtifact(artifact)
+	got, _ := engine.MarshalArtifact(recovered.Artifact)
+	if !recovered.Complete || !bytes.Equal(got, want) {
+		t.Errorf("RecoverLog() of a finished log = complete %v, artifact equal %v, want the run's artifact", recovered.Complete, bytes.Equal(got, want))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial := filepath.Join(t.TempDir(), "partial.log")
+	if err := os.WriteFile(partial, data[:len(data)/2], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	recovered, err = RecoverLog(partial)
+	if err != nil {
+		t.Fatalf("RecoverLog() of a torn log error = %v", err)
+	}
+	if recovered.Complete || recovered.LastTick <= 0 || recovered.LastTick >= artifact.Metadata.TickCount-1 {
+		t.Errorf("RecoverLog() of a torn log = complete %v, last tick %d", recovered.Complete, recovered.LastTick)
+	}
+	snapshots, events := recovered.Artifact.Snapshots, recovered.Artifact.Events
+	if last := events[len(events)-1]; last.Tick > recovered.LastTick {
+		t.Errorf("recovered event at tick %d after the last snapshot at tick %d", last.Tick, recovered.LastTick)
+	}
+	last, original := events[len(events)-1], artifact.Events[len(events)-1]
+	if snapshots[len(snapshots)-1].Tick != artifact.Snapshots[len(snapshots)-1].Tick || last.Type != original.Type || last.TokenID != original.TokenID {
+		t.Error("recovered snapshots and events are not a prefix of the run's")
+	}
+	caveats := recovered.Artifact.Metadata.Caveats
+	if len(caveats) != 1 || !strings.Contains(caveats[0], "partial log") {
+		t.Errorf("Metadata.Caveats = %q, want the partial log caveat", caveats)
+	}
+	if err := engine.ValidateArtifact(recovered.Artifact); err != nil {
+		t.Errorf("ValidateArtifact() error = %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"finit/arrow"
+	"finit/engine"
+)
+
+var arrowTableNames = []string{"metadata", "events", "token_states", "stage_states"}
+
+func WriteArrow(ctx context.Context, dir string, artifact engine.Artifact) error {
+	if IsRemote(dir) {
+		return errors.New("arrow output must be a local directory")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tables := arrowTables(artifact)
+	for _, name := range arrowTableNames {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := arrow.WriteFile(filepath.Join(dir, name+".arrow"), tables[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func arrowTables(artifact engine.Artifact) map[string]arrow.Table {
+	m := artifact.Metadata
+	metadata := arrow.Table{Columns: []arrow.Column{
+		&arrow.StringColumn{Field: "scenario_id", Values: []string{m.ScenarioID}},
+		&arrow.Int64Column{Field: "seed", Values: []int64{m.Seed}},
+		&arrow.StringColumn{Field: "engine_version", Values: []string{m.EngineVersion}},
+		&arrow.StringColumn{Field: "replay_id", Values: []string{m.ReplayID}},
+		&arrow.Int64Column{Field: "tick_count", Values: []int64{int64(m.TickCount)}},
+		&arrow.Int64Column{Field: "tick_duration_ms", Values: []int64{int64(m.TickDurationMs)}},
+		&arrow.Int64Column{Field: "total_duration_ms", Values: []int64{int64(m.TotalDurationMs)}},
+	}}
+
+	n := len(artifact.Events)
+	var (
+		tick        = &arrow.Int64Column{Field: "tick", Values: make([]int64, n)}
+		typ         = &arrow.StringColumn{Field: "type", Values: make([]string, n)}
+		reason      = &arrow.StringColumn{Field: "reason_code", Values: make([]string, n)}
+		tokenID     = &arrow.StringColumn{Field: "token_id", Values: make([]string, n)}
+		stageID     = &arrow.StringColumn{Field: "stage_id", Values: make([]string, n)}
+		class       = &arrow.StringColumn{Field: "class", Values: make([]string, n)}
+		rule        = &arrow.StringColumn{Field: "rule", Values: make([]string, n), Valid: make([]bool, n)}
+		queueLength = &arrow.Int64Column{Field: "queue_length", Values: make([]int64, n), Valid: make([]bool, n)}
+		waitTicks   = &arrow.Int64Column{Field: "wait_ticks", Values: make([]int64, n), Valid: make([]bool, n)}
+	)
+	for i, e := range artifact.Events {
+		tick.Values[i] = int64(e.Tick)
+		typ.Values[i] = e.Type
+		reason.Values[i] = e.ReasonCode
+		tokenID.Values[i] = e.TokenID
+		stageID.Values[i] = e.StageID
+		class.Values[i] = e.Class
+		if e.Context != nil {
+			rule.Values[i], rule.Valid[i] = e.Context.Rule, true
+			queueLength.Values[i], queueLength.Valid[i] = int64(e.Context.QueueLength), true
+			waitTicks.Values[i], waitTicks.Valid[i] = int64(e.Context.WaitTicks), true
+		}
+	}
+	events := arrow.Table{Columns: []arrow.Column{tick, typ, reason, tokenID, stageID, class, rule, queueLength, waitTicks}}
+
+	var (
+		tsTick      = &arrow.Int64Column{Field: "tick"}
+		tsToken     = &arrow.StringColumn{Field: "token_id"}
+		tsClass     = &arrow.StringColumn{Field: "class"}
+		tsState     = &arrow.StringColumn{Field: "state"}
+		tsStage     = &arrow.StringColumn{Field: "stage_id"}
+		tsQueue     = &arrow.Int64Column{Field: "queue_index"}
+		tsRemaining = &arrow.Int64Column{Field: "service_remaining"}
+
+		ssTick     = &arrow.Int64Column{Field: "tick"}
+		ssStage    = &arrow.StringColumn{Field: "stage_id"}
+		ssQueue    = &arrow.Int64Column{Field: "queue_length"}
+		ssUsed     = &arrow.Int64Column{Field: "capacity_used"}
+		ssCapacity = &arrow.Int64Column{Field: "capacity_total"}
+	)
+	for _, snapshot := range artifact.Snapshots {
+		for _, t := range snapshot.Tokens {
+			tsTick.Values = append(tsTick.Values, int64(snapshot.Tick))
+			tsToken.Values = append(tsToken.Values, t.ID)
+			tsClass.Values = append(tsClass.Values, t.Class)
+			tsState.Values = append(tsState.Values, t.State)
+			tsStage.Values = append(tsStage.Values, t.StageID)
+			tsQueue.Values = append(tsQueue.Values, int64(t.QueueIndex))
+			tsRemaining.Values = append(tsRemaining.Values, int64(t.ServiceRemaining))
+		}
+		for _, s := range snapshot.Stages {
+			ssTick.Values = append(ssTick.Values, int64(snapshot.Tick))
+			ssStage.Values = append(ssStage.Values, s.ID)
+			ssQueue.Values = append(ssQueue.Values, int64(s.QueueLength))
+			ssUsed.Values = append(ssUsed.Values, int64(s.CapacityUsed))
+			ssCapacity.Values = append(ssCapacity.Values, int64(s.CapacityTotal))
+		}
+	}
+
+	return map[string]arrow.Table{
+		"metadata":     metadata,
+		"events":       events,
+		"token_states": {Columns: []arrow.Column{tsTick, tsToken, tsClass, tsState, tsStage, tsQueue, tsRemaining}},
+		"stage_states": {Columns: []arrow.Column{ssTick, ssStage, ssQueue, ssUsed, ssCapacity}},
+	}
+}
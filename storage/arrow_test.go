@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestWriteArrow(t *testing.T) {
+	artifact, err := engine.Run(engine.Config{Seed: 1})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	dir := filepath.Join(t.TempDir(), "run")
+	if err := WriteArrow(context.Background(), dir, artifact); err != nil {
+		t.Fatalf("WriteArrow() error = %v", err)
+	}
+	for _, name := range arrowTableNames {
+		if _, err := os.Stat(filepath.Join(dir, name+".arrow")); err != nil {
+			t.Errorf("missing %s.arrow: %v", name, err)
+		}
+	}
+
+	tokenStates := 0
+	for _, snapshot := range artifact.Snapshots {
+		tokenStates += len(snapshot.Tokens)
+	}
+	tables := arrowTables(artifact)
+	counts := map[string]int{
+		"metadata":     1,
+		"events":       len(artifact.Events),
+		"token_states": tokenStates,
+		"stage_states": len(artifact.Snapshots) * len(artifact.Snapshots[0].Stages),
+	}
+	for name, want := range counts {
+		if got := tables[name].Len(); got != want {
+			t.Errorf("%s rows = %d, want %d", name, got, want)
+		}
+	}
+
+	if err := WriteArrow(context.Background(), "s3://runs/arrow", artifact); err == nil {
+		t.Error("WriteArrow(s3) error = nil, want error")
+	}
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+type Object struct {
+	ContentType string
+	Metadata    map[string]string
+}
+
+type S3 struct {
+	Region      string
+	Endpoint    string
+	Credentials Credentials
+	Client      *http.Client
+	Retries     int
+	Backoff     time.Duration
+	Now         func() time.Time
+}
+
+func S3FromEnv() (*S3, error) {
+	creds := Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, errors.New("s3: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3{
+		Region:      region,
+		Endpoint:    os.Getenv("AWS_ENDPOINT_URL"),
+		Credentials: creds,
+	}, nil
+}
+
+func ParseS3URL(raw string) (bucket string, key string, err error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", err
+	}
+	key = strings.TrimPrefix(u.Path, "/")
+	if u.Scheme != "s3" || u.Host == "" || key == "" {
+		return "", "", fmt.Errorf("invalid s3 url %q (want s3://bucket/key)", raw)
+	}
+	return u.Host, key, nil
+}
+
+func (s *S3) Put(ctx context.Context, bucket string, key string, data []byte, object Object) error {
+	target, err := s.objectURL(bucket, key)
+	if err != nil {
+		return err
+	}
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	retries := s.Retries
+	if retries <= 0 {
+		retries = 3
+	}
+	backoff := s.Backoff
+	if backoff <= 0 {
+		backoff = 250 * time.Millisecond
+	}
+	payloadHash := hashHex(data)
+
+	var lastErr error
+	for attempt := 0; attempt < retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff << (attempt - 1)):
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.ContentLength = int64(len(data))
+		if object.ContentType != "" {
+			req.Header.Set("Content-Type", object.ContentType)
+		}
+		for name, value := range object.Metadata {
+			req.Header.Set("X-Amz-Meta-"+name, value)
+		}
+		signRequest(req, payloadHash, s.Credentials, s.Region, "s3", s.now())
+
+		retry, err := s.do(client, req)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			return err
+		}
+	}
+	return fmt.Errorf("s3: giving up after %d attempts: %w", retries, lastErr)
+}
+
+func (s *S3) do(client *http.Client, req *http.Request) (bool, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("s3: PUT %s: %s: %s", req.URL.Path, resp.Status, strings.TrimSpace(string(detail)))
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, err
+}
+
+func (s *S3) objectURL(bucket string, key string) (string, error) {
+	escaped := (&url.URL{Path: "/" + key}).EscapedPath()
+	if s.Endpoint == "" {
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com%s", bucket, s.Region, escaped), nil
+	}
+	endpoint, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return "", fmt.Errorf("s3: invalid endpoint: %w", err)
+	}
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/") + "/" + bucket
+	return endpoint.String() + escaped, nil
+}
+
+func (s *S3) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+
+	"finit/engine"
+)
+
+var sqliteSchema = []string{
+	`CREATE TABLE metadata (
+		scenario_id TEXT NOT NULL,
+		seed INTEGER NOT NULL,
+		engine_version TEXT NOT NULL,
+		replay_id TEXT NOT NULL,
+		tick_count INTEGER NOT NULL,
+		tick_duration_ms INTEGER NOT NULL,
+		total_duration_ms INTEGER NOT NULL
+	)`,
+	`CREATE TABLE events (
+		id INTEGER PRIMARY KEY,
+		tick INTEGER NOT NULL,
+		type TEXT NOT NULL,
+		reason_code TEXT NOT NULL,
+		token_id TEXT NOT NULL,
+		stage_id TEXT NOT NULL,
+		class TEXT NOT NULL,
+		rule TEXT,
+		queue_length INTEGER,
+		wait_ticks INTEGER
+	)`,
+	`CREATE TABLE token_states (
+		tick INTEGER NOT NULL,
+		token_id TEXT NOT NULL,
+		class TEXT NOT NULL,
+		state TEXT NOT NULL,
+		stage_id TEXT NOT NULL,
+		queue_index INTEGER NOT NULL,
+		service_remaining INTEGER NOT NULL
+	)`,
+	`CREATE TABLE stage_states (
+		tick INTEGER NOT NULL,
+		stage_id TEXT NOT NULL,
+		queue_length INTEGER NOT NULL,
+		capacity_used INTEGER NOT NULL,
+		capacity_total INTEGER NOT NULL
+	)`,
+	`CREATE INDEX events_tick ON events (tick)`,
+	`CREATE INDEX events_token_id ON events (token_id)`,
+	`CREATE INDEX token_states_tick ON token_states (tick)`,
+	`CREATE INDEX token_states_token_id ON token_states (token_id)`,
+	`CREATE INDEX stage_states_tick ON stage_states (tick)`,
+}
+
+func WriteSQLite(ctx context.Context, path string, artifact engine.Artifact) error {
+	if IsRemote(path) {
+		return errors.New("sqlite output must be a local file")
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := writeSQLite(ctx, tx, artifact); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+func writeSQLite(ctx context.Context, tx *sql.Tx, artifact engine.Artifact) error {
+	for _, statement := range sqliteSchema {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	m := artifact.Metadata
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO metadata VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		m.ScenarioID, m.Seed, m.EngineVersion, m.ReplayID, m.TickCount, m.TickDurationMs, m.TotalDurationMs,
+	); err != nil {
+		return err
+	}
+
+	events, err := tx.PrepareContext(ctx, `INSERT INTO events
+		(tick, type, reason_code, token_id, stage_id, class, rule, queue_length, wait_ticks)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer events.Close()
+	for _, e := range artifact.Events {
+		var rule, queueLength, waitTicks any
+		if e.Context != nil {
+			rule, queueLength, waitTicks = e.Context.Rule, e.Context.QueueLength, e.Context.WaitTicks
+		}
+		if _, err := events.ExecContext(ctx, e.Tick, e.Type, e.ReasonCode, e.TokenID, e.StageID, e.Class, rule, queueLength, waitTicks); err != nil {
+			return err
+		}
+	}
+
+	tokens, err := tx.PrepareContext(ctx, `INSERT INTO token_states VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer tokens.Close()
+	stages, err := tx.PrepareContext(ctx, `INSERT INTO stage_states VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stages.Close()
+
+	for _, snapshot := range artifact.Snapshots {
+		for _, t := range snapshot.Tokens {
+			if _, err := tokens.ExecContext(ctx, snapshot.Tick, t.ID, t.Class, t.State, t.StageID, t.QueueIndex, t.ServiceRemaining); err != nil {
+				return err
+			}
+		}
+		for _, s := range snapshot.Stages {
+			if _, err := stages.ExecContext(ctx, snapshot.Tick, s.ID, s.QueueLength, s.CapacityUsed, s.CapacityTotal); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
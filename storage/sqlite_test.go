@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestWriteSQLite(t *testing.T) {
+	artifact, err := engine.Run(engine.Config{Seed: 1})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "run.db")
+	if err := WriteSQLite(context.Background(), path, artifact); err != nil {
+		t.Fatalf("WriteSQLite() error = %v", err)
+	}
+	if err := WriteSQLite(context.Background(), path, artifact); err != nil {
+		t.Fatalf("WriteSQLite() overwrite error = %v", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	var replayID string
+	if err := db.QueryRow(`SELECT replay_id FROM metadata`).Scan(&replayID); err != nil {
+		t.Fatalf("query metadata: %v", err)
+	}
+	if replayID != artifact.Metadata.ReplayID {
+		t.Errorf("replay_id = %q, want %q", replayID, artifact.Metadata.ReplayID)
+	}
+
+	tokenStates := 0
+	for _, snapshot := range artifact.Snapshots {
+		tokenStates += len(snapshot.Tokens)
+	}
+	counts := map[string]int{
+		"events":       len(artifact.Events),
+		"token_states": tokenStates,
+		"stage_states": len(artifact.Snapshots) * len(artifact.Snapshots[0].Stages),
+	}
+	for table, want := range counts {
+		var got int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&got); err != nil {
+			t.Fatalf("count %s: %v", table, err)
+		}
+		if got != want {
+			t.Errorf("%s rows = %d, want %d", table, got, want)
+		}
+	}
+
+	var rejects int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM events WHERE type = ? AND class = ?`, engine.EventReject, engine.ClassAnon).Scan(&rejects); err != nil {
+		t.Fatalf("query rejects: %v", err)
+	}
+	if rejects == 0 {
+		t.Error("expected ANON rejections in the canonical run")
+	}
+
+	var indexes int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name LIKE '%tick'`).Scan(&indexes); err != nil {
+		t.Fatalf("query indexes: %v", err)
+	}
+	if indexes != 3 {
+		t.Errorf("tick indexes = %d, want 3", indexes)
+	}
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"finit/engine"
+)
+
+func IsRemote(dest string) bool {
+	return strings.HasPrefix(dest, "s3://")
+}
+
+func WriteChunked(ctx context.Context, dest string, artifact engine.Artifact, chunkTicks int) error {
+	if IsRemote(dest) {
+		return errors.New("chunked output must be a local file")
+	}
+	if dir := filepath.Dir(dest); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	_, err := engine.WriteChunked(dest, artifact, chunkTicks)
+	return err
+}
+
+func WriteArtifact(ctx context.Context, dest string, artifact engine.Artifact) error {
+	if !IsRemote(dest) {
+		if dir := filepath.Dir(dest); dir != "." {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+		}
+		return engine.WriteArtifact(dest, artifact)
+	}
+
+	bucket, key, err := ParseS3URL(dest)
+	if err != nil {
+		return err
+	}
+	client, err := S3FromEnv()
+	if err != nil {
+		return err
+	}
+	data, err := engine.MarshalArtifact(artifact)
+	if err != nil {
+		return err
+	}
+	return client.Put(ctx, bucket, key, data, ArtifactObject(artifact.Metadata))
+}
+
+func ArtifactObject(metadata engine.Metadata) Object {
+	return Object{
+		ContentType: "application/json",
+		Metadata: map[string]string{
+			"Finit-Scenario-Id":    metadata.ScenarioID,
+			"Finit-Replay-Id":      metadata.ReplayID,
+			"Finit-Seed":           strconv.FormatInt(metadata.Seed, 10),
+			"Finit-Engine-Version": metadata.EngineVersion,
+		},
+	}
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"finit/analysis"
+	"finit/engine"
+)
+
+type UIArtifact struct {
+	Metadata      engine.Metadata   `json:"metadata"`
+	Summary       analysis.Summary  `json:"summary"`
+	KeyframeEvery int               `json:"keyframe_every"`
+	Keyframes     []engine.Snapshot `json:"keyframes"`
+	Deltas        []UIDelta         `json:"deltas"`
+	Events        []engine.Event    `json:"events"`
+}
+
+type UIDelta struct {
+	Tick    int                 `json:"tick"`
+	TimeMs  int                 `json:"time_ms"`
+	Tokens  []engine.TokenState `json:"tokens,omitempty"`
+	Removed []string            `json:"removed,omitempty"`
+	Stages  []engine.StageState `json:"stages,omitempty"`
+	Warmup  bool                `json:"warmup,omitempty"`
+	Ledger  *engine.Ledger      `json:"ledger,omitempty"`
+}
+
+func BuildUI(artifact engine.Artifact, every int) (UIArtifact, error) {
+	if every <= 0 {
+		return UIArtifact{}, fmt.Errorf("keyframe interval must be positive, got %d", every)
+	}
+	ui := UIArtifact{
+		Metadata:      artifact.Metadata,
+		Summary:       analysis.Summarize(artifact),
+		KeyframeEvery: every,
+		Events:        make([]engine.Event, len(artifact.Events)),
+	}
+	for i, event := range artifact.Events {
+		event.Context = nil
+		ui.Events[i] = event
+	}
+	for i, snapshot := range artifact.Snapshots {
+		if i%every == 0 {
+			ui.Keyframes = append(ui.Keyframes, snapshot)
+			continue
+		}
+		ui.Deltas = append(ui.Deltas, snapshotDelta(artifact.Snapshots[i-1], snapshot))
+	}
+	return ui, nil
+}
+
+func snapshotDelta(prev, next engine.Snapshot) UIDelta {
+	delta := UIDelta{Tick: next.Tick, TimeMs: next.TimeMs, Warmup: next.Warmup, Ledger: next.Ledger}
+	last := make(map[string]engine.TokenState, len(prev.Tokens))
+	for _, token := range prev.Tokens {
+		last[token.ID] = token
+	}
+	listed := make(map[string]bool, len(next.Tokens))
+	for _, token := range next.Tokens {
+		listed[token.ID] = true
+		if before, ok := last[token.ID]; !ok || before != token {
+			delta.Tokens = append(delta.Tokens, token)
+		}
+	}
+	for _, token := range prev.Tokens {
+		if !listed[token.ID] {
+			delta.Removed = append(delta.Removed, token.ID)
+		}
+	}
+	if !reflect.DeepEqual(prev.Stages, next.Stages) {
+		delta.Stages = next.Stages
+	}
+	return delta
+}
+
+func (ui UIArtifact) Artifact() engine.Artifact {
+	artifact := engine.Artifact{Metadata: ui.Metadata, Events: ui.Events}
+	var prev engine.Snapshot
+	deltas := ui.Deltas
+	for i := range ui.Keyframes {
+		prev = ui.Keyframes[i]
+		artifact.Snapshots = append(artifact.Snapshots, prev)
+		for j := 1; j < ui.KeyframeEvery && len(deltas) > 0; j++ {
+			prev = applyDelta(prev, deltas[0])
+			artifact.Snapshots = append(artifact.Snapshots, prev)
+			deltas = deltas[1:]
+		}
+	}
+	return artifact
+}
+
+func applyDelta(prev engine.Snapshot, delta UIDelta) engine.Snapshot {
+	next := engine.Snapshot{
+		Tick:   delta.Tick,
+		TimeMs: delta.TimeMs,
+		Tokens: make([]engine.TokenState, 0, len(prev.Tokens)+len(delta.Tokens)),
+		Stages: prev.Stages,
+		Warmup: delta.Warmup,
+		Ledger: delta.Ledger,
+	}
+	if delta.Stages != nil {
+		next.Stages = delta.Stages
+	}
+	removed := make(map[string]bool, len(delta.Removed))
+	for _, id := range delta.Removed {
+		removed[id] = true
+	}
+	changed := make(map[string]engine.TokenState, len(delta.Tokens))
+	for _, token := range delta.Tokens {
+		changed[token.ID] = token
+	}
+	for _, token := range prev.Tokens {
+		if removed[token.ID] {
+			continue
+		}
+		if updated, ok := changed[token.ID]; ok {
+			token = updated
+			delete(changed, token.ID)
+		}
+		next.Tokens = append(next.Tokens, token)
+	}
+	for _, token := range delta.Tokens {
+		if _, ok := changed[token.ID]; ok {
+			next.Tokens = append(next.Tokens, token)
+		}
+	}
+	return next
+}
+
+func WriteUI(ctx context.Context, dest string, artifact engine.Artifact, every int) error {
+	ui, err := BuildUI(artifact, every)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(ui)
+	if err != nil {
+		return err
+	}
+	if IsRemote(dest) {
+		bucket, key, err := ParseS3URL(dest)
+		if err != nil {
+			return err
+		}
+		client, err := S3FromEnv()
+		if err != nil {
+			return err
+		}
+		return client.Put(ctx, bucket, key, data, ArtifactObject(artifact.Metadata))
+	}
+	if dir := filepath.Dir(dest); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(dest, data, 0o644)
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"finit/engine"
+)
+
+func TestWriteUI(t *testing.T) {
+	artifact, err := engine.Run(engine.Config{Seed: 1})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "ui.json")
+	if err := WriteUI(context.Background(), path, artifact, 4); err != nil {
+		t.Fatalf("WriteUI() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := engine.MarshalArtifact(artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) >= len(full)/2 {
+		t.Errorf("ui export is %d bytes, want under half of the %d byte artifact", len(data), len(full))
+	}
+
+	var ui UIArtifact
+	if err := json.Unmarshal(data, &ui); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got, want := len(ui.Keyframes), (len(artifact.Snapshots)+3)/4; got != want {
+		t.Errorf("keyframes = %d, want %d", got, want)
+	}
+	if ui.Summary.Arrived == 0 || len(ui.Events) != len(artifact.Events) {
+		t.Errorf("summary arrived %d, %d events, want the run's summary and all %d events", ui.Summary.Arrived, len(ui.Events), len(artifact.Events))
+	}
+	got := engine.ExpandSnapshots(ui.Artifact()).Snapshots
+	want := engine.ExpandSnapshots(artifact).Snapshots
+	if len(got) != len(want) {
+		t.Fatalf("rebuilt %d snapshots, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Fatalf("rebuilt snapshot at tick %d = %+v, want %+v", want[i].Tick, got[i], want[i])
+		}
+	}
+
+	if _, err := BuildUI(artifact, 0); err == nil {
+		t.Error("BuildUI() with every 0 error = nil, want an error")
+	}
+}
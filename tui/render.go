@@ -0,0 +1,121 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"finit/engine"
+	"finit/playback"
+)
+
+const maxEventLines = 8
+
+type Status struct {
+	Metadata engine.Metadata
+	Speed    float64
+	Paused   bool
+	Live     bool
+	Width    int
+}
+
+func Render(frame playback.Frame, status Status) string {
+	width := status.Width
+	if width <= 0 {
+		width = 80
+	}
+
+	classes := status.Metadata.ClassNames()
+	queued := make([]engine.TokenState, 0)
+	var inService []engine.TokenState
+	counts := map[string]map[string]int{
+		engine.StateQueued:     {},
+		engine.StateProcessing: {},
+		engine.StateDone:       {},
+		engine.StateRejected:   {},
+	}
+	for _, token := range frame.Snapshot.Tokens {
+		if byClass, ok := counts[token.State]; ok {
+			byClass[token.Class]++
+		}
+		switch token.State {
+		case engine.StateQueued:
+			queued = append(queued, token)
+		case engine.StateProcessing:
+			inService = append(inService, token)
+		}
+	}
+	sort.Slice(queued, func(i, j int) bool {
+		return queued[i].QueueIndex < queued[j].QueueIndex
+	})
+
+	capacity := 0
+	for _, stage := range frame.Snapshot.Stages {
+		if stage.ID == engine.StageService {
+			capacity = stage.CapacityTotal
+		}
+	}
+
+	mode := "replay"
+	if status.Live {
+		mode = "live"
+	}
+	state := "playing"
+	if status.Paused {
+		state = "paused"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "finit · %s · seed %d · %s\n", status.Metadata.ScenarioID, status.Metadata.Seed, mode)
+	fmt.Fprintf(&b, "replay %s\n", truncate(status.Metadata.ReplayID, width-7))
+	fmt.Fprintf(&b, "tick %d/%d   t=%.2fs   speed %gx   %s\n\n",
+		frame.Tick+1, status.Metadata.TickCount, float64(frame.TimeMs)/1000, status.Speed, state)
+
+	fmt.Fprintf(&b, "%-10s %3d  %s\n", "queue", len(queued), classCounts(classes, counts[engine.StateQueued]))
+	lane := make([]byte, 0, len(queued))
+	for _, token := range queued {
+		lane = append(lane, token.Class[0])
+	}
+	fmt.Fprintf(&b, "%-10s      %s\n", "", truncate(string(lane), width-16))
+
+	slots := make([]string, 0, capacity)
+	for _, token := range inService {
+		slots = append(slots, fmt.Sprintf("%s:%s", token.ID, token.Class))
+	}
+	for len(slots) < capacity {
+		slots = append(slots, "idle")
+	}
+	fmt.Fprintf(&b, "%-10s %d/%d  %s\n", "service", len(inService), capacity, truncate(strings.Join(slots, "  "), width-16))
+	fmt.Fprintf(&b, "%-10s      %s\n", "done", classCounts(classes, counts[engine.StateDone]))
+	fmt.Fprintf(&b, "%-10s      %s\n\n", "rejected", classCounts(classes, counts[engine.StateRejected]))
+
+	b.WriteString("events\n")
+	for i, event := range frame.Events {
+		if i == maxEventLines {
+			fmt.Fprintf(&b, "  … %d more\n", len(frame.Events)-maxEventLines)
+			break
+		}
+		fmt.Fprintf(&b, "  %-9s %s  %-4s  %s\n", event.Type, event.TokenID, event.Class, event.ReasonCode)
+	}
+	if len(frame.Events) == 0 {
+		b.WriteString("  (none)\n")
+	}
+
+	b.WriteString("\n[space] pause  [n] step  [+/-] speed  [q] quit\n")
+	return b.String()
+}
+
+func classCounts(classes []string, byClass map[string]int) string {
+	parts := make([]string, 0, len(classes))
+	for _, class := range classes {
+		parts = append(parts, fmt.Sprintf("%s %d", class, byClass[class]))
+	}
+	return strings.Join(parts, "  ")
+}
+
+func truncate(value string, width int) string {
+	if width <= 1 || len(value) <= width {
+		return value
+	}
+	return value[:width-1] + "…"
+}
@@ -0,0 +1,113 @@
+package tui
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"finit/playback"
+)
+
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	minSpeed    = 0.25
+	maxSpeed    = 64
+)
+
+type Dashboard struct {
+	player *playback.Player
+	out    io.Writer
+	live   bool
+	width  int
+
+	mu    sync.Mutex
+	frame playback.Frame
+	drawn bool
+}
+
+func New(player *playback.Player, out io.Writer, live bool, width int) *Dashboard {
+	return &Dashboard{
+		player: player,
+		out:    out,
+		live:   live,
+		width:  width,
+	}
+}
+
+func (d *Dashboard) Run(ctx context.Context, keys io.Reader) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if keys != nil {
+		go d.readKeys(keys, cancel)
+	}
+
+	err := d.player.Run(ctx, func(frame playback.Frame) error {
+		d.mu.Lock()
+		d.frame = frame
+		d.drawn = true
+		d.mu.Unlock()
+		return d.draw()
+	})
+	if err == context.Canceled {
+		return nil
+	}
+	return err
+}
+
+func (d *Dashboard) HandleKey(key byte) bool {
+	switch key {
+	case 'q', 'Q', 3:
+		return false
+	case ' ', 'p':
+		if d.player.Paused() {
+			d.player.Resume()
+		} else {
+			d.player.Pause()
+		}
+	case 'n', '.':
+		d.player.Step()
+	case '+', '=':
+		if speed := d.player.Speed() * 2; speed <= maxSpeed {
+			_ = d.player.SetSpeed(speed)
+		}
+	case '-', '_':
+		if speed := d.player.Speed() / 2; speed >= minSpeed {
+			_ = d.player.SetSpeed(speed)
+		}
+	}
+	_ = d.draw()
+	return true
+}
+
+func (d *Dashboard) readKeys(keys io.Reader, cancel context.CancelFunc) {
+	buf := make([]byte, 1)
+	for {
+		n, err := keys.Read(buf)
+		if err != nil {
+			return
+		}
+		if n == 1 && !d.HandleKey(buf[0]) {
+			cancel()
+			return
+		}
+	}
+}
+
+func (d *Dashboard) draw() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.drawn {
+		return nil
+	}
+	screen := Render(d.frame, Status{
+		Metadata: d.player.Metadata(),
+		Speed:    d.player.Speed(),
+		Paused:   d.player.Paused(),
+		Live:     d.live,
+		Width:    d.width,
+	})
+	_, err := io.WriteString(d.out, clearScreen+strings.ReplaceAll(screen, "\n", "\r\n"))
+	return err
+}
@@ -0,0 +1,100 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"finit/engine"
+	"finit/playback"
+)
+
+func TestRender(t *testing.T) {
+	frame := playback.Frame{
+		Tick:   9,
+		TimeMs: 2250,
+		Snapshot: engine.Snapshot{
+			Tick:   9,
+			TimeMs: 2250,
+			Tokens: []engine.TokenState{
+				{ID: "T0001", Class: engine.ClassFree, State: engine.StateQueued, StageID: engine.StageQueue, QueueIndex: 1},
+				{ID: "T0002", Class: engine.ClassPaid, State: engine.StateQueued, StageID: engine.StageQueue, QueueIndex: 0},
+				{ID: "T0003", Class: engine.ClassAnon, State: engine.StateProcessing, StageID: engine.StageService, QueueIndex: -1},
+				{ID: "T0004", Class: engine.ClassAnon, State: engine.StateRejected, StageID: engine.StageRejected, QueueIndex: -1},
+			},
+			Stages: []engine.StageState{
+				{ID: engine.StageService, CapacityUsed: 1, CapacityTotal: 3},
+			},
+		},
+		Events: []engine.Event{
+			{Tick: 9, Type: engine.EventReject, ReasonCode: engine.ReasonRejectOverload, TokenID: "T0004", Class: engine.ClassAnon},
+		},
+	}
+
+	screen := Render(frame, Status{
+		Metadata: engine.Metadata{ScenarioID: engine.ScenarioID, Seed: 3, TickCount: 240},
+		Speed:    2,
+		Paused:   true,
+	})
+
+	for _, want := range []string{
+		"canonical_v1 · seed 3 · replay",
+		"tick 10/240",
+		"speed 2x   paused",
+		"PF\n",
+		"1/3  T0003:ANON  idle  idle",
+		"ANON 1",
+		"REJECT    T0004  ANON  REJECT_OVERLOAD",
+	} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("Render() missing %q in:\n%s", want, screen)
+		}
+	}
+}
+
+func TestDashboard_Keys(t *testing.T) {
+	sim, err := engine.NewSimulator(engine.Config{Seed: 1})
+	if err != nil {
+		t.Fatalf("NewSimulator() error = %v", err)
+	}
+	player := playback.NewPlayer(playback.LiveSource(sim), nil)
+	player.Pause()
+
+	var out bytes.Buffer
+	dashboard := New(player, &out, true, 80)
+
+	if !dashboard.HandleKey('+') || player.Speed() != 2 {
+		t.Errorf("'+' speed = %v, want 2", player.Speed())
+	}
+	dashboard.HandleKey('-')
+	dashboard.HandleKey('-')
+	if player.Speed() != 0.5 {
+		t.Errorf("'-' speed = %v, want 0.5", player.Speed())
+	}
+	dashboard.HandleKey(' ')
+	if player.Paused() {
+		t.Error("space should resume playback")
+	}
+	if dashboard.HandleKey('q') {
+		t.Error("'q' should stop the dashboard")
+	}
+}
+
+func TestDashboard_RunQuits(t *testing.T) {
+	sim, err := engine.NewSimulator(engine.Config{Seed: 1})
+	if err != nil {
+		t.Fatalf("NewSimulator() error = %v", err)
+	}
+	player := playback.NewPlayer(playback.LiveSource(sim), nil)
+	player.Pause()
+
+	var out bytes.Buffer
+	dashboard := New(player, &out, true, 80)
+	if err := dashboard.Run(context.Background(), strings.NewReader("nnq")); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if player.Speed() != 1 {
+		t.Errorf("Speed() = %v, want 1", player.Speed())
+	}
+}
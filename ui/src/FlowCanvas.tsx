@@ -133,11 +133,20 @@ const tokenClassName = (tokenClass: string) => {
       return 'border border-dashed border-[var(--token-anon-border)] bg-[var(--token-anon)]'
     case 'PAID':
       return 'border-2 border-[var(--token-paid-border)] bg-[var(--token-paid)] shadow-[0_0_0_2px_rgba(37,63,93,0.16)]'
-    default:
+    case 'FREE':
       return 'border border-[var(--token-free-border)] bg-transparent'
+    default:
+      return 'border border-[var(--ink)]'
   }
 }
 
+const builtinClasses = new Set(['ANON', 'FREE', 'PAID'])
+
+const customClassColor = (artifact: Artifact, tokenClass: string) =>
+  builtinClasses.has(tokenClass)
+    ? undefined
+    : artifact.metadata.classes?.find((def) => def.name === tokenClass)?.color
+
 const motionProfile = (tokenState: TokenState | undefined, animationDuration: number) => {
   if (!tokenState) {
     return { duration: animationDuration, delay: 0 }
@@ -461,7 +470,7 @@ export const FlowCanvas = ({
 
     for (const event of artifact.events) {
       if (event.tick !== currentTick) continue
-      if (event.reason_code === 'PRIORITY_SCHEDULE') {
+      if (event.type === 'SCHEDULE' && event.reason_code !== 'PS_SCHEDULE') {
         if (queueDepth > 0) {
           map.set(event.token_id, emphasisGlow.PRIORITY_SCHEDULE)
         }
@@ -990,7 +999,7 @@ export const FlowCanvas = ({
         if (inspectorEnabled) {
           const stage = stageStates.get(stageId)
           if (stageId === 'queue') {
-            badgeText = `queue ${stage?.queue ?? 0}`
+            badgeText = stage?.total ? `queue ${stage.used}/${stage.total}` : `queue ${stage?.queue ?? 0}`
           } else if (stageId === 'service') {
             badgeText = `capacity ${stage?.used ?? 0}/${stage?.total ?? 0}`
           } else if (stageId === 'done') {
@@ -1039,7 +1048,11 @@ export const FlowCanvas = ({
             className={`absolute rounded-full ${
               token.id === highlightedTokenId ? 'ring-2 ring-[var(--ink)]' : ''
             } ${tokenClassName(token.class)}`}
-            style={{ width: tokenSize, height: tokenSize }}
+            style={{
+              width: tokenSize,
+              height: tokenSize,
+              backgroundColor: customClassColor(artifact, token.class),
+            }}
           />
         ))}
       </div>
@@ -71,7 +71,10 @@ export const ReviewLog = ({
               const time = event.tick * artifact.metadata.tick_duration_ms
               const isSelected = selectedTokenId === event.token_id
               const isExpanded = expandedKey === key
-              const accent = classAccent[event.class] ?? 'var(--ink)'
+              const accent =
+                classAccent[event.class] ??
+                artifact.metadata.classes?.find((def) => def.name === event.class)?.color ??
+                'var(--ink)'
               return (
                 <button
                   key={key}
@@ -93,7 +96,7 @@ export const ReviewLog = ({
                     </div>
                   </div>
                   <div className="mt-2 text-sm font-medium leading-relaxed text-[var(--ink)]">
-                    {explainEvent(event)}
+                    {explainEvent(event, artifact.metadata)}
                   </div>
                   {inspectorEnabled ? (
                     <div className="mt-2 text-[11px] font-medium text-[var(--muted)]">
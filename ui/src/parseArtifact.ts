@@ -1,6 +1,8 @@
 import type {
   Artifact,
+  ClassDef,
   Metadata,
+  ReasonDef,
   Snapshot,
   TokenClass,
   TokenState,
@@ -20,8 +22,95 @@ const isString = (value: unknown): value is string => typeof value === 'string'
 
 const isArray = (value: unknown): value is unknown[] => Array.isArray(value)
 
-const isTokenClass = (value: unknown): value is TokenClass =>
-  value === 'ANON' || value === 'FREE' || value === 'PAID'
+const isTokenClass = (value: unknown): value is TokenClass => isString(value) && value !== ''
+
+const parseClasses = (value: unknown): ClassDef[] | undefined => {
+  if (!isArray(value)) return undefined
+  const classes: ClassDef[] = []
+  for (const entry of value) {
+    if (!isRecord(entry) || !isTokenClass(entry.name) || !isNumber(entry.priority)) continue
+    classes.push({
+      name: entry.name,
+      priority: entry.priority,
+      ...(isString(entry.color) && { color: entry.color }),
+    })
+  }
+  return classes
+}
+
+const parseReasons = (value: unknown): ReasonDef[] | undefined => {
+  if (!isArray(value)) return undefined
+  const reasons: ReasonDef[] = []
+  for (const entry of value) {
+    if (!isRecord(entry) || !isString(entry.code) || !isString(entry.description)) continue
+    reasons.push({ code: entry.code, description: entry.description })
+  }
+  return reasons
+}
+
+const applyPresentation = (classes: ClassDef[], value: unknown): ClassDef[] => {
+  if (!isRecord(value) || !isArray(value.classes)) return classes
+  const hints = new Map<string, Record<string, unknown>>()
+  for (const entry of value.classes) {
+    if (isRecord(entry) && isString(entry.name)) hints.set(entry.name, entry)
+  }
+  return classes.map((def) => {
+    const hint = hints.get(def.name)
+    if (!hint) return def
+    return {
+      ...def,
+      ...(isString(hint.color) && hint.color !== '' && { color: hint.color }),
+      ...(isString(hint.label) && hint.label !== '' && { label: hint.label }),
+    }
+  })
+}
+
+const expandSnapshots = (snapshots: Snapshot[]): Snapshot[] => {
+  const latest = new Map<string, TokenState>()
+  return snapshots.map((snapshot) => {
+    for (const token of snapshot.tokens) {
+      latest.set(token.id, token)
+    }
+    return { ...snapshot, tokens: Array.from(latest.values()) }
+  })
+}
+
+const rebuildKeyframes = (data: Record<string, unknown>): unknown[] | undefined => {
+  const { keyframes, deltas, keyframe_every: every } = data
+  if (!isArray(keyframes) || !isArray(deltas) || !isNumber(every) || every <= 0) return undefined
+  const snapshots: unknown[] = []
+  let next = 0
+  for (const keyframe of keyframes) {
+    if (!isRecord(keyframe) || !isArray(keyframe.tokens)) return undefined
+    let prev: Record<string, unknown> = keyframe
+    snapshots.push(prev)
+    for (let i = 1; i < every && next < deltas.length; i++) {
+      const delta = deltas[next++]
+      if (!isRecord(delta) || !isArray(prev.tokens)) return undefined
+      const changed = new Map<string, unknown>()
+      for (const token of isArray(delta.tokens) ? delta.tokens : []) {
+        if (isRecord(token) && isString(token.id)) changed.set(token.id, token)
+      }
+      const removed = new Set(isArray(delta.removed) ? delta.removed : [])
+      const tokens: unknown[] = []
+      for (const token of prev.tokens) {
+        if (!isRecord(token) || removed.has(token.id)) continue
+        const id = token.id as string
+        tokens.push(changed.get(id) ?? token)
+        changed.delete(id)
+      }
+      tokens.push(...changed.values())
+      prev = {
+        tick: delta.tick,
+        time_ms: delta.time_ms,
+        tokens,
+        stages: isArray(delta.stages) ? delta.stages : prev.stages,
+      }
+      snapshots.push(prev)
+    }
+  }
+  return snapshots
+}
 
 export function parseArtifact(data: unknown): ParseResult {
   if (!isRecord(data)) {
@@ -29,7 +118,7 @@ export function parseArtifact(data: unknown): ParseResult {
   }
 
   const metadata = data.metadata
-  const snapshots = data.snapshots
+  const snapshots = data.keyframes !== undefined ? rebuildKeyframes(data) : data.snapshots
   const events = data.events
 
   if (!isRecord(metadata)) {
@@ -45,6 +134,14 @@ export function parseArtifact(data: unknown): ParseResult {
     tick_duration_ms: isNumber(metadata.tick_duration_ms) ? metadata.tick_duration_ms : NaN,
     total_duration_ms: isNumber(metadata.total_duration_ms) ? metadata.total_duration_ms : NaN,
   }
+  const classes = parseClasses(metadata.classes)
+  if (classes) {
+    meta.classes = applyPresentation(classes, metadata.presentation)
+  }
+  const reasons = parseReasons(metadata.reasons)
+  if (reasons) {
+    meta.reasons = reasons
+  }
 
   if (
     !meta.scenario_id ||
@@ -84,7 +181,9 @@ export function parseArtifact(data: unknown): ParseResult {
         !isString(token.state) ||
         !isString(token.stage_id) ||
         !isNumber(token.queue_index) ||
-        !isNumber(token.service_remaining)
+        !isNumber(token.service_remaining) ||
+        (token.eta_ms !== undefined && !isNumber(token.eta_ms)) ||
+        (token.ticket !== undefined && !isNumber(token.ticket))
       ) {
         return { ok: false, error: 'Token fields are missing or malformed.' }
       }
@@ -95,6 +194,8 @@ export function parseArtifact(data: unknown): ParseResult {
         stage_id: token.stage_id,
         queue_index: token.queue_index,
         service_remaining: token.service_remaining,
+        ...(token.eta_ms !== undefined && { eta_ms: token.eta_ms }),
+        ...(token.ticket !== undefined && { ticket: token.ticket }),
       })
     }
 
@@ -158,7 +259,8 @@ export function parseArtifact(data: unknown): ParseResult {
     ok: true,
     artifact: {
       metadata: meta,
-      snapshots: parsedSnapshots,
+      snapshots:
+        metadata.snapshot_schema === 'active' ? expandSnapshots(parsedSnapshots) : parsedSnapshots,
       events: parsedEvents,
     },
   }
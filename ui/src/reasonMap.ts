@@ -1,4 +1,4 @@
-import type { Event } from './types'
+import type { Event, Metadata } from './types'
 
 const classLabel: Record<string, string> = {
   ANON: 'Anonymous',
@@ -19,6 +19,15 @@ const reasons: Record<string, ReasonEntry> = {
   PRIORITY_SCHEDULE: {
     summary: (event) => sentence(`${classLabel[event.class] ?? 'User'} request starts sooner`),
   },
+  LIFO_SCHEDULE: {
+    summary: (event) => sentence(`${classLabel[event.class] ?? 'User'} request starts ahead of older ones`),
+  },
+  SJF_SCHEDULE: {
+    summary: (event) => sentence(`${classLabel[event.class] ?? 'User'} request starts as the shortest job`),
+  },
+  PS_SCHEDULE: {
+    summary: (event) => sentence(`${classLabel[event.class] ?? 'User'} request starts on a shared server`),
+  },
   SERVICE_COMPLETE: {
     summary: (event) => sentence(`${classLabel[event.class] ?? 'User'} request completes`),
   },
@@ -27,12 +36,17 @@ const reasons: Record<string, ReasonEntry> = {
   },
 }
 
-export const explainEvent = (event: Event) => {
+export const explainEvent = (event: Event, metadata?: Metadata) => {
   const entry = reasons[event.reason_code]
   if (entry) {
     return entry.summary(event)
   }
 
+  const registered = metadata?.reasons?.find((reason) => reason.code === event.reason_code)
+  if (registered) {
+    return sentence(`${classLabel[event.class] ?? 'User'} request: ${registered.description}`)
+  }
+
   if (import.meta.env.DEV) {
     return sentence(`Event recorded (unknown reason: ${event.reason_code})`)
   }
@@ -40,4 +54,4 @@ export const explainEvent = (event: Event) => {
   return sentence('Event recorded (reason unavailable)')
 }
 
-export const labelForClass = (value: string) => classLabel[value] ?? 'User'
+export const labelForClass = (value: string) => classLabel[value] ?? value
@@ -1,4 +1,16 @@
-export type TokenClass = 'ANON' | 'FREE' | 'PAID'
+export type TokenClass = string
+
+export type ClassDef = {
+  name: string
+  priority: number
+  color?: string
+  label?: string
+}
+
+export type ReasonDef = {
+  code: string
+  description: string
+}
 
 export type Metadata = {
   scenario_id: string
@@ -8,6 +20,8 @@ export type Metadata = {
   tick_count: number
   tick_duration_ms: number
   total_duration_ms: number
+  classes?: ClassDef[]
+  reasons?: ReasonDef[]
 }
 
 export type TokenState = {
@@ -17,6 +31,8 @@ export type TokenState = {
   stage_id: string
   queue_index: number
   service_remaining: number
+  eta_ms?: number
+  ticket?: number
 }
 
 export type StageState = {
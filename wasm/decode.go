@@ -0,0 +1,231 @@
+package wasm
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	maxLocals = 1024
+	maxDepth  = 256
+	maxStack  = 4096
+)
+
+const (
+	opUnreachable = 0x00
+	opNop         = 0x01
+	opBlock       = 0x02
+	opLoop        = 0x03
+	opIf          = 0x04
+	opElse        = 0x05
+	opEnd         = 0x0b
+	opBr          = 0x0c
+	opBrIf        = 0x0d
+	opBrTable     = 0x0e
+	opReturn      = 0x0f
+	opCall        = 0x10
+	opDrop        = 0x1a
+	opSelect      = 0x1b
+	opLocalGet    = 0x20
+	opLocalSet    = 0x21
+	opLocalTee    = 0x22
+	opGlobalGet   = 0x23
+	opGlobalSet   = 0x24
+	opMemorySize  = 0x3f
+	opMemoryGrow  = 0x40
+	opI32Const    = 0x41
+	opI64Const    = 0x42
+	opF64Const    = 0x44
+	opPrefix      = 0xfc
+)
+
+type instr struct {
+	op byte
+	// imm holds the constant, local or global index, label depth, callee,
+	// memory offset or 0xfc sub-opcode; for block, loop and if it holds the
+	// block's result arity.
+	imm     uint64
+	els     int
+	end     int
+	targets []uint32
+}
+
+func numeric(op byte) bool {
+	switch {
+	case op >= 0x45 && op <= 0x5a, op >= 0x61 && op <= 0x66:
+		return true
+	case op >= 0x67 && op <= 0x8a, op >= 0x99 && op <= 0xa6:
+		return true
+	case op == 0xa7, op >= 0xaa && op <= 0xad, op == 0xb0, op == 0xb1:
+		return true
+	case op >= 0xb7 && op <= 0xba, op == 0xbd, op == 0xbf, op >= 0xc0 && op <= 0xc4:
+		return true
+	}
+	return false
+}
+
+func memoryOp(op byte) bool {
+	return op >= 0x28 && op <= 0x3e && op != 0x2a && op != 0x38
+}
+
+func decode(r *reader, m *Module, locals, funcs int) ([]instr, error) {
+	var code []instr
+	open := []int{-1}
+	for len(open) > 0 {
+		at := r.pos
+		op, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		in := instr{op: op, els: -1, end: -1}
+		switch op {
+		case opUnreachable, opNop, opReturn, opDrop, opSelect:
+		case opBlock, opLoop, opIf:
+			b, err := r.byte()
+			if err != nil {
+				return nil, err
+			}
+			if b != 0x40 {
+				if _, err := (&reader{data: []byte{b}}).valueType(); err != nil {
+					return nil, fmt.Errorf("block type at byte %d: %w", at, err)
+				}
+				in.imm = 1
+			}
+			open = append(open, len(code))
+		case opElse:
+			top := open[len(open)-1]
+			if top < 0 || code[top].op != opIf || code[top].els >= 0 {
+				return nil, fmt.Errorf("else without if at byte %d", at)
+			}
+			code[top].els = len(code)
+		case opEnd:
+			if top := open[len(open)-1]; top >= 0 {
+				code[top].end = len(code)
+			}
+			open = open[:len(open)-1]
+		case opBr, opBrIf:
+			if in.imm, err = label(r, len(open)); err != nil {
+				return nil, err
+			}
+		case opBrTable:
+			count, err := r.u32()
+			if err != nil {
+				return nil, err
+			}
+			for i := uint32(0); i <= count; i++ {
+				depth, err := label(r, len(open))
+				if err != nil {
+					return nil, err
+				}
+				in.targets = append(in.targets, uint32(depth))
+			}
+		case opCall:
+			if in.imm, err = r.uleb(32); err != nil {
+				return nil, err
+			}
+			if in.imm >= uint64(funcs) {
+				return nil, fmt.Errorf("call to unknown function %d", in.imm)
+			}
+		case opLocalGet, opLocalSet, opLocalTee:
+			if in.imm, err = r.uleb(32); err != nil {
+				return nil, err
+			}
+			if in.imm >= uint64(locals) {
+				return nil, fmt.Errorf("local %d out of range", in.imm)
+			}
+		case opGlobalGet, opGlobalSet:
+			if in.imm, err = r.uleb(32); err != nil {
+				return nil, err
+			}
+			if in.imm >= uint64(len(m.globals)) {
+				return nil, fmt.Errorf("global %d out of range", in.imm)
+			}
+			if op == opGlobalSet && !m.globals[in.imm].mutable {
+				return nil, fmt.Errorf("global %d is immutable", in.imm)
+			}
+		case opMemorySize, opMemoryGrow:
+			if m.memory == nil {
+				return nil, fmt.Errorf("memory instruction at byte %d in a module without memory", at)
+			}
+			if b, err := r.byte(); err != nil {
+				return nil, err
+			} else if b != 0 {
+				return nil, fmt.Errorf("unknown memory %d", b)
+			}
+		case opPrefix:
+			if in.imm, err = r.uleb(32); err != nil {
+				return nil, err
+			}
+			switch in.imm {
+			case 0x02, 0x03, 0x06, 0x07:
+			case 0x0a, 0x0b:
+				if m.memory == nil {
+					return nil, fmt.Errorf("memory instruction at byte %d in a module without memory", at)
+				}
+				reserved := 1
+				if in.imm == 0x0a {
+					reserved = 2
+				}
+				for ; reserved > 0; reserved-- {
+					if b, err := r.byte(); err != nil {
+						return nil, err
+					} else if b != 0 {
+						return nil, fmt.Errorf("unknown memory %d", b)
+					}
+				}
+			default:
+				return nil, fmt.Errorf("unsupported opcode 0xfc %d at byte %d", in.imm, at)
+			}
+		case opI32Const:
+			v, err := r.sleb(32)
+			if err != nil {
+				return nil, err
+			}
+			in.imm = uint64(uint32(v))
+		case opI64Const:
+			v, err := r.sleb(64)
+			if err != nil {
+				return nil, err
+			}
+			in.imm = uint64(v)
+		case opF64Const:
+			v, err := r.f64()
+			if err != nil {
+				return nil, err
+			}
+			in.imm = f64bits(v)
+		default:
+			if memoryOp(op) {
+				if m.memory == nil {
+					return nil, fmt.Errorf("memory instruction at byte %d in a module without memory", at)
+				}
+				if _, err := r.u32(); err != nil {
+					return nil, err
+				}
+				if in.imm, err = r.uleb(32); err != nil {
+					return nil, err
+				}
+				break
+			}
+			if !numeric(op) {
+				return nil, fmt.Errorf("unsupported opcode 0x%02x at byte %d", op, at)
+			}
+		}
+		code = append(code, in)
+	}
+	if !r.done() {
+		return nil, errors.New("code after the final end")
+	}
+	return code, nil
+}
+
+func label(r *reader, open int) (uint64, error) {
+	depth, err := r.uleb(32)
+	if err != nil {
+		return 0, err
+	}
+	if depth >= uint64(open) {
+		return 0, fmt.Errorf("branch depth %d out of range", depth)
+	}
+	return depth, nil
+}
@@ -0,0 +1,548 @@
+package wasm
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+type Trap struct {
+	Reason string
+}
+
+func (t *Trap) Error() string {
+	return "wasm trap: " + t.Reason
+}
+
+func trap(format string, args ...any) {
+	panic(&Trap{Reason: fmt.Sprintf(format, args...)})
+}
+
+func (m *Module) Call(name string, fuel int64, args ...uint64) (results []uint64, err error) {
+	index, ok := m.exports[name]
+	if !ok {
+		return nil, fmt.Errorf("no exported function %q", name)
+	}
+	typ := m.types[m.funcs[index].typ]
+	if len(args) != len(typ.Params) {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", name, len(typ.Params), len(args))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t, ok := r.(*Trap)
+			if !ok {
+				panic(r)
+			}
+			results, err = nil, t
+		}
+	}()
+	e := &machine{module: m, fuel: fuel}
+	for _, g := range m.globals {
+		e.globals = append(e.globals, g.init)
+	}
+	e.stack = append(e.stack, args...)
+	e.call(index, 0)
+	return e.stack, nil
+}
+
+type machine struct {
+	module  *Module
+	stack   []uint64
+	fuel    int64
+	globals []uint64
+	pages   [][]byte
+	owned   []bool
+}
+
+type frame struct {
+	pc     int
+	height int
+	arity  int
+	loop   bool
+}
+
+func (e *machine) push(v uint64) {
+	if len(e.stack) >= maxStack {
+		trap("value stack exhausted")
+	}
+	e.stack = append(e.stack, v)
+}
+
+func (e *machine) pop() uint64 {
+	if len(e.stack) == 0 {
+		trap("value stack underflow")
+	}
+	v := e.stack[len(e.stack)-1]
+	e.stack = e.stack[:len(e.stack)-1]
+	return v
+}
+
+func (e *machine) unwind(height, arity int) {
+	if len(e.stack) < height+arity {
+		trap("value stack underflow")
+	}
+	copy(e.stack[height:], e.stack[len(e.stack)-arity:])
+	e.stack = e.stack[:height+arity]
+}
+
+func (e *machine) call(index, depth int) {
+	if depth >= maxDepth {
+		trap("call stack exhausted")
+	}
+	fn := &e.module.funcs[index]
+	typ := e.module.types[fn.typ]
+	if len(e.stack) < len(typ.Params) {
+		trap("value stack underflow")
+	}
+	locals := make([]uint64, len(typ.Params)+len(fn.locals))
+	copy(locals, e.stack[len(e.stack)-len(typ.Params):])
+	e.stack = e.stack[:len(e.stack)-len(typ.Params)]
+	body := frame{pc: len(fn.code), height: len(e.stack), arity: len(typ.Results)}
+	labels := []frame{body}
+
+	branch := func(depth uint64) int {
+		target := labels[len(labels)-1-int(depth)]
+		labels = labels[:len(labels)-1-int(depth)]
+		e.unwind(target.height, target.arity)
+		if target.loop {
+			labels = append(labels, target)
+		}
+		return target.pc
+	}
+
+	for pc := 0; pc < len(fn.code); {
+		if e.fuel--; e.fuel < 0 {
+			trap("fuel exhausted")
+		}
+		in := &fn.code[pc]
+		pc++
+		switch in.op {
+		case opUnreachable:
+			trap("unreachable executed")
+		case opNop:
+		case opBlock:
+			labels = append(labels, frame{pc: in.end + 1, height: len(e.stack), arity: int(in.imm)})
+		case opLoop:
+			labels = append(labels, frame{pc: pc, height: len(e.stack), loop: true})
+		case opIf:
+			cond := uint32(e.pop())
+			labels = append(labels, frame{pc: in.end + 1, height: len(e.stack), arity: int(in.imm)})
+			if cond == 0 {
+				if in.els >= 0 {
+					pc = in.els + 1
+				} else {
+					pc = in.end
+				}
+			}
+		case opElse:
+			pc = branch(0)
+		case opEnd:
+			labels = labels[:len(labels)-1]
+		case opBr:
+			pc = branch(in.imm)
+		case opBrIf:
+			if uint32(e.pop()) != 0 {
+				pc = branch(in.imm)
+			}
+		case opBrTable:
+			i := uint64(uint32(e.pop()))
+			if i >= uint64(len(in.targets)-1) {
+				i = uint64(len(in.targets) - 1)
+			}
+			pc = branch(uint64(in.targets[i]))
+		case opReturn:
+			pc = branch(uint64(len(labels) - 1))
+		case opCall:
+			e.call(int(in.imm), depth+1)
+		case opDrop:
+			e.pop()
+		case opSelect:
+			cond, b, a := uint32(e.pop()), e.pop(), e.pop()
+			if cond != 0 {
+				e.push(a)
+			} else {
+				e.push(b)
+			}
+		case opLocalGet:
+			e.push(locals[in.imm])
+		case opLocalSet:
+			locals[in.imm] = e.pop()
+		case opLocalTee:
+			v := e.pop()
+			locals[in.imm] = v
+			e.push(v)
+		case opGlobalGet:
+			e.push(e.globals[in.imm])
+		case opGlobalSet:
+			e.globals[in.imm] = e.pop()
+		case opMemorySize:
+			e.push(uint64(len(e.memory())))
+		case opMemoryGrow:
+			e.push(e.grow(uint32(e.pop())))
+		case opI32Const, opI64Const, opF64Const:
+			e.push(in.imm)
+		case opPrefix:
+			if in.imm >= 0x0a {
+				e.bulk(in.imm)
+			} else {
+				e.push(saturate(in.imm, f64(e.pop())))
+			}
+		default:
+			if memoryOp(in.op) {
+				e.access(in.op, in.imm)
+				break
+			}
+			e.numeric(in.op)
+		}
+	}
+	e.unwind(body.height, body.arity)
+}
+
+func (e *machine) numeric(op byte) {
+	switch {
+	case op == 0x45:
+		e.push(boolean(uint32(e.pop()) == 0))
+	case op >= 0x46 && op <= 0x4f:
+		b, a := uint32(e.pop()), uint32(e.pop())
+		e.push(boolean(compareI32(op, a, b)))
+	case op == 0x50:
+		e.push(boolean(e.pop() == 0))
+	case op >= 0x51 && op <= 0x5a:
+		b, a := e.pop(), e.pop()
+		e.push(boolean(compareI64(op-0x0b, a, b)))
+	case op >= 0x61 && op <= 0x66:
+		b, a := f64(e.pop()), f64(e.pop())
+		e.push(boolean(compareF64(op, a, b)))
+	case op >= 0x67 && op <= 0x69:
+		a := uint32(e.pop())
+		switch op {
+		case 0x67:
+			e.push(uint64(bits.LeadingZeros32(a)))
+		case 0x68:
+			e.push(uint64(bits.TrailingZeros32(a)))
+		default:
+			e.push(uint64(bits.OnesCount32(a)))
+		}
+	case op >= 0x6a && op <= 0x78:
+		b, a := uint32(e.pop()), uint32(e.pop())
+		e.push(uint64(arithI32(op, a, b)))
+	case op >= 0x79 && op <= 0x7b:
+		a := e.pop()
+		switch op {
+		case 0x79:
+			e.push(uint64(bits.LeadingZeros64(a)))
+		case 0x7a:
+			e.push(uint64(bits.TrailingZeros64(a)))
+		default:
+			e.push(uint64(bits.OnesCount64(a)))
+		}
+	case op >= 0x7c && op <= 0x8a:
+		b, a := e.pop(), e.pop()
+		e.push(arithI64(op-0x12, a, b))
+	case op >= 0x99 && op <= 0x9f:
+		e.push(f64bits(unaryF64(op, f64(e.pop()))))
+	case op >= 0xa0 && op <= 0xa6:
+		b, a := f64(e.pop()), f64(e.pop())
+		e.push(f64bits(arithF64(op, a, b)))
+	default:
+		e.push(convert(op, e.pop()))
+	}
+}
+
+func boolean(v bool) uint64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func compareI32(op byte, a, b uint32) bool {
+	switch op {
+	case 0x46:
+		return a == b
+	case 0x47:
+		return a != b
+	case 0x48:
+		return int32(a) < int32(b)
+	case 0x49:
+		return a < b
+	case 0x4a:
+		return int32(a) > int32(b)
+	case 0x4b:
+		return a > b
+	case 0x4c:
+		return int32(a) <= int32(b)
+	case 0x4d:
+		return a <= b
+	case 0x4e:
+		return int32(a) >= int32(b)
+	}
+	return a >= b
+}
+
+// compareI64 takes i64 opcodes shifted down onto their i32 counterparts.
+func compareI64(op byte, a, b uint64) bool {
+	switch op {
+	case 0x46:
+		return a == b
+	case 0x47:
+		return a != b
+	case 0x48:
+		return int64(a) < int64(b)
+	case 0x49:
+		return a < b
+	case 0x4a:
+		return int64(a) > int64(b)
+	case 0x4b:
+		return a > b
+	case 0x4c:
+		return int64(a) <= int64(b)
+	case 0x4d:
+		return a <= b
+	case 0x4e:
+		return int64(a) >= int64(b)
+	}
+	return a >= b
+}
+
+func compareF64(op byte, a, b float64) bool {
+	switch op {
+	case 0x61:
+		return a == b
+	case 0x62:
+		return a != b
+	case 0x63:
+		return a < b
+	case 0x64:
+		return a > b
+	case 0x65:
+		return a <= b
+	}
+	return a >= b
+}
+
+func arithI32(op byte, a, b uint32) uint32 {
+	switch op {
+	case 0x6a:
+		return a + b
+	case 0x6b:
+		return a - b
+	case 0x6c:
+		return a * b
+	case 0x6d:
+		if b == 0 {
+			trap("integer divide by zero")
+		}
+		if int32(a) == math.MinInt32 && int32(b) == -1 {
+			trap("integer overflow")
+		}
+		return uint32(int32(a) / int32(b))
+	case 0x6e:
+		if b == 0 {
+			trap("integer divide by zero")
+		}
+		return a / b
+	case 0x6f:
+		if b == 0 {
+			trap("integer divide by zero")
+		}
+		if int32(b) == -1 {
+			return 0
+		}
+		return uint32(int32(a) % int32(b))
+	case 0x70:
+		if b == 0 {
+			trap("integer divide by zero")
+		}
+		return a % b
+	case 0x71:
+		return a & b
+	case 0x72:
+		return a | b
+	case 0x73:
+		return a ^ b
+	case 0x74:
+		return a << (b & 31)
+	case 0x75:
+		return uint32(int32(a) >> (b & 31))
+	case 0x76:
+		return a >> (b & 31)
+	case 0x77:
+		return bits.RotateLeft32(a, int(b&31))
+	}
+	return bits.RotateLeft32(a, -int(b&31))
+}
+
+// arithI64 takes i64 opcodes shifted down onto their i32 counterparts.
+func arithI64(op byte, a, b uint64) uint64 {
+	switch op {
+	case 0x6a:
+		return a + b
+	case 0x6b:
+		return a - b
+	case 0x6c:
+		return a * b
+	case 0x6d:
+		if b == 0 {
+			trap("integer divide by zero")
+		}
+		if int64(a) == math.MinInt64 && int64(b) == -1 {
+			trap("integer overflow")
+		}
+		return uint64(int64(a) / int64(b))
+	case 0x6e:
+		if b == 0 {
+			trap("integer divide by zero")
+		}
+		return a / b
+	case 0x6f:
+		if b == 0 {
+			trap("integer divide by zero")
+		}
+		if int64(b) == -1 {
+			return 0
+		}
+		return uint64(int64(a) % int64(b))
+	case 0x70:
+		if b == 0 {
+			trap("integer divide by zero")
+		}
+		return a % b
+	case 0x71:
+		return a & b
+	case 0x72:
+		return a | b
+	case 0x73:
+		return a ^ b
+	case 0x74:
+		return a << (b & 63)
+	case 0x75:
+		return uint64(int64(a) >> (b & 63))
+	case 0x76:
+		return a >> (b & 63)
+	case 0x77:
+		return bits.RotateLeft64(a, int(b&63))
+	}
+	return bits.RotateLeft64(a, -int(b&63))
+}
+
+func unaryF64(op byte, a float64) float64 {
+	switch op {
+	case 0x99:
+		return math.Abs(a)
+	case 0x9a:
+		return -a
+	case 0x9b:
+		return math.Ceil(a)
+	case 0x9c:
+		return math.Floor(a)
+	case 0x9d:
+		return math.Trunc(a)
+	case 0x9e:
+		return math.RoundToEven(a)
+	}
+	return math.Sqrt(a)
+}
+
+func arithF64(op byte, a, b float64) float64 {
+	switch op {
+	case 0xa0:
+		return a + b
+	case 0xa1:
+		return a - b
+	case 0xa2:
+		return a * b
+	case 0xa3:
+		return a / b
+	case 0xa4:
+		return math.Min(a, b)
+	case 0xa5:
+		return math.Max(a, b)
+	}
+	return math.Copysign(a, b)
+}
+
+func convert(op byte, v uint64) uint64 {
+	switch op {
+	case 0xa7:
+		return uint64(uint32(v))
+	case 0xaa:
+		return uint64(uint32(int32(truncate(f64(v), -1<<31, 1<<31))))
+	case 0xab:
+		return uint64(uint32(truncate(f64(v), 0, 1<<32)))
+	case 0xac:
+		return uint64(int64(int32(v)))
+	case 0xad:
+		return uint64(uint32(v))
+	case 0xb0:
+		return uint64(int64(truncate(f64(v), -1<<63, 1<<63)))
+	case 0xb1:
+		t := truncate(f64(v), 0, 1<<64)
+		if t >= 1<<63 {
+			return uint64(t-(1<<63)) + 1<<63
+		}
+		return uint64(t)
+	case 0xb7:
+		return f64bits(float64(int32(v)))
+	case 0xb8:
+		return f64bits(float64(uint32(v)))
+	case 0xb9:
+		return f64bits(float64(int64(v)))
+	case 0xba:
+		return f64bits(float64(v))
+	case 0xbd, 0xbf:
+		return v
+	case 0xc0:
+		return uint64(uint32(int32(int8(v))))
+	case 0xc1:
+		return uint64(uint32(int32(int16(v))))
+	case 0xc2:
+		return uint64(int64(int8(v)))
+	case 0xc3:
+		return uint64(int64(int16(v)))
+	}
+	return uint64(int64(int32(v)))
+}
+
+func saturate(op uint64, v float64) uint64 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	switch op {
+	case 0x02:
+		return uint64(uint32(int32(math.Max(math.MinInt32, math.Min(math.MaxInt32, v)))))
+	case 0x03:
+		return uint64(uint32(math.Max(0, math.Min(math.MaxUint32, v))))
+	case 0x06:
+		if v >= 1<<63 {
+			return math.MaxInt64
+		}
+		return uint64(int64(math.Max(math.MinInt64, v)))
+	}
+	if v >= 1<<64 {
+		return math.MaxUint64
+	}
+	if v >= 1<<63 {
+		return uint64(v-(1<<63)) + 1<<63
+	}
+	return uint64(math.Max(0, v))
+}
+
+func truncate(v, lo, hi float64) float64 {
+	if math.IsNaN(v) {
+		trap("invalid conversion to integer")
+	}
+	t := math.Trunc(v)
+	if t < lo || t >= hi {
+		trap("integer overflow")
+	}
+	return t
+}
+
+func f64(v uint64) float64 {
+	return math.Float64frombits(v)
+}
+
+func f64bits(v float64) uint64 {
+	return math.Float64bits(v)
+}
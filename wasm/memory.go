@@ -0,0 +1,138 @@
+package wasm
+
+const (
+	pageSize = 1 << 16
+	maxPages = 256
+)
+
+// memory is a module's initial linear memory. Pages that no data segment
+// touches stay nil and read as zeros; calls copy a page before writing to it.
+type memory struct {
+	pages [][]byte
+	max   int
+}
+
+func (m *memory) init(offset uint64, data []byte) bool {
+	if offset+uint64(len(data)) > uint64(len(m.pages))*pageSize {
+		return false
+	}
+	for i, b := range data {
+		addr := offset + uint64(i)
+		page := &m.pages[addr/pageSize]
+		if *page == nil {
+			*page = make([]byte, pageSize)
+		}
+		(*page)[addr%pageSize] = b
+	}
+	return true
+}
+
+func (e *machine) memory() [][]byte {
+	if e.pages == nil {
+		e.pages = append([][]byte{}, e.module.memory.pages...)
+		e.owned = make([]bool, len(e.pages))
+	}
+	return e.pages
+}
+
+func (e *machine) address(base uint64, offset uint64, n int) uint64 {
+	addr := uint64(uint32(base)) + offset
+	if addr+uint64(n) > uint64(len(e.memory()))*pageSize {
+		trap("out of bounds memory access")
+	}
+	return addr
+}
+
+func (e *machine) load(addr uint64, n int) uint64 {
+	var v uint64
+	for i := n - 1; i >= 0; i-- {
+		a := addr + uint64(i)
+		if page := e.pages[a/pageSize]; page != nil {
+			v = v<<8 | uint64(page[a%pageSize])
+		} else {
+			v <<= 8
+		}
+	}
+	return v
+}
+
+func (e *machine) store(addr uint64, n int, v uint64) {
+	for i := 0; i < n; i++ {
+		a := addr + uint64(i)
+		index := a / pageSize
+		if !e.owned[index] {
+			page := make([]byte, pageSize)
+			copy(page, e.pages[index])
+			e.pages[index], e.owned[index] = page, true
+		}
+		e.pages[index][a%pageSize] = byte(v >> (8 * i))
+	}
+}
+
+func width(op byte) int {
+	switch op {
+	case 0x2c, 0x2d, 0x30, 0x31, 0x3a, 0x3c:
+		return 1
+	case 0x2e, 0x2f, 0x32, 0x33, 0x3b, 0x3d:
+		return 2
+	case 0x28, 0x34, 0x35, 0x36, 0x3e:
+		return 4
+	}
+	return 8
+}
+
+func (e *machine) access(op byte, offset uint64) {
+	n := width(op)
+	if op >= 0x36 {
+		v := e.pop()
+		e.store(e.address(e.pop(), offset, n), n, v)
+		return
+	}
+	v := e.load(e.address(e.pop(), offset, n), n)
+	switch op {
+	case 0x2c:
+		v = uint64(uint32(int32(int8(v))))
+	case 0x2e:
+		v = uint64(uint32(int32(int16(v))))
+	case 0x30:
+		v = uint64(int64(int8(v)))
+	case 0x32:
+		v = uint64(int64(int16(v)))
+	case 0x34:
+		v = uint64(int64(int32(v)))
+	}
+	e.push(v)
+}
+
+func (e *machine) grow(delta uint32) uint64 {
+	old := len(e.memory())
+	if uint64(old)+uint64(delta) > uint64(e.module.memory.max) {
+		return uint64(uint32(0xffffffff))
+	}
+	e.pages = append(e.pages, make([][]byte, delta)...)
+	e.owned = append(e.owned, make([]bool, delta)...)
+	return uint64(old)
+}
+
+func (e *machine) bulk(op uint64) {
+	n := uint32(e.pop())
+	if e.fuel -= int64(n); e.fuel < 0 {
+		trap("fuel exhausted")
+	}
+	if op == 0x0b {
+		v, dst := e.pop(), e.address(e.pop(), 0, int(n))
+		for i := uint64(0); i < uint64(n); i++ {
+			e.store(dst+i, 1, v)
+		}
+		return
+	}
+	src := e.address(e.pop(), 0, int(n))
+	dst := e.address(e.pop(), 0, int(n))
+	buf := make([]byte, n)
+	for i := range buf {
+		buf[i] = byte(e.load(src+uint64(i), 1))
+	}
+	for i, b := range buf {
+		e.store(dst+uint64(i), 1, uint64(b))
+	}
+}
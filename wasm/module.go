@@ -0,0 +1,511 @@
+package wasm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+)
+
+type ValueType byte
+
+const (
+	I32 ValueType = 0x7f
+	I64 ValueType = 0x7e
+	F64 ValueType = 0x7c
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case I32:
+		return "i32"
+	case I64:
+		return "i64"
+	case F64:
+		return "f64"
+	}
+	return fmt.Sprintf("type 0x%02x", byte(t))
+}
+
+type FuncType struct {
+	Params  []ValueType
+	Results []ValueType
+}
+
+type function struct {
+	typ    int
+	locals []ValueType
+	code   []instr
+}
+
+type global struct {
+	typ     ValueType
+	mutable bool
+	init    uint64
+}
+
+type Module struct {
+	types   []FuncType
+	funcs   []function
+	exports map[string]int
+	globals []global
+	memory  *memory
+}
+
+var sectionNames = map[byte]string{2: "import", 4: "table", 8: "start", 9: "element"}
+
+var errTruncated = errors.New("unexpected end of module")
+
+func Compile(data []byte) (*Module, error) {
+	if !bytes.HasPrefix(data, []byte("\x00asm\x01\x00\x00\x00")) {
+		return nil, errors.New("not a WebAssembly 1.0 module")
+	}
+	m := &Module{exports: make(map[string]int)}
+	var funcTypes []uint32
+	r := &reader{data: data[8:]}
+	for !r.done() {
+		id, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		body, err := r.bytes(int(size))
+		if err != nil {
+			return nil, err
+		}
+		section := &reader{data: body}
+		switch id {
+		case 0:
+			continue
+		case 1:
+			err = m.readTypes(section)
+		case 3:
+			funcTypes, err = section.u32s()
+		case 5:
+			err = m.readMemory(section)
+		case 6:
+			err = m.readGlobals(section)
+		case 7:
+			err = m.readExports(section)
+		case 10:
+			err = m.readCode(section, funcTypes)
+		case 11:
+			err = m.readData(section)
+		case 12:
+			_, err = section.u32()
+		default:
+			if name, ok := sectionNames[id]; ok {
+				return nil, fmt.Errorf("%s section not supported: plugins must be self-contained, with no imports, tables or start function", name)
+			}
+			return nil, fmt.Errorf("unknown section id %d", id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !section.done() {
+			return nil, fmt.Errorf("section %d has %d trailing bytes", id, len(section.data)-section.pos)
+		}
+	}
+	if len(m.funcs) != len(funcTypes) {
+		return nil, fmt.Errorf("%d functions declared but %d bodies given", len(funcTypes), len(m.funcs))
+	}
+	for name, index := range m.exports {
+		if index >= len(m.funcs) {
+			return nil, fmt.Errorf("export %q names function %d, which does not exist", name, index)
+		}
+	}
+	return m, nil
+}
+
+func (m *Module) Export(name string) (FuncType, bool) {
+	index, ok := m.exports[name]
+	if !ok {
+		return FuncType{}, false
+	}
+	return m.types[m.funcs[index].typ], true
+}
+
+func (m *Module) readTypes(r *reader) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		if form, err := r.byte(); err != nil {
+			return err
+		} else if form != 0x60 {
+			return fmt.Errorf("type %d: unknown form 0x%02x", i, form)
+		}
+		var typ FuncType
+		if typ.Params, err = r.valueTypes(); err != nil {
+			return fmt.Errorf("type %d: %w", i, err)
+		}
+		if typ.Results, err = r.valueTypes(); err != nil {
+			return fmt.Errorf("type %d: %w", i, err)
+		}
+		if len(typ.Results) > 1 {
+			return fmt.Errorf("type %d: multiple results not supported", i)
+		}
+		m.types = append(m.types, typ)
+	}
+	return nil
+}
+
+func (m *Module) readExports(r *reader) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		name, err := r.name()
+		if err != nil {
+			return err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return err
+		}
+		index, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if kind == 0 {
+			m.exports[name] = int(index)
+		}
+	}
+	return nil
+}
+
+func (m *Module) readCode(r *reader, funcTypes []uint32) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	if int(count) != len(funcTypes) {
+		return fmt.Errorf("%d functions declared but %d bodies given", len(funcTypes), count)
+	}
+	for i := uint32(0); i < count; i++ {
+		size, err := r.u32()
+		if err != nil {
+			return err
+		}
+		body, err := r.bytes(int(size))
+		if err != nil {
+			return err
+		}
+		if int(funcTypes[i]) >= len(m.types) {
+			return fmt.Errorf("function %d: unknown type %d", i, funcTypes[i])
+		}
+		fn := function{typ: int(funcTypes[i])}
+		b := &reader{data: body}
+		groups, err := b.u32()
+		if err != nil {
+			return fmt.Errorf("function %d: %w", i, err)
+		}
+		for g := uint32(0); g < groups; g++ {
+			n, err := b.u32()
+			if err != nil {
+				return fmt.Errorf("function %d: %w", i, err)
+			}
+			typ, err := b.valueType()
+			if err != nil {
+				return fmt.Errorf("function %d: %w", i, err)
+			}
+			if len(fn.locals)+int(n) > maxLocals {
+				return fmt.Errorf("function %d: more than %d locals", i, maxLocals)
+			}
+			for ; n > 0; n-- {
+				fn.locals = append(fn.locals, typ)
+			}
+		}
+		if fn.code, err = decode(b, m, len(m.types[fn.typ].Params)+len(fn.locals), int(count)); err != nil {
+			return fmt.Errorf("function %d: %w", i, err)
+		}
+		m.funcs = append(m.funcs, fn)
+	}
+	return nil
+}
+
+func (m *Module) readMemory(r *reader) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	if count > 1 {
+		return errors.New("multiple memories not supported")
+	}
+	if count == 0 {
+		return nil
+	}
+	flags, err := r.byte()
+	if err != nil {
+		return err
+	}
+	if flags > 1 {
+		return fmt.Errorf("memory limits flags 0x%02x not supported", flags)
+	}
+	initial, err := r.u32()
+	if err != nil {
+		return err
+	}
+	limit := uint32(maxPages)
+	if flags == 1 {
+		declared, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if declared < initial {
+			return fmt.Errorf("memory maximum %d is below its minimum %d", declared, initial)
+		}
+		limit = min(declared, maxPages)
+	}
+	if initial > maxPages {
+		return fmt.Errorf("memory of %d pages exceeds the %d-page limit", initial, maxPages)
+	}
+	m.memory = &memory{pages: make([][]byte, initial), max: int(limit)}
+	return nil
+}
+
+func (m *Module) readGlobals(r *reader) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		var g global
+		if g.typ, err = r.valueType(); err != nil {
+			return fmt.Errorf("global %d: %w", i, err)
+		}
+		mut, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if mut > 1 {
+			return fmt.Errorf("global %d: unknown mutability 0x%02x", i, mut)
+		}
+		g.mutable = mut == 1
+		if g.init, err = r.constant(g.typ); err != nil {
+			return fmt.Errorf("global %d: %w", i, err)
+		}
+		m.globals = append(m.globals, g)
+	}
+	return nil
+}
+
+func (m *Module) readData(r *reader) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		flags, err := r.u32()
+		if err != nil {
+			return err
+		}
+		switch flags {
+		case 0:
+		case 2:
+			if index, err := r.u32(); err != nil {
+				return err
+			} else if index != 0 {
+				return fmt.Errorf("data segment %d: unknown memory %d", i, index)
+			}
+		default:
+			return fmt.Errorf("data segment %d: passive segments not supported", i)
+		}
+		offset, err := r.constant(I32)
+		if err != nil {
+			return fmt.Errorf("data segment %d: %w", i, err)
+		}
+		n, err := r.u32()
+		if err != nil {
+			return err
+		}
+		data, err := r.bytes(int(n))
+		if err != nil {
+			return err
+		}
+		if m.memory == nil {
+			return fmt.Errorf("data segment %d: module has no memory", i)
+		}
+		if !m.memory.init(offset, data) {
+			return fmt.Errorf("data segment %d: %d bytes at %d do not fit in memory", i, n, offset)
+		}
+	}
+	return nil
+}
+
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) done() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *reader) byte() (byte, error) {
+	if r.done() {
+		return 0, errTruncated
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *reader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, errTruncated
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *reader) u32() (uint32, error) {
+	v, err := r.uleb(32)
+	return uint32(v), err
+}
+
+func (r *reader) uleb(bits uint) (uint64, error) {
+	var v uint64
+	for shift := uint(0); shift < bits+7; shift += 7 {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+	return 0, errors.New("integer too long")
+}
+
+func (r *reader) sleb(bits uint) (int64, error) {
+	var v int64
+	var shift uint
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		v |= int64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			if shift < 64 && b&0x40 != 0 {
+				v |= -1 << shift
+			}
+			return v, nil
+		}
+		if shift >= bits+7 {
+			return 0, errors.New("integer too long")
+		}
+	}
+}
+
+func (r *reader) f64() (float64, error) {
+	b, err := r.bytes(8)
+	if err != nil {
+		return 0, err
+	}
+	var bits uint64
+	for i := 7; i >= 0; i-- {
+		bits = bits<<8 | uint64(b[i])
+	}
+	return math.Float64frombits(bits), nil
+}
+
+func (r *reader) constant(typ ValueType) (uint64, error) {
+	op, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	switch {
+	case op == opI32Const && typ == I32:
+		n, err := r.sleb(32)
+		if err != nil {
+			return 0, err
+		}
+		v = uint64(uint32(n))
+	case op == opI64Const && typ == I64:
+		n, err := r.sleb(64)
+		if err != nil {
+			return 0, err
+		}
+		v = uint64(n)
+	case op == opF64Const && typ == F64:
+		f, err := r.f64()
+		if err != nil {
+			return 0, err
+		}
+		v = f64bits(f)
+	default:
+		return 0, fmt.Errorf("initializer opcode 0x%02x not supported for %s", op, typ)
+	}
+	if end, err := r.byte(); err != nil {
+		return 0, err
+	} else if end != opEnd {
+		return 0, errors.New("initializer is not a single constant")
+	}
+	return v, nil
+}
+
+func (r *reader) u32s() ([]uint32, error) {
+	count, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	if int(count) > len(r.data)-r.pos {
+		return nil, errTruncated
+	}
+	values := make([]uint32, count)
+	for i := range values {
+		if values[i], err = r.u32(); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func (r *reader) name() (string, error) {
+	n, err := r.u32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(int(n))
+	return string(b), err
+}
+
+func (r *reader) valueType() (ValueType, error) {
+	b, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	switch t := ValueType(b); t {
+	case I32, I64, F64:
+		return t, nil
+	}
+	return 0, fmt.Errorf("value type 0x%02x not supported (want i32, i64 or f64)", b)
+}
+
+func (r *reader) valueTypes() ([]ValueType, error) {
+	count, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	if int(count) > len(r.data)-r.pos {
+		return nil, errTruncated
+	}
+	types := make([]ValueType, count)
+	for i := range types {
+		if types[i], err = r.valueType(); err != nil {
+			return nil, err
+		}
+	}
+	return types, nil
+}
@@ -0,0 +1,65 @@
+// Built with:
+//
+//	rustc +nightly --target wasm32-unknown-unknown --crate-type cdylib -C opt-level=2 -C panic=abort fair_share.rs
+//
+// no_core keeps the module free of the standard library; rustc still emits the
+// memory, globals and data segment every wasm32 cdylib carries.
+#![feature(no_core, lang_items)]
+#![allow(internal_features)]
+#![no_core]
+#![no_std]
+
+#[lang = "pointee_sized"]
+pub trait PointeeSized {}
+#[lang = "meta_sized"]
+pub trait MetaSized: PointeeSized {}
+#[lang = "sized"]
+pub trait Sized: MetaSized {}
+#[lang = "copy"]
+pub trait Copy {}
+impl Copy for i32 {}
+
+#[lang = "add"]
+pub trait Add<Rhs = Self> {
+    type Output;
+    fn add(self, rhs: Rhs) -> Self::Output;
+}
+impl Add for i32 {
+    type Output = i32;
+    fn add(self, rhs: i32) -> i32 { self + rhs }
+}
+#[lang = "sub"]
+pub trait Sub<Rhs = Self> {
+    type Output;
+    fn sub(self, rhs: Rhs) -> Self::Output;
+}
+impl Sub for i32 {
+    type Output = i32;
+    fn sub(self, rhs: i32) -> i32 { self - rhs }
+}
+#[lang = "mul"]
+pub trait Mul<Rhs = Self> {
+    type Output;
+    fn mul(self, rhs: Rhs) -> Self::Output;
+}
+impl Mul for i32 {
+    type Output = i32;
+    fn mul(self, rhs: i32) -> i32 { self * rhs }
+}
+
+// Per-class weights; rustc lowers the match to a lookup table in linear memory.
+fn weight(class: i32) -> i32 {
+    match class {
+        0 => 1,
+        1 => 2,
+        2 => 5,
+        3 => 7,
+        4 => 11,
+        _ => 3,
+    }
+}
+
+#[no_mangle]
+pub extern "C" fn schedule(class: i32, priority: i32, wait: i32, _age: i32, _size: i32, _tick: i32, _queue_length: i32, _class_queue_length: i32, _busy: i32, _capacity: i32) -> i32 {
+    weight(class) * priority * 20 - wait
+}
@@ -0,0 +1,367 @@
+package wasm
+
+import (
+	"errors"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+type testFunc struct {
+	name    string
+	params  []ValueType
+	results []ValueType
+	locals  []ValueType
+	body    []byte
+}
+
+func vec(items ...[]byte) []byte {
+	out := []byte{byte(len(items))}
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+func section(id byte, body []byte) []byte {
+	return append([]byte{id, byte(len(body))}, body...)
+}
+
+func types(ts []ValueType) []byte {
+	out := []byte{byte(len(ts))}
+	for _, t := range ts {
+		out = append(out, byte(t))
+	}
+	return out
+}
+
+func assemble(funcs ...testFunc) []byte {
+	var sigs, indices, exports, bodies [][]byte
+	for i, fn := range funcs {
+		sigs = append(sigs, append(append([]byte{0x60}, types(fn.params)...), types(fn.results)...))
+		indices = append(indices, []byte{byte(i)})
+		if fn.name != "" {
+			exports = append(exports, append(append([]byte{byte(len(fn.name))}, fn.name...), 0, byte(i)))
+		}
+		body := []byte{byte(len(fn.locals))}
+		for _, local := range fn.locals {
+			body = append(body, 1, byte(local))
+		}
+		body = append(append(body, fn.body...), opEnd)
+		bodies = append(bodies, append([]byte{byte(len(body))}, body...))
+	}
+	out := []byte("\x00asm\x01\x00\x00\x00")
+	out = append(out, section(1, vec(sigs...))...)
+	out = append(out, section(3, vec(indices...))...)
+	out = append(out, section(7, vec(exports...))...)
+	return append(out, section(10, vec(bodies...))...)
+}
+
+func withSections(module []byte, sections ...[]byte) []byte {
+	out := append([]byte{}, module[:8]...)
+	for _, s := range sections {
+		out = append(out, s...)
+	}
+	return append(out, module[8:]...)
+}
+
+func f64const(v float64) []byte {
+	out := []byte{opF64Const}
+	b := math.Float64bits(v)
+	for i := 0; i < 8; i++ {
+		out = append(out, byte(b>>(8*i)))
+	}
+	return out
+}
+
+func TestCall(t *testing.T) {
+	i32x2 := []ValueType{I32, I32}
+	tests := []struct {
+		name string
+		fn   testFunc
+		args []uint64
+		want uint64
+	}{
+		{
+			name: "add",
+			fn:   testFunc{params: i32x2, results: []ValueType{I32}, body: []byte{opLocalGet, 0, opLocalGet, 1, 0x6a}},
+			args: []uint64{2, 3},
+			want: 5,
+		},
+		{
+			name: "signed division",
+			fn:   testFunc{params: i32x2, results: []ValueType{I32}, body: []byte{opLocalGet, 0, opLocalGet, 1, 0x6d}},
+			args: []uint64{uint64(uint32(0xfffffff9)), 2},
+			want: uint64(uint32(0xfffffffd)),
+		},
+		{
+			name: "factorial loop",
+			fn: testFunc{
+				params:  []ValueType{I64},
+				results: []ValueType{I64},
+				locals:  []ValueType{I64},
+				body: []byte{
+					opI64Const, 1, opLocalSet, 1,
+					opBlock, 0x40,
+					opLoop, 0x40,
+					opLocalGet, 0, 0x50, opBrIf, 1,
+					opLocalGet, 1, opLocalGet, 0, 0x7e, opLocalSet, 1,
+					opLocalGet, 0, opI64Const, 1, 0x7d, opLocalSet, 0,
+					opBr, 0,
+					opEnd,
+					opEnd,
+					opLocalGet, 1,
+				},
+			},
+			args: []uint64{10},
+			want: 3628800,
+		},
+		{
+			name: "if else",
+			fn: testFunc{
+				params:  []ValueType{I32},
+				results: []ValueType{I32},
+				body:    []byte{opLocalGet, 0, opIf, byte(I32), opI32Const, 10, opElse, opI32Const, 20, opEnd},
+			},
+			args: []uint64{0},
+			want: 20,
+		},
+		{
+			name: "br_table",
+			fn: testFunc{
+				params:  []ValueType{I32},
+				results: []ValueType{I32},
+				body: []byte{
+					opBlock, 0x40, opBlock, 0x40, opBlock, 0x40,
+					opLocalGet, 0, opBrTable, 2, 0, 1, 2,
+					opEnd, opI32Const, 10, opReturn,
+					opEnd, opI32Const, 11, opReturn,
+					opEnd, opI32Const, 12,
+				},
+			},
+			args: []uint64{1},
+			want: 11,
+		},
+		{
+			name: "f64 arithmetic",
+			fn: testFunc{
+				params:  []ValueType{I32},
+				results: []ValueType{F64},
+				body:    append(append([]byte{opLocalGet, 0, 0xb7}, f64const(0.5)...), 0xa2),
+			},
+			args: []uint64{7},
+			want: math.Float64bits(3.5),
+		},
+		{
+			name: "select",
+			fn: testFunc{
+				params:  i32x2,
+				results: []ValueType{I32},
+				body:    []byte{opLocalGet, 0, opLocalGet, 1, opLocalGet, 0, opLocalGet, 1, 0x48, opSelect},
+			},
+			args: []uint64{4, 9},
+			want: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn.name = "f"
+			m, err := Compile(assemble(tt.fn))
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, err := m.Call("f", 1000, tt.args...)
+			if err != nil {
+				t.Fatalf("Call() error = %v", err)
+			}
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("Call() = %v, want [%d]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCall_Internal(t *testing.T) {
+	m, err := Compile(assemble(
+		testFunc{name: "twice", params: []ValueType{I32}, results: []ValueType{I32}, body: []byte{opLocalGet, 0, opCall, 1, opCall, 1}},
+		testFunc{params: []ValueType{I32}, results: []ValueType{I32}, body: []byte{opLocalGet, 0, opI32Const, 3, 0x6c}},
+	))
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if typ, ok := m.Export("twice"); !ok || len(typ.Params) != 1 || typ.Results[0] != I32 {
+		t.Errorf("Export() = %v, %v", typ, ok)
+	}
+	got, err := m.Call("twice", 100, 2)
+	if err != nil || got[0] != 18 {
+		t.Errorf("Call() = %v, %v, want [18]", got, err)
+	}
+}
+
+func TestCall_Memory(t *testing.T) {
+	// One page of memory holding 42 at byte 16, and a mutable i32 global set to 7.
+	memory := section(5, []byte{1, 0, 1})
+	globals := section(6, []byte{1, byte(I32), 1, opI32Const, 7, opEnd})
+	data := section(11, []byte{1, 0, opI32Const, 16, opEnd, 1, 42})
+	i32 := []ValueType{I32}
+	tests := []struct {
+		name string
+		body []byte
+		want uint64
+	}{
+		{name: "data", body: []byte{opI32Const, 0, 0x28, 2, 16}, want: 42},
+		{name: "global", body: []byte{opGlobalGet, 0}, want: 7},
+		{
+			name: "store load",
+			body: []byte{opI32Const, 0, opI32Const, 0x7f, 0x37, 3, 60, opI32Const, 60, 0x2c, 0, 0},
+			want: 0xffffffff,
+		},
+		{
+			name: "fresh per call",
+			body: []byte{
+				opGlobalGet, 0, opI32Const, 1, 0x6a, opGlobalSet, 0,
+				opI32Const, 16, opI32Const, 16, 0x28, 2, 0, opI32Const, 1, 0x6a, 0x36, 2, 0,
+				opGlobalGet, 0, opI32Const, 16, 0x28, 2, 0, 0x6a,
+			},
+			want: 51,
+		},
+		{name: "size", body: []byte{opMemorySize, 0}, want: 1},
+		{name: "grow", body: []byte{opI32Const, 2, opMemoryGrow, 0, opMemorySize, 0, 0x6a}, want: 4},
+		{name: "grow past limit", body: []byte{opI32Const, 0x80, 0x04, opMemoryGrow, 0}, want: 0xffffffff},
+		{
+			name: "fill",
+			body: []byte{opI32Const, 16, opI32Const, 9, opI32Const, 4, opPrefix, 0x0b, 0, opI32Const, 16, 0x28, 2, 0},
+			want: 0x09090909,
+		},
+		{
+			name: "copy",
+			body: []byte{opI32Const, 32, opI32Const, 16, opI32Const, 1, opPrefix, 0x0a, 0, 0, opI32Const, 32, 0x2d, 0, 0},
+			want: 42,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Compile(withSections(assemble(testFunc{name: "f", results: i32, body: tt.body}), memory, globals, data))
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			for call := 0; call < 2; call++ {
+				got, err := m.Call("f", 100)
+				if err != nil || len(got) != 1 || got[0] != tt.want {
+					t.Errorf("Call() #%d = %v, %v, want [%d]", call, got, err, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCall_Toolchain(t *testing.T) {
+	data, err := os.ReadFile("testdata/fair_share.wasm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := Compile(data)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	tests := []struct {
+		class, priority, wait uint64
+		want                  int32
+	}{
+		{class: 0, priority: 3, wait: 7, want: 53},
+		{class: 2, priority: 3, wait: 7, want: 293},
+		{class: 4, priority: 1, wait: 0, want: 220},
+		{class: 9, priority: 2, wait: 200, want: -80},
+	}
+	for _, tt := range tests {
+		got, err := m.Call("schedule", 1000, tt.class, tt.priority, tt.wait, 0, 1, 0, 0, 0, 0, 1)
+		if err != nil || len(got) != 1 || int32(got[0]) != tt.want {
+			t.Errorf("Call(schedule, %d, %d, %d) = %v, %v, want [%d]", tt.class, tt.priority, tt.wait, got, err, tt.want)
+		}
+	}
+}
+
+func TestCall_Traps(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   testFunc
+		fuel int64
+		want string
+	}{
+		{name: "unreachable", fn: testFunc{body: []byte{opUnreachable}}, fuel: 10, want: "unreachable"},
+		{
+			name: "divide by zero",
+			fn:   testFunc{results: []ValueType{I32}, body: []byte{opI32Const, 1, opI32Const, 0, 0x6e}},
+			fuel: 10,
+			want: "divide by zero",
+		},
+		{name: "fuel", fn: testFunc{body: []byte{opLoop, 0x40, opBr, 0, opEnd}}, fuel: 1000, want: "fuel exhausted"},
+		{name: "recursion", fn: testFunc{body: []byte{opCall, 0}}, fuel: 1000, want: "call stack exhausted"},
+		{name: "underflow", fn: testFunc{body: []byte{opDrop}}, fuel: 10, want: "underflow"},
+		{
+			name: "float conversion",
+			fn:   testFunc{results: []ValueType{I32}, body: append(f64const(math.NaN()), 0xaa)},
+			fuel: 10,
+			want: "invalid conversion",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn.name = "f"
+			m, err := Compile(assemble(tt.fn))
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			_, err = m.Call("f", tt.fuel)
+			var trap *Trap
+			if !errors.As(err, &trap) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Call() error = %v, want trap containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	valid := assemble(testFunc{name: "f", results: []ValueType{I32}, body: []byte{opI32Const, 1}})
+	typesEnd := 8 + 2 + int(valid[9])
+	for n := 0; n < len(valid); n++ {
+		if _, err := Compile(valid[:n]); err == nil && n != 8 && n != typesEnd {
+			t.Errorf("Compile(truncated to %d bytes) succeeded", n)
+		}
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{name: "magic", data: []byte("\x00wat\x01\x00\x00\x00"), want: "not a WebAssembly"},
+		{name: "import", data: append([]byte("\x00asm\x01\x00\x00\x00"), section(2, vec())...), want: "import section"},
+		{name: "table", data: append([]byte("\x00asm\x01\x00\x00\x00"), section(4, vec())...), want: "table section"},
+		{name: "start", data: append([]byte("\x00asm\x01\x00\x00\x00"), section(8, []byte{0})...), want: "start section"},
+		{name: "memory limit", data: append([]byte("\x00asm\x01\x00\x00\x00"), section(5, []byte{1, 0, 0x81, 0x02})...), want: "257 pages"},
+		{
+			name: "data bounds",
+			data: append([]byte("\x00asm\x01\x00\x00\x00"), append(section(5, []byte{1, 0, 0}), section(11, []byte{1, 0, opI32Const, 0, opEnd, 1, 1})...)...),
+			want: "do not fit",
+		},
+		{name: "no memory", data: assemble(testFunc{body: []byte{opI32Const, 0, 0x28, 2, 0, opDrop}}), want: "without memory"},
+		{
+			name: "immutable global",
+			data: withSections(assemble(testFunc{body: []byte{opI32Const, 1, opGlobalSet, 0}}), section(6, []byte{1, byte(I32), 0, opI32Const, 0, opEnd})),
+			want: "immutable",
+		},
+		{name: "opcode", data: assemble(testFunc{body: []byte{0x2a, 2, 0}}), want: "unsupported opcode 0x2a"},
+		{name: "local", data: assemble(testFunc{body: []byte{opLocalGet, 3, opDrop}}), want: "local 3 out of range"},
+		{name: "branch", data: assemble(testFunc{body: []byte{opBr, 1}}), want: "branch depth 1"},
+		{name: "call", data: assemble(testFunc{body: []byte{opCall, 4}}), want: "unknown function 4"},
+		{name: "f32", data: assemble(testFunc{params: []ValueType{0x7d}}), want: "value type 0x7d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile(tt.data); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Compile() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}